@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"TRADE/pkg/bench"
+)
+
+// runBench times the tick hot path stage by stage and fails when the p99
+// latency of the whole pipeline exceeds the budget
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	ticks := flags.Int("ticks", 100000, "Ticks timed one by one for the latency percentiles")
+	seed := flags.Int64("seed", 1, "Seed of the generated price path")
+	budget := flags.Duration("budget", 100*time.Microsecond, "Maximum p99 latency of the pipeline per tick; 0 disables the check")
+	asJSON := flags.Bool("json", false, "Print the results as JSON, for comparing runs")
+	flags.Parse(args)
+
+	results := bench.Run(bench.Options{Ticks: *ticks, Seed: *seed})
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%-10s %10s %10s %10s %10s\n", "STAGE", "MEAN", "P50", "P99", "MAX")
+		for _, result := range results {
+			fmt.Printf("%-10s %10s %10s %10s %10s\n", result.Name, result.Mean, result.P50, result.P99, result.Max)
+		}
+	}
+
+	pipeline := results[len(results)-1]
+	if *budget > 0 && pipeline.P99 > *budget {
+		return fmt.Errorf("pipeline p99 of %s per tick is over the %s budget", pipeline.P99, *budget)
+	}
+	return nil
+}
@@ -0,0 +1,797 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/bars"
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/config"
+	"TRADE/pkg/daemon"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/export"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+	"TRADE/pkg/state"
+	"TRADE/pkg/store"
+	"TRADE/pkg/strategy"
+	"TRADE/pkg/types"
+	"TRADE/pkg/version"
+)
+
+// command is a CLI subcommand such as "trade restart"
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+// commands lists all available subcommands
+var commands = []command{
+	{"adopt", "Hand a position opened outside TRADE over to its exit management", runAdopt},
+	{"annotate", "Tag a trade of the trade history or add a review note to it", runAnnotate},
+	{"bench", "Time the tick hot path and check its p99 latency against a budget", runBench},
+	{"config", "Write a commented default config (init) or check a config file (validate)", runConfig},
+	{"data", "Check tick datasets for missing columns, time order, duplicates and price spikes (verify)", runData},
+	{"export", "Export trades, daily PnL and a performance summary to XLSX or CSV", runExport},
+	{"history", "Query closed trades by symbol, date range, outcome, run and tag", runHistory},
+	{"logs", "Show or follow session log entries filtered by level, component, symbol and time", runLogs},
+	{"resample", "Convert tick datasets into OHLCV bar files (CSV or Parquet)", runResample},
+	{"restart", "Hand off open trades and restart the running instance", runRestart},
+	{"scenario", "Inject flash crashes, gaps, feed freezes and spread blowouts into simulations and grade the response", runScenario},
+	{"snapshot", "Dump the running instance's state to a JSON file", runSnapshot},
+	{"version", "Print version and build information", runVersion},
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printCommands prints the list of available subcommands
+func printCommands() {
+	fmt.Println("Available commands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.description)
+	}
+}
+
+// runRestart asks the running instance to persist its open trades and exit
+// so the supervisor can start a new process that resumes managing them
+func runRestart(args []string) error {
+	flags := flag.NewFlagSet("restart", flag.ExitOnError)
+	pidFile := flags.String("pid-file", "trade.pid", "PID file of the running instance")
+	flags.Parse(args)
+
+	pid, err := signalInstance(*pidFile, syscall.SIGUSR2)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restart requested for process %d\n", pid)
+	return nil
+}
+
+// runSnapshot asks the running instance to dump its state (written to
+// the instance's snapshots/ directory)
+func runSnapshot(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	pidFile := flags.String("pid-file", "trade.pid", "PID file of the running instance")
+	flags.Parse(args)
+
+	pid, err := signalInstance(*pidFile, syscall.SIGUSR1)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot requested from process %d (see its snapshots/ directory)\n", pid)
+	return nil
+}
+
+// runAdopt adds an exchange position opened manually to the handoff
+// state, so the next start of the instance manages its exit like one of
+// its own trades
+func runAdopt(args []string) error {
+	flags := flag.NewFlagSet("adopt", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file of the instance taking over (its trading.symbol is adopted)")
+	price := flags.String("price", "", "Average entry price of the position")
+	quantity := flags.String("quantity", "", "Size of the position")
+	stop := flags.Float64("stop", 0, "Stop loss price (0 leaves the exit to the strategy)")
+	since := flags.String("since", "", "Entry time: a duration ago (2h) or a time (2006-01-02 15:04); default now")
+	accountName := flags.String("account", "", "Account holding the position, when accounts are configured")
+	stateFile := flags.String("state-file", "state/handoff.json", "Handoff state file of the instance")
+	pidFile := flags.String("pid-file", "trade.pid", "PID file of the instance")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if pid, running := daemon.InstanceRunning(*pidFile); running {
+		return fmt.Errorf("instance %d is running; stop it first, its handoff would overwrite the adopted position", pid)
+	}
+	entryFill, err := decimal.Parse(*price)
+	if err != nil || entryFill.Sign() <= 0 {
+		return fmt.Errorf("--price must be a positive number")
+	}
+	size, err := decimal.Parse(*quantity)
+	if err != nil || size.Sign() <= 0 {
+		return fmt.Errorf("--quantity must be a positive number")
+	}
+	if *stop < 0 || *stop >= entryFill.Float64() {
+		return fmt.Errorf("--stop must be below the entry price")
+	}
+	entryTime := time.Now()
+	if *since != "" {
+		if entryTime, err = parseTimeFlag(*since); err != nil {
+			return err
+		}
+	}
+
+	var holdings []account.Allocation
+	if len(cfg.Accounts) > 0 {
+		name, err := adoptAccount(cfg, *accountName)
+		if err != nil {
+			return err
+		}
+		holdings = []account.Allocation{{Account: name, Quantity: size}}
+	} else if *accountName != "" {
+		return fmt.Errorf("--account given but no accounts are configured")
+	}
+
+	symbol := strings.ToLower(cfg.Trading.Symbol)
+	trade := strategy.AdoptedTrade(entryFill.Float64(), entryTime, *stop)
+	err = state.Adopt(*stateFile, state.Position{
+		Symbol:    symbol,
+		Quantity:  size,
+		EntryFill: entryFill,
+		Holdings:  holdings,
+		Trade:     *trade,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Adopted %s %s at %s as trade %s; its exit is managed from the next start\n", size, symbol, entryFill, trade.ID)
+	return nil
+}
+
+// adoptAccount returns the configured account an adopted position is
+// booked to: the one named, or the only account trading the strategy
+func adoptAccount(cfg *config.Config, name string) (string, error) {
+	var assigned []string
+	for _, acc := range cfg.Accounts {
+		for _, strategyName := range acc.Strategies {
+			if strategyName == cfg.Trading.Strategy {
+				assigned = append(assigned, acc.Name)
+				break
+			}
+		}
+	}
+	if name == "" {
+		if len(assigned) != 1 {
+			return "", fmt.Errorf("--account is required: %d accounts trade %s", len(assigned), cfg.Trading.Strategy)
+		}
+		return assigned[0], nil
+	}
+	for _, acc := range assigned {
+		if acc == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("account %s does not trade %s", name, cfg.Trading.Strategy)
+}
+
+// signalInstance sends a signal to the instance named in the PID file
+func signalInstance(pidFile string, sig os.Signal) (int, error) {
+	pid, err := daemon.ReadPIDFile(pidFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %v", err)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := process.Signal(sig); err != nil {
+		return 0, fmt.Errorf("failed to signal process %d: %v", pid, err)
+	}
+	return pid, nil
+}
+
+// runVersion prints the build information
+func runVersion(args []string) error {
+	fmt.Println(version.Get())
+	return nil
+}
+
+// runHistory prints the closed trades in the trade history that match the
+// given symbol, time range, outcome and run, followed by a summary
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file whose storage section selects the database")
+	path := flags.String("db", "", "SQLite trade history database (overrides the config)")
+	symbol := flags.String("symbol", "", "Only trades in this symbol")
+	since := flags.String("since", "", "Closed at or after: a duration ago (24h) or a time (2006-01-02)")
+	until := flags.String("until", "", "Closed before, same formats as --since")
+	outcome := flags.String("outcome", "", "Only win, loss or flat trades")
+	run := flags.String("run", "", "Only trades of this run ID")
+	mode := flags.String("mode", "", "Only live, paper or backtest trades")
+	tags := flags.String("tag", "", "Only trades carrying these tags, comma separated (e.g. regime:trending)")
+	last := flags.Int("n", 0, "Print only the last N matching trades (0 prints all)")
+	notes := flags.Bool("notes", false, "Print the notes of each trade under it")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	query := store.TradeQuery{Symbol: *symbol, RunID: *run, Mode: *mode, Limit: *last}
+	if query.Tags, err = parseTagsFlag(*tags); err != nil {
+		return err
+	}
+	if query.Since, err = parseTimeFlag(*since); err != nil {
+		return err
+	}
+	if query.Until, err = parseTimeFlag(*until); err != nil {
+		return err
+	}
+	if query.Outcome, err = store.ParseOutcome(*outcome); err != nil {
+		return err
+	}
+
+	history, err := openHistory(cfg, *path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	trades, err := history.Trades(query)
+	if err != nil {
+		return err
+	}
+	if len(trades) == 0 {
+		fmt.Println("No matching trades")
+		return nil
+	}
+
+	fmt.Printf("%-19s  %-10s  %-8s  %-12s  %-12s  %-12s  %-12s  %8s  %-16s  %s\n",
+		"CLOSED", "SYMBOL", "MODE", "ENTRY", "EXIT", "QUANTITY", "PNL", "PNL %", "REASON", "TAGS")
+	wins, total := 0, decimal.Zero
+	for _, trade := range trades {
+		fmt.Printf("%-19s  %-10s  %-8s  %-12s  %-12s  %-12s  %-12s  %7.2f%%  %-16s  %s\n",
+			calendar.Display(trade.ExitTime).Format("2006-01-02 15:04:05"), trade.Symbol, trade.Mode,
+			trade.EntryPrice, trade.ExitPrice, trade.Quantity, trade.PnL, trade.PnLPercent, trade.Reason,
+			strings.Join(trade.Tags, ","))
+		if *notes {
+			for _, note := range trade.Notes {
+				printNote(note, "    ")
+			}
+		}
+		if trade.Outcome() == store.OutcomeWin {
+			wins++
+		}
+		total = total.Add(trade.PnL)
+	}
+	fmt.Printf("\n%d trade(s), %d win(s) (%.1f%%), total PnL %s\n",
+		len(trades), wins, float64(wins)/float64(len(trades))*100, total)
+	return nil
+}
+
+// runAnnotate tags and untags a trade of the trade history and adds notes
+// to it, then prints its tags and notes. Open trades can be annotated once
+// their entry order is in the history.
+func runAnnotate(args []string) error {
+	flags := flag.NewFlagSet("annotate", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file whose storage section selects the database")
+	path := flags.String("db", "", "SQLite trade history database (overrides the config)")
+	tags := flags.String("tag", "", "Tags to add, comma separated (e.g. review:late-entry)")
+	untag := flags.String("untag", "", "Tags to remove, comma separated")
+	text := flags.String("note", "", "Note to add")
+	author := flags.String("author", os.Getenv("USER"), "Author of the note")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: trade annotate [flags] <trade-id>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one trade ID")
+	}
+	tradeID := flags.Arg(0)
+	added, err := parseTagsFlag(*tags)
+	if err != nil {
+		return err
+	}
+	removed, err := parseTagsFlag(*untag)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	history, err := openHistory(cfg, *path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	// The trade is closed, or open with its entry order recorded
+	closed, err := history.Trades(store.TradeQuery{ID: tradeID})
+	if err != nil {
+		return err
+	}
+	if len(closed) == 0 {
+		orders, err := history.Orders(store.OrderQuery{TradeID: tradeID, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return fmt.Errorf("unknown trade: %s", tradeID)
+		}
+	}
+
+	if len(added) > 0 {
+		if err := history.TagTrade(tradeID, added...); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		if err := history.UntagTrade(tradeID, removed...); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(*text) != "" {
+		if err := history.AddNote(&store.Note{TradeID: tradeID, Time: time.Now(), Author: *author, Text: *text}); err != nil {
+			return err
+		}
+	}
+
+	current, notes, err := history.Annotations(tradeID)
+	if err != nil {
+		return err
+	}
+	state := "closed"
+	if len(closed) == 0 {
+		state = "open"
+	}
+	fmt.Printf("Trade %s (%s)\n", tradeID, state)
+	if len(current) == 0 {
+		fmt.Println("Tags:  none")
+	} else {
+		fmt.Printf("Tags:  %s\n", strings.Join(current, ", "))
+	}
+	for _, note := range notes {
+		printNote(note, "")
+	}
+	return nil
+}
+
+// printNote prints a note on a trade with its time and author
+func printNote(note store.Note, indent string) {
+	author := ""
+	if note.Author != "" {
+		author = " " + note.Author
+	}
+	fmt.Printf("%s%s%s: %s\n", indent, calendar.Display(note.Time).Format("2006-01-02 15:04:05"), author, note.Text)
+}
+
+// parseTagsFlag parses a comma separated list of tags
+func parseTagsFlag(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	return store.ParseTags(strings.Split(value, ","))
+}
+
+// runExport writes the trades closed in a period, their daily PnL and a
+// performance summary as an XLSX workbook or a set of CSV files
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file whose storage section selects the database")
+	path := flags.String("db", "", "SQLite trade history database (overrides the config)")
+	format := flags.String("format", "xlsx", "Output format: xlsx (one workbook) or csv (one file per sheet)")
+	out := flags.String("out", "", "Output file (xlsx) or file name prefix (csv); default trades_<date>")
+	symbol := flags.String("symbol", "", "Only trades in this symbol")
+	since := flags.String("since", "", "Period start: a duration ago (720h) or a time (2006-01-02)")
+	until := flags.String("until", "", "Period end (exclusive), same formats as --since")
+	run := flags.String("run", "", "Only trades of this run ID")
+	mode := flags.String("mode", "", "Only live, paper or backtest trades")
+	tags := flags.String("tag", "", "Only trades carrying these tags, comma separated")
+	flags.Parse(args)
+
+	if *format != "xlsx" && *format != "csv" {
+		return fmt.Errorf("unknown export format: %s (want xlsx or csv)", *format)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	query := store.TradeQuery{Symbol: *symbol, RunID: *run, Mode: *mode}
+	if query.Tags, err = parseTagsFlag(*tags); err != nil {
+		return err
+	}
+	if query.Since, err = parseTimeFlag(*since); err != nil {
+		return err
+	}
+	if query.Until, err = parseTimeFlag(*until); err != nil {
+		return err
+	}
+
+	history, err := openHistory(cfg, *path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	trades, err := history.Trades(query)
+	if err != nil {
+		return err
+	}
+	report := export.NewReport(trades, query.Since, query.Until, calendar.DisplayLocation())
+
+	if *out == "" {
+		*out = "trades_" + time.Now().Format("2006-01-02")
+	}
+	if *format == "csv" {
+		paths, err := export.WriteCSVFiles(strings.TrimSuffix(*out, ".csv"), report.Tables())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d trade(s) to %s\n", len(trades), strings.Join(paths, ", "))
+		return nil
+	}
+
+	if !strings.HasSuffix(*out, ".xlsx") {
+		*out += ".xlsx"
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteXLSX(file, report.Tables()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %v", *out, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d trade(s) to %s\n", len(trades), *out)
+	return nil
+}
+
+// barOutput is one bar file being written by resample
+type barOutput struct {
+	path      string
+	file      *os.File
+	writer    bars.Writer
+	resampler *bars.Resampler
+	count     int
+	err       error
+}
+
+// runResample converts tick datasets into OHLCV bar files, one per dataset
+// and interval, for bar-based backtests and external charting tools
+func runResample(args []string) error {
+	flags := flag.NewFlagSet("resample", flag.ExitOnError)
+	intervals := flags.String("interval", "1m", "Bar intervals, comma separated (e.g. 1m,15m,1h,1d)")
+	format := flags.String("format", "csv", "Output format: csv or parquet")
+	outDir := flags.String("out", filepath.Join("data", "bars"), "Output directory")
+	fill := flags.Bool("fill", false, "Write flat bars at the previous close for intervals without ticks")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: trade resample [flags] <dataset.csv>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *format != "csv" && *format != "parquet" {
+		return fmt.Errorf("unknown bar format: %s (want csv or parquet)", *format)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no tick dataset given")
+	}
+	var durations []time.Duration
+	for _, value := range strings.Split(*intervals, ",") {
+		interval, err := bars.ParseInterval(value)
+		if err != nil {
+			return err
+		}
+		durations = append(durations, interval)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	for _, dataset := range flags.Args() {
+		if err := resampleDataset(dataset, durations, *format, *outDir, *fill); err != nil {
+			return fmt.Errorf("%s: %v", dataset, err)
+		}
+	}
+	return nil
+}
+
+// resampleDataset reads a tick dataset once and writes a bar file for each
+// interval
+func resampleDataset(dataset string, intervals []time.Duration, format, outDir string, fill bool) error {
+	input, err := os.Open(dataset)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	ticks, err := market.NewTickReader(bufio.NewReader(input))
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(dataset), filepath.Ext(dataset))
+	outputs := make([]*barOutput, 0, len(intervals))
+	defer func() {
+		for _, output := range outputs {
+			output.file.Close()
+		}
+	}()
+	for _, interval := range intervals {
+		resampler, err := bars.NewResampler(interval, fill)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(outDir, fmt.Sprintf("%s_%s.%s", name, bars.FormatInterval(interval), format))
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		output := &barOutput{path: path, file: file, resampler: resampler}
+		outputs = append(outputs, output)
+		if format == "parquet" {
+			output.writer = bars.NewParquetWriter(file)
+		} else if output.writer, err = bars.NewCSVWriter(file); err != nil {
+			return err
+		}
+	}
+
+	read, invalid := 0, 0
+	var tick types.TickData
+	for {
+		err := ticks.Read(&tick)
+		var rowErr *market.InvalidRowError
+		if errors.As(err, &rowErr) {
+			invalid++
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		read++
+		for _, output := range outputs {
+			output.resampler.Add(&tick, output.write)
+		}
+	}
+
+	for _, output := range outputs {
+		output.resampler.Flush(output.write)
+		if output.err == nil {
+			output.err = output.writer.Close()
+		}
+		if output.err != nil {
+			return fmt.Errorf("failed to write %s: %v", output.path, output.err)
+		}
+		if err := output.file.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d %s bar(s) to %s\n", output.count, bars.FormatInterval(output.resampler.Interval()), output.path)
+		if late := output.resampler.Late(); late > 0 {
+			fmt.Printf("  %d out-of-order tick(s) dropped\n", late)
+		}
+	}
+	fmt.Printf("Resampled %d tick(s) from %s", read, dataset)
+	if invalid > 0 {
+		fmt.Printf(" (%d invalid row(s) skipped)", invalid)
+	}
+	fmt.Println()
+	return nil
+}
+
+// write writes a bar, keeping the first error
+func (o *barOutput) write(bar bars.Bar) {
+	if o.err != nil {
+		return
+	}
+	o.err = o.writer.Write(bar)
+	o.count++
+}
+
+// loadConfig loads a command's config file (the defaults if path is empty)
+// and shows times in its time zone
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	location, err := calendar.LoadLocation(cfg.Calendar.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	calendar.SetDisplayLocation(location)
+	return cfg, nil
+}
+
+// openHistory opens the trade history database: the SQLite file at path
+// if given, otherwise the one configured in cfg
+func openHistory(cfg *config.Config, path string) (store.Store, error) {
+	storage := cfg.Storage
+	if path != "" {
+		storage = config.StorageConfig{Type: "sqlite", Path: path}
+	}
+	switch storage.Type {
+	case "":
+		return nil, fmt.Errorf("trade history storage is disabled in the config")
+	case "sqlite":
+		if _, err := os.Stat(storage.Path); err != nil {
+			return nil, fmt.Errorf("no trade history: %v", err)
+		}
+	}
+	return store.Open(storage.Type, storage.Source())
+}
+
+// runLogs prints, and optionally follows, session log entries matching
+// the given level, component, symbol and time range
+func runLogs(args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file whose time zone the log times are shown in")
+	dir := flags.String("dir", "logs", "Directory holding the session logs")
+	file := flags.String("file", "", "Log file to read (default: latest session log)")
+	all := flags.Bool("all", false, "Read all session logs, oldest first")
+	level := flags.String("level", "debug", "Minimum level: debug, info, warning, error or critical")
+	component := flags.String("component", "", "Only entries from this component (e.g. market)")
+	symbol := flags.String("symbol", "", "Only entries for this symbol")
+	since := flags.String("since", "", "Start of the time range: a duration ago (30m) or a time (2006-01-02 15:04:05)")
+	until := flags.String("until", "", "End of the time range, same formats as --since")
+	last := flags.Int("n", 0, "Print only the last N matching entries (0 prints all)")
+	follow := flags.Bool("f", false, "Keep following the log file for new entries")
+	flags.Parse(args)
+
+	if _, err := loadConfig(*configPath); err != nil {
+		return err
+	}
+	query := logger.Query{Component: *component, Symbol: *symbol}
+	var err error
+	if query.MinLevel, err = logger.ParseLevel(*level); err != nil {
+		return err
+	}
+	if query.Since, err = parseTimeFlag(*since); err != nil {
+		return err
+	}
+	if query.Until, err = parseTimeFlag(*until); err != nil {
+		return err
+	}
+
+	// Select the files to read
+	var files []string
+	switch {
+	case *file != "":
+		files = []string{*file}
+	case *all:
+		if files, err = logger.SessionLogs(*dir); err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no session logs in %s", *dir)
+		}
+	default:
+		latest, err := logger.LatestSessionLog(*dir)
+		if err != nil {
+			return err
+		}
+		files = []string{latest}
+	}
+
+	filter := &logFilter{query: query}
+	var matched []string
+	emit := func(line string) {
+		matched = append(matched, line)
+		if *last > 0 && len(matched) > *last {
+			matched = matched[1:]
+		}
+	}
+
+	var reader *bufio.Reader
+	for _, path := range files {
+		filter.partial = ""
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		reader = bufio.NewReader(f)
+		readLogLines(reader, filter, emit)
+		if *follow && path == files[len(files)-1] {
+			// Keep the last file open to follow it
+			defer f.Close()
+		} else {
+			f.Close()
+		}
+	}
+
+	for _, line := range matched {
+		fmt.Println(line)
+	}
+	if !*follow {
+		return nil
+	}
+
+	// Poll for entries appended by the running instance
+	printLine := func(line string) { fmt.Println(line) }
+	for {
+		time.Sleep(500 * time.Millisecond)
+		readLogLines(reader, filter, printLine)
+	}
+}
+
+// logFilter decides which log lines to print. Lines that are not entries
+// (continuations) follow the decision for the entry before them.
+type logFilter struct {
+	query   logger.Query
+	matched bool
+	partial string // Incomplete last line, completed by a later read
+}
+
+// accept reports whether the line should be printed
+func (f *logFilter) accept(line string) bool {
+	if entry, ok := logger.ParseEntry(line); ok {
+		f.matched = f.query.Match(entry)
+	}
+	return f.matched
+}
+
+// readLogLines passes every complete line up to EOF through the filter.
+// A line still being written is kept until the next call completes it.
+func readLogLines(reader *bufio.Reader, filter *logFilter, emit func(line string)) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			filter.partial += line
+			return
+		}
+		line = strings.TrimRight(filter.partial+line, "\r\n")
+		filter.partial = ""
+		if filter.accept(line) {
+			emit(line)
+		}
+	}
+}
+
+// parseTimeFlag parses a time range flag: empty, a duration before now,
+// or a time in the display time zone
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, calendar.DisplayLocation()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
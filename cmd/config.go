@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"TRADE/pkg/asset"
+	"TRADE/pkg/bars"
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/config"
+	"TRADE/pkg/execution"
+	"TRADE/pkg/guard"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+	"TRADE/pkg/schedule"
+	"TRADE/pkg/status"
+	"TRADE/pkg/strategy"
+)
+
+// runConfig writes the default config (init) or checks a config file
+// (validate)
+func runConfig(args []string) error {
+	usage := "usage: trade config init [--out=FILE] | trade config validate [--live-trading] <config.yaml>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "init":
+		return runConfigInit(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	default:
+		return fmt.Errorf("unknown config command %q; %s", args[0], usage)
+	}
+}
+
+// runConfigInit writes the default configuration with every setting
+// commented, to stdout or a new file
+func runConfigInit(args []string) error {
+	flags := flag.NewFlagSet("config init", flag.ExitOnError)
+	out := flags.String("out", "", "File to write (default: stdout)")
+	force := flags.Bool("force", false, "Overwrite the file if it exists")
+	flags.Parse(args)
+
+	if *out == "" {
+		_, err := os.Stdout.Write(config.Example)
+		return err
+	}
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(*out, mode, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists (use --force to overwrite it)", *out)
+		}
+		return err
+	}
+	if _, err := file.Write(config.Example); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the default config to %s\n", *out)
+	return nil
+}
+
+// runConfigValidate checks a config file before startup: unknown keys,
+// values out of range, names the components do not know and, for live
+// trading, the API keys in the environment
+func runConfigValidate(args []string) error {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	live := flags.Bool("live-trading", false, "Also check what live trading needs (implied by execution.acknowledge_live_trading)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: trade config validate [flags] <config.yaml>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("no config file given")
+	}
+	path := flags.Arg(0)
+
+	cfg, problems := config.LoadStrict(path)
+	if cfg != nil {
+		problems = append(problems, cfg.Validate()...)
+		problems = append(problems, checkComponents(cfg)...)
+		if *live || cfg.Execution.AcknowledgeLiveTrading {
+			problems = append(problems, cfg.ValidateLive()...)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%s:\n", path)
+		for _, problem := range problems {
+			fmt.Printf("  - %v\n", problem)
+		}
+		return fmt.Errorf("%s is invalid: %d problem(s)", path, len(problems))
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+// checkComponents checks the settings the components parse, with their
+// own parsers, so names are accepted exactly as at startup
+func checkComponents(cfg *config.Config) []error {
+	var problems []error
+	check := func(setting string, err error) {
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %v", setting, err))
+		}
+	}
+
+	_, err := logger.ParseLevel(cfg.Logging.Level)
+	check("logging.level", err)
+	_, err = logger.ParseFormat(cfg.Logging.Format)
+	check("logging.format", err)
+	_, err = logger.ParseConsolePolicy(cfg.Logging.Console)
+	check("logging.console", err)
+	for component, name := range cfg.Logging.Components {
+		_, err = logger.ParseLevel(name)
+		check("logging.components."+component, err)
+	}
+	switch remote := cfg.Logging.Remote; remote.Type {
+	case "":
+	case "syslog", "loki":
+		_, err = logger.ParseLevel(remote.Level)
+		check("logging.remote.level", err)
+	default:
+		check("logging.remote.type", fmt.Errorf("unknown type %q (want syslog, loki or empty)", remote.Type))
+	}
+	switch tracker := cfg.Logging.ErrorTracker.Type; tracker {
+	case "", "sentry", "webhook":
+	default:
+		check("logging.error_tracker.type", fmt.Errorf("unknown type %q (want sentry, webhook or empty)", tracker))
+	}
+	_, err = status.NewRenderer(cfg.Status.Renderer)
+	check("status.renderer", err)
+
+	_, err = calendar.LoadLocation(cfg.Calendar.Timezone)
+	check("calendar.timezone", err)
+	class, err := asset.Lookup(cfg.Trading.AssetClass)
+	check("trading.asset_class", err)
+	for symbol, name := range cfg.Market.AssetClasses {
+		_, err = asset.Lookup(name)
+		check("market.asset_classes."+symbol, err)
+	}
+	// An unknown asset class leaves no calendar to fall back on
+	exchange := cfg.Calendar.Exchange
+	if exchange == "" {
+		exchange = class.Exchange
+	}
+	if exchange != "" {
+		_, err = calendar.Load(exchange, cfg.Calendar.Holidays)
+		check("calendar", err)
+	}
+
+	if candles := cfg.Market.Candles; len(candles.Timeframes) > 0 {
+		timeframes, err := market.ParseTimeframes(candles.Timeframes)
+		check("market.candles.timeframes", err)
+		if err == nil {
+			_, err = market.NewCandleBuilder(timeframes, candles.History)
+			check("market.candles.timeframes", err)
+		}
+		if candles.Indicators != "" {
+			indicators, err := bars.ParseInterval(candles.Indicators)
+			if err == nil && !slices.Contains(timeframes, indicators) {
+				err = fmt.Errorf("%s candles are not built (market.candles.timeframes)", candles.Indicators)
+			}
+			check("market.candles.indicators", err)
+		}
+	} else if candles.Indicators != "" {
+		check("market.candles.indicators", fmt.Errorf("no candles are built (market.candles.timeframes)"))
+	}
+	built, _ := market.ParseTimeframes(cfg.Market.Candles.Timeframes)
+	for _, name := range cfg.Strategy.Timeframes.Timeframes() {
+		timeframe, err := bars.ParseInterval(name)
+		if err == nil && !slices.Contains(built, timeframe) {
+			err = fmt.Errorf("%s candles are not built (market.candles.timeframes)", name)
+		}
+		check("strategy.timeframes", err)
+	}
+	if exchange := strings.ToLower(cfg.Market.Exchange); exchange != "" && exchange != "binance" {
+		check("market.exchange", fmt.Errorf("unknown exchange %q (want binance)", cfg.Market.Exchange))
+	}
+	if exchange := strings.ToLower(cfg.Execution.Exchange); exchange != "" && exchange != "binance" {
+		check("execution.exchange", fmt.Errorf("unknown exchange %q (want binance)", cfg.Execution.Exchange))
+	}
+	_, err = execution.ParseOrderType(cfg.Execution.OrderType)
+	check("execution.order_type", err)
+
+	for i, plugin := range cfg.Strategy.Plugins {
+		_, err = strategy.LoadPlugin(strategy.PluginSettings{
+			Path:    plugin.Path,
+			Name:    plugin.Name,
+			Command: plugin.Command,
+			Args:    plugin.Args,
+			Timeout: plugin.Timeout,
+		})
+		check(fmt.Sprintf("strategy.plugins[%d]", i), err)
+	}
+	_, err = strategy.Lookup(cfg.Strategy.Type)
+	check("strategy.type", err)
+	for _, problem := range strategy.CheckThresholds(cfg.Strategy.Thresholds) {
+		check("strategy.thresholds", problem)
+	}
+	names := make(map[string]bool, len(cfg.Strategy.Instances))
+	for i, instance := range cfg.Strategy.Instances {
+		setting := fmt.Sprintf("strategy.instances[%d]", i)
+		_, err = strategy.Lookup(instance.Type)
+		check(setting+".type", err)
+		for _, problem := range strategy.CheckThresholds(cfg.Strategy.InstanceThresholds(instance)) {
+			check(setting+".thresholds", problem)
+		}
+		name := instance.Name
+		if name == "" {
+			name = strings.ToLower(instance.Type)
+		}
+		if names[name] {
+			check(setting+".name", fmt.Errorf("instance %q configured twice; name the instances of the same type", name))
+		}
+		names[name] = true
+	}
+	_, err = strategy.ParseArbitration(cfg.Strategy.Arbitration.Mode)
+	check("strategy.arbitration.mode", err)
+	if votes := cfg.Strategy.Arbitration.MinVotes; votes < 0 || votes > len(cfg.Strategy.StrategyInstances()) {
+		check("strategy.arbitration.min_votes", fmt.Errorf("must be between 0 (a majority) and the number of instances"))
+	}
+	_, err = strategy.ParseImbalanceSource(cfg.Strategy.Imbalance)
+	check("strategy.imbalance", err)
+	_, err = strategy.ParseSizingMode(cfg.Strategy.Sizing.Mode)
+	check("strategy.sizing.mode", err)
+	if adaptive := cfg.Adaptive; adaptive.Enabled {
+		parameters := make(map[string]strategy.Scaling, len(adaptive.Parameters))
+		for name, scaling := range adaptive.Parameters {
+			parameters[name] = strategy.Scaling{Low: scaling.Low, High: scaling.High}
+		}
+		_, err = strategy.NewAdaptive(strategy.AdaptiveSettings{Parameters: parameters})
+		check("adaptive.parameters", err)
+	}
+
+	if cfg.News.Enabled {
+		_, err = guard.ParseImpact(cfg.News.MinImpact)
+		check("news.min_impact", err)
+	}
+	if cfg.DailySummary.Enabled {
+		_, err = schedule.ParseClock(cfg.DailySummary.At)
+		check("daily_summary.at", err)
+	}
+	return problems
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"TRADE/pkg/market"
+)
+
+// runData checks tick datasets (verify)
+func runData(args []string) error {
+	usage := "usage: trade data verify [--repair] [--dedupe] [--spike=FRACTION] <dataset.csv>..."
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "verify":
+		return runDataVerify(args[1:])
+	default:
+		return fmt.Errorf("unknown data command %q; %s", args[0], usage)
+	}
+}
+
+// runDataVerify checks tick datasets for missing columns, unparseable
+// rows, rows out of time order and price spikes, counts duplicate rows,
+// saves a quality report next to each and, with --repair, a repaired copy
+func runDataVerify(args []string) error {
+	flags := flag.NewFlagSet("data verify", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "Write a repaired copy next to each dataset with issues (<name>.repaired.csv): valid rows in time order, without price spikes")
+	dedupe := flags.Bool("dedupe", false, "Also drop duplicate rows from the repaired copy")
+	spike := flags.Float64("spike", 0.05, "Deviation from the median price of the neighbouring rows, as a fraction, above which a price is a spike; 0 disables the check")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: trade data verify [flags] <dataset.csv>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no tick dataset given")
+	}
+	if *spike < 0 {
+		return fmt.Errorf("--spike cannot be negative")
+	}
+
+	failed := 0
+	for _, dataset := range flags.Args() {
+		report, err := verifyDataset(dataset, market.VerifyOptions{SpikeThreshold: *spike}, *repair, *dedupe)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dataset, err)
+		}
+		if !report.OK() && report.Repaired == "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d dataset(s) have issues", failed, flags.NArg())
+	}
+	return nil
+}
+
+// verifyDataset checks a dataset, prints a summary and saves the report
+// (and the repaired dataset) next to it
+func verifyDataset(dataset string, opts market.VerifyOptions, repair, dedupe bool) (*market.QualityReport, error) {
+	input, err := os.Open(dataset)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	report, err := market.VerifyDataset(dataset, bufio.NewReader(input), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(dataset, ".csv")
+	if repair && (!report.OK() || dedupe && report.DuplicateRows > 0) && len(report.MissingColumns) == 0 {
+		path := base + ".repaired.csv"
+		if report.RepairedRows, err = writeRepaired(report, path, dedupe); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		report.Repaired = path
+	}
+
+	reportPath := base + ".quality.json"
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(reportPath, append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("%s: %d row(s), %d valid", dataset, report.Rows, report.ValidRows)
+	if !report.First.IsZero() {
+		fmt.Printf(", %s to %s", report.First.Format("2006-01-02 15:04:05"), report.Last.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf(", %d sharing a timestamp, %d duplicate(s)\n", report.DuplicateTimestamps, report.DuplicateRows)
+	if report.OK() {
+		fmt.Println("  no issues")
+	}
+	kinds := make([]string, 0, len(report.Counts))
+	for kind := range report.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("  %-16s %d\n", kind, report.Counts[kind])
+	}
+	if report.Repaired != "" {
+		fmt.Printf("  repaired: %d row(s) written to %s\n", report.RepairedRows, report.Repaired)
+	}
+	fmt.Printf("  report: %s\n", reportPath)
+	return report, nil
+}
+
+// writeRepaired writes the repaired dataset to a new file
+func writeRepaired(report *market.QualityReport, path string, dedupe bool) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	writer := bufio.NewWriter(file)
+	written, err := report.Repair(writer, dedupe)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
@@ -7,46 +7,263 @@ import (
 	"os/signal"
 	"syscall"
 
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/config"
+	"TRADE/pkg/daemon"
 	"TRADE/pkg/logger"
 	"TRADE/pkg/manager"
+	"TRADE/pkg/version"
 )
 
+// exitRestart is the exit code used after a state handoff so the
+// supervisor restarts the process (see RestartForceExitStatus in the unit)
+const exitRestart = 75
+
 func main() {
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Parse command line arguments
-	mode := flag.String("mode", "live", "Trading mode: live or backtest")
+	mode := flag.String("mode", "live", "Trading mode: live, paper, backtest or sim")
+	daemonMode := flag.Bool("daemon", false, "Run as a supervised daemon (PID file, systemd notifications)")
+	pidFile := flag.String("pid-file", "trade.pid", "PID file path used in daemon mode")
+	configPath := flag.String("config", "", "Path to the YAML config file")
+	liveTrading := flag.Bool("live-trading", false, "Send real orders (requires execution.acknowledge_live_trading in config)")
+	logLevel := flag.String("log-level", "", "Log level: debug, info, warning, error or critical (overrides config)")
+	logFormat := flag.String("log-format", "", "Log format: text or json (overrides config)")
+	logConsole := flag.String("log-console", "", "Console log policy: errors, quiet or verbose (overrides config)")
+	statusRenderer := flag.String("status", "", "Status display: console, json or tui (overrides config)")
+	stateFile := flag.String("state-file", "state/handoff.json", "File used to hand off open trades across restarts")
+	dataset := flag.String("dataset", "", "Tick datasets replayed in backtest mode: a path, or comma-separated paths and glob patterns merged in time order (default: first CSV file in data/)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [command] [options]\n", os.Args[0])
+		printCommands()
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	// Initialize logger
-	log := logger.NewLogger()
+	// Load configuration
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Resolve execution mode; anything short of explicit opt-in is paper
+	execMode, err := cfg.ExecutionMode(*liveTrading)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Show times in the configured zone; they stay UTC internally
+	location, err := calendar.LoadLocation(cfg.Calendar.Timezone)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	calendar.SetDisplayLocation(location)
+
+	// Initialize logger; flags override the config file
+	if *logLevel != "" {
+		cfg.Logging.Level = *logLevel
+	}
+	if *logFormat != "" {
+		cfg.Logging.Format = *logFormat
+	}
+	if *logConsole != "" {
+		cfg.Logging.Console = *logConsole
+	}
+	if *statusRenderer != "" {
+		cfg.Status.Renderer = *statusRenderer
+	}
+	logOptions := logger.DefaultOptions()
+	if logOptions.Level, err = logger.ParseLevel(cfg.Logging.Level); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if logOptions.Format, err = logger.ParseFormat(cfg.Logging.Format); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if logOptions.Console, err = logger.ParseConsolePolicy(cfg.Logging.Console); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	logOptions.ComponentLevels = make(map[string]logger.LogLevel)
+	for component, name := range cfg.Logging.Components {
+		level, err := logger.ParseLevel(name)
+		if err != nil {
+			fmt.Printf("Error: component %s: %v\n", component, err)
+			os.Exit(1)
+		}
+		logOptions.ComponentLevels[component] = level
+	}
+	logOptions.DedupWindow = cfg.Logging.DedupWindow
+	if remote := cfg.Logging.Remote; remote.Type != "" {
+		shipLevel, err := logger.ParseLevel(remote.Level)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		logOptions.Shipping = &logger.ShippingOptions{
+			Type:          remote.Type,
+			Address:       remote.Address,
+			Network:       remote.Network,
+			Labels:        remote.Labels,
+			Level:         shipLevel,
+			BatchSize:     remote.BatchSize,
+			BufferSize:    remote.BufferSize,
+			FlushInterval: remote.FlushInterval,
+		}
+	}
+	switch tracker := cfg.Logging.ErrorTracker; tracker.Type {
+	case "":
+	case "sentry":
+		sentry, err := logger.NewSentryTracker(tracker.DSN, tracker.Environment, version.Version)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		logOptions.ErrorTracker = sentry
+	case "webhook":
+		logOptions.ErrorTracker = logger.NewWebhookTracker(tracker.URL)
+	default:
+		fmt.Printf("Error: unknown error tracker type: %s\n", tracker.Type)
+		os.Exit(1)
+	}
+	log := logger.NewLoggerWithOptions(logOptions)
+	defer log.CapturePanic()
+	if cfg.Logging.JournalPath != "" {
+		if err := log.EnableJournal(cfg.Logging.JournalPath); err != nil {
+			log.Error(fmt.Sprintf("Trade journal disabled: %v", err))
+		}
+	}
+	if cfg.Logging.AuditPath != "" {
+		if err := log.EnableAudit(cfg.Logging.AuditPath); err != nil {
+			log.Error(fmt.Sprintf("Order audit log disabled: %v", err))
+		}
+	}
 	log.Info("Starting Trading System")
+	log.Info(version.Get().String())
+	fmt.Println(version.Get())
+	fmt.Printf("Execution mode: %s\n", execMode)
+
+	// Write PID file so supervisors and tools can find the process
+	if *daemonMode {
+		if err := daemon.WritePIDFile(*pidFile); err != nil {
+			log.Critical(fmt.Sprintf("Failed to write PID file: %v", err))
+			log.Close()
+			os.Exit(1)
+		}
+		defer daemon.RemovePIDFile(*pidFile)
+	}
 
 	// Create and initialize the trading manager
-	tradingManager := manager.NewManager(log)
+	tradingManager := manager.NewManager(log, cfg)
+	tradingManager.SetExecutionMode(execMode)
+	tradingManager.SetStatePath(*stateFile)
+	tradingManager.SetDataset(*dataset)
 
 	// Start the trading system in the specified mode
 	switch *mode {
 	case "live":
 		fmt.Println("Starting live market data analysis...")
 		fmt.Println("Press Ctrl+C to exit")
-		tradingManager.StartLiveMode()
+		err = tradingManager.StartLiveMode()
+
+	case "paper":
+		fmt.Println("Starting paper trading on live market data...")
+		fmt.Println("Press Ctrl+C to exit")
+		err = tradingManager.StartPaperMode()
 
 	case "backtest":
 		fmt.Println("Starting backtest mode...")
-		tradingManager.StartBacktestMode()
+		err = tradingManager.StartBacktestMode()
+
+	case "sim":
+		fmt.Println("Starting simulation on synthetic market data...")
+		fmt.Println("Press Ctrl+C to exit")
+		err = tradingManager.StartSimMode()
 
 	default:
 		fmt.Printf("Unknown mode: %s\n", *mode)
 		fmt.Println("Available modes:")
 		fmt.Println("  --mode=live     # Run in live trading mode")
+		fmt.Println("  --mode=paper    # Paper trade live market data with simulated fills")
 		fmt.Println("  --mode=backtest # Run in backtest mode")
+		fmt.Println("  --mode=sim      # Run on a synthetic market simulator")
 		return
 	}
 
-	// Wait for interrupt signal
+	// Exit with a failure code so a supervisor can restart us
+	if err != nil {
+		log.Critical(fmt.Sprintf("Failed to start trading system: %v", err))
+		daemon.RemovePIDFile(*pidFile)
+		log.Close()
+		os.Exit(1)
+	}
+
+	// A backtest is done once its dataset is replayed
+	if *mode == "backtest" {
+		tradingManager.Shutdown()
+		log.Close()
+		return
+	}
+
+	// Tell systemd we are ready
+	if *daemonMode {
+		if _, err := daemon.Notify(daemon.StateReady); err != nil {
+			log.Warning(fmt.Sprintf("Failed to notify systemd: %v", err))
+		}
+	}
+
+	// Wait for signals: SIGHUP reopens the log file, SIGUSR1 dumps a state
+	// snapshot, SIGUSR2 hands off open trades and restarts, SIGINT/SIGTERM stop
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+	exitCode := 0
+	for sig := range sigChan {
+		if sig == syscall.SIGUSR1 {
+			if _, err := tradingManager.WriteSnapshot(""); err != nil {
+				log.Error(fmt.Sprintf("Failed to write snapshot: %v", err))
+			}
+			continue
+		}
+		if sig == syscall.SIGHUP {
+			daemon.Notify(daemon.StateReloading)
+			if err := log.Reopen(); err != nil {
+				log.Error(fmt.Sprintf("Failed to reopen log file: %v", err))
+			}
+			daemon.Notify(daemon.StateReady)
+			continue
+		}
+		if sig == syscall.SIGUSR2 {
+			if err := tradingManager.SaveState(); err != nil {
+				log.Error(fmt.Sprintf("Failed to save state, ignoring restart request: %v", err))
+				continue
+			}
+			exitCode = exitRestart
+		}
+		break
+	}
 
 	fmt.Println("\nShutting down gracefully...")
+	daemon.Notify(daemon.StateStopping)
 	tradingManager.Shutdown()
-}
\ No newline at end of file
+	log.Close()
+
+	if exitCode != 0 {
+		daemon.RemovePIDFile(*pidFile)
+		os.Exit(exitCode)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/scenario"
+)
+
+// runScenario injects scripted extreme markets into simulations of the
+// configured system and fails when one of them was mishandled
+func runScenario(args []string) error {
+	flags := flag.NewFlagSet("scenario", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file of the system under test; its simulator section sets the base market")
+	names := flags.String("scenario", "all", "Scenarios to run, comma separated: "+strings.Join(scenario.Names(), ", "))
+	seed := flags.Int64("seed", 0, "Seed of the base price path (0 keeps simulator.seed)")
+	asJSON := flags.Bool("json", false, "Print the results as JSON")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	scenarios, err := scenario.Select(*names)
+	if err != nil {
+		return err
+	}
+	if *seed != 0 {
+		cfg.Simulator.Seed = *seed
+	}
+
+	// The runs log to a session log of their own, the report to stdout
+	options := logger.DefaultOptions()
+	if options.Level, err = logger.ParseLevel(cfg.Logging.Level); err != nil {
+		return err
+	}
+	log := logger.NewLoggerWithOptions(options)
+	defer log.Close()
+
+	results := scenario.Run(cfg, log, scenarios)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		scenario.WriteReport(os.Stdout, results)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenario(s) failed", failed, len(results))
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 module TRADE
 
-go 1.20
+go 1.21
 
 require (
 	github.com/gorilla/websocket v1.5.0
-	github.com/montanaflynn/stats v0.7.0
-)
\ No newline at end of file
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,292 @@
+// Package account keeps the exchange accounts one process trades for: their
+// credentials, the strategies assigned to each, and a ledger of each
+// account's cash, open position and realized PnL built from its fills.
+//
+// Orders of a strategy are split across the accounts assigned to it in
+// proportion to their balances, so every account holds the same share of
+// its balance in the strategy's position. Amounts are in the quote currency
+// of the traded symbol.
+package account
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"TRADE/pkg/decimal"
+)
+
+// Credentials are the API keys of an account
+type Credentials struct {
+	APIKey    string
+	APISecret string
+}
+
+// Empty reports whether no key is set
+func (c Credentials) Empty() bool {
+	return c.APIKey == "" || c.APISecret == ""
+}
+
+// String hides the keys so credentials never reach logs
+func (c Credentials) String() string {
+	if c.Empty() {
+		return "<none>"
+	}
+	return "<redacted>"
+}
+
+// GoString hides the keys from %#v
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+// CredentialsFromEnv reads the API key and secret from environment
+// variables; empty names leave the field unset
+func CredentialsFromEnv(keyVar, secretVar string) (Credentials, error) {
+	var creds Credentials
+	for _, v := range []struct {
+		name  string
+		value *string
+	}{{keyVar, &creds.APIKey}, {secretVar, &creds.APISecret}} {
+		if v.name == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(v.name)
+		if !ok || value == "" {
+			return Credentials{}, fmt.Errorf("environment variable %s is not set", v.name)
+		}
+		*v.value = value
+	}
+	return creds, nil
+}
+
+// Account is an exchange account or subaccount trading for one or more
+// strategies
+type Account struct {
+	Name           string
+	Exchange       string
+	Subaccount     string // Empty for the main account
+	Credentials    Credentials
+	Strategies     []string        // Strategies whose orders the account takes part in
+	InitialBalance decimal.Decimal // Starting cash
+}
+
+// trades reports whether the account is assigned a strategy
+func (a *Account) trades(strategy string) bool {
+	for _, name := range a.Strategies {
+		if name == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// Balance is the ledger of one account
+type Balance struct {
+	Account     string          `json:"account"`
+	Cash        decimal.Decimal `json:"cash"`
+	Quantity    decimal.Decimal `json:"quantity"`   // Open position
+	EntryFill   decimal.Decimal `json:"entry_fill"` // Average fill price of the open position
+	RealizedPnL decimal.Decimal `json:"realized_pnl"`
+	Fees        decimal.Decimal `json:"fees"`
+	Funding     decimal.Decimal `json:"funding"` // Funding received, negative if paid
+	Fills       int             `json:"fills"`
+}
+
+// Equity returns the cash plus the open position marked at price
+func (b Balance) Equity(price decimal.Decimal) decimal.Decimal {
+	return b.Cash.Add(b.Quantity.Mul(price))
+}
+
+// Allocation is an account's share of an order
+type Allocation struct {
+	Account  string
+	Quantity decimal.Decimal
+}
+
+// Book holds the accounts and their ledgers
+type Book struct {
+	accounts []*Account
+	balances map[string]*Balance
+	mutex    sync.RWMutex
+}
+
+// NewBook creates a book of accounts, each starting with its initial
+// balance in cash. Names must be unique and every account needs a strategy.
+func NewBook(accounts []Account) (*Book, error) {
+	book := &Book{balances: make(map[string]*Balance, len(accounts))}
+	for i := range accounts {
+		account := accounts[i]
+		account.Name = strings.TrimSpace(account.Name)
+		switch {
+		case account.Name == "":
+			return nil, fmt.Errorf("account %d has no name", i+1)
+		case book.balances[account.Name] != nil:
+			return nil, fmt.Errorf("duplicate account %s", account.Name)
+		case len(account.Strategies) == 0:
+			return nil, fmt.Errorf("account %s has no strategy", account.Name)
+		case account.InitialBalance.Sign() <= 0:
+			return nil, fmt.Errorf("account %s needs a positive balance", account.Name)
+		}
+		book.accounts = append(book.accounts, &account)
+		book.balances[account.Name] = &Balance{Account: account.Name, Cash: account.InitialBalance}
+	}
+	return book, nil
+}
+
+// Accounts returns the accounts, in configuration order
+func (b *Book) Accounts() []Account {
+	accounts := make([]Account, len(b.accounts))
+	for i, account := range b.accounts {
+		accounts[i] = *account
+	}
+	return accounts
+}
+
+// Assigned returns the names of the accounts trading a strategy
+func (b *Book) Assigned(strategy string) []string {
+	var names []string
+	for _, account := range b.accounts {
+		if account.trades(strategy) {
+			names = append(names, account.Name)
+		}
+	}
+	return names
+}
+
+// Capital returns the initial balance of the accounts trading a strategy
+func (b *Book) Capital(strategy string) decimal.Decimal {
+	total := decimal.Zero
+	for _, account := range b.accounts {
+		if account.trades(strategy) {
+			total = total.Add(account.InitialBalance)
+		}
+	}
+	return total
+}
+
+// Split divides the quantity of a strategy's entry across its accounts in
+// proportion to their cash. round rounds each share to a tradable quantity;
+// accounts whose share rounds to zero are left out.
+func (b *Book) Split(strategy string, quantity decimal.Decimal, round func(decimal.Decimal) decimal.Decimal) []Allocation {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	total := decimal.Zero
+	for _, account := range b.accounts {
+		if account.trades(strategy) {
+			if cash := b.balances[account.Name].Cash; cash.Sign() > 0 {
+				total = total.Add(cash)
+			}
+		}
+	}
+	if total.Sign() <= 0 {
+		return nil
+	}
+
+	var allocations []Allocation
+	for _, account := range b.accounts {
+		cash := b.balances[account.Name].Cash
+		if !account.trades(strategy) || cash.Sign() <= 0 {
+			continue
+		}
+		share := round(quantity.Mul(cash).Div(total))
+		if share.Sign() > 0 {
+			allocations = append(allocations, Allocation{Account: account.Name, Quantity: share})
+		}
+	}
+	return allocations
+}
+
+// Holdings returns the open position of every account that has one
+func (b *Book) Holdings() []Allocation {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var holdings []Allocation
+	for _, account := range b.accounts {
+		if quantity := b.balances[account.Name].Quantity; quantity.Sign() > 0 {
+			holdings = append(holdings, Allocation{Account: account.Name, Quantity: quantity})
+		}
+	}
+	return holdings
+}
+
+// Fill books a fill of an account's order
+func (b *Book) Fill(name, side string, price, quantity, fee decimal.Decimal) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	balance, ok := b.balances[name]
+	if !ok {
+		return fmt.Errorf("unknown account %s", name)
+	}
+	notional := price.Mul(quantity)
+	switch side {
+	case "buy":
+		held := balance.Quantity.Add(quantity)
+		balance.EntryFill = balance.EntryFill.Mul(balance.Quantity).Add(notional).Div(held)
+		balance.Quantity = held
+		balance.Cash = balance.Cash.Sub(notional)
+	case "sell":
+		if quantity.Cmp(balance.Quantity) > 0 {
+			return fmt.Errorf("account %s sells %s but holds %s", name, quantity, balance.Quantity)
+		}
+		balance.RealizedPnL = balance.RealizedPnL.Add(price.Sub(balance.EntryFill).Mul(quantity))
+		balance.Quantity = balance.Quantity.Sub(quantity)
+		balance.Cash = balance.Cash.Add(notional)
+		if balance.Quantity.Sign() == 0 {
+			balance.EntryFill = decimal.Zero
+		}
+	default:
+		return fmt.Errorf("unknown side %q", side)
+	}
+	balance.Cash = balance.Cash.Sub(fee)
+	balance.Fees = balance.Fees.Add(fee)
+	balance.Fills++
+	return nil
+}
+
+// Funding books a funding payment of an account's position; amount is
+// negative if the account paid
+func (b *Book) Funding(name string, amount decimal.Decimal) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	balance, ok := b.balances[name]
+	if !ok {
+		return fmt.Errorf("unknown account %s", name)
+	}
+	balance.Cash = balance.Cash.Add(amount)
+	balance.Funding = balance.Funding.Add(amount)
+	return nil
+}
+
+// Balances returns the ledgers of all accounts, sorted by name
+func (b *Book) Balances() []Balance {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	balances := make([]Balance, 0, len(b.balances))
+	for _, balance := range b.balances {
+		balances = append(balances, *balance)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Account < balances[j].Account })
+	return balances
+}
+
+// Restore replaces the ledgers of the named accounts, e.g. with balances
+// handed off by a previous process; unknown accounts are ignored
+func (b *Book) Restore(balances []Balance) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, balance := range balances {
+		if _, ok := b.balances[balance.Account]; ok {
+			restored := balance
+			b.balances[balance.Account] = &restored
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the latency histogram buckets
+var latencyBounds = []time.Duration{
+	10 * time.Microsecond, 25 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, time.Second,
+}
+
+// Histogram counts latencies in fixed buckets. Observe is lock-free, so it
+// can be called on the hot path of every tick.
+type Histogram struct {
+	counts []int64 // One per bound plus an overflow bucket
+	count  int64
+	sum    int64 // Nanoseconds
+	max    int64 // Nanoseconds
+}
+
+// NewHistogram creates an empty latency histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, len(latencyBounds)+1)}
+}
+
+// Observe records one latency
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&h.max, max, int64(d)) {
+			break
+		}
+	}
+}
+
+// Bucket is the number of observations up to a bound
+type Bucket struct {
+	LE    string `json:"le"` // Upper bound, "+Inf" for the overflow bucket
+	Count int64  `json:"count"`
+}
+
+// HistogramSnapshot is a point-in-time view of a histogram. Percentiles
+// are the upper bounds of the buckets they fall in.
+type HistogramSnapshot struct {
+	Count   int64    `json:"count"`
+	Mean    string   `json:"mean"`
+	Max     string   `json:"max"`
+	P50     string   `json:"p50"`
+	P90     string   `json:"p90"`
+	P99     string   `json:"p99"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Snapshot returns the current state of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	counts := make([]int64, len(h.counts))
+	var total int64
+	for i := range h.counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+		total += counts[i]
+	}
+
+	snapshot := HistogramSnapshot{
+		Count: total,
+		Max:   time.Duration(atomic.LoadInt64(&h.max)).String(),
+		P50:   percentile(counts, total, 0.50),
+		P90:   percentile(counts, total, 0.90),
+		P99:   percentile(counts, total, 0.99),
+	}
+	if count := atomic.LoadInt64(&h.count); count > 0 {
+		snapshot.Mean = (time.Duration(atomic.LoadInt64(&h.sum)) / time.Duration(count)).String()
+	}
+	for i, n := range counts {
+		snapshot.Buckets = append(snapshot.Buckets, Bucket{LE: bound(i), Count: n})
+	}
+	return snapshot
+}
+
+// percentile returns the bound of the bucket holding the q-th observation
+func percentile(counts []int64, total int64, q float64) string {
+	if total == 0 {
+		return ""
+	}
+	rank := int64(q*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return bound(i)
+		}
+	}
+	return bound(len(counts) - 1)
+}
+
+// bound names the upper bound of bucket i
+func bound(i int) string {
+	if i >= len(latencyBounds) {
+		return "+Inf"
+	}
+	return latencyBounds[i].String()
+}
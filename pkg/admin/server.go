@@ -0,0 +1,216 @@
+// Package admin serves runtime diagnostics over HTTP: Go runtime and heap
+// statistics, latency histograms of the trading pipeline and, when enabled,
+// the net/http/pprof profiles, so a live session can be profiled without
+// redeploying.
+//
+// The server has no authentication; it should listen on a loopback or
+// otherwise private address.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/version"
+)
+
+// Options configures the admin server
+type Options struct {
+	Address string // Listen address, e.g. "127.0.0.1:6060"
+	Pprof   bool   // Serve the pprof profiles under /debug/pprof/
+}
+
+// Server serves the diagnostics endpoints
+type Server struct {
+	options    Options
+	logger     logger.Interface
+	server     *http.Server
+	listener   net.Listener
+	started    time.Time
+	histograms map[string]*Histogram
+	stats      map[string]func() interface{}
+	mutex      sync.RWMutex
+	stopOnce   sync.Once
+}
+
+// NewServer creates an admin server
+func NewServer(options Options, log logger.Interface) *Server {
+	s := &Server{
+		options:    options,
+		logger:     log,
+		started:    time.Now(),
+		histograms: make(map[string]*Histogram),
+		stats:      make(map[string]func() interface{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/runtime", s.handleRuntime)
+	if options.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	// Profiles and traces stream for as long as requested, so there is no
+	// write timeout
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// AddHistogram reports a latency histogram under the given name
+func (s *Server) AddHistogram(name string, histogram *Histogram) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.histograms[name] = histogram
+}
+
+// AddStats reports the value returned by collect under the given name;
+// collect is called on every request and must be safe for concurrent use
+func (s *Server) AddStats(name string, collect func() interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stats[name] = collect
+}
+
+// Start listens on the configured address and serves in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.options.Address, err)
+	}
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error(fmt.Sprintf("Admin server stopped: %v", err))
+		}
+	}()
+	s.logger.Info(fmt.Sprintf("Admin diagnostics listening on http://%s/debug/runtime", listener.Addr()),
+		"pprof", s.options.Pprof)
+	return nil
+}
+
+// Addr returns the listen address, or nil before Start
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop shuts the server down
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	})
+}
+
+// RuntimeStats is the document served on /debug/runtime
+type RuntimeStats struct {
+	Version    string                       `json:"version"`
+	GoVersion  string                       `json:"go_version"`
+	Uptime     string                       `json:"uptime"`
+	Goroutines int                          `json:"goroutines"`
+	GOMAXPROCS int                          `json:"gomaxprocs"`
+	NumCPU     int                          `json:"num_cpu"`
+	Heap       HeapStats                    `json:"heap"`
+	GC         GCStats                      `json:"gc"`
+	Latency    map[string]HistogramSnapshot `json:"latency"`
+	Components map[string]interface{}       `json:"components,omitempty"`
+}
+
+// HeapStats describes the Go heap, in bytes and objects
+type HeapStats struct {
+	Alloc        uint64 `json:"alloc_bytes"`
+	InUse        uint64 `json:"inuse_bytes"`
+	Idle         uint64 `json:"idle_bytes"`
+	Sys          uint64 `json:"sys_bytes"`
+	Objects      uint64 `json:"objects"`
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+	NextGCTarget uint64 `json:"next_gc_bytes"`
+}
+
+// GCStats describes garbage collection
+type GCStats struct {
+	Cycles     uint32  `json:"cycles"`
+	PauseTotal string  `json:"pause_total"`
+	LastPause  string  `json:"last_pause"`
+	LastGC     string  `json:"last_gc,omitempty"`
+	CPUPercent float64 `json:"cpu_percent"`
+}
+
+// Stats collects the current runtime statistics
+func (s *Server) Stats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		Version:    version.Version,
+		GoVersion:  runtime.Version(),
+		Uptime:     time.Since(s.started).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Heap: HeapStats{
+			Alloc:        mem.HeapAlloc,
+			InUse:        mem.HeapInuse,
+			Idle:         mem.HeapIdle,
+			Sys:          mem.HeapSys,
+			Objects:      mem.HeapObjects,
+			TotalAlloc:   mem.TotalAlloc,
+			Mallocs:      mem.Mallocs,
+			Frees:        mem.Frees,
+			NextGCTarget: mem.NextGC,
+		},
+		GC: GCStats{
+			Cycles:     mem.NumGC,
+			PauseTotal: time.Duration(mem.PauseTotalNs).String(),
+			LastPause:  time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String(),
+			CPUPercent: mem.GCCPUFraction * 100,
+		},
+		Latency: make(map[string]HistogramSnapshot),
+	}
+	if mem.LastGC > 0 {
+		stats.GC.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339Nano)
+	}
+
+	s.mutex.RLock()
+	for name, histogram := range s.histograms {
+		stats.Latency[name] = histogram.Snapshot()
+	}
+	if len(s.stats) > 0 {
+		stats.Components = make(map[string]interface{}, len(s.stats))
+		for name, collect := range s.stats {
+			stats.Components[name] = collect()
+		}
+	}
+	s.mutex.RUnlock()
+	return stats
+}
+
+// handleRuntime serves the runtime statistics as JSON
+func (s *Server) handleRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s.Stats()); err != nil {
+		s.logger.Debug(fmt.Sprintf("Failed to write runtime stats: %v", err))
+	}
+}
@@ -1,37 +1,124 @@
 package analyzer
 
 import (
+	"fmt"
 	"math"
 	"sync"
 	"time"
 
-	"github.com/montanaflynn/stats"
 	"TRADE/pkg/logger"
 	"TRADE/pkg/market"
+	"TRADE/pkg/rolling"
 	"TRADE/pkg/types"
 )
 
+// minimumTicks is the number of ticks metrics are first calculated at
+const minimumTicks = 20
+
+// minutesPerYear annualizes realized volatility unless set otherwise: the
+// tick returns are taken as minute returns of a market trading around the
+// clock every day
+const minutesPerYear = 365 * 1440
+
+// Windows sets the lengths of the rolling windows metrics are measured over
+type Windows struct {
+	RelativeStrength int // Recent returns relative strength is measured over
+	Trend            int // Recent prices trend strength and the market efficiency ratio are measured over
+	ATR              int // Recent true ranges the tick ATR averages
+}
+
+// DefaultWindows returns the windows of a new analyzer
+func DefaultWindows() Windows {
+	return Windows{RelativeStrength: 500, Trend: 30, ATR: 14}
+}
+
 // Analyzer calculates and analyzes market metrics
 type Analyzer struct {
-	market          *market.MarketData
-	logger          *logger.Logger
-	metrics         *types.MarketMetrics
-	trendStrengthWindow []float64
-	warmupTicks     int
-	warmupComplete  bool
-	mutex           sync.RWMutex
+	market              *market.MarketData
+	logger              logger.Interface
+	metrics             *types.MarketMetrics
+	trendStrengthWindow *rolling.Stats
+	returns             *rolling.Stats       // Tick returns over the price history
+	recentReturns       *rolling.Stats       // Returns of the relative strength window
+	recentMoves         *rolling.Stats       // Absolute returns of the same window
+	trend               *rolling.Regression  // Regression of the trend window's prices
+	path                *rolling.Stats       // Absolute price moves of the trend window
+	trueRanges          *rolling.Stats       // True ranges of the ATR period
+	seen                int64                // Ticks of the market data the returns include
+	bookWindow          time.Duration        // Window the book imbalance trend is measured over
+	divergence          *DivergenceDetector  // Detects divergences when set
+	candles             *candleIndicators    // ATR and RSI on candles when set
+	indicators          *indicatorSet        // RSI, MACD, Bollinger Bands and crossover when set
+	indicatorTicks      bool                 // The indicators are computed on the ticks, not candles
+	timeframes          []*timeframeAnalysis // Candle timeframes analyzed, shortest first
+	patterns            *patternAnalysis     // Candlestick patterns detected when set
+	windows             Windows
+	periodsPerYear      float64 // Tick returns in a year, annualizing realized volatility
+	warmupTicks         int
+	warmupComplete      bool
+	lastUpdate          time.Time // Wall-clock time metrics were last calculated
+	mutex               sync.RWMutex
 }
 
 // NewAnalyzer creates a new market analyzer
-func NewAnalyzer(marketData *market.MarketData, log *logger.Logger) *Analyzer {
-	return &Analyzer{
-		market:          marketData,
-		logger:          log,
-		metrics:         types.NewMarketMetrics(),
-		trendStrengthWindow: make([]float64, 0, 20),
-		warmupTicks:     300, // Default warmup period
-		warmupComplete:  false,
+func NewAnalyzer(marketData *market.MarketData, log logger.Interface) *Analyzer {
+	a := &Analyzer{
+		market:              marketData,
+		logger:              log,
+		metrics:             types.NewMarketMetrics(),
+		trendStrengthWindow: rolling.NewStats(20),
+		returns:             rolling.NewStats(market.HistorySize - 1),
+		bookWindow:          5 * time.Second,
+		periodsPerYear:      minutesPerYear,
+		warmupTicks:         300, // Default warmup period
+		warmupComplete:      false,
 	}
+	a.sizeWindows(DefaultWindows())
+	return a
+}
+
+// sizeWindows creates the rolling windows of the given lengths
+func (a *Analyzer) sizeWindows(windows Windows) {
+	a.windows = windows
+	a.recentReturns = rolling.NewStats(windows.RelativeStrength)
+	a.recentMoves = rolling.NewStats(windows.RelativeStrength)
+	a.trend = rolling.NewRegression(windows.Trend)
+	a.path = rolling.NewStats(windows.Trend - 1)
+	a.trueRanges = rolling.NewStats(windows.ATR)
+}
+
+// SetWindows sets the lengths of the rolling windows. It must be called
+// before the first tick is processed.
+func (a *Analyzer) SetWindows(windows Windows) error {
+	switch {
+	case windows.RelativeStrength < 2:
+		return fmt.Errorf("relative strength window must be at least 2 returns, got %d", windows.RelativeStrength)
+	case windows.Trend < 3:
+		return fmt.Errorf("trend window must be at least 3 prices, got %d", windows.Trend)
+	case windows.ATR < 1:
+		return fmt.Errorf("ATR window must be at least 1 true range, got %d", windows.ATR)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.sizeWindows(windows)
+	return nil
+}
+
+// Windows returns the lengths of the rolling windows
+func (a *Analyzer) Windows() Windows {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.windows
+}
+
+// SetAnnualization sets the number of tick returns in a year realized
+// volatility is annualized over: the minutes a market trades in a year,
+// the tick returns being taken as minute returns
+func (a *Analyzer) SetAnnualization(periodsPerYear float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.periodsPerYear = periodsPerYear
 }
 
 // SetWarmupTicks sets the number of ticks required before analysis starts
@@ -41,104 +128,153 @@ func (a *Analyzer) SetWarmupTicks(ticks int) {
 	a.warmupTicks = ticks
 }
 
+// SetBookImbalanceWindow sets the window the trend of the book imbalance
+// is measured over
+func (a *Analyzer) SetBookImbalanceWindow(window time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.bookWindow = window
+}
+
+// SetDivergence enables divergence detection over the last lookback
+// ticks, with the previous high or low at least separation ticks back
+func (a *Analyzer) SetDivergence(lookback, separation int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.divergence = NewDivergenceDetector(lookback, separation)
+}
+
+// SetCandleIndicators computes ATR and RSI on the closed candles of a
+// timeframe the market data builds, smoothed over period candles, instead
+// of ATR on the ticks. ATR stays on the ticks until period candles closed.
+func (a *Analyzer) SetCandleIndicators(timeframe time.Duration, period int) error {
+	builder := a.market.CandleBuilder()
+	if builder == nil {
+		return fmt.Errorf("no candles are built")
+	}
+	if _, err := builder.Candles(timeframe, 1); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	indicators := newCandleIndicators(period)
+	a.candles = indicators
+	a.mutex.Unlock()
+
+	builder.OnClose(func(closed time.Duration, candle market.Candle) {
+		if closed != timeframe {
+			return
+		}
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		indicators.add(candle)
+	})
+	return nil
+}
+
 // HasSufficientData checks if we have enough data for analysis
 func (a *Analyzer) HasSufficientData() bool {
 	return a.warmupComplete
 }
 
-// ProcessTick processes a new market tick and updates metrics
+// ProcessTick processes a new market tick and updates metrics. It runs
+// on every tick, so it takes each lock once, only allocates the copy of
+// the metrics it returns, and updates each metric in O(1) from the prices
+// added instead of recomputing it over its window.
 func (a *Analyzer) ProcessTick(tick *types.TickData) *types.MarketMetrics {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Compute over the market buffers in place instead of copying them
+	ticks := 0
+	a.market.ReadSeries(func(series market.Series) {
+		ticks = series.Prices.Len()
+		if ticks >= minimumTicks {
+			added := series.Count != a.seen
+			a.updateMetrics(&series)
+			if added && tick != nil {
+				a.updateDivergence(series.Prices.Last(), tick)
+			}
+		}
+	})
+
 	// Check if we have minimum data for analysis
-	if !a.market.HasMinimumData(20) {
+	if ticks < minimumTicks {
 		return nil
 	}
-	
-	// Calculate metrics
-	a.calculateMetrics()
-	
+
+	// The book metrics come from the depth stream, not the tick buffers
+	a.metrics.BookImbalance, a.metrics.BookImbalanceTrend, _ = a.market.BookImbalance(a.bookWindow)
+	book, _ := a.market.BookStats()
+	a.metrics.SpreadBps, a.metrics.TopImbalance, a.metrics.WeightedMid = book.SpreadBps, book.TopImbalance, book.WeightedMid
+
 	// Check if warmup is complete
-	if !a.warmupComplete && a.market.HasMinimumData(a.warmupTicks) {
-		a.mutex.Lock()
+	if !a.warmupComplete && ticks >= a.warmupTicks {
 		a.warmupComplete = true
-		a.mutex.Unlock()
 		a.logger.Info("Warmup phase completed")
 	}
-	
+
 	// Return a copy of the metrics
-	return a.GetMetrics()
+	metricsCopy := *a.metrics
+	return &metricsCopy
 }
 
 // GetMetrics returns a copy of the current metrics
 func (a *Analyzer) GetMetrics() *types.MarketMetrics {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
-	
-	// Create a copy of the metrics
-	metricsCopy := &types.MarketMetrics{
-		RealizedVolatility:   a.metrics.RealizedVolatility,
-		ATR:                  a.metrics.ATR,
-		RelativeStrength:     a.metrics.RelativeStrength,
-		OrderImbalance:       a.metrics.OrderImbalance,
-		TrendStrength:        a.metrics.TrendStrength,
-		AvgTrendStrength:     a.metrics.AvgTrendStrength,
-		MarketEfficiencyRatio: a.metrics.MarketEfficiencyRatio,
-	}
-	
-	return metricsCopy
+
+	metricsCopy := *a.metrics
+	return &metricsCopy
 }
 
-// calculateMetrics calculates all market metrics
-func (a *Analyzer) calculateMetrics() {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-	
-	// Get price and volume data
-	prices := a.market.GetPriceArray()
-	if len(prices) < 2 {
+// updateMetrics calculates the metrics from the market series
+func (a *Analyzer) updateMetrics(series *market.Series) {
+	prices := series.Prices
+	if prices.Len() < 2 {
 		return
 	}
-	
-	// Calculate returns
-	returns := make([]float64, len(prices)-1)
-	for i := 1; i < len(prices); i++ {
-		returns[i-1] = (prices[i] / prices[i-1]) - 1
-	}
-	
+
+	// Bring the rolling windows up to date with the new prices
+	a.updateReturns(series)
+
 	// Calculate realized volatility
-	stdDev, _ := stats.StandardDeviation(returns)
-	realizedVolatility := stdDev * math.Sqrt(252*1440) * 100
-	
-	// Calculate ATR (Average True Range)
-	atr := a.calculateATR(prices)
-	
+	stdDev := math.Sqrt(a.returns.Variance())
+	realizedVolatility := stdDev * math.Sqrt(a.periodsPerYear) * 100
+
+	// Calculate ATR (Average True Range), on candles once enough closed
+	atr := a.calculateATR(series)
+	if a.candles != nil && a.candles.ready() {
+		atr = a.candles.ATR()
+		if a.indicators == nil {
+			a.metrics.RSI = a.candles.RSI()
+		}
+	}
+	if a.indicatorTicks {
+		a.indicators.report(a.metrics)
+	}
+
 	// Calculate relative strength
-	relativeStrength := a.calculateRelativeStrength(returns)
-	
+	relativeStrength := a.calculateRelativeStrength()
+
 	// Calculate order imbalance
-	orderImbalance := a.calculateOrderImbalance()
-	
+	orderImbalance := a.calculateOrderImbalance(series)
+
 	// Calculate trend strength
-	trendStrength := a.calculateTrendStrength(prices)
-	
+	trendStrength := a.calculateTrendStrength()
+
 	// Update trend strength window
-	if len(a.trendStrengthWindow) >= 20 {
-		a.trendStrengthWindow = a.trendStrengthWindow[1:]
-	}
-	a.trendStrengthWindow = append(a.trendStrengthWindow, trendStrength)
-	
+	a.trendStrengthWindow.Push(trendStrength)
+
 	// Calculate average trend strength
 	avgTrendStrength := 0.0
-	if len(a.trendStrengthWindow) >= 7 {
-		sum := 0.0
-		for _, v := range a.trendStrengthWindow {
-			sum += v
-		}
-		avgTrendStrength = sum / float64(len(a.trendStrengthWindow))
+	if a.trendStrengthWindow.Len() >= 7 {
+		avgTrendStrength = a.trendStrengthWindow.Mean()
 	}
-	
+
 	// Calculate market efficiency ratio
 	mer := a.calculateMarketEfficiencyRatio(prices)
-	
+
 	// Update metrics
 	a.metrics.RealizedVolatility = realizedVolatility
 	a.metrics.ATR = atr
@@ -147,181 +283,214 @@ func (a *Analyzer) calculateMetrics() {
 	a.metrics.TrendStrength = trendStrength
 	a.metrics.AvgTrendStrength = avgTrendStrength
 	a.metrics.MarketEfficiencyRatio = mer
+	a.lastUpdate = time.Now()
+}
+
+// updateDivergence adds the tick to the divergence detector, signing its
+// volume by the side that initiated it
+func (a *Analyzer) updateDivergence(price float64, tick *types.TickData) {
+	if a.divergence == nil {
+		return
+	}
+	volume := tick.Volume
+	if tick.IsAsk {
+		volume = -volume
+	}
+	a.metrics.StrengthDivergence, a.metrics.DeltaDivergence = a.divergence.Push(price, a.metrics.RelativeStrength, volume)
 }
 
-// calculateATR calculates the Average True Range
-func (a *Analyzer) calculateATR(prices []float64) float64 {
-	highPrices := a.market.GetHighPricesArray()
-	lowPrices := a.market.GetLowPricesArray()
-	
-	if len(highPrices) < 14 || len(lowPrices) < 14 || len(prices) < 14 {
+// LastUpdate returns the wall-clock time metrics were last calculated
+func (a *Analyzer) LastUpdate() time.Time {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.lastUpdate
+}
+
+// calculateATR calculates the Average True Range of the last ticks of the
+// ATR window
+func (a *Analyzer) calculateATR(series *market.Series) float64 {
+	prices := series.Prices
+	if a.trueRanges.Len() < a.windows.ATR {
 		// Not enough data, use volatility as a proxy
-		if len(prices) > 0 {
-			return a.metrics.RealizedVolatility * prices[len(prices)-1] / 100
+		if prices.Len() > 0 {
+			return a.metrics.RealizedVolatility * prices.Last() / 100
 		}
 		return 0
 	}
-	
-	// Use the last 14 periods for ATR calculation
-	period := 14
-	highPrices = highPrices[len(highPrices)-period:]
-	lowPrices = lowPrices[len(lowPrices)-period:]
-	closes := prices[len(prices)-period-1 : len(prices)-1]
-	
-	// Calculate true ranges
-	trueRanges := make([]float64, period)
-	for i := 0; i < period; i++ {
+	return a.trueRanges.Mean()
+}
+
+// updateReturns pushes the returns, moves and true ranges of the prices
+// added since the last call, so each tick costs one of each instead of
+// the whole history. After a reset, or more new prices than the history
+// holds, they are rebuilt.
+func (a *Analyzer) updateReturns(series *market.Series) {
+	prices := series.Prices
+	added := series.Count - a.seen
+	from := prices.Len() - int(added)
+	if added < 0 || from < 1 {
+		if a.divergence != nil && added < 0 {
+			a.divergence.Reset()
+		}
+		if a.candles != nil && added < 0 {
+			a.candles.reset()
+			a.metrics.RSI = neutralRSI
+		}
+		if a.indicators != nil && added < 0 {
+			a.indicators.reset()
+			a.indicators.report(a.metrics)
+		}
+		if added < 0 {
+			a.resetTimeframes()
+			a.resetPatterns()
+		}
+		a.returns.Reset()
+		a.recentReturns.Reset()
+		a.recentMoves.Reset()
+		a.trend.Reset()
+		a.path.Reset()
+		a.trueRanges.Reset()
+		a.trend.Push(prices.At(0))
+		if a.indicatorTicks {
+			a.indicators.push(prices.At(0))
+		}
+		from = 1
+	}
+	for i := from; i < prices.Len(); i++ {
+		price, previous := prices.At(i), prices.At(i-1)
+		ret := (price / previous) - 1
+		a.returns.Push(ret)
+		a.recentReturns.Push(ret)
+		a.recentMoves.Push(math.Abs(ret))
+		a.trend.Push(price)
+		a.path.Push(math.Abs(price - previous))
+		if a.indicatorTicks {
+			a.indicators.push(price)
+		}
+
 		// True Range is the greatest of:
 		// 1. Current High - Current Low
 		// 2. |Current High - Previous Close|
 		// 3. |Current Low - Previous Close|
-		tr1 := highPrices[i] - lowPrices[i]
-		tr2 := math.Abs(highPrices[i] - closes[i])
-		tr3 := math.Abs(lowPrices[i] - closes[i])
-		
-		trueRanges[i] = math.Max(tr1, math.Max(tr2, tr3))
+		high, low := series.HighPrices.At(i), series.LowPrices.At(i)
+		a.trueRanges.Push(math.Max(high-low, math.Max(math.Abs(high-previous), math.Abs(low-previous))))
 	}
-	
-	// Calculate average
-	sum := 0.0
-	for _, tr := range trueRanges {
-		sum += tr
-	}
-	
-	return sum / float64(period)
+	a.seen = series.Count
 }
 
-// calculateRelativeStrength calculates the Relative Strength
-func (a *Analyzer) calculateRelativeStrength(returns []float64) float64 {
-	if len(returns) < 2 {
+// calculateRelativeStrength calculates the Relative Strength: the share of
+// gains in the price moves of the relative strength window
+func (a *Analyzer) calculateRelativeStrength() float64 {
+	if a.returns.Len() < 2 {
 		return 0.5
 	}
-	
-	// Use up to 500 most recent returns
-	window := int(math.Min(500, float64(len(returns))))
-	windowReturns := returns[len(returns)-window:]
-	
-	// Calculate gains and losses
-	gains := 0.0
-	losses := 0.0
-	
-	for _, ret := range windowReturns {
-		if ret > 0 {
-			gains += ret
-		} else {
-			losses -= ret
-		}
-	}
-	
-	// Calculate RS
-	if gains+losses == 0 {
+
+	// Gains and losses from the sums of the returns and of their sizes
+	moves := a.recentMoves.Sum()
+	if moves <= 0 {
 		return 0.5
 	}
-	
-	return gains / (gains + losses)
+	gains := (moves + a.recentReturns.Sum()) / 2
+
+	return math.Max(0, math.Min(1, gains/moves))
 }
 
 // calculateOrderImbalance calculates the order imbalance
-func (a *Analyzer) calculateOrderImbalance() float64 {
-	bidVolume := a.market.GetBidVolumeArray()
-	askVolume := a.market.GetAskVolumeArray()
-	
-	totalBidVol := 0.0
-	for _, vol := range bidVolume {
-		totalBidVol += vol
-	}
-	
-	totalAskVol := 0.0
-	for _, vol := range askVolume {
-		totalAskVol += vol
-	}
-	
+func (a *Analyzer) calculateOrderImbalance(series *market.Series) float64 {
+	totalBidVol := series.BidVolume
+	totalAskVol := series.AskVolume
+
 	if totalBidVol+totalAskVol == 0 {
 		return 0.5
 	}
-	
+
 	return totalBidVol / (totalBidVol + totalAskVol)
 }
 
-// calculateTrendStrength calculates the trend strength using linear regression
-func (a *Analyzer) calculateTrendStrength(prices []float64) float64 {
-	if len(prices) < 30 {
+// calculateTrendStrength calculates the trend strength using linear
+// regression over the prices of the trend window
+func (a *Analyzer) calculateTrendStrength() float64 {
+	if a.trend.Len() < a.windows.Trend {
 		return 0.0
 	}
-	
-	// Use last 30 prices for trend calculation
-	windowPrices := prices[len(prices)-30:]
-	x := make([]float64, len(windowPrices))
-	for i := range x {
-		x[i] = float64(i)
-	}
-	
-	// Calculate linear regression
-	slope, intercept, r := linearRegression(x, windowPrices)
-	
+
+	// Linear regression against the tick index
+	slope, _, r := a.trend.Fit()
+
 	// Scale slope by r-squared and price level
-	meanPrice, _ := stats.Mean(windowPrices)
-	trendStrength := slope * r * r * (30 / meanPrice) * 100000
-	
+	meanPrice := a.trend.Mean()
+	trendStrength := slope * r * r * (float64(a.windows.Trend) / meanPrice) * 100000
+
 	return trendStrength
 }
 
 // calculateMarketEfficiencyRatio calculates the Market Efficiency Ratio
-func (a *Analyzer) calculateMarketEfficiencyRatio(prices []float64) float64 {
-	if len(prices) < 30 {
+// over the prices of the trend window
+func (a *Analyzer) calculateMarketEfficiencyRatio(prices rolling.View[float64]) float64 {
+	n := prices.Len()
+	trendWindow := a.windows.Trend
+	if n < trendWindow || a.path.Len() < trendWindow-1 {
 		return 0.5
 	}
-	
-	// Net directional movement
-	netMovement := math.Abs(prices[len(prices)-1] - prices[len(prices)-30])
-	
-	// Total price path length
-	pathLength := 0.0
-	for i := len(prices) - 29; i < len(prices); i++ {
-		pathLength += math.Abs(prices[i] - prices[i-1])
-	}
-	
-	// Calculate MER
-	if pathLength == 0 {
+
+	// Net directional movement over the total price path length; the
+	// largest move is exact where the rolling sum can keep rounding residue
+	if a.path.Max() == 0 {
 		return 0.5
 	}
-	
-	return netMovement / pathLength
+	netMovement := math.Abs(prices.At(n-1) - prices.At(n-trendWindow))
+
+	return netMovement / a.path.Sum()
 }
 
-// linearRegression calculates linear regression parameters
-func linearRegression(x, y []float64) (slope, intercept, r float64) {
-	n := float64(len(x))
-	
-	if n != float64(len(y)) || n < 2 {
+// sum adds up the values of a view
+func sum(values rolling.View[float64]) float64 {
+	total := 0.0
+	head, tail := values.Segments()
+	for _, value := range head {
+		total += value
+	}
+	for _, value := range tail {
+		total += value
+	}
+	return total
+}
+
+// linearRegression calculates linear regression parameters of y against
+// its index 0, 1, ..., n-1
+func linearRegression(y rolling.View[float64]) (slope, intercept, r float64) {
+	n := float64(y.Len())
+
+	if n < 2 {
 		return 0, 0, 0
 	}
-	
+
 	sumX, sumY := 0.0, 0.0
 	sumXY, sumXX := 0.0, 0.0
 	sumYY := 0.0
-	
-	for i := 0; i < len(x); i++ {
-		sumX += x[i]
-		sumY += y[i]
-		sumXY += x[i] * y[i]
-		sumXX += x[i] * x[i]
-		sumYY += y[i] * y[i]
+
+	for i := 0; i < y.Len(); i++ {
+		x, yi := float64(i), y.At(i)
+		sumX += x
+		sumY += yi
+		sumXY += x * yi
+		sumXX += x * x
+		sumYY += yi * yi
 	}
-	
+
 	// Calculate slope and intercept
 	slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
 	intercept = (sumY - slope*sumX) / n
-	
+
 	// Calculate correlation coefficient
 	numerator := n*sumXY - sumX*sumY
 	denominator := math.Sqrt((n*sumXX - sumX*sumX) * (n*sumYY - sumY*sumY))
-	
+
 	if denominator == 0 {
 		r = 0
 	} else {
 		r = numerator / denominator
 	}
-	
+
 	return slope, intercept, r
-}
\ No newline at end of file
+}
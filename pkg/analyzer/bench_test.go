@@ -0,0 +1,21 @@
+package analyzer_test
+
+import (
+	"testing"
+
+	"TRADE/pkg/bench"
+	"TRADE/pkg/events"
+)
+
+// BenchmarkProcessTick measures calculating the metrics of a tick over the
+// full history
+func BenchmarkProcessTick(b *testing.B) {
+	ticks := bench.Ticks(2*bench.HistorySize, 1)
+	a := bench.NewAnalyzer(bench.NewMarket(events.NewBus(), ticks[:bench.HistorySize]))
+	replay := ticks[bench.HistorySize:]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.ProcessTick(&replay[i%len(replay)])
+	}
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"math"
+
+	"TRADE/pkg/market"
+)
+
+// DefaultIndicatorPeriod is the number of candles ATR and RSI are
+// smoothed over unless configured otherwise
+const DefaultIndicatorPeriod = 14
+
+// neutralRSI is the RSI reported until it is computed
+const neutralRSI = 50
+
+// candleIndicators computes ATR and RSI on closed candles with Wilder's
+// smoothing: the first value averages period candles, each later one
+// moves 1/period of the way to the new candle's value
+type candleIndicators struct {
+	period    int
+	count     int // Candles added
+	prevClose float64
+	atr       float64
+	avgGain   float64
+	avgLoss   float64
+}
+
+// newCandleIndicators creates indicators over period candles
+func newCandleIndicators(period int) *candleIndicators {
+	if period <= 0 {
+		period = DefaultIndicatorPeriod
+	}
+	return &candleIndicators{period: period}
+}
+
+// add adds a closed candle
+func (c *candleIndicators) add(candle market.Candle) {
+	c.count++
+	if c.count == 1 {
+		// The first candle has no previous close to measure against
+		c.prevClose = candle.Close
+		return
+	}
+
+	trueRange := math.Max(candle.High-candle.Low,
+		math.Max(math.Abs(candle.High-c.prevClose), math.Abs(candle.Low-c.prevClose)))
+	change := candle.Close - c.prevClose
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+	c.prevClose = candle.Close
+
+	// Average the first period changes, then smooth
+	n := float64(c.period)
+	if samples := c.count - 1; samples <= c.period {
+		n = float64(samples)
+	}
+	c.atr += (trueRange - c.atr) / n
+	c.avgGain += (gain - c.avgGain) / n
+	c.avgLoss += (loss - c.avgLoss) / n
+}
+
+// ready returns whether period candle changes were averaged
+func (c *candleIndicators) ready() bool {
+	return c.count > c.period
+}
+
+// ATR returns the average true range of the candles
+func (c *candleIndicators) ATR() float64 {
+	return c.atr
+}
+
+// RSI returns the relative strength index of the candles, 0 to 100
+func (c *candleIndicators) RSI() float64 {
+	if c.avgLoss == 0 {
+		if c.avgGain == 0 {
+			return neutralRSI
+		}
+		return 100
+	}
+	return 100 - 100/(1+c.avgGain/c.avgLoss)
+}
+
+// reset drops the candles added
+func (c *candleIndicators) reset() {
+	*c = candleIndicators{period: c.period}
+}
@@ -0,0 +1,155 @@
+package analyzer
+
+import (
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// divergenceSample is the price and the oscillators at a tick
+type divergenceSample struct {
+	price    float64
+	strength float64 // Relative strength
+	delta    float64 // Cumulative volume delta
+}
+
+// DivergenceDetector finds the price making a new high over its lookback
+// while an oscillator makes a lower high than at the previous high
+// (bearish), or a new low while the oscillator makes a higher low
+// (bullish). The previous extreme is looked for at least separation ticks
+// back, so the ticks of the current swing are not compared to each other.
+// A divergence is reported until the next new extreme, or for at most the
+// lookback. Each tick costs O(1) amortized, whatever the lookback.
+type DivergenceDetector struct {
+	samples    *rolling.Window[divergenceSample]
+	prices     *rolling.Stats // Prices of the samples, for their high and low
+	highs      extremeQueue   // Previous highs: samples before the current swing
+	lows       extremeQueue   // Previous lows
+	pushed     int64          // Samples pushed since the last reset
+	separation int
+	delta      float64 // Cumulative volume delta of all pushed ticks
+	strength   float64 // Divergence of the relative strength
+	volume     float64 // Divergence of the volume delta
+	age        int     // Ticks since the last new extreme
+}
+
+// NewDivergenceDetector creates a detector over the last lookback ticks
+func NewDivergenceDetector(lookback, separation int) *DivergenceDetector {
+	return &DivergenceDetector{
+		samples:    rolling.NewWindow[divergenceSample](lookback),
+		prices:     rolling.NewStats(lookback),
+		highs:      extremeQueue{high: true},
+		separation: separation,
+	}
+}
+
+// Push adds a tick, with the relative strength at the tick and its signed
+// volume (positive for buyers, negative for sellers), and returns the
+// divergences of the relative strength and of the volume delta
+func (d *DivergenceDetector) Push(price, strength, volume float64) (strengthDivergence, deltaDivergence float64) {
+	d.delta += volume
+	current := divergenceSample{price: price, strength: strength, delta: d.delta}
+	defer d.add(current)
+
+	d.age++
+	if d.age >= d.samples.Cap() {
+		d.strength, d.volume = 0, 0
+	}
+	n := d.samples.Len()
+	if n <= d.separation {
+		return d.strength, d.volume
+	}
+
+	// The sample separation ticks before the newest leaves the current
+	// swing; the previous extremes are those of the samples before it
+	oldest := d.pushed - int64(n)
+	swing := n - d.separation - 1
+	d.highs.push(oldest+int64(swing), d.samples.At(swing))
+	d.lows.push(oldest+int64(swing), d.samples.At(swing))
+	d.highs.evict(oldest)
+	d.lows.evict(oldest)
+
+	// Extremes of the whole lookback, and the previous ones before the
+	// current swing
+	high, low := d.prices.Max(), d.prices.Min()
+	previousHigh, previousLow := d.highs.front(), d.lows.front()
+
+	switch {
+	case price > high:
+		d.strength = divergence(strength < previousHigh.strength, types.DivergenceBearish)
+		d.volume = divergence(current.delta < previousHigh.delta, types.DivergenceBearish)
+		d.age = 0
+	case price < low:
+		d.strength = divergence(strength > previousLow.strength, types.DivergenceBullish)
+		d.volume = divergence(current.delta > previousLow.delta, types.DivergenceBullish)
+		d.age = 0
+	}
+	return d.strength, d.volume
+}
+
+// add stores a sample once it was compared
+func (d *DivergenceDetector) add(sample divergenceSample) {
+	d.samples.Push(sample)
+	d.prices.Push(sample.price)
+	d.pushed++
+}
+
+// Reset drops the ticks and the reported divergences
+func (d *DivergenceDetector) Reset() {
+	d.samples.Reset()
+	d.prices.Reset()
+	d.highs.reset()
+	d.lows.reset()
+	d.pushed = 0
+	d.delta, d.strength, d.volume, d.age = 0, 0, 0, 0
+}
+
+// extremeQueue keeps the samples that can still become the highest (or
+// lowest) of a sliding window, oldest first: the front is the extreme,
+// the latest of equal prices
+type extremeQueue struct {
+	high    bool
+	entries []extremeEntry
+}
+
+// extremeEntry is a sample of an extremeQueue with its push sequence
+type extremeEntry struct {
+	seq    int64
+	sample divergenceSample
+}
+
+// push adds a sample, dropping those it outlasts without being exceeded
+func (q *extremeQueue) push(seq int64, sample divergenceSample) {
+	for len(q.entries) > 0 {
+		last := q.entries[len(q.entries)-1].sample.price
+		if (q.high && last > sample.price) || (!q.high && last < sample.price) {
+			break
+		}
+		q.entries = q.entries[:len(q.entries)-1]
+	}
+	q.entries = append(q.entries, extremeEntry{seq: seq, sample: sample})
+}
+
+// evict drops the samples pushed before oldest
+func (q *extremeQueue) evict(oldest int64) {
+	for len(q.entries) > 0 && q.entries[0].seq < oldest {
+		q.entries = q.entries[1:]
+	}
+}
+
+// front returns the extreme sample
+func (q *extremeQueue) front() divergenceSample {
+	return q.entries[0].sample
+}
+
+// reset drops the samples
+func (q *extremeQueue) reset() {
+	q.entries = q.entries[:0]
+}
+
+// divergence returns direction if diverged, 0 otherwise
+func divergence(diverged bool, direction float64) float64 {
+	if diverged {
+		return direction
+	}
+	return 0
+}
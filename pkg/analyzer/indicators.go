@@ -0,0 +1,282 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"TRADE/pkg/market"
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// MovingAverage is the kind of the moving averages of a crossover
+type MovingAverage string
+
+// Moving averages
+const (
+	MovingAverageEMA MovingAverage = "ema"
+	MovingAverageSMA MovingAverage = "sma"
+)
+
+// IndicatorSettings are the periods of the classic indicators, in prices:
+// ticks, or closes of the candles they are computed on
+type IndicatorSettings struct {
+	RSIPeriod int
+	// MACDFast and MACDSlow are the periods of the EMAs whose difference
+	// is the MACD line, and MACDSignal that of its signal line
+	MACDFast   int
+	MACDSlow   int
+	MACDSignal int
+	// BollingerPeriod is the period of the middle band, and
+	// BollingerDeviations the standard deviations the outer bands are from it
+	BollingerPeriod     int
+	BollingerDeviations float64
+	// Crossover is the kind of the fast and slow moving averages crossed
+	Crossover     MovingAverage
+	CrossoverFast int
+	CrossoverSlow int
+}
+
+// DefaultIndicatorSettings returns the customary periods: RSI 14, MACD
+// 12/26/9, Bollinger Bands 20 at 2 deviations and an EMA 9/21 crossover
+func DefaultIndicatorSettings() IndicatorSettings {
+	return IndicatorSettings{
+		RSIPeriod:           DefaultIndicatorPeriod,
+		MACDFast:            12,
+		MACDSlow:            26,
+		MACDSignal:          9,
+		BollingerPeriod:     20,
+		BollingerDeviations: 2,
+		Crossover:           MovingAverageEMA,
+		CrossoverFast:       9,
+		CrossoverSlow:       21,
+	}
+}
+
+// Validate checks the periods
+func (s IndicatorSettings) Validate() error {
+	switch {
+	case s.RSIPeriod <= 0:
+		return fmt.Errorf("RSI period must be positive")
+	case s.MACDFast <= 0 || s.MACDSignal <= 0:
+		return fmt.Errorf("MACD periods must be positive")
+	case s.MACDFast >= s.MACDSlow:
+		return fmt.Errorf("MACD fast period must be shorter than its slow period")
+	case s.BollingerPeriod < 2:
+		return fmt.Errorf("Bollinger period must be at least 2")
+	case s.BollingerDeviations <= 0:
+		return fmt.Errorf("Bollinger deviations must be positive")
+	case s.Crossover != MovingAverageEMA && s.Crossover != MovingAverageSMA:
+		return fmt.Errorf("crossover moving average must be ema or sma, not %q", s.Crossover)
+	case s.CrossoverFast <= 0:
+		return fmt.Errorf("crossover periods must be positive")
+	case s.CrossoverFast >= s.CrossoverSlow:
+		return fmt.Errorf("crossover fast period must be shorter than its slow period")
+	}
+	return nil
+}
+
+// movingAverage is an exponential or simple moving average
+type movingAverage interface {
+	push(value float64)
+	ready() bool
+	average() float64
+	reset()
+}
+
+// exponentialAverage is an EMA seeded with the simple average of its
+// first period values
+type exponentialAverage struct {
+	period int
+	count  int
+	value  float64
+}
+
+// push adds a value
+func (e *exponentialAverage) push(value float64) {
+	e.count++
+	n := float64(e.count)
+	if e.count > e.period {
+		n = float64(e.period+1) / 2
+	}
+	e.value += (value - e.value) / n
+}
+
+// ready returns whether period values were averaged
+func (e *exponentialAverage) ready() bool {
+	return e.count >= e.period
+}
+
+// average returns the EMA
+func (e *exponentialAverage) average() float64 {
+	return e.value
+}
+
+// reset drops the values added
+func (e *exponentialAverage) reset() {
+	*e = exponentialAverage{period: e.period}
+}
+
+// simpleAverage is an SMA over a rolling window
+type simpleAverage struct {
+	values *rolling.Stats
+}
+
+// push adds a value
+func (s simpleAverage) push(value float64) {
+	s.values.Push(value)
+}
+
+// ready returns whether the window is full
+func (s simpleAverage) ready() bool {
+	return s.values.Len() == s.values.Cap()
+}
+
+// average returns the SMA
+func (s simpleAverage) average() float64 {
+	return s.values.Mean()
+}
+
+// reset drops the values added
+func (s simpleAverage) reset() {
+	s.values.Reset()
+}
+
+// newMovingAverage creates a moving average of a kind over period values
+func newMovingAverage(kind MovingAverage, period int) movingAverage {
+	if kind == MovingAverageSMA {
+		return simpleAverage{values: rolling.NewStats(period)}
+	}
+	return &exponentialAverage{period: period}
+}
+
+// indicatorSet computes RSI, MACD, Bollinger Bands and a moving average
+// crossover on a series of prices, each in O(1) per price
+type indicatorSet struct {
+	rsi        *candleIndicators // Only its RSI: prices are added as flat candles
+	macdFast   exponentialAverage
+	macdSlow   exponentialAverage
+	macdSignal exponentialAverage
+	bollinger  *rolling.Stats
+	deviations float64
+	fast       movingAverage
+	slow       movingAverage
+	last       float64
+}
+
+// newIndicatorSet creates the indicators of the settings
+func newIndicatorSet(settings IndicatorSettings) *indicatorSet {
+	return &indicatorSet{
+		rsi:        newCandleIndicators(settings.RSIPeriod),
+		macdFast:   exponentialAverage{period: settings.MACDFast},
+		macdSlow:   exponentialAverage{period: settings.MACDSlow},
+		macdSignal: exponentialAverage{period: settings.MACDSignal},
+		bollinger:  rolling.NewStats(settings.BollingerPeriod),
+		deviations: settings.BollingerDeviations,
+		fast:       newMovingAverage(settings.Crossover, settings.CrossoverFast),
+		slow:       newMovingAverage(settings.Crossover, settings.CrossoverSlow),
+	}
+}
+
+// push adds a price
+func (s *indicatorSet) push(price float64) {
+	s.last = price
+	s.rsi.add(market.Candle{Open: price, High: price, Low: price, Close: price})
+	s.macdFast.push(price)
+	s.macdSlow.push(price)
+	if s.macdSlow.ready() {
+		s.macdSignal.push(s.macdFast.average() - s.macdSlow.average())
+	}
+	s.bollinger.Push(price)
+	s.fast.push(price)
+	s.slow.push(price)
+}
+
+// report writes the indicators into the metrics, leaving those not yet
+// measured neutral
+func (s *indicatorSet) report(metrics *types.MarketMetrics) {
+	metrics.RSI = neutralRSI
+	if s.rsi.ready() {
+		metrics.RSI = s.rsi.RSI()
+	}
+
+	metrics.MACD, metrics.MACDSignal, metrics.MACDHistogram = 0, 0, 0
+	if s.macdSignal.ready() {
+		metrics.MACD = s.macdFast.average() - s.macdSlow.average()
+		metrics.MACDSignal = s.macdSignal.average()
+		metrics.MACDHistogram = metrics.MACD - metrics.MACDSignal
+	}
+
+	metrics.BollingerUpper, metrics.BollingerMiddle, metrics.BollingerLower, metrics.BollingerPercentB = 0, 0, 0, 0.5
+	if s.bollinger.Len() == s.bollinger.Cap() {
+		middle, width := s.bollinger.Mean(), s.deviations*s.bollinger.StdDev()
+		metrics.BollingerMiddle = middle
+		metrics.BollingerUpper, metrics.BollingerLower = middle+width, middle-width
+		if width > 0 {
+			metrics.BollingerPercentB = (s.last - metrics.BollingerLower) / (2 * width)
+		}
+	}
+
+	metrics.FastMA, metrics.SlowMA, metrics.MACross = 0, 0, 0
+	if s.fast.ready() && s.slow.ready() {
+		metrics.FastMA, metrics.SlowMA = s.fast.average(), s.slow.average()
+		if metrics.FastMA != metrics.SlowMA {
+			metrics.MACross = math.Copysign(1, metrics.FastMA-metrics.SlowMA)
+		}
+	}
+}
+
+// reset drops the prices added
+func (s *indicatorSet) reset() {
+	s.rsi.reset()
+	s.macdFast.reset()
+	s.macdSlow.reset()
+	s.macdSignal.reset()
+	s.bollinger.Reset()
+	s.fast.reset()
+	s.slow.reset()
+	s.last = 0
+}
+
+// SetIndicators computes RSI, MACD, Bollinger Bands and the moving average
+// crossover of the settings and reports them in the metrics. They are
+// computed on the closes of the candles of timeframe, one the market data
+// builds, or on every tick's price when timeframe is 0. The RSI replaces
+// that of the candle indicators.
+func (a *Analyzer) SetIndicators(settings IndicatorSettings, timeframe time.Duration) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	var builder *market.CandleBuilder
+	if timeframe > 0 {
+		builder = a.market.CandleBuilder()
+		if builder == nil {
+			return fmt.Errorf("no candles are built")
+		}
+		if _, err := builder.Candles(timeframe, 1); err != nil {
+			return err
+		}
+	}
+
+	indicators := newIndicatorSet(settings)
+	a.mutex.Lock()
+	a.indicators = indicators
+	a.indicatorTicks = timeframe == 0
+	indicators.report(a.metrics)
+	a.mutex.Unlock()
+	if builder == nil {
+		return nil
+	}
+
+	builder.OnClose(func(closed time.Duration, candle market.Candle) {
+		if closed != timeframe {
+			return
+		}
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		indicators.push(candle.Close)
+		indicators.report(a.metrics)
+	})
+	return nil
+}
@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/bars"
+	"TRADE/pkg/market"
+	"TRADE/pkg/patterns"
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// PatternHandler is called with each candlestick pattern detected and the
+// candle completing it
+type PatternHandler func(pattern types.CandlePattern, candle market.Candle)
+
+// patternAnalysis detects the candlestick patterns of the closed candles
+// of a timeframe
+type patternAnalysis struct {
+	name     string // Timeframe, e.g. 5m
+	detector *patterns.Detector
+	candles  *rolling.Window[market.Candle]         // Last candles, for the patterns spanning several
+	found    *rolling.Window[[]types.CandlePattern] // Patterns of the last recent candles, oldest first
+}
+
+// add adds a closed candle and returns the patterns it completes
+func (p *patternAnalysis) add(candle market.Candle) []types.CandlePattern {
+	p.candles.Push(candle)
+	candles := p.candles.View().AppendTo(make([]market.Candle, 0, patterns.MaxCandles))
+	var found []types.CandlePattern
+	for _, match := range p.detector.Detect(candles) {
+		found = append(found, types.CandlePattern{Name: match.Name, Bias: match.Bias, Timeframe: p.name, Time: candle.Time})
+	}
+	p.found.Push(found)
+	return found
+}
+
+// patterns returns the patterns of the recent candles, newest first
+func (p *patternAnalysis) patterns() []types.CandlePattern {
+	var recent []types.CandlePattern
+	found := p.found.View()
+	for i := found.Len() - 1; i >= 0; i-- {
+		recent = append(recent, found.At(i)...)
+	}
+	return recent
+}
+
+// reset drops the candles added
+func (p *patternAnalysis) reset() {
+	p.candles.Reset()
+	p.found.Reset()
+}
+
+// SetPatterns detects candlestick patterns on the closed candles of a
+// timeframe the market data builds, reports those completed by the last
+// recent candles in the metrics' Patterns and calls handler, if any, with
+// each one as it is detected
+func (a *Analyzer) SetPatterns(timeframe time.Duration, detector *patterns.Detector, recent int, handler PatternHandler) error {
+	builder := a.market.CandleBuilder()
+	if builder == nil {
+		return fmt.Errorf("no candles are built")
+	}
+	if _, err := builder.Candles(timeframe, 1); err != nil {
+		return err
+	}
+	if recent <= 0 {
+		return fmt.Errorf("patterns must be reported for at least 1 candle")
+	}
+	analysis := &patternAnalysis{
+		name:     bars.FormatInterval(timeframe),
+		detector: detector,
+		candles:  rolling.NewWindow[market.Candle](patterns.MaxCandles),
+		found:    rolling.NewWindow[[]types.CandlePattern](recent),
+	}
+
+	a.mutex.Lock()
+	a.patterns = analysis
+	a.mutex.Unlock()
+
+	builder.OnClose(func(closed time.Duration, candle market.Candle) {
+		if closed != timeframe {
+			return
+		}
+		a.mutex.Lock()
+		found := analysis.add(candle)
+		a.metrics.Patterns = analysis.patterns()
+		a.mutex.Unlock()
+
+		// Outside the lock: handlers may read the metrics
+		if handler != nil {
+			for _, pattern := range found {
+				handler(pattern, candle)
+			}
+		}
+	})
+	return nil
+}
+
+// resetPatterns drops the candles of the pattern timeframe
+func (a *Analyzer) resetPatterns() {
+	if a.patterns == nil {
+		return
+	}
+	a.patterns.reset()
+	a.metrics.Patterns = nil
+}
@@ -0,0 +1,139 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"TRADE/pkg/bars"
+	"TRADE/pkg/market"
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// timeframeAnalysis measures the trend, efficiency ratio and RSI of the
+// closed candles of a timeframe
+type timeframeAnalysis struct {
+	name       string                   // Timeframe, e.g. 15m
+	closes     *rolling.Window[float64] // Closes of the last period candles
+	indicators *candleIndicators
+}
+
+// newTimeframeAnalysis creates the analysis of a timeframe over period
+// candles
+func newTimeframeAnalysis(timeframe time.Duration, period int) *timeframeAnalysis {
+	indicators := newCandleIndicators(period)
+	return &timeframeAnalysis{
+		name:       bars.FormatInterval(timeframe),
+		closes:     rolling.NewWindow[float64](indicators.period),
+		indicators: indicators,
+	}
+}
+
+// add adds a closed candle
+func (t *timeframeAnalysis) add(candle market.Candle) {
+	t.closes.Push(candle.Close)
+	t.indicators.add(candle)
+}
+
+// metrics returns the metrics of the candles added
+func (t *timeframeAnalysis) metrics() types.TimeframeMetrics {
+	metrics := types.TimeframeMetrics{
+		Timeframe:             t.name,
+		MarketEfficiencyRatio: 0.5,
+		RSI:                   t.indicators.RSI(),
+		Ready:                 t.indicators.ready(),
+	}
+	closes := t.closes.View()
+	if closes.Len() < 2 {
+		return metrics
+	}
+	slope, _, r := linearRegression(closes)
+	if mean := sum(closes) / float64(closes.Len()); mean > 0 {
+		metrics.Trend = slope / mean * 100 * r * r
+	}
+	metrics.MarketEfficiencyRatio = efficiencyRatio(closes)
+	return metrics
+}
+
+// reset drops the candles added
+func (t *timeframeAnalysis) reset() {
+	t.closes.Reset()
+	t.indicators.reset()
+}
+
+// efficiencyRatio returns the net move of the values over the length of
+// their path, 0.5 without movement
+func efficiencyRatio(values rolling.View[float64]) float64 {
+	n := values.Len()
+	path := 0.0
+	for i := 1; i < n; i++ {
+		path += math.Abs(values.At(i) - values.At(i-1))
+	}
+	if path == 0 {
+		return 0.5
+	}
+	return math.Abs(values.At(n-1)-values.At(0)) / path
+}
+
+// SetTimeframes measures the trend, efficiency ratio and RSI of the closed
+// candles of timeframes the market data builds, over period candles each,
+// and reports them in the metrics' Timeframes, shortest first
+func (a *Analyzer) SetTimeframes(timeframes []time.Duration, period int) error {
+	builder := a.market.CandleBuilder()
+	if builder == nil {
+		return fmt.Errorf("no candles are built")
+	}
+	analyses := make(map[time.Duration]*timeframeAnalysis, len(timeframes))
+	for _, timeframe := range timeframes {
+		if _, err := builder.Candles(timeframe, 1); err != nil {
+			return err
+		}
+		analyses[timeframe] = newTimeframeAnalysis(timeframe, period)
+	}
+
+	// Report them in the order the builder closes them, shortest first
+	a.mutex.Lock()
+	a.timeframes = a.timeframes[:0]
+	for _, timeframe := range builder.Timeframes() {
+		if analysis, ok := analyses[timeframe]; ok {
+			a.timeframes = append(a.timeframes, analysis)
+		}
+	}
+	a.publishTimeframes()
+	a.mutex.Unlock()
+
+	builder.OnClose(func(timeframe time.Duration, candle market.Candle) {
+		analysis, ok := analyses[timeframe]
+		if !ok {
+			return
+		}
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		analysis.add(candle)
+		a.publishTimeframes()
+	})
+	return nil
+}
+
+// publishTimeframes replaces the timeframe metrics with those of the
+// candles added, leaving the slices of earlier copies untouched
+func (a *Analyzer) publishTimeframes() {
+	if len(a.timeframes) == 0 {
+		a.metrics.Timeframes = nil
+		return
+	}
+	metrics := make([]types.TimeframeMetrics, len(a.timeframes))
+	for i, analysis := range a.timeframes {
+		metrics[i] = analysis.metrics()
+	}
+	a.metrics.Timeframes = metrics
+}
+
+// resetTimeframes drops the candles of the timeframes analyzed
+func (a *Analyzer) resetTimeframes() {
+	for _, analysis := range a.timeframes {
+		analysis.reset()
+	}
+	a.publishTimeframes()
+}
@@ -0,0 +1,465 @@
+// Package api serves the REST control API of a running instance: its
+// status and trades can be read, trades tagged and noted for review, and
+// entries paused and resumed or the open position closed, without
+// restarting it. With a stream, GET /stream upgrades to a WebSocket that
+// pushes market state snapshots, signals and trade events to dashboards.
+//
+// Requests carry the configured bearer token when one is set; browsers,
+// which cannot set headers on a WebSocket, may pass it as the access_token
+// parameter of /stream instead. Without a token the server should listen
+// on a loopback or otherwise private address. POST requests a browser sends
+// from another origin, or with a body that is not JSON, are refused in
+// every mode, so a web page cannot forge control requests.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/store"
+	"TRADE/pkg/types"
+)
+
+// defaultTradesLimit is the number of trades /trades returns without a
+// limit
+const defaultTradesLimit = 100
+
+// maxBodySize caps the size of request bodies
+const maxBodySize = 64 << 10
+
+// ErrNoPosition is returned when a close is requested without an open
+// position
+var ErrNoPosition = errors.New("no open position")
+
+// ErrNoHistory is returned for trades when no trade history is kept
+var ErrNoHistory = errors.New("no trade history is kept (storage.type)")
+
+// ErrUnknownTrade is returned when annotating a trade that is neither
+// open nor in the trade history
+var ErrUnknownTrade = errors.New("unknown trade")
+
+// Status is the document served on GET /status
+type Status struct {
+	Status         string                    `json:"status"`
+	Mode           types.ExecutionMode       `json:"mode"`
+	RunID          string                    `json:"run_id"`
+	Symbol         string                    `json:"symbol"`
+	Price          float64                   `json:"price"`
+	LastTick       time.Time                 `json:"last_tick"`
+	Position       *types.TradeData          `json:"position,omitempty"`
+	CloseRequested bool                      `json:"close_requested"` // The open position closes on the next tick
+	KillSwitch     string                    `json:"kill_switch,omitempty"`
+	Performance    *types.PerformanceMetrics `json:"performance"`
+	Equity         *types.EquityPoint        `json:"equity,omitempty"`
+	Portfolio      *types.PortfolioView      `json:"portfolio,omitempty"` // Set once instances run other symbols
+}
+
+// Trade is a closed trade served on GET /trades
+type Trade struct {
+	ID         string          `json:"id"`
+	RunID      string          `json:"run_id"`
+	Mode       string          `json:"mode"`
+	Symbol     string          `json:"symbol"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	ExitPrice  decimal.Decimal `json:"exit_price"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	PnL        decimal.Decimal `json:"pnl"`
+	PnLPercent float64         `json:"pnl_percent"`
+	Reason     string          `json:"reason"`
+	EntryTime  time.Time       `json:"entry_time"`
+	ExitTime   time.Time       `json:"exit_time"`
+	Tags       []string        `json:"tags,omitempty"`
+	Notes      []Note          `json:"notes,omitempty"`
+}
+
+// Note is a note on a trade
+type Note struct {
+	Time   time.Time `json:"time"`
+	Author string    `json:"author,omitempty"`
+	Text   string    `json:"text"`
+}
+
+// Annotation is the body of POST /trades/{id}/annotations: tags to add and
+// remove and a note to add, all optional
+type Annotation struct {
+	Tags   []string `json:"tags"`
+	Untag  []string `json:"untag"`
+	Note   string   `json:"note"`
+	Author string   `json:"author"` // Author of the note; defaults to "api"
+}
+
+// Annotations are the tags and notes of a trade, served once annotated
+type Annotations struct {
+	TradeID string   `json:"trade_id"`
+	Open    bool     `json:"open"` // The trade is still open
+	Tags    []string `json:"tags"`
+	Notes   []Note   `json:"notes"`
+}
+
+// Control is the running instance the API inspects and controls
+type Control interface {
+	// Status returns the current state of the instance
+	Status() *Status
+	// Trades returns the closed trades of the trade history matching the
+	// query, or ErrNoHistory
+	Trades(query store.TradeQuery) ([]store.Trade, error)
+	// Annotate tags, untags and adds a note to the open trade or a closed
+	// trade of the history, returning its annotations, or ErrNoHistory or
+	// ErrUnknownTrade
+	Annotate(tradeID string, annotation Annotation) (*Annotations, error)
+	// Pause stops new entries; open trades keep being managed
+	Pause() error
+	// Resume re-enables new entries
+	Resume() error
+	// ClosePosition requests the open position to be closed at the next
+	// tick, returning its trade ID
+	ClosePosition() (string, error)
+}
+
+// Options configures the API server
+type Options struct {
+	Address string  // Listen address, e.g. "127.0.0.1:8080"
+	Token   string  // Bearer token every request must carry; empty accepts any
+	Stream  *Stream // Served on /stream when set
+}
+
+// Server serves the control API
+type Server struct {
+	options  Options
+	control  Control
+	logger   logger.Interface
+	server   *http.Server
+	listener net.Listener
+	stopOnce sync.Once
+}
+
+// NewServer creates an API server for an instance
+func NewServer(options Options, control Control, log logger.Interface) *Server {
+	s := &Server{options: options, control: control, logger: log}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.get(s.handleStatus))
+	mux.HandleFunc("/trades", s.get(s.handleTrades))
+	mux.HandleFunc("/trades/", s.post(s.handleAnnotate))
+	mux.HandleFunc("/strategy/pause", s.post(s.handlePause))
+	mux.HandleFunc("/strategy/resume", s.post(s.handleResume))
+	mux.HandleFunc("/positions/close", s.post(s.handleClose))
+	if options.Stream != nil {
+		mux.HandleFunc("/stream", s.get(options.Stream.ServeHTTP))
+	}
+	s.server = &http.Server{
+		Handler:           s.authorize(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return s
+}
+
+// Start listens on the configured address and serves in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.options.Address, err)
+	}
+	s.listener = listener
+	if s.options.Stream != nil {
+		s.options.Stream.start()
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error(fmt.Sprintf("API server stopped: %v", err))
+		}
+	}()
+	s.logger.Info(fmt.Sprintf("Control API listening on http://%s", listener.Addr()),
+		"authenticated", s.options.Token != "")
+	return nil
+}
+
+// Addr returns the listen address, or nil before Start
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop shuts the server down
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		if s.options.Stream != nil {
+			s.options.Stream.stop() // Shutdown leaves upgraded connections open
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+	})
+}
+
+// authorize rejects requests without the bearer token when one is set,
+// taking it from the access_token parameter of WebSocket upgrades too
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.options.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.options.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if token := r.URL.Query().Get("access_token"); header == "" && token != "" && websocket.IsWebSocketUpgrade(r) {
+			header = "Bearer " + token
+		}
+		if subtle.ConstantTimeCompare([]byte(header), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// get accepts only GET and HEAD requests
+func (s *Server) get(handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		handle(w, r)
+	}
+}
+
+// post accepts only POST requests, which change the instance and are logged.
+// Requests from pages of another origin and bodies a cross-origin form could
+// send without a preflight (anything but JSON) are refused.
+func (s *Server) post(handle http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		if !sameOrigin(r) {
+			s.logger.Warning(fmt.Sprintf("Refused cross-origin control request %s %s", r.Method, r.URL.Path),
+				"remote", r.RemoteAddr, "origin", r.Header.Get("Origin"))
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "" || r.ContentLength != 0 {
+			if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("request body must be application/json"))
+				return
+			}
+		}
+		s.logger.Info(fmt.Sprintf("Control request %s %s", r.Method, r.URL.Path), "remote", r.RemoteAddr)
+		handle(w, r)
+	}
+}
+
+// sameOrigin reports whether a request comes from a page of the API's own
+// origin; requests without Origin and Sec-Fetch-Site do not come from a
+// browser
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// handleStatus serves the status of the instance
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.write(w, http.StatusOK, s.control.Status())
+}
+
+// handleTrades serves the closed trades, most recent last. The run
+// parameter selects a session, "all" every session, the default the
+// current one; limit caps the trades returned (0 returns all), since and
+// until bound their exit times (RFC 3339) and outcome selects win, loss or
+// flat trades; each tag parameter selects the trades carrying that tag.
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTradeQuery(r, s.control.Status().RunID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	trades, err := s.control.Trades(query)
+	switch {
+	case errors.Is(err, ErrNoHistory):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	documents := make([]Trade, len(trades))
+	for i, trade := range trades {
+		documents[i] = Trade{
+			ID:         trade.ID,
+			RunID:      trade.RunID,
+			Mode:       trade.Mode,
+			Symbol:     trade.Symbol,
+			EntryPrice: trade.EntryPrice,
+			ExitPrice:  trade.ExitPrice,
+			Quantity:   trade.Quantity,
+			PnL:        trade.PnL,
+			PnLPercent: trade.PnLPercent,
+			Reason:     trade.Reason,
+			EntryTime:  trade.EntryTime,
+			ExitTime:   trade.ExitTime,
+			Tags:       trade.Tags,
+			Notes:      Notes(trade.Notes),
+		}
+	}
+	s.write(w, http.StatusOK, documents)
+}
+
+// parseTradeQuery reads the trade query of a /trades request to an
+// instance running the session currentRun
+func parseTradeQuery(r *http.Request, currentRun string) (store.TradeQuery, error) {
+	values := r.URL.Query()
+	query := store.TradeQuery{RunID: values.Get("run"), Symbol: values.Get("symbol"), Limit: defaultTradesLimit}
+	switch query.RunID {
+	case "":
+		query.RunID = currentRun
+	case "all":
+		query.RunID = ""
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("invalid limit %q", limit)
+		}
+		query.Limit = n
+	}
+	for name, bound := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := values.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s %q: want RFC 3339, e.g. 2025-03-10T00:00:00Z", name, value)
+			}
+			*bound = parsed
+		}
+	}
+	outcome, err := store.ParseOutcome(strings.ToLower(values.Get("outcome")))
+	if err != nil {
+		return query, err
+	}
+	query.Outcome = outcome
+	if query.Tags, err = store.ParseTags(values["tag"]); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// handleAnnotate tags, untags and notes a trade on
+// POST /trades/{id}/annotations
+func (s *Server) handleAnnotate(w http.ResponseWriter, r *http.Request) {
+	tradeID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/trades/"), "/")
+	if tradeID == "" || action != "annotations" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found: %s", r.URL.Path))
+		return
+	}
+	var annotation Annotation
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&annotation); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid annotation: %v", err))
+		return
+	}
+	if len(annotation.Tags) == 0 && len(annotation.Untag) == 0 && strings.TrimSpace(annotation.Note) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid annotation: no tags, untag or note"))
+		return
+	}
+	if _, err := store.ParseTags(annotation.Tags); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	annotations, err := s.control.Annotate(tradeID, annotation)
+	switch {
+	case errors.Is(err, ErrNoHistory), errors.Is(err, ErrUnknownTrade):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.write(w, http.StatusOK, annotations)
+}
+
+// Notes converts the notes of a trade for the API
+func Notes(stored []store.Note) []Note {
+	if len(stored) == 0 {
+		return nil
+	}
+	converted := make([]Note, len(stored))
+	for i, note := range stored {
+		converted[i] = Note{Time: note.Time, Author: note.Author, Text: note.Text}
+	}
+	return converted
+}
+
+// handlePause stops new entries
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if err := s.control.Pause(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.write(w, http.StatusOK, s.control.Status())
+}
+
+// handleResume re-enables new entries
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if err := s.control.Resume(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.write(w, http.StatusOK, s.control.Status())
+}
+
+// handleClose requests the open position to be closed at the next tick
+func (s *Server) handleClose(w http.ResponseWriter, r *http.Request) {
+	tradeID, err := s.control.ClosePosition()
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.write(w, http.StatusAccepted, map[string]string{
+		"trade_id": tradeID,
+		"message":  "the position closes at the next tick",
+	})
+}
+
+// write writes a JSON document
+func (s *Server) write(w http.ResponseWriter, code int, document interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		s.logger.Debug(fmt.Sprintf("Failed to write API response: %v", err))
+	}
+}
+
+// writeError writes an error as a JSON document
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
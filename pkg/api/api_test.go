@@ -0,0 +1,68 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/store"
+)
+
+// fakeControl counts the control requests that reach the instance
+type fakeControl struct {
+	pauses int
+}
+
+func (c *fakeControl) Status() *Status { return &Status{Status: "RUNNING"} }
+func (c *fakeControl) Trades(store.TradeQuery) ([]store.Trade, error) {
+	return nil, ErrNoHistory
+}
+func (c *fakeControl) Annotate(tradeID string, annotation Annotation) (*Annotations, error) {
+	return &Annotations{TradeID: tradeID, Tags: annotation.Tags}, nil
+}
+func (c *fakeControl) Pause() error                   { c.pauses++; return nil }
+func (c *fakeControl) Resume() error                  { return nil }
+func (c *fakeControl) ClosePosition() (string, error) { return "", ErrNoPosition }
+
+func TestControlRequestsRefuseCrossOriginAndForms(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		path    string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"curl without body", "", "/strategy/pause", nil, "", http.StatusOK},
+		{"same origin", "", "/strategy/pause", map[string]string{"Origin": "http://127.0.0.1:8080", "Sec-Fetch-Site": "same-origin"}, "", http.StatusOK},
+		{"json body", "", "/trades/t1/annotations", map[string]string{"Content-Type": "application/json; charset=utf-8"}, `{"tags":["a"]}`, http.StatusOK},
+		{"other origin", "", "/strategy/pause", map[string]string{"Origin": "https://evil.example"}, "", http.StatusForbidden},
+		{"null origin", "", "/positions/close", map[string]string{"Origin": "null"}, "", http.StatusForbidden},
+		{"cross-site fetch", "", "/strategy/pause", map[string]string{"Sec-Fetch-Site": "cross-site"}, "", http.StatusForbidden},
+		{"form post", "", "/strategy/pause", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "a=1", http.StatusUnsupportedMediaType},
+		{"text body", "", "/trades/t1/annotations", map[string]string{"Content-Type": "text/plain"}, `{"tags":["a"]}`, http.StatusUnsupportedMediaType},
+		{"body without type", "", "/trades/t1/annotations", nil, `{"tags":["a"]}`, http.StatusUnsupportedMediaType},
+		{"other origin with token", "secret", "/strategy/pause", map[string]string{"Authorization": "Bearer secret", "Origin": "https://evil.example"}, "", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		control := &fakeControl{}
+		s := NewServer(Options{Address: "127.0.0.1:8080", Token: test.token}, control,
+			logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)))
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080"+test.path, strings.NewReader(test.body))
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(recorder, req)
+		if recorder.Code != test.want {
+			t.Errorf("%s: status %d, want %d (%s)", test.name, recorder.Code, test.want, strings.TrimSpace(recorder.Body.String()))
+		}
+		if test.want != http.StatusOK && control.pauses != 0 {
+			t.Errorf("%s: refused request reached the instance", test.name)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// TypeState is the type of the market state snapshots pushed on /stream
+const TypeState = "state"
+
+// StreamTypes are the event types pushed on /stream besides the market
+// state snapshots
+var StreamTypes = []events.Type{events.TypeSignal, events.TypeOrder, events.TypeFill, events.TypeTradeClosed, events.TypePattern}
+
+// Timing of the WebSocket connections
+const (
+	streamWriteTimeout = 10 * time.Second
+	streamPingInterval = 30 * time.Second
+	streamPongTimeout  = 2 * streamPingInterval
+)
+
+// maxClientMessage caps the size of the messages clients send; the stream
+// only reads them to answer pings and notice closes
+const maxClientMessage = 4 << 10
+
+// StreamOptions configures the WebSocket push stream
+type StreamOptions struct {
+	StateInterval  time.Duration // Least time between two snapshots of a symbol
+	BufferSize     int           // Messages buffered per client before dropping
+	MaxClients     int           // 0 means unlimited
+	AllowedOrigins []string      // Origins of the web pages allowed to connect; "*" for any
+}
+
+// Message is a JSON message pushed on /stream: a market state snapshot
+// (types.MarketState), a signal, a trade event or a candlestick pattern
+type Message struct {
+	Type   string      `json:"type"` // TypeState or an event type
+	Symbol string      `json:"symbol,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+// Snapshot returns the market state of a symbol at a metrics update
+type Snapshot func(update *events.MetricsEvent) *types.MarketState
+
+// Stream pushes market state snapshots, signals and trade events from the
+// bus to WebSocket clients as JSON. Messages are encoded once on the
+// publishing goroutine and pushed without ever blocking it: a client that
+// falls behind its buffer loses messages instead.
+type Stream struct {
+	bus          *events.Bus
+	options      StreamOptions
+	snapshot     Snapshot
+	logger       logger.Interface
+	upgrader     websocket.Upgrader
+	subscription []events.SubscriptionID
+	count        int64 // Connected clients, read without the lock
+	mutex        sync.Mutex
+	clients      map[*streamClient]bool
+	snapshots    map[string]time.Time // Time of the last snapshot pushed per symbol
+	connections  sync.WaitGroup
+	stopped      bool
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+// streamClient is a connected WebSocket client
+type streamClient struct {
+	types   map[string]bool // Empty accepts every type
+	symbol  string          // Empty accepts every symbol
+	send    chan []byte
+	dropped int64
+}
+
+// NewStream creates a push stream of the events published on bus, with
+// the market state snapshots taken by snapshot
+func NewStream(bus *events.Bus, options StreamOptions, snapshot Snapshot, log logger.Interface) *Stream {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 256
+	}
+	s := &Stream{
+		bus:       bus,
+		options:   options,
+		snapshot:  snapshot,
+		logger:    log,
+		clients:   make(map[*streamClient]bool),
+		snapshots: make(map[string]time.Time),
+		done:      make(chan struct{}),
+	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	return s
+}
+
+// start subscribes to the bus
+func (s *Stream) start() {
+	s.subscription = append(s.subscription, s.bus.Subscribe(events.TypeMetrics, s.push))
+	for _, eventType := range StreamTypes {
+		s.subscription = append(s.subscription, s.bus.Subscribe(eventType, s.push))
+	}
+}
+
+// stop unsubscribes and closes the connections, waiting for the clients
+// to be told
+func (s *Stream) stop() {
+	s.stopOnce.Do(func() {
+		for _, id := range s.subscription {
+			s.bus.Unsubscribe(id)
+		}
+		s.mutex.Lock()
+		s.stopped = true
+		s.mutex.Unlock()
+		close(s.done)
+		s.connections.Wait()
+	})
+}
+
+// checkOrigin accepts requests from the allowed origins; those without an
+// origin do not come from a browser
+func (s *Stream) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// push encodes an event, or a snapshot of the state at a metrics update,
+// and queues it for the clients that want it
+func (s *Stream) push(event events.Event) {
+	if atomic.LoadInt64(&s.count) == 0 {
+		return
+	}
+	message := Message{Type: string(event.Type()), Symbol: strings.ToLower(events.SymbolOf(event)), Data: event}
+	switch ev := event.(type) {
+	case *events.MetricsEvent:
+		if !s.snapshotDue(message.Symbol) {
+			return
+		}
+		message.Type = TypeState
+		message.Data = s.snapshot(ev)
+	case *events.SignalEvent:
+		signal := *ev.Signal
+		if signal.Metrics != nil {
+			signal.Metrics = signal.Metrics.Finite()
+		}
+		message.Data = &signal
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		s.logger.Debug(fmt.Sprintf("Stream skipped %s message: %v", message.Type, err))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for client := range s.clients {
+		if len(client.types) > 0 && !client.types[message.Type] {
+			continue
+		}
+		if client.symbol != "" && client.symbol != message.Symbol {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			client.dropped++
+		}
+	}
+}
+
+// snapshotDue returns whether a state snapshot of symbol is due, marking
+// it pushed if so
+func (s *Stream) snapshotDue(symbol string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(s.snapshots[symbol]) < s.options.StateInterval {
+		return false
+	}
+	s.snapshots[symbol] = now
+	return true
+}
+
+// ServeHTTP upgrades a GET /stream request to a WebSocket and pushes the
+// messages to it until either side closes it. The types parameter selects
+// the message types, comma separated (state, signal, order, fill,
+// trade_closed, pattern); symbol selects one symbol.
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client, err := parseStreamClient(r.URL.Query(), s.options.BufferSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !s.register(client) {
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many stream clients"))
+		return
+	}
+	defer s.connections.Done()
+	defer s.unregister(client)
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader has answered the request
+	}
+	defer conn.Close()
+	s.logger.Info(fmt.Sprintf("Stream client %s connected", r.RemoteAddr), "symbol", client.symbol)
+
+	closed := make(chan struct{})
+	go s.read(conn, closed)
+	s.write(conn, client, closed)
+
+	s.mutex.Lock()
+	dropped := client.dropped
+	s.mutex.Unlock()
+	s.logger.Info(fmt.Sprintf("Stream client %s disconnected", r.RemoteAddr), "dropped", dropped)
+}
+
+// parseStreamClient reads the message types and symbol a client selects
+func parseStreamClient(values url.Values, bufferSize int) (*streamClient, error) {
+	client := &streamClient{
+		types:  make(map[string]bool),
+		symbol: strings.ToLower(values.Get("symbol")),
+		send:   make(chan []byte, bufferSize),
+	}
+	known := map[string]bool{TypeState: true}
+	for _, eventType := range StreamTypes {
+		known[string(eventType)] = true
+	}
+	for _, name := range strings.Split(values.Get("types"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown message type %q (want state, signal, order, fill, trade_closed or pattern)", name)
+		}
+		client.types[name] = true
+	}
+	return client, nil
+}
+
+// register adds a client unless the stream is full or stopped
+func (s *Stream) register(client *streamClient) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped || s.options.MaxClients > 0 && len(s.clients) >= s.options.MaxClients {
+		return false
+	}
+	s.connections.Add(1)
+	s.clients[client] = true
+	atomic.StoreInt64(&s.count, int64(len(s.clients)))
+	return true
+}
+
+// unregister removes a client
+func (s *Stream) unregister(client *streamClient) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.clients, client)
+	atomic.StoreInt64(&s.count, int64(len(s.clients)))
+}
+
+// read discards what the client sends, answering its pings, until the
+// connection fails or is closed
+func (s *Stream) read(conn *websocket.Conn, closed chan struct{}) {
+	defer close(closed)
+	conn.SetReadLimit(maxClientMessage)
+	conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// write sends the queued messages and pings to the client until it is
+// gone or the stream stops
+func (s *Stream) write(conn *websocket.Conn, client *streamClient, closed chan struct{}) {
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case data := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.done:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(streamWriteTimeout))
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package asset holds the asset-class presets: how long a market trades in
+// a day and how many days in a year, which sets how volatility and Sharpe
+// ratios are annualized, the exchange calendar it trades on, and the
+// analyzer windows that suit its ticks. Figures annualized with the
+// presets compare across markets: crypto trades 24/7, equities 6.5 hours
+// a session on 252 days, FX 24 hours a day five days a week.
+package asset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"TRADE/pkg/analyzer"
+)
+
+// Class is the preset of an asset class
+type Class struct {
+	Name        string
+	Exchange    string        // Calendar gating entries unless calendar.exchange is set
+	TradingDays float64       // Trading days in a year
+	TradingDay  time.Duration // Trading time in a day
+	WarmupTicks int           // Ticks analyzed before signals are generated
+	Windows     analyzer.Windows
+}
+
+// Default is the class of symbols no class is configured for
+const Default = "crypto"
+
+// classes are the presets known by name
+var classes = map[string]Class{
+	"crypto": {
+		Name:        "crypto",
+		Exchange:    "crypto",
+		TradingDays: 365,
+		TradingDay:  24 * time.Hour,
+		WarmupTicks: 300,
+		Windows:     analyzer.DefaultWindows(),
+	},
+	"equities": {
+		Name:        "equities",
+		Exchange:    "nyse",
+		TradingDays: 252,
+		TradingDay:  6*time.Hour + 30*time.Minute,
+		WarmupTicks: 200,
+		Windows:     analyzer.Windows{RelativeStrength: 300, Trend: 20, ATR: 14},
+	},
+	"fx": {
+		Name:        "fx",
+		Exchange:    "fx",
+		TradingDays: 260,
+		TradingDay:  24 * time.Hour,
+		WarmupTicks: 500,
+		Windows:     analyzer.Windows{RelativeStrength: 1000, Trend: 50, ATR: 20},
+	},
+}
+
+// Names returns the names of the asset classes
+func Names() []string {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the preset of an asset class; empty selects Default
+func Lookup(name string) (Class, error) {
+	if name == "" {
+		name = Default
+	}
+	class, ok := classes[strings.ToLower(name)]
+	if !ok {
+		return Class{}, fmt.Errorf("unknown asset class %q (want %s)", name, strings.Join(Names(), ", "))
+	}
+	return class, nil
+}
+
+// PeriodsPerYear returns the number of periods of the given length in the
+// trading time of a year
+func (c Class) PeriodsPerYear(period time.Duration) float64 {
+	return c.TradingDays * float64(c.TradingDay) / float64(period)
+}
+
+// String describes the class
+func (c Class) String() string {
+	return fmt.Sprintf("%s (%g trading days of %s a year)", c.Name, c.TradingDays, c.TradingDay)
+}
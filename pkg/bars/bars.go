@@ -0,0 +1,138 @@
+// Package bars resamples ticks into OHLCV bars and writes them as CSV or
+// Parquet files for bar-based backtests and external charting tools.
+package bars
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"TRADE/pkg/types"
+)
+
+// Bar is the open, high, low, close and volume of one interval
+type Bar struct {
+	Time      time.Time // Start of the interval (UTC)
+	Open      float64
+	High      float64
+	Low       float64
+	Close     float64
+	Volume    float64
+	AskVolume float64 // Volume of ticks flagged is_ask
+	BidVolume float64
+	Ticks     int64
+}
+
+// Writer writes bars to a file format; Close completes the file but does
+// not close the underlying writer
+type Writer interface {
+	Write(bar Bar) error
+	Close() error
+}
+
+// Resampler aggregates a time-ordered tick stream into bars of a fixed
+// interval. Intervals are aligned to the Unix epoch, so 1h bars start on
+// the hour and 1d bars at midnight UTC.
+type Resampler struct {
+	interval time.Duration
+	fill     bool
+	current  *Bar
+	late     int
+}
+
+// NewResampler creates a resampler for bars of the given interval. With
+// fill, intervals without ticks produce flat bars at the previous close.
+func NewResampler(interval time.Duration, fill bool) (*Resampler, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid bar interval: %s", interval)
+	}
+	return &Resampler{interval: interval, fill: fill}, nil
+}
+
+// Interval returns the bar interval
+func (r *Resampler) Interval() time.Duration {
+	return r.interval
+}
+
+// Add adds a tick and calls emit for every bar the tick completes. Ticks
+// older than the current bar are counted as late and dropped.
+func (r *Resampler) Add(tick *types.TickData, emit func(bar Bar)) {
+	start := tick.Timestamp.UTC().Truncate(r.interval)
+	if r.current != nil && start.Before(r.current.Time) {
+		r.late++
+		return
+	}
+
+	if r.current != nil && start.After(r.current.Time) {
+		emit(*r.current)
+		if r.fill {
+			last := r.current.Close
+			for next := r.current.Time.Add(r.interval); next.Before(start); next = next.Add(r.interval) {
+				emit(Bar{Time: next, Open: last, High: last, Low: last, Close: last})
+			}
+		}
+		r.current = nil
+	}
+
+	if r.current == nil {
+		r.current = &Bar{Time: start, Open: tick.Price, High: tick.Price, Low: tick.Price}
+	}
+	bar := r.current
+	if tick.Price > bar.High {
+		bar.High = tick.Price
+	}
+	if tick.Price < bar.Low {
+		bar.Low = tick.Price
+	}
+	bar.Close = tick.Price
+	bar.Volume += tick.Volume
+	if tick.IsAsk {
+		bar.AskVolume += tick.Volume
+	} else {
+		bar.BidVolume += tick.Volume
+	}
+	bar.Ticks++
+}
+
+// Flush emits the last, possibly incomplete, bar
+func (r *Resampler) Flush(emit func(bar Bar)) {
+	if r.current != nil {
+		emit(*r.current)
+		r.current = nil
+	}
+}
+
+// Late returns the number of out-of-order ticks dropped
+func (r *Resampler) Late() int {
+	return r.late
+}
+
+// ParseInterval parses a bar interval such as 1m, 15m, 4h or 1d
+func ParseInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if d, err := time.ParseDuration(days + "h"); err == nil {
+			return d * 24, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid bar interval: %s (e.g. 1m, 15m, 4h, 1d)", value)
+	}
+	return d, nil
+}
+
+// FormatInterval names an interval for file names: 1m, 4h, 1d
+func FormatInterval(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
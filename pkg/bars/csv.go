@@ -0,0 +1,42 @@
+package bars
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the header of bar CSV files
+var csvHeader = []string{"timestamp", "open", "high", "low", "close", "volume", "ask_volume", "bid_volume", "ticks"}
+
+// CSVWriter writes bars as CSV rows with RFC 3339 timestamps
+type CSVWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSVWriter writes the header and returns a writer for the rows
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{writer: writer, record: make([]string, len(csvHeader))}, nil
+}
+
+// Write writes one bar
+func (c *CSVWriter) Write(bar Bar) error {
+	c.record[0] = bar.Time.UTC().Format(time.RFC3339)
+	for i, value := range []float64{bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, bar.AskVolume, bar.BidVolume} {
+		c.record[i+1] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	c.record[8] = strconv.FormatInt(bar.Ticks, 10)
+	return c.writer.Write(c.record)
+}
+
+// Close flushes the buffered rows
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
@@ -0,0 +1,293 @@
+package bars
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Parquet constants (parquet.thrift)
+const (
+	parquetInt64           = 2
+	parquetDouble          = 5
+	parquetRequired        = 0
+	parquetPlain           = 0
+	parquetRLE             = 3
+	parquetUncompressed    = 0
+	parquetDataPage        = 0
+	parquetTimestampMicros = 10 // ConvertedType
+)
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// parquetColumn is one column of the bar schema with its PLAIN-encoded
+// values
+type parquetColumn struct {
+	name      string
+	kind      int32
+	timestamp bool
+	values    bytes.Buffer
+}
+
+// ParquetWriter writes bars as a Parquet file: one row group of required,
+// PLAIN-encoded, uncompressed columns, readable by pandas, pyarrow, DuckDB
+// and Spark. Bars are buffered until Close.
+type ParquetWriter struct {
+	w       io.Writer
+	columns []*parquetColumn
+	rows    int64
+}
+
+// NewParquetWriter returns a writer producing a Parquet file on w
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	p := &ParquetWriter{w: w}
+	p.columns = append(p.columns, &parquetColumn{name: csvHeader[0], kind: parquetInt64, timestamp: true})
+	for _, name := range csvHeader[1:8] {
+		p.columns = append(p.columns, &parquetColumn{name: name, kind: parquetDouble})
+	}
+	p.columns = append(p.columns, &parquetColumn{name: csvHeader[8], kind: parquetInt64})
+	return p
+}
+
+// Write buffers one bar
+func (p *ParquetWriter) Write(bar Bar) error {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(bar.Time.UnixMicro()))
+	p.columns[0].values.Write(b[:])
+	for i, value := range []float64{bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, bar.AskVolume, bar.BidVolume} {
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(value))
+		p.columns[i+1].values.Write(b[:])
+	}
+	binary.LittleEndian.PutUint64(b[:], uint64(bar.Ticks))
+	p.columns[8].values.Write(b[:])
+	p.rows++
+	return nil
+}
+
+// Close writes the file: the column chunks followed by the footer
+func (p *ParquetWriter) Close() error {
+	offset := int64(len(parquetMagic))
+	if _, err := io.WriteString(p.w, parquetMagic); err != nil {
+		return err
+	}
+
+	// Each column chunk is a single data page
+	offsets := make([]int64, len(p.columns))
+	sizes := make([]int64, len(p.columns))
+	for i, column := range p.columns {
+		header := &thrift{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(column.values.Len()))
+		header.i32(3, int32(column.values.Len()))
+		header.structBegin(5) // DataPageHeader
+		header.i32(1, int32(p.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.structEnd()
+		header.stop()
+
+		offsets[i] = offset
+		sizes[i] = int64(header.buf.Len() + column.values.Len())
+		if _, err := p.w.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(column.values.Bytes()); err != nil {
+			return err
+		}
+		offset += sizes[i]
+	}
+
+	footer := p.footer(offsets, sizes)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	for _, part := range [][]byte{footer, length[:], []byte(parquetMagic)} {
+		if _, err := p.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// footer encodes the FileMetaData
+func (p *ParquetWriter) footer(offsets, sizes []int64) []byte {
+	meta := &thrift{}
+	meta.i32(1, 1) // Format version
+
+	// Schema: the root followed by one leaf per column
+	meta.listBegin(2, thriftStruct, len(p.columns)+1)
+	meta.elemBegin()
+	meta.binary(4, "bar")
+	meta.i32(5, int32(len(p.columns)))
+	meta.elemEnd()
+	for _, column := range p.columns {
+		meta.elemBegin()
+		meta.i32(1, column.kind)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, column.name)
+		if column.timestamp {
+			meta.i32(6, parquetTimestampMicros)
+			meta.structBegin(10) // LogicalType
+			meta.structBegin(8)  // TIMESTAMP
+			meta.boolean(1, true)
+			meta.structBegin(2) // Unit
+			meta.structBegin(2) // MICROS
+			meta.structEnd()
+			meta.structEnd()
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.elemEnd()
+	}
+
+	meta.i64(3, p.rows)
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	meta.listBegin(4, thriftStruct, 1)
+	meta.elemBegin() // RowGroup
+	meta.listBegin(1, thriftStruct, len(p.columns))
+	for i, column := range p.columns {
+		meta.elemBegin() // ColumnChunk
+		meta.i64(2, offsets[i])
+		meta.structBegin(3) // ColumnMetaData
+		meta.i32(1, column.kind)
+		meta.listBegin(2, thriftI32, 2)
+		meta.zigzag(parquetPlain)
+		meta.zigzag(parquetRLE)
+		meta.listBegin(3, thriftBinary, 1)
+		meta.rawString(column.name)
+		meta.i32(4, parquetUncompressed)
+		meta.i64(5, p.rows)
+		meta.i64(6, sizes[i])
+		meta.i64(7, sizes[i])
+		meta.i64(9, offsets[i])
+		meta.structEnd()
+		meta.elemEnd()
+	}
+	meta.i64(2, total)
+	meta.i64(3, p.rows)
+	meta.elemEnd()
+
+	meta.binary(6, "TRADE")
+	meta.stop()
+	return meta.buf.Bytes()
+}
+
+// Thrift compact protocol types
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift encodes structs in the Thrift compact protocol, which Parquet
+// uses for page headers and the footer
+type thrift struct {
+	buf   bytes.Buffer
+	last  int16   // Last field ID of the current struct
+	stack []int16 // Last field IDs of the enclosing structs
+}
+
+// field writes a field header
+func (t *thrift) field(id int16, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+// varint writes an unsigned varint
+func (t *thrift) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// zigzag writes a signed varint
+func (t *thrift) zigzag(v int64) {
+	t.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+// i32 writes an i32 field
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+// i64 writes an i64 field
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+// boolean writes a bool field
+func (t *thrift) boolean(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+// binary writes a string field
+func (t *thrift) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.rawString(v)
+}
+
+// rawString writes a length-prefixed string, e.g. a list element
+func (t *thrift) rawString(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// listBegin writes the header of a list field; its elements follow
+func (t *thrift) listBegin(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | kind)
+	} else {
+		t.buf.WriteByte(0xF0 | kind)
+		t.varint(uint64(size))
+	}
+}
+
+// structBegin starts a struct field
+func (t *thrift) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+// structEnd ends a struct field
+func (t *thrift) structEnd() {
+	t.elemEnd()
+}
+
+// elemBegin starts a struct that is a list element
+func (t *thrift) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// elemEnd ends a struct that is a list element
+func (t *thrift) elemEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop ends the fields of the current struct
+func (t *thrift) stop() {
+	t.buf.WriteByte(0)
+}
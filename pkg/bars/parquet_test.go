@@ -0,0 +1,263 @@
+package bars
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// parquetGolden is the file written for goldenBar. Its page headers and
+// footer were decoded by hand against parquet.thrift.
+const parquetGolden = "" +
+	"504152311500151015102c1502150015061506000000902db6fb2f06001500151015102c150215001506150600000000" +
+	"0000006ae8401500151015102c15021500150615060000000000009076e8401500151015102c15021500150615060000" +
+	"00000000c863e8401500151015102c15021500150615060000000000004070e8401500151015102c1502150015061506" +
+	"0000000000000000f83f1500151015102c15021500150615060000000000000000e03f1500151015102c150215001506" +
+	"15060000000000000000f03f1500151015102c150215001506150600002a00000000000000150219ac48036261721512" +
+	"0015042500180974696d657374616d7025144c8c111c2c0000000000150a250018046f70656e00150a25001804686967" +
+	"6800150a250018036c6f7700150a25001805636c6f736500150a25001806766f6c756d6500150a2500180a61736b5f76" +
+	"6f6c756d6500150a2500180a6269645f766f6c756d65001504250018057469636b73001602191c199c26081c15041925" +
+	"000619180974696d657374616d70150016021632163226080000263a1c150a192500061918046f70656e150016021632" +
+	"1632263a0000266c1c150a19250006191804686967681500160216321632266c0000269e011c150a192500061918036c" +
+	"6f771500160216321632269e01000026d0011c150a19250006191805636c6f7365150016021632163226d00100002682" +
+	"021c150a19250006191806766f6c756d651500160216321632268202000026b4021c150a1925000619180a61736b5f76" +
+	"6f6c756d65150016021632163226b402000026e6021c150a1925000619180a6269645f766f6c756d6515001602163216" +
+	"3226e60200002698031c1504192500061918057469636b731500160216321632269803000016c2031602002805545241" +
+	"444500be01000050415231"
+
+var goldenBar = Bar{
+	Time:      time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+	Open:      50000,
+	High:      50100.5,
+	Low:       49950.25,
+	Close:     50050,
+	Volume:    1.5,
+	AskVolume: 0.5,
+	BidVolume: 1,
+	Ticks:     42,
+}
+
+func writeParquet(t *testing.T, bars ...Bar) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf)
+	for _, bar := range bars {
+		if err := w.Write(bar); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParquetMatchesGolden(t *testing.T) {
+	want, err := hex.DecodeString(parquetGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := writeParquet(t, goldenBar); !bytes.Equal(got, want) {
+		t.Errorf("wrote\n%x\nwant\n%x", got, want)
+	}
+}
+
+func TestParquetFooterSchemaAndValues(t *testing.T) {
+	bars := []Bar{
+		goldenBar,
+		{Time: goldenBar.Time.Add(time.Minute), Open: 50050, High: 50060, Low: 49990.75, Close: 50000.125, Volume: 0.25, BidVolume: 0.25, Ticks: 3},
+		{Time: goldenBar.Time.Add(2 * time.Minute), Open: 50000.125, High: 50000.125, Low: 50000.125, Close: 50000.125},
+	}
+	file := writeParquet(t, bars...)
+
+	if string(file[:4]) != parquetMagic || string(file[len(file)-4:]) != parquetMagic {
+		t.Fatalf("file does not start and end with %s", parquetMagic)
+	}
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &thriftReader{t: t, buf: file[len(file)-8-length : len(file)-8]}
+	meta := footer.structure()
+	if footer.pos != length {
+		t.Fatalf("footer decoded %d of %d bytes", footer.pos, length)
+	}
+
+	if meta[1] != int64(1) {
+		t.Errorf("version = %v, want 1", meta[1])
+	}
+	if meta[3] != int64(len(bars)) {
+		t.Errorf("num_rows = %v, want %d", meta[3], len(bars))
+	}
+	if meta[6] != "TRADE" {
+		t.Errorf("created_by = %v, want TRADE", meta[6])
+	}
+
+	// Schema: the root and one required leaf per CSV column
+	schema := meta[2].([]any)
+	root := schema[0].(map[int16]any)
+	if root[4] != "bar" || root[5] != int64(len(csvHeader)) {
+		t.Errorf("schema root = %v, want bar with %d children", root, len(csvHeader))
+	}
+	if len(schema) != len(csvHeader)+1 {
+		t.Fatalf("schema has %d elements, want %d", len(schema), len(csvHeader)+1)
+	}
+	kinds := []int64{parquetInt64, parquetDouble, parquetDouble, parquetDouble, parquetDouble, parquetDouble, parquetDouble, parquetDouble, parquetInt64}
+	for i, element := range schema[1:] {
+		leaf := element.(map[int16]any)
+		if leaf[4] != csvHeader[i] || leaf[1] != kinds[i] || leaf[3] != int64(parquetRequired) {
+			t.Errorf("schema leaf %d = %v, want required %s of type %d", i, leaf, csvHeader[i], kinds[i])
+		}
+	}
+	timestamp := schema[1].(map[int16]any)
+	if timestamp[6] != int64(parquetTimestampMicros) {
+		t.Errorf("timestamp converted_type = %v, want TIMESTAMP_MICROS", timestamp[6])
+	}
+	// LogicalType TIMESTAMP(isAdjustedToUTC=true, unit=MICROS)
+	logical := map[int16]any{8: map[int16]any{1: true, 2: map[int16]any{2: map[int16]any{}}}}
+	if !reflect.DeepEqual(timestamp[10], logical) {
+		t.Errorf("timestamp logicalType = %v, want %v", timestamp[10], logical)
+	}
+
+	// One row group whose column chunks each hold a single PLAIN data page
+	groups := meta[4].([]any)
+	if len(groups) != 1 {
+		t.Fatalf("%d row groups, want 1", len(groups))
+	}
+	group := groups[0].(map[int16]any)
+	if group[3] != int64(len(bars)) {
+		t.Errorf("row group num_rows = %v, want %d", group[3], len(bars))
+	}
+	chunks := group[1].([]any)
+	if len(chunks) != len(csvHeader) {
+		t.Fatalf("%d column chunks, want %d", len(chunks), len(csvHeader))
+	}
+	var total int64
+	for i, chunk := range chunks {
+		column := chunk.(map[int16]any)[3].(map[int16]any)
+		if !reflect.DeepEqual(column[3], []any{csvHeader[i]}) || column[1] != kinds[i] || column[4] != int64(parquetUncompressed) {
+			t.Errorf("column %d metadata = %v", i, column)
+		}
+		if column[5] != int64(len(bars)) {
+			t.Errorf("column %s num_values = %v, want %d", csvHeader[i], column[5], len(bars))
+		}
+		offset, size := column[9].(int64), column[7].(int64)
+		total += size
+
+		page := &thriftReader{t: t, buf: file[offset : offset+size]}
+		header := page.structure()
+		data := header[5].(map[int16]any)
+		if header[1] != int64(parquetDataPage) || data[1] != int64(len(bars)) || data[2] != int64(parquetPlain) {
+			t.Errorf("column %s page header = %v", csvHeader[i], header)
+		}
+		values := page.buf[page.pos:]
+		if int64(len(values)) != header[3] || len(values) != 8*len(bars) {
+			t.Fatalf("column %s holds %d bytes of values, page size %v", csvHeader[i], len(values), header[3])
+		}
+		for row, bar := range bars {
+			raw := binary.LittleEndian.Uint64(values[8*row:])
+			var got, want any
+			switch i {
+			case 0:
+				got, want = int64(raw), bar.Time.UnixMicro()
+			case 8:
+				got, want = int64(raw), bar.Ticks
+			default:
+				got = math.Float64frombits(raw)
+				want = []float64{bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, bar.AskVolume, bar.BidVolume}[i-1]
+			}
+			if got != want {
+				t.Errorf("row %d %s = %v, want %v", row, csvHeader[i], got, want)
+			}
+		}
+	}
+	if group[2] != total {
+		t.Errorf("row group total_byte_size = %v, want %d", group[2], total)
+	}
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps of field
+// ID to value, independently of the writer's encoder
+type thriftReader struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.buf) {
+		r.t.Fatalf("thrift: unexpected end at %d", r.pos)
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("thrift: bad varint at %d", r.pos)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) structure() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		switch kind := header & 0x0F; kind {
+		case thriftTrue:
+			fields[id] = true
+		case thriftFalse:
+			fields[id] = false
+		default:
+			fields[id] = r.value(kind)
+		}
+	}
+}
+
+func (r *thriftReader) value(kind byte) any {
+	switch kind {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.uvarint())
+		if r.pos+n > len(r.buf) {
+			r.t.Fatalf("thrift: string past end at %d", r.pos)
+		}
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0F)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	r.t.Fatalf("thrift: unsupported type %d at %d", kind, r.pos)
+	return nil
+}
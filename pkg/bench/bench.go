@@ -0,0 +1,226 @@
+// Package bench times the tick hot path: a tick entering the market data,
+// the analyzer turning it into metrics and the strategy checking them for
+// a signal. Each stage is timed on its own and as a whole, so a latency
+// regression can be traced to the stage that caused it. The throughput and
+// allocations of the stages are measured by the Go benchmarks next to
+// their code (go test -bench . -benchmem ./...), on the same fixtures.
+package bench
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"TRADE/pkg/analyzer"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+	"TRADE/pkg/strategy"
+	"TRADE/pkg/types"
+)
+
+// HistorySize is the number of ticks the market data keeps, filled before
+// anything is measured so every stage works on full windows
+const HistorySize = 1000
+
+// Options configures a benchmark run
+type Options struct {
+	Ticks int   // Ticks timed one by one for the latency percentiles
+	Seed  int64 // Seed of the generated price path
+}
+
+// Result is the latency of a stage per tick
+type Result struct {
+	Name  string        `json:"name"`
+	Ticks int           `json:"ticks"` // Ticks the latencies are taken over
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// stage is a part of the hot path; setup returns the function processing
+// one tick, with the market data already holding a full history
+type stage struct {
+	name  string
+	setup func(ticks []types.TickData) func(tick *types.TickData)
+}
+
+// stages are the benchmarked parts of the hot path, the whole path last
+var stages = []stage{
+	{"market", setupMarket},
+	{"analyzer", setupAnalyzer},
+	{"strategy", setupStrategy},
+	{"pipeline", Pipeline},
+}
+
+// Run times every stage of the hot path
+func Run(opts Options) []Result {
+	if opts.Ticks <= 0 {
+		opts.Ticks = 100000
+	}
+	ticks := Ticks(HistorySize+opts.Ticks, opts.Seed)
+
+	results := make([]Result, 0, len(stages))
+	for _, stage := range stages {
+		results = append(results, measure(stage, ticks, opts.Ticks))
+	}
+	return results
+}
+
+// measure times the ticks of a stage one by one for the latency
+// percentiles
+func measure(stage stage, ticks []types.TickData, timed int) Result {
+	process := stage.setup(ticks[:HistorySize])
+	latencies := make([]time.Duration, timed)
+	var total time.Duration
+	for i := range latencies {
+		start := time.Now()
+		process(&ticks[HistorySize+i])
+		latencies[i] = time.Since(start)
+		total += latencies[i]
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return Result{
+		Name:  stage.name,
+		Ticks: timed,
+		Mean:  total / time.Duration(timed),
+		P50:   percentile(latencies, 0.50),
+		P99:   percentile(latencies, 0.99),
+		Max:   latencies[len(latencies)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+}
+
+// Ticks generates a reproducible random-walk price path with alternating
+// trends, so the strategy sees both entries and exits
+func Ticks(n int, seed int64) []types.TickData {
+	rng := rand.New(rand.NewSource(seed))
+	ticks := make([]types.TickData, n)
+	price := 50000.0
+	drift := 0.0
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range ticks {
+		if i%500 == 0 {
+			drift = (rng.Float64() - 0.5) * 0.0002
+		}
+		price *= 1 + drift + rng.NormFloat64()*0.0003
+		ticks[i] = types.TickData{
+			Price:     price,
+			Volume:    rng.ExpFloat64() * 0.05,
+			IsAsk:     rng.Intn(2) == 0,
+			Timestamp: start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}
+	}
+	return ticks
+}
+
+// Logger returns a logger dropping everything below errors, as the hot
+// path should not be measured writing logs
+func Logger() logger.Interface {
+	return logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+// NewMarket returns market data holding the history ticks
+func NewMarket(bus *events.Bus, history []types.TickData) *market.MarketData {
+	md := market.NewMarketData("btcusdt", Logger(), bus)
+	for i := range history {
+		AddTick(md, &history[i])
+	}
+	return md
+}
+
+// AddTick adds a copy of a tick taken from the pool, as the feeds do
+func AddTick(md *market.MarketData, tick *types.TickData) {
+	pooled := market.NewTick()
+	*pooled = *tick
+	md.AddTick(pooled)
+}
+
+// NewAnalyzer returns an analyzer past its warmup
+func NewAnalyzer(md *market.MarketData) *analyzer.Analyzer {
+	a := analyzer.NewAnalyzer(md, Logger())
+	a.SetWarmupTicks(HistorySize)
+	a.ProcessTick(nil)
+	return a
+}
+
+// NewEngine returns an engine running the default strategy
+func NewEngine(a *analyzer.Analyzer) *strategy.Engine {
+	engine, err := strategy.NewEngine(strategy.DefaultStrategy, a, Logger())
+	if err != nil {
+		panic(err)
+	}
+	return engine
+}
+
+// setupMarket measures storing and publishing a tick without subscribers
+func setupMarket(history []types.TickData) func(tick *types.TickData) {
+	md := NewMarket(events.NewBus(), history)
+	return func(tick *types.TickData) {
+		AddTick(md, tick)
+	}
+}
+
+// setupAnalyzer measures calculating the metrics over the full history
+func setupAnalyzer(history []types.TickData) func(tick *types.TickData) {
+	a := NewAnalyzer(NewMarket(events.NewBus(), history))
+	return func(tick *types.TickData) {
+		a.ProcessTick(tick)
+	}
+}
+
+// setupStrategy measures checking metrics for a signal, with the metrics
+// of the history
+func setupStrategy(history []types.TickData) func(tick *types.TickData) {
+	metrics := NewAnalyzer(NewMarket(events.NewBus(), history)).GetMetrics()
+	s := NewEngine(nil)
+	return func(tick *types.TickData) {
+		s.GenerateSignal(context.Background(), tick.Price, tick.Timestamp, metrics)
+	}
+}
+
+// Pipeline returns the processing of a tick along the whole path, wired
+// through the event bus as the manager wires it: tick, metrics, signal
+func Pipeline(history []types.TickData) func(tick *types.TickData) {
+	bus := events.NewBus()
+	md := NewMarket(bus, history)
+	a := NewAnalyzer(md)
+	s := NewEngine(a)
+
+	bus.Subscribe(events.TypeTick, func(event events.Event) {
+		tickEvent := event.(*events.TickEvent)
+		metrics := a.ProcessTick(tickEvent.Tick)
+		if metrics == nil {
+			return
+		}
+		bus.Publish(&events.MetricsEvent{
+			Symbol:    tickEvent.Symbol,
+			Price:     tickEvent.Tick.Price,
+			Timestamp: tickEvent.Tick.Timestamp,
+			Metrics:   metrics,
+		})
+	})
+	bus.Subscribe(events.TypeMetrics, func(event events.Event) {
+		metricsEvent := event.(*events.MetricsEvent)
+		if !a.HasSufficientData() {
+			return
+		}
+		s.GenerateSignal(context.Background(), metricsEvent.Price, metricsEvent.Timestamp, metricsEvent.Metrics)
+	})
+	return func(tick *types.TickData) {
+		AddTick(md, tick)
+	}
+}
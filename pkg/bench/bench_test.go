@@ -0,0 +1,16 @@
+package bench
+
+import "testing"
+
+// BenchmarkPipeline measures a tick along the whole path, from the market
+// data through the analyzer to the strategy
+func BenchmarkPipeline(b *testing.B) {
+	ticks := Ticks(2*HistorySize, 1)
+	process := Pipeline(ticks[:HistorySize])
+	replay := ticks[HistorySize:]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		process(&replay[i%len(replay)])
+	}
+}
@@ -0,0 +1,169 @@
+// Package calendar models exchange trading sessions and holidays, and the
+// time zone timestamps are displayed in.
+//
+// Timestamps are kept in UTC inside the system. A Calendar interprets them
+// in its exchange's local time to decide whether the market is open and
+// which trading day they belong to (for daily resets); Display converts
+// them to the configured display zone for logs, status and reports.
+package calendar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session is a trading session within a day, as wall-clock offsets from
+// midnight in the exchange's time zone. Close is exclusive and at most 24h.
+type Session struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// date is a calendar day
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// dateOf returns the calendar day of t in its location
+func dateOf(t time.Time) date {
+	year, month, day := t.Date()
+	return date{year, month, day}
+}
+
+// HolidayRule returns the holidays of a year, as dates in any location
+type HolidayRule func(year int) []time.Time
+
+// Calendar holds the weekly sessions and the holidays of an exchange
+type Calendar struct {
+	name     string
+	location *time.Location
+	weekly   [7][]Session // Indexed by time.Weekday
+	rule     HolidayRule
+	holidays map[date]bool
+	years    map[int]bool // Years whose rule holidays are in holidays
+	mutex    sync.Mutex
+}
+
+// New creates a calendar trading the given sessions on each weekday, in
+// the exchange's location. rule may be nil.
+func New(name string, location *time.Location, weekly map[time.Weekday][]Session, rule HolidayRule) *Calendar {
+	c := &Calendar{
+		name:     name,
+		location: location,
+		rule:     rule,
+		holidays: make(map[date]bool),
+		years:    make(map[int]bool),
+	}
+	for weekday, sessions := range weekly {
+		c.weekly[weekday] = append([]Session(nil), sessions...)
+	}
+	return c
+}
+
+// Name returns the exchange name
+func (c *Calendar) Name() string {
+	return c.name
+}
+
+// Location returns the exchange's time zone
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// AddHolidays closes the exchange on the given days (taken as dates,
+// regardless of their location)
+func (c *Calendar) AddHolidays(days ...time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, day := range days {
+		c.holidays[dateOf(day)] = true
+	}
+}
+
+// IsHoliday reports whether t falls on an exchange holiday
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	local := t.In(c.location)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.rule != nil && !c.years[local.Year()] {
+		for _, day := range c.rule(local.Year()) {
+			c.holidays[dateOf(day)] = true
+		}
+		c.years[local.Year()] = true
+	}
+	return c.holidays[dateOf(local)]
+}
+
+// IsTradingDay reports whether the exchange has sessions on t's day
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	local := t.In(c.location)
+	return len(c.weekly[local.Weekday()]) > 0 && !c.IsHoliday(local)
+}
+
+// IsOpen reports whether the exchange is in session at t
+func (c *Calendar) IsOpen(t time.Time) bool {
+	local := t.In(c.location)
+	if !c.IsTradingDay(local) {
+		return false
+	}
+	clock := wallClock(local)
+	for _, session := range c.weekly[local.Weekday()] {
+		if clock >= session.Open && clock < session.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t if the exchange is open, otherwise the start of the
+// next session. It returns the zero time if no session starts within a
+// year (e.g. a calendar without sessions).
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	if c.IsOpen(t) {
+		return t
+	}
+	local := t.In(c.location)
+	for offset := 0; offset <= 366; offset++ {
+		day := local.AddDate(0, 0, offset)
+		if !c.IsTradingDay(day) {
+			continue
+		}
+		for _, session := range c.weekly[day.Weekday()] {
+			open := at(day, session.Open)
+			if open.After(t) {
+				return open
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// TradingDay returns midnight (exchange time) of the day t belongs to;
+// daily statistics and limits reset when it changes
+func (c *Calendar) TradingDay(t time.Time) time.Time {
+	local := t.In(c.location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+}
+
+// String describes the calendar
+func (c *Calendar) String() string {
+	return fmt.Sprintf("%s (%s)", c.name, c.location)
+}
+
+// wallClock returns the time of day shown on a clock at t
+func wallClock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// at returns the instant the clock shows offset on day, in day's location
+func at(day time.Time, offset time.Duration) time.Time {
+	hours, minutes := offset/time.Hour, offset%time.Hour/time.Minute
+	return time.Date(day.Year(), day.Month(), day.Day(), int(hours), int(minutes), 0,
+		int(offset%time.Minute), day.Location())
+}
@@ -0,0 +1,232 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hm returns a wall-clock offset of hours and minutes
+func hm(hours, minutes int) time.Duration {
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
+
+// weekdays trades the sessions Monday to Friday
+func weekdays(sessions ...Session) map[time.Weekday][]Session {
+	weekly := make(map[time.Weekday][]Session)
+	for day := time.Monday; day <= time.Friday; day++ {
+		weekly[day] = sessions
+	}
+	return weekly
+}
+
+// exchanges builds the calendars known by name
+var exchanges = map[string]func() *Calendar{
+	"crypto": Crypto,
+	"nyse":   NYSE,
+	"nasdaq": func() *Calendar { c := NYSE(); c.name = "nasdaq"; return c },
+	"lse":    LSE,
+	"tse":    TSE,
+	"fx":     FX,
+}
+
+// Exchanges returns the names of the built-in calendars
+func Exchanges() []string {
+	names := make([]string, 0, len(exchanges))
+	for name := range exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a new calendar of a built-in exchange
+func Lookup(name string) (*Calendar, error) {
+	build, ok := exchanges[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown exchange calendar: %s (known: %s)", name, strings.Join(Exchanges(), ", "))
+	}
+	return build(), nil
+}
+
+// Load returns the calendar of a built-in exchange with extra holidays
+// given as 2006-01-02 dates
+func Load(exchange string, holidays []string) (*Calendar, error) {
+	c, err := Lookup(exchange)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range holidays {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: want YYYY-MM-DD", value)
+		}
+		c.AddHolidays(day)
+	}
+	return c, nil
+}
+
+// Crypto trades around the clock every day; its trading days are UTC days
+func Crypto() *Calendar {
+	weekly := make(map[time.Weekday][]Session)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		weekly[day] = []Session{{Open: 0, Close: 24 * time.Hour}}
+	}
+	return New("crypto", time.UTC, weekly, nil)
+}
+
+// NYSE trades 09:30-16:00 New York time on weekdays, closed on the NYSE
+// holidays. Early closes are not modeled.
+func NYSE() *Calendar {
+	return New("nyse", mustLoad("America/New_York"),
+		weekdays(Session{Open: hm(9, 30), Close: hm(16, 0)}), nyseHolidays)
+}
+
+// LSE trades 08:00-16:30 London time on weekdays, closed on English bank
+// holidays
+func LSE() *Calendar {
+	return New("lse", mustLoad("Europe/London"),
+		weekdays(Session{Open: hm(8, 0), Close: hm(16, 30)}), lseHolidays)
+}
+
+// TSE trades 09:00-11:30 and 12:30-15:30 Tokyo time on weekdays, closed
+// over the new year (Dec 31 - Jan 3). Japanese national holidays must be
+// configured as holidays.
+func TSE() *Calendar {
+	return New("tse", mustLoad("Asia/Tokyo"),
+		weekdays(Session{Open: hm(9, 0), Close: hm(11, 30)}, Session{Open: hm(12, 30), Close: hm(15, 30)}),
+		tseHolidays)
+}
+
+// FX trades around the clock from the Sunday 17:00 New York open to the
+// Friday 17:00 close. Its trading days are New York days, so the Sunday
+// evening is a short trading day of its own.
+func FX() *Calendar {
+	weekly := weekdays(Session{Open: 0, Close: 24 * time.Hour})
+	weekly[time.Sunday] = []Session{{Open: hm(17, 0), Close: 24 * time.Hour}}
+	weekly[time.Friday] = []Session{{Open: 0, Close: hm(17, 0)}}
+	return New("fx", mustLoad("America/New_York"), weekly, nil)
+}
+
+// nyseHolidays returns the NYSE holidays of a year
+func nyseHolidays(year int) []time.Time {
+	easter := easterSunday(year)
+	days := []time.Time{
+		day(year, time.January, 1),
+		nthWeekday(year, time.January, time.Monday, 3),  // Martin Luther King Jr. Day
+		nthWeekday(year, time.February, time.Monday, 3), // Washington's Birthday
+		easter.AddDate(0, 0, -2),                        // Good Friday
+		lastWeekday(year, time.May, time.Monday),        // Memorial Day
+		day(year, time.July, 4),
+		nthWeekday(year, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(year, time.November, time.Thursday, 4), // Thanksgiving
+		day(year, time.December, 25),
+	}
+	if year >= 2022 {
+		days = append(days, day(year, time.June, 19)) // Juneteenth
+	}
+
+	// Saturday holidays are observed on Friday and Sunday ones on Monday,
+	// except that New Year's Day is not moved back into December
+	var observed []time.Time
+	for _, holiday := range days {
+		switch holiday.Weekday() {
+		case time.Saturday:
+			if holiday.Month() != time.January || holiday.Day() != 1 {
+				observed = append(observed, holiday.AddDate(0, 0, -1))
+			}
+		case time.Sunday:
+			observed = append(observed, holiday.AddDate(0, 0, 1))
+		default:
+			observed = append(observed, holiday)
+		}
+	}
+	return observed
+}
+
+// lseHolidays returns the English bank holidays of a year
+func lseHolidays(year int) []time.Time {
+	easter := easterSunday(year)
+	newYear := day(year, time.January, 1)
+	switch newYear.Weekday() {
+	case time.Saturday:
+		newYear = newYear.AddDate(0, 0, 2)
+	case time.Sunday:
+		newYear = newYear.AddDate(0, 0, 1)
+	}
+
+	// Christmas and Boxing Day move to the next weekdays not already taken
+	christmas, boxing := day(year, time.December, 25), day(year, time.December, 26)
+	switch christmas.Weekday() {
+	case time.Friday:
+		boxing = day(year, time.December, 28)
+	case time.Saturday:
+		christmas, boxing = day(year, time.December, 27), day(year, time.December, 28)
+	case time.Sunday:
+		christmas = day(year, time.December, 27)
+	}
+
+	return []time.Time{
+		newYear,
+		easter.AddDate(0, 0, -2), // Good Friday
+		easter.AddDate(0, 0, 1),  // Easter Monday
+		nthWeekday(year, time.May, time.Monday, 1),  // Early May bank holiday
+		lastWeekday(year, time.May, time.Monday),    // Spring bank holiday
+		lastWeekday(year, time.August, time.Monday), // Summer bank holiday
+		christmas,
+		boxing,
+	}
+}
+
+// tseHolidays returns the TSE new year closure of a year
+func tseHolidays(year int) []time.Time {
+	return []time.Time{
+		day(year, time.January, 1), day(year, time.January, 2), day(year, time.January, 3),
+		day(year, time.December, 31),
+	}
+}
+
+// day returns a date
+func day(year int, month time.Month, dayOfMonth int) time.Time {
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the nth given weekday of a month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := day(year, month, 1)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last given weekday of a month
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := day(year, month+1, 0)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easterSunday returns Western Easter Sunday (anonymous Gregorian algorithm)
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	dayOfMonth := (h+l-7*m+114)%31 + 1
+	return day(year, time.Month(month), dayOfMonth)
+}
+
+// mustLoad loads a time zone from the embedded database
+func mustLoad(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("calendar: time zone %s: %v", name, err))
+	}
+	return location
+}
@@ -0,0 +1,53 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	_ "time/tzdata" // Zones resolve even on hosts without a zoneinfo database
+)
+
+// display is the *time.Location timestamps are shown in
+var display atomic.Pointer[time.Location]
+
+// LoadLocation resolves a time zone name: an IANA name such as
+// "America/New_York", "UTC", or "" / "Local" for the host's zone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", name)
+	}
+	return location, nil
+}
+
+// SetDisplayLocation sets the zone logs, status and reports show times in
+func SetDisplayLocation(location *time.Location) {
+	display.Store(location)
+}
+
+// DisplayLocation returns the display zone (the host's zone by default)
+func DisplayLocation() *time.Location {
+	if location := display.Load(); location != nil {
+		return location
+	}
+	return time.Local
+}
+
+// Display converts a timestamp to the display zone
+func Display(t time.Time) time.Time {
+	return t.In(DisplayLocation())
+}
+
+// ZoneName names a zone for readers; the host's zone is shown as its
+// abbreviation and current offset
+func ZoneName(location *time.Location) string {
+	if location == time.Local {
+		return time.Now().Format("MST -07:00")
+	}
+	return location.String()
+}
@@ -0,0 +1,9 @@
+package config
+
+import _ "embed"
+
+// Example is the default configuration with every setting commented, as
+// written by "trade config init"
+//
+//go:embed config.example.yaml
+var Example []byte
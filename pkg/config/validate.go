@@ -0,0 +1,332 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadStrict reads a YAML configuration file on top of the defaults like
+// Load, but rejects keys that are not settings, such as misspelled ones.
+// Every unknown key is reported.
+func LoadStrict(path string) (*Config, []error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read config file: %v", err)}
+	}
+
+	cfg := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return cfg, nil
+	case errors.As(err, &typeErr):
+		problems := make([]error, 0, len(typeErr.Errors))
+		for _, message := range typeErr.Errors {
+			problems = append(problems, errors.New(message))
+		}
+		return cfg, problems
+	default:
+		return nil, []error{fmt.Errorf("failed to parse config file: %v", err)}
+	}
+}
+
+// Validate checks the settings for values out of their range, returning
+// every problem found. Names of levels, exchanges and other values parsed
+// by the components are checked by those components.
+func (c *Config) Validate() []error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	trading := c.Trading
+	check(trading.Symbol != "", "trading.symbol is required")
+	check(trading.Strategy != "", "trading.strategy is required")
+	check(trading.Capital > 0 || len(c.Accounts) > 0, "trading.capital must be positive")
+	check(trading.TickSize > 0, "trading.tick_size must be positive")
+	check(trading.LotSize > 0, "trading.lot_size must be positive")
+	check(trading.WarmupTicks >= 0, "trading.warmup_ticks cannot be negative")
+	windows := trading.Windows
+	check(windows.RelativeStrength == 0 || windows.RelativeStrength >= 2, "trading.windows.relative_strength must be at least 2")
+	check(windows.Trend == 0 || windows.Trend >= 3, "trading.windows.trend must be at least 3")
+	check(windows.ATR >= 0, "trading.windows.atr cannot be negative")
+	for symbol := range c.Market.AssetClasses {
+		check(slices.Contains(c.Market.Symbols, symbol), "market.asset_classes.%s: %s is not in market.symbols", symbol, symbol)
+	}
+
+	if book := c.Market.OrderBook; book.Enabled {
+		check(book.Levels > 0, "market.order_book.levels must be positive")
+		check(book.SnapshotLimit >= book.Levels && book.SnapshotLimit <= 5000,
+			"market.order_book.snapshot_limit must be between market.order_book.levels and 5000")
+	}
+	backpressure := c.Market.Backpressure
+	check(backpressure.QueueSize > 0, "market.backpressure.queue_size must be positive")
+	switch backpressure.Policy {
+	case "", "coalesce", "drop_oldest", "drop_newest", "block":
+	default:
+		check(false, "market.backpressure.policy must be coalesce, drop_oldest, drop_newest or block, not %q", backpressure.Policy)
+	}
+
+	levels := c.Strategy.BookImbalance.Levels
+	check(levels == 5 || levels == 10 || levels == 20, "strategy.book_imbalance.levels must be 5, 10 or 20")
+	window := c.Strategy.BookImbalance.TrendWindow
+	check(window > 0 && window <= time.Minute, "strategy.book_imbalance.trend_window must be positive and at most 1m")
+	total, unweighted := 0.0, false
+	for _, instance := range c.Strategy.Instances {
+		check(instance.Weight >= 0 && instance.Weight <= 1, "strategy.instances weight of %s must be between 0 and 1", instance.Name)
+		total += instance.Weight
+		unweighted = unweighted || instance.Weight == 0
+	}
+	check(total <= 1+1e-9, "strategy.instances weights add up to %.2f, over 1", total)
+	check(!unweighted || total < 1-1e-9, "strategy.instances weights leave nothing to the instances without one")
+	if divergence := c.Strategy.Divergence; divergence.Enabled {
+		check(divergence.Separation > 0, "strategy.divergence.separation must be positive")
+		check(divergence.Lookback > divergence.Separation, "strategy.divergence.lookback must be greater than strategy.divergence.separation")
+	}
+	if timeframes := c.Strategy.Timeframes; len(timeframes.Timeframes()) > 0 {
+		check(timeframes.Period >= 2, "strategy.timeframes.period must be at least 2")
+		check(timeframes.MinTrend >= 0, "strategy.timeframes.min_trend cannot be negative")
+	}
+	if patterns := c.Strategy.Patterns; patterns.Enabled {
+		check(patterns.Timeframe != "", "strategy.patterns.timeframe is required")
+		check(patterns.Recent >= 1, "strategy.patterns.recent must be at least 1")
+	}
+	if indicators := c.Strategy.Indicators; indicators.Enabled {
+		check(indicators.RSIPeriod > 0, "strategy.indicators.rsi_period must be positive")
+		macd := indicators.MACD
+		check(macd.Fast > 0 && macd.Signal > 0, "strategy.indicators.macd periods must be positive")
+		check(macd.Fast < macd.Slow, "strategy.indicators.macd.fast must be shorter than strategy.indicators.macd.slow")
+		check(indicators.Bollinger.Period >= 2, "strategy.indicators.bollinger.period must be at least 2")
+		check(indicators.Bollinger.Deviations > 0, "strategy.indicators.bollinger.deviations must be positive")
+		crossover := indicators.Crossover
+		check(crossover.Type == "ema" || crossover.Type == "sma", "strategy.indicators.crossover.type must be ema or sma, not %q", crossover.Type)
+		check(crossover.Fast > 0, "strategy.indicators.crossover periods must be positive")
+		check(crossover.Fast < crossover.Slow, "strategy.indicators.crossover.fast must be shorter than strategy.indicators.crossover.slow")
+	}
+	if sizing := c.Strategy.Sizing; sizing.Mode != "" && sizing.Mode != "none" {
+		check(sizing.Equity >= 0, "strategy.sizing.equity cannot be negative")
+		check(sizing.RiskPercent > 0 && sizing.RiskPercent <= 100, "strategy.sizing.risk_percent must be above 0 and at most 100")
+	}
+
+	check(c.Logging.DedupWindow >= 0, "logging.dedup_window cannot be negative")
+	check(c.Status.Interval > 0, "status.interval must be positive")
+	check(c.Status.Portfolio.TopRisks >= 0, "status.portfolio.top_risks cannot be negative")
+
+	execution := c.Execution
+	check(execution.PollInterval > 0, "execution.poll_interval must be positive")
+	check(execution.OrderTimeout > 0, "execution.order_timeout must be positive")
+	check(execution.RecvWindow > 0, "execution.recv_window must be positive")
+	check(execution.Paper.SlippageBps >= 0, "execution.paper.slippage_bps cannot be negative")
+	check(execution.Paper.FeeRate >= 0 && execution.Paper.FeeRate < 1, "execution.paper.fee_rate must be in [0, 1)")
+	check(execution.Paper.Latency >= 0, "execution.paper.latency cannot be negative")
+	check(execution.Dedup.Window >= 0, "execution.dedup.window cannot be negative")
+
+	check(c.Watchdog.StaleAfter > 0, "watchdog.stale_after must be positive")
+	if outage := c.Outage; outage.Enabled {
+		check(outage.Window > 0, "outage.window must be positive")
+		check(outage.MaxConnectFailures > 0, "outage.max_connect_failures must be positive")
+		check(outage.MaxServerErrors > 0, "outage.max_server_errors must be positive")
+		check(outage.Recovery > 0, "outage.recovery must be positive")
+		check(outage.MaxRetryInterval > 0, "outage.max_retry_interval must be positive")
+	}
+
+	sim := c.Simulator
+	check(sim.InitialPrice > 0, "simulator.initial_price must be positive")
+	check(sim.TickInterval > 0, "simulator.tick_interval must be positive")
+	check(sim.MaxTicks >= 0, "simulator.max_ticks cannot be negative")
+	check(sim.RegimeSwitchProb >= 0 && sim.RegimeSwitchProb <= 1, "simulator.regime_switch_probability must be in [0, 1]")
+	check(len(sim.Regimes) > 0, "simulator.regimes needs at least one regime")
+
+	if c.API.Enabled {
+		check(c.API.Address != "", "api.address is required with api.enabled")
+		if stream := c.API.Stream; stream.Enabled {
+			check(stream.StateInterval > 0, "api.stream.state_interval must be positive")
+			check(stream.BufferSize > 0, "api.stream.buffer_size must be positive")
+			check(stream.MaxClients > 0, "api.stream.max_clients must be positive")
+		}
+	}
+	if c.GRPC.Enabled {
+		check(c.GRPC.BufferSize > 0, "grpc.buffer_size must be positive")
+		check(c.GRPC.MaxSubscribers > 0, "grpc.max_subscribers must be positive")
+	}
+	switch c.Publisher.Type {
+	case "":
+	case "nats":
+		check(c.Publisher.URL != "", "publisher.url is required with publisher.type nats")
+		check(c.Publisher.Format == "protobuf" || c.Publisher.Format == "json", "publisher.format must be protobuf or json")
+		check(c.Publisher.BufferSize > 0, "publisher.buffer_size must be positive")
+	default:
+		check(false, "publisher.type must be nats or empty")
+	}
+	switch c.Storage.Type {
+	case "":
+	case "sqlite":
+		check(c.Storage.Path != "", "storage.path is required with storage.type sqlite")
+	case "postgres":
+		check(c.Storage.URL != "", "storage.url is required with storage.type postgres")
+	default:
+		check(false, "storage.type must be sqlite, postgres or empty")
+	}
+
+	check(c.Currency.Reporting != "", "currency.reporting is required")
+	for symbol, rate := range c.Currency.Rates {
+		check(rate > 0, "currency.rates.%s must be positive", symbol)
+	}
+
+	risk := c.Risk
+	check(risk.MaxOpenPositions >= 0, "risk.max_open_positions cannot be negative")
+	check(risk.MaxSymbolNotional >= 0 && risk.MaxTotalNotional >= 0 && risk.MaxCorrelatedNotional >= 0,
+		"risk notional limits cannot be negative")
+	check(risk.MaxVolatilityContribution >= 0, "risk.max_volatility_contribution cannot be negative")
+	check(risk.CorrelationThreshold >= 0 && risk.CorrelationThreshold <= 1, "risk.correlation_threshold must be in [0, 1]")
+	check(risk.SampleInterval > 0, "risk.sample_interval must be positive")
+	check(risk.Window > 1, "risk.window must be at least 2")
+	check(risk.MaxStopDistance >= 0 && risk.MaxStopDistance < 1, "risk.max_stop_distance must be in [0, 1)")
+	check(risk.MaxStopRisk >= 0 && risk.MaxStopRisk <= 1, "risk.max_stop_risk must be in [0, 1]")
+	check(risk.KillSwitch.MaxDailyLoss >= 0 && risk.KillSwitch.MaxConsecutiveLosses >= 0 && risk.KillSwitch.MaxOpenExposure >= 0,
+		"risk.kill_switch limits cannot be negative")
+
+	if rule := c.Stops.BreakEven; rule.Enabled {
+		check(rule.TriggerMultiple > 0, "stops.break_even.trigger_multiple must be positive")
+		check(rule.FeeBuffer >= 0, "stops.break_even.fee_buffer cannot be negative")
+	}
+
+	filter := c.EntryFilter
+	check(filter.MaxSpreadBps >= 0, "entry_filter.max_spread_bps cannot be negative")
+	check(filter.MinVolume >= 0, "entry_filter.min_volume cannot be negative")
+	check(filter.MaxQuoteAge > 0, "entry_filter.max_quote_age must be positive")
+	check(filter.MinVolume == 0 || filter.VolumeWindow > 0, "entry_filter.volume_window must be positive with min_volume")
+
+	if adaptive := c.Adaptive; adaptive.Enabled {
+		check(adaptive.SampleInterval > 0, "adaptive.sample_interval must be positive")
+		check(adaptive.Window > 0, "adaptive.window must be positive")
+		check(adaptive.MinSamples > 0 && adaptive.MinSamples <= adaptive.Window, "adaptive.min_samples must be between 1 and adaptive.window")
+	}
+
+	if news := c.News; news.Enabled {
+		check(news.File != "" || news.URL != "", "news.file or news.url is required with news.enabled")
+		check(news.URL == "" || news.RefreshInterval > 0, "news.refresh_interval must be positive")
+		check(news.Before >= 0 && news.After >= 0 && news.FlattenBefore >= 0, "news.before, after and flatten_before cannot be negative")
+	}
+
+	if c.Heartbeat.Enabled {
+		check(c.Heartbeat.Interval > 0, "heartbeat.interval must be positive")
+		check(c.Heartbeat.RunFile != "", "heartbeat.run_file is required")
+	}
+	if notifications := c.Notifications; notifications.Enabled {
+		check(notifications.BufferSize > 0, "notifications.buffer_size must be positive")
+		check(notifications.Timeout > 0, "notifications.timeout must be positive")
+		check(notifications.Repeat >= 0, "notifications.repeat cannot be negative")
+		check(len(notifications.Routes) > 0, "notifications.routes needs at least one route")
+		notifiers := make(map[string]bool, len(notifications.Notifiers))
+		for i, notifier := range notifications.Notifiers {
+			label := fmt.Sprintf("notifications.notifiers[%d]", i)
+			if notifier.Name != "" {
+				label = "notifier " + notifier.Name
+			}
+			check(notifier.Name != "", "%s has no name", label)
+			check(!notifiers[notifier.Name], "%s is listed twice", label)
+			notifiers[notifier.Name] = true
+			switch notifier.Type {
+			case "slack", "discord":
+				check(notifier.WebhookEnv != "", "%s needs webhook_env", label)
+			case "email":
+				smtp := notifier.SMTP
+				check(smtp.Host != "", "%s needs smtp.host", label)
+				check(smtp.Port >= 0 && smtp.Port <= 65535, "%s has an invalid smtp.port", label)
+				check(smtp.From != "" && len(smtp.To) > 0, "%s needs smtp.from and smtp.to", label)
+			default:
+				check(false, "%s type must be slack, discord or email, not %q", label, notifier.Type)
+			}
+		}
+		for i, route := range notifications.Routes {
+			label := fmt.Sprintf("notifications.routes[%d]", i)
+			check(len(route.Notifiers) > 0, "%s has no notifiers", label)
+			for _, name := range route.Notifiers {
+				check(notifiers[name], "%s sends to unknown notifier %q", label, name)
+			}
+			level := strings.ToUpper(route.MinLevel)
+			check(level == "" || level == "INFO" || level == "WARNING" || level == "CRITICAL",
+				"%s min_level must be INFO, WARNING or CRITICAL, not %q", label, route.MinLevel)
+		}
+	}
+	if lock := c.InstanceLock; lock.Enabled {
+		switch lock.Type {
+		case "file":
+			check(lock.Dir != "", "instance_lock.dir is required with instance_lock.type file")
+		case "postgres":
+			check(lock.URL != "" || c.Storage.URL != "", "instance_lock.url or storage.url is required with instance_lock.type postgres")
+		default:
+			check(false, "instance_lock.type must be file or postgres, not %q", lock.Type)
+		}
+	}
+	if c.TradeContext.Enabled {
+		check(c.TradeContext.Dir != "", "trade_context.dir is required")
+		check(c.TradeContext.Before >= 0 && c.TradeContext.After >= 0, "trade_context.before and after cannot be negative")
+	}
+	if tracing := c.Tracing; tracing.Enabled {
+		check(tracing.Endpoint != "", "tracing.endpoint is required")
+		check(tracing.SampleRatio >= 0 && tracing.SampleRatio <= 1, "tracing.sample_ratio must be in [0, 1]")
+		check(tracing.BatchSize > 0 && tracing.BufferSize > 0, "tracing.batch_size and buffer_size must be positive")
+		check(tracing.FlushInterval > 0, "tracing.flush_interval must be positive")
+	}
+
+	names := make(map[string]bool, len(c.Accounts))
+	for i, account := range c.Accounts {
+		label := fmt.Sprintf("accounts[%d]", i)
+		if account.Name != "" {
+			label = "account " + account.Name
+		}
+		check(account.Name != "", "%s has no name", label)
+		check(!names[account.Name], "%s is listed twice", label)
+		check(account.Balance > 0, "%s needs a positive balance", label)
+		check(len(account.Strategies) > 0, "%s has no strategy", label)
+		names[account.Name] = true
+	}
+
+	return problems
+}
+
+// ValidateLive checks that the API keys live trading needs are set in the
+// environment: those of every account, or of the execution config without
+// accounts
+func (c *Config) ValidateLive() []error {
+	var problems []error
+	require := func(owner, setting, variable string) {
+		switch {
+		case variable == "":
+			problems = append(problems, fmt.Errorf("%s: %s is required for live trading", owner, setting))
+		case os.Getenv(variable) == "":
+			problems = append(problems, fmt.Errorf("%s: environment variable %s (%s) is not set", owner, variable, setting))
+		}
+	}
+
+	if !c.Execution.AcknowledgeLiveTrading {
+		problems = append(problems, fmt.Errorf("execution.acknowledge_live_trading must be true for live trading"))
+	}
+	if len(c.Accounts) == 0 {
+		require("execution", "api_key_env", c.Execution.APIKeyEnv)
+		require("execution", "api_secret_env", c.Execution.APISecretEnv)
+	}
+	for _, account := range c.Accounts {
+		require("account "+account.Name, "api_key_env", account.APIKeyEnv)
+		require("account "+account.Name, "api_secret_env", account.APISecretEnv)
+	}
+	if c.API.Enabled {
+		require("api", "token_env", c.API.TokenEnv)
+	}
+	return problems
+}
@@ -0,0 +1,193 @@
+// Package currency converts amounts between assets, so that the PnL and
+// exposure of symbols quoted in different assets (USDT, BTC, EUR) add up
+// in one reporting currency.
+//
+// Rates come from the prices of exchange symbols: a price of 65000 for
+// btcusdt means 1 BTC = 65000 USDT. A converter uses a pair directly,
+// inverted, or chained through one intermediate asset.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+)
+
+// ErrNoRate is returned when no usable rate links two assets
+var ErrNoRate = errors.New("no conversion rate")
+
+// quoteAssets are the quote assets recognized at the end of a symbol,
+// longest first so "fdusd" is not read as "usd"
+var quoteAssets = []string{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD",
+	"BTC", "ETH", "BNB", "EUR", "GBP", "TRY", "USD", "JPY", "DAI",
+}
+
+// Split splits an exchange symbol such as "btcusdt" or "ETH/BTC" into its
+// upper-case base and quote assets
+func Split(symbol string) (base, quote string, err error) {
+	upper := strings.ToUpper(symbol)
+	if parts := strings.Split(upper, "/"); len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return parts[0], parts[1], nil
+	}
+	for _, asset := range quoteAssets {
+		if strings.HasSuffix(upper, asset) && len(upper) > len(asset) {
+			return upper[:len(upper)-len(asset)], asset, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown quote asset in symbol %s", symbol)
+}
+
+// pair is an ordered asset pair: 1 base = price quote
+type pair struct {
+	base  string
+	quote string
+}
+
+// rate is the last price of a pair
+type rate struct {
+	price float64
+	time  time.Time // Zero for fixed rates, which never go stale
+}
+
+// Converter holds the latest rates and converts amounts between assets
+type Converter struct {
+	reporting string
+	maxAge    time.Duration
+	rates     map[pair]rate
+	mutex     sync.RWMutex
+}
+
+// NewConverter creates a converter reporting in the given currency
+func NewConverter(reporting string) *Converter {
+	return &Converter{
+		reporting: strings.ToUpper(reporting),
+		rates:     make(map[pair]rate),
+	}
+}
+
+// Reporting returns the reporting currency
+func (c *Converter) Reporting() string {
+	return c.reporting
+}
+
+// SetMaxAge makes rates older than maxAge (relative to the conversion
+// time) unusable; 0 accepts rates of any age
+func (c *Converter) SetMaxAge(maxAge time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxAge = maxAge
+}
+
+// SetRate records that 1 base is worth price quote at the given time.
+// A zero time marks a fixed rate.
+func (c *Converter) SetRate(base, quote string, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	key := pair{strings.ToUpper(base), strings.ToUpper(quote)}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rates[key] = rate{price: price, time: at}
+}
+
+// SetPrice records the price of an exchange symbol as a rate
+func (c *Converter) SetPrice(symbol string, price float64, at time.Time) error {
+	base, quote, err := Split(symbol)
+	if err != nil {
+		return err
+	}
+	c.SetRate(base, quote, price, at)
+	return nil
+}
+
+// Rate returns how many units of to one unit of from is worth at the given
+// time
+func (c *Converter) Rate(from, to string, at time.Time) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if price, ok := c.direct(from, to, at); ok {
+		return price, nil
+	}
+	// Chain through an intermediate asset, e.g. EUR -> USDT -> BTC
+	for _, via := range c.assets() {
+		if via == from || via == to {
+			continue
+		}
+		first, ok := c.direct(from, via, at)
+		if !ok {
+			continue
+		}
+		if second, ok := c.direct(via, to, at); ok {
+			return first * second, nil
+		}
+	}
+	return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+}
+
+// direct returns the rate of a pair or its inverse, if fresh enough
+func (c *Converter) direct(from, to string, at time.Time) (float64, bool) {
+	if r, ok := c.rates[pair{from, to}]; ok && c.fresh(r, at) {
+		return r.price, true
+	}
+	if r, ok := c.rates[pair{to, from}]; ok && c.fresh(r, at) {
+		return 1 / r.price, true
+	}
+	return 0, false
+}
+
+// fresh reports whether a rate may be used at the given time
+func (c *Converter) fresh(r rate, at time.Time) bool {
+	return c.maxAge <= 0 || r.time.IsZero() || at.Sub(r.time) <= c.maxAge
+}
+
+// assets returns the known assets in a stable order
+func (c *Converter) assets() []string {
+	seen := make(map[string]bool)
+	for key := range c.rates {
+		seen[key.base] = true
+		seen[key.quote] = true
+	}
+	assets := make([]string, 0, len(seen))
+	for asset := range seen {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// Convert converts an amount between assets. Amounts in the same asset
+// are returned unchanged.
+func (c *Converter) Convert(amount decimal.Decimal, from, to string, at time.Time) (decimal.Decimal, error) {
+	if strings.EqualFold(from, to) {
+		return amount, nil
+	}
+	price, err := c.Rate(from, to, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	// Multiply as floats: an inverted rate such as 1/65000 would lose most
+	// of its digits as a Decimal
+	return decimal.FromFloat(amount.Float64() * price), nil
+}
+
+// ToReporting converts an amount into the reporting currency
+func (c *Converter) ToReporting(amount decimal.Decimal, from string, at time.Time) (decimal.Decimal, error) {
+	return c.Convert(amount, from, c.reporting, at)
+}
+
+// FromReporting converts an amount in the reporting currency into an asset
+func (c *Converter) FromReporting(amount decimal.Decimal, to string, at time.Time) (decimal.Decimal, error) {
+	return c.Convert(amount, c.reporting, to, at)
+}
@@ -0,0 +1,122 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+)
+
+// DefaultTickerURL is Binance's latest-price endpoint
+const DefaultTickerURL = "https://api.binance.com/api/v3/ticker/price"
+
+// Feed polls an exchange ticker for the prices of the conversion symbols
+// (e.g. eurusdt) that no market feed delivers, and records them as rates
+type Feed struct {
+	converter *Converter
+	url       string
+	symbols   []string
+	interval  time.Duration
+	client    *http.Client
+	logger    logger.Interface
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewFeed creates a feed updating the converter every interval
+func NewFeed(converter *Converter, tickerURL string, symbols []string, interval time.Duration, log logger.Interface) *Feed {
+	if tickerURL == "" {
+		tickerURL = DefaultTickerURL
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	upper := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		upper[i] = strings.ToUpper(symbol)
+	}
+	return &Feed{
+		converter: converter,
+		url:       tickerURL,
+		symbols:   upper,
+		interval:  interval,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logger:    log,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start fetches the prices once and then polls in the background
+func (f *Feed) Start() {
+	if err := f.Refresh(); err != nil {
+		f.logger.Warning(fmt.Sprintf("Failed to fetch conversion rates: %v", err))
+	}
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := f.Refresh(); err != nil {
+					f.logger.Warning(fmt.Sprintf("Failed to fetch conversion rates: %v", err))
+				}
+			case <-f.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling
+func (f *Feed) Stop() {
+	f.stopOnce.Do(func() { close(f.stopChan) })
+}
+
+// tickerPrice is one entry of the ticker response
+type tickerPrice struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
+// Refresh fetches the current prices of all symbols
+func (f *Feed) Refresh() error {
+	if len(f.symbols) == 0 {
+		return nil
+	}
+	query, err := json.Marshal(f.symbols)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Get(f.url + "?symbols=" + url.QueryEscape(string(query)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ticker returned %s", resp.Status)
+	}
+
+	var prices []tickerPrice
+	if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+		return fmt.Errorf("invalid ticker response: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, entry := range prices {
+		price, err := strconv.ParseFloat(entry.Price, 64)
+		if err != nil {
+			return fmt.Errorf("invalid price %q for %s", entry.Price, entry.Symbol)
+		}
+		if err := f.converter.SetPrice(entry.Symbol, price, now); err != nil {
+			return err
+		}
+	}
+	f.logger.Debug(fmt.Sprintf("Updated %d conversion rate(s)", len(prices)))
+	return nil
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// systemd notification states
+const (
+	StateReady     = "READY=1"
+	StateStopping  = "STOPPING=1"
+	StateReloading = "RELOADING=1"
+	StateWatchdog  = "WATCHDOG=1"
+)
+
+// WritePIDFile writes the current process ID to the given file.
+// It fails if the file names another process that is still running.
+func WritePIDFile(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && pid != os.Getpid() && ProcessExists(pid) {
+			return fmt.Errorf("process %d is already running (pid file %s)", pid, path)
+		}
+	}
+
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+}
+
+// ReadPIDFile returns the process ID stored in the given file
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %v", path, err)
+	}
+	return pid, nil
+}
+
+// RemovePIDFile removes the PID file if it still belongs to this process
+func RemovePIDFile(path string) error {
+	pid, err := ReadPIDFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if pid != os.Getpid() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// InstanceRunning returns the PID in a PID file and whether that process
+// is alive; false without a PID file
+func InstanceRunning(path string) (int, bool) {
+	pid, err := ReadPIDFile(path)
+	if err != nil {
+		return 0, false
+	}
+	return pid, ProcessExists(pid)
+}
+
+// ProcessExists checks whether a process with the given ID is alive on
+// this host
+func ProcessExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// Notify sends a state notification to systemd (sd_notify protocol).
+// It returns false without error when not running under systemd.
+func Notify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+
+	// Abstract namespace sockets are prefixed with '@'
+	if strings.HasPrefix(socketPath, "@") {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Run describes a running process in its run file
+type Run struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+// MarkRunning writes a run file for the current process. It returns the
+// run found in the file if that process is gone without removing it,
+// i.e. it crashed or was killed; nil otherwise.
+func MarkRunning(path string) (*Run, error) {
+	var crashed *Run
+	if data, err := os.ReadFile(path); err == nil {
+		previous := &Run{}
+		if err := json.Unmarshal(data, previous); err != nil {
+			return nil, fmt.Errorf("invalid run file %s: %v", path, err)
+		}
+		if previous.PID != os.Getpid() && !ProcessExists(previous.PID) {
+			crashed = previous
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read run file: %v", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return crashed, fmt.Errorf("failed to create run file directory: %v", err)
+		}
+	}
+	data, err := json.Marshal(&Run{PID: os.Getpid(), Started: time.Now()})
+	if err != nil {
+		return crashed, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return crashed, fmt.Errorf("failed to write run file: %v", err)
+	}
+	return crashed, nil
+}
+
+// ClearRunning removes the run file on a clean exit, if it still belongs
+// to this process
+func ClearRunning(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	run := &Run{}
+	if err := json.Unmarshal(data, run); err == nil && run.PID != os.Getpid() {
+		return nil
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,294 @@
+package decimal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Places is the number of decimal places a Decimal holds
+const Places = 8
+
+// scale is 10^Places
+const scale = 100000000
+
+// Decimal is a fixed-point number with 8 decimal places, stored as a scaled
+// integer so prices, quantities and PnL add up exactly. Its range is about
+// ±92 billion, ample for prices and notionals.
+type Decimal struct {
+	units int64 // Value * 10^Places
+}
+
+// Zero is the zero Decimal
+var Zero = Decimal{}
+
+// New returns value * 10^exp (e.g. New(1, -2) is 0.01)
+func New(value int64, exp int) Decimal {
+	d := Decimal{units: value}
+	for i := exp + Places; i > 0; i-- {
+		d.units *= 10
+	}
+	for i := exp + Places; i < 0; i++ {
+		d.units /= 10
+	}
+	return d
+}
+
+// FromFloat converts a float, rounding to the nearest 10^-Places
+func FromFloat(value float64) Decimal {
+	return Decimal{units: int64(math.Round(value * scale))}
+}
+
+// maxExponent bounds the exponent Parse accepts: beyond it any non-zero
+// value is out of range or below 10^-Places
+const maxExponent = 64
+
+// Parse parses a decimal string such as "50123.45" or "1e-5", truncated to
+// 10^-Places
+func Parse(value string) (Decimal, error) {
+	input := value
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	exponent := 0
+	if i := strings.IndexAny(value, "eE"); i >= 0 {
+		var err error
+		exponent, err = strconv.Atoi(value[i+1:])
+		if err != nil || exponent < -maxExponent || exponent > maxExponent {
+			return Zero, fmt.Errorf("invalid decimal: %q", input)
+		}
+		value = value[:i]
+	}
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return Zero, fmt.Errorf("invalid decimal: %q", input)
+	}
+	whole, fraction = shiftPoint(whole, fraction, exponent)
+	if len(fraction) > Places {
+		fraction = fraction[:Places]
+	}
+	fraction += strings.Repeat("0", Places-len(fraction))
+
+	units, err := strconv.ParseInt("0"+whole+fraction, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return Zero, fmt.Errorf("decimal out of range: %q", input)
+	}
+	if err != nil {
+		return Zero, fmt.Errorf("invalid decimal: %q", input)
+	}
+	if negative {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+// shiftPoint moves the decimal point between the whole and fraction digits
+// of a number exponent places to the right (left if negative)
+func shiftPoint(whole, fraction string, exponent int) (string, string) {
+	digits := whole + fraction
+	point := len(whole) + exponent
+	switch {
+	case point < 0:
+		return "", strings.Repeat("0", -point) + digits
+	case point > len(digits):
+		return digits + strings.Repeat("0", point-len(digits)), ""
+	default:
+		return digits[:point], digits[point:]
+	}
+}
+
+// MustParse is like Parse but panics on invalid input; for constants
+func MustParse(value string) Decimal {
+	d, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Float64 returns the nearest float
+func (d Decimal) Float64() float64 {
+	return float64(d.units) / scale
+}
+
+// String formats the decimal without trailing zeros
+func (d Decimal) String() string {
+	sign := ""
+	units := d.units
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	whole := units / scale
+	fraction := strings.TrimRight(fmt.Sprintf("%08d", units%scale), "0")
+	if fraction == "" {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	return fmt.Sprintf("%s%d.%s", sign, whole, fraction)
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{units: d.units + other.units}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{units: d.units - other.units}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units}
+}
+
+// Mul returns d * other, truncated to 10^-Places. It panics if the product
+// is out of range.
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(other.units))
+	product.Quo(product, big.NewInt(scale))
+	if !product.IsInt64() {
+		panic(fmt.Sprintf("decimal: %s * %s overflows", d, other))
+	}
+	return Decimal{units: product.Int64()}
+}
+
+// Div returns d / other, truncated to 10^-Places; zero if other is zero.
+// It panics if the quotient is out of range.
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.units == 0 {
+		return Zero
+	}
+	quotient := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(scale))
+	quotient.Quo(quotient, big.NewInt(other.units))
+	if !quotient.IsInt64() {
+		panic(fmt.Sprintf("decimal: %s / %s overflows", d, other))
+	}
+	return Decimal{units: quotient.Int64()}
+}
+
+// Cmp returns -1, 0 or +1 as d is less than, equal to or greater than other
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.units < other.units:
+		return -1
+	case d.units > other.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether d is zero
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Sign returns -1, 0 or +1 for negative, zero or positive d
+func (d Decimal) Sign() int {
+	return d.Cmp(Zero)
+}
+
+// Scale returns the number of decimal places d has without trailing
+// zeros (e.g. 2 for 0.01, 0 for 5)
+func (d Decimal) Scale() int {
+	units := d.units
+	scale := Places
+	for scale > 0 && units%10 == 0 {
+		units /= 10
+		scale--
+	}
+	return scale
+}
+
+// FloorToStep rounds d down to a multiple of step (e.g. an exchange tick
+// or lot size). A zero step returns d unchanged.
+func (d Decimal) FloorToStep(step Decimal) Decimal {
+	if step.units <= 0 {
+		return d
+	}
+	remainder := d.units % step.units
+	if remainder < 0 {
+		remainder += step.units
+	}
+	return Decimal{units: d.units - remainder}
+}
+
+// CeilToStep rounds d up to a multiple of step
+func (d Decimal) CeilToStep(step Decimal) Decimal {
+	floor := d.FloorToStep(step)
+	if floor.units == d.units || step.units <= 0 {
+		return floor
+	}
+	return Decimal{units: floor.units + step.units}
+}
+
+// RoundToStep rounds d to the nearest multiple of step, halves away from zero
+func (d Decimal) RoundToStep(step Decimal) Decimal {
+	if step.units <= 0 {
+		return d
+	}
+	floor := d.FloorToStep(step)
+	if 2*(d.units-floor.units) >= step.units {
+		return Decimal{units: floor.units + step.units}
+	}
+	return floor
+}
+
+// MarshalJSON encodes the decimal as a JSON string to keep it exact
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON decodes a JSON string or number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" || value == "" {
+		*d = Zero
+		return nil
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores the decimal in a database as exact text
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads a decimal stored as text (or a number) from a database
+func (d *Decimal) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case int64:
+		*d = New(v, 0)
+		return nil
+	case float64:
+		*d = FromFloat(v)
+		return nil
+	case nil:
+		*d = Zero
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a decimal", src)
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
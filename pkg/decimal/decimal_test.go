@@ -0,0 +1,115 @@
+package decimal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"50123.45", "50123.45"},
+		{"-0.001", "-0.001"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{" 12.5 ", "12.5"},
+		{"0.123456789", "0.12345678"},
+		{"-0.123456789", "-0.12345678"},
+		{"1e-5", "0.00001"},
+		{"1E-8", "0.00000001"},
+		{"1e-9", "0"},
+		{"2.5e3", "2500"},
+		{"-1.5E+2", "-150"},
+		{"123.456e-2", "1.23456"},
+		{"0.0001e4", "1"},
+		{"92233720368.54775807", "92233720368.54775807"},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.input, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // Start of the error
+	}{
+		{"", "invalid"},
+		{"-", "invalid"},
+		{"abc", "invalid"},
+		{"1.2.3", "invalid"},
+		{"1e", "invalid"},
+		{"e5", "invalid"},
+		{"1e5.5", "invalid"},
+		{"1e1000", "invalid"},
+		{"92233720368.54775808", "decimal out of range"},
+		{"1e11", "decimal out of range"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.input)
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("Parse(%q) error = %v, want %s", test.input, err, test.want)
+		}
+	}
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"floor", MustParse("1.2345").FloorToStep(MustParse("0.01")), "1.23"},
+		{"floor negative", MustParse("-1.2345").FloorToStep(MustParse("0.01")), "-1.24"},
+		{"floor zero step", MustParse("1.2345").FloorToStep(Zero), "1.2345"},
+		{"ceil", MustParse("1.2301").CeilToStep(MustParse("0.01")), "1.24"},
+		{"ceil exact", MustParse("1.23").CeilToStep(MustParse("0.01")), "1.23"},
+		{"round down", MustParse("1.2349").RoundToStep(MustParse("0.01")), "1.23"},
+		{"round half up", MustParse("1.235").RoundToStep(MustParse("0.01")), "1.24"},
+		{"round lot", MustParse("0.00149").RoundToStep(MustParse("0.001")), "0.001"},
+		{"from float", FromFloat(0.1 + 0.2), "0.3"},
+		{"from float nearest", FromFloat(0.000000016), "0.00000002"},
+		{"mul truncates", MustParse("0.00000003").Mul(MustParse("0.5")), "0.00000001"},
+		{"div truncates", MustParse("1").Div(MustParse("3")), "0.33333333"},
+		{"div negative", MustParse("-1").Div(MustParse("3")), "-0.33333333"},
+		{"div by zero", MustParse("1").Div(Zero), "0"},
+		{"mul large", MustParse("90000000000").Mul(MustParse("1")), "90000000000"},
+		{"new", New(5, -3), "0.005"},
+	}
+	for _, test := range tests {
+		if test.got.String() != test.want {
+			t.Errorf("%s = %s, want %s", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestOverflowPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func() Decimal
+	}{
+		{"mul", func() Decimal { return MustParse("10000000000").Mul(MustParse("10")) }},
+		{"mul negative", func() Decimal { return MustParse("-10000000000").Mul(MustParse("10")) }},
+		{"div", func() Decimal { return MustParse("10000000000").Div(MustParse("0.01")) }},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic on overflow", test.name)
+				}
+			}()
+			test.op()
+		}()
+	}
+}
@@ -0,0 +1,78 @@
+// Package errs defines the error kinds shared across TRADE so callers can
+// branch with errors.Is instead of parsing log strings.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds
+var (
+	ErrFeedDisconnected = errors.New("feed disconnected")
+	ErrInsufficientData = errors.New("insufficient data")
+	ErrOrderRejected    = errors.New("order rejected")
+	ErrRiskLimit        = errors.New("risk limit exceeded")
+	// ErrExchangeUnavailable is a request the exchange answered with a
+	// server error or did not answer, e.g. during maintenance
+	ErrExchangeUnavailable = errors.New("exchange unavailable")
+)
+
+// kinds lists the error kinds in the order Kind checks them
+var kinds = []error{ErrFeedDisconnected, ErrInsufficientData, ErrOrderRejected, ErrRiskLimit, ErrExchangeUnavailable}
+
+// Error is an error of a known kind raised by an operation. Both the kind
+// and the underlying cause match errors.Is and errors.As.
+type Error struct {
+	Kind error  // One of the ErrXxx kinds
+	Op   string // Operation or component that failed, e.g. "portfolio.Reserve"
+	Err  error  // Underlying cause, may be nil
+}
+
+// Error returns "op: kind: cause", leaving out empty parts
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Op != "" {
+		msg = e.Op + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns the kind and the underlying cause
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Wrap returns err tagged with kind, or nil if err is nil
+func Wrap(kind error, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Op: op, Err: err}
+}
+
+// Errorf returns a new error of the given kind with a formatted cause
+func Errorf(kind error, op string, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Op: op, Err: fmt.Errorf(format, args...)}
+}
+
+// Kind returns the kind of the outermost Error in err's chain, or the first
+// bare kind err matches, or nil
+func Kind(err error) error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
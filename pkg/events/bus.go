@@ -0,0 +1,102 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Handler is a function that receives published events
+type Handler func(event Event)
+
+// SubscriptionID identifies a subscription so it can be cancelled
+type SubscriptionID uint64
+
+// subscription is a registered handler
+type subscription struct {
+	id        SubscriptionID
+	eventType Type // empty for subscriptions to all events
+	handler   Handler
+}
+
+// Bus dispatches typed events to subscribed components.
+// Handlers run synchronously on the publisher's goroutine, in subscription
+// order, so a backtest replay stays deterministic.
+type Bus struct {
+	// Subscriptions are replaced, never modified, so Publish reads them
+	// without a lock or a copy on every tick
+	subscriptions atomic.Pointer[[]subscription]
+	nextID        SubscriptionID
+	mutex         sync.Mutex // Serializes (un)subscribing
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for events of the given type
+func (b *Bus) Subscribe(eventType Type, handler Handler) SubscriptionID {
+	return b.subscribe(eventType, handler)
+}
+
+// SubscribeAll registers a handler that receives every event
+func (b *Bus) SubscribeAll(handler Handler) SubscriptionID {
+	return b.subscribe("", handler)
+}
+
+// subscribe adds a subscription and returns its ID
+func (b *Bus) subscribe(eventType Type, handler Handler) SubscriptionID {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nextID++
+	current := b.current()
+	subscriptions := make([]subscription, len(current), len(current)+1)
+	copy(subscriptions, current)
+	subscriptions = append(subscriptions, subscription{
+		id:        b.nextID,
+		eventType: eventType,
+		handler:   handler,
+	})
+	b.subscriptions.Store(&subscriptions)
+	return b.nextID
+}
+
+// Unsubscribe removes a previously registered handler
+func (b *Bus) Unsubscribe(id SubscriptionID) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	current := b.current()
+	for i, sub := range current {
+		if sub.id == id {
+			subscriptions := append(current[:i:i], current[i+1:]...)
+			b.subscriptions.Store(&subscriptions)
+			return
+		}
+	}
+}
+
+// current returns the current subscriptions, which must not be modified
+func (b *Bus) current() []subscription {
+	if subscriptions := b.subscriptions.Load(); subscriptions != nil {
+		return *subscriptions
+	}
+	return nil
+}
+
+// Publish delivers an event to all matching subscribers. Handlers may
+// publish further events or (un)subscribe; changes apply from the next
+// event on.
+func (b *Bus) Publish(event Event) {
+	if b == nil || event == nil {
+		return
+	}
+
+	eventType := event.Type()
+	for _, sub := range b.current() {
+		if sub.eventType == "" || sub.eventType == eventType {
+			sub.handler(event)
+		}
+	}
+}
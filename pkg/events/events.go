@@ -0,0 +1,305 @@
+package events
+
+import (
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/tracing"
+	"TRADE/pkg/types"
+)
+
+// Type identifies the kind of an event
+type Type string
+
+const (
+	// Event types
+	TypeTick         Type = "tick"
+	TypeMetrics      Type = "metrics"
+	TypeSignal       Type = "signal"
+	TypeOrder        Type = "order"
+	TypeFill         Type = "fill"
+	TypeTradeClosed  Type = "trade_closed"
+	TypeRiskRejected Type = "risk_rejected"
+	TypeFunding      Type = "funding"
+	TypeDailySummary Type = "daily_summary"
+	TypeError        Type = "error"
+	TypeAlert        Type = "alert"
+	TypeStatus       Type = "status"
+	TypePattern      Type = "pattern"
+)
+
+// Event is implemented by every message published on the bus
+type Event interface {
+	Type() Type
+}
+
+// SymbolOf returns the symbol an event refers to, or "" if it has none
+func SymbolOf(event Event) string {
+	switch ev := event.(type) {
+	case *TickEvent:
+		return ev.Symbol
+	case *MetricsEvent:
+		return ev.Symbol
+	case *SignalEvent:
+		return ev.Symbol
+	case *OrderEvent:
+		return ev.Symbol
+	case *FillEvent:
+		return ev.Symbol
+	case *TradeClosedEvent:
+		return ev.Symbol
+	case *RiskRejectedEvent:
+		return ev.Symbol
+	case *FundingEvent:
+		return ev.Symbol
+	case *DailySummaryEvent:
+		return ev.Symbol
+	case *AlertEvent:
+		return ev.Symbol
+	case *StatusEvent:
+		return ev.Symbol
+	case *PatternEvent:
+		return ev.Symbol
+	default:
+		return ""
+	}
+}
+
+// TickEvent is published for every market tick received. The tick is
+// pooled and only valid while handlers run; see Detach.
+type TickEvent struct {
+	Symbol string
+	Tick   *types.TickData
+	// Backlog is the number of ticks of the live stream queued behind this
+	// one, waiting for the pipeline; 0 when it keeps up and in backtests
+	Backlog int
+}
+
+// Type returns the event type
+func (e *TickEvent) Type() Type { return TypeTick }
+
+// Detach returns an event that is safe to keep after the handler it was
+// received in returns: tick events get their own copy of the pooled tick,
+// other events are returned as they are
+func Detach(event Event) Event {
+	if tick, ok := event.(*TickEvent); ok && tick.Tick != nil {
+		return &TickEvent{Symbol: tick.Symbol, Tick: tick.Tick.Clone(), Backlog: tick.Backlog}
+	}
+	return event
+}
+
+// MetricsEvent is published whenever the analyzer updates its metrics
+type MetricsEvent struct {
+	Symbol    string
+	Price     float64
+	Timestamp time.Time
+	Metrics   *types.MarketMetrics
+	Span      *tracing.Span `json:"-"` // Trace span of the tick; nil if not traced
+}
+
+// Type returns the event type
+func (e *MetricsEvent) Type() Type { return TypeMetrics }
+
+// SignalEvent is published when a strategy generates a trading signal
+type SignalEvent struct {
+	Symbol string
+	Signal *types.Signal
+	Span   *tracing.Span `json:"-"` // Trace span of the tick the signal came from; nil if none
+}
+
+// Type returns the event type
+func (e *SignalEvent) Type() Type { return TypeSignal }
+
+// OrderEvent is published when an order is sent for execution
+type OrderEvent struct {
+	OrderID       string
+	CorrelationID string
+	TradeID       string
+	Symbol        string
+	Side          string
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	Reason        string
+	Account       string // Account the order is sent for; empty without accounts
+	Timestamp     time.Time
+}
+
+// Type returns the event type
+func (e *OrderEvent) Type() Type { return TypeOrder }
+
+// FillEvent is published when an order is (fully or partially) filled
+type FillEvent struct {
+	FillID        string
+	OrderID       string
+	CorrelationID string
+	TradeID       string
+	Symbol        string
+	Side          string
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	Fee           decimal.Decimal
+	Account       string // Account of the filled order; empty without accounts
+	Timestamp     time.Time
+}
+
+// Type returns the event type
+func (e *FillEvent) Type() Type { return TypeFill }
+
+// TradeClosedEvent is published when a round-trip trade is closed, in live,
+// paper and backtest runs alike
+type TradeClosedEvent struct {
+	TradeID       string
+	CorrelationID string
+	Symbol        string
+	EntryPrice    decimal.Decimal
+	ExitPrice     decimal.Decimal
+	Quantity      decimal.Decimal
+	PnL           decimal.Decimal // Realized PnL in quote currency, funding included
+	PnLPercent    float64
+	Reason        string
+	Direction     string // types.DirectionLong or types.DirectionShort
+	Strategy      string // Instance of the strategy that opened the trade
+	EntryTime     time.Time
+	ExitTime      time.Time
+	Currency      string // Quote currency of the prices and PnL
+	// MFE and MAE are the maximum favorable and adverse excursions of
+	// the price while the trade was open, in percent of the entry price
+	MFE float64
+	MAE float64
+	// Funding is the sum of the funding payments received (negative if
+	// paid) while a perpetual position was open
+	Funding decimal.Decimal
+	// ReportingPnL is PnL converted into ReportingCurrency at exit; the
+	// currency is empty if no conversion rate was available
+	ReportingPnL      decimal.Decimal
+	ReportingCurrency string
+	// Tags are the tags the trade was given at entry, e.g. the regime it
+	// was entered in
+	Tags []string
+}
+
+// Type returns the event type
+func (e *TradeClosedEvent) Type() Type { return TypeTradeClosed }
+
+// NormalizedPnL returns the PnL in the reporting currency, or in the quote
+// currency if it could not be converted
+func (e *TradeClosedEvent) NormalizedPnL() decimal.Decimal {
+	if e.ReportingCurrency != "" {
+		return e.ReportingPnL
+	}
+	return e.PnL
+}
+
+// RiskRejectedEvent is published when the risk manager refuses an entry
+type RiskRejectedEvent struct {
+	TradeID       string
+	CorrelationID string
+	Symbol        string
+	Rule          string  // Limit the entry broke, e.g. "max_total_notional"
+	Notional      float64 // Notional of the refused entry, in the reporting currency
+	Value         float64 // Value the entry would have brought the rule to
+	Limit         float64
+	Related       []string // Correlated symbols counted against the limit
+	Message       string
+	Timestamp     time.Time
+}
+
+// Type returns the event type
+func (e *RiskRejectedEvent) Type() Type { return TypeRiskRejected }
+
+// FundingEvent is published for every mark price update of a perpetual
+// contract. Rate is the funding rate that will be settled at
+// NextFundingTime; longs pay it to shorts when positive.
+type FundingEvent struct {
+	Symbol          string
+	MarkPrice       float64
+	IndexPrice      float64
+	Rate            float64
+	NextFundingTime time.Time
+	Timestamp       time.Time
+}
+
+// Type returns the event type
+func (e *FundingEvent) Type() Type { return TypeFunding }
+
+// DailySummaryEvent is published once a day with the performance of the
+// trades closed since the previous summary
+type DailySummaryEvent struct {
+	Symbol   string
+	Date     string // YYYY-MM-DD of the day summarized
+	Start    time.Time
+	End      time.Time
+	Trades   int
+	Wins     int
+	Losses   int
+	WinRate  float64 // Percent of the trades
+	PnL      decimal.Decimal
+	Fees     decimal.Decimal
+	Currency string // Currency of PnL and fees
+}
+
+// Type returns the event type
+func (e *DailySummaryEvent) Type() Type { return TypeDailySummary }
+
+// ErrorEvent is published when a component encounters an error
+type ErrorEvent struct {
+	Component string
+	Err       error
+	Timestamp time.Time
+}
+
+// Type returns the event type
+func (e *ErrorEvent) Type() Type { return TypeError }
+
+// AlertLevel is the severity of an alert
+type AlertLevel string
+
+const (
+	// Alert levels
+	AlertInfo     AlertLevel = "INFO"
+	AlertWarning  AlertLevel = "WARNING"
+	AlertCritical AlertLevel = "CRITICAL"
+)
+
+// AlertEvent is an operational alert meant for a human (e.g. stale feed)
+type AlertEvent struct {
+	Level     AlertLevel
+	Source    string
+	Symbol    string
+	Message   string
+	Timestamp time.Time
+}
+
+// Type returns the event type
+func (e *AlertEvent) Type() Type { return TypeAlert }
+
+// StatusEvent is a periodic summary of market and trade state for display
+type StatusEvent struct {
+	Symbol        string
+	Status        string // Lifecycle state of the trading system
+	ExecutionMode types.ExecutionMode
+	Price         float64
+	Metrics       *types.MarketMetrics
+	TradeActive   bool
+	TradePnL      float64
+	Performance   *types.PerformanceMetrics
+	Equity        *types.EquityPoint // Marked-to-market equity and drawdown
+	// Portfolio aggregates the instances trading other symbols; nil while
+	// only this symbol runs
+	Portfolio *types.PortfolioView
+	Timestamp time.Time
+}
+
+// Type returns the event type
+func (e *StatusEvent) Type() Type { return TypeStatus }
+
+// PatternEvent is published when a closed candle completes a candlestick
+// pattern
+type PatternEvent struct {
+	Symbol  string
+	Pattern types.CandlePattern
+	Close   float64 // Close of the candle completing it
+}
+
+// Type returns the event type
+func (e *PatternEvent) Type() Type { return TypePattern }
@@ -0,0 +1,205 @@
+package execution
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/errs"
+)
+
+// DefaultBinanceURL is the base URL of Binance's spot REST API
+const DefaultBinanceURL = "https://api.binance.com"
+
+// binanceOrderPath is the endpoint placing, querying and cancelling orders
+const binanceOrderPath = "/api/v3/order"
+
+// BinanceExecutor sends orders to a Binance spot account through signed
+// REST requests
+type BinanceExecutor struct {
+	baseURL     string
+	credentials account.Credentials
+	recvWindow  time.Duration
+	client      *http.Client
+}
+
+// NewBinanceExecutor creates an executor trading the account of the API keys
+func NewBinanceExecutor(credentials account.Credentials) *BinanceExecutor {
+	return &BinanceExecutor{
+		baseURL:     DefaultBinanceURL,
+		credentials: credentials,
+		recvWindow:  5 * time.Second,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetURL sets the base URL of the REST API, e.g. the spot testnet
+func (b *BinanceExecutor) SetURL(baseURL string) {
+	b.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetRecvWindow sets how long after its timestamp a request stays valid
+func (b *BinanceExecutor) SetRecvWindow(window time.Duration) {
+	b.recvWindow = window
+}
+
+// binanceOrder is the order response of the REST API; fills are only
+// returned when the order is placed
+type binanceOrder struct {
+	OrderID            int64           `json:"orderId"`
+	ClientOrderID      string          `json:"clientOrderId"`
+	Status             string          `json:"status"`
+	ExecutedQty        decimal.Decimal `json:"executedQty"`
+	CumulativeQuoteQty decimal.Decimal `json:"cummulativeQuoteQty"`
+	TransactTime       int64           `json:"transactTime"`
+	UpdateTime         int64           `json:"updateTime"`
+	Fills              []struct {
+		Commission      decimal.Decimal `json:"commission"`
+		CommissionAsset string          `json:"commissionAsset"`
+	} `json:"fills"`
+}
+
+// binanceError is the error body of the REST API
+type binanceError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+// Submit places an order. Limit orders are immediate-or-cancel, so an order
+// is done once the response returns.
+func (b *BinanceExecutor) Submit(order *Order) (*Report, error) {
+	params := url.Values{}
+	params.Set("symbol", strings.ToUpper(order.Symbol))
+	params.Set("side", strings.ToUpper(order.Side))
+	params.Set("quantity", order.Quantity.String())
+	params.Set("newClientOrderId", clientOrderID(order.ID))
+	params.Set("newOrderRespType", "FULL")
+	switch order.Type {
+	case OrderLimit:
+		params.Set("type", "LIMIT")
+		params.Set("timeInForce", "IOC")
+		params.Set("price", order.Price.String())
+	default:
+		params.Set("type", "MARKET")
+	}
+	return b.order(http.MethodPost, order.ID, params)
+}
+
+// Status returns the current state of an order
+func (b *BinanceExecutor) Status(symbol, orderID string) (*Report, error) {
+	params := url.Values{}
+	params.Set("symbol", strings.ToUpper(symbol))
+	params.Set("origClientOrderId", clientOrderID(orderID))
+	return b.order(http.MethodGet, orderID, params)
+}
+
+// Cancel cancels what is left of an order
+func (b *BinanceExecutor) Cancel(symbol, orderID string) (*Report, error) {
+	params := url.Values{}
+	params.Set("symbol", strings.ToUpper(symbol))
+	params.Set("origClientOrderId", clientOrderID(orderID))
+	return b.order(http.MethodDelete, orderID, params)
+}
+
+// order sends a signed request to the order endpoint and converts its
+// response into a report of the order
+func (b *BinanceExecutor) order(method, orderID string, params url.Values) (*Report, error) {
+	body, err := b.signed(method, binanceOrderPath, params)
+	if err != nil {
+		return nil, err
+	}
+	response := &binanceOrder{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, fmt.Errorf("invalid order response: %v", err)
+	}
+
+	report := &Report{
+		OrderID:    orderID,
+		ExchangeID: strconv.FormatInt(response.OrderID, 10),
+		Status:     Status(response.Status),
+		Filled:     response.ExecutedQty,
+		Price:      response.CumulativeQuoteQty.Div(response.ExecutedQty),
+		Time:       time.Now(),
+	}
+	if response.TransactTime > 0 {
+		report.Time = time.UnixMilli(response.TransactTime)
+	} else if response.UpdateTime > 0 {
+		report.Time = time.UnixMilli(response.UpdateTime)
+	}
+	for _, fill := range response.Fills {
+		report.Fee = report.Fee.Add(fill.Commission)
+		report.FeeAsset = fill.CommissionAsset
+	}
+	return report, nil
+}
+
+// signed sends a request signed with the account's secret and returns its
+// body. The exchange refusing a request is an ErrOrderRejected error,
+// server and network errors are ErrExchangeUnavailable; rate limits are
+// neither.
+func (b *BinanceExecutor) signed(method, path string, params url.Values) ([]byte, error) {
+	if b.credentials.Empty() {
+		return nil, fmt.Errorf("no API keys")
+	}
+	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("recvWindow", strconv.FormatInt(b.recvWindow.Milliseconds(), 10))
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(b.credentials.APISecret))
+	mac.Write([]byte(query))
+	query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	request, err := http.NewRequest(method, b.baseURL+path+"?"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-MBX-APIKEY", b.credentials.APIKey)
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrExchangeUnavailable, "binance", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if response.StatusCode == http.StatusOK {
+		return body, nil
+	}
+
+	apiErr := &binanceError{}
+	if json.Unmarshal(body, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	err = fmt.Errorf("%s %s: %s (code %d)", method, path, apiErr.Message, apiErr.Code)
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusTeapot:
+		return nil, fmt.Errorf("rate limited: %v", err)
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		return nil, errs.Wrap(errs.ErrOrderRejected, "binance", err)
+	case response.StatusCode >= 500:
+		return nil, errs.Wrap(errs.ErrExchangeUnavailable, "binance", err)
+	default:
+		return nil, err
+	}
+}
+
+// clientOrderID shortens an order ID to the 36 characters Binance allows
+// in client order IDs; the UUID of the ID keeps it unique
+func clientOrderID(orderID string) string {
+	id := strings.ReplaceAll(orderID, "-", "")
+	if len(id) > 36 {
+		id = id[len(id)-36:]
+	}
+	return id
+}
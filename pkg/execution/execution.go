@@ -0,0 +1,139 @@
+// Package execution sends orders to exchanges and follows them until they
+// are filled, cancelled or rejected.
+package execution
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/errs"
+)
+
+// OrderType is how an order is priced
+type OrderType string
+
+const (
+	// Order types
+	OrderMarket OrderType = "market" // Filled at the best prices available
+	OrderLimit  OrderType = "limit"  // Filled at the limit price or better, immediately or not at all
+)
+
+// ParseOrderType parses an order type name
+func ParseOrderType(name string) (OrderType, error) {
+	switch OrderType(strings.ToLower(name)) {
+	case "", OrderMarket:
+		return OrderMarket, nil
+	case OrderLimit:
+		return OrderLimit, nil
+	default:
+		return "", fmt.Errorf("unknown order type %q (want market or limit)", name)
+	}
+}
+
+// Status is the state of an order at the exchange
+type Status string
+
+const (
+	// Order states
+	StatusNew             Status = "NEW"
+	StatusPartiallyFilled Status = "PARTIALLY_FILLED"
+	StatusFilled          Status = "FILLED"
+	StatusCanceled        Status = "CANCELED"
+	StatusRejected        Status = "REJECTED"
+	StatusExpired         Status = "EXPIRED"
+)
+
+// Final reports whether an order in the state can no longer fill
+func (s Status) Final() bool {
+	switch s {
+	case StatusFilled, StatusCanceled, StatusRejected, StatusExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// Order is an order to send to an exchange
+type Order struct {
+	ID       string // Client order ID, unique per order
+	Symbol   string
+	Side     string // "buy" or "sell"
+	Type     OrderType
+	Quantity decimal.Decimal
+	Price    decimal.Decimal // Limit price; ignored by market orders
+}
+
+// Report is the state of an order at the exchange
+type Report struct {
+	OrderID    string // Client order ID
+	ExchangeID string // Order ID assigned by the exchange
+	Status     Status
+	Filled     decimal.Decimal // Quantity executed so far
+	Price      decimal.Decimal // Average price of the executed quantity
+	Fee        decimal.Decimal // Fees charged so far, in FeeAsset
+	FeeAsset   string          // Empty when no fee is known yet
+	Time       time.Time
+}
+
+// Executor sends orders to an exchange account
+type Executor interface {
+	// Submit sends an order and returns its state right after the exchange
+	// accepted it. A rejected order returns an ErrOrderRejected error.
+	Submit(order *Order) (*Report, error)
+	// Status returns the current state of an order
+	Status(symbol, orderID string) (*Report, error)
+	// Cancel cancels what is left of an order and returns its final state
+	Cancel(symbol, orderID string) (*Report, error)
+}
+
+// Await polls an order every interval until it can no longer fill and
+// returns its final state. An order still open after timeout is cancelled.
+func Await(executor Executor, symbol string, report *Report, interval, timeout time.Duration) (*Report, error) {
+	deadline := time.Now().Add(timeout)
+	for !report.Status.Final() {
+		var latest *Report
+		var err error
+		if time.Now().After(deadline) {
+			if latest, err = executor.Cancel(symbol, report.OrderID); err != nil {
+				return report, fmt.Errorf("order %s open after %s and not cancelled: %v", report.OrderID, timeout, err)
+			}
+		} else {
+			time.Sleep(interval)
+			if latest, err = executor.Status(symbol, report.OrderID); err != nil {
+				return report, fmt.Errorf("failed to poll order %s: %v", report.OrderID, err)
+			}
+		}
+		// Fees are only known from the fills of the submit response
+		if latest.FeeAsset == "" {
+			latest.Fee, latest.FeeAsset = report.Fee, report.FeeAsset
+		}
+		report = latest
+	}
+	return report, nil
+}
+
+// Execute submits an order and waits for it to be done, returning the
+// quantity filled, if any, with the final state
+func Execute(executor Executor, order *Order, interval, timeout time.Duration) (*Report, error) {
+	report, err := executor.Submit(order)
+	if err != nil {
+		if errors.Is(err, errs.ErrOrderRejected) {
+			return nil, err
+		}
+		// The order may have reached the exchange before the error
+		if report, err = executor.Status(order.Symbol, order.ID); err != nil {
+			return nil, fmt.Errorf("order %s not placed: %w", order.ID, err)
+		}
+	}
+	report, err = Await(executor, order.Symbol, report, interval, timeout)
+	if err != nil {
+		return report, err
+	}
+	if report.Status == StatusRejected {
+		return report, errs.Errorf(errs.ErrOrderRejected, "execution", "order %s rejected by the exchange", order.ID)
+	}
+	return report, nil
+}
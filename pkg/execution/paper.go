@@ -0,0 +1,127 @@
+package execution
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/errs"
+)
+
+// PriceSource returns the best bid and ask of a symbol; ok is false when
+// no price is known
+type PriceSource func(symbol string) (bid, ask decimal.Decimal, ok bool)
+
+// PaperParams configures the simulated fills of a PaperExecutor
+type PaperParams struct {
+	SlippageBps float64         // Adverse price move from the quote, in basis points
+	FeeRate     float64         // Fee as a fraction of the filled notional
+	Latency     time.Duration   // Delay before an order is filled
+	TickSize    decimal.Decimal // Fill prices are rounded away from the trader to ticks
+	// Clock returns the time of a fill; nil uses the wall clock. Replays
+	// of historical data fill at the time of the data.
+	Clock func() time.Time
+}
+
+// PaperExecutor fills orders against live prices without sending them
+// anywhere: buys at the ask and sells at the bid, moved by the slippage,
+// after the latency, with fees charged in the quote asset
+type PaperExecutor struct {
+	prices   PriceSource
+	feeAsset string
+	params   PaperParams
+	orders   map[string]*Report
+	sequence int64
+	mutex    sync.Mutex
+}
+
+// NewPaperExecutor creates an executor filling against the prices of a
+// source and charging fees in feeAsset
+func NewPaperExecutor(prices PriceSource, feeAsset string, params PaperParams) *PaperExecutor {
+	return &PaperExecutor{
+		prices:   prices,
+		feeAsset: feeAsset,
+		params:   params,
+		orders:   make(map[string]*Report),
+	}
+}
+
+// Submit fills an order at the current price after the latency. Limit
+// orders are immediate-or-cancel: they expire unfilled if the fill price
+// is beyond the limit.
+func (p *PaperExecutor) Submit(order *Order) (*Report, error) {
+	if order.Quantity.Sign() <= 0 {
+		return nil, errs.Errorf(errs.ErrOrderRejected, "paper", "invalid quantity %s", order.Quantity)
+	}
+	if p.params.Latency > 0 {
+		time.Sleep(p.params.Latency)
+	}
+	bid, ask, ok := p.prices(order.Symbol)
+	if !ok {
+		return nil, errs.Errorf(errs.ErrOrderRejected, "paper", "no price for %s", order.Symbol)
+	}
+
+	slippage := p.params.SlippageBps / 10000
+	var price decimal.Decimal
+	var beyondLimit bool
+	switch order.Side {
+	case "buy":
+		price = decimal.FromFloat(ask.Float64() * (1 + slippage))
+		if p.params.TickSize.Sign() > 0 {
+			price = price.CeilToStep(p.params.TickSize)
+		}
+		beyondLimit = price.Cmp(order.Price) > 0
+	case "sell":
+		price = decimal.FromFloat(bid.Float64() * (1 - slippage))
+		if p.params.TickSize.Sign() > 0 {
+			price = price.FloorToStep(p.params.TickSize)
+		}
+		beyondLimit = price.Cmp(order.Price) < 0
+	default:
+		return nil, errs.Errorf(errs.ErrOrderRejected, "paper", "invalid side %q", order.Side)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.sequence++
+	report := &Report{
+		OrderID:    order.ID,
+		ExchangeID: "paper-" + strconv.FormatInt(p.sequence, 10),
+		Status:     StatusFilled,
+		Time:       time.Now(),
+	}
+	if p.params.Clock != nil {
+		report.Time = p.params.Clock()
+	}
+	if order.Type == OrderLimit && beyondLimit {
+		report.Status = StatusExpired
+	} else {
+		report.Filled = order.Quantity
+		report.Price = price
+		report.Fee = decimal.FromFloat(price.Mul(order.Quantity).Float64() * p.params.FeeRate)
+		report.FeeAsset = p.feeAsset
+	}
+	p.orders[order.ID] = report
+	copied := *report
+	return &copied, nil
+}
+
+// Status returns the state of an order submitted before
+func (p *PaperExecutor) Status(symbol, orderID string) (*Report, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	report, ok := p.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("unknown order %s", orderID)
+	}
+	copied := *report
+	return &copied, nil
+}
+
+// Cancel returns the final state of an order; paper orders are always done
+// once submitted
+func (p *PaperExecutor) Cancel(symbol, orderID string) (*Report, error) {
+	return p.Status(symbol, orderID)
+}
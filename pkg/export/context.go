@@ -0,0 +1,256 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// maxContextPoints bounds the points kept while waiting for trades to
+// close, should an entry never be closed or rejected
+const maxContextPoints = 200000
+
+// ContextSettings configures the trade contexts recorded
+type ContextSettings struct {
+	Dir    string        // Directory the context files are written to
+	Before time.Duration // Market context before the entry
+	After  time.Duration // Market context after the exit
+	// LosingOnly records the trades closed at a loss only
+	LosingOnly bool
+}
+
+// ContextPoint is the price and metrics of one tick
+type ContextPoint struct {
+	Time  int64   `json:"time"` // Unix milliseconds
+	Price float64 `json:"price"`
+	*types.MarketMetrics
+}
+
+// ContextMarker marks the entry or exit of the trade on the chart
+type ContextMarker struct {
+	Time  int64   `json:"time"` // Unix milliseconds
+	Price float64 `json:"price"`
+	Type  string  `json:"type"` // "entry" or "exit"
+	Label string  `json:"label"`
+}
+
+// ContextTrade describes the trade of a context
+type ContextTrade struct {
+	ID         string          `json:"id"`
+	Symbol     string          `json:"symbol"`
+	Direction  string          `json:"direction"`
+	Strategy   string          `json:"strategy,omitempty"`
+	EntryTime  time.Time       `json:"entry_time"`
+	ExitTime   time.Time       `json:"exit_time"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	ExitPrice  decimal.Decimal `json:"exit_price"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	PnL        decimal.Decimal `json:"pnl"`
+	PnLPercent float64         `json:"pnl_percent"`
+	Reason     string          `json:"reason"`
+	MFE        float64         `json:"mfe_percent"`
+	MAE        float64         `json:"mae_percent"`
+}
+
+// TradeContext is the file written for a trade: the ticks with their
+// metrics from Before its entry to After its exit, and markers at its
+// entry and exit. Times are Unix milliseconds, as charting libraries
+// take them.
+type TradeContext struct {
+	Trade   ContextTrade    `json:"trade"`
+	From    int64           `json:"from"`
+	To      int64           `json:"to"`
+	Markers []ContextMarker `json:"markers"`
+	Points  []ContextPoint  `json:"points"`
+}
+
+// ContextRecorder keeps the recent ticks and metrics of a symbol and
+// writes the market context of each trade closed once the ticks After
+// its exit arrived, or when it is closed
+type ContextRecorder struct {
+	settings ContextSettings
+	symbol   string
+	points   []ContextPoint
+	open     map[string]time.Time // Entry times of the trades not closed yet
+	pending  []*TradeContext      // Closed trades waiting for the ticks after their exit
+	written  int
+	logger   logger.Interface
+	mutex    sync.Mutex
+}
+
+// NewContextRecorder creates a recorder of the trades of symbol, creating
+// the directory of the files
+func NewContextRecorder(symbol string, settings ContextSettings, log logger.Interface) (*ContextRecorder, error) {
+	if settings.Before < 0 || settings.After < 0 {
+		return nil, fmt.Errorf("trade context before and after cannot be negative")
+	}
+	if err := os.MkdirAll(settings.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ContextRecorder{
+		settings: settings,
+		symbol:   symbol,
+		open:     make(map[string]time.Time),
+		logger:   log,
+	}, nil
+}
+
+// Subscribe records the metrics, signals and closed trades of the bus and
+// returns the subscriptions, for unsubscribing
+func (r *ContextRecorder) Subscribe(bus *events.Bus) []events.SubscriptionID {
+	metrics := bus.Subscribe(events.TypeMetrics, func(event events.Event) {
+		if metrics := event.(*events.MetricsEvent); metrics.Symbol == r.symbol {
+			r.observe(metrics.Timestamp, metrics.Price, metrics.Metrics)
+		}
+	})
+	signals := bus.Subscribe(events.TypeSignal, func(event events.Event) {
+		if signal := event.(*events.SignalEvent).Signal; signal.IsEntry() {
+			r.mutex.Lock()
+			r.open[signal.TradeID] = signal.Time
+			r.mutex.Unlock()
+		}
+	})
+	rejections := bus.Subscribe(events.TypeRiskRejected, func(event events.Event) {
+		r.mutex.Lock()
+		delete(r.open, event.(*events.RiskRejectedEvent).TradeID)
+		r.mutex.Unlock()
+	})
+	closed := bus.Subscribe(events.TypeTradeClosed, func(event events.Event) {
+		r.closed(event.(*events.TradeClosedEvent))
+	})
+	return []events.SubscriptionID{metrics, signals, rejections, closed}
+}
+
+// observe adds the point of a tick, writes the contexts it completes and
+// drops the points no context needs
+func (r *ContextRecorder) observe(timestamp time.Time, price float64, metrics *types.MarketMetrics) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	point := ContextPoint{Time: timestamp.UnixMilli(), Price: price}
+	if metrics != nil {
+		point.MarketMetrics = metrics.Finite()
+	}
+	r.points = append(r.points, point)
+
+	pending := r.pending[:0]
+	for _, context := range r.pending {
+		if point.Time > context.To {
+			if err := r.write(context); err != nil {
+				r.logger.Error(fmt.Sprintf("Failed to write the context of trade %s: %v", context.Trade.ID, err),
+					logger.TradeIDKey, context.Trade.ID)
+			}
+			continue
+		}
+		context.Points = append(context.Points, point)
+		pending = append(pending, context)
+	}
+	clear(r.pending[len(pending):])
+	r.pending = pending
+
+	// Keep Before of points, or those since the oldest open entry
+	keep := timestamp.Add(-r.settings.Before)
+	for _, entry := range r.open {
+		if since := entry.Add(-r.settings.Before); since.Before(keep) {
+			keep = since
+		}
+	}
+	drop := 0
+	for drop < len(r.points) && r.points[drop].Time < keep.UnixMilli() {
+		drop++
+	}
+	drop = max(drop, len(r.points)-maxContextPoints)
+	if drop > len(r.points)/2 {
+		r.points = append(r.points[:0:0], r.points[drop:]...)
+	} else if drop > 0 {
+		r.points = r.points[drop:]
+	}
+}
+
+// closed starts the context of a closed trade with the points since
+// Before its entry
+func (r *ContextRecorder) closed(trade *events.TradeClosedEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.open, trade.TradeID)
+	if trade.Symbol != r.symbol || (r.settings.LosingOnly && trade.PnL.Sign() >= 0) {
+		return
+	}
+	context := &TradeContext{
+		Trade: ContextTrade{
+			ID:         trade.TradeID,
+			Symbol:     trade.Symbol,
+			Direction:  trade.Direction,
+			Strategy:   trade.Strategy,
+			EntryTime:  trade.EntryTime,
+			ExitTime:   trade.ExitTime,
+			EntryPrice: trade.EntryPrice,
+			ExitPrice:  trade.ExitPrice,
+			Quantity:   trade.Quantity,
+			PnL:        trade.PnL,
+			PnLPercent: trade.PnLPercent,
+			Reason:     trade.Reason,
+			MFE:        trade.MFE,
+			MAE:        trade.MAE,
+		},
+		From: trade.EntryTime.Add(-r.settings.Before).UnixMilli(),
+		To:   trade.ExitTime.Add(r.settings.After).UnixMilli(),
+		Markers: []ContextMarker{
+			{Time: trade.EntryTime.UnixMilli(), Price: trade.EntryPrice.Float64(), Type: "entry", Label: trade.Direction},
+			{Time: trade.ExitTime.UnixMilli(), Price: trade.ExitPrice.Float64(), Type: "exit", Label: trade.Reason},
+		},
+	}
+	for _, point := range r.points {
+		if point.Time >= context.From && point.Time <= context.To {
+			context.Points = append(context.Points, point)
+		}
+	}
+	r.pending = append(r.pending, context)
+}
+
+// write writes a context to its file
+func (r *ContextRecorder) write(context *TradeContext) error {
+	data, err := json.Marshal(context)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s_%s.json", context.Trade.Symbol,
+		context.Trade.EntryTime.UTC().Format("20060102_150405"), context.Trade.ID)
+	if err := os.WriteFile(filepath.Join(r.settings.Dir, name), data, 0644); err != nil {
+		return err
+	}
+	r.written++
+	return nil
+}
+
+// Written returns the number of context files written
+func (r *ContextRecorder) Written() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.written
+}
+
+// Close writes the contexts still waiting for ticks after their exit
+// with the ticks received
+func (r *ContextRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var first error
+	for _, context := range r.pending {
+		if err := r.write(context); err != nil && first == nil {
+			first = err
+		}
+	}
+	r.pending = nil
+	return first
+}
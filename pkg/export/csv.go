@@ -0,0 +1,84 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/decimal"
+)
+
+// WriteCSV writes a table as CSV with a header row
+func WriteCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Header); err != nil {
+		return err
+	}
+	record := make([]string, len(table.Header))
+	for _, row := range table.Rows {
+		record = record[:0]
+		for _, cell := range row {
+			record = append(record, formatCell(cell))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVFiles writes each table to <prefix>_<table>.csv, e.g.
+// report_trades.csv, and returns the paths written
+func WriteCSVFiles(prefix string, tables []Table) ([]string, error) {
+	if dir := filepath.Dir(prefix); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %v", err)
+		}
+	}
+
+	var paths []string
+	for _, table := range tables {
+		name := strings.ToLower(strings.ReplaceAll(table.Name, " ", "_"))
+		path := fmt.Sprintf("%s_%s.csv", prefix, name)
+		file, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create %s: %v", path, err)
+		}
+		err = WriteCSV(file, table)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// formatCell converts a cell to text; decimals keep their exact digits
+func formatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case decimal.Decimal:
+		return v.String()
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(timeLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,169 @@
+// Package export turns the trade history into CSV files and XLSX workbooks
+// for tax reporting and for sharing results outside the team, and records
+// the market context of each trade as JSON for reviewing it on a chart.
+package export
+
+import (
+	"strings"
+	"time"
+
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/performance"
+	"TRADE/pkg/status"
+	"TRADE/pkg/store"
+	"TRADE/pkg/types"
+)
+
+// Time layouts used in exported cells
+const (
+	dateLayout = "2006-01-02"
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// DailyPnL is the realized result of one calendar day
+type DailyPnL struct {
+	Date          time.Time // Midnight of the day in the report location
+	Trades        int
+	Wins          int
+	Losses        int
+	PnL           decimal.Decimal
+	CumulativePnL decimal.Decimal
+}
+
+// Report holds the trades of a period with their daily and overall results
+type Report struct {
+	From     time.Time // Zero means from the first trade
+	To       time.Time // Zero means up to the last trade
+	Location *time.Location
+	Trades   []store.Trade
+	Daily    []DailyPnL
+	Summary  types.PerformanceMetrics
+}
+
+// NewReport builds a report of trades (oldest first) closed between from
+// and to. Days are calendar days in loc.
+func NewReport(trades []store.Trade, from, to time.Time, loc *time.Location) *Report {
+	report := &Report{From: from, To: to, Location: loc, Trades: trades}
+
+	// The summary is computed exactly as the live performance tracker does
+	tracker := performance.NewTracker(nil)
+	cumulative := decimal.Zero
+	for i := range trades {
+		trade := &trades[i]
+		tracker.Record(trade.Event())
+		cumulative = cumulative.Add(trade.PnL)
+
+		exit := trade.ExitTime.In(loc)
+		date := time.Date(exit.Year(), exit.Month(), exit.Day(), 0, 0, 0, 0, loc)
+		if n := len(report.Daily); n == 0 || !report.Daily[n-1].Date.Equal(date) {
+			report.Daily = append(report.Daily, DailyPnL{Date: date})
+		}
+		day := &report.Daily[len(report.Daily)-1]
+		day.Trades++
+		switch trade.Outcome() {
+		case store.OutcomeWin:
+			day.Wins++
+		case store.OutcomeLoss:
+			day.Losses++
+		}
+		day.PnL = day.PnL.Add(trade.PnL)
+		day.CumulativePnL = cumulative
+	}
+	report.Summary = *tracker.Metrics()
+	return report
+}
+
+// Table is one sheet of an export: a header row and data rows. Cells are
+// strings, ints, float64s, decimals or times.
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// Tables returns the report as trades, daily PnL and summary tables
+func (r *Report) Tables() []Table {
+	return []Table{r.tradesTable(), r.dailyTable(), r.summaryTable()}
+}
+
+// tradesTable lists every trade
+func (r *Report) tradesTable() Table {
+	table := Table{
+		Name: "Trades",
+		Header: []string{"Trade ID", "Run ID", "Mode", "Symbol", "Entry Time", "Exit Time",
+			"Entry Price", "Exit Price", "Quantity", "PnL", "PnL %", "Outcome", "Reason", "Tags", "Notes"},
+	}
+	for _, trade := range r.Trades {
+		table.Rows = append(table.Rows, []interface{}{
+			trade.ID, trade.RunID, trade.Mode, trade.Symbol,
+			trade.EntryTime.In(r.Location), trade.ExitTime.In(r.Location),
+			trade.EntryPrice, trade.ExitPrice, trade.Quantity, trade.PnL, trade.PnLPercent,
+			string(trade.Outcome()), trade.Reason, strings.Join(trade.Tags, ", "), r.notes(trade.Notes),
+		})
+	}
+	return table
+}
+
+// notes joins the notes of a trade into one cell, oldest first
+func (r *Report) notes(notes []store.Note) string {
+	lines := make([]string, len(notes))
+	for i, note := range notes {
+		lines[i] = note.Time.In(r.Location).Format(timeLayout) + " "
+		if note.Author != "" {
+			lines[i] += note.Author + ": "
+		}
+		lines[i] += note.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dailyTable lists the result of every day with trades
+func (r *Report) dailyTable() Table {
+	table := Table{
+		Name:   "Daily PnL",
+		Header: []string{"Date", "Trades", "Wins", "Losses", "PnL", "Cumulative PnL"},
+	}
+	for _, day := range r.Daily {
+		table.Rows = append(table.Rows, []interface{}{
+			day.Date.Format(dateLayout), day.Trades, day.Wins, day.Losses, day.PnL, day.CumulativePnL,
+		})
+	}
+	return table
+}
+
+// summaryTable lists the performance metrics of the period
+func (r *Report) summaryTable() Table {
+	from, to := "", ""
+	if !r.From.IsZero() {
+		from = r.From.In(r.Location).Format(timeLayout)
+	} else if len(r.Trades) > 0 {
+		from = r.Trades[0].ExitTime.In(r.Location).Format(timeLayout)
+	}
+	if !r.To.IsZero() {
+		to = r.To.In(r.Location).Format(timeLayout)
+	} else if len(r.Trades) > 0 {
+		to = r.Trades[len(r.Trades)-1].ExitTime.In(r.Location).Format(timeLayout)
+	}
+
+	metrics := &r.Summary
+	return Table{
+		Name:   "Summary",
+		Header: []string{"Metric", "Value"},
+		Rows: [][]interface{}{
+			{"Period start", from},
+			{"Period end", to},
+			{"Time zone", calendar.ZoneName(r.Location)},
+			{"Total trades", metrics.TotalTrades},
+			{"Winning trades", metrics.WinningTrades},
+			{"Losing trades", metrics.LosingTrades},
+			{"Win rate %", metrics.WinRate},
+			{"Total PnL", metrics.TotalPnL},
+			{"Average PnL", metrics.AveragePnL},
+			{"Max drawdown", metrics.MaxDrawdown},
+			{"Profit factor", status.FormatProfitFactor(metrics)},
+			{"Expectancy %", metrics.Expectancy},
+			{"Exposure time", metrics.ExposureTime.Round(time.Second).String()},
+		},
+	}
+}
@@ -0,0 +1,203 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/decimal"
+)
+
+// Fixed parts of an Office Open XML workbook
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+%s</Types>`
+	xlsxSheetType = `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+%s</sheets>
+</workbook>`
+	xlsxSheetEntry   = `<sheet name="%s" sheetId="%d" r:id="rId%d"/>` + "\n"
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+%s<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+	xlsxSheetRel = `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>` + "\n"
+	// Style 1 is the bold header; style 2 formats serial dates as date and time
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`
+)
+
+// Cell styles defined in xlsxStyles
+const (
+	styleHeader = 1
+	styleTime   = 2
+)
+
+// excelEpoch is day zero of Excel's 1900 date system (after its leap year bug)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// WriteXLSX writes the tables as the sheets of an XLSX workbook. Numbers
+// and times are written as native cells so they can be summed and charted.
+func WriteXLSX(w io.Writer, tables []Table) error {
+	archive := zip.NewWriter(w)
+
+	var types, sheets, rels strings.Builder
+	for i, table := range tables {
+		n := i + 1
+		fmt.Fprintf(&types, xlsxSheetType, n)
+		fmt.Fprintf(&sheets, xlsxSheetEntry, escapeXML(sheetName(table.Name)), n, n)
+		fmt.Fprintf(&rels, xlsxSheetRel, n, n)
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, types.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, rels.String(), len(tables)+1)},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, table := range tables {
+		file, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := writeSheet(file, table); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeSheet writes the worksheet XML of a table
+func writeSheet(w io.Writer, table Table) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// Freeze the header row
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString("<sheetData>")
+
+	header := make([]interface{}, len(table.Header))
+	for i, name := range table.Header {
+		header[i] = name
+	}
+	writeRow(&b, 1, header, styleHeader)
+	for i, row := range table.Rows {
+		writeRow(&b, i+2, row, 0)
+	}
+
+	b.WriteString("</sheetData></worksheet>")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRow writes one row; style applies to text cells
+func writeRow(b *strings.Builder, number int, cells []interface{}, style int) {
+	fmt.Fprintf(b, `<row r="%d">`, number)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(number)
+		switch v := cell.(type) {
+		case int:
+			fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			writeNumber(b, ref, v)
+		case decimal.Decimal:
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, v.String())
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			// Serial days since the epoch, in the time's own zone
+			local := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+			days := local.Sub(excelEpoch).Hours() / 24
+			fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleTime, strconv.FormatFloat(days, 'f', -1, 64))
+		default:
+			text := formatCell(cell)
+			if text == "" {
+				continue
+			}
+			if style != 0 {
+				fmt.Fprintf(b, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, style, escapeXML(text))
+			} else {
+				fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escapeXML(text))
+			}
+		}
+	}
+	b.WriteString("</row>")
+}
+
+// writeNumber writes a numeric cell; values Excel cannot hold become text
+func writeNumber(b *strings.Builder, ref string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, formatCell(value))
+		return
+	}
+	fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// columnName returns the letters of a zero-based column (A, B, ..., AA)
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName makes a table name valid as a sheet name
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	return name
+}
+
+// escapeXML escapes text for XML content and attributes
+func escapeXML(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
@@ -0,0 +1,26 @@
+// Package guard holds the entry guards that veto a new position for
+// reasons outside the strategy's signal, such as market conditions that
+// would make the entry too expensive to be worth taking.
+package guard
+
+import (
+	"fmt"
+)
+
+// Block explains why a guard blocked an entry
+type Block struct {
+	Guard  string  // Guard that blocked the entry, e.g. "liquidity"
+	Reason string  // Rule within the guard, e.g. "spread"
+	Value  float64 // Measured value
+	Limit  float64 // Configured limit
+	Detail string  // What caused the block, if more than the value
+}
+
+// Error describes the block
+func (b *Block) Error() string {
+	msg := fmt.Sprintf("entry blocked by %s guard: %s %.6g (limit %.6g)", b.Guard, b.Reason, b.Value, b.Limit)
+	if b.Detail != "" {
+		msg += ": " + b.Detail
+	}
+	return msg
+}
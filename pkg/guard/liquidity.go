@@ -0,0 +1,67 @@
+package guard
+
+import (
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/market"
+)
+
+// Market is the market state the liquidity guard reads
+type Market interface {
+	Quote() (market.Quote, bool)
+	RecentVolume(window time.Duration) float64
+}
+
+// Liquidity blocks entries while the spread is wider than MaxSpreadBps or
+// less than MinVolume traded within VolumeWindow, when the cost of filling
+// would eat the expected edge. Zero disables a rule.
+type Liquidity struct {
+	MaxSpreadBps float64
+	MinVolume    float64 // In base asset units
+	VolumeWindow time.Duration
+	// MaxQuoteAge ignores quotes older than this; feeds without a book
+	// ticker (simulation, backtests) are never blocked on spread
+	MaxQuoteAge time.Duration
+
+	checked, spreadBlocks, volumeBlocks int64
+}
+
+// LiquidityStats counts the checks of a liquidity guard
+type LiquidityStats struct {
+	Checked      int64 `json:"checked"`
+	SpreadBlocks int64 `json:"spread_blocks"`
+	VolumeBlocks int64 `json:"volume_blocks"`
+}
+
+// Check returns a *Block if the market is too illiquid to enter at now
+func (l *Liquidity) Check(m Market, now time.Time) error {
+	atomic.AddInt64(&l.checked, 1)
+
+	if l.MaxSpreadBps > 0 {
+		quote, ok := m.Quote()
+		if ok && (l.MaxQuoteAge <= 0 || now.Sub(quote.Time) <= l.MaxQuoteAge) {
+			if spread := quote.SpreadBps(); spread > l.MaxSpreadBps {
+				atomic.AddInt64(&l.spreadBlocks, 1)
+				return &Block{Guard: "liquidity", Reason: "spread_bps", Value: spread, Limit: l.MaxSpreadBps}
+			}
+		}
+	}
+
+	if l.MinVolume > 0 && l.VolumeWindow > 0 {
+		if volume := m.RecentVolume(l.VolumeWindow); volume < l.MinVolume {
+			atomic.AddInt64(&l.volumeBlocks, 1)
+			return &Block{Guard: "liquidity", Reason: "volume", Value: volume, Limit: l.MinVolume}
+		}
+	}
+	return nil
+}
+
+// Stats returns the number of checks and blocks
+func (l *Liquidity) Stats() LiquidityStats {
+	return LiquidityStats{
+		Checked:      atomic.LoadInt64(&l.checked),
+		SpreadBlocks: atomic.LoadInt64(&l.spreadBlocks),
+		VolumeBlocks: atomic.LoadInt64(&l.volumeBlocks),
+	}
+}
@@ -0,0 +1,354 @@
+package guard
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Impact is the expected market impact of a scheduled news event
+type Impact int
+
+const (
+	// Impact levels
+	ImpactNone Impact = iota // Holidays and non-economic entries
+	ImpactLow
+	ImpactMedium
+	ImpactHigh
+)
+
+// ParseImpact parses an impact level as published by economic calendars
+func ParseImpact(s string) (Impact, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none", "holiday", "non-economic":
+		return ImpactNone, nil
+	case "low":
+		return ImpactLow, nil
+	case "medium", "moderate":
+		return ImpactMedium, nil
+	case "high":
+		return ImpactHigh, nil
+	}
+	return ImpactNone, fmt.Errorf("unknown impact %q", s)
+}
+
+// String returns the impact level name
+func (i Impact) String() string {
+	switch i {
+	case ImpactLow:
+		return "low"
+	case ImpactMedium:
+		return "medium"
+	case ImpactHigh:
+		return "high"
+	}
+	return "none"
+}
+
+// MarshalText encodes the impact level by name
+func (i Impact) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// NewsEvent is a scheduled economic release or announcement
+type NewsEvent struct {
+	Time     time.Time `json:"time"`
+	Title    string    `json:"title"`
+	Currency string    `json:"currency"` // Currency or country the event concerns, e.g. "USD"
+	Impact   Impact    `json:"impact"`
+}
+
+// String describes the event for logs
+func (e NewsEvent) String() string {
+	return fmt.Sprintf("%s (%s, %s) at %s", e.Title, e.Impact, e.Currency, e.Time.UTC().Format("2006-01-02 15:04 UTC"))
+}
+
+// scheduleEntry is one event of a JSON schedule. Both the plain field
+// names and those of common calendar exports (date, country) are accepted.
+type scheduleEntry struct {
+	Time     string `json:"time"`
+	Date     string `json:"date"`
+	Title    string `json:"title"`
+	Currency string `json:"currency"`
+	Country  string `json:"country"`
+	Impact   string `json:"impact"`
+}
+
+// event converts the entry into a NewsEvent
+func (e scheduleEntry) event() (NewsEvent, error) {
+	value := e.Time
+	if value == "" {
+		value = e.Date
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return NewsEvent{}, fmt.Errorf("event %q: invalid time %q (want RFC 3339)", e.Title, value)
+	}
+	impact, err := ParseImpact(e.Impact)
+	if err != nil {
+		return NewsEvent{}, fmt.Errorf("event %q: %v", e.Title, err)
+	}
+	currency := e.Currency
+	if currency == "" {
+		currency = e.Country
+	}
+	return NewsEvent{
+		Time:     t.UTC(),
+		Title:    strings.TrimSpace(e.Title),
+		Currency: strings.ToUpper(strings.TrimSpace(currency)),
+		Impact:   impact,
+	}, nil
+}
+
+// ParseSchedule reads a news schedule in "json" (an array of events) or
+// "csv" (with a header naming the time, title, impact and currency
+// columns) format. Events are returned in time order.
+func ParseSchedule(r io.Reader, format string) ([]NewsEvent, error) {
+	var entries []scheduleEntry
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid news schedule: %v", err)
+		}
+	case "csv":
+		var err error
+		if entries, err = parseScheduleCSV(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown news schedule format %q", format)
+	}
+
+	events := make([]NewsEvent, 0, len(entries))
+	for _, entry := range entries {
+		event, err := entry.event()
+		if err != nil {
+			return nil, fmt.Errorf("invalid news schedule: %v", err)
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// parseScheduleCSV reads the entries of a CSV schedule
+func parseScheduleCSV(r io.Reader) ([]scheduleEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid news schedule: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["time"]; !ok {
+		return nil, fmt.Errorf("invalid news schedule: no time column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []scheduleEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid news schedule: %v", err)
+		}
+		entries = append(entries, scheduleEntry{
+			Time:     field(record, "time"),
+			Title:    field(record, "title"),
+			Currency: field(record, "currency"),
+			Impact:   field(record, "impact"),
+		})
+	}
+}
+
+// scheduleFormat picks the schedule format from a file name or URL
+func scheduleFormat(name string) string {
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// LoadSchedule reads a news schedule file; files ending in .csv are read
+// as CSV, others as JSON
+func LoadSchedule(path string) ([]NewsEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open news schedule: %v", err)
+	}
+	defer file.Close()
+	return ParseSchedule(file, scheduleFormat(path))
+}
+
+// Blackout blocks entries from Before an event until After it for events
+// of at least MinImpact, and asks for open positions to be flattened
+// FlattenBefore an event. Entries are blocked for at least FlattenBefore,
+// so a flattened position is not reopened before the event.
+type Blackout struct {
+	Before        time.Duration
+	After         time.Duration
+	FlattenBefore time.Duration // 0 keeps positions open through events
+	MinImpact     Impact
+	// Currencies limits the blackout to events concerning these
+	// currencies; empty includes all
+	Currencies []string
+
+	events                    []NewsEvent
+	updated                   time.Time
+	checked, blocks, flattens int64
+	mutex                     sync.Mutex
+}
+
+// BlackoutStats describes the schedule and counts the checks of a
+// blackout guard
+type BlackoutStats struct {
+	Events   int        `json:"events"` // Scheduled events that can cause a blackout
+	Updated  time.Time  `json:"updated"`
+	Next     *NewsEvent `json:"next,omitempty"`
+	Checked  int64      `json:"checked"`
+	Blocks   int64      `json:"blocks"`
+	Flattens int64      `json:"flattens"`
+}
+
+// SetSchedule replaces the scheduled events; events below MinImpact or
+// outside Currencies are dropped
+func (b *Blackout) SetSchedule(events []NewsEvent) {
+	kept := make([]NewsEvent, 0, len(events))
+	for _, event := range events {
+		if b.relevant(event) {
+			kept = append(kept, event)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Time.Before(kept[j].Time) })
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.events = kept
+	b.updated = time.Now()
+}
+
+// relevant reports whether an event can cause a blackout
+func (b *Blackout) relevant(event NewsEvent) bool {
+	if event.Impact == ImpactNone || event.Impact < b.MinImpact {
+		return false
+	}
+	if len(b.Currencies) == 0 {
+		return true
+	}
+	for _, currency := range b.Currencies {
+		if strings.EqualFold(currency, event.Currency) {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first event whose window from before it until after it
+// contains now
+func (b *Blackout) find(now time.Time, before, after time.Duration) (NewsEvent, bool) {
+	// Events are sorted, so skip those whose window ended
+	i := sort.Search(len(b.events), func(i int) bool { return !b.events[i].Time.Add(after).Before(now) })
+	for ; i < len(b.events); i++ {
+		event := b.events[i]
+		if event.Time.Add(-before).After(now) {
+			break
+		}
+		if now.Before(event.Time.Add(after)) {
+			return event, true
+		}
+	}
+	return NewsEvent{}, false
+}
+
+// Check returns a *Block if an entry at now falls into the blackout of a
+// scheduled event
+func (b *Blackout) Check(now time.Time) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.checked++
+
+	before := b.Before
+	if b.FlattenBefore > before {
+		before = b.FlattenBefore
+	}
+	event, ok := b.find(now, before, b.After)
+	if !ok {
+		return nil
+	}
+	b.blocks++
+	return &Block{
+		Guard:  "news",
+		Reason: "minutes_to_event",
+		Value:  event.Time.Sub(now).Minutes(),
+		Limit:  before.Minutes(),
+		Detail: event.String(),
+	}
+}
+
+// Flatten reports whether positions should be closed at now ahead of an
+// upcoming event, and which event
+func (b *Blackout) Flatten(now time.Time) (NewsEvent, bool) {
+	if b.FlattenBefore <= 0 {
+		return NewsEvent{}, false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	event, ok := b.find(now, b.FlattenBefore, 0)
+	if ok {
+		b.flattens++
+	}
+	return event, ok
+}
+
+// Next returns the next event that can cause a blackout after now
+func (b *Blackout) Next(now time.Time) (NewsEvent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	i := sort.Search(len(b.events), func(i int) bool { return b.events[i].Time.After(now) })
+	if i == len(b.events) {
+		return NewsEvent{}, false
+	}
+	return b.events[i], true
+}
+
+// Stats returns the schedule size, the next event and the number of checks,
+// blocks and flattens
+func (b *Blackout) Stats() BlackoutStats {
+	next, ok := b.Next(time.Now())
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	stats := BlackoutStats{
+		Events:   len(b.events),
+		Updated:  b.updated,
+		Checked:  b.checked,
+		Blocks:   b.blocks,
+		Flattens: b.flattens,
+	}
+	if ok {
+		stats.Next = &next
+	}
+	return stats
+}
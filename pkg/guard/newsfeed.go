@@ -0,0 +1,86 @@
+package guard
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+)
+
+// NewsFeed polls a news calendar URL and hands the schedule to a blackout
+// guard
+type NewsFeed struct {
+	blackout *Blackout
+	url      string
+	interval time.Duration
+	client   *http.Client
+	logger   logger.Interface
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewNewsFeed creates a feed refreshing the blackout's schedule every
+// interval
+func NewNewsFeed(blackout *Blackout, url string, interval time.Duration, log logger.Interface) *NewsFeed {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &NewsFeed{
+		blackout: blackout,
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		logger:   log,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start fetches the schedule once and then polls in the background. A
+// failed fetch keeps the previous schedule.
+func (f *NewsFeed) Start() {
+	if err := f.Refresh(); err != nil {
+		f.logger.Warning(fmt.Sprintf("Failed to fetch news calendar: %v", err))
+	}
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := f.Refresh(); err != nil {
+					f.logger.Warning(fmt.Sprintf("Failed to fetch news calendar: %v", err))
+				}
+			case <-f.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling
+func (f *NewsFeed) Stop() {
+	f.stopOnce.Do(func() { close(f.stopChan) })
+}
+
+// Refresh fetches the schedule and replaces the blackout's events
+func (f *NewsFeed) Refresh() error {
+	resp, err := f.client.Get(f.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("news calendar returned %s", resp.Status)
+	}
+
+	events, err := ParseSchedule(resp.Body, scheduleFormat(f.url))
+	if err != nil {
+		return err
+	}
+	f.blackout.SetSchedule(events)
+	f.logger.Debug(fmt.Sprintf("Loaded %d news event(s)", len(events)))
+	return nil
+}
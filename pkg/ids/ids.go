@@ -0,0 +1,107 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ID prefixes, so an ID names the kind of object it refers to
+const (
+	SignalPrefix      = "sig"
+	TradePrefix       = "trd"
+	OrderPrefix       = "ord"
+	FillPrefix        = "fil"
+	CorrelationPrefix = "cor"
+	RunPrefix         = "run"
+)
+
+// generator issues time-ordered UUIDs (version 7). IDs created in the same
+// millisecond are kept ordered by a counter in the random bits; more than
+// the counter holds run into the following milliseconds.
+type generator struct {
+	lastMillis int64
+	counter    uint16
+	mutex      sync.Mutex
+}
+
+// defaultGenerator is the process-wide ID source
+var defaultGenerator = &generator{}
+
+// next returns a new version 7 UUID
+func (g *generator) next() [16]byte {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+
+	g.mutex.Lock()
+	millis := time.Now().UnixMilli()
+	if millis <= g.lastMillis && g.counter < 0x0fff {
+		millis = g.lastMillis
+		g.counter++
+	} else {
+		// A new millisecond, or the 12-bit counter of this one is used up
+		// and the next is taken ahead of the clock
+		if millis <= g.lastMillis {
+			millis = g.lastMillis + 1
+		}
+		g.lastMillis = millis
+		g.counter = binary.BigEndian.Uint16(uuid[6:8]) & 0x07ff
+	}
+	counter := g.counter
+	g.mutex.Unlock()
+
+	// 48-bit timestamp, version, 12-bit counter, variant, random
+	uuid[0] = byte(millis >> 40)
+	uuid[1] = byte(millis >> 32)
+	uuid[2] = byte(millis >> 24)
+	uuid[3] = byte(millis >> 16)
+	uuid[4] = byte(millis >> 8)
+	uuid[5] = byte(millis)
+	uuid[6] = 0x70 | byte(counter>>8)&0x0f
+	uuid[7] = byte(counter)
+	uuid[8] = 0x80 | uuid[8]&0x3f
+	return uuid
+}
+
+// New returns a time-ordered UUID string
+func New() string {
+	u := defaultGenerator.next()
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// NewWithPrefix returns "<prefix>_<uuid>"
+func NewWithPrefix(prefix string) string {
+	return prefix + "_" + New()
+}
+
+// Signal returns a new signal ID
+func Signal() string {
+	return NewWithPrefix(SignalPrefix)
+}
+
+// Trade returns a new trade ID
+func Trade() string {
+	return NewWithPrefix(TradePrefix)
+}
+
+// Order returns a new order ID
+func Order() string {
+	return NewWithPrefix(OrderPrefix)
+}
+
+// Fill returns a new fill ID
+func Fill() string {
+	return NewWithPrefix(FillPrefix)
+}
+
+// Correlation returns a new correlation ID
+func Correlation() string {
+	return NewWithPrefix(CorrelationPrefix)
+}
+
+// Run returns a new run ID, identifying one live, paper or backtest session
+func Run() string {
+	return NewWithPrefix(RunPrefix)
+}
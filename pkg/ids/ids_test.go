@@ -0,0 +1,22 @@
+package ids
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIDsStayOrderedPastTheCounter(t *testing.T) {
+	// Far more IDs than the 12-bit counter holds, within a few milliseconds
+	g := &generator{}
+	previous := g.next()
+	for i := 0; i < 20000; i++ {
+		id := g.next()
+		if bytes.Compare(id[:8], previous[:8]) <= 0 {
+			t.Fatalf("ID %d %x does not sort after %x", i, id[:8], previous[:8])
+		}
+		if id[6]>>4 != 7 || id[8]>>6 != 2 {
+			t.Fatalf("ID %x is not a version 7 UUID", id)
+		}
+		previous = id
+	}
+}
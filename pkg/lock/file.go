@@ -0,0 +1,114 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"TRADE/pkg/daemon"
+)
+
+// FileLocker takes locks as files of a directory, created only if absent,
+// for instances on one host. A lock file left behind by a process of this
+// host that is gone is taken over.
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker creates a locker of the lock files in dir
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	return &FileLocker{dir: dir}, nil
+}
+
+// Acquire creates the lock file of key holding this process
+func (l *FileLocker) Acquire(key string) (Lock, error) {
+	path := filepath.Join(l.dir, key+".lock")
+	holder := self()
+	data, err := json.Marshal(&holder)
+	if err != nil {
+		return nil, err
+	}
+
+	// A second attempt follows the removal of a stale lock file
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %v", err)
+			}
+			return &fileLock{key: key, path: path, holder: holder}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %v", err)
+		}
+
+		current, err := readHolder(path)
+		if os.IsNotExist(err) {
+			continue // Released meanwhile
+		}
+		if err != nil || !stale(current) {
+			// An unreadable lock file may be being written by its holder
+			return nil, fmt.Errorf("%w (lock file %s)", &HeldError{Key: key, Holder: current}, path)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %v", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to create lock file %s: taken and released repeatedly", path)
+}
+
+// Close does nothing: lock files are released one by one
+func (l *FileLocker) Close() error {
+	return nil
+}
+
+// readHolder reads the holder in a lock file
+func readHolder(path string) (Holder, error) {
+	var holder Holder
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return holder, err
+	}
+	err = json.Unmarshal(data, &holder)
+	return holder, err
+}
+
+// stale returns whether the holder of a lock file is a process of this
+// host that is gone. Processes of other hosts are never presumed gone.
+func stale(holder Holder) bool {
+	host, _ := os.Hostname()
+	return holder.Host == host && holder.PID != os.Getpid() && !daemon.ProcessExists(holder.PID)
+}
+
+// fileLock is a lock file held by this process
+type fileLock struct {
+	key    string
+	path   string
+	holder Holder
+}
+
+// Key returns the name of the lock
+func (l *fileLock) Key() string {
+	return l.key
+}
+
+// Release removes the lock file if it still holds this process
+func (l *fileLock) Release() error {
+	current, err := readHolder(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err == nil && (current.PID != l.holder.PID || current.Host != l.holder.Host) {
+		return nil // Taken over by another instance
+	}
+	return os.Remove(l.path)
+}
@@ -0,0 +1,93 @@
+// Package lock keeps two TRADE instances from managing the same position:
+// each instance holds a lock per account and symbol it trades, in a lock
+// file on the host or in a PostgreSQL database shared across hosts.
+package lock
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Holder identifies the instance holding a lock
+type Holder struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// String describes the holder, e.g. "pid 4242 on trader-1 since 2025-03-10T21:44:15Z"
+func (h Holder) String() string {
+	if h.PID == 0 {
+		return "an unknown instance"
+	}
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Host, h.Acquired.UTC().Format(time.RFC3339))
+}
+
+// self returns the holder of the locks this process takes
+func self() Holder {
+	host, _ := os.Hostname()
+	return Holder{PID: os.Getpid(), Host: host, Acquired: time.Now()}
+}
+
+// HeldError is returned when another instance holds a lock
+type HeldError struct {
+	Key    string
+	Holder Holder
+}
+
+// Error names the lock and its holder
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("lock %s is held by %s: another instance manages this account and symbol", e.Key, e.Holder)
+}
+
+// Lock is held by one instance at a time until released
+type Lock interface {
+	// Key returns the name of the lock
+	Key() string
+	// Release gives the lock up
+	Release() error
+}
+
+// Key returns the name of the lock of an account and symbol, e.g.
+// "main-btcusdt"
+func Key(account, symbol string) string {
+	return strings.ToLower(account + "-" + symbol)
+}
+
+// Locker takes locks of one backend
+type Locker interface {
+	// Acquire takes the lock of key, or returns a *HeldError naming the
+	// instance holding it
+	Acquire(key string) (Lock, error)
+	// Close releases the resources of the backend; locks still held are
+	// released with them
+	Close() error
+}
+
+// AcquireAll takes the locks of keys, releasing those taken if one of them
+// is held
+func AcquireAll(locker Locker, keys []string) ([]Lock, error) {
+	locks := make([]Lock, 0, len(keys))
+	for _, key := range keys {
+		lock, err := locker.Acquire(key)
+		if err != nil {
+			ReleaseAll(locks)
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// ReleaseAll releases locks, returning the first error
+func ReleaseAll(locks []Lock) error {
+	var first error
+	for _, lock := range locks {
+		if err := lock.Release(); err != nil && first == nil {
+			first = fmt.Errorf("failed to release lock %s: %v", lock.Key(), err)
+		}
+	}
+	return first
+}
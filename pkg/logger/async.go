@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// logQueueSize bounds the number of entries waiting to be written
+const logQueueSize = 4096
+
+// asyncWriter moves log file writes off the logging goroutines. Entries are
+// queued (blocking only when the queue is full) and written through a
+// buffer that is flushed whenever the queue drains.
+type asyncWriter struct {
+	dest    io.Writer
+	queue   chan []byte
+	flushes chan chan struct{}
+	done    chan struct{}
+	closed  bool
+	mutex   sync.RWMutex
+	direct  sync.Mutex // Serializes the synchronous writes after Close
+}
+
+// newAsyncWriter starts an asynchronous writer in front of dest
+func newAsyncWriter(dest io.Writer) *asyncWriter {
+	w := &asyncWriter{
+		dest:    dest,
+		queue:   make(chan []byte, logQueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p. After Close it writes synchronously, once the
+// queued entries are written.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.closed {
+		<-w.done
+		w.direct.Lock()
+		defer w.direct.Unlock()
+		return w.dest.Write(p)
+	}
+	w.queue <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+// run writes queued entries until the queue is closed
+func (w *asyncWriter) run() {
+	buffered := bufio.NewWriterSize(w.dest, 64*1024)
+	defer close(w.done)
+
+	for {
+		select {
+		case p, ok := <-w.queue:
+			if !ok {
+				buffered.Flush()
+				return
+			}
+			buffered.Write(p)
+			if len(w.queue) == 0 {
+				buffered.Flush()
+			}
+
+		case reply := <-w.flushes:
+			for len(w.queue) > 0 {
+				buffered.Write(<-w.queue)
+			}
+			buffered.Flush()
+			close(reply)
+		}
+	}
+}
+
+// Flush blocks until every queued entry has been written
+func (w *asyncWriter) Flush() {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.closed {
+		return
+	}
+	reply := make(chan struct{})
+	w.flushes <- reply
+	<-reply
+}
+
+// Close writes all queued entries and stops the writer
+func (w *asyncWriter) Close() {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mutex.Unlock()
+
+	<-w.done
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedWriter holds its first write until released and records every write
+type gatedWriter struct {
+	release chan struct{}
+	gated   atomic.Bool
+	mutex   sync.Mutex
+	written strings.Builder
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	if g.gated.CompareAndSwap(false, true) {
+		<-g.release
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.written.Write(p)
+}
+
+func TestWriteAfterCloseWaitsForTheDrain(t *testing.T) {
+	dest := &gatedWriter{release: make(chan struct{})}
+	w := newAsyncWriter(dest)
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("queued\n"))
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	for {
+		w.mutex.RLock()
+		isClosed := w.closed
+		w.mutex.RUnlock()
+		if isClosed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A line logged while the queue drains lands after the queued lines
+	late := make(chan struct{})
+	go func() {
+		w.Write([]byte("late\n"))
+		close(late)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(dest.release)
+	<-closed
+	<-late
+
+	want := strings.Repeat("queued\n", 100) + "late\n"
+	if got := dest.written.String(); got != want {
+		t.Errorf("wrote %d lines ending %q, want the late line last", strings.Count(got, "\n"), got[len(got)-20:])
+	}
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditKind is the type of an order audit record
+type AuditKind string
+
+const (
+	// Order lifecycle stages recorded in the audit log
+	AuditIntent   AuditKind = "INTENT"
+	AuditSubmit   AuditKind = "SUBMIT"
+	AuditResponse AuditKind = "RESPONSE"
+	AuditCancel   AuditKind = "CANCEL"
+	AuditAmend    AuditKind = "AMEND"
+)
+
+// AuditRecord is one append-only line of the order audit log
+type AuditRecord struct {
+	Seq       uint64                 `json:"seq"`
+	Timestamp time.Time              `json:"timestamp"`
+	Kind      AuditKind              `json:"kind"`
+	OrderID   string                 `json:"order_id,omitempty"`
+	Symbol    string                 `json:"symbol,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// AuditLog appends order records as JSON lines with monotonically
+// increasing sequence numbers that continue across restarts
+type AuditLog struct {
+	file    *os.File
+	lastSeq uint64
+	mutex   sync.Mutex
+}
+
+// NewAuditLog opens (or creates) an audit log for appending
+func NewAuditLog(path string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+
+	lastSeq, err := readLastSeq(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &AuditLog{file: file, lastSeq: lastSeq}, nil
+}
+
+// readLastSeq returns the sequence number of the last record in the file
+func readLastSeq(file *os.File) (uint64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		return 0, nil
+	}
+
+	// The last record is within the file's tail
+	tailSize := int64(64 * 1024)
+	if tailSize > info.Size() {
+		tailSize = info.Size()
+	}
+	tail := make([]byte, tailSize)
+	if _, err := file.ReadAt(tail, info.Size()-tailSize); err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read audit log: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(tail), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var record AuditRecord
+		if err := json.Unmarshal(lines[i], &record); err == nil {
+			return record.Seq, nil
+		}
+	}
+	return 0, fmt.Errorf("audit log has no readable records")
+}
+
+// Record appends a record, assigning the next sequence number
+func (a *AuditLog) Record(record AuditRecord) (uint64, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	record.Seq = a.lastSeq + 1
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode audit record: %v", err)
+	}
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write audit record: %v", err)
+	}
+	if err := a.file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync audit log: %v", err)
+	}
+
+	a.lastSeq = record.Seq
+	return record.Seq, nil
+}
+
+// Close closes the audit log file
+func (a *AuditLog) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.file.Close()
+}
+
+// EnableAudit starts recording order events to the audit log at path
+func (l *Logger) EnableAudit(path string) error {
+	audit, err := NewAuditLog(path)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	oldAudit := l.audit
+	l.audit = audit
+	l.mutex.Unlock()
+
+	if oldAudit != nil {
+		oldAudit.Close()
+	}
+	return nil
+}
+
+// Audit records an order event in the audit log (if enabled)
+func (l *Logger) Audit(kind AuditKind, orderID string, symbol string, details map[string]interface{}) {
+	l.mutex.Lock()
+	audit := l.audit
+	l.mutex.Unlock()
+
+	if audit == nil {
+		return
+	}
+	record := AuditRecord{
+		Kind:    kind,
+		OrderID: orderID,
+		Symbol:  symbol,
+		Details: details,
+	}
+	if _, err := audit.Record(record); err != nil {
+		l.Error(fmt.Sprintf("Failed to write audit log: %v", err))
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repeatedEntry tracks suppressed repeats of one message
+type repeatedEntry struct {
+	handler  slog.Handler // Handler the summary is written to
+	record   slog.Record  // Last suppressed record
+	first    time.Time    // When the window started
+	repeated int
+}
+
+// dedupState is shared by a dedup handler and all handlers derived from it
+type dedupState struct {
+	window  time.Duration
+	entries map[string]*repeatedEntry
+	mutex   sync.Mutex
+}
+
+// dedupHandler collapses identical messages logged within a window into a
+// single "message repeated N times" summary
+type dedupHandler struct {
+	inner  slog.Handler
+	state  *dedupState
+	prefix string // Attributes added with WithAttrs, part of the message key
+}
+
+// newDedupHandler wraps a handler with deduplication of repeated messages
+func newDedupHandler(inner slog.Handler, window time.Duration) *dedupHandler {
+	return &dedupHandler{
+		inner: inner,
+		state: &dedupState{
+			window:  window,
+			entries: make(map[string]*repeatedEntry),
+		},
+	}
+}
+
+// Enabled reports whether the wrapped handler logs the level
+func (h *dedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle logs the first occurrence of a message in each window and counts
+// the repeats
+func (h *dedupHandler) Handle(ctx context.Context, record slog.Record) error {
+	key := fmt.Sprintf("%d|%s|%s", record.Level, h.prefix, record.Message)
+	now := record.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	h.state.mutex.Lock()
+	expired := h.state.expire(now)
+	entry, seen := h.state.entries[key]
+	if seen {
+		entry.repeated++
+		entry.record = record.Clone()
+	} else {
+		h.state.entries[key] = &repeatedEntry{handler: h.inner, first: now}
+	}
+	h.state.mutex.Unlock()
+
+	writeSummaries(ctx, expired)
+	if seen {
+		return nil
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler adding the attributes, sharing repeat tracking
+func (h *dedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	for _, attr := range attrs {
+		b.WriteString(attr.String() + " ")
+	}
+	return &dedupHandler{inner: h.inner.WithAttrs(attrs), state: h.state, prefix: b.String()}
+}
+
+// WithGroup returns a handler grouping subsequent attributes
+func (h *dedupHandler) WithGroup(name string) slog.Handler {
+	return &dedupHandler{inner: h.inner.WithGroup(name), state: h.state, prefix: h.prefix + name + "."}
+}
+
+// flush writes summaries for all suppressed repeats (used on close)
+func (h *dedupHandler) flush() {
+	h.state.mutex.Lock()
+	expired := h.state.expire(time.Time{})
+	h.state.mutex.Unlock()
+
+	writeSummaries(context.Background(), expired)
+}
+
+// expire removes entries whose window ended before now (all entries for a
+// zero time) and returns those with suppressed repeats
+func (s *dedupState) expire(now time.Time) []*repeatedEntry {
+	var expired []*repeatedEntry
+	for key, entry := range s.entries {
+		if !now.IsZero() && now.Sub(entry.first) < s.window {
+			continue
+		}
+		delete(s.entries, key)
+		if entry.repeated > 0 {
+			expired = append(expired, entry)
+		}
+	}
+	return expired
+}
+
+// writeSummaries logs a "message repeated N times" entry per repeated message
+func writeSummaries(ctx context.Context, entries []*repeatedEntry) {
+	for _, entry := range entries {
+		summary := slog.NewRecord(entry.record.Time, entry.record.Level,
+			fmt.Sprintf("%s (message repeated %d times)", entry.record.Message, entry.repeated), 0)
+		entry.record.Attrs(func(attr slog.Attr) bool {
+			summary.AddAttrs(attr)
+			return true
+		})
+		summary.AddAttrs(slog.Int("repeated", entry.repeated))
+		entry.handler.Handle(ctx, summary)
+	}
+}
@@ -0,0 +1,271 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/calendar"
+)
+
+// Attribute keys with special meaning in TRADE log entries
+const (
+	ComponentKey = "component"
+	SymbolKey    = "symbol"
+	StrategyKey  = "strategy"
+	TradeIDKey   = "trade_id"
+	OrderIDKey   = "order_id"
+	SignalIDKey  = "signal_id"
+	// CorrelationIDKey ties a signal to its orders, fills and journal rows
+	CorrelationIDKey = "correlation_id"
+	// TraceIDKey ties a log entry to the trace of its tick and signal
+	TraceIDKey = "trace_id"
+)
+
+// LevelCritical is the slog level used for CRITICAL entries
+const LevelCritical = slog.Level(12)
+
+// toSlogLevel converts a LogLevel to the matching slog level
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DEBUG:
+		return slog.LevelDebug
+	case WARNING:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	case CRITICAL:
+		return LevelCritical
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// levelName returns the TRADE name of a slog level
+func levelName(level slog.Level) string {
+	switch {
+	case level >= LevelCritical:
+		return CRITICAL.String()
+	case level >= slog.LevelError:
+		return ERROR.String()
+	case level >= slog.LevelWarn:
+		return WARNING.String()
+	case level >= slog.LevelInfo:
+		return INFO.String()
+	default:
+		return DEBUG.String()
+	}
+}
+
+// switchWriter is an io.Writer whose destination can be replaced (log reopen)
+type switchWriter struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+// Write writes to the current destination
+func (w *switchWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(p)
+}
+
+// set replaces the destination
+func (w *switchWriter) set(writer io.Writer) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.writer = writer
+}
+
+// NewJSONHandler returns a slog JSON handler using TRADE field names
+// (timestamp, level, message) and level names (WARNING, CRITICAL)
+func NewJSONHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				a.Key = "timestamp"
+			case slog.MessageKey:
+				a.Key = "message"
+			case slog.LevelKey:
+				if level, ok := a.Value.Any().(slog.Level); ok {
+					a.Value = slog.StringValue(levelName(level))
+				}
+			}
+			return a
+		},
+	})
+}
+
+// TextHandler writes entries as
+// "2006/01/02 15:04:05 [LEVEL] [component] [symbol] message key=value"
+type TextHandler struct {
+	writer    io.Writer
+	level     slog.Leveler
+	component string
+	symbol    string
+	attrs     []slog.Attr
+	group     string
+}
+
+// NewTextHandler creates a handler writing TRADE's text log format
+func NewTextHandler(w io.Writer, level slog.Leveler) *TextHandler {
+	return &TextHandler{writer: w, level: level}
+}
+
+// Enabled reports whether the level is logged
+func (h *TextHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats and writes a record
+func (h *TextHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	timestamp := record.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	b.WriteString(calendar.Display(timestamp).Format("2006/01/02 15:04:05"))
+	b.WriteString(" [" + levelName(record.Level) + "]")
+
+	component, symbol := h.component, h.symbol
+	var fields strings.Builder
+	for _, attr := range h.attrs {
+		writeTextAttr(&fields, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		switch {
+		case h.group == "" && attr.Key == ComponentKey:
+			component = attr.Value.String()
+		case h.group == "" && attr.Key == SymbolKey:
+			symbol = attr.Value.String()
+		default:
+			writeTextAttr(&fields, h.group, attr)
+		}
+		return true
+	})
+
+	if component != "" {
+		b.WriteString(" [" + component + "]")
+	}
+	if symbol != "" {
+		b.WriteString(" [" + symbol + "]")
+	}
+	b.WriteString(" " + record.Message)
+	b.WriteString(fields.String())
+	b.WriteString("\n")
+
+	_, err := io.WriteString(h.writer, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that adds the attributes to every entry
+func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		switch {
+		case h.group == "" && attr.Key == ComponentKey:
+			child.component = attr.Value.String()
+		case h.group == "" && attr.Key == SymbolKey:
+			child.symbol = attr.Value.String()
+		default:
+			if h.group != "" {
+				attr.Key = h.group + "." + attr.Key
+			}
+			child.attrs = append(child.attrs, attr)
+		}
+	}
+	return &child
+}
+
+// WithGroup returns a handler that prefixes attribute keys with the group name
+func (h *TextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	if h.group != "" {
+		child.group = h.group + "." + name
+	} else {
+		child.group = name
+	}
+	return &child
+}
+
+// writeTextAttr appends " key=value" for an attribute
+func writeTextAttr(b *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if group != "" {
+		key = group + "." + key
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, member := range attr.Value.Group() {
+			writeTextAttr(b, key, member)
+		}
+		return
+	}
+
+	b.WriteString(fmt.Sprintf(" %s=%v", key, attr.Value.Any()))
+}
+
+// multiHandler fans records out to several handlers
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+// Enabled reports whether any handler logs the level
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to every handler that logs its level
+func (h *multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WithAttrs applies the attributes to every handler
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// WithGroup applies the group to every handler
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+)
+
+// journalHeader lists the trade journal CSV columns
+var journalHeader = []string{
+	"timestamp", "symbol", "action", "side", "price", "quantity", "notional",
+	"reason", "profit_percent", "pnl", "execution_mode", "trade_id",
+	"correlation_id", "order_id", "mfe_percent", "mae_percent",
+}
+
+// JournalEntry is one executed or simulated trade in the journal
+type JournalEntry struct {
+	Time          time.Time
+	Symbol        string
+	Action        string // BUY, CLOSE, ...
+	Side          string
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	Notional      decimal.Decimal
+	Reason        string
+	ProfitPercent float64
+	PnL           decimal.Decimal
+	ExecutionMode string
+	TradeID       string
+	CorrelationID string
+	OrderID       string
+	MFE           float64 // Excursions of the trade a CLOSE exits, in percent
+	MAE           float64
+}
+
+// record converts the entry to a CSV row
+func (e JournalEntry) record() []string {
+	return []string{
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Symbol,
+		e.Action,
+		e.Side,
+		e.Price.String(),
+		e.Quantity.String(),
+		e.Notional.String(),
+		e.Reason,
+		strconv.FormatFloat(e.ProfitPercent, 'f', 4, 64),
+		e.PnL.String(),
+		e.ExecutionMode,
+		e.TradeID,
+		e.CorrelationID,
+		e.OrderID,
+		strconv.FormatFloat(e.MFE, 'f', 4, 64),
+		strconv.FormatFloat(e.MAE, 'f', 4, 64),
+	}
+}
+
+// TradeJournal appends trades to a CSV file, separate from the text log
+type TradeJournal struct {
+	file   *os.File
+	writer *csv.Writer
+	mutex  sync.Mutex
+}
+
+// NewTradeJournal opens (or creates) a journal file for appending. A
+// journal written with other columns is moved aside to a file named after
+// its modification time, so every file has a single header.
+func NewTradeJournal(path string) (*TradeJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %v", err)
+	}
+	if err := rotateStaleJournal(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trade journal: %v", err)
+	}
+
+	journal := &TradeJournal{
+		file:   file,
+		writer: csv.NewWriter(file),
+	}
+
+	// Write the header for a new journal
+	info, err := file.Stat()
+	if err == nil && info.Size() == 0 {
+		journal.writer.Write(journalHeader)
+		journal.writer.Flush()
+	}
+
+	return journal, nil
+}
+
+// rotateStaleJournal renames the journal at path if its header is not
+// journalHeader
+func rotateStaleJournal(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open trade journal: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open trade journal: %v", err)
+	}
+	header, err := bufio.NewReader(file).ReadString('\n')
+	file.Close()
+	if info.Size() == 0 || err == nil && strings.TrimRight(header, "\r\n") == strings.Join(journalHeader, ",") {
+		return nil
+	}
+
+	ext := filepath.Ext(path)
+	stale := strings.TrimSuffix(path, ext) + "." + info.ModTime().Format("20060102T150405") + ext
+	if err := os.Rename(path, stale); err != nil {
+		return fmt.Errorf("failed to move aside trade journal with old columns: %v", err)
+	}
+	return nil
+}
+
+// Record appends a trade and flushes it to disk
+func (j *TradeJournal) Record(entry JournalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if err := j.writer.Write(entry.record()); err != nil {
+		return err
+	}
+	j.writer.Flush()
+	return j.writer.Error()
+}
+
+// Close flushes and closes the journal file
+func (j *TradeJournal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.writer.Flush()
+	return j.file.Close()
+}
+
+// EnableJournal starts recording trades to the CSV journal at path
+func (l *Logger) EnableJournal(path string) error {
+	journal, err := NewTradeJournal(path)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	oldJournal := l.journal
+	l.journal = journal
+	l.mutex.Unlock()
+
+	if oldJournal != nil {
+		oldJournal.Close()
+	}
+	return nil
+}
+
+// LogTrade records a trade in the journal (if enabled) and the regular log
+func (l *Logger) LogTrade(entry JournalEntry) {
+	l.Info(fmt.Sprintf("Trade %s %s at %s", entry.Action, entry.Symbol, entry.Price),
+		"action", entry.Action, "price", entry.Price, "quantity", entry.Quantity,
+		"reason", entry.Reason, "pnl", entry.PnL)
+
+	l.mutex.Lock()
+	journal := l.journal
+	l.mutex.Unlock()
+
+	if journal == nil {
+		return
+	}
+	if err := journal.Record(entry); err != nil {
+		l.Error(fmt.Sprintf("Failed to write trade journal: %v", err))
+	}
+}
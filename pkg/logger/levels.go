@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// componentLevels holds the global minimum level and per-component overrides
+type componentLevels struct {
+	global *slog.LevelVar
+	levels map[string]slog.Level
+	mutex  sync.RWMutex
+}
+
+// newComponentLevels creates component levels on top of the global level
+func newComponentLevels(global *slog.LevelVar) *componentLevels {
+	return &componentLevels{global: global, levels: make(map[string]slog.Level)}
+}
+
+// set overrides the minimum level of a component
+func (c *componentLevels) set(component string, level slog.Level) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.levels[component] = level
+}
+
+// levelFor returns the minimum level of a component
+func (c *componentLevels) levelFor(component string) slog.Level {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if level, ok := c.levels[component]; ok {
+		return level
+	}
+	return c.global.Level()
+}
+
+// Level returns the lowest level any component logs at, so handlers below
+// the level filter let through everything it allows
+func (c *componentLevels) Level() slog.Level {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	min := c.global.Level()
+	for _, level := range c.levels {
+		if level < min {
+			min = level
+		}
+	}
+	return min
+}
+
+// levelHandler filters entries by the minimum level of their component
+type levelHandler struct {
+	inner     slog.Handler
+	levels    *componentLevels
+	component string
+}
+
+// Enabled reports whether the component logs the level
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	// The component may still be set on the record itself; Handle decides
+	return level >= h.levels.Level() && h.inner.Enabled(ctx, level)
+}
+
+// Handle passes the record on if its component logs its level
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	component := h.component
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == ComponentKey {
+			component = attr.Value.String()
+			return false
+		}
+		return true
+	})
+
+	if record.Level < h.levels.levelFor(component) {
+		return nil
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler that tracks the component attribute
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := &levelHandler{inner: h.inner.WithAttrs(attrs), levels: h.levels, component: h.component}
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			child.component = attr.Value.String()
+		}
+	}
+	return child
+}
+
+// WithGroup returns a handler grouping subsequent attributes
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{inner: h.inner.WithGroup(name), levels: h.levels, component: h.component}
+}
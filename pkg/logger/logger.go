@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,190 +27,444 @@ const (
 	CRITICAL
 )
 
-// Logger provides logging functionality with different severity levels
-type Logger struct {
+// String returns the name of the log level
+func (l LogLevel) String() string {
+	switch l {
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARNING:
+		return "WARNING"
+	case ERROR:
+		return "ERROR"
+	case CRITICAL:
+		return "CRITICAL"
+	default:
+		return "INFO"
+	}
+}
+
+// ParseLevel converts a level name (debug, info, warning, error, critical)
+// to a LogLevel
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return DEBUG, nil
+	case "", "info":
+		return INFO, nil
+	case "warning", "warn":
+		return WARNING, nil
+	case "error":
+		return ERROR, nil
+	case "critical":
+		return CRITICAL, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// Format defines how log entries are encoded
+type Format int
+
+const (
+	// Log formats
+	FormatText Format = iota
+	FormatJSON
+)
+
+// ParseFormat converts a format name ("text" or "json") to a Format
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("unknown log format: %s", name)
+	}
+}
+
+// ConsolePolicy selects which entries are mirrored to the console
+type ConsolePolicy int
+
+const (
+	// Console policies
+	ConsoleErrors  ConsolePolicy = iota // ERROR and CRITICAL only (default)
+	ConsoleQuiet                        // Nothing; for daemon operation
+	ConsoleVerbose                      // Everything written to the log file
+)
+
+// ParseConsolePolicy converts a policy name ("errors", "quiet" or
+// "verbose") to a ConsolePolicy
+func ParseConsolePolicy(name string) (ConsolePolicy, error) {
+	switch name {
+	case "", "errors":
+		return ConsoleErrors, nil
+	case "quiet":
+		return ConsoleQuiet, nil
+	case "verbose":
+		return ConsoleVerbose, nil
+	default:
+		return ConsoleErrors, fmt.Errorf("unknown console policy: %s", name)
+	}
+}
+
+// output is the state shared by a logger and all loggers derived from it
+type output struct {
 	logFile    *os.File
-	logger     *log.Logger
-	level      LogLevel
+	fileWriter *switchWriter
+	async      *asyncWriter // Queues file writes in front of fileWriter
+	level      *slog.LevelVar
+	levels     *componentLevels // Per-component overrides of level
 	mutex      sync.Mutex
-	statusChan chan string
-	statusDone chan struct{}
+	execMode   types.ExecutionMode
+	journal    *TradeJournal
+	audit      *AuditLog
+	shipper    *remoteShipper
+	dedup      *dedupHandler
+	reporter   *errorReporter
+	closeOnce  sync.Once
 }
 
-// NewLogger creates a new logger instance
+// Logger provides logging functionality with different severity levels on
+// top of log/slog. Loggers derived with WithComponent/WithSymbol share the
+// same output.
+type Logger struct {
+	*output
+	slog *slog.Logger
+}
+
+// Interface is the leveled logging API consumed by trading components.
+// *Logger implements it; tests and embedders may inject their own.
+type Interface interface {
+	Debug(message string, fields ...interface{})
+	Info(message string, fields ...interface{})
+	Warning(message string, fields ...interface{})
+	Error(message string, fields ...interface{})
+	Critical(message string, fields ...interface{})
+	// With returns a logger that adds the key/value fields to every entry
+	With(fields ...interface{}) Interface
+}
+
+// Options configures a new logger
+type Options struct {
+	Level  LogLevel
+	Format Format
+	// ComponentLevels overrides Level for components (e.g. market=DEBUG)
+	ComponentLevels map[string]LogLevel
+	// Console selects which entries are mirrored to the console
+	Console ConsolePolicy
+	// Handler replaces the built-in file handler (e.g. a zap or zerolog
+	// slog adapter). It should honor Level for dynamic level changes.
+	Handler func(w io.Writer, level slog.Leveler) slog.Handler
+	// Shipping optionally sends entries to a remote syslog or Loki endpoint
+	Shipping *ShippingOptions
+	// DedupWindow collapses identical messages repeated within the window
+	// into a "message repeated N times" summary; zero disables it
+	DedupWindow time.Duration
+	// ErrorTracker optionally receives ERROR/CRITICAL entries and panics
+	// (e.g. Sentry) with stack traces and runtime context
+	ErrorTracker ErrorTracker
+}
+
+// DefaultOptions returns the default logger options
+func DefaultOptions() Options {
+	return Options{
+		Level:  INFO,
+		Format: FormatText,
+	}
+}
+
+// NewLogger creates a new logger instance with default options
 func NewLogger() *Logger {
-	// Create logs directory if it doesn't exist
-	logsDir := "logs"
-	if _, err := os.Stat(logsDir); os.IsNotExist(err) {
-		os.Mkdir(logsDir, 0755)
+	return NewLoggerWithOptions(DefaultOptions())
+}
+
+// NewLoggerWithOptions creates a new logger instance
+func NewLoggerWithOptions(opts Options) *Logger {
+	out := &output{
+		fileWriter: &switchWriter{writer: os.Stdout},
+		level:      &slog.LevelVar{},
+		execMode:   types.ExecutionPaper,
+	}
+	out.level.Set(toSlogLevel(opts.Level))
+	out.levels = newComponentLevels(out.level)
+	for component, level := range opts.ComponentLevels {
+		out.levels.set(component, toSlogLevel(level))
 	}
+	out.async = newAsyncWriter(out.fileWriter)
 
-	// Create log file with timestamp in name
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	logPath := filepath.Join(logsDir, fmt.Sprintf("trade_%s.log", timestamp))
-	
-	file, err := os.Create(logPath)
+	file, err := createLogFile()
 	if err != nil {
 		log.Printf("Failed to create log file: %v", err)
-		return &Logger{
-			logger:     log.New(os.Stdout, "", log.LstdFlags),
-			level:      INFO,
-			statusChan: make(chan string, 10),
-			statusDone: make(chan struct{}),
+	} else {
+		out.logFile = file
+		out.fileWriter.set(file)
+	}
+
+	// File handler, pluggable
+	var fileHandler slog.Handler
+	switch {
+	case opts.Handler != nil:
+		fileHandler = opts.Handler(out.async, out.levels)
+	case opts.Format == FormatJSON:
+		fileHandler = NewJSONHandler(out.async, out.levels)
+	default:
+		fileHandler = NewTextHandler(out.async, out.levels)
+	}
+
+	// Mirror entries to the console according to the policy
+	handlers := []slog.Handler{fileHandler}
+	switch opts.Console {
+	case ConsoleErrors:
+		handlers = append(handlers, NewTextHandler(os.Stderr, slog.LevelError))
+	case ConsoleVerbose:
+		handlers = append(handlers, NewTextHandler(os.Stderr, out.levels))
+	}
+
+	// Ship entries to a remote endpoint
+	if opts.Shipping != nil {
+		shipper, err := newRemoteShipper(*opts.Shipping)
+		if err != nil {
+			log.Printf("Log shipping disabled: %v", err)
+		} else {
+			out.shipper = shipper
+			handlers = append(handlers, newShippingHandler(shipper, toSlogLevel(opts.Shipping.Level)))
 		}
 	}
-	
-	// Create logger with file and stdout output
-	logger := log.New(file, "", log.LstdFlags)
-	
-	// Start status reporter
+
+	// Report errors to an external tracker
+	if opts.ErrorTracker != nil {
+		out.reporter = newErrorReporter(opts.ErrorTracker)
+		handlers = append(handlers, &trackerHandler{out: out})
+	}
+
+	var handler slog.Handler = &multiHandler{handlers: handlers}
+
+	// Collapse storms of identical messages
+	if opts.DedupWindow > 0 {
+		out.dedup = newDedupHandler(handler, opts.DedupWindow)
+		handler = out.dedup
+	}
+
+	// Apply per-component levels before anything else
+	handler = &levelHandler{inner: handler, levels: out.levels}
+
 	l := &Logger{
-		logFile:    file,
-		logger:     logger,
-		level:      INFO,
-		statusChan: make(chan string, 10),
-		statusDone: make(chan struct{}),
-	}
-	
-	go l.statusReporter()
-	
+		output: out,
+		slog:   slog.New(handler),
+	}
+	if file == nil {
+		return l
+	}
+
 	l.Info("Logger initialized")
 	return l
 }
 
-// statusReporter prints status updates to the console
-func (l *Logger) statusReporter() {
-	for {
-		select {
-		case status := <-l.statusChan:
-			fmt.Println(status)
-		case <-l.statusDone:
-			return
-		}
+// NewLoggerWithHandler creates a logger writing only to the given slog handler
+func NewLoggerWithHandler(handler slog.Handler) *Logger {
+	out := &output{
+		fileWriter: &switchWriter{writer: io.Discard},
+		level:      &slog.LevelVar{},
+		execMode:   types.ExecutionPaper,
 	}
+
+	return &Logger{output: out, slog: slog.New(handler)}
 }
 
-// SetLevel sets the minimum log level
-func (l *Logger) SetLevel(level LogLevel) {
+// Slog returns the underlying slog logger for native key/value logging
+func (l *Logger) Slog() *slog.Logger {
+	return l.slog
+}
+
+// WithComponent returns a logger that tags every entry with a component name
+func (l *Logger) WithComponent(component string) *Logger {
+	return &Logger{output: l.output, slog: l.slog.With(ComponentKey, component)}
+}
+
+// WithSymbol returns a logger that tags every entry with a symbol
+func (l *Logger) WithSymbol(symbol string) *Logger {
+	return &Logger{output: l.output, slog: l.slog.With(SymbolKey, symbol)}
+}
+
+// WithStrategy returns a logger that tags every entry with a strategy name
+func (l *Logger) WithStrategy(name string) *Logger {
+	return &Logger{output: l.output, slog: l.slog.With(StrategyKey, name)}
+}
+
+// WithTradeID returns a logger that tags every entry with a trade ID.
+// An empty ID returns the logger unchanged.
+func (l *Logger) WithTradeID(id string) *Logger {
+	if id == "" {
+		return l
+	}
+	return &Logger{output: l.output, slog: l.slog.With(TradeIDKey, id)}
+}
+
+// WithOrderID returns a logger that tags every entry with an order ID.
+// An empty ID returns the logger unchanged.
+func (l *Logger) WithOrderID(id string) *Logger {
+	if id == "" {
+		return l
+	}
+	return &Logger{output: l.output, slog: l.slog.With(OrderIDKey, id)}
+}
+
+// With returns a logger that adds the key/value fields to every entry
+func (l *Logger) With(fields ...interface{}) Interface {
+	return &Logger{output: l.output, slog: l.slog.With(fields...)}
+}
+
+// createLogFile creates a new session log file with a timestamp in its name
+func createLogFile() (*os.File, error) {
+	// Create logs directory if it doesn't exist
+	logsDir := "logs"
+	if _, err := os.Stat(logsDir); os.IsNotExist(err) {
+		os.Mkdir(logsDir, 0755)
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	logPath := filepath.Join(logsDir, fmt.Sprintf("trade_%s.log", timestamp))
+
+	// Append so a reopen within the same second does not truncate the file
+	return os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+}
+
+// Reopen closes the current log file and starts a new one (used on SIGHUP)
+func (l *Logger) Reopen() error {
+	file, err := createLogFile()
+	if err != nil {
+		return err
+	}
+
+	// Entries queued so far belong in the old file
+	if l.async != nil {
+		l.async.Flush()
+	}
+
+	l.mutex.Lock()
+	oldFile := l.logFile
+	l.logFile = file
+	l.fileWriter.set(file)
+	l.mutex.Unlock()
+
+	if oldFile != nil {
+		oldFile.Close()
+	}
+
+	l.Info("Log file reopened")
+	l.writeHeader()
+	return nil
+}
+
+// SetExecutionMode records the execution mode and writes it to the log header
+func (l *Logger) SetExecutionMode(mode types.ExecutionMode) {
 	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	l.level = level
+	l.execMode = mode
+	l.mutex.Unlock()
+
+	l.writeHeader()
 }
 
-// log writes a log message with the specified level
-func (l *Logger) log(level LogLevel, message string) {
+// writeHeader writes a session header naming the execution mode
+func (l *Logger) writeHeader() {
 	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	
-	if level < l.level {
-		return
-	}
-	
-	levelStr := "INFO"
-	switch level {
-	case DEBUG:
-		levelStr = "DEBUG"
-	case INFO:
-		levelStr = "INFO"
-	case WARNING:
-		levelStr = "WARNING"
-	case ERROR:
-		levelStr = "ERROR"
-	case CRITICAL:
-		levelStr = "CRITICAL"
+	execMode := l.execMode
+	l.mutex.Unlock()
+
+	header := fmt.Sprintf("=== TRADE session | EXECUTION MODE: %s ===", execMode)
+	l.slog.Info(header, "execution_mode", string(execMode))
+	if execMode == types.ExecutionLive {
+		log.Println(header)
 	}
-	
-	logMessage := fmt.Sprintf("[%s] %s", levelStr, message)
-	l.logger.Println(logMessage)
-	
-	// Also print to stdout for ERROR and CRITICAL
-	if level >= ERROR {
-		log.Println(logMessage)
+}
+
+// SetLevel sets the minimum log level
+func (l *Logger) SetLevel(level LogLevel) {
+	l.level.Set(toSlogLevel(level))
+}
+
+// SetComponentLevel sets the minimum log level of one component
+func (l *Logger) SetComponentLevel(component string, level LogLevel) {
+	if l.levels != nil {
+		l.levels.set(component, toSlogLevel(level))
 	}
 }
 
-// Debug logs a debug message
-func (l *Logger) Debug(message string) {
-	l.log(DEBUG, message)
+// log writes a log message with the specified level and key/value fields
+func (l *Logger) log(level LogLevel, message string, fields []interface{}) {
+	l.slog.Log(context.Background(), toSlogLevel(level), message, fields...)
 }
 
-// Info logs an info message
-func (l *Logger) Info(message string) {
-	l.log(INFO, message)
+// Debug logs a debug message with optional key/value fields
+func (l *Logger) Debug(message string, fields ...interface{}) {
+	l.log(DEBUG, message, fields)
 }
 
-// Warning logs a warning message
-func (l *Logger) Warning(message string) {
-	l.log(WARNING, message)
+// Info logs an info message with optional key/value fields
+func (l *Logger) Info(message string, fields ...interface{}) {
+	l.log(INFO, message, fields)
 }
 
-// Error logs an error message
-func (l *Logger) Error(message string) {
-	l.log(ERROR, message)
+// Warning logs a warning message with optional key/value fields
+func (l *Logger) Warning(message string, fields ...interface{}) {
+	l.log(WARNING, message, fields)
 }
 
-// Critical logs a critical message
-func (l *Logger) Critical(message string) {
-	l.log(CRITICAL, message)
+// Error logs an error message with optional key/value fields
+func (l *Logger) Error(message string, fields ...interface{}) {
+	l.log(ERROR, message, fields)
 }
 
-// ReportStatus sends a status update to the console
-func (l *Logger) ReportStatus(status string) {
-	select {
-	case l.statusChan <- status:
-		// Status sent
-	default:
-		// Channel full, log it instead
-		l.Info(fmt.Sprintf("Status update: %s", status))
-	}
-}
-
-// ReportMarketStatus reports the current market status
-func (l *Logger) ReportMarketStatus(price float64, metrics *types.MarketMetrics, tradeActive bool, tradePnL float64) {
-	// Format market status message
-	var statusMsg string
-	
-	if tradeActive {
-		statusMsg = fmt.Sprintf(
-			"\n=== MARKET STATUS ===\n"+
-			"Price: %.6f | Vol: %.2f%% | RS: %.2f\n"+
-			"Trend: %.2f | Order Imb: %.2f | MER: %.2f\n"+
-			"Active Trade | Current PnL: %.2f%%\n"+
-			"=====================",
-			price,
-			metrics.RealizedVolatility,
-			metrics.RelativeStrength,
-			metrics.TrendStrength,
-			metrics.OrderImbalance,
-			metrics.MarketEfficiencyRatio,
-			tradePnL,
-		)
-	} else {
-		statusMsg = fmt.Sprintf(
-			"\n=== MARKET STATUS ===\n"+
-			"Price: %.6f | Vol: %.2f%% | RS: %.2f\n"+
-			"Trend: %.2f | Order Imb: %.2f | MER: %.2f\n"+
-			"No Active Trade\n"+
-			"=====================",
-			price,
-			metrics.RealizedVolatility,
-			metrics.RelativeStrength,
-			metrics.TrendStrength,
-			metrics.OrderImbalance,
-			metrics.MarketEfficiencyRatio,
-		)
-	}
-	
-	l.ReportStatus(statusMsg)
-}
-
-// Close closes the logger and its resources
+// Critical logs a critical message with optional key/value fields
+func (l *Logger) Critical(message string, fields ...interface{}) {
+	l.log(CRITICAL, message, fields)
+}
+
+// Flush blocks until all queued log entries have been written
+func (l *Logger) Flush() {
+	if l.async != nil {
+		l.async.Flush()
+	}
+}
+
+// Close flushes and closes the logger and its resources.
+// It is safe to call more than once; later calls do nothing.
 func (l *Logger) Close() {
-	// Signal status reporter to stop
-	close(l.statusDone)
-	
-	// Close log file
+	l.closeOnce.Do(l.close)
+}
+
+// close flushes and closes the logger's resources
+func (l *Logger) close() {
+	// Write pending repeat summaries
+	if l.dedup != nil {
+		l.dedup.flush()
+	}
+
+	// Send pending tracked errors
+	l.closeReporter()
+
+	// Ship remaining entries
+	if l.shipper != nil {
+		l.shipper.close()
+	}
+
+	// Close trade journal and audit log
+	if l.journal != nil {
+		l.journal.Close()
+	}
+	if l.audit != nil {
+		l.audit.Close()
+	}
+
+	// Write queued entries and sync the log file to disk
+	if l.async != nil {
+		l.async.Close()
+	}
 	if l.logFile != nil {
+		l.logFile.Sync()
 		l.logFile.Close()
 	}
-}
\ No newline at end of file
+}
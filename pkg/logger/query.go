@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"TRADE/pkg/calendar"
+)
+
+// Entry is a log line parsed back from a session log file
+type Entry struct {
+	Time    time.Time
+	Level   LogLevel
+	Tags    []string // Component and symbol of text entries
+	Message string
+	Fields  map[string]interface{} // Fields of JSON entries
+	Raw     string
+}
+
+// Query selects log entries; zero values match everything
+type Query struct {
+	MinLevel  LogLevel
+	Component string
+	Symbol    string
+	Since     time.Time
+	Until     time.Time
+}
+
+// ParseEntry parses a text or JSON log line. It returns false for lines
+// that are not log entries (e.g. continuation lines).
+func ParseEntry(line string) (Entry, bool) {
+	if strings.HasPrefix(line, "{") {
+		return parseJSONEntry(line)
+	}
+	return parseTextEntry(line)
+}
+
+// parseTextEntry parses "2006/01/02 15:04:05 [LEVEL] [tag]... message"
+func parseTextEntry(line string) (Entry, bool) {
+	const layout = "2006/01/02 15:04:05"
+	if len(line) < len(layout)+3 {
+		return Entry{}, false
+	}
+	timestamp, err := time.ParseInLocation(layout, line[:len(layout)], calendar.DisplayLocation())
+	if err != nil {
+		return Entry{}, false
+	}
+
+	entry := Entry{Time: timestamp, Raw: line}
+	rest := strings.TrimSpace(line[len(layout):])
+
+	first := true
+	for strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			break
+		}
+		tag := rest[1:end]
+		if first {
+			level, err := ParseLevel(tag)
+			if err != nil {
+				return Entry{}, false
+			}
+			entry.Level = level
+			first = false
+		} else {
+			entry.Tags = append(entry.Tags, tag)
+		}
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+	if first {
+		return Entry{}, false
+	}
+
+	entry.Message = rest
+	return entry, true
+}
+
+// parseJSONEntry parses a line written by the JSON handler
+func parseJSONEntry(line string) (Entry, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return Entry{}, false
+	}
+
+	entry := Entry{Raw: line, Fields: fields}
+	if value, ok := fields["timestamp"].(string); ok {
+		entry.Time, _ = time.Parse(time.RFC3339Nano, value)
+	}
+	if value, ok := fields["level"].(string); ok {
+		entry.Level, _ = ParseLevel(value)
+	}
+	if value, ok := fields["message"].(string); ok {
+		entry.Message = value
+	}
+	for _, key := range []string{ComponentKey, SymbolKey} {
+		if value, ok := fields[key].(string); ok {
+			entry.Tags = append(entry.Tags, value)
+		}
+	}
+	return entry, true
+}
+
+// Match reports whether the entry satisfies the query
+func (q Query) Match(entry Entry) bool {
+	if entry.Level < q.MinLevel {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && entry.Time.After(q.Until) {
+		return false
+	}
+	if q.Component != "" && !entry.hasTag(q.Component) {
+		return false
+	}
+	if q.Symbol != "" && !entry.hasTag(q.Symbol) && !entry.hasField(SymbolKey, q.Symbol) {
+		return false
+	}
+	return true
+}
+
+// hasTag reports whether the entry carries the tag (case-insensitive)
+func (e Entry) hasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField reports whether a text entry has key=value in its message
+func (e Entry) hasField(key, value string) bool {
+	return strings.Contains(strings.ToLower(e.Message), strings.ToLower(fmt.Sprintf("%s=%s", key, value)))
+}
+
+// SessionLogs returns the session log files in dir, oldest first
+func SessionLogs(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "trade_*.log"))
+	if err != nil {
+		return nil, err
+	}
+	// Names embed the session start time, so lexical order is chronological
+	sort.Strings(files)
+	return files, nil
+}
+
+// LatestSessionLog returns the most recent session log file in dir
+func LatestSessionLog(dir string) (string, error) {
+	files, err := SessionLogs(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no session logs in %s", dir)
+	}
+	return files[len(files)-1], nil
+}
@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ShippingOptions configures shipping of log entries to a remote endpoint
+type ShippingOptions struct {
+	Type          string            // "syslog" or "loki"
+	Address       string            // host:port for syslog, base URL for Loki
+	Network       string            // "udp" (default) or "tcp" for syslog
+	Labels        map[string]string // Loki stream labels
+	Level         LogLevel          // Minimum level shipped
+	BatchSize     int               // Entries per push
+	BufferSize    int               // Entries kept while the endpoint is down
+	FlushInterval time.Duration
+}
+
+// shippedEntry is a log line waiting to be shipped
+type shippedEntry struct {
+	time  time.Time
+	level slog.Level
+	line  []byte
+}
+
+// shipper sends batches of entries to a remote endpoint
+type shipper interface {
+	ship(entries []shippedEntry) error
+	close() error
+}
+
+// remoteShipper buffers entries and ships them in the background with retry
+type remoteShipper struct {
+	opts     ShippingOptions
+	target   shipper
+	buffer   []shippedEntry
+	dropped  int
+	notify   chan struct{}
+	done     chan struct{}
+	finished chan struct{}
+	mutex    sync.Mutex
+}
+
+// newRemoteShipper creates a shipper for the configured endpoint
+func newRemoteShipper(opts ShippingOptions) (*remoteShipper, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 10000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+
+	var target shipper
+	switch opts.Type {
+	case "syslog":
+		if opts.Network == "" {
+			opts.Network = "udp"
+		}
+		target = &syslogShipper{network: opts.Network, address: opts.Address}
+	case "loki":
+		target = &lokiShipper{
+			url:    opts.Address + "/loki/api/v1/push",
+			labels: opts.Labels,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	default:
+		return nil, fmt.Errorf("unknown log shipping type: %s", opts.Type)
+	}
+
+	s := &remoteShipper{
+		opts:     opts,
+		target:   target,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// enqueue buffers an entry, dropping the oldest when the buffer is full
+func (s *remoteShipper) enqueue(entry shippedEntry) {
+	s.mutex.Lock()
+	if len(s.buffer) >= s.opts.BufferSize {
+		s.buffer = s.buffer[1:]
+		s.dropped++
+	}
+	s.buffer = append(s.buffer, entry)
+	full := len(s.buffer) >= s.opts.BatchSize
+	s.mutex.Unlock()
+
+	if full {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run ships batches until closed, retrying failures with backoff
+func (s *remoteShipper) run() {
+	defer close(s.finished)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	backoff := time.Duration(0)
+
+	for {
+		select {
+		case <-s.done:
+			s.flush()
+			return
+		case <-ticker.C:
+		case <-s.notify:
+		}
+
+		if backoff > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.done:
+				s.flush()
+				return
+			}
+		}
+
+		if err := s.flush(); err != nil {
+			if backoff == 0 {
+				backoff = time.Second
+			} else if backoff < time.Minute {
+				backoff *= 2
+			}
+			fmt.Fprintf(os.Stderr, "log shipping failed (retrying in %s): %v\n", backoff, err)
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// flush ships all buffered entries; failed batches stay buffered
+func (s *remoteShipper) flush() error {
+	for {
+		s.mutex.Lock()
+		if s.dropped > 0 {
+			fmt.Fprintf(os.Stderr, "log shipping buffer full, dropped %d entries\n", s.dropped)
+			s.dropped = 0
+		}
+		n := len(s.buffer)
+		if n > s.opts.BatchSize {
+			n = s.opts.BatchSize
+		}
+		batch := append([]shippedEntry(nil), s.buffer[:n]...)
+		s.mutex.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.target.ship(batch); err != nil {
+			return err
+		}
+
+		s.mutex.Lock()
+		// The buffer may have dropped entries meanwhile; remove what we shipped
+		if n > len(s.buffer) {
+			n = len(s.buffer)
+		}
+		s.buffer = s.buffer[n:]
+		s.mutex.Unlock()
+	}
+}
+
+// close ships the remaining entries and stops the shipper
+func (s *remoteShipper) close() {
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+	<-s.finished
+	s.target.close()
+}
+
+// shippingHandler is a slog handler that encodes entries for a remote shipper
+type shippingHandler struct {
+	shipper *remoteShipper
+	inner   slog.Handler
+	buffer  *bytes.Buffer
+	mutex   *sync.Mutex
+}
+
+// newShippingHandler creates a handler that ships JSON-encoded entries
+func newShippingHandler(s *remoteShipper, level slog.Leveler) *shippingHandler {
+	buffer := &bytes.Buffer{}
+	return &shippingHandler{
+		shipper: s,
+		inner:   NewJSONHandler(buffer, level),
+		buffer:  buffer,
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// Enabled reports whether the level is shipped
+func (h *shippingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle encodes the record and queues it for shipping
+func (h *shippingHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.buffer.Reset()
+	if err := h.inner.Handle(ctx, record); err != nil {
+		return err
+	}
+	line := bytes.TrimRight(h.buffer.Bytes(), "\n")
+
+	h.shipper.enqueue(shippedEntry{
+		time:  record.Time,
+		level: record.Level,
+		line:  append([]byte(nil), line...),
+	})
+	return nil
+}
+
+// WithAttrs returns a handler adding the attributes to shipped entries
+func (h *shippingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.inner = h.inner.WithAttrs(attrs)
+	return &child
+}
+
+// WithGroup returns a handler grouping subsequent attributes
+func (h *shippingHandler) WithGroup(name string) slog.Handler {
+	child := *h
+	child.inner = h.inner.WithGroup(name)
+	return &child
+}
+
+// syslogShipper sends entries as RFC 5424 syslog messages
+type syslogShipper struct {
+	network string
+	address string
+	conn    net.Conn
+}
+
+// ship writes each entry as a syslog message, reconnecting on failure
+func (s *syslogShipper) ship(entries []shippedEntry) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	hostname, _ := os.Hostname()
+	for _, entry := range entries {
+		// Facility local0 (16)
+		priority := 16*8 + syslogSeverity(entry.level)
+		message := fmt.Sprintf("<%d>1 %s %s trade %d - - %s\n",
+			priority, entry.time.UTC().Format(time.RFC3339Nano), hostname, os.Getpid(), entry.line)
+
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// close closes the syslog connection
+func (s *syslogShipper) close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// syslogSeverity maps a slog level to a syslog severity
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelCritical:
+		return 2
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// lokiShipper pushes entries to the Grafana Loki push API
+type lokiShipper struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+}
+
+// ship pushes a batch as a single Loki stream
+func (s *lokiShipper) ship(entries []shippedEntry) error {
+	labels := map[string]string{"job": "trade"}
+	for key, value := range s.labels {
+		labels[key] = value
+	}
+
+	values := make([][2]string, len(entries))
+	for i, entry := range entries {
+		values[i] = [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), string(entry.line)}
+	}
+
+	payload := map[string]interface{}{
+		"streams": []map[string]interface{}{
+			{"stream": labels, "values": values},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("loki push failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// close releases idle HTTP connections
+func (s *lokiShipper) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
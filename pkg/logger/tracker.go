@@ -0,0 +1,400 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// StackFrame is one frame of a captured stack trace
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// TrackedError is an ERROR/CRITICAL entry or panic reported to an error tracker
+type TrackedError struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Component string                 `json:"component,omitempty"`
+	Symbol    string                 `json:"symbol,omitempty"`
+	Panic     bool                   `json:"panic,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"` // Runtime context (last price, open position, ...)
+	Stack     []StackFrame           `json:"stack,omitempty"`   // Innermost frame first
+}
+
+// ErrorTracker reports errors to an external service such as Sentry
+type ErrorTracker interface {
+	Capture(event *TrackedError) error
+}
+
+// ContextProvider returns runtime context attached to tracked errors
+type ContextProvider func() map[string]interface{}
+
+// trackerQueueSize bounds the errors waiting to be reported
+const trackerQueueSize = 100
+
+// errorReporter sends tracked errors in the background so logging never
+// blocks on the network
+type errorReporter struct {
+	tracker  ErrorTracker
+	queue    chan *TrackedError
+	done     chan struct{}
+	provider ContextProvider
+}
+
+// newErrorReporter starts reporting to the tracker
+func newErrorReporter(tracker ErrorTracker) *errorReporter {
+	r := &errorReporter{
+		tracker: tracker,
+		queue:   make(chan *TrackedError, trackerQueueSize),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// report queues an error, dropping it if the queue is full
+func (r *errorReporter) report(event *TrackedError) {
+	select {
+	case r.queue <- event:
+	default:
+		fmt.Fprintf(os.Stderr, "error tracker queue full, dropped: %s\n", event.Message)
+	}
+}
+
+// run sends queued errors until the queue is closed
+func (r *errorReporter) run() {
+	defer close(r.done)
+	for event := range r.queue {
+		if err := r.tracker.Capture(event); err != nil {
+			fmt.Fprintf(os.Stderr, "error tracker failed: %v\n", err)
+		}
+	}
+}
+
+// close sends the remaining errors, waiting at most timeout
+func (r *errorReporter) close(timeout time.Duration) {
+	close(r.queue)
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+	}
+}
+
+// trackerHandler is a slog handler forwarding ERROR and CRITICAL entries to
+// an error reporter with a stack trace and runtime context
+type trackerHandler struct {
+	out       *output
+	component string
+	symbol    string
+	attrs     []slog.Attr
+}
+
+// Enabled reports whether the level is tracked
+func (h *trackerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelError
+}
+
+// Handle converts the record to a TrackedError and queues it
+func (h *trackerHandler) Handle(_ context.Context, record slog.Record) error {
+	event := &TrackedError{
+		Timestamp: record.Time,
+		Level:     levelName(record.Level),
+		Message:   record.Message,
+		Component: h.component,
+		Symbol:    h.symbol,
+		Fields:    make(map[string]interface{}),
+		Stack:     captureStack(0),
+	}
+	addField := func(attr slog.Attr) bool {
+		switch attr.Key {
+		case ComponentKey:
+			event.Component = attr.Value.String()
+		case SymbolKey:
+			event.Symbol = attr.Value.String()
+		default:
+			event.Fields[attr.Key] = attr.Value.Resolve().Any()
+		}
+		return true
+	}
+	for _, attr := range h.attrs {
+		addField(attr)
+	}
+	record.Attrs(addField)
+
+	h.out.reportError(event)
+	return nil
+}
+
+// WithAttrs returns a handler adding the attributes to tracked errors
+func (h *trackerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		switch attr.Key {
+		case ComponentKey:
+			child.component = attr.Value.String()
+		case SymbolKey:
+			child.symbol = attr.Value.String()
+		default:
+			child.attrs = append(child.attrs, attr)
+		}
+	}
+	return &child
+}
+
+// WithGroup returns the handler unchanged; tracked fields are not grouped
+func (h *trackerHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// reportError attaches runtime context and queues an error for tracking
+func (o *output) reportError(event *TrackedError) {
+	o.mutex.Lock()
+	reporter := o.reporter
+	o.mutex.Unlock()
+	if reporter == nil {
+		return
+	}
+
+	if reporter.provider != nil {
+		event.Context = reporter.provider()
+	}
+	reporter.report(event)
+}
+
+// SetErrorContext sets the provider of runtime context (symbol, last price,
+// open position) attached to tracked errors
+func (l *Logger) SetErrorContext(provider ContextProvider) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.reporter != nil {
+		l.reporter.provider = provider
+	}
+}
+
+// CapturePanic reports a panic with its stack trace and re-panics. Use it
+// with defer at the top of goroutines.
+func (l *Logger) CapturePanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	message := fmt.Sprintf("panic: %v", r)
+	l.log(CRITICAL, message, nil)
+	l.reportError(&TrackedError{
+		Timestamp: time.Now(),
+		Level:     CRITICAL.String(),
+		Message:   message,
+		Panic:     true,
+		Stack:     captureStack(0),
+	})
+
+	// The process is going down: send the report and flush the log first
+	l.closeReporter()
+	l.Flush()
+	panic(r)
+}
+
+// closeReporter sends pending tracked errors and stops tracking
+func (o *output) closeReporter() {
+	o.mutex.Lock()
+	reporter := o.reporter
+	o.reporter = nil
+	o.mutex.Unlock()
+
+	if reporter != nil {
+		reporter.close(5 * time.Second)
+	}
+}
+
+// captureStack returns the calling stack, skipping logging internals
+func captureStack(skip int) []StackFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2+skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []StackFrame
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "log/slog.") ||
+			strings.HasPrefix(frame.Function, "TRADE/pkg/logger.") ||
+			strings.HasPrefix(frame.Function, "runtime.")
+		if !internal {
+			stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// SentryTracker reports errors to Sentry through its envelope API
+type SentryTracker struct {
+	endpoint    string
+	auth        string
+	dsn         string
+	environment string
+	release     string
+	client      *http.Client
+}
+
+// NewSentryTracker creates a tracker from a Sentry DSN
+// (https://<key>@<host>/<project>)
+func NewSentryTracker(dsn, environment, release string) (*SentryTracker, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry DSN: %v", err)
+	}
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing public key")
+	}
+	project := strings.Trim(parsed.Path, "/")
+	if project == "" {
+		return nil, fmt.Errorf("invalid sentry DSN: missing project ID")
+	}
+
+	return &SentryTracker{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/envelope/", parsed.Scheme, parsed.Host, project),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=trade/1.0", parsed.User.Username()),
+		dsn:         dsn,
+		environment: environment,
+		release:     release,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Capture sends the error as a Sentry event
+func (t *SentryTracker) Capture(event *TrackedError) error {
+	eventID := make([]byte, 16)
+	rand.Read(eventID)
+	id := hex.EncodeToString(eventID)
+
+	level := "error"
+	if event.Level == CRITICAL.String() {
+		level = "fatal"
+	}
+	exceptionType := "error"
+	if event.Panic {
+		exceptionType = "panic"
+	}
+
+	// Sentry expects the outermost frame first
+	frames := make([]map[string]interface{}, 0, len(event.Stack))
+	for i := len(event.Stack) - 1; i >= 0; i-- {
+		frame := event.Stack[i]
+		frames = append(frames, map[string]interface{}{
+			"function": frame.Function,
+			"abs_path": frame.File,
+			"lineno":   frame.Line,
+			"in_app":   strings.HasPrefix(frame.Function, "TRADE/") || strings.HasPrefix(frame.Function, "main."),
+		})
+	}
+
+	tags := map[string]string{}
+	if event.Component != "" {
+		tags["component"] = event.Component
+	}
+	if event.Symbol != "" {
+		tags["symbol"] = event.Symbol
+	}
+	extra := map[string]interface{}{}
+	for key, value := range event.Fields {
+		extra[key] = value
+	}
+	for key, value := range event.Context {
+		extra[key] = value
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    id,
+		"timestamp":   event.Timestamp.UTC().Format(time.RFC3339Nano),
+		"level":       level,
+		"platform":    "go",
+		"logger":      event.Component,
+		"environment": t.environment,
+		"release":     t.release,
+		"message":     map[string]string{"formatted": event.Message},
+		"tags":        tags,
+		"extra":       extra,
+		"exception": []map[string]interface{}{{
+			"type":       exceptionType,
+			"value":      event.Message,
+			"stacktrace": map[string]interface{}{"frames": frames},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var envelope bytes.Buffer
+	fmt.Fprintf(&envelope, "{\"event_id\":%q,\"dsn\":%q}\n", id, t.dsn)
+	fmt.Fprintf(&envelope, "{\"type\":\"event\",\"length\":%d}\n", len(body))
+	envelope.Write(body)
+	envelope.WriteString("\n")
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, &envelope)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry rejected event: %s", resp.Status)
+	}
+	return nil
+}
+
+// WebhookTracker posts tracked errors as JSON to a generic HTTP endpoint
+type WebhookTracker struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookTracker creates a tracker posting to the URL
+func NewWebhookTracker(url string) *WebhookTracker {
+	return &WebhookTracker{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Capture posts the error as JSON
+func (t *WebhookTracker) Capture(event *TrackedError) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error webhook failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// setupAccounts creates the book of configured accounts and sets the
+// strategy's capital to the balance of the accounts assigned to it.
+// Without accounts the capital is trading.capital.
+func (m *Manager) setupAccounts() error {
+	m.capital = m.config.Trading.Capital
+	if len(m.config.Accounts) == 0 {
+		return nil
+	}
+
+	accounts := make([]account.Account, 0, len(m.config.Accounts))
+	for _, cfg := range m.config.Accounts {
+		creds, err := account.CredentialsFromEnv(cfg.APIKeyEnv, cfg.APISecretEnv)
+		if err != nil {
+			return fmt.Errorf("account %s: %v", cfg.Name, err)
+		}
+		if m.execMode == types.ExecutionLive && creds.Empty() {
+			return fmt.Errorf("account %s: live trading requires api_key_env and api_secret_env", cfg.Name)
+		}
+		accounts = append(accounts, account.Account{
+			Name:           cfg.Name,
+			Exchange:       cfg.Exchange,
+			Subaccount:     cfg.Subaccount,
+			Credentials:    creds,
+			Strategies:     cfg.Strategies,
+			InitialBalance: decimal.FromFloat(cfg.Balance),
+		})
+	}
+	book, err := account.NewBook(accounts)
+	if err != nil {
+		return fmt.Errorf("invalid accounts config: %v", err)
+	}
+	assigned := book.Assigned(m.strategyName)
+	if len(assigned) == 0 {
+		return fmt.Errorf("invalid accounts config: no account trades strategy %s", m.strategyName)
+	}
+
+	// Capital is kept in the reporting currency, balances in the quote
+	capital := book.Capital(m.strategyName)
+	if converted, err := m.fx.ToReporting(capital, m.quote, time.Time{}); err == nil {
+		capital = converted
+	} else {
+		m.logger.Warning(fmt.Sprintf("Account balances not converted into the reporting currency: %v", err))
+	}
+	m.accounts = book
+	m.capital = capital.Float64()
+	m.logger.Info(fmt.Sprintf("Trading %s for %d account(s): %s", m.strategyName, len(assigned), strings.Join(assigned, ", ")))
+	return nil
+}
+
+// allocate splits an entry of quantity across the strategy's accounts. It
+// fails if no account's share amounts to a tradable quantity.
+func (m *Manager) allocate(quantity decimal.Decimal) ([]account.Allocation, error) {
+	allocations := m.accounts.Split(m.strategyName, quantity, m.instrument.FloorQuantity)
+	if len(allocations) == 0 {
+		return nil, fmt.Errorf("%s split across accounts is below the minimum quantity", quantity)
+	}
+	return allocations, nil
+}
+
+// recordFill books a fill in the ledger of its account
+func (m *Manager) recordFill(fill *events.FillEvent) {
+	if m.accounts == nil || fill.Account == "" {
+		return
+	}
+	if err := m.accounts.Fill(fill.Account, fill.Side, fill.Price, fill.Quantity, fill.Fee); err != nil {
+		m.logger.Error(fmt.Sprintf("Fill not booked: %v", err),
+			logger.ComponentKey, "accounts", logger.OrderIDKey, fill.OrderID, logger.TradeIDKey, fill.TradeID)
+	}
+}
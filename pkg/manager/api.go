@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"TRADE/pkg/api"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/store"
+	"TRADE/pkg/types"
+)
+
+// startAPI serves the control API when enabled. Live trading requires a
+// token, as the API can close positions.
+func (m *Manager) startAPI() error {
+	cfg := m.config.API
+	if !cfg.Enabled {
+		return nil
+	}
+	token := ""
+	if cfg.TokenEnv != "" {
+		if token = os.Getenv(cfg.TokenEnv); token == "" {
+			return fmt.Errorf("invalid api config: environment variable %s (token_env) is not set", cfg.TokenEnv)
+		}
+	}
+	if token == "" && m.execMode == types.ExecutionLive && !m.paper {
+		return fmt.Errorf("invalid api config: token_env is required for live trading")
+	}
+
+	log := m.logger.With(logger.ComponentKey, "api")
+	options := api.Options{Address: cfg.Address, Token: token}
+	if cfg.Stream.Enabled {
+		options.Stream = api.NewStream(m.bus, api.StreamOptions{
+			StateInterval:  cfg.Stream.StateInterval,
+			BufferSize:     cfg.Stream.BufferSize,
+			MaxClients:     cfg.Stream.MaxClients,
+			AllowedOrigins: cfg.Stream.AllowedOrigins,
+		}, m.marketState, log)
+	}
+	server := api.NewServer(options, control{m}, log)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	m.apiServer = server
+	m.onAbort(func() {
+		m.apiServer.Stop()
+		m.apiServer = nil
+	})
+	return nil
+}
+
+// marketState returns the market state pushed to stream clients at a
+// metrics update, with the open trade and performance for the traded symbol
+func (m *Manager) marketState(update *events.MetricsEvent) *types.MarketState {
+	state := &types.MarketState{Timestamp: update.Timestamp, CurrentPrice: update.Price}
+	if update.Metrics != nil {
+		state.Metrics = update.Metrics.Finite()
+	}
+	if strings.EqualFold(update.Symbol, m.symbol) {
+		if trade := m.strategy.GetActiveTradeData(); trade.Active {
+			state.ActiveTrade = trade
+		}
+		state.Performance = m.tracker.Metrics()
+	}
+	return state
+}
+
+// control exposes the manager to the control API
+type control struct {
+	m *Manager
+}
+
+// Status returns the state of the session
+func (c control) Status() *api.Status {
+	m := c.m
+	status := &api.Status{
+		Status:         m.Status().String(),
+		Mode:           m.execMode,
+		RunID:          m.runID,
+		Symbol:         m.symbol,
+		Price:          m.market.GetCurrentPrice(),
+		LastTick:       m.market.LastTimestamp(),
+		CloseRequested: m.closeRequested.Load(),
+		Performance:    m.tracker.Metrics(),
+		Portfolio:      m.portfolioView.Load(),
+	}
+	if trade := m.strategy.GetActiveTradeData(); trade.Active {
+		status.Position = trade
+	}
+	if m.killSwitch != nil {
+		if trip, ok := m.killSwitch.Tripped(); ok {
+			status.KillSwitch = trip.String()
+		}
+	}
+	if m.equity != nil {
+		equity := m.equity.Current()
+		status.Equity = &equity
+	}
+	return status
+}
+
+// Trades returns the closed trades of the trade history
+func (c control) Trades(query store.TradeQuery) ([]store.Trade, error) {
+	if c.m.store == nil {
+		return nil, api.ErrNoHistory
+	}
+	return c.m.store.Trades(query)
+}
+
+// Annotate tags, untags and notes the open trade or a closed trade of the
+// trade history
+func (c control) Annotate(tradeID string, annotation api.Annotation) (*api.Annotations, error) {
+	m := c.m
+	if m.store == nil {
+		return nil, api.ErrNoHistory
+	}
+	active := m.strategy.GetActiveTradeData()
+	open := active.Active && active.ID == tradeID
+	if !open {
+		closed, err := m.store.Trades(store.TradeQuery{ID: tradeID})
+		if err != nil {
+			return nil, err
+		}
+		if len(closed) == 0 {
+			return nil, fmt.Errorf("%w %s", api.ErrUnknownTrade, tradeID)
+		}
+	}
+
+	if len(annotation.Tags) > 0 {
+		if err := m.store.TagTrade(tradeID, annotation.Tags...); err != nil {
+			return nil, err
+		}
+	}
+	if len(annotation.Untag) > 0 {
+		if err := m.store.UntagTrade(tradeID, annotation.Untag...); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(annotation.Note) != "" {
+		author := annotation.Author
+		if author == "" {
+			author = "api"
+		}
+		note := &store.Note{TradeID: tradeID, Time: time.Now(), Author: author, Text: annotation.Note}
+		if err := m.store.AddNote(note); err != nil {
+			return nil, err
+		}
+	}
+
+	tags, notes, err := m.store.Annotations(tradeID)
+	if err != nil {
+		return nil, err
+	}
+	annotations := &api.Annotations{TradeID: tradeID, Open: open, Tags: tags, Notes: api.Notes(notes)}
+	if annotations.Tags == nil {
+		annotations.Tags = []string{}
+	}
+	if annotations.Notes == nil {
+		annotations.Notes = []api.Note{}
+	}
+	return annotations, nil
+}
+
+// Pause stops new entries
+func (c control) Pause() error {
+	return c.m.Pause()
+}
+
+// Resume re-enables new entries, re-arming the kill-switch
+func (c control) Resume() error {
+	return c.m.Resume()
+}
+
+// ClosePosition has the open position closed at the next tick of the
+// traded symbol, on the feed goroutine that manages it
+func (c control) ClosePosition() (string, error) {
+	m := c.m
+	trade := m.strategy.GetActiveTradeData()
+	if !trade.Active {
+		return "", api.ErrNoPosition
+	}
+	m.closeRequested.Store(true)
+	m.logger.Warning(fmt.Sprintf("Close of trade %s requested through the control API", trade.ID),
+		logger.ComponentKey, "api", logger.SymbolKey, m.symbol)
+	return trade.ID, nil
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/asset"
+)
+
+// setupAnalyzer annualizes the analyzer's volatility over the trading time
+// of the asset class and sizes its warmup and windows, the configured ones
+// overriding the class's
+func (m *Manager) setupAnalyzer() error {
+	trading := m.config.Trading
+	class := m.assetClass
+	m.analyzer.SetAnnualization(class.PeriodsPerYear(time.Minute))
+
+	warmup := class.WarmupTicks
+	if trading.WarmupTicks > 0 {
+		warmup = trading.WarmupTicks
+	}
+	m.analyzer.SetWarmupTicks(warmup)
+
+	windows := class.Windows
+	if trading.Windows.RelativeStrength > 0 {
+		windows.RelativeStrength = trading.Windows.RelativeStrength
+	}
+	if trading.Windows.Trend > 0 {
+		windows.Trend = trading.Windows.Trend
+	}
+	if trading.Windows.ATR > 0 {
+		windows.ATR = trading.Windows.ATR
+	}
+	if err := m.analyzer.SetWindows(windows); err != nil {
+		return err
+	}
+	m.logger.Info(fmt.Sprintf("Asset class %s: warmup %d ticks, relative strength over %d returns, trend over %d prices, ATR over %d ticks",
+		class, warmup, windows.RelativeStrength, windows.Trend, windows.ATR))
+	return nil
+}
+
+// setupAssetClasses scales the volatility the risk checks sample of the
+// traded and watched symbols to the trading day of their asset class;
+// watched symbols without one configured trade in the traded symbol's
+func (m *Manager) setupAssetClasses() error {
+	m.risk.SetTradingDay(m.symbol, m.assetClass.TradingDay)
+	for _, symbol := range m.config.Market.Symbols {
+		class := m.assetClass
+		if name, ok := m.config.Market.AssetClasses[symbol]; ok {
+			var err error
+			if class, err = asset.Lookup(name); err != nil {
+				return fmt.Errorf("invalid market config: asset_classes.%s: %v", symbol, err)
+			}
+		}
+		m.risk.SetTradingDay(symbol, class.TradingDay)
+	}
+	return nil
+}
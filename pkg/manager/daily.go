@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/performance"
+	"TRADE/pkg/schedule"
+	"TRADE/pkg/store"
+)
+
+// setupDailySummary schedules the daily performance summary, if enabled.
+// Backtests replay history faster than the clock and get none.
+func (m *Manager) setupDailySummary() error {
+	cfg := m.config.DailySummary
+	if !cfg.Enabled || m.backtest {
+		return nil
+	}
+	task, err := schedule.NewDaily("daily summary", cfg.At, calendar.DisplayLocation(), m.sendDailySummary,
+		m.logger.With(logger.ComponentKey, "daily_summary"))
+	if err != nil {
+		return fmt.Errorf("invalid daily summary config: %v", err)
+	}
+	m.day = performance.NewDay(time.Now())
+	m.daily = task
+	return nil
+}
+
+// recordDailyFee adds the fee of a fill to the day, in the reporting
+// currency when it can be converted
+func (m *Manager) recordDailyFee(fill *events.FillEvent) {
+	if fill.Fee.Sign() == 0 {
+		return
+	}
+	fee, err := m.fx.ToReporting(fill.Fee, m.quote, fill.Timestamp)
+	if err != nil {
+		m.logger.Warning(fmt.Sprintf("Fee of fill %s left in %s in the daily summary: %v", fill.FillID, m.quote, err),
+			logger.TradeIDKey, fill.TradeID)
+		fee = fill.Fee
+	}
+	m.day.AddFee(fee)
+}
+
+// sendDailySummary closes the day, logs its summary, publishes it on the
+// bus (and so on gRPC and NATS) and saves it in the trade history
+func (m *Manager) sendDailySummary(now time.Time) {
+	summary := m.day.Close(now, calendar.DisplayLocation(), m.fx.Reporting())
+	m.logger.Info(fmt.Sprintf("Daily summary %s: %d trade(s), win rate %.1f%%, PnL %s %s, fees %s %s",
+		summary.Date, summary.Trades, summary.WinRate, summary.PnL, summary.Currency, summary.Fees, summary.Currency),
+		logger.ComponentKey, "daily_summary", logger.SymbolKey, m.symbol)
+
+	m.bus.Publish(&events.DailySummaryEvent{
+		Symbol:   m.symbol,
+		Date:     summary.Date,
+		Start:    summary.Start,
+		End:      summary.End,
+		Trades:   summary.Trades,
+		Wins:     summary.Wins,
+		Losses:   summary.Losses,
+		WinRate:  summary.WinRate,
+		PnL:      summary.PnL,
+		Fees:     summary.Fees,
+		Currency: summary.Currency,
+	})
+
+	if m.store == nil {
+		return
+	}
+	if err := m.store.SaveDailySummary(&store.DailySummary{
+		ID:       fmt.Sprintf("%s-%s", m.runID, summary.Date),
+		RunID:    m.runID,
+		Mode:     m.runMode(),
+		Symbol:   m.symbol,
+		Date:     summary.Date,
+		Start:    summary.Start,
+		End:      summary.End,
+		Trades:   summary.Trades,
+		Wins:     summary.Wins,
+		Losses:   summary.Losses,
+		WinRate:  summary.WinRate,
+		PnL:      summary.PnL,
+		Fees:     summary.Fees,
+		Currency: summary.Currency,
+	}); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to save daily summary: %v", err), logger.ComponentKey, "storage")
+	}
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/tracing"
+	"TRADE/pkg/types"
+)
+
+// signalMemory is how long the signals let through are remembered to
+// recognize repeats of them
+const signalMemory = time.Hour
+
+// errRepeated marks a signal dropped as a repeat of one let through,
+// whose trade is the strategy's own
+var errRepeated = errors.New("repeated")
+
+// DedupStats counts the signals the guard dropped
+type DedupStats struct {
+	Duplicates int `json:"duplicates"`
+	OutOfOrder int `json:"out_of_order"`
+}
+
+// signalGuard drops the signals that repeat one already let through or
+// arrive out of order, before they reach execution. Strategies, batched
+// callbacks and external sources can each deliver the same intent.
+type signalGuard struct {
+	window  time.Duration        // An entry blocks others of its strategy and direction this long
+	seen    map[string]time.Time // Signal IDs and trade intents let through, by signal time
+	entries map[string]guardEntry
+	last    time.Time // Time of the latest signal let through
+	pruned  time.Time
+	stats   DedupStats
+	mutex   sync.Mutex
+}
+
+// guardEntry is the latest entry of a strategy in a direction
+type guardEntry struct {
+	tradeID string
+	time    time.Time
+}
+
+// newSignalGuard creates a guard blocking repeated entries within window
+func newSignalGuard(window time.Duration) *signalGuard {
+	return &signalGuard{
+		window:  window,
+		seen:    make(map[string]time.Time),
+		entries: make(map[string]guardEntry),
+	}
+}
+
+// intent returns the key of what the signal asks for: entering, exiting
+// or moving the stop of its trade; empty for a signal of no trade
+func intent(signal *types.Signal) string {
+	if signal.TradeID == "" {
+		return ""
+	}
+	switch signal.Action {
+	case "BUY", "SHORT":
+		return "entry|" + signal.TradeID
+	case "SELL", "CLOSE":
+		return "exit|" + signal.TradeID
+	}
+	return signal.Action + "|" + signal.TradeID + "|" + strconv.FormatFloat(signal.UpdatedStopLoss, 'g', -1, 64)
+}
+
+// Check returns why a signal must be dropped, or nil to let it through.
+// Exits are dropped only when their trade was already exited: a late exit
+// still closes the position.
+func (g *signalGuard) Check(signal *types.Signal) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	key := intent(signal)
+	if _, ok := g.seen[signal.ID]; ok {
+		g.stats.Duplicates++
+		return fmt.Errorf("%w signal %s", errRepeated, signal.ID)
+	}
+	if _, ok := g.seen[key]; ok && key != "" {
+		g.stats.Duplicates++
+		return fmt.Errorf("%w %s of trade %s", errRepeated, signal.Action, signal.TradeID)
+	}
+	exit := signal.Action == "SELL" || signal.Action == "CLOSE"
+	if !exit && signal.Time.Before(g.last) {
+		g.stats.OutOfOrder++
+		return fmt.Errorf("%s of %s is older than the signal of %s", signal.Action,
+			signal.Time.Format(time.RFC3339Nano), g.last.Format(time.RFC3339Nano))
+	}
+	strategy := signal.Strategy + "|" + signal.Direction
+	if signal.IsEntry() {
+		if entry, ok := g.entries[strategy]; ok && signal.Time.Sub(entry.time) < g.window {
+			g.stats.Duplicates++
+			return fmt.Errorf("%s within %s of the entry of trade %s", signal.Action, g.window, entry.tradeID)
+		}
+		g.entries[strategy] = guardEntry{tradeID: signal.TradeID, time: signal.Time}
+	}
+	if exit {
+		// The next entry of the strategy is a new intent
+		for strategy, entry := range g.entries {
+			if entry.tradeID == signal.TradeID {
+				delete(g.entries, strategy)
+			}
+		}
+	}
+
+	g.seen[signal.ID] = signal.Time
+	if key != "" {
+		g.seen[key] = signal.Time
+	}
+	if signal.Time.After(g.last) {
+		g.last = signal.Time
+	}
+	g.prune()
+	return nil
+}
+
+// RetryExit lets the next exit of a trade through, for the rest of a
+// position its exit left unfilled
+func (g *signalGuard) RetryExit(tradeID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.seen, "exit|"+tradeID)
+}
+
+// prune forgets the signals older than signalMemory, at most once per
+// signalMemory
+func (g *signalGuard) prune() {
+	if g.last.Sub(g.pruned) < signalMemory {
+		return
+	}
+	g.pruned = g.last
+	for key, at := range g.seen {
+		if g.last.Sub(at) > signalMemory {
+			delete(g.seen, key)
+		}
+	}
+}
+
+// Stats returns the counts of signals dropped
+func (g *signalGuard) Stats() DedupStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stats
+}
+
+// publishSignal publishes a signal for execution unless the guard drops
+// it; the trade of a new entry dropped is cancelled in the strategy. The
+// signal's trace continues under parent, or starts with it without one.
+func (m *Manager) publishSignal(symbol string, signal *types.Signal, parent *tracing.Span) {
+	if err := m.signals.Check(signal); err != nil {
+		if signal.IsEntry() && !errors.Is(err, errRepeated) {
+			m.strategy.CancelEntry(signal.TradeID)
+		}
+		parent.SetAttributes(tracing.String("signal_dropped", err.Error()))
+		m.logger.Warning(fmt.Sprintf("Signal dropped: %v", err),
+			logger.ComponentKey, "dedup", logger.SymbolKey, symbol,
+			logger.SignalIDKey, signal.ID, logger.TradeIDKey, signal.TradeID)
+		return
+	}
+	m.bus.Publish(&events.SignalEvent{Symbol: symbol, Signal: signal, Span: parent})
+}
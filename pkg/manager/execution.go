@@ -0,0 +1,328 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
+	"TRADE/pkg/execution"
+	"TRADE/pkg/ids"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/tracing"
+	"TRADE/pkg/types"
+)
+
+// setupExecution creates the executors sending live orders: one per
+// account, or one with the API keys of the execution config without
+// accounts. Paper trading on live data and backtests simulate the fills
+// instead; other paper runs fill every order at its price.
+func (m *Manager) setupExecution() error {
+	if m.paper || m.backtest {
+		return m.setupPaperExecution()
+	}
+	if m.execMode != types.ExecutionLive {
+		return nil
+	}
+	cfg := m.config.Execution
+	orderType, err := execution.ParseOrderType(cfg.OrderType)
+	if err != nil {
+		return fmt.Errorf("invalid execution config: %v", err)
+	}
+	if cfg.PollInterval <= 0 || cfg.OrderTimeout <= 0 {
+		return fmt.Errorf("invalid execution config: poll_interval and order_timeout must be positive")
+	}
+
+	executors := make(map[string]execution.Executor)
+	if m.accounts == nil {
+		creds, err := account.CredentialsFromEnv(cfg.APIKeyEnv, cfg.APISecretEnv)
+		if err != nil {
+			return fmt.Errorf("live trading API keys: %v", err)
+		}
+		if creds.Empty() {
+			return fmt.Errorf("live trading requires execution.api_key_env and execution.api_secret_env")
+		}
+		if executors[""], err = m.newExecutor(cfg.Exchange, creds); err != nil {
+			return err
+		}
+	} else {
+		for _, acc := range m.accounts.Accounts() {
+			exchange := acc.Exchange
+			if exchange == "" {
+				exchange = cfg.Exchange
+			}
+			if executors[acc.Name], err = m.newExecutor(exchange, acc.Credentials); err != nil {
+				return fmt.Errorf("account %s: %v", acc.Name, err)
+			}
+		}
+	}
+
+	m.orderType = orderType
+	m.executors = executors
+	m.logger.Info(fmt.Sprintf("Live orders are sent as %s orders to %d account(s)", orderType, len(executors)),
+		logger.ComponentKey, "execution")
+	return nil
+}
+
+// setupPaperExecution creates the executors simulating the fills of each
+// account against the live prices. Backtests fill at once, at the time of
+// the tick being replayed.
+func (m *Manager) setupPaperExecution() error {
+	cfg := m.config.Execution.Paper
+	if cfg.SlippageBps < 0 || cfg.FeeRate < 0 || cfg.Latency < 0 {
+		return fmt.Errorf("invalid execution.paper config: slippage_bps, fee_rate and latency cannot be negative")
+	}
+	params := execution.PaperParams{
+		SlippageBps: cfg.SlippageBps,
+		FeeRate:     cfg.FeeRate,
+		Latency:     cfg.Latency,
+		TickSize:    m.instrument.TickSize,
+	}
+	if m.backtest {
+		params.Latency = 0
+		params.Clock = func() time.Time { return m.market.LastTimestamp() }
+	}
+	names := []string{""}
+	if m.accounts != nil {
+		names = names[:0]
+		for _, acc := range m.accounts.Accounts() {
+			names = append(names, acc.Name)
+		}
+	}
+	m.executors = make(map[string]execution.Executor, len(names))
+	for _, name := range names {
+		m.executors[name] = execution.NewPaperExecutor(m.paperPrices, m.quote, params)
+	}
+	m.orderType = execution.OrderMarket
+	m.logger.Info(fmt.Sprintf("Paper fills at the bid/ask with %.1f bps slippage, %.3f%% fees and %s latency",
+		params.SlippageBps, params.FeeRate*100, params.Latency), logger.ComponentKey, "execution")
+	return nil
+}
+
+// paperPrices returns the best bid and ask of the traded symbol for paper
+// fills, or its last price for both without a recent quote (always in
+// backtests, whose datasets hold trades only)
+func (m *Manager) paperPrices(symbol string) (decimal.Decimal, decimal.Decimal, bool) {
+	if quote, ok := m.market.Quote(); ok && !m.backtest && quote.Bid > 0 && quote.Ask > 0 &&
+		time.Since(quote.Time) <= m.config.EntryFilter.MaxQuoteAge {
+		return decimal.FromFloat(quote.Bid), decimal.FromFloat(quote.Ask), true
+	}
+	price := m.market.GetCurrentPrice()
+	if price <= 0 {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return decimal.FromFloat(price), decimal.FromFloat(price), true
+}
+
+// newExecutor creates the executor of an exchange account
+func (m *Manager) newExecutor(exchange string, creds account.Credentials) (execution.Executor, error) {
+	cfg := m.config.Execution
+	switch strings.ToLower(exchange) {
+	case "", "binance":
+		executor := execution.NewBinanceExecutor(creds)
+		if cfg.RESTURL != "" {
+			executor.SetURL(cfg.RESTURL)
+		}
+		if cfg.RecvWindow > 0 {
+			executor.SetRecvWindow(cfg.RecvWindow)
+		}
+		return executor, nil
+	default:
+		return nil, fmt.Errorf("invalid execution config: unknown exchange %q (want binance)", exchange)
+	}
+}
+
+// submitOrder sends an order to the exchange account and waits until it is
+// done, publishing its fill. It returns the quantity filled and its average
+// price; zero if nothing was filled.
+func (m *Manager) submitOrder(ctx context.Context, signal *types.Signal, orderID, accountName, side string, price, quantity decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	span := tracing.SpanFromContext(ctx)
+	log := m.logger.With(logger.ComponentKey, "execution", logger.SymbolKey, m.symbol, logger.OrderIDKey, orderID,
+		logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+	if span != nil {
+		log = log.With(logger.TraceIDKey, span.TraceID())
+	}
+	executor, ok := m.executors[accountName]
+	if !ok {
+		span.RecordError(fmt.Errorf("no executor for account %q", accountName))
+		log.Error(fmt.Sprintf("No executor for account %q; order not sent", accountName))
+		return decimal.Zero, decimal.Zero
+	}
+
+	cfg := m.config.Execution
+	report, err := execution.Execute(executor, &execution.Order{
+		ID:       orderID,
+		Symbol:   m.symbol,
+		Side:     side,
+		Type:     m.orderType,
+		Quantity: quantity,
+		Price:    price,
+	}, cfg.PollInterval, cfg.OrderTimeout)
+	if report != nil {
+		span.SetAttributes(tracing.String("status", string(report.Status)), tracing.String("filled", report.Filled.String()))
+		m.logger.Audit(logger.AuditResponse, orderID, m.symbol, map[string]interface{}{
+			"exchange_order_id": report.ExchangeID,
+			"status":            string(report.Status),
+			"filled":            report.Filled,
+			"price":             report.Price,
+			"fee":               report.Fee,
+			"fee_asset":         report.FeeAsset,
+			"account":           accountName,
+			"trade_id":          signal.TradeID,
+			"correlation_id":    signal.CorrelationID,
+		})
+	}
+	if err != nil {
+		if errs.Kind(err) == nil {
+			err = errs.Wrap(errs.ErrOrderRejected, "execution", err)
+		}
+		span.RecordError(err)
+		log.Error(fmt.Sprintf("Order %s %s %s failed: %v", side, quantity, m.symbol, err))
+		m.bus.Publish(&events.ErrorEvent{Component: "execution", Err: err, Timestamp: time.Now()})
+	}
+	if report == nil || report.Filled.Sign() <= 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	if report.Filled.Cmp(quantity) < 0 {
+		log.Warning(fmt.Sprintf("Order %s %s %s filled %s (%s)", side, quantity, m.symbol, report.Filled, report.Status))
+	}
+
+	m.bus.Publish(&events.FillEvent{
+		FillID:        ids.Fill(),
+		OrderID:       orderID,
+		CorrelationID: signal.CorrelationID,
+		TradeID:       signal.TradeID,
+		Symbol:        m.symbol,
+		Side:          side,
+		Price:         report.Price,
+		Quantity:      report.Filled,
+		Fee:           m.fillFee(report),
+		Account:       accountName,
+		Timestamp:     report.Time,
+	})
+	return report.Filled, report.Price
+}
+
+// fillFee returns the fee of an order in the quote currency. Fees in an
+// asset without a conversion rate are left out, with a warning.
+func (m *Manager) fillFee(report *execution.Report) decimal.Decimal {
+	if report.Fee.Sign() == 0 || report.FeeAsset == "" {
+		return decimal.Zero
+	}
+	fee, err := m.fx.Convert(report.Fee, strings.ToUpper(report.FeeAsset), m.quote, report.Time)
+	if err != nil {
+		m.logger.Warning(fmt.Sprintf("Fee of %s %s on order %s not booked: %v", report.Fee, report.FeeAsset, report.OrderID, err),
+			logger.ComponentKey, "execution")
+		return decimal.Zero
+	}
+	return fee
+}
+
+// orderQueue runs the signals and funding settlements of live and paper
+// trading on live data one at a time, in order, off the feed goroutine:
+// orders are polled until done, which must not hold up the ticks behind
+// them
+type orderQueue struct {
+	jobs    []func()
+	pending int // Jobs queued or running
+	closed  bool
+	mutex   sync.Mutex
+	ready   *sync.Cond
+	done    chan struct{}
+}
+
+// newOrderQueue creates a queue; run starts it
+func newOrderQueue() *orderQueue {
+	q := &orderQueue{done: make(chan struct{})}
+	q.ready = sync.NewCond(&q.mutex)
+	return q
+}
+
+// push queues a job, or returns false once the queue is closed
+func (q *orderQueue) push(job func()) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return false
+	}
+	q.jobs = append(q.jobs, job)
+	q.pending++
+	q.ready.Signal()
+	return true
+}
+
+// busy reports whether jobs are queued or running
+func (q *orderQueue) busy() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.pending > 0
+}
+
+// run runs the jobs until the queue is closed and drained
+func (q *orderQueue) run() {
+	defer close(q.done)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for {
+		for len(q.jobs) == 0 && !q.closed {
+			q.ready.Wait()
+		}
+		if len(q.jobs) == 0 {
+			return
+		}
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		q.mutex.Unlock()
+		job()
+		q.mutex.Lock()
+		q.pending--
+	}
+}
+
+// close stops taking jobs and waits for the queued ones to be done
+func (q *orderQueue) close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.ready.Signal()
+	q.mutex.Unlock()
+	<-q.done
+}
+
+// startOrders runs the signals of live trading and of paper trading on
+// live data on an order queue until Shutdown; backtests and simulations
+// execute them on the tick that sent them
+func (m *Manager) startOrders() {
+	queue := newOrderQueue()
+	go func() {
+		defer m.logger.CapturePanic()
+		queue.run()
+	}()
+	m.orders = queue
+	m.onAbort(m.stopOrders)
+}
+
+// stopOrders executes the signals still queued and stops the order queue.
+// Jobs sent after it run at once.
+func (m *Manager) stopOrders() {
+	if m.orders != nil {
+		m.orders.close()
+	}
+}
+
+// execute runs a job touching the open position on the order queue, or
+// at once without a running one
+func (m *Manager) execute(job func()) {
+	if m.orders == nil || !m.orders.push(job) {
+		job()
+	}
+}
+
+// ordersPending reports whether signals are still being executed
+func (m *Manager) ordersPending() bool {
+	return m.orders != nil && m.orders.busy()
+}
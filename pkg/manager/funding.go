@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"fmt"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// settleFunding books the payment of a funding settlement that passed
+// against the open position, if it was entered before the settlement. Each
+// account's payment is journaled as a FUNDING row and added to its ledger;
+// the sum is carried into the PnL of the trade when it closes.
+func (m *Manager) settleFunding(event *events.FundingEvent) {
+	if event.Symbol != m.symbol {
+		return
+	}
+	settlement, ok := m.funding.Update(event)
+	if !ok || m.quantity.Sign() <= 0 || !m.entryTime.Before(settlement.Time) {
+		return
+	}
+
+	position, _ := m.position.Load().(positionContext)
+	holdings := m.holdings
+	if holdings == nil {
+		holdings = []account.Allocation{{Quantity: m.quantity}}
+	}
+	mark := decimal.FromFloat(settlement.MarkPrice)
+	total := decimal.Zero
+	for _, holding := range holdings {
+		amount := settlement.Payment(holding.Quantity)
+		if m.short {
+			amount = amount.Neg() // Shorts receive what longs pay
+		}
+		total = total.Add(amount)
+		if holding.Account != "" {
+			if err := m.accounts.Funding(holding.Account, amount); err != nil {
+				m.logger.Error(fmt.Sprintf("Funding not booked: %v", err),
+					logger.ComponentKey, "accounts", logger.TradeIDKey, position.TradeID)
+			}
+		}
+		m.logger.LogTrade(logger.JournalEntry{
+			Time:          settlement.Time,
+			Symbol:        m.symbol,
+			Action:        "FUNDING",
+			Price:         mark,
+			Quantity:      holding.Quantity,
+			Notional:      mark.Mul(holding.Quantity),
+			Reason:        fmt.Sprintf("funding_rate=%g", settlement.Rate),
+			PnL:           amount,
+			ExecutionMode: string(m.execMode),
+			TradeID:       position.TradeID,
+		})
+	}
+
+	m.funding.Book(total)
+	m.openFunding = m.openFunding.Add(total)
+	position.Funding = m.openFunding
+	m.position.Store(position)
+	m.logger.Info(fmt.Sprintf("Funding of %s %s at rate %g (mark %s): %s %s, %s %s since entry",
+		m.quantity, m.symbol, settlement.Rate, mark, total, m.quote, m.openFunding, m.quote),
+		logger.ComponentKey, "funding", logger.SymbolKey, m.symbol, logger.TradeIDKey, position.TradeID)
+}
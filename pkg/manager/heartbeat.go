@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/daemon"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// setupHeartbeat checks the heartbeat config. Backtests are not left
+// unattended and get no heartbeat.
+func (m *Manager) setupHeartbeat() error {
+	cfg := m.config.Heartbeat
+	if !cfg.Enabled || m.backtest {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("invalid heartbeat config: interval must be positive")
+	}
+	if cfg.RunFile == "" {
+		return fmt.Errorf("invalid heartbeat config: run_file is required")
+	}
+	m.runFile = cfg.RunFile
+	return nil
+}
+
+// startHeartbeat marks the process as running, alerts if the previous
+// process crashed and sends a heartbeat every interval until stopChan closes
+func (m *Manager) startHeartbeat(stopChan chan struct{}) {
+	if m.runFile == "" {
+		return
+	}
+
+	crashed, err := daemon.MarkRunning(m.runFile)
+	if err != nil {
+		m.logger.Warning(fmt.Sprintf("Crash detection unavailable: %v", err), logger.ComponentKey, "heartbeat")
+	}
+	if crashed != nil {
+		m.alert(events.AlertCritical, fmt.Sprintf("Restarted after a crash: the previous process (pid %d, started %s) exited without shutting down",
+			crashed.PID, crashed.Started.Format(time.RFC3339)))
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.config.Heartbeat.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				m.alert(events.AlertInfo, m.heartbeat())
+			}
+		}
+	}()
+}
+
+// stopHeartbeat removes the run file, so the next start knows this
+// process shut down cleanly
+func (m *Manager) stopHeartbeat() {
+	if m.runFile == "" {
+		return
+	}
+	if err := daemon.ClearRunning(m.runFile); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to remove run file: %v", err), logger.ComponentKey, "heartbeat")
+	}
+}
+
+// heartbeat describes the liveness of the system: its feed, equity and
+// open risk
+func (m *Manager) heartbeat() string {
+	currency := m.fx.Reporting()
+	return fmt.Sprintf("Alive: %s, feed %s, equity %.2f %s, open risk %.2f %s",
+		m.Status(), m.feedState(), m.equity.Current().Equity, currency, m.openRisk(time.Now()).Float64(), currency)
+}
+
+// feedState describes the market data feed for heartbeats
+func (m *Manager) feedState() string {
+	m.statusMutex.RLock()
+	frozen := m.feedFrozen
+	m.statusMutex.RUnlock()
+
+	switch {
+	case m.simulator != nil:
+		return "simulated"
+	case !m.live.Connected():
+		return "DISCONNECTED"
+	case frozen:
+		return "STALE"
+	default:
+		return "OK"
+	}
+}
+
+// openRisk returns what the open position loses, in the reporting
+// currency, if the price falls from its current level to the stop; the
+// whole position without a stop
+func (m *Manager) openRisk(now time.Time) decimal.Decimal {
+	position, ok := m.position.Load().(positionContext)
+	if !ok || position.Quantity.Sign() <= 0 {
+		return decimal.Zero
+	}
+	price := decimal.FromFloat(m.market.GetCurrentPrice())
+	stop := decimal.Zero
+	if m.strategy.IsActiveTrade() {
+		stop = decimal.FromFloat(m.strategy.GetActiveTradeData().StopLoss)
+	}
+	distance := price.Sub(stop)
+	if position.Short {
+		// Without a stop a short's loss is unbounded; count its notional
+		distance = price
+		if stop.Sign() > 0 {
+			distance = stop.Sub(price)
+		}
+	}
+	if distance.Sign() <= 0 {
+		return decimal.Zero
+	}
+	risk := distance.Mul(position.Quantity)
+	converted, err := m.fx.ToReporting(risk, m.quote, now)
+	if err != nil {
+		m.logger.Debug(fmt.Sprintf("Open risk left in %s: %v", m.quote, err))
+		return risk
+	}
+	return converted
+}
+
+// alert logs an operational alert and publishes it on the bus (and so on
+// gRPC and NATS). Critical alerts also reach the error tracker.
+func (m *Manager) alert(level events.AlertLevel, message string) {
+	switch level {
+	case events.AlertCritical:
+		m.logger.Critical(message, logger.ComponentKey, "heartbeat")
+	case events.AlertWarning:
+		m.logger.Warning(message, logger.ComponentKey, "heartbeat")
+	default:
+		m.logger.Info(message, logger.ComponentKey, "heartbeat")
+	}
+	m.bus.Publish(&events.AlertEvent{
+		Level:     level,
+		Source:    "heartbeat",
+		Symbol:    m.symbol,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
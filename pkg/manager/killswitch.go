@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/risk"
+)
+
+// setupKillSwitch creates the kill-switch and counts closed trades
+// towards its loss limits
+func (m *Manager) setupKillSwitch() {
+	cfg := m.config.Risk.KillSwitch
+	m.killSwitch = risk.NewKillSwitch(risk.KillSwitchLimits{
+		MaxDailyLoss:         cfg.MaxDailyLoss,
+		MaxConsecutiveLosses: cfg.MaxConsecutiveLosses,
+		MaxOpenExposure:      cfg.MaxOpenExposure,
+		Flatten:              cfg.Flatten,
+	})
+	m.subscribe(events.TypeTradeClosed, func(event events.Event) {
+		closed := event.(*events.TradeClosedEvent)
+		day := m.calendar.TradingDay(closed.ExitTime)
+		if trip := m.killSwitch.RecordTrade(day, closed.NormalizedPnL().Float64(), closed.ExitTime); trip != nil {
+			m.tripKillSwitch(*trip)
+		}
+	})
+}
+
+// checkExposure trips the kill-switch when the open position, marked to
+// market at price, is over the exposure limit
+func (m *Manager) checkExposure(price float64, at time.Time) {
+	if m.killSwitch.Limits().MaxOpenExposure <= 0 {
+		return
+	}
+	position, ok := m.position.Load().(positionContext)
+	if !ok || position.Quantity.Sign() <= 0 {
+		return
+	}
+	exposure, err := m.fx.ToReporting(decimal.FromFloat(price).Mul(position.Quantity), m.quote, at)
+	if err != nil {
+		m.logger.Debug(fmt.Sprintf("Open exposure not checked: %v", err), logger.ComponentKey, "risk")
+		return
+	}
+	if trip := m.killSwitch.CheckExposure(exposure.Float64(), at); trip != nil {
+		m.tripKillSwitch(*trip)
+	}
+}
+
+// tripKillSwitch halts new entries, alerts and, if configured, closes the
+// open trade at the last price
+func (m *Manager) tripKillSwitch(trip risk.Trip) {
+	message := fmt.Sprintf("Kill-switch tripped on %s; new entries halted until resumed", trip)
+	m.logger.Critical(message, logger.ComponentKey, "risk", logger.SymbolKey, m.symbol)
+	if err := m.Halt("kill-switch " + trip.String()); err != nil {
+		m.logger.Error(fmt.Sprintf("Kill-switch could not halt trading: %v", err), logger.ComponentKey, "risk")
+	}
+	m.bus.Publish(&events.AlertEvent{
+		Level:     events.AlertCritical,
+		Source:    "kill_switch",
+		Symbol:    m.symbol,
+		Message:   message,
+		Timestamp: trip.Time,
+	})
+
+	if !m.killSwitch.Limits().Flatten {
+		return
+	}
+	if signal := m.strategy.ForceExit(m.market.GetCurrentPrice(), trip.Time, "kill_switch"); signal != nil {
+		m.publishSignal(m.symbol, signal, nil)
+	}
+}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"TRADE/pkg/lock"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// lockKeys returns the keys of the instance locks: one per account, or
+// the default account without accounts, with the traded symbol. Paper
+// sessions send no orders and lock keys of their own.
+func (m *Manager) lockKeys() []string {
+	accounts := []string{"default"}
+	if len(m.config.Accounts) > 0 {
+		accounts = accounts[:0]
+		for _, account := range m.config.Accounts {
+			accounts = append(accounts, account.Name)
+		}
+	}
+	prefix := ""
+	if m.paper || m.execMode != types.ExecutionLive {
+		prefix = "paper-"
+	}
+	keys := make([]string, len(accounts))
+	for i, account := range accounts {
+		keys[i] = prefix + lock.Key(account, m.symbol)
+	}
+	return keys
+}
+
+// acquireLocks takes the instance locks of the accounts and symbol traded,
+// failing with the holder of a lock another instance holds
+func (m *Manager) acquireLocks() error {
+	cfg := m.config.InstanceLock
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var locker lock.Locker
+	var err error
+	switch cfg.Type {
+	case "", "file":
+		locker, err = lock.NewFileLocker(cfg.Dir)
+	case "postgres":
+		url := cfg.URL
+		if url == "" {
+			url = m.config.Storage.URL
+		}
+		locker, err = lock.OpenPostgres(url)
+	default:
+		return fmt.Errorf("invalid instance_lock config: type must be file or postgres, not %q", cfg.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("instance lock: %v", err)
+	}
+
+	keys := m.lockKeys()
+	locks, err := lock.AcquireAll(locker, keys)
+	if err != nil {
+		locker.Close()
+		return fmt.Errorf("instance lock: %w", err)
+	}
+	m.locker, m.locks = locker, locks
+	m.logger.Info(fmt.Sprintf("Instance locks held: %s", strings.Join(keys, ", ")), logger.ComponentKey, "lock")
+	return nil
+}
+
+// releaseLocks gives the instance locks up, once this instance stopped
+// managing its positions
+func (m *Manager) releaseLocks() {
+	if m.locker == nil {
+		return
+	}
+	if err := lock.ReleaseAll(m.locks); err != nil {
+		m.logger.Warning(err.Error(), logger.ComponentKey, "lock")
+	}
+	if err := m.locker.Close(); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to close instance locker: %v", err), logger.ComponentKey, "lock")
+	}
+	m.locker, m.locks = nil, nil
+}
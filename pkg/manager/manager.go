@@ -1,211 +1,2610 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"TRADE/pkg/account"
+	"TRADE/pkg/admin"
 	"TRADE/pkg/analyzer"
+	"TRADE/pkg/api"
+	"TRADE/pkg/asset"
+	"TRADE/pkg/bars"
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/config"
+	"TRADE/pkg/currency"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
+	"TRADE/pkg/execution"
+	"TRADE/pkg/export"
+	"TRADE/pkg/guard"
+	"TRADE/pkg/ids"
+	"TRADE/pkg/lock"
 	"TRADE/pkg/logger"
 	"TRADE/pkg/market"
+	"TRADE/pkg/notify"
+	"TRADE/pkg/patterns"
+	"TRADE/pkg/performance"
+	"TRADE/pkg/portfolio"
+	"TRADE/pkg/publisher"
+	"TRADE/pkg/risk"
+	"TRADE/pkg/rpc"
+	"TRADE/pkg/schedule"
+	"TRADE/pkg/state"
+	"TRADE/pkg/status"
+	"TRADE/pkg/store"
 	"TRADE/pkg/strategy"
+	"TRADE/pkg/tracing"
 	"TRADE/pkg/types"
+	"TRADE/pkg/version"
+	"TRADE/pkg/watchdog"
 )
 
+// Portfolio defaults
+const (
+	defaultMaxExposure       = 1.0
+	defaultRebalanceInterval = 24 * time.Hour
+	defaultStatePath         = "state/handoff.json"
+)
+
+// exitRetryInterval is how long after an exit that left part of the
+// position unfilled it is sent again
+const exitRetryInterval = 30 * time.Second
+
+// Logger is the logging API the manager needs: leveled logging plus the
+// session's execution mode header, trade journal and audit log
+type Logger interface {
+	logger.Interface
+	SetExecutionMode(mode types.ExecutionMode)
+	LogTrade(entry logger.JournalEntry)
+	Audit(kind logger.AuditKind, orderID, symbol string, details map[string]interface{})
+	SetErrorContext(provider logger.ContextProvider)
+	CapturePanic()
+	Close()
+}
+
+// positionContext describes the open position in error reports
+type positionContext struct {
+	TradeID    string
+	EntryPrice float64
+	Notional   float64
+	EntryFill  decimal.Decimal // Filled entry price, for marking to market
+	Quantity   decimal.Decimal
+	Funding    decimal.Decimal // Funding received while open
+	Short      bool            // Sold short: profits from a falling price
+}
+
+// pnl returns the PnL of the position closed at price, funding included
+func (p positionContext) pnl(price decimal.Decimal) decimal.Decimal {
+	return positionPnL(p.EntryFill, price, p.Quantity, p.Short).Add(p.Funding)
+}
+
+// direction returns the direction of the open position
+func (m *Manager) direction() string {
+	if m.short {
+		return types.DirectionShort
+	}
+	return types.DirectionLong
+}
+
+// allocationOf returns the portfolio allocation of a strategy instance, or
+// that of the first instance for one no longer configured
+func (m *Manager) allocationOf(instance string) string {
+	if name, ok := m.allocations[instance]; ok {
+		return name
+	}
+	return m.allocations[m.strategy.Engines()[0].Instance()]
+}
+
+// positionPnL returns the PnL of quantity entered at entry and closed at
+// exit, long or short
+func positionPnL(entry, exit, quantity decimal.Decimal, short bool) decimal.Decimal {
+	pnl := exit.Sub(entry).Mul(quantity)
+	if short {
+		return pnl.Neg()
+	}
+	return pnl
+}
+
 // Manager coordinates all components of the trading system
 type Manager struct {
-	logger   *logger.Logger
-	market   *market.MarketData
-	analyzer *analyzer.Analyzer
-	strategy *strategy.Strategy
-	running  bool
+	config    *config.Config
+	execMode  types.ExecutionMode
+	logger    Logger
+	bus       *events.Bus
+	market    *market.MarketData
+	watched   []*market.MarketData // Streamed symbols that are not traded
+	live      *market.Stream       // Live connection feeding market and watched
+	analyzer  *analyzer.Analyzer
+	strategy  *strategy.Arbiter
+	portfolio *portfolio.Portfolio
+	accounts  *account.Book // Accounts orders are split across; nil without accounts
+	capital   float64       // Capital of the strategy, in the reporting currency
+	risk      *risk.Manager
+	liquidity *guard.Liquidity // Blocks entries into wide spreads or thin trading
+	news      *guard.Blackout  // Blocks entries around scheduled news; nil if disabled
+	newsFeed  *guard.NewsFeed
+	executors map[string]execution.Executor // Send live orders per account ("" without accounts); nil in paper mode
+	orderType execution.OrderType           // Type of the live orders
+	paper     bool                          // Paper trading on live data, with simulated fills
+	watchdog  *watchdog.Watchdog
+	simulator *market.Simulator
+	calendar  *calendar.Calendar
+	fx        *currency.Converter // Converts PnL and exposure into the reporting currency
+	rateFeed  *currency.Feed
+	base      string // Base asset of the traded symbol
+	quote     string // Quote asset of the traded symbol, which its prices and PnL are in
+	reporter  *status.Reporter
+	tracker   *performance.Tracker
+	breakdown *performance.Strategies  // Performance of each strategy instance
+	equity    *performance.EquityCurve // Marked-to-market equity of live and paper sessions
+	funding   *performance.Funding     // Funding settlements of a perpetual; nil for spot
+	day       *performance.Day         // Trades and fees since the last daily summary; nil if disabled
+	daily     *schedule.Daily          // Sends the daily summary; nil if disabled
+	runFile   string                   // Marks the process running for crash alerts; empty without heartbeat
+	locker    lock.Locker              // Takes the instance locks; nil without them
+	locks     []lock.Lock              // Instance locks held on the accounts and symbol traded
+	contexts  *export.ContextRecorder  // Writes the market context of closed trades; nil if disabled
+	tracer    *tracing.Tracer          // Traces the tick to order path; nil if disabled
+	stream    *rpc.Server
+	publisher *publisher.Publisher
+	notifier  *notify.Dispatcher // Routes events to the notifiers; nil if disabled
+	admin     *admin.Server
+	apiServer *api.Server // Serves the control API; nil if disabled
+	store     store.Store
+	runID     string // Tags the trades of this session in the trade history
+	backtest  bool
+	simulated bool // Ticks come from the market simulator
+	dataset   string
+	statePath string
+
+	// Halts entries once losses or open exposure go past their limits
+	killSwitch *risk.KillSwitch
+
+	// Drops repeated and out-of-order signals before they are executed
+	signals *signalGuard
+
+	// The open position
+	allocation  string               // Portfolio allocation the open position is reserved in
+	reserved    float64              // Notional reserved for the open position
+	quantity    decimal.Decimal      // Filled quantity of the open position
+	entryFill   decimal.Decimal      // Fill price of the open position's entry
+	entryTime   time.Time            // Time the open position was entered
+	short       bool                 // The open position is short
+	holdings    []account.Allocation // Quantity of the open position per account
+	openFunding decimal.Decimal      // Funding received by the open position, negative if paid
+	exited      decimal.Decimal      // Quantity sold by exits that left part of the position unfilled
+	exitValue   decimal.Decimal      // Quote value of the exited quantity, for its average price
+	exitRetry   atomic.Int64         // Unix nanoseconds after which an unfilled exit is sent again; 0 if none
+	position    atomic.Value         // positionContext of the open trade, for error reports
+
+	// What is traded, from the trading config
+	symbol       string
+	strategyName string              // Strategy name in logs and account assignments
+	allocations  map[string]string   // Portfolio allocation of each strategy instance
+	instrument   *types.Instrument   // Exchange tick and lot sizes of symbol
+	instruments  *market.Instruments // Tick and lot sizes of the traded and watched symbols
+	assetClass   asset.Class         // Market symbol trades in, annualizing its volatility and Sharpe ratio
+
+	// Time from a tick's arrival on the bus until the whole pipeline
+	// (metrics, signals, orders) has handled it
+	tickLatency *admin.Histogram
+	// Ticks the strategy skipped as older than others queued behind them
+	backlogSkipped int64
+	// The control API asked for the open position to be closed
+	closeRequested atomic.Bool
+	// Portfolio view of the instances running other symbols, as of the
+	// last status; nil while only this symbol runs
+	portfolioView atomic.Pointer[types.PortfolioView]
+
+	// Lifecycle state
+	status      Status
+	feedFrozen  bool           // Entries frozen by the watchdog until data is fresh
+	safeReturn  Status         // Status restored when the exchange recovers from an outage
+	outage      *outageMonitor // nil without outage detection
+	statusMutex sync.RWMutex
+	stopChan    chan struct{}
+	cleanup     []func()    // Undoes the steps of the start in progress; see abortStart
+	orders      *orderQueue // Executes live signals off the feed goroutine; nil in backtests and simulations
+	// Handlers this start subscribed to the bus, which outlives it
+	subscriptions []events.SubscriptionID
+}
+
+// NewManager creates a new trading system manager.
+// Execution always starts in paper mode until SetExecutionMode says otherwise.
+func NewManager(log Logger, cfg *config.Config) *Manager {
+	symbol := strings.ToLower(cfg.Trading.Symbol)
+	return &Manager{
+		config:       cfg,
+		execMode:     types.ExecutionPaper,
+		logger:       log,
+		bus:          events.NewBus(),
+		runID:        ids.Run(),
+		symbol:       symbol,
+		strategyName: cfg.Trading.Strategy,
+		tickLatency:  admin.NewHistogram(),
+		statePath:    defaultStatePath,
+		status:       StatusStopped,
+	}
+}
+
+// SetDataset sets the tick datasets replayed in backtest mode: a path, or
+// comma-separated paths and glob patterns merged in time order; empty
+// replays the first dataset in the data directory
+func (m *Manager) SetDataset(path string) {
+	m.dataset = path
+}
+
+// SetStatePath sets the file used to hand off open trades across restarts
+func (m *Manager) SetStatePath(path string) {
+	m.statePath = path
+}
+
+// SetExecutionMode selects paper or live execution
+func (m *Manager) SetExecutionMode(mode types.ExecutionMode) {
+	m.execMode = mode
+	m.logger.SetExecutionMode(mode)
+	if mode == types.ExecutionLive {
+		m.logger.Warning("LIVE TRADING ENABLED - real orders will be sent")
+	}
+}
+
+// ExecutionMode returns the current execution mode
+func (m *Manager) ExecutionMode() types.ExecutionMode {
+	return m.execMode
+}
+
+// GetPerformance returns the performance metrics of the trades closed in
+// this run, live, paper or backtest
+func (m *Manager) GetPerformance() *types.PerformanceMetrics {
+	return m.tracker.Metrics()
+}
+
+// GetStrategyPerformance returns the performance metrics of each strategy
+// instance, by name
+func (m *Manager) GetStrategyPerformance() map[string]*types.PerformanceMetrics {
+	return m.breakdown.Metrics()
+}
+
+// EventBus returns the bus on which all component events are published
+func (m *Manager) EventBus() *events.Bus {
+	return m.bus
+}
+
+// Initialize sets up all components of the trading system. Each component
+// started pushes its stop on the cleanup stack, which abortStart unwinds if
+// the start fails.
+func (m *Manager) Initialize() error {
+	m.logger.Info("Initializing trading system components")
+	m.onAbort(m.unsubscribe)
+
+	// Annualize volatility and Sharpe ratios over the trading time of the
+	// traded symbol's market
+	class, err := asset.Lookup(m.config.Trading.AssetClass)
+	if err != nil {
+		return fmt.Errorf("invalid trading config: %v", err)
+	}
+	m.assetClass = class
+
+	// Load the exchange calendar that gates entries, the asset class's
+	// unless one is configured
+	exchange := m.config.Calendar.Exchange
+	if exchange == "" {
+		exchange = class.Exchange
+	}
+	cal, err := calendar.Load(exchange, m.config.Calendar.Holidays)
+	if err != nil {
+		return err
+	}
+	m.calendar = cal
+	m.logger.Info(fmt.Sprintf("Exchange calendar: %s, display time zone: %s",
+		m.calendar, calendar.ZoneName(calendar.DisplayLocation())))
+
+	// Trade the configured symbol in exchange-acceptable steps
+	trading := m.config.Trading
+	if trading.TickSize <= 0 || trading.LotSize <= 0 {
+		return fmt.Errorf("invalid trading config: tick_size and lot_size must be positive")
+	}
+	m.instrument = types.NewInstrument(m.symbol, decimal.FromFloat(trading.TickSize), decimal.FromFloat(trading.LotSize))
+	m.setupInstruments()
+
+	// Report capital, exposure and PnL in one currency
+	if err := m.setupCurrency(); err != nil {
+		return err
+	}
+
+	// Load the accounts orders are split across
+	if err := m.setupAccounts(); err != nil {
+		return err
+	}
+
+	// Connect live orders to the exchange accounts
+	if err := m.setupExecution(); err != nil {
+		return err
+	}
+
+	// Initialize market data component
+	m.market = market.NewMarketData(m.symbol, m.logger.With(logger.ComponentKey, "market", logger.SymbolKey, m.symbol), m.bus)
+	m.market.SetInstrument(m.instrument)
+	if err := m.setupStream(); err != nil {
+		return err
+	}
+	if err := m.setupCandles(); err != nil {
+		return fmt.Errorf("invalid market config: %v", err)
+	}
+	if trading.Perpetual {
+		m.funding = &performance.Funding{}
+	}
+
+	// Initialize analyzer with market data
+	m.analyzer = analyzer.NewAnalyzer(m.market, m.logger.With(logger.ComponentKey, "analyzer"))
+	if err := m.setupAnalyzer(); err != nil {
+		return fmt.Errorf("invalid trading config: %v", err)
+	}
+	if candles := m.config.Market.Candles; candles.Indicators != "" {
+		period := candles.Period
+		if period <= 0 {
+			period = analyzer.DefaultIndicatorPeriod
+		}
+		timeframe, err := bars.ParseInterval(candles.Indicators)
+		if err == nil {
+			err = m.analyzer.SetCandleIndicators(timeframe, period)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid market config: candle indicators: %v", err)
+		}
+		m.logger.Info(fmt.Sprintf("ATR and RSI computed on %s candles over %d periods", candles.Indicators, period))
+	}
+
+	// Initialize the strategy instances with analyzer, under an arbiter
+	// deciding which of their entries are taken
+	if err := m.setupStrategies(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupImbalance(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupDivergence(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupTimeframes(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupPatterns(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupIndicators(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupSizing(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	if err := m.setupShorts(); err != nil {
+		return fmt.Errorf("invalid strategy config: %v", err)
+	}
+	m.onAbort(m.closeStrategy)
+	for _, engine := range m.strategy.Engines() {
+		if rule := m.config.Stops.BreakEven; rule.Enabled {
+			engine.SetBreakEven(&strategy.BreakEvenRule{
+				TriggerMultiple: rule.TriggerMultiple,
+				FeeBuffer:       rule.FeeBuffer,
+			})
+		}
+		if adaptive := m.config.Adaptive; adaptive.Enabled {
+			parameters := make(map[string]strategy.Scaling, len(adaptive.Parameters))
+			for name, scaling := range adaptive.Parameters {
+				parameters[name] = strategy.Scaling{Low: scaling.Low, High: scaling.High}
+			}
+			thresholds, err := strategy.NewAdaptive(strategy.AdaptiveSettings{
+				SampleInterval: adaptive.SampleInterval,
+				Window:         adaptive.Window,
+				MinSamples:     adaptive.MinSamples,
+				Parameters:     parameters,
+			})
+			if err != nil {
+				return fmt.Errorf("invalid adaptive config: %v", err)
+			}
+			engine.SetAdaptive(thresholds)
+		}
+	}
+
+	// Record the market around each trade for charting
+	if err := m.setupTradeContext(); err != nil {
+		return fmt.Errorf("invalid trade_context config: %v", err)
+	}
+	m.onAbort(m.closeTradeContext)
+
+	// Trace ticks through analysis, signals and orders
+	if err := m.setupTracing(); err != nil {
+		return fmt.Errorf("invalid tracing config: %v", err)
+	}
+	m.onAbort(m.closeTracing)
+
+	// Give each strategy instance its share of the capital
+	m.portfolio = portfolio.NewPortfolio(m.capital, defaultMaxExposure, m.logger.With(logger.ComponentKey, "portfolio"))
+	m.allocations = make(map[string]string)
+	weights := m.config.Strategy.InstanceWeights()
+	for i, engine := range m.strategy.Engines() {
+		name := m.symbol + "/" + engine.Instance()
+		if err := m.portfolio.Register(name, weights[i]); err != nil {
+			return err
+		}
+		m.allocations[engine.Instance()] = name
+	}
+
+	// Block entries the spread or thin trading would make too costly
+	filter := m.config.EntryFilter
+	m.liquidity = &guard.Liquidity{
+		MaxSpreadBps: filter.MaxSpreadBps,
+		MinVolume:    filter.MinVolume,
+		VolumeWindow: filter.VolumeWindow,
+		MaxQuoteAge:  filter.MaxQuoteAge,
+	}
+
+	// Keep out of the market around high-impact news
+	if err := m.setupNews(); err != nil {
+		return err
+	}
+
+	// Summarize each day's trades at the configured time and send heartbeats
+	if err := m.setupDailySummary(); err != nil {
+		return err
+	}
+	if err := m.setupHeartbeat(); err != nil {
+		return err
+	}
+
+	// Check entries against the exposure limits of the whole book
+	limits := m.config.Risk
+	m.risk = risk.NewManager(risk.Limits{
+		MaxOpenPositions:          limits.MaxOpenPositions,
+		MaxSymbolNotional:         limits.MaxSymbolNotional,
+		MaxTotalNotional:          limits.MaxTotalNotional,
+		MaxVolatilityContribution: limits.MaxVolatilityContribution,
+		MaxCorrelatedNotional:     limits.MaxCorrelatedNotional,
+		CorrelationThreshold:      limits.CorrelationThreshold,
+		SampleInterval:            limits.SampleInterval,
+		Window:                    limits.Window,
+		MaxStopDistance:           limits.MaxStopDistance,
+		MaxStopRisk:               limits.MaxStopRisk,
+		ResizeStops:               limits.ResizeStops,
+	})
+	if err := m.setupAssetClasses(); err != nil {
+		return err
+	}
+	m.setupKillSwitch()
+	m.setupOutage()
+
+	// Drop signals repeating an intent or arriving out of order
+	m.signals = newSignalGuard(m.config.Execution.Dedup.Window)
+
+	// Persist closed trades to the trade history
+	if err := m.openStore(); err != nil {
+		return err
+	}
+	m.onAbort(m.closeStore)
+
+	// Attach runtime context to tracked errors
+	m.position.Store(positionContext{})
+	m.logger.SetErrorContext(m.errorContext)
+
+	// Display status updates published on the bus
+	renderer, err := status.NewRenderer(m.config.Status.Renderer)
+	if err != nil {
+		return err
+	}
+	m.reporter = status.NewReporter(m.bus, renderer, os.Stdout)
+	m.reporter.Start()
+	m.onAbort(m.reporter.Stop)
+
+	// Track performance of closed trades
+	m.tracker = performance.NewTracker(m.bus)
+	m.tracker.SetAnnualization(m.calendar, m.assetClass.TradingDays)
+	m.tracker.Start()
+	m.onAbort(m.tracker.Stop)
+	m.equity = performance.NewEquityCurve(m.capital)
+
+	// Stream events to external gRPC consumers
+	if cfg := m.config.GRPC; cfg.Enabled {
+		m.stream = rpc.NewServer(m.bus, rpc.Options{
+			Address:        cfg.Address,
+			CertFile:       cfg.CertFile,
+			KeyFile:        cfg.KeyFile,
+			BufferSize:     cfg.BufferSize,
+			MaxSubscribers: cfg.MaxSubscribers,
+		}, m.logger.With(logger.ComponentKey, "grpc"))
+		if err := m.stream.Start(); err != nil {
+			m.stream = nil
+			return err
+		}
+		m.onAbort(func() {
+			m.stream.Stop()
+			m.stream = nil
+		})
+	}
+
+	// Fan signals and trade events out to the message bus
+	if err := m.startPublisher(); err != nil {
+		return err
+	}
+
+	// Tell people about trades, alerts and errors
+	if err := m.startNotifications(); err != nil {
+		return err
+	}
+
+	// Serve runtime diagnostics and profiles
+	if cfg := m.config.Admin; cfg.Enabled {
+		m.admin = admin.NewServer(admin.Options{
+			Address: cfg.Address,
+			Pprof:   cfg.Pprof,
+		}, m.logger.With(logger.ComponentKey, "admin"))
+		m.admin.AddHistogram("tick_processing", m.tickLatency)
+		m.admin.AddStats("market_feed", func() interface{} { return m.market.FeedStats() })
+		if m.live != nil {
+			m.admin.AddStats("pipeline", func() interface{} { return m.pipelineStats() })
+		}
+		m.admin.AddStats("equity", func() interface{} { return m.equity.Current() })
+		m.admin.AddStats("entry_filter", func() interface{} { return m.liquidity.Stats() })
+		m.admin.AddStats("signal_dedup", func() interface{} { return m.signals.Stats() })
+		if m.accounts != nil {
+			m.admin.AddStats("accounts", func() interface{} { return m.accounts.Balances() })
+		}
+		if outage := m.outage; outage != nil {
+			m.admin.AddStats("outage", func() interface{} { return outage.Stats() })
+		}
+		if tracer := m.tracer; tracer != nil {
+			m.admin.AddStats("tracing", func() interface{} { return tracer.Stats() })
+		}
+		if m.funding != nil {
+			m.admin.AddStats("funding", func() interface{} { return m.funding.Stats() })
+		}
+		if m.news != nil {
+			m.admin.AddStats("news_blackout", func() interface{} { return m.news.Stats() })
+		}
+		if len(m.config.Strategy.Instances) > 0 {
+			m.admin.AddStats("strategies", func() interface{} { return m.breakdown.Metrics() })
+		}
+		if m.config.Adaptive.Enabled {
+			m.admin.AddStats("adaptive_thresholds", func() interface{} {
+				stats, _ := m.strategy.AdaptiveStats()
+				return stats
+			})
+		}
+		if err := m.admin.Start(); err != nil {
+			m.admin = nil
+			return err
+		}
+		m.onAbort(func() {
+			m.admin.Stop()
+			m.admin = nil
+		})
+	}
+
+	// Set up event subscriptions
+	m.setupSubscriptions()
+
+	return nil
+}
+
+// setupCurrency creates the converter into the reporting currency with
+// the configured fixed rates
+func (m *Manager) setupCurrency() error {
+	cfg := m.config.Currency
+	base, quote, err := currency.Split(m.symbol)
+	if err != nil {
+		return err
+	}
+	m.base, m.quote = base, quote
+
+	reporting := cfg.Reporting
+	if reporting == "" {
+		reporting = quote
+	}
+	m.fx = currency.NewConverter(reporting)
+	m.fx.SetMaxAge(cfg.MaxAge)
+	for symbol, price := range cfg.Rates {
+		if err := m.fx.SetPrice(symbol, price, time.Time{}); err != nil {
+			return fmt.Errorf("invalid conversion rate: %v", err)
+		}
+	}
+	if _, err := m.fx.Rate(quote, reporting, time.Time{}); err != nil && len(cfg.Symbols) == 0 {
+		m.logger.Warning(fmt.Sprintf("No rate converts %s into reporting currency %s yet; PnL stays in %s until one is known",
+			quote, reporting, quote))
+	}
+	m.logger.Info(fmt.Sprintf("Reporting currency: %s", reporting))
+	return nil
+}
+
+// setupInstruments reads the tick and lot sizes of the traded and watched
+// symbols from the exchange metadata in live and paper mode. The traded
+// symbol keeps the configured sizes when they cannot be read, and in
+// backtests and simulations; watched symbols without metadata round their
+// prices to a precision guessed from the first one.
+func (m *Manager) setupInstruments() {
+	m.instruments = market.NewInstruments()
+	m.instruments.Add(m.instrument)
+	if m.backtest || m.simulated {
+		return
+	}
+
+	cfg := m.config.Market
+	if exchange := strings.ToLower(cfg.Exchange); exchange != "" && exchange != "binance" {
+		return
+	}
+	endpoint := cfg.InstrumentsURL
+	if endpoint == "" {
+		endpoint = market.DefaultInstrumentsURL
+		if m.config.Trading.Perpetual {
+			endpoint = market.DefaultFuturesInstrumentsURL
+		}
+	}
+	log := m.logger.With(logger.ComponentKey, "market")
+	symbols := append([]string{m.symbol}, cfg.Symbols...)
+	if _, err := m.instruments.FetchBinance(endpoint, symbols); err != nil {
+		log.Warning(fmt.Sprintf("Failed to read exchange metadata, using the configured tick and lot sizes: %v", err))
+		return
+	}
+
+	instrument, _ := m.instruments.Get(m.symbol)
+	if instrument == m.instrument {
+		log.Warning(fmt.Sprintf("No exchange metadata for %s; trading with tick size %s and lot size %s from the config",
+			m.symbol, m.instrument.TickSize, m.instrument.LotSize))
+		return
+	}
+	if instrument.TickSize != m.instrument.TickSize || instrument.LotSize != m.instrument.LotSize {
+		log.Info(fmt.Sprintf("Exchange tick size %s and lot size %s of %s override the configured %s and %s",
+			instrument.TickSize, instrument.LotSize, m.symbol, m.instrument.TickSize, m.instrument.LotSize))
+	}
+	m.instrument = instrument
+}
+
+// setupStream creates the live feed of the configured exchange and routes
+// the traded symbol and the watched symbols each into its own market data
+func (m *Manager) setupStream() error {
+	cfg := m.config.Market
+	log := m.logger.With(logger.ComponentKey, "market")
+	var feed market.Feed
+	switch strings.ToLower(cfg.Exchange) {
+	case "", "binance":
+		binance := market.NewBinanceFeed(log, m.bus)
+		if cfg.StreamURL != "" {
+			binance.SetURL(cfg.StreamURL)
+		}
+		binance.SetPerpetual(m.config.Trading.Perpetual)
+		feed = binance
+	default:
+		return fmt.Errorf("invalid market config: unknown exchange %q (want binance)", cfg.Exchange)
+	}
+	m.live = market.NewStream(feed, log, m.bus)
+	policy, err := market.ParseBackpressurePolicy(cfg.Backpressure.Policy)
+	if err != nil {
+		return fmt.Errorf("invalid market config: %v", err)
+	}
+	if err := m.live.SetQueue(market.QueueSettings{Size: cfg.Backpressure.QueueSize, Policy: policy}); err != nil {
+		return fmt.Errorf("invalid market config: %v", err)
+	}
+	if book := cfg.OrderBook; book.Enabled {
+		endpoint := book.SnapshotURL
+		if endpoint == "" {
+			endpoint = market.DefaultDepthSnapshotURL
+			if m.config.Trading.Perpetual {
+				endpoint = market.DefaultFuturesDepthSnapshotURL
+			}
+		}
+		if err := m.live.SetOrderBook(market.OrderBookSettings{
+			Levels:        book.Levels,
+			SnapshotURL:   endpoint,
+			SnapshotLimit: book.SnapshotLimit,
+		}); err != nil {
+			return fmt.Errorf("invalid market config: %v", err)
+		}
+		log.Info(fmt.Sprintf("Local order books of the top %d levels, from snapshots at %s", book.Levels, endpoint))
+	}
+	if err := m.live.Add(m.market); err != nil {
+		return err
+	}
+	m.watched = nil
+	for _, symbol := range cfg.Symbols {
+		watched := market.NewMarketData(symbol, m.logger.With(logger.ComponentKey, "market", logger.SymbolKey, symbol), m.bus)
+		if instrument, ok := m.instruments.Get(symbol); ok {
+			watched.SetInstrument(instrument)
+		}
+		if err := m.live.Add(watched); err != nil {
+			return fmt.Errorf("invalid market config: %v", err)
+		}
+		m.watched = append(m.watched, watched)
+	}
+	return nil
+}
+
+// PipelineStats are the queue counts of the live stream and the ticks the
+// strategy skipped behind the feed
+type PipelineStats struct {
+	market.PipelineStats
+	StrategySkipped int64 `json:"strategy_skipped"`
+}
+
+// pipelineStats returns the counts of the live tick pipeline
+func (m *Manager) pipelineStats() PipelineStats {
+	return PipelineStats{
+		PipelineStats:   m.live.PipelineStats(),
+		StrategySkipped: atomic.LoadInt64(&m.backlogSkipped),
+	}
+}
+
+// setupStrategies creates the engines of the strategy instances, each
+// with its thresholds, and the arbiter running them
+func (m *Manager) setupStrategies() error {
+	cfg := m.config.Strategy
+	for _, plugin := range cfg.Plugins {
+		names, err := strategy.LoadPlugin(strategy.PluginSettings{
+			Path:    plugin.Path,
+			Name:    plugin.Name,
+			Command: plugin.Command,
+			Args:    plugin.Args,
+			Timeout: plugin.Timeout,
+		})
+		if err != nil {
+			return err
+		}
+		m.logger.Info(fmt.Sprintf("Loaded strategy plugin %s: %s", plugin.Path+plugin.Command, strings.Join(names, ", ")))
+	}
+	several := len(cfg.Instances) > 0
+	instances := make([]strategy.Instance, 0, len(cfg.StrategyInstances()))
+	for _, instance := range cfg.StrategyInstances() {
+		fields := []interface{}{
+			logger.ComponentKey, "strategy",
+			logger.SymbolKey, m.symbol,
+			logger.StrategyKey, m.strategyName,
+		}
+		if several && instance.Name != "" {
+			fields = append(fields, "instance", instance.Name)
+		}
+		engine, err := strategy.NewEngine(instance.Type, m.analyzer, m.logger.With(fields...))
+		if err != nil {
+			return err
+		}
+		if instance.Name != "" {
+			engine.SetInstance(instance.Name)
+		}
+		engine.SetInstrument(m.instrument)
+		if err := engine.SetThresholds(cfg.InstanceThresholds(instance)); err != nil {
+			return fmt.Errorf("%s: %v", engine.Instance(), err)
+		}
+		instances = append(instances, strategy.Instance{Engine: engine, Priority: instance.Priority})
+	}
+
+	arbiter, err := strategy.NewArbiter(instances, strategy.ArbiterSettings{
+		Mode:       strategy.Arbitration(cfg.Arbitration.Mode),
+		MinVotes:   cfg.Arbitration.MinVotes,
+		VoteWindow: cfg.Arbitration.VoteWindow,
+	}, m.logger.With(logger.ComponentKey, "strategy", logger.SymbolKey, m.symbol))
+	if err != nil {
+		return err
+	}
+	m.strategy = arbiter
+	names := make([]string, len(instances))
+	for i, instance := range instances {
+		names[i] = instance.Engine.Instance()
+	}
+	m.breakdown = performance.NewStrategies(names)
+	if !several {
+		m.logger.Info(fmt.Sprintf("Trading %s with the %s strategy", m.symbol, instances[0].Engine.Name()))
+		return nil
+	}
+	for i, instance := range instances {
+		names[i] = fmt.Sprintf("%s (%s, priority %d)", instance.Engine.Instance(), instance.Engine.Name(), instance.Priority)
+	}
+	m.logger.Info(fmt.Sprintf("Trading %s with %d strategies under %s arbitration: %s",
+		m.symbol, len(instances), arbiter.Mode(), strings.Join(names, ", ")))
+	return nil
+}
+
+// setupCandles makes the traded market data build the configured candles
+func (m *Manager) setupCandles() error {
+	cfg := m.config.Market.Candles
+	if len(cfg.Timeframes) == 0 {
+		return nil
+	}
+	timeframes, err := market.ParseTimeframes(cfg.Timeframes)
+	if err != nil {
+		return err
+	}
+	candles, err := market.NewCandleBuilder(timeframes, cfg.History)
+	if err != nil {
+		return err
+	}
+	m.market.SetCandles(candles)
+	return nil
+}
+
+// setupImbalance selects the order imbalance entries require; the book
+// imbalance subscribes the live stream to the depth of the book, unless
+// local order books deliver it
+func (m *Manager) setupImbalance() error {
+	cfg := m.config.Strategy
+	source, err := strategy.ParseImbalanceSource(cfg.Imbalance)
+	if err != nil {
+		return err
+	}
+	for _, engine := range m.strategy.Engines() {
+		engine.SetImbalanceSource(source)
+	}
+	if source != strategy.ImbalanceBook {
+		return nil
+	}
+
+	book := cfg.BookImbalance
+	levels := book.Levels
+	if orderBook := m.config.Market.OrderBook; orderBook.Enabled {
+		levels = orderBook.Levels
+	} else if err := m.live.SetDepth(levels); err != nil {
+		return err
+	}
+	if book.TrendWindow <= 0 {
+		return fmt.Errorf("book imbalance trend window must be positive")
+	}
+	m.analyzer.SetBookImbalanceWindow(book.TrendWindow)
+	m.logger.Info(fmt.Sprintf("Entries require the book imbalance of the top %d levels, trend over %s",
+		levels, book.TrendWindow))
+	return nil
+}
+
+// setupDivergence enables the divergence detector and the entry and exit
+// rules using it
+func (m *Manager) setupDivergence() error {
+	cfg := m.config.Strategy.Divergence
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Separation <= 0 || cfg.Lookback <= cfg.Separation {
+		return fmt.Errorf("divergence lookback must be greater than its separation, which must be positive")
+	}
+	m.analyzer.SetDivergence(cfg.Lookback, cfg.Separation)
+	for _, engine := range m.strategy.Engines() {
+		engine.SetDivergence(&strategy.DivergenceRule{EntryFilter: cfg.EntryFilter, Exit: cfg.Exit})
+	}
+	m.logger.Info(fmt.Sprintf("Divergences detected over %d ticks (entry filter: %t, exit: %t)",
+		cfg.Lookback, cfg.EntryFilter, cfg.Exit))
+	return nil
+}
+
+// setupTimeframes measures the metrics of the candle timeframes analyzed
+// and requires the confirming ones to agree with entries
+func (m *Manager) setupTimeframes() error {
+	cfg := m.config.Strategy.Timeframes
+	names := cfg.Timeframes()
+	if len(names) == 0 {
+		return nil
+	}
+	if cfg.Period < 2 {
+		return fmt.Errorf("timeframes period must be at least 2 candles")
+	}
+	if cfg.MinTrend < 0 {
+		return fmt.Errorf("timeframes min_trend cannot be negative")
+	}
+	timeframes, err := market.ParseTimeframes(names)
+	if err != nil {
+		return err
+	}
+	if err := m.analyzer.SetTimeframes(timeframes, cfg.Period); err != nil {
+		return fmt.Errorf("timeframes: %v (add them to market.candles.timeframes)", err)
+	}
+	m.logger.Info(fmt.Sprintf("Metrics measured on %s candles over %d periods", strings.Join(names, ", "), cfg.Period))
+	if len(cfg.Confirm) == 0 {
+		return nil
+	}
+
+	// Name the confirming timeframes as the metrics do
+	confirm := make([]string, len(cfg.Confirm))
+	for i, name := range cfg.Confirm {
+		timeframe, _ := bars.ParseInterval(name)
+		confirm[i] = bars.FormatInterval(timeframe)
+	}
+	for _, engine := range m.strategy.Engines() {
+		engine.SetTimeframes(&strategy.TimeframeRule{Timeframes: confirm, MinTrend: cfg.MinTrend})
+	}
+	m.logger.Info(fmt.Sprintf("Entries require the %s trend to confirm their direction (min trend %.4f%%)",
+		strings.Join(confirm, ", "), cfg.MinTrend))
+	return nil
+}
+
+// setupPatterns detects candlestick patterns on the closed candles of a
+// timeframe, publishing each one, and applies them to entries
+func (m *Manager) setupPatterns() error {
+	cfg := m.config.Strategy.Patterns
+	if !cfg.Enabled {
+		return nil
+	}
+	timeframe, err := bars.ParseInterval(cfg.Timeframe)
+	if err != nil {
+		return fmt.Errorf("patterns: %v", err)
+	}
+	detector, err := patterns.NewDetector(cfg.Detect)
+	if err != nil {
+		return fmt.Errorf("patterns: %v", err)
+	}
+	err = m.analyzer.SetPatterns(timeframe, detector, cfg.Recent, func(pattern types.CandlePattern, candle market.Candle) {
+		m.bus.Publish(&events.PatternEvent{Symbol: m.symbol, Pattern: pattern, Close: candle.Close})
+	})
+	if err != nil {
+		return fmt.Errorf("patterns: %v (add it to market.candles.timeframes)", err)
+	}
+	detected := "every pattern"
+	if len(cfg.Detect) > 0 {
+		detected = strings.Join(cfg.Detect, ", ")
+	}
+	m.logger.Info(fmt.Sprintf("Candlestick patterns detected on %s candles: %s", bars.FormatInterval(timeframe), detected))
+	if !cfg.Confirm && !cfg.Veto {
+		return nil
+	}
+	for _, engine := range m.strategy.Engines() {
+		engine.SetPatterns(&strategy.PatternRule{Confirm: cfg.Confirm, Veto: cfg.Veto})
+	}
+	m.logger.Info(fmt.Sprintf("Entries filtered by the patterns of the last %d candles (confirm %t, veto %t)",
+		cfg.Recent, cfg.Confirm, cfg.Veto))
+	return nil
+}
+
+// setupIndicators reports RSI, MACD, Bollinger Bands and the moving average
+// crossover in the metrics, on the ticks or the candles of a timeframe
+func (m *Manager) setupIndicators() error {
+	cfg := m.config.Strategy.Indicators
+	if !cfg.Enabled {
+		return nil
+	}
+	settings := analyzer.IndicatorSettings{
+		RSIPeriod:           cfg.RSIPeriod,
+		MACDFast:            cfg.MACD.Fast,
+		MACDSlow:            cfg.MACD.Slow,
+		MACDSignal:          cfg.MACD.Signal,
+		BollingerPeriod:     cfg.Bollinger.Period,
+		BollingerDeviations: cfg.Bollinger.Deviations,
+		Crossover:           analyzer.MovingAverage(cfg.Crossover.Type),
+		CrossoverFast:       cfg.Crossover.Fast,
+		CrossoverSlow:       cfg.Crossover.Slow,
+	}
+	source := "ticks"
+	var timeframe time.Duration
+	if cfg.Timeframe != "" {
+		var err error
+		if timeframe, err = bars.ParseInterval(cfg.Timeframe); err != nil {
+			return fmt.Errorf("indicators: %v", err)
+		}
+		source = bars.FormatInterval(timeframe) + " candles"
+	}
+	if err := m.analyzer.SetIndicators(settings, timeframe); err != nil {
+		return fmt.Errorf("indicators: %v", err)
+	}
+	m.logger.Info(fmt.Sprintf("Indicators computed on %s: RSI %d, MACD %d/%d/%d, Bollinger %d/%.1f, %s crossover %d/%d",
+		source, cfg.RSIPeriod, cfg.MACD.Fast, cfg.MACD.Slow, cfg.MACD.Signal,
+		cfg.Bollinger.Period, cfg.Bollinger.Deviations, strings.ToUpper(cfg.Crossover.Type), cfg.Crossover.Fast, cfg.Crossover.Slow))
+	return nil
+}
+
+// setupSizing makes entry signals carry a quantity sized from the equity,
+// by default the capital converted into the quote currency
+func (m *Manager) setupSizing() error {
+	cfg := m.config.Strategy.Sizing
+	mode, err := strategy.ParseSizingMode(cfg.Mode)
+	if err != nil {
+		return err
+	}
+	if mode == strategy.SizingNone {
+		return nil
+	}
+	if cfg.RiskPercent <= 0 || cfg.RiskPercent > 100 {
+		return fmt.Errorf("sizing risk percent must be above 0 and at most 100")
+	}
+	equity := cfg.Equity
+	if equity <= 0 {
+		capital, err := m.fx.FromReporting(decimal.FromFloat(m.capital), m.quote, time.Time{})
+		if err != nil {
+			return fmt.Errorf("sizing equity must be set in %s: %v", m.quote, err)
+		}
+		equity = capital.Float64()
+	}
+	for _, engine := range m.strategy.Engines() {
+		engine.SetSizing(&strategy.Sizing{Mode: mode, Equity: equity, RiskPercent: cfg.RiskPercent})
+	}
+	m.logger.Info(fmt.Sprintf("Entries sized %s: %.2f%% of %.2f %s equity per trade",
+		mode, cfg.RiskPercent, equity, m.quote))
+	return nil
+}
+
+// setupShorts enables short entries; live orders can only sell short on
+// a perpetual, and accounts only book long holdings
+func (m *Manager) setupShorts() error {
+	if !m.config.Strategy.Shorts {
+		return nil
+	}
+	if m.accounts != nil {
+		return fmt.Errorf("shorts are not supported with accounts")
+	}
+	live := m.execMode == types.ExecutionLive && !m.paper && !m.backtest
+	if live && !m.config.Trading.Perpetual {
+		return fmt.Errorf("live shorts need a perpetual (trading.perpetual)")
+	}
+	for _, engine := range m.strategy.Engines() {
+		engine.SetShorts(true)
+	}
+	m.logger.Info("Short entries enabled")
+	return nil
+}
+
+// warnNoDepth warns that entries conditioned on the book imbalance are
+// never taken in a mode without book depth data
+func (m *Manager) warnNoDepth(mode string) {
+	if source, _ := strategy.ParseImbalanceSource(m.config.Strategy.Imbalance); source == strategy.ImbalanceBook {
+		m.logger.Warning(fmt.Sprintf("%s mode has no order book depth: entries conditioned on the book imbalance will not be taken", mode))
+	}
+}
+
+// setupNews creates the news blackout guard and loads its schedule file
+func (m *Manager) setupNews() error {
+	cfg := m.config.News
+	if !cfg.Enabled {
+		return nil
+	}
+	impact, err := guard.ParseImpact(cfg.MinImpact)
+	if err != nil {
+		return fmt.Errorf("invalid news config: %v", err)
+	}
+	m.news = &guard.Blackout{
+		Before:        cfg.Before,
+		After:         cfg.After,
+		FlattenBefore: cfg.FlattenBefore,
+		MinImpact:     impact,
+		Currencies:    cfg.Currencies,
+	}
+	if cfg.File != "" {
+		schedule, err := guard.LoadSchedule(cfg.File)
+		if err != nil {
+			return err
+		}
+		m.news.SetSchedule(schedule)
+		m.logger.Info(fmt.Sprintf("Loaded %d news event(s) from %s", len(schedule), cfg.File),
+			logger.ComponentKey, "news")
+	}
+	return nil
+}
+
+// startNewsFeed polls the news calendar URL, if any
+func (m *Manager) startNewsFeed() {
+	if m.news == nil || m.config.News.URL == "" {
+		return
+	}
+	m.newsFeed = guard.NewNewsFeed(m.news, m.config.News.URL, m.config.News.RefreshInterval,
+		m.logger.With(logger.ComponentKey, "news"))
+	m.newsFeed.Start()
+}
+
+// checkEntry applies the entry guards to an entry signal at t and returns
+// the component of the guard that blocked it
+func (m *Manager) checkEntry(t time.Time) (string, error) {
+	if err := m.liquidity.Check(m.market, time.Now()); err != nil {
+		return "entry_filter", err
+	}
+	if m.news != nil {
+		if err := m.news.Check(t); err != nil {
+			return "news", err
+		}
+	}
+	return "", nil
+}
+
+// startRateFeed polls the live prices of the conversion symbols, if any
+func (m *Manager) startRateFeed() {
+	cfg := m.config.Currency
+	if len(cfg.Symbols) == 0 {
+		return
+	}
+	m.rateFeed = currency.NewFeed(m.fx, cfg.TickerURL, cfg.Symbols, cfg.RefreshInterval,
+		m.logger.With(logger.ComponentKey, "currency"))
+	m.rateFeed.Start()
+}
+
+// normalizePnL converts a closed trade's PnL into the reporting currency
+func (m *Manager) normalizePnL(trade *events.TradeClosedEvent) {
+	pnl, err := m.fx.ToReporting(trade.PnL, trade.Currency, trade.ExitTime)
+	if err != nil {
+		m.logger.Warning(fmt.Sprintf("PnL of trade %s left in %s: %v", trade.TradeID, trade.Currency, err),
+			logger.TradeIDKey, trade.TradeID)
+		return
+	}
+	trade.ReportingPnL = pnl
+	trade.ReportingCurrency = m.fx.Reporting()
+}
+
+// openStore opens the configured trade history database, if any
+func (m *Manager) openStore() error {
+	cfg := m.config.Storage
+	if cfg.Type == "" {
+		return nil
+	}
+	history, err := store.Open(cfg.Type, cfg.Source())
+	if err != nil {
+		return err
+	}
+	m.store = history
+	m.logger.Info(fmt.Sprintf("Saving trade history to %s database", cfg.Type), "run_id", m.runID)
+	return nil
+}
+
+// setupTradeContext starts recording the ticks and metrics around the
+// trades of the traded symbol
+func (m *Manager) setupTradeContext() error {
+	cfg := m.config.TradeContext
+	if !cfg.Enabled {
+		return nil
+	}
+	contexts, err := export.NewContextRecorder(m.symbol, export.ContextSettings{
+		Dir:        cfg.Dir,
+		Before:     cfg.Before,
+		After:      cfg.After,
+		LosingOnly: cfg.LosingOnly,
+	}, m.logger.With(logger.ComponentKey, "export"))
+	if err != nil {
+		return err
+	}
+	m.subscriptions = append(m.subscriptions, contexts.Subscribe(m.bus)...)
+	m.contexts = contexts
+	m.logger.Info(fmt.Sprintf("Trade contexts written to %s (%s before the entry, %s after the exit)",
+		cfg.Dir, cfg.Before, cfg.After))
+	return nil
+}
+
+// closeTradeContext writes the contexts of the trades closed less than
+// After ago with the ticks received
+func (m *Manager) closeTradeContext() {
+	if m.contexts == nil {
+		return
+	}
+	if err := m.contexts.Close(); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to write trade contexts: %v", err))
+	}
+	m.logger.Info(fmt.Sprintf("Wrote %d trade context file(s) to %s", m.contexts.Written(), m.config.TradeContext.Dir))
+	m.contexts = nil
+}
+
+// setupTracing starts exporting the traces of the tick to order path
+func (m *Manager) setupTracing() error {
+	cfg := m.config.Tracing
+	if !cfg.Enabled {
+		return nil
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for key, value := range cfg.Headers {
+		headers[key] = os.ExpandEnv(value)
+	}
+	tracer, err := tracing.NewTracer(tracing.Options{
+		Endpoint:       cfg.Endpoint,
+		Headers:        headers,
+		ServiceName:    cfg.ServiceName,
+		ServiceVersion: version.Version,
+		SampleRatio:    cfg.SampleRatio,
+		BatchSize:      cfg.BatchSize,
+		BufferSize:     cfg.BufferSize,
+		FlushInterval:  cfg.FlushInterval,
+	}, m.logger.With(logger.ComponentKey, "tracing"))
+	if err != nil {
+		return err
+	}
+	m.tracer = tracer
+	m.logger.Info(fmt.Sprintf("Traces exported to %s (%.4g of ticks sampled)", cfg.Endpoint, cfg.SampleRatio))
+	return nil
+}
+
+// closeTracing exports the spans still buffered
+func (m *Manager) closeTracing() {
+	if m.tracer == nil {
+		return
+	}
+	if err := m.tracer.Close(); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to export the last spans: %v", err))
+	}
+	stats := m.tracer.Stats()
+	m.logger.Info(fmt.Sprintf("Exported %d span(s), %d dropped", stats.Exported, stats.Dropped))
+	m.tracer = nil
+}
+
+// closeStrategy stops the processes of the strategy instances
+func (m *Manager) closeStrategy() {
+	if m.strategy == nil {
+		return
+	}
+	if err := m.strategy.Close(); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to stop strategy: %v", err))
+	}
+}
+
+// closeStore closes the trade history database
+func (m *Manager) closeStore() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Close(); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to close trade history: %v", err))
+	}
+	m.store = nil
+}
+
+// runMode returns the mode the trades of this run are tagged with
+func (m *Manager) runMode() string {
+	if m.backtest {
+		return store.ModeBacktest
+	}
+	if m.execMode == types.ExecutionLive {
+		return store.ModeLive
+	}
+	return store.ModePaper
+}
+
+// startPublisher starts the configured message bus publisher, if any
+func (m *Manager) startPublisher() error {
+	cfg := m.config.Publisher
+	switch cfg.Type {
+	case "":
+		return nil
+	case "nats":
+	default:
+		return fmt.Errorf("unknown publisher type: %s", cfg.Type)
+	}
+
+	options := publisher.Options{
+		URL:        cfg.URL,
+		Topic:      cfg.Topic,
+		Topics:     cfg.Topics,
+		Format:     cfg.Format,
+		BufferSize: cfg.BufferSize,
+	}
+	for _, name := range cfg.Types {
+		options.Types = append(options.Types, events.Type(name))
+	}
+	pub, err := publisher.NewPublisher(m.bus, options, m.logger.With(logger.ComponentKey, "publisher"))
+	if err != nil {
+		return err
+	}
+	m.publisher = pub
+	m.publisher.Start()
+	m.onAbort(func() {
+		m.publisher.Stop()
+		m.publisher = nil
+	})
+	return nil
+}
+
+// subscribe registers a handler on the bus until Shutdown or a failed
+// start removes it
+func (m *Manager) subscribe(eventType events.Type, handler events.Handler) {
+	m.subscriptions = append(m.subscriptions, m.bus.Subscribe(eventType, handler))
+}
+
+// unsubscribe removes the handlers of this start from the bus, so the next
+// start does not run them twice
+func (m *Manager) unsubscribe() {
+	for _, id := range m.subscriptions {
+		m.bus.Unsubscribe(id)
+	}
+	m.subscriptions = nil
+}
+
+// setupSubscriptions wires the components together through the event bus
+func (m *Manager) setupSubscriptions() {
+	// Process every new tick through the analyzer
+	m.subscribe(events.TypeTick, func(event events.Event) {
+		// The whole pipeline runs on the feed goroutine; report its panics
+		defer m.logger.CapturePanic()
+		start := time.Now()
+		defer func() { m.tickLatency.Observe(time.Since(start)) }()
+
+		tickEvent := event.(*events.TickEvent)
+		tick := tickEvent.Tick
+		m.risk.ObservePrice(tickEvent.Symbol, tick.Price, tick.Timestamp)
+
+		// Watched symbols only update correlations and conversion rates
+		if tickEvent.Symbol != m.symbol {
+			if err := m.fx.SetPrice(tickEvent.Symbol, tick.Price, tick.Timestamp); err != nil {
+				m.logger.Debug(fmt.Sprintf("No conversion rate from %s: %v", tickEvent.Symbol, err))
+			}
+			return
+		}
+
+		// The traded symbol's own price is its live conversion rate
+		m.fx.SetRate(m.base, m.quote, tick.Price, tick.Timestamp)
+
+		span := m.tracer.Start("tick", tracing.String("symbol", tickEvent.Symbol), tracing.Float("price", tick.Price))
+		defer span.End()
+		analysis := span.Child("analyze")
+		metrics := m.analyzer.ProcessTick(tick)
+		analysis.End()
+		if metrics == nil {
+			return
+		}
+
+		// Behind the feed, only the newest tick is worth a decision; open
+		// trades still see every price
+		if tickEvent.Backlog > 0 && m.config.Market.Backpressure.SkipBacklog && !m.strategy.IsActiveTrade() {
+			atomic.AddInt64(&m.backlogSkipped, 1)
+			span.SetAttributes(tracing.Int("backlog", int64(tickEvent.Backlog)))
+			return
+		}
+
+		m.bus.Publish(&events.MetricsEvent{
+			Symbol:    tickEvent.Symbol,
+			Price:     tick.Price,
+			Timestamp: tick.Timestamp,
+			Metrics:   metrics,
+			Span:      span,
+		})
+	})
+
+	// Check for trading signals once we have enough data
+	m.subscribe(events.TypeMetrics, func(event events.Event) {
+		metricsEvent := event.(*events.MetricsEvent)
+		m.checkExposure(metricsEvent.Price, metricsEvent.Timestamp)
+
+		// Close the open position on request of the control API
+		if m.closeRequested.Swap(false) {
+			if signal := m.strategy.ForceExit(metricsEvent.Price, metricsEvent.Timestamp, "manual_close"); signal != nil {
+				m.publishSignal(metricsEvent.Symbol, signal, metricsEvent.Span)
+			}
+			return
+		}
+
+		// Send again the exit of a position left partly unfilled
+		if retry := m.exitRetry.Load(); retry != 0 && metricsEvent.Timestamp.UnixNano() >= retry {
+			m.exitRetry.Store(0)
+			if signal := m.strategy.ForceExit(metricsEvent.Price, metricsEvent.Timestamp, "exit_retry"); signal != nil {
+				m.publishSignal(metricsEvent.Symbol, signal, metricsEvent.Span)
+			}
+			return
+		}
+		if !m.analyzer.HasSufficientData() {
+			return
+		}
+
+		// No entries while an exit is still being executed: the rest of a
+		// partly filled exit is managed again
+		if m.ordersPending() && !m.strategy.IsActiveTrade() {
+			return
+		}
+
+		// While paused, halted or out of session only open trades are managed
+		if !m.entriesAllowed() || !m.calendar.IsOpen(metricsEvent.Timestamp) {
+			if !m.strategy.IsActiveTrade() {
+				return
+			}
+		}
+
+		// Flatten ahead of high-impact news
+		if m.news != nil && m.strategy.IsActiveTrade() {
+			if event, ok := m.news.Flatten(metricsEvent.Timestamp); ok {
+				m.logger.Warning(fmt.Sprintf("Flattening ahead of %s", event),
+					logger.ComponentKey, "news", logger.SymbolKey, metricsEvent.Symbol)
+				if signal := m.strategy.ForceExit(metricsEvent.Price, metricsEvent.Timestamp, "news_flatten"); signal != nil {
+					m.publishSignal(metricsEvent.Symbol, signal, metricsEvent.Span)
+				}
+				return
+			}
+		}
+
+		ctx := tracing.ContextWithSpan(context.Background(), metricsEvent.Span)
+		signal := m.strategy.GenerateSignal(ctx, metricsEvent.Price, metricsEvent.Timestamp, metricsEvent.Metrics)
+		if signal != nil && signal.IsEntry() {
+			if component, err := m.checkEntry(metricsEvent.Timestamp); err != nil {
+				metricsEvent.Span.SetAttributes(tracing.String("entry_blocked", component))
+				m.strategy.CancelEntry(signal.TradeID)
+				m.logger.Info(fmt.Sprintf("Entry signal dropped: %v", err),
+					logger.ComponentKey, component, logger.SymbolKey, metricsEvent.Symbol)
+				return
+			}
+		}
+		if signal != nil {
+			m.publishSignal(metricsEvent.Symbol, signal, metricsEvent.Span)
+		}
+	})
+
+	// Process trading signals
+	m.subscribe(events.TypeSignal, func(event events.Event) {
+		signalEvent := event.(*events.SignalEvent)
+		signal := signalEvent.Signal
+
+		// Signals are rare and what traces are for: always export theirs
+		span := m.tracer.StartUnder(signalEvent.Span, "signal", tracing.String("action", signal.Action),
+			tracing.String("trade_id", signal.TradeID), tracing.String("correlation_id", signal.CorrelationID))
+		span.Keep()
+		m.execute(func() {
+			defer span.End()
+			m.processSignal(tracing.ContextWithSpan(context.Background(), span), signal, signal.Price, signal.Time)
+		})
+	})
+
+	// Log orders and fills with the correlation ID of their signal
+	m.subscribe(events.TypeOrder, func(event events.Event) {
+		order := event.(*events.OrderEvent)
+		m.logger.Info(fmt.Sprintf("[%s] Order %s %s %s at %s", m.execMode, order.Side, order.Quantity, order.Symbol, order.Price),
+			logger.ComponentKey, "execution", logger.SymbolKey, order.Symbol, logger.OrderIDKey, order.OrderID,
+			logger.TradeIDKey, order.TradeID, logger.CorrelationIDKey, order.CorrelationID)
+	})
+	m.subscribe(events.TypeFill, func(event events.Event) {
+		fill := event.(*events.FillEvent)
+		m.logger.Info(fmt.Sprintf("[%s] Fill %s %s %s at %s", m.execMode, fill.Side, fill.Quantity, fill.Symbol, fill.Price),
+			logger.ComponentKey, "execution", logger.SymbolKey, fill.Symbol, logger.OrderIDKey, fill.OrderID,
+			"fill_id", fill.FillID, logger.TradeIDKey, fill.TradeID, logger.CorrelationIDKey, fill.CorrelationID)
+		m.recordFill(fill)
+	})
+
+	// Count closed trades and fees towards the daily summary
+	if m.day != nil {
+		m.subscribe(events.TypeTradeClosed, func(event events.Event) {
+			m.day.Record(event.(*events.TradeClosedEvent))
+		})
+		m.subscribe(events.TypeFill, func(event events.Event) {
+			m.recordDailyFee(event.(*events.FillEvent))
+		})
+	}
+
+	// Book funding settlements of perpetuals against the open position
+	if m.funding != nil {
+		m.subscribe(events.TypeFunding, func(event events.Event) {
+			settlement := event.(*events.FundingEvent)
+			m.execute(func() { m.settleFunding(settlement) })
+		})
+	}
+
+	// Record orders, closed trades and the equity they leave in the history
+	if m.store != nil {
+		m.subscribe(events.TypeOrder, func(event events.Event) {
+			order := store.OrderFromEvent(event.(*events.OrderEvent), m.runID, m.runMode())
+			if err := m.store.SaveOrder(order); err != nil {
+				m.logger.Error(fmt.Sprintf("Failed to save order: %v", err),
+					logger.ComponentKey, "storage", logger.OrderIDKey, order.ID)
+			}
+		})
+		m.subscribe(events.TypeTradeClosed, func(event events.Event) {
+			trade := store.TradeFromEvent(event.(*events.TradeClosedEvent), m.runID, m.runMode())
+			if err := m.store.SaveTrade(trade); err != nil {
+				m.logger.Error(fmt.Sprintf("Failed to save trade: %v", err),
+					logger.ComponentKey, "storage", logger.TradeIDKey, trade.ID)
+			}
+			// The tracker subscribed first, so its metrics include this trade
+			realized := m.tracker.Metrics().TotalPnL
+			if err := m.store.SaveEquity(&store.EquitySnapshot{
+				RunID:       m.runID,
+				Mode:        trade.Mode,
+				Time:        trade.ExitTime,
+				Equity:      m.capital + realized,
+				RealizedPnL: realized,
+			}); err != nil {
+				m.logger.Error(fmt.Sprintf("Failed to save equity snapshot: %v", err),
+					logger.ComponentKey, "storage")
+			}
+		})
+	}
+
+	// Log component errors
+	m.subscribe(events.TypeError, func(event events.Event) {
+		errorEvent := event.(*events.ErrorEvent)
+		fields := []interface{}{logger.ComponentKey, errorEvent.Component}
+		if kind := errs.Kind(errorEvent.Err); kind != nil {
+			fields = append(fields, "error_kind", kind.Error())
+		}
+		m.logger.Debug(fmt.Sprintf("Error event from %s: %v", errorEvent.Component, errorEvent.Err), fields...)
+	})
+}
+
+// processSignal handles trading signals from the strategy
+func (m *Manager) processSignal(ctx context.Context, signal *types.Signal, price float64, timestamp time.Time) {
+	// Tag every line of this signal's pipeline with its trade
+	span := tracing.SpanFromContext(ctx)
+	log := m.logger.With(
+		logger.SymbolKey, m.symbol,
+		logger.SignalIDKey, signal.ID,
+		logger.TradeIDKey, signal.TradeID,
+		logger.CorrelationIDKey, signal.CorrelationID,
+	)
+	if span != nil {
+		log = log.With(logger.TraceIDKey, span.TraceID())
+	}
+
+	switch signal.Action {
+	case "BUY", "SHORT":
+		log.Info(fmt.Sprintf("[%s] %s SIGNAL at price %.6f", m.execMode, signal.Action, price),
+			"action", signal.Action, "price", price, "execution_mode", string(m.execMode))
+
+		// Reserve capital from the allocation of the strategy instance.
+		// Capital is kept in the reporting currency; the order is sized in
+		// the quote currency. Entries whose stop is too far away risk less,
+		// or nothing.
+		allocation := m.allocationOf(signal.Strategy)
+		available := m.portfolio.AvailableCapital(allocation)
+		m.auditIntent(signal, available)
+		notional, err := m.signalNotional(signal, available)
+		component := "portfolio"
+		if err == nil {
+			component = "risk"
+			notional, err = m.sizeForStop(signal, notional)
+		}
+		var quoteNotional decimal.Decimal
+		if err == nil {
+			component = "portfolio"
+			quoteNotional, err = m.fx.FromReporting(decimal.FromFloat(notional), m.quote, signal.Time)
+		}
+		if err == nil {
+			if err = m.checkRisk(signal, notional); err != nil {
+				component = "risk"
+			}
+		}
+
+		// Size the order in whole lots at an exchange-acceptable price, and
+		// split it across the strategy's accounts
+		fillPrice := m.instrument.RoundPrice(decimal.FromFloat(signal.Price))
+		quantity := m.instrument.FloorQuantity(quoteNotional.Div(fillPrice))
+		if err == nil && quantity.Sign() <= 0 {
+			component = "portfolio"
+			err = fmt.Errorf("%s %s at %s is less than one lot of %s", quoteNotional, m.quote, fillPrice, m.instrument.LotSize)
+		}
+		var allocations []account.Allocation
+		if err == nil && m.accounts != nil {
+			if allocations, err = m.allocate(quantity); err != nil {
+				component = "accounts"
+			}
+		}
+		if err == nil {
+			err = m.portfolio.Reserve(allocation, notional)
+		}
+		if err != nil {
+			err = errs.Wrap(errs.ErrOrderRejected, "entry", err)
+			span.RecordError(err)
+			log.Warning(fmt.Sprintf("Entry rejected by %s: %v", component, err))
+			m.bus.Publish(&events.ErrorEvent{Component: component, Err: err, Timestamp: signal.Time})
+			m.logger.Audit(logger.AuditCancel, "", m.symbol, map[string]interface{}{
+				"reason":         err.Error(),
+				"signal_id":      signal.ID,
+				"trade_id":       signal.TradeID,
+				"correlation_id": signal.CorrelationID,
+			})
+			// The strategy opened the trade with the signal; drop it so
+			// it looks for a new entry instead of managing a phantom
+			m.strategy.CancelEntry(signal.TradeID)
+			return
+		}
+		m.allocation = allocation
+		m.reserved = notional
+		m.risk.Open(m.symbol, notional)
+
+		m.quantity = quantity
+		if allocations != nil {
+			m.quantity = decimal.Zero
+			for _, allocation := range allocations {
+				m.quantity = m.quantity.Add(allocation.Quantity)
+			}
+		}
+		m.holdings = allocations
+		m.entryFill = fillPrice
+		m.entryTime = signal.Time
+		m.short = signal.Short()
+
+		// The position is what was filled, at the prices it was filled at
+		fills, filled, average := m.executeOrders(ctx, signal, fillPrice)
+		if filled.Cmp(m.quantity) < 0 {
+			m.resizeEntry(signal, filled)
+			if filled.Sign() <= 0 {
+				return
+			}
+		}
+		if m.holdings != nil {
+			m.holdings = fills
+		}
+		m.quantity = filled
+		m.entryFill = average
+		m.position.Store(positionContext{TradeID: signal.TradeID, EntryPrice: signal.Price, Notional: m.reserved,
+			EntryFill: m.entryFill, Quantity: m.quantity, Short: m.short})
+		m.tagEntry(signal)
+
+	case "SELL", "CLOSE":
+		log.Info(fmt.Sprintf("[%s] %s SIGNAL at price %.6f (reason: %s)", m.execMode, strings.ToUpper(signal.Side), price, signal.Reason),
+			"action", signal.Action, "price", price, "reason", signal.Reason,
+			"profit_percent", signal.ProfitPercent, "execution_mode", string(m.execMode))
+
+		m.auditIntent(signal, m.reserved)
+
+		// Release the allocation and record the result for rebalancing
+		if m.reserved > 0 {
+			fillPrice := m.instrument.RoundPrice(decimal.FromFloat(signal.Price))
+			fills, filled, average := m.executeOrders(ctx, signal, fillPrice)
+			if filled.Sign() > 0 {
+				m.exited = m.exited.Add(filled)
+				m.exitValue = m.exitValue.Add(average.Mul(filled))
+			}
+			if filled.Cmp(m.quantity) < 0 {
+				m.keepRemainder(signal, fills, filled)
+				return
+			}
+			m.exitRetry.Store(0)
+			average = fillPrice
+			if m.exited.Sign() > 0 {
+				average = m.exitValue.Div(m.exited)
+			}
+			pnl := positionPnL(m.entryFill, average, m.exited, m.short).Add(m.openFunding)
+			m.portfolio.Release(m.allocation, m.reserved, signal.ProfitPercent)
+			m.risk.Close(m.symbol, m.reserved)
+			closed := &events.TradeClosedEvent{
+				TradeID:       signal.TradeID,
+				CorrelationID: signal.CorrelationID,
+				Symbol:        m.symbol,
+				EntryPrice:    m.entryFill,
+				ExitPrice:     average,
+				Quantity:      m.exited,
+				PnL:           pnl,
+				PnLPercent:    signal.ProfitPercent,
+				Reason:        signal.Reason,
+				Direction:     m.direction(),
+				Strategy:      signal.Strategy,
+				EntryTime:     m.entryTime,
+				ExitTime:      signal.Time,
+				Currency:      m.quote,
+				MFE:           signal.MFE,
+				MAE:           signal.MAE,
+				Funding:       m.openFunding,
+				Tags:          signal.Tags,
+			}
+			m.normalizePnL(closed)
+			m.breakdown.Record(closed)
+			m.bus.Publish(closed)
+			m.reserved = 0
+			m.quantity = decimal.Zero
+			m.holdings = nil
+			m.entryFill = decimal.Zero
+			m.entryTime = time.Time{}
+			m.short = false
+			m.openFunding = decimal.Zero
+			m.allocation = ""
+			m.exited = decimal.Zero
+			m.exitValue = decimal.Zero
+			m.position.Store(positionContext{})
+		}
+	case "MOVE_STOP":
+		// The stop is kept by the strategy; live execution amends the
+		// exchange's stop order from the audit trail and the signal stream
+		log.Info(fmt.Sprintf("[%s] STOP MOVED to %.6f (reason: %s)", m.execMode, signal.UpdatedStopLoss, signal.Reason),
+			"action", signal.Action, "stop_loss", signal.UpdatedStopLoss, "reason", signal.Reason,
+			"execution_mode", string(m.execMode))
+		m.logger.Audit(logger.AuditAmend, "", m.symbol, map[string]interface{}{
+			"side":           signal.Side,
+			"stop_loss":      signal.UpdatedStopLoss,
+			"quantity":       m.quantity,
+			"reason":         signal.Reason,
+			"signal_id":      signal.ID,
+			"trade_id":       signal.TradeID,
+			"correlation_id": signal.CorrelationID,
+		})
+
+	default:
+		log.Warning(fmt.Sprintf("Unknown signal action: %s", signal.Action))
+	}
 }
 
-// NewManager creates a new trading system manager
-func NewManager(log *logger.Logger) *Manager {
-	return &Manager{
-		logger:  log,
-		running: false,
+// signalNotional returns the notional of the quantity a sized entry
+// signal carries, at most the available capital; unsized entries use all
+// of it
+func (m *Manager) signalNotional(signal *types.Signal, available float64) (float64, error) {
+	if signal.Quantity <= 0 {
+		return available, nil
+	}
+	requested, err := m.fx.ToReporting(decimal.FromFloat(signal.Quantity*signal.Price), m.quote, signal.Time)
+	if err != nil {
+		return 0, err
+	}
+	if requested.Float64() > available {
+		m.logger.Info(fmt.Sprintf("Entry of %g %s capped to the available capital %.2f", signal.Quantity, m.symbol, available),
+			logger.ComponentKey, "portfolio", logger.SymbolKey, m.symbol, logger.TradeIDKey, signal.TradeID)
+		return available, nil
 	}
+	return requested.Float64(), nil
 }
 
-// Initialize sets up all components of the trading system
-func (m *Manager) Initialize() error {
-	m.logger.Info("Initializing trading system components")
+// sizeForStop checks the stop distance of an entry against the risk
+// limits and returns the notional to enter with, publishing the rejection
+// if the entry is refused
+func (m *Manager) sizeForStop(signal *types.Signal, notional float64) (float64, error) {
+	sized, err := m.risk.SizeForStop(m.symbol, notional, signal.Price, signal.InitialRisk, m.portfolio.TotalCapital())
+	if err != nil {
+		m.publishRejection(signal, notional, err)
+		return 0, err
+	}
+	if sized < notional {
+		m.logger.Info(fmt.Sprintf("Entry resized from %.2f to %.2f: stop %.6g away is over the stop limits",
+			notional, sized, signal.InitialRisk), logger.ComponentKey, "risk", logger.SymbolKey, m.symbol,
+			logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+	}
+	return sized, nil
+}
 
-	// Initialize market data component
-	m.market = market.NewMarketData(m.logger)
+// checkRisk checks an entry against the risk limits and publishes the
+// rejection if it breaks one
+func (m *Manager) checkRisk(signal *types.Signal, notional float64) error {
+	err := m.risk.Check(m.symbol, notional, m.portfolio.TotalCapital())
+	m.publishRejection(signal, notional, err)
+	return err
+}
 
-	// Initialize analyzer with market data
-	m.analyzer = analyzer.NewAnalyzer(m.market, m.logger)
+// publishRejection publishes the risk rule a refused entry broke
+func (m *Manager) publishRejection(signal *types.Signal, notional float64, err error) {
+	var rejection *risk.Rejection
+	if errors.As(err, &rejection) {
+		m.bus.Publish(&events.RiskRejectedEvent{
+			TradeID:       signal.TradeID,
+			CorrelationID: signal.CorrelationID,
+			Symbol:        m.symbol,
+			Rule:          string(rejection.Rule),
+			Notional:      notional,
+			Value:         rejection.Value,
+			Limit:         rejection.Limit,
+			Related:       rejection.Related,
+			Message:       rejection.Error(),
+			Timestamp:     signal.Time,
+		})
+	}
+}
 
-	// Initialize strategy with analyzer
-	m.strategy = strategy.NewStrategy(m.analyzer, m.logger)
+// errorContext returns the runtime context attached to tracked errors.
+// It only reads cached or independently locked state, as it may be called
+// while any component is logging.
+func (m *Manager) errorContext() map[string]interface{} {
+	context := map[string]interface{}{
+		"symbol":         m.symbol,
+		"execution_mode": string(m.execMode),
+		"status":         m.Status().String(),
+	}
+	if m.market != nil {
+		context["last_price"] = m.market.GetCurrentPrice()
+	}
+	if position, ok := m.position.Load().(positionContext); ok && position.TradeID != "" {
+		context["open_position"] = map[string]interface{}{
+			"trade_id":    position.TradeID,
+			"entry_price": position.EntryPrice,
+			"notional":    position.Notional,
+		}
+	}
+	return context
+}
 
-	// Set up callbacks
-	m.setupCallbacks()
+// auditIntent records the order intent behind a signal in the audit log
+func (m *Manager) auditIntent(signal *types.Signal, notional float64) {
+	m.logger.Audit(logger.AuditIntent, "", m.symbol, map[string]interface{}{
+		"action":         signal.Action,
+		"side":           signal.Side,
+		"price":          signal.Price,
+		"notional":       notional,
+		"reason":         signal.Reason,
+		"execution_mode": string(m.execMode),
+		"signal_id":      signal.ID,
+		"trade_id":       signal.TradeID,
+		"correlation_id": signal.CorrelationID,
+	})
+}
 
-	return nil
+// executeOrders sends the orders of a signal for the open position, one per
+// account holding part of it, and journals what they filled with its PnL.
+// It returns the fills per account (nil without accounts), the total
+// quantity filled and its average price.
+func (m *Manager) executeOrders(ctx context.Context, signal *types.Signal, price decimal.Decimal) ([]account.Allocation, decimal.Decimal, decimal.Decimal) {
+	holdings := m.holdings
+	if holdings == nil {
+		holdings = []account.Allocation{{Quantity: m.quantity}}
+	}
+
+	var fills []account.Allocation
+	filled, notional := decimal.Zero, decimal.Zero
+	average := price
+	for _, holding := range holdings {
+		orderID, quantity, fillPrice := m.executeSignal(ctx, signal, holding.Account, price, holding.Quantity)
+		if quantity.Sign() <= 0 {
+			continue
+		}
+		pnl := decimal.Zero
+		if !signal.IsEntry() {
+			pnl = positionPnL(m.entryFill, fillPrice, quantity, m.short)
+		}
+		m.journalTrade(signal, orderID, fillPrice, quantity, pnl)
+
+		fills = append(fills, account.Allocation{Account: holding.Account, Quantity: quantity})
+		if fillPrice.Cmp(price) != 0 {
+			average = decimal.Zero
+		}
+		filled = filled.Add(quantity)
+		notional = notional.Add(fillPrice.Mul(quantity))
+	}
+	// Orders filled at other prices than sent, live, are averaged
+	if average.IsZero() {
+		average = notional.Div(filled)
+	}
+	return fills, filled, average
 }
 
-// setupCallbacks configures event handlers between components
-func (m *Manager) setupCallbacks() {
-	// Set up callback for when new market data is received
-	m.market.SetTickCallback(func(tick *types.TickData) {
-		// Process the tick through the analyzer
-		metrics := m.analyzer.ProcessTick(tick)
-		
-		// If we have valid metrics and enough data, check for trading signals
-		if metrics != nil && m.analyzer.HasSufficientData() {
-			// Generate trading signals based on the metrics
-			signal := m.strategy.GenerateSignal(tick.Price, tick.Timestamp, metrics)
-			
-			// Process any trading signals
-			if signal != nil {
-				m.processSignal(signal, tick.Price, tick.Timestamp)
+// resizeEntry shrinks the reservation of an entry to the quantity its
+// orders filled; an entry that filled nothing is cancelled
+func (m *Manager) resizeEntry(signal *types.Signal, filled decimal.Decimal) {
+	kept := m.reserved * filled.Div(m.quantity).Float64()
+	m.portfolio.Unreserve(m.allocation, m.reserved-kept)
+	m.risk.Close(m.symbol, m.reserved)
+	if filled.Sign() > 0 {
+		m.logger.Warning(fmt.Sprintf("Entry of trade %s filled %s of %s %s", signal.TradeID, filled, m.quantity, m.symbol),
+			logger.ComponentKey, "execution", logger.SymbolKey, m.symbol, logger.TradeIDKey, signal.TradeID)
+		m.risk.Open(m.symbol, kept)
+		m.reserved = kept
+		return
+	}
+
+	m.logger.Error(fmt.Sprintf("Entry of trade %s not filled; trade cancelled", signal.TradeID),
+		logger.ComponentKey, "execution", logger.SymbolKey, m.symbol, logger.TradeIDKey, signal.TradeID)
+	m.strategy.CancelEntry(signal.TradeID)
+	m.allocation = ""
+	m.reserved = 0
+	m.quantity = decimal.Zero
+	m.holdings = nil
+	m.entryFill = decimal.Zero
+	m.entryTime = time.Time{}
+	m.short = false
+	m.position.Store(positionContext{})
+}
+
+// keepRemainder keeps what an exit left unfilled as the open position and
+// hands the trade back to the strategy, which sends the exit again after
+// exitRetryInterval. The reservation is released when the last of the
+// position is sold, with the PnL of all its exits.
+func (m *Manager) keepRemainder(signal *types.Signal, fills []account.Allocation, filled decimal.Decimal) {
+	m.logger.Critical(fmt.Sprintf("Exit of trade %s filled %s of %s %s; keeping the rest open and retrying in %s",
+		signal.TradeID, filled, m.quantity, m.symbol, exitRetryInterval),
+		logger.ComponentKey, "execution", logger.SymbolKey, m.symbol, logger.TradeIDKey, signal.TradeID)
+	m.quantity = m.quantity.Sub(filled)
+	if m.holdings != nil {
+		sold := make(map[string]decimal.Decimal, len(fills))
+		for _, fill := range fills {
+			sold[fill.Account] = fill.Quantity
+		}
+		holdings := m.holdings[:0:0]
+		for _, holding := range m.holdings {
+			holding.Quantity = holding.Quantity.Sub(sold[holding.Account])
+			if holding.Quantity.Sign() > 0 {
+				holdings = append(holdings, holding)
 			}
 		}
-	})
+		m.holdings = holdings
+	}
+	position, _ := m.position.Load().(positionContext)
+	position.Quantity = m.quantity
+	m.position.Store(position)
+	m.signals.RetryExit(signal.TradeID)
+	m.strategy.KeepTrade(signal.TradeID)
+	m.exitRetry.Store(signal.Time.Add(exitRetryInterval).UnixNano())
 }
 
-// processSignal handles trading signals from the strategy
-func (m *Manager) processSignal(signal *types.Signal, price float64, timestamp time.Time) {
-	switch signal.Action {
-	case "BUY":
-		m.logger.Info(fmt.Sprintf("BUY SIGNAL at price %.6f", price))
-		// Execute buy logic here
-		
-	case "SELL", "CLOSE":
-		m.logger.Info(fmt.Sprintf("SELL SIGNAL at price %.6f (reason: %s)", price, signal.Reason))
-		// Execute sell logic here
-		
-	default:
-		m.logger.Warning(fmt.Sprintf("Unknown signal action: %s", signal.Action))
+// executeSignal publishes the order for a signal, for an account if not
+// empty, and returns its ID with the quantity filled and its price. Orders
+// go to the executors of live trading and of paper trading on live data;
+// otherwise they are filled immediately at the order price.
+func (m *Manager) executeSignal(ctx context.Context, signal *types.Signal, accountName string, price, quantity decimal.Decimal) (string, decimal.Decimal, decimal.Decimal) {
+	side := signal.Side
+	if side == "" && signal.Action == "CLOSE" {
+		side = "sell"
+		if m.short {
+			side = "buy"
+		}
+	}
+	orderID := ids.Order()
+	span := tracing.SpanFromContext(ctx).Child("order", tracing.String("order_id", orderID), tracing.String("side", side),
+		tracing.String("quantity", quantity.String()), tracing.String("account", accountName))
+	defer span.End()
+
+	m.logger.Audit(logger.AuditSubmit, orderID, m.symbol, map[string]interface{}{
+		"side":           side,
+		"price":          price,
+		"quantity":       quantity,
+		"account":        accountName,
+		"signal_id":      signal.ID,
+		"trade_id":       signal.TradeID,
+		"correlation_id": signal.CorrelationID,
+	})
+
+	m.bus.Publish(&events.OrderEvent{
+		OrderID:       orderID,
+		CorrelationID: signal.CorrelationID,
+		TradeID:       signal.TradeID,
+		Symbol:        m.symbol,
+		Side:          side,
+		Price:         price,
+		Quantity:      quantity,
+		Reason:        signal.Reason,
+		Account:       accountName,
+		Timestamp:     signal.Time,
+	})
+
+	if m.executors != nil {
+		filled, fillPrice := m.submitOrder(tracing.ContextWithSpan(ctx, span), signal, orderID, accountName, side, price, quantity)
+		return orderID, filled, fillPrice
 	}
+
+	m.bus.Publish(&events.FillEvent{
+		FillID:        ids.Fill(),
+		OrderID:       orderID,
+		CorrelationID: signal.CorrelationID,
+		TradeID:       signal.TradeID,
+		Symbol:        m.symbol,
+		Side:          side,
+		Price:         price,
+		Quantity:      quantity,
+		Account:       accountName,
+		Timestamp:     signal.Time,
+	})
+	return orderID, quantity, price
+}
+
+// journalTrade records an executed or simulated trade in the trade journal
+func (m *Manager) journalTrade(signal *types.Signal, orderID string, price, quantity, pnl decimal.Decimal) {
+	m.logger.LogTrade(logger.JournalEntry{
+		Time:          signal.Time,
+		Symbol:        m.symbol,
+		Action:        signal.Action,
+		Side:          signal.Side,
+		Price:         price,
+		Quantity:      quantity,
+		Notional:      price.Mul(quantity),
+		Reason:        signal.Reason,
+		ProfitPercent: signal.ProfitPercent,
+		PnL:           pnl,
+		MFE:           signal.MFE,
+		MAE:           signal.MAE,
+		ExecutionMode: string(m.execMode),
+		TradeID:       signal.TradeID,
+		CorrelationID: signal.CorrelationID,
+		OrderID:       orderID,
+	})
 }
 
-// StartLiveMode starts the system in live trading mode
+// StartLiveMode starts the system in live trading mode.
+// It returns an error if the system is already started.
 func (m *Manager) StartLiveMode() error {
+	return m.startLive(false)
+}
+
+// startLive starts the system on live market data, trading on paper or
+// for real
+func (m *Manager) startLive(paper bool) error {
+	if err := m.beginStart(); err != nil {
+		return err
+	}
+	m.paper, m.backtest, m.simulated = paper, false, false
+
+	// Refuse to trade what another instance trades
+	if err := m.acquireLocks(); err != nil {
+		m.abortStart()
+		return err
+	}
+	m.onAbort(m.releaseLocks)
+
 	if err := m.Initialize(); err != nil {
+		m.abortStart()
+		return err
+	}
+
+	if m.paper {
+		m.logger.Info("Starting paper trading on live market data")
+	} else {
+		m.logger.Info("Starting live trading mode")
+	}
+
+	// Resume management of trades handed off by a previous process;
+	// positions that cannot be managed keep the system from trading
+	if err := m.restoreState(); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to restore handed-off state: %v", err))
+		m.abortStart()
+		return err
+	}
+
+	// Let operators inspect and control the session
+	if err := m.startAPI(); err != nil {
+		m.abortStart()
 		return err
 	}
-	
-	m.running = true
-	m.logger.Info("Starting live trading mode")
-	
+
+	// Orders are polled until done; keep that off the feed goroutine
+	m.startOrders()
+
 	// Connect to live market data
-	if err := m.market.ConnectLive([]string{"btcusdt"}); err != nil {
+	if err := m.live.Connect(); err != nil {
 		m.logger.Error(fmt.Sprintf("Failed to connect to live market: %v", err))
+		m.abortStart()
+		return err
+	}
+
+	// Start periodic status reporting and allocation rebalancing
+	m.stopChan = make(chan struct{})
+	go m.startStatusReporting(m.stopChan)
+	m.startHeartbeat(m.stopChan)
+	if m.daily != nil {
+		m.daily.Start()
+	}
+	m.portfolio.StartRebalancing(defaultRebalanceInterval)
+
+	// Keep the rates of the conversion symbols and the news calendar current
+	m.startRateFeed()
+	m.startNewsFeed()
+
+	// Watch for stalled market data and exchange outages
+	m.startWatchdog(m.symbol)
+	m.startOutageMonitor(m.stopChan)
+
+	m.setStatus(StatusRunning)
+	if reason := m.outage.Reason(); reason != "" {
+		// The outage began while starting
+		m.enterSafe(reason)
+	}
+	return nil
+}
+
+// StartPaperMode starts paper trading on live market data: orders are
+// filled by a simulated executor at the live prices, with slippage, fees
+// and latency. It returns an error if the system is already started or
+// execution is live.
+func (m *Manager) StartPaperMode() error {
+	if m.execMode == types.ExecutionLive {
+		return fmt.Errorf("paper mode cannot send live orders; drop --live-trading")
+	}
+	return m.startLive(true)
+}
+
+// StartSimMode starts the system on a synthetic market feed.
+// It returns an error if the system is already started.
+func (m *Manager) StartSimMode() error {
+	if err := m.beginStart(); err != nil {
+		return err
+	}
+	m.paper, m.backtest, m.simulated = false, false, true
+
+	if err := m.Initialize(); err != nil {
+		m.abortStart()
+		return err
+	}
+
+	m.logger.Info("Starting simulation mode")
+	m.warnNoDepth("Simulation")
+
+	// Let operators inspect and control the session
+	if err := m.startAPI(); err != nil {
+		m.abortStart()
+		return err
+	}
+
+	simulator, err := market.NewSimulator(m.market, simulatorParams(m.config.Simulator), m.logger.With(logger.ComponentKey, "simulator"))
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to create simulator: %v", err))
+		m.abortStart()
 		return err
 	}
-	
-	// Start periodic status reporting
-	go m.startStatusReporting()
-	
+	m.simulator = simulator
+
+	// Start periodic status reporting and the synthetic feed
+	m.stopChan = make(chan struct{})
+	go m.startStatusReporting(m.stopChan)
+	m.startHeartbeat(m.stopChan)
+	if m.daily != nil {
+		m.daily.Start()
+	}
+	go m.simulator.Run()
+
+	m.setStatus(StatusRunning)
 	return nil
 }
 
-// startStatusReporting periodically reports system status
-func (m *Manager) startStatusReporting() {
-	ticker := time.NewTicker(30 * time.Second)
+// simulatorParams converts the simulator configuration
+func simulatorParams(cfg config.SimulatorConfig) market.SimulatorParams {
+	params := market.SimulatorParams{
+		Seed:             cfg.Seed,
+		InitialPrice:     cfg.InitialPrice,
+		TickInterval:     cfg.TickInterval,
+		Realtime:         cfg.Realtime,
+		MaxTicks:         cfg.MaxTicks,
+		JumpIntensity:    cfg.JumpIntensity,
+		JumpMean:         cfg.JumpMean,
+		JumpStd:          cfg.JumpStd,
+		RegimeSwitchProb: cfg.RegimeSwitchProb,
+		MeanVolume:       cfg.MeanVolume,
+	}
+	for _, regime := range cfg.Regimes {
+		params.Regimes = append(params.Regimes, market.SimRegime{
+			Name:       regime.Name,
+			Drift:      regime.Drift,
+			Volatility: regime.Volatility,
+		})
+	}
+	return params
+}
+
+// startWatchdog monitors the live feed and analyzer for stalls
+func (m *Manager) startWatchdog(symbol string) {
+	m.watchdog = m.newWatchdog(watchdog.Source{
+		Symbol:     symbol,
+		LastTick:   m.market.LastTickTime,
+		LastUpdate: m.analyzer.LastUpdate,
+		Reconnect:  m.reconnectFeed,
+	})
+	m.watchdog.Start()
+}
+
+// newWatchdog creates the watchdog of a source, freezing entries while its
+// data is stale
+func (m *Manager) newWatchdog(source watchdog.Source) *watchdog.Watchdog {
+	cfg := m.config.Watchdog
+	monitor := watchdog.NewWatchdog(cfg.StaleAfter, cfg.FreezeEntries, m.bus, m.logger.With(logger.ComponentKey, "watchdog"))
+	monitor.AddSource(source)
+	monitor.SetFreezeHandler(func(symbol string, frozen bool) {
+		m.statusMutex.Lock()
+		m.feedFrozen = frozen
+		m.statusMutex.Unlock()
+
+		if frozen {
+			m.logger.Warning(fmt.Sprintf("Entries frozen for %s until fresh data resumes", symbol))
+		} else {
+			m.logger.Info(fmt.Sprintf("Entries unfrozen for %s", symbol))
+		}
+	})
+	return monitor
+}
+
+// startStatusReporting periodically publishes a status event until stopChan closes
+func (m *Manager) startStatusReporting(stopChan chan struct{}) {
+	interval := m.config.Status.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	
+
 	for {
-		if !m.running {
+		select {
+		case <-stopChan:
 			return
+		case <-ticker.C:
 		}
-		
-		<-ticker.C
-		
+
 		// Get current market state
-		currentPrice := m.market.GetCurrentPrice()
-		metrics := m.analyzer.GetMetrics()
 		tradeActive := m.strategy.IsActiveTrade()
-		
+
 		// Calculate PnL if there's an active trade
 		tradePnL := 0.0
 		if tradeActive {
 			tradeData := m.strategy.GetActiveTradeData()
 			tradePnL = tradeData.CurrentPnL
 		}
-		
-		// Report status
-		m.logger.ReportMarketStatus(currentPrice, metrics, tradeActive, tradePnL)
+
+		now := time.Now()
+		price := m.market.GetCurrentPrice()
+		results := m.GetPerformance()
+		equity := m.markEquity(now, price, results.TotalPnL)
+		view := m.sharePortfolio(now, price, equity)
+		m.portfolioView.Store(view)
+
+		m.bus.Publish(&events.StatusEvent{
+			Symbol:        m.symbol,
+			Status:        m.Status().String(),
+			ExecutionMode: m.execMode,
+			Price:         price,
+			Metrics:       m.analyzer.GetMetrics(),
+			TradeActive:   tradeActive,
+			TradePnL:      tradePnL,
+			Performance:   results,
+			Equity:        &equity,
+			Portfolio:     view,
+			Timestamp:     now,
+		})
+	}
+}
+
+// markEquity marks the open position to market at price, adds the equity
+// to the curve and persists it
+func (m *Manager) markEquity(now time.Time, price, realized float64) types.EquityPoint {
+	unrealized := 0.0
+	if position, ok := m.position.Load().(positionContext); ok && position.Quantity.Sign() > 0 && price > 0 {
+		pnl := position.pnl(decimal.FromFloat(price))
+		if converted, err := m.fx.ToReporting(pnl, m.quote, now); err == nil {
+			unrealized = converted.Float64()
+		} else {
+			m.logger.Debug(fmt.Sprintf("Open position not marked to market: %v", err))
+		}
+	}
+
+	point := m.equity.Mark(now, realized, unrealized)
+	if m.store != nil {
+		if err := m.store.SaveEquity(&store.EquitySnapshot{
+			RunID:       m.runID,
+			Mode:        m.runMode(),
+			Time:        point.Time,
+			Equity:      point.Equity,
+			RealizedPnL: point.RealizedPnL,
+		}); err != nil {
+			m.logger.Error(fmt.Sprintf("Failed to save equity snapshot: %v", err),
+				logger.ComponentKey, "storage")
+		}
 	}
+	return point
 }
 
-// StartBacktestMode starts the system in backtest mode
+// StartBacktestMode starts the system in backtest mode.
+// It returns an error if the system is already started.
 func (m *Manager) StartBacktestMode() error {
+	if err := m.beginStart(); err != nil {
+		return err
+	}
+	m.paper, m.backtest, m.simulated = false, true, false
+
 	if err := m.Initialize(); err != nil {
+		m.abortStart()
 		return err
 	}
-	
-	m.running = true
+
+	m.setStatus(StatusRunning)
 	m.logger.Info("Starting backtest mode")
-	
-	// Get available datasets
-	datasets, err := m.market.GetAvailableDatasets()
-	if err != nil {
-		m.logger.Error(fmt.Sprintf("Failed to get datasets: %v", err))
-		return err
-	}
-	
-	if len(datasets) == 0 {
-		m.logger.Warning("No datasets available for backtesting")
-		return fmt.Errorf("no datasets available")
-	}
-	
-	// Display available datasets
-	fmt.Println("\nAvailable historical datasets:")
-	for i, dataset := range datasets {
-		fmt.Printf("%d. %s\n", i+1, dataset)
-	}
-	
-	// Select dataset (in a real implementation, this would be interactive)
-	selectedDataset := datasets[0]
-	fmt.Printf("\nSelected dataset: %s\n", selectedDataset)
-	
-	// Load and process the dataset
-	if err := m.market.LoadHistoricalData(selectedDataset); err != nil {
+	m.warnNoDepth("Backtest")
+
+	// Replay the datasets set on the command line, or the first available
+	selectedDataset := m.dataset
+	if selectedDataset == "" {
+		datasets, err := m.market.GetAvailableDatasets()
+		if err != nil {
+			m.logger.Error(fmt.Sprintf("Failed to get datasets: %v", err))
+			m.abortStart()
+			return err
+		}
+
+		if len(datasets) == 0 {
+			m.logger.Warning("No datasets available for backtesting")
+			m.abortStart()
+			return errs.Errorf(errs.ErrInsufficientData, "", "no datasets available")
+		}
+
+		// Display available datasets
+		fmt.Println("\nAvailable historical datasets:")
+		for i, dataset := range datasets {
+			fmt.Printf("%d. %s\n", i+1, dataset)
+		}
+		selectedDataset = datasets[0]
+	}
+	paths, err := expandDatasets(selectedDataset)
+	if err != nil {
+		m.abortStart()
+		return err
+	}
+	if len(paths) == 1 {
+		fmt.Printf("\nSelected dataset: %s\n", paths[0])
+	} else {
+		fmt.Printf("\nSelected datasets (merged in time order):\n")
+		for _, path := range paths {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	selectedDataset = strings.Join(paths, ",")
+
+	// Feed every tick through the analyzer, strategy and simulated
+	// execution, exactly as live ticks are processed
+	startedAt := time.Now()
+	if err := m.replayDatasets(paths); err != nil {
 		m.logger.Error(fmt.Sprintf("Failed to load dataset: %v", err))
+		m.abortStart()
 		return err
 	}
-	
+
+	// A trade still open at the end of the data is closed at the last
+	// price, so the results include it
+	if signal := m.strategy.ForceExit(m.market.GetCurrentPrice(), m.market.LastTimestamp(), "end_of_data"); signal != nil {
+		m.publishSignal(m.symbol, signal, nil)
+	}
+
 	// Report final results
 	m.reportBacktestResults()
-	
+	m.saveBacktestRun(selectedDataset, startedAt)
+
 	return nil
 }
 
+// saveBacktestRun records the backtest results in the trade history
+func (m *Manager) saveBacktestRun(dataset string, startedAt time.Time) {
+	if m.store == nil {
+		return
+	}
+	metrics := m.tracker.Metrics()
+	err := m.store.SaveBacktestRun(&store.BacktestRun{
+		ID:           m.runID,
+		Dataset:      dataset,
+		Version:      version.Get().String(),
+		StartedAt:    startedAt,
+		FinishedAt:   time.Now(),
+		TotalTrades:  metrics.TotalTrades,
+		WinRate:      metrics.WinRate,
+		TotalPnL:     metrics.TotalPnL,
+		MaxDrawdown:  metrics.MaxDrawdown,
+		ProfitFactor: metrics.ProfitFactor,
+	})
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to save backtest run: %v", err), logger.ComponentKey, "storage")
+	}
+}
+
 // reportBacktestResults reports the results of the backtest
 func (m *Manager) reportBacktestResults() {
-	// In a real implementation, this would calculate and report performance metrics
+	metrics := m.tracker.Metrics()
+
 	fmt.Println("\nBacktest Results:")
 	fmt.Println("=================")
-	fmt.Println("Backtest completed successfully")
-	
-	// If we had a performance tracker, we would report metrics like:
-	// - Total trades
-	// - Win rate
-	// - Average profit/loss
-	// - Maximum drawdown
-	// - Sharpe ratio
-	// etc.
-}
-
-// Shutdown gracefully stops all components
+	fmt.Printf("Build: %s\n", version.Get())
+	fmt.Printf("Total trades:  %d (%d won, %d lost)\n", metrics.TotalTrades, metrics.WinningTrades, metrics.LosingTrades)
+	fmt.Printf("Win rate:      %.2f%%\n", metrics.WinRate)
+	fmt.Printf("Total PnL:     %.2f %s\n", metrics.TotalPnL, m.fx.Reporting())
+	fmt.Printf("Average PnL:   %.2f %s\n", metrics.AveragePnL, m.fx.Reporting())
+	fmt.Printf("Max drawdown:  %.2f %s\n", metrics.MaxDrawdown, m.fx.Reporting())
+	fmt.Printf("Profit factor: %s\n", status.FormatProfitFactor(metrics))
+	fmt.Printf("Sharpe ratio:  %.2f (per trade), %.2f annualized (%s)\n", metrics.SharpeRatio, metrics.AnnualizedSharpe, m.assetClass.Name)
+	fmt.Printf("Expectancy:    %+.2f%% per trade\n", metrics.Expectancy)
+	fmt.Printf("Exposure time: %s\n", metrics.ExposureTime.Round(time.Second))
+	fmt.Printf("Average MFE:   %.2f%%\n", metrics.AverageMFE)
+	fmt.Printf("Average MAE:   %.2f%%\n", metrics.AverageMAE)
+	if len(m.config.Strategy.Instances) > 0 {
+		m.reportStrategyResults()
+	}
+
+	m.logger.Info("Backtest completed",
+		"total_trades", metrics.TotalTrades, "win_rate", metrics.WinRate,
+		"total_pnl", metrics.TotalPnL, "max_drawdown", metrics.MaxDrawdown,
+		"profit_factor", metrics.ProfitFactor, "sharpe_ratio", metrics.SharpeRatio, "annualized_sharpe", metrics.AnnualizedSharpe, "expectancy", metrics.Expectancy,
+		"average_mfe", metrics.AverageMFE, "average_mae", metrics.AverageMAE)
+}
+
+// reportStrategyResults reports the backtest results of each strategy
+// instance
+func (m *Manager) reportStrategyResults() {
+	strategies := m.breakdown.Metrics()
+
+	fmt.Println("\nBy strategy:")
+	fmt.Printf("  %-20s %7s %9s %12s %10s %14s\n", "Strategy", "Trades", "Win rate", "Total PnL", "Drawdown", "Profit factor")
+	for _, name := range m.breakdown.Names() {
+		metrics := strategies[name]
+		fmt.Printf("  %-20s %7d %8.2f%% %12.2f %10.2f %14s\n", name, metrics.TotalTrades, metrics.WinRate,
+			metrics.TotalPnL, metrics.MaxDrawdown, status.FormatProfitFactor(metrics))
+		m.logger.Info("Strategy backtest results", "instance", name,
+			"total_trades", metrics.TotalTrades, "win_rate", metrics.WinRate, "total_pnl", metrics.TotalPnL,
+			"max_drawdown", metrics.MaxDrawdown, "profit_factor", metrics.ProfitFactor)
+	}
+}
+
+// SaveState persists open positions and stops so the next process can
+// resume exit management after a restart
+func (m *Manager) SaveState() error {
+	if m.strategy == nil {
+		return nil
+	}
+
+	handoff := &state.HandoffState{SavedAt: time.Now()}
+	trade := m.strategy.GetActiveTradeData()
+	if trade.Active {
+		handoff.Positions = append(handoff.Positions, state.Position{
+			Symbol:           m.symbol,
+			Allocation:       m.allocation,
+			ReservedNotional: m.reserved,
+			Quantity:         m.quantity,
+			EntryFill:        m.entryFill,
+			Holdings:         m.holdings,
+			Funding:          m.openFunding,
+			Trade:            *trade,
+		})
+	}
+	if m.accounts != nil {
+		handoff.Balances = m.accounts.Balances()
+	}
+
+	if err := state.Save(m.statePath, handoff); err != nil {
+		return err
+	}
+
+	m.logger.Info(fmt.Sprintf("Saved %d open position(s) to %s", len(handoff.Positions), m.statePath))
+	return nil
+}
+
+// restoreState loads handed-off positions into the strategy and portfolio.
+// The state file is removed once consumed so positions are never restored
+// twice. A position this instance could not exit is an error, and leaves
+// the file for the instance trading it or the operator.
+func (m *Manager) restoreState() error {
+	handoff, err := state.Load(m.statePath)
+	if err != nil || handoff == nil {
+		return err
+	}
+	if len(handoff.Positions) > 1 {
+		return fmt.Errorf("%s holds %d positions; an instance manages one", m.statePath, len(handoff.Positions))
+	}
+	for _, position := range handoff.Positions {
+		if !strings.EqualFold(position.Symbol, m.symbol) {
+			return fmt.Errorf("%s holds a position in %s, not %s", m.statePath, position.Symbol, m.symbol)
+		}
+	}
+
+	for _, position := range handoff.Positions {
+		if position.Adopted {
+			m.adoptPosition(&position)
+		}
+		trade := position.Trade
+
+		// The allocation follows the strategy instance, whose name outlives
+		// the allocation names of older releases. Exits release it, so a
+		// position without one could not be closed.
+		allocation := m.allocationOf(trade.Strategy)
+		if position.ReservedNotional <= 0 {
+			return fmt.Errorf("position of trade %s has no reserved notional", trade.ID)
+		}
+		if err := m.portfolio.Reserve(allocation, position.ReservedNotional); err != nil {
+			return fmt.Errorf("failed to restore the allocation of trade %s: %v", trade.ID, err)
+		}
+		m.strategy.RestoreTrade(&trade)
+		m.allocation = allocation
+		m.reserved = position.ReservedNotional
+		m.risk.Open(position.Symbol, position.ReservedNotional)
+		m.quantity = position.Quantity
+		m.holdings = position.Holdings
+		m.entryFill = position.EntryFill
+		m.entryTime = trade.EntryTime
+		m.short = trade.Short()
+		m.openFunding = position.Funding
+		m.position.Store(positionContext{TradeID: trade.ID, EntryPrice: trade.EntryPrice, Notional: m.reserved,
+			EntryFill: m.entryFill, Quantity: m.quantity, Funding: m.openFunding, Short: m.short})
+
+		m.logger.Info(fmt.Sprintf("Resumed exit management for %s entered at %.6f", position.Symbol, trade.EntryPrice),
+			logger.TradeIDKey, trade.ID)
+	}
+	if m.accounts != nil {
+		m.accounts.Restore(handoff.Balances)
+		for _, position := range handoff.Positions {
+			if !position.Adopted {
+				continue
+			}
+			for _, holding := range position.Holdings {
+				if err := m.accounts.Fill(holding.Account, "buy", position.EntryFill, holding.Quantity, decimal.Zero); err != nil {
+					m.logger.Warning(fmt.Sprintf("Adopted position not booked: %v", err))
+				}
+			}
+		}
+	}
+
+	return state.Remove(m.statePath)
+}
+
+// adoptPosition prepares a position opened outside TRADE for restoring:
+// its allocation reserves the entry notional in the reporting currency
+func (m *Manager) adoptPosition(position *state.Position) {
+	position.Allocation = m.allocationOf(position.Trade.Strategy)
+	notional := position.EntryFill.Mul(position.Quantity)
+	converted, err := m.fx.ToReporting(notional, m.quote, time.Now())
+	if err != nil {
+		m.logger.Warning(fmt.Sprintf("Adopted position notional left in %s: %v", m.quote, err))
+		converted = notional
+	}
+	position.ReservedNotional = converted.Float64()
+	m.logger.Info(fmt.Sprintf("Adopting a position of %s %s entered at %s", position.Quantity, position.Symbol, position.EntryFill),
+		logger.TradeIDKey, position.Trade.ID)
+}
+
+// Shutdown gracefully stops all components.
+// It is a no-op if the system is already stopped or being stopped.
 func (m *Manager) Shutdown() {
-	if !m.running {
+	if !m.beginStop() {
 		return
 	}
-	
+
 	m.logger.Info("Shutting down trading system")
-	m.running = false
-	
+
+	// Stop background reporting
+	if m.stopChan != nil {
+		close(m.stopChan)
+		m.stopChan = nil
+	}
+
 	// Disconnect market data
-	if m.market != nil {
-		m.market.Disconnect()
+	if m.live != nil {
+		m.live.Disconnect()
+	}
+
+	// Finish executing the signals of the last ticks
+	m.stopOrders()
+
+	// Stop the synthetic feed
+	if m.simulator != nil {
+		m.simulator.Stop()
+		m.simulator = nil
+	}
+
+	// Stop allocation rebalancing
+	if m.portfolio != nil {
+		m.portfolio.Stop()
+	}
+
+	// Stop polling conversion rates and the news calendar
+	if m.rateFeed != nil {
+		m.rateFeed.Stop()
+		m.rateFeed = nil
+	}
+	if m.newsFeed != nil {
+		m.newsFeed.Stop()
+		m.newsFeed = nil
+	}
+
+	// Stop feed monitoring
+	if m.watchdog != nil {
+		m.watchdog.Stop()
+		m.watchdog = nil
+	}
+
+	// Stop the status display and performance tracking
+	if m.daily != nil {
+		m.daily.Stop()
+	}
+	if m.reporter != nil {
+		m.reporter.Stop()
+	}
+	if m.tracker != nil {
+		m.tracker.Stop()
+	}
+
+	// End gRPC streams, message bus publishing and notifications
+	if m.stream != nil {
+		m.stream.Stop()
+		m.stream = nil
+	}
+	if m.publisher != nil {
+		m.publisher.Stop()
+		m.publisher = nil
+	}
+	if m.notifier != nil {
+		m.notifier.Stop()
+		m.notifier = nil
+	}
+
+	// Stop serving diagnostics and the control API
+	if m.admin != nil {
+		m.admin.Stop()
+		m.admin = nil
+	}
+	if m.apiServer != nil {
+		m.apiServer.Stop()
+		m.apiServer = nil
 	}
-	
+
+	// Stop the strategies' processes and detach their pipeline from the bus
+	m.closeStrategy()
+	m.unsubscribe()
+
+	// Close the trade history, once out of the portfolio of the other
+	// instances, and write the pending trade contexts
+	m.leavePortfolio()
+	m.closeStore()
+	m.closeTradeContext()
+	m.closeTracing()
+
+	// A clean exit; the next start sends no crash alert
+	m.stopHeartbeat()
+	m.releaseLocks()
+
 	// Perform any other cleanup
+	m.setStatus(StatusStopped)
 	m.logger.Info("Trading system shutdown complete")
-}
\ No newline at end of file
+
+	// Flush and close log files, journal and audit log
+	m.logger.Close()
+}
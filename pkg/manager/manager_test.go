@@ -0,0 +1,516 @@
+package manager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"TRADE/pkg/config"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/execution"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+	"TRADE/pkg/state"
+	"TRADE/pkg/types"
+)
+
+// testPrice is the price the test entries are signalled at
+const testPrice = 100.0
+
+// newTestManager initializes a backtest manager without storage, on the
+// defaults changed by configure, with the market at testPrice
+func newTestManager(t *testing.T, configure func(cfg *config.Config)) *Manager {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Storage.Type = ""
+	if configure != nil {
+		configure(cfg)
+	}
+	m := NewManager(logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)), cfg)
+	m.backtest = true
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() {
+		m.reporter.Stop()
+		m.tracker.Stop()
+	})
+
+	tick := market.NewTick()
+	tick.Price = testPrice
+	tick.Volume = 1
+	tick.Timestamp = time.Now()
+	m.market.AddTick(tick)
+	return m
+}
+
+// enter has the strategy open a trade and the manager process its entry
+// signal, for quantity (0 for the available capital)
+func enter(m *Manager, tradeID string, quantity float64) {
+	now := time.Now()
+	m.strategy.Engines()[0].RestoreTrade(&types.TradeData{
+		ID:         tradeID,
+		Direction:  types.DirectionLong,
+		EntryPrice: testPrice,
+		EntryTime:  now,
+	})
+	signal := types.NewBuySignal(testPrice, now, types.NewMarketMetrics())
+	signal.TradeID = tradeID
+	signal.Quantity = quantity
+	m.processSignal(context.Background(), signal, testPrice, now)
+}
+
+// requireFlat fails unless neither the strategy nor the manager holds a
+// trade
+func requireFlat(t *testing.T, m *Manager) {
+	t.Helper()
+	if trade := m.strategy.GetActiveTradeData(); trade.Active {
+		t.Fatalf("strategy still manages rejected trade %s", trade.ID)
+	}
+	if m.reserved != 0 || m.quantity.Sign() != 0 {
+		t.Fatalf("rejected entry left %.2f reserved for %s", m.reserved, m.quantity)
+	}
+}
+
+// requireOpen fails unless both the strategy and the manager hold trade
+func requireOpen(t *testing.T, m *Manager, tradeID string) {
+	t.Helper()
+	if trade := m.strategy.GetActiveTradeData(); !trade.Active || trade.ID != tradeID {
+		t.Fatalf("strategy manages %+v, want trade %s", trade, tradeID)
+	}
+	if m.reserved <= 0 || m.quantity.Sign() <= 0 {
+		t.Fatalf("entry of %s reserved %.2f for %s", tradeID, m.reserved, m.quantity)
+	}
+}
+
+func TestRiskRejectedEntryIsCancelled(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.Risk.MaxSymbolNotional = 100
+	})
+
+	// The whole capital is over the symbol limit
+	enter(m, "trd_rejected", 0)
+	requireFlat(t, m)
+
+	// An entry within it is taken
+	enter(m, "trd_taken", 0.5)
+	requireOpen(t, m, "trd_taken")
+}
+
+func TestEntryOnFullPortfolioIsCancelled(t *testing.T) {
+	m := newTestManager(t, nil)
+
+	// Another position holds the whole allocation
+	allocation := m.allocationOf("")
+	held := m.portfolio.AvailableCapital(allocation)
+	if err := m.portfolio.Reserve(allocation, held); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	enter(m, "trd_rejected", 0.5)
+	requireFlat(t, m)
+
+	// Its capital is available again once it closes
+	m.portfolio.Release(allocation, held, 0)
+	enter(m, "trd_taken", 0.5)
+	requireOpen(t, m, "trd_taken")
+}
+
+func TestEntryReservesInstanceAllocation(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.Strategy.Instances = []config.StrategyInstanceConfig{
+			{Name: "main", Type: cfg.Strategy.Type, Weight: 0.75},
+			{Name: "probe", Type: cfg.Strategy.Type},
+		}
+	})
+	weights := make(map[string]float64)
+	for _, allocation := range m.portfolio.GetAllocations() {
+		weights[allocation.Name] = allocation.Weight
+	}
+	if len(weights) != 2 || weights[m.allocationOf("main")] != 0.75 || weights[m.allocationOf("probe")] != 0.25 {
+		t.Fatalf("allocations %v, want main at 0.75 and probe at 0.25", weights)
+	}
+
+	// The entry of the second instance takes its capital only
+	probe := m.allocationOf("probe")
+	available := m.portfolio.AvailableCapital(probe)
+	now := time.Now()
+	m.strategy.Engines()[1].RestoreTrade(&types.TradeData{ID: "trd_probe", Direction: types.DirectionLong,
+		EntryPrice: testPrice, EntryTime: now})
+	signal := types.NewBuySignal(testPrice, now, types.NewMarketMetrics())
+	signal.TradeID = "trd_probe"
+	signal.Strategy = "probe"
+	m.processSignal(context.Background(), signal, testPrice, now)
+	if m.allocation != probe || m.reserved <= 0 || m.reserved > available {
+		t.Fatalf("entry reserved %.2f in %s, want at most %.2f in %s", m.reserved, m.allocation, available, probe)
+	}
+	if main := m.allocationOf("main"); m.portfolio.AvailableCapital(main) != m.capital*0.75 {
+		t.Fatalf("entry of probe took capital of main")
+	}
+}
+
+func TestEntryBelowAccountSplitIsCancelled(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.Trading.LotSize = 0.1
+		for _, name := range []string{"main", "spare"} {
+			cfg.Accounts = append(cfg.Accounts, config.AccountConfig{
+				Name:       name,
+				Balance:    500,
+				Strategies: []string{cfg.Trading.Strategy},
+			})
+		}
+	})
+
+	// Half a lot per account rounds down to nothing
+	enter(m, "trd_rejected", 0.1)
+	requireFlat(t, m)
+
+	enter(m, "trd_taken", 0.4)
+	requireOpen(t, m, "trd_taken")
+}
+
+func TestEntryBelowOneLotIsCancelled(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.Trading.LotSize = 1
+	})
+
+	enter(m, "trd_rejected", 0.5)
+	requireFlat(t, m)
+
+	enter(m, "trd_taken", 1)
+	requireOpen(t, m, "trd_taken")
+}
+
+// partialExecutor fills half of every order and cancels the rest
+type partialExecutor struct{}
+
+func (partialExecutor) Submit(order *execution.Order) (*execution.Report, error) {
+	return &execution.Report{
+		OrderID: order.ID,
+		Status:  execution.StatusCanceled,
+		Filled:  order.Quantity.Div(decimal.New(2, 0)),
+		Price:   order.Price,
+	}, nil
+}
+
+func (partialExecutor) Status(symbol, orderID string) (*execution.Report, error) {
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func (partialExecutor) Cancel(symbol, orderID string) (*execution.Report, error) {
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func TestPartialExitKeepsRemainder(t *testing.T) {
+	m := newTestManager(t, nil)
+	var closed []*events.TradeClosedEvent
+	m.EventBus().Subscribe(events.TypeTradeClosed, func(event events.Event) {
+		closed = append(closed, event.(*events.TradeClosedEvent))
+	})
+	exit := func() {
+		signal := m.strategy.ForceExit(testPrice, time.Now(), "test")
+		if signal == nil {
+			t.Fatal("no trade to exit")
+		}
+		m.processSignal(context.Background(), signal, testPrice, signal.Time)
+	}
+
+	enter(m, "trd_partial", 1)
+	requireOpen(t, m, "trd_partial")
+	paper := m.executors[""]
+
+	// Half the position is sold; the strategy manages the other half
+	m.executors[""] = partialExecutor{}
+	exit()
+	requireOpen(t, m, "trd_partial")
+	if want := decimal.New(5, -1); m.quantity.Cmp(want) != 0 {
+		t.Fatalf("position of %s after the partial exit, want %s", m.quantity, want)
+	}
+	if len(closed) != 0 || m.exitRetry.Load() == 0 {
+		t.Fatalf("partial exit closed %d trade(s), retry at %d", len(closed), m.exitRetry.Load())
+	}
+
+	// The retried exit closes the trade with the quantity of both
+	m.executors[""] = paper
+	exit()
+	requireFlat(t, m)
+	if len(closed) != 1 || closed[0].Quantity.Cmp(decimal.New(1, 0)) != 0 {
+		t.Fatalf("closed %+v, want one trade of 1", closed)
+	}
+	if m.exitRetry.Load() != 0 {
+		t.Fatal("exit still retried after the trade closed")
+	}
+}
+
+func TestOrderQueueRunsJobsInOrder(t *testing.T) {
+	queue := newOrderQueue()
+	go queue.run()
+
+	release := make(chan struct{})
+	var order []int
+	queue.push(func() { <-release })
+	for i := 0; i < 3; i++ {
+		i := i
+		queue.push(func() { order = append(order, i) })
+	}
+	if !queue.busy() {
+		t.Fatal("queue with a running job is not busy")
+	}
+	close(release)
+
+	// Closing runs what is queued, then refuses more
+	queue.close()
+	if fmt.Sprint(order) != "[0 1 2]" || queue.busy() {
+		t.Fatalf("ran %v, busy %v", order, queue.busy())
+	}
+	if queue.push(func() {}) {
+		t.Fatal("closed queue took a job")
+	}
+}
+
+// writeCert writes a self-signed certificate and key for localhost to a
+// temporary directory and returns their paths
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestFailedStartStopsComponents(t *testing.T) {
+	// The admin server cannot listen on the address another one holds
+	held, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer held.Close()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	grpcAddress := free.Addr().String()
+	free.Close()
+
+	cfg := config.Default()
+	cfg.Storage.Type = ""
+	cfg.GRPC.Enabled = true
+	cfg.GRPC.Address = grpcAddress
+	cfg.GRPC.CertFile, cfg.GRPC.KeyFile = writeCert(t)
+	cfg.Admin.Enabled = true
+	cfg.Admin.Address = held.Addr().String()
+	m := NewManager(logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)), cfg)
+	err = m.StartBacktestMode()
+	if err == nil {
+		t.Fatal("backtest started with the admin address taken")
+	}
+	if !strings.Contains(err.Error(), held.Addr().String()) {
+		t.Fatalf("start failed on something else than the admin address: %v", err)
+	}
+
+	if status := m.Status(); status != StatusStopped {
+		t.Fatalf("status %s after a failed start", status)
+	}
+	if m.stream != nil || m.admin != nil {
+		t.Fatal("failed start left servers running")
+	}
+	listener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		t.Fatalf("gRPC address still held after a failed start: %v", err)
+	}
+	listener.Close()
+}
+
+func TestOutageStatsWithoutDetection(t *testing.T) {
+	var o *outageMonitor
+	if stats := o.Stats(); stats != (OutageStats{}) {
+		t.Fatalf("stats without detection = %+v, want zero", stats)
+	}
+}
+
+func TestConcurrentShutdown(t *testing.T) {
+	m := newTestManager(t, nil)
+	m.stopChan = make(chan struct{})
+	m.setStatus(StatusRunning)
+
+	// Every call but one returns; a second close of stopChan would panic
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			m.Shutdown()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if status := m.Status(); status != StatusStopped {
+		t.Fatalf("status %s after shutdown", status)
+	}
+}
+
+// writeDataset writes a dataset of n ticks oscillating around testPrice
+func writeDataset(t *testing.T, n int) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("timestamp,price,volume,is_ask\n")
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		price := testPrice * (1 + 0.01*math.Sin(float64(i)/20))
+		fmt.Fprintf(&b, "%d,%.2f,0.001,%t\n", start.Add(time.Duration(i)*time.Second).UnixMilli(), price, i%2 == 0)
+	}
+	path := filepath.Join(t.TempDir(), "btcusdt_test.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestRestartDoesNotSubscribeTwice(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Type = ""
+	m := NewManager(logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)), cfg)
+	m.SetDataset(writeDataset(t, 600))
+	var metrics int
+	m.EventBus().Subscribe(events.TypeMetrics, func(events.Event) { metrics++ })
+
+	// Each run analyzes every tick once
+	var runs []int
+	for i := 0; i < 2; i++ {
+		metrics = 0
+		if err := m.StartBacktestMode(); err != nil {
+			t.Fatalf("StartBacktestMode: %v", err)
+		}
+		m.Shutdown()
+		runs = append(runs, metrics)
+	}
+	if runs[0] == 0 || runs[1] != runs[0] {
+		t.Fatalf("metrics events per run = %v, want the same positive count", runs)
+	}
+}
+
+func TestFailedBacktestUndoesStart(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Type = ""
+	m := NewManager(logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)), cfg)
+	var metrics int
+	m.EventBus().Subscribe(events.TypeMetrics, func(events.Event) { metrics++ })
+
+	// A dataset that cannot be read fails the start and undoes it
+	m.SetDataset(filepath.Join(t.TempDir(), "missing.csv"))
+	if err := m.StartBacktestMode(); err == nil {
+		t.Fatal("backtest of a missing dataset started")
+	}
+	if status := m.Status(); status != StatusStopped {
+		t.Fatalf("status %s after the failed start", status)
+	}
+
+	// The next run analyzes each tick once, with a single pipeline
+	m.SetDataset(writeDataset(t, 600))
+	if err := m.StartBacktestMode(); err != nil {
+		t.Fatalf("StartBacktestMode: %v", err)
+	}
+	m.Shutdown()
+	if metrics == 0 || metrics > 600 {
+		t.Fatalf("%d metrics events for 600 ticks", metrics)
+	}
+}
+
+func TestPaperStartOnStartedSystemKeepsMode(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage.Type = ""
+	m := NewManager(logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)), cfg)
+	m.SetDataset(writeDataset(t, 100))
+	if err := m.StartBacktestMode(); err != nil {
+		t.Fatalf("StartBacktestMode: %v", err)
+	}
+	defer m.Shutdown()
+
+	if err := m.StartPaperMode(); err == nil {
+		t.Fatal("paper mode started on a running backtest")
+	}
+	if m.paper || !m.backtest {
+		t.Fatalf("refused start changed the mode: paper %v, backtest %v", m.paper, m.backtest)
+	}
+}
+
+func TestUnmanageableHandoffIsRefused(t *testing.T) {
+	m := newTestManager(t, nil)
+	m.statePath = filepath.Join(t.TempDir(), "handoff.json")
+	position := func(symbol string, reserved float64) state.Position {
+		return state.Position{
+			Symbol:           symbol,
+			ReservedNotional: reserved,
+			Quantity:         decimal.New(1, 0),
+			EntryFill:        decimal.New(100, 0),
+			Trade:            types.TradeData{ID: "trd_handoff", Direction: types.DirectionLong, EntryPrice: testPrice},
+		}
+	}
+
+	for name, handoff := range map[string]state.Position{
+		"other symbol":    position("ethusdt", 100),
+		"over allocation": position(m.symbol, 10*m.capital),
+		"no reservation":  position(m.symbol, 0),
+	} {
+		if err := state.Save(m.statePath, &state.HandoffState{Positions: []state.Position{handoff}}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := m.restoreState(); err == nil {
+			t.Fatalf("%s: position restored", name)
+		}
+		requireFlat(t, m)
+		if _, err := os.Stat(m.statePath); err != nil {
+			t.Fatalf("%s: state of the refused position removed: %v", name, err)
+		}
+	}
+
+	if err := state.Save(m.statePath, &state.HandoffState{Positions: []state.Position{position(m.symbol, 100)}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := m.restoreState(); err != nil {
+		t.Fatalf("restoreState: %v", err)
+	}
+	requireOpen(t, m, "trd_handoff")
+}
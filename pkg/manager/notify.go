@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"TRADE/pkg/config"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/notify"
+)
+
+// startNotifications starts routing the bus events to the configured
+// notifiers, in live, paper and simulated sessions; backtests notify no one
+func (m *Manager) startNotifications() error {
+	cfg := m.config.Notifications
+	if !cfg.Enabled || m.backtest {
+		return nil
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(cfg.Notifiers))
+	for _, nc := range cfg.Notifiers {
+		notifier, err := newNotifier(nc)
+		if err != nil {
+			return fmt.Errorf("invalid notifications config: notifier %s: %v", nc.Name, err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	routes := make([]notify.Route, 0, len(cfg.Routes))
+	for _, rc := range cfg.Routes {
+		route := notify.Route{Symbols: rc.Symbols, Notifiers: rc.Notifiers}
+		for _, name := range rc.Types {
+			route.Types = append(route.Types, events.Type(strings.ToLower(name)))
+		}
+		if rc.MinLevel != "" {
+			level, err := notify.ParseLevel(rc.MinLevel)
+			if err != nil {
+				return fmt.Errorf("invalid notifications config: %v", err)
+			}
+			route.MinLevel = level
+		}
+		routes = append(routes, route)
+	}
+
+	dispatcher, err := notify.NewDispatcher(m.bus, notifiers, routes, notify.Options{
+		BufferSize: cfg.BufferSize,
+		Timeout:    cfg.Timeout,
+		Repeat:     cfg.Repeat,
+	}, m.logger.With(logger.ComponentKey, "notify"))
+	if err != nil {
+		return fmt.Errorf("invalid notifications config: %v", err)
+	}
+	m.notifier = dispatcher
+	m.notifier.Start()
+	m.onAbort(func() {
+		m.notifier.Stop()
+		m.notifier = nil
+	})
+	m.logger.Info(fmt.Sprintf("Notifications routed to %d notifier(s) along %d route(s)", len(notifiers), len(routes)))
+	return nil
+}
+
+// newNotifier creates a configured notifier, reading its secret from the
+// environment
+func newNotifier(cfg config.NotifierConfig) (notify.Notifier, error) {
+	switch cfg.Type {
+	case "slack", "discord":
+		if cfg.WebhookEnv == "" {
+			return nil, fmt.Errorf("webhook_env is required")
+		}
+		url := os.Getenv(cfg.WebhookEnv)
+		if url == "" {
+			return nil, fmt.Errorf("environment variable %s (webhook_env) is not set", cfg.WebhookEnv)
+		}
+		if cfg.Type == "slack" {
+			return notify.NewSlack(cfg.Name, url, cfg.Username), nil
+		}
+		return notify.NewDiscord(cfg.Name, url, cfg.Username), nil
+	case "email":
+		password := ""
+		if cfg.SMTP.PasswordEnv != "" {
+			if password = os.Getenv(cfg.SMTP.PasswordEnv); password == "" {
+				return nil, fmt.Errorf("environment variable %s (smtp.password_env) is not set", cfg.SMTP.PasswordEnv)
+			}
+		}
+		return notify.NewEmail(cfg.Name, notify.EmailOptions{
+			Host:          cfg.SMTP.Host,
+			Port:          cfg.SMTP.Port,
+			Username:      cfg.SMTP.Username,
+			Password:      password,
+			From:          cfg.SMTP.From,
+			To:            cfg.SMTP.To,
+			SubjectPrefix: cfg.SMTP.SubjectPrefix,
+		})
+	default:
+		return nil, fmt.Errorf("unknown type %q (want slack, discord or email)", cfg.Type)
+	}
+}
@@ -0,0 +1,298 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"TRADE/pkg/config"
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// Outage detection timing: how often a SAFE system checks whether the
+// exchange recovered, and the first delay of its reconnect backoff
+const (
+	outageCheckInterval = 5 * time.Second
+	outageFirstRetry    = 15 * time.Second
+)
+
+// OutageStats describes the outage detection
+type OutageStats struct {
+	Safe            bool       `json:"safe"`
+	Since           *time.Time `json:"since,omitempty"` // Start of the current SAFE state
+	Reason          string     `json:"reason,omitempty"`
+	ConnectFailures int        `json:"connect_failures"` // Within the window
+	ServerErrors    int        `json:"server_errors"`    // Within the window
+	Outages         int        `json:"outages"`          // SAFE states entered
+	NextReconnect   *time.Time `json:"next_reconnect,omitempty"`
+}
+
+// outageMonitor counts the feed connection failures and exchange server
+// errors that signal an outage or maintenance, and paces the reconnects of
+// the feed while it lasts. A nil monitor detects nothing.
+type outageMonitor struct {
+	config          config.OutageConfig
+	connectFailures []time.Time
+	serverErrors    []time.Time
+	lastFailure     time.Time
+	healthySince    time.Time // Since when the feed is connected and fresh while SAFE
+	safe            bool
+	since           time.Time
+	reason          string
+	outages         int
+	retryDelay      time.Duration
+	nextRetry       time.Time
+	mutex           sync.Mutex
+}
+
+// newOutageMonitor creates a monitor of the given limits
+func newOutageMonitor(cfg config.OutageConfig) *outageMonitor {
+	return &outageMonitor{config: cfg}
+}
+
+// Record counts a failure of an error kind at now. It returns why an
+// outage is suspected when the failure reaches a limit, or empty; failures
+// of other kinds and those while SAFE only delay the recovery.
+func (o *outageMonitor) Record(kind error, now time.Time) string {
+	if o == nil {
+		return ""
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var failures *[]time.Time
+	var limit int
+	var what string
+	switch kind {
+	case errs.ErrFeedDisconnected:
+		failures, limit, what = &o.connectFailures, o.config.MaxConnectFailures, "feed connection failures"
+	case errs.ErrExchangeUnavailable:
+		failures, limit, what = &o.serverErrors, o.config.MaxServerErrors, "exchange server errors"
+	default:
+		return ""
+	}
+	o.lastFailure = now
+	*failures = append(o.prune(*failures, now), now)
+	if o.safe || len(*failures) < limit {
+		return ""
+	}
+	o.safe = true
+	o.since = now
+	o.outages++
+	o.reason = fmt.Sprintf("%d %s within %s", len(*failures), what, o.config.Window)
+	o.retryDelay = 0
+	o.nextRetry = time.Time{}
+	return o.reason
+}
+
+// prune drops the failures older than the window
+func (o *outageMonitor) prune(failures []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(failures) && now.Sub(failures[i]) > o.config.Window {
+		i++
+	}
+	return failures[i:]
+}
+
+// Recover leaves the SAFE state when the feed is healthy and no failure
+// happened for the recovery time, returning how long the outage lasted
+func (o *outageMonitor) Recover(now time.Time, healthy bool) (time.Duration, bool) {
+	if o == nil {
+		return 0, false
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if !o.safe {
+		return 0, false
+	}
+	if !healthy {
+		o.healthySince = time.Time{}
+		return 0, false
+	}
+	if o.healthySince.IsZero() {
+		o.healthySince = now
+	}
+	if now.Sub(o.healthySince) < o.config.Recovery || now.Sub(o.lastFailure) < o.config.Recovery {
+		return 0, false
+	}
+	lasted := now.Sub(o.since)
+	o.reset()
+	return lasted, true
+}
+
+// reset leaves the SAFE state and forgets the failures. The caller holds
+// the lock.
+func (o *outageMonitor) reset() {
+	o.safe = false
+	o.reason = ""
+	o.healthySince = time.Time{}
+	o.connectFailures = o.connectFailures[:0]
+	o.serverErrors = o.serverErrors[:0]
+	o.retryDelay = 0
+	o.nextRetry = time.Time{}
+}
+
+// Clear leaves the SAFE state, on an operator's resume
+func (o *outageMonitor) Clear() {
+	if o == nil {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.reset()
+}
+
+// Reason returns why the system is SAFE, or empty
+func (o *outageMonitor) Reason() string {
+	if o == nil {
+		return ""
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.reason
+}
+
+// ReconnectDue reports whether the feed may be reconnected at now. While
+// SAFE the reconnects back off, doubling up to the maximum retry interval.
+func (o *outageMonitor) ReconnectDue(now time.Time) bool {
+	if o == nil {
+		return true
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if !o.safe {
+		return true
+	}
+	if now.Before(o.nextRetry) {
+		return false
+	}
+	o.retryDelay = min(max(2*o.retryDelay, outageFirstRetry), o.config.MaxRetryInterval)
+	o.nextRetry = now.Add(o.retryDelay)
+	return true
+}
+
+// Stats returns the state of the detection; zero without detection
+func (o *outageMonitor) Stats() OutageStats {
+	if o == nil {
+		return OutageStats{}
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	now := time.Now()
+	stats := OutageStats{
+		Safe:            o.safe,
+		Reason:          o.reason,
+		ConnectFailures: len(o.prune(o.connectFailures, now)),
+		ServerErrors:    len(o.prune(o.serverErrors, now)),
+		Outages:         o.outages,
+	}
+	if o.safe {
+		since, next := o.since, o.nextRetry
+		stats.Since, stats.NextReconnect = &since, &next
+	}
+	return stats
+}
+
+// setupOutage counts the feed and exchange failures published on the bus
+// towards an outage. Backtests talk to no exchange and get no detection.
+func (m *Manager) setupOutage() {
+	cfg := m.config.Outage
+	if !cfg.Enabled || m.backtest {
+		return
+	}
+	m.outage = newOutageMonitor(cfg)
+	m.subscribe(events.TypeError, func(event events.Event) {
+		errorEvent := event.(*events.ErrorEvent)
+		if reason := m.outage.Record(errs.Kind(errorEvent.Err), time.Now()); reason != "" {
+			m.enterSafe(reason)
+		}
+	})
+}
+
+// startOutageMonitor checks whether the exchange recovered from an outage
+// until stopChan closes
+func (m *Manager) startOutageMonitor(stopChan chan struct{}) {
+	if m.outage == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(outageCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopChan:
+				return
+			case now := <-ticker.C:
+				m.checkRecovery(now)
+			}
+		}
+	}()
+}
+
+// enterSafe moves a running or paused system into the SAFE state, which
+// freezes entries while exits are still managed, and alerts
+func (m *Manager) enterSafe(reason string) {
+	m.statusMutex.Lock()
+	switch m.status {
+	case StatusRunning, StatusPaused:
+		m.safeReturn = m.status
+		m.status = StatusSafe
+	}
+	status := m.status
+	m.statusMutex.Unlock()
+
+	message := fmt.Sprintf("Exchange outage suspected (%s): SAFE state entered, new entries frozen while open trades are managed", reason)
+	if status != StatusSafe {
+		message = fmt.Sprintf("Exchange outage suspected (%s) while %s", reason, status)
+	}
+	m.logger.Critical(message, logger.ComponentKey, "outage", logger.SymbolKey, m.symbol)
+	m.bus.Publish(&events.AlertEvent{
+		Level:     events.AlertCritical,
+		Source:    "outage",
+		Symbol:    m.symbol,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
+
+// checkRecovery leaves the SAFE state once the feed has stayed connected
+// and fresh for the recovery time without a failure, returning to the
+// status the outage interrupted
+func (m *Manager) checkRecovery(now time.Time) {
+	healthy := m.live.Connected() && now.Sub(m.market.LastTickTime()) < m.config.Watchdog.StaleAfter
+	lasted, ok := m.outage.Recover(now, healthy)
+	if !ok {
+		return
+	}
+
+	m.statusMutex.Lock()
+	if m.status == StatusSafe {
+		m.status = m.safeReturn
+	}
+	status := m.status
+	m.statusMutex.Unlock()
+
+	message := fmt.Sprintf("Exchange recovered after %s: SAFE state left, trading %s", lasted.Round(time.Second), status)
+	m.logger.Info(message, logger.ComponentKey, "outage", logger.SymbolKey, m.symbol)
+	m.bus.Publish(&events.AlertEvent{
+		Level:     events.AlertInfo,
+		Source:    "outage",
+		Symbol:    m.symbol,
+		Message:   message,
+		Timestamp: now,
+	})
+}
+
+// reconnectFeed reconnects the live feed for the watchdog. During an
+// outage the attempts back off instead of repeating on every check.
+func (m *Manager) reconnectFeed() error {
+	if !m.outage.ReconnectDue(time.Now()) {
+		return nil
+	}
+	return m.live.Reconnect()
+}
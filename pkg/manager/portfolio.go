@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/store"
+	"TRADE/pkg/types"
+)
+
+// staleInstances is the number of status intervals after which an
+// instance that stopped sharing its state, having crashed, is left out of
+// the portfolio view
+const staleInstances = 3
+
+// instanceMode returns the mode the instances of a portfolio share: live
+// and paper sessions and simulations are kept apart
+func (m *Manager) instanceMode() string {
+	if m.simulated {
+		return "simulation"
+	}
+	return m.runMode()
+}
+
+// sharePortfolio shares the state of this instance through the trade
+// history and returns the portfolio view of the instances running, or nil
+// while they trade a single symbol
+func (m *Manager) sharePortfolio(now time.Time, price float64, equity types.EquityPoint) *types.PortfolioView {
+	cfg := m.config.Status.Portfolio
+	if !cfg.Enabled || m.store == nil {
+		return nil
+	}
+	snapshot := &store.InstanceSnapshot{
+		RunID:   m.runID,
+		Mode:    m.instanceMode(),
+		Symbol:  m.symbol,
+		Time:    now,
+		Equity:  equity.Equity,
+		OpenPnL: equity.UnrealizedPnL,
+	}
+	if position, ok := m.position.Load().(positionContext); ok && position.Quantity.Sign() > 0 && price > 0 {
+		snapshot.Direction = types.DirectionLong
+		if position.Short {
+			snapshot.Direction = types.DirectionShort
+		}
+		notional := decimal.FromFloat(price).Mul(position.Quantity)
+		if converted, err := m.fx.ToReporting(notional, m.quote, now); err == nil {
+			notional = converted
+		}
+		snapshot.Notional = notional.Float64()
+		snapshot.OpenRisk = m.openRisk(now).Float64()
+	}
+	if err := m.store.SaveInstance(snapshot); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to share instance state: %v", err), logger.ComponentKey, "storage")
+		return nil
+	}
+
+	interval := m.config.Status.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	snapshots, err := m.store.Instances(snapshot.Mode, now.Add(-staleInstances*interval))
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to load the portfolio: %v", err), logger.ComponentKey, "storage")
+		return nil
+	}
+	symbols := make(map[string]bool, len(snapshots))
+	for _, instance := range snapshots {
+		symbols[instance.Symbol] = true
+	}
+	if len(symbols) < 2 {
+		return nil
+	}
+	return aggregatePortfolio(snapshots, cfg.TopRisks, now)
+}
+
+// leavePortfolio removes this instance from the portfolio view of the
+// others
+func (m *Manager) leavePortfolio() {
+	if !m.config.Status.Portfolio.Enabled || m.store == nil {
+		return
+	}
+	if err := m.store.RemoveInstance(m.runID); err != nil {
+		m.logger.Warning(fmt.Sprintf("Failed to leave the portfolio: %v", err), logger.ComponentKey, "storage")
+	}
+}
+
+// aggregatePortfolio sums the instances of a portfolio up, ranking the
+// symbols by their share of the open risk
+func aggregatePortfolio(snapshots []store.InstanceSnapshot, topRisks int, now time.Time) *types.PortfolioView {
+	view := &types.PortfolioView{
+		Time:      now,
+		Positions: make([]types.SymbolPosition, 0, len(snapshots)),
+		TopRisks:  []types.RiskContributor{},
+	}
+	risks := make(map[string]float64)
+	for _, instance := range snapshots {
+		view.Equity += instance.Equity
+		view.OpenPnL += instance.OpenPnL
+		view.OpenRisk += instance.OpenRisk
+		view.GrossExposure += instance.Notional
+		if instance.Direction == types.DirectionShort {
+			view.NetExposure -= instance.Notional
+		} else {
+			view.NetExposure += instance.Notional
+		}
+		if instance.OpenRisk > 0 {
+			risks[instance.Symbol] += instance.OpenRisk
+		}
+		view.Positions = append(view.Positions, types.SymbolPosition{
+			Symbol:    instance.Symbol,
+			RunID:     instance.RunID,
+			Direction: instance.Direction,
+			Notional:  instance.Notional,
+			OpenPnL:   instance.OpenPnL,
+			OpenRisk:  instance.OpenRisk,
+			Equity:    instance.Equity,
+			Updated:   instance.Time,
+		})
+	}
+
+	for symbol, risk := range risks {
+		view.TopRisks = append(view.TopRisks, types.RiskContributor{Symbol: symbol, OpenRisk: risk, Share: risk / view.OpenRisk})
+	}
+	sort.Slice(view.TopRisks, func(i, j int) bool {
+		a, b := view.TopRisks[i], view.TopRisks[j]
+		if a.OpenRisk != b.OpenRisk {
+			return a.OpenRisk > b.OpenRisk
+		}
+		return a.Symbol < b.Symbol
+	})
+	if len(view.TopRisks) > topRisks {
+		view.TopRisks = view.TopRisks[:topRisks]
+	}
+	return view
+}
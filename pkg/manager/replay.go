@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"TRADE/pkg/errs"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+)
+
+// expandDatasets splits the dataset setting into its comma-separated paths
+// and expands glob patterns, keeping the order given
+func expandDatasets(setting string) ([]string, error) {
+	var paths []string
+	for _, pattern := range strings.Split(setting, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataset pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			// Not a pattern, or nothing matched: opening it reports why
+			matches = []string{pattern}
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// replayDatasets feeds the datasets, merged into one chronological
+// stream, through the market data as live ticks are fed. Datasets named
+// after a watched symbol feed that symbol; all others feed the traded one.
+func (m *Manager) replayDatasets(paths []string) error {
+	replay, err := market.OpenReplay(paths, m.logger.With(logger.ComponentKey, "market"))
+	if err != nil {
+		return err
+	}
+	defer replay.Close()
+
+	m.market.Reset()
+	targets := make(map[string]*market.MarketData, len(m.watched))
+	for _, watched := range m.watched {
+		watched.Reset()
+		targets[watched.Symbol()] = watched
+	}
+
+	traded := 0
+	var tick market.Tick
+	for {
+		if err := replay.Next(&tick); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		target, ok := targets[tick.Symbol]
+		if !ok {
+			target = m.market
+			traded++
+		}
+		// Hand a pooled copy to the market data, which releases it
+		pooled := market.NewTick()
+		*pooled = tick.TickData
+		target.AddTick(pooled)
+	}
+
+	stats := replay.Stats()
+	m.logger.Info(fmt.Sprintf("Replayed %d tick(s) from %d dataset(s), %d of %s: %d reordered, %d duplicate(s) and %d late row(s) dropped, %d invalid",
+		stats.Ticks, stats.Datasets, traded, m.symbol, stats.Reordered, stats.Duplicates, stats.Late, stats.Invalid),
+		logger.ComponentKey, "market")
+	if traded == 0 {
+		return errs.Errorf(errs.ErrInsufficientData, "", "no valid rows of %s in %s", m.symbol, strings.Join(paths, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/market"
+	"TRADE/pkg/types"
+	"TRADE/pkg/watchdog"
+)
+
+// Script shapes the synthetic market of a scenario run
+type Script interface {
+	// Shape rewrites tick, the next of the simulator, in place and returns
+	// the book quote around it; done ends the run before the tick
+	Shape(tick *types.TickData) (quote market.Quote, done bool)
+}
+
+// RunScenario runs the system on the simulator's market as shaped by
+// script, tick by tick as a backtest replays a dataset. The watchdog checks
+// the feed on the tick clock and the entry filter sees the scripted quotes.
+// A trade still open at the end is closed.
+func (m *Manager) RunScenario(script Script) error {
+	if err := m.beginStart(); err != nil {
+		return err
+	}
+	m.paper, m.backtest, m.simulated = false, true, false
+
+	if err := m.Initialize(); err != nil {
+		m.abortStart()
+		return err
+	}
+	simulator, err := market.NewSimulator(m.market, simulatorParams(m.config.Simulator), m.logger.With(logger.ComponentKey, "simulator"))
+	if err != nil {
+		m.abortStart()
+		return err
+	}
+	m.setStatus(StatusRunning)
+
+	// Silence is measured in tick time, between the ticks of the script
+	m.watchdog = m.newWatchdog(watchdog.Source{Symbol: m.symbol, LastTick: m.market.LastTimestamp})
+	interval := m.watchdog.CheckInterval()
+	var nextCheck time.Time
+
+	ticks := 0
+	for {
+		tick := simulator.Next()
+		quote, done := script.Shape(tick)
+		if done {
+			break
+		}
+		if !nextCheck.IsZero() {
+			for ; nextCheck.Before(tick.Timestamp); nextCheck = nextCheck.Add(interval) {
+				m.watchdog.Check(nextCheck)
+			}
+		} else {
+			nextCheck = tick.Timestamp.Add(interval)
+		}
+
+		// Quotes are stamped on arrival, as the live feed does
+		quote.Symbol = m.symbol
+		quote.Time = time.Now()
+		m.market.SetQuote(quote)
+		m.market.AddTick(tick)
+		ticks++
+	}
+	m.logger.Info(fmt.Sprintf("Scenario ran %d tick(s)", ticks), logger.ComponentKey, "simulator")
+
+	if signal := m.strategy.ForceExit(m.market.GetCurrentPrice(), m.market.LastTimestamp(), "end_of_scenario"); signal != nil {
+		m.publishSignal(m.symbol, signal, nil)
+	}
+	return nil
+}
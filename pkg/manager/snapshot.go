@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/config"
+	"TRADE/pkg/guard"
+	"TRADE/pkg/market"
+	"TRADE/pkg/performance"
+	"TRADE/pkg/portfolio"
+	"TRADE/pkg/risk"
+	"TRADE/pkg/types"
+	"TRADE/pkg/version"
+)
+
+// defaultSnapshotDir is where runtime state snapshots are written
+const defaultSnapshotDir = "snapshots"
+
+// Snapshot is a dump of the full runtime state for debugging
+type Snapshot struct {
+	Timestamp     time.Time
+	Build         version.Info
+	Status        string
+	ExecutionMode types.ExecutionMode
+	Market        *market.Summary
+	Watched       []market.Summary // Streamed symbols that are not traded
+	Metrics       *types.MarketMetrics
+	Positions     []*types.TradeData
+	Orders        []interface{}
+	Allocations   []portfolio.Allocation
+	Risk          *risk.Exposure
+	KillSwitch    *risk.Trip            // Why new entries are halted, if the kill-switch tripped
+	EntryFilter   *guard.LiquidityStats // Entry signals checked and blocked by the liquidity guard
+	Equity        *types.EquityPoint
+	Accounts      []account.Balance
+	Funding       *performance.FundingStats
+	Config        *config.Config
+}
+
+// Snapshot captures the current runtime state
+func (m *Manager) Snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Timestamp:     time.Now(),
+		Build:         version.Get(),
+		Status:        m.Status().String(),
+		ExecutionMode: m.execMode,
+		Positions:     make([]*types.TradeData, 0),
+		Orders:        make([]interface{}, 0),
+		Config:        m.config,
+	}
+
+	if m.market != nil {
+		summary := m.market.GetSummary()
+		snapshot.Market = &summary
+	}
+	for _, watched := range m.watched {
+		snapshot.Watched = append(snapshot.Watched, watched.GetSummary())
+	}
+	if m.analyzer != nil {
+		snapshot.Metrics = m.analyzer.GetMetrics()
+	}
+	if m.strategy != nil {
+		if trade := m.strategy.GetActiveTradeData(); trade.Active {
+			snapshot.Positions = append(snapshot.Positions, trade)
+		}
+	}
+	if m.portfolio != nil {
+		snapshot.Allocations = m.portfolio.GetAllocations()
+	}
+	if m.equity != nil {
+		equity := m.equity.Current()
+		snapshot.Equity = &equity
+	}
+	if m.risk != nil {
+		exposure := m.risk.Exposure()
+		snapshot.Risk = &exposure
+	}
+	if m.killSwitch != nil {
+		if trip, ok := m.killSwitch.Tripped(); ok {
+			snapshot.KillSwitch = &trip
+		}
+	}
+	if m.liquidity != nil {
+		stats := m.liquidity.Stats()
+		snapshot.EntryFilter = &stats
+	}
+	if m.accounts != nil {
+		snapshot.Accounts = m.accounts.Balances()
+	}
+	if m.funding != nil {
+		funding := m.funding.Stats()
+		snapshot.Funding = &funding
+	}
+
+	return snapshot
+}
+
+// WriteSnapshot dumps the runtime state to a timestamped JSON file in dir
+// and returns the file path
+func (m *Manager) WriteSnapshot(dir string) (string, error) {
+	if dir == "" {
+		dir = defaultSnapshotDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+
+	snapshot := m.Snapshot()
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("snapshot_%s.json", snapshot.Timestamp.Format("20060102_150405")))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %v", err)
+	}
+
+	m.logger.Info(fmt.Sprintf("State snapshot written to %s", path))
+	return path, nil
+}
@@ -0,0 +1,166 @@
+package manager
+
+import (
+	"fmt"
+)
+
+// Status describes the lifecycle state of the trading system
+type Status int
+
+const (
+	// Lifecycle states
+	StatusStopped Status = iota
+	StatusStarting
+	StatusRunning
+	StatusPaused
+	StatusHalted
+	StatusSafe     // Entries frozen by a suspected exchange outage
+	StatusStopping // Shutdown in progress
+)
+
+// String returns the name of the status
+func (s Status) String() string {
+	switch s {
+	case StatusStopped:
+		return "STOPPED"
+	case StatusStarting:
+		return "STARTING"
+	case StatusRunning:
+		return "RUNNING"
+	case StatusPaused:
+		return "PAUSED"
+	case StatusHalted:
+		return "HALTED"
+	case StatusSafe:
+		return "SAFE"
+	case StatusStopping:
+		return "STOPPING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Status returns the current lifecycle state
+func (m *Manager) Status() Status {
+	m.statusMutex.RLock()
+	defer m.statusMutex.RUnlock()
+	return m.status
+}
+
+// beginStop moves a started system to STOPPING, reporting false if it is
+// stopped, still starting or already being stopped
+func (m *Manager) beginStop() bool {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+
+	switch m.status {
+	case StatusStopped, StatusStarting, StatusStopping:
+		return false
+	}
+	m.status = StatusStopping
+	return true
+}
+
+// entriesAllowed reports whether new trades may be opened.
+// Paused, halted and SAFE systems, and stale feeds, keep managing exits of
+// open trades.
+func (m *Manager) entriesAllowed() bool {
+	m.statusMutex.RLock()
+	defer m.statusMutex.RUnlock()
+	return m.status == StatusRunning && !m.feedFrozen
+}
+
+// beginStart moves from STOPPED to STARTING, failing if already started
+func (m *Manager) beginStart() error {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+
+	if m.status != StatusStopped {
+		return fmt.Errorf("trading system already started (status %s)", m.status)
+	}
+	m.status = StatusStarting
+	m.cleanup = nil
+	return nil
+}
+
+// onAbort pushes the step undoing one the start in progress took
+func (m *Manager) onAbort(undo func()) {
+	m.cleanup = append(m.cleanup, undo)
+}
+
+// abortStart undoes the steps of a failed start, last first, and moves
+// back to STOPPED
+func (m *Manager) abortStart() {
+	for i := len(m.cleanup) - 1; i >= 0; i-- {
+		m.cleanup[i]()
+	}
+	m.cleanup = nil
+	m.setStatus(StatusStopped)
+}
+
+// setStatus changes the lifecycle state
+func (m *Manager) setStatus(status Status) {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+	m.status = status
+}
+
+// Pause stops opening new trades while exits keep being managed
+func (m *Manager) Pause() error {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+
+	switch m.status {
+	case StatusPaused:
+		return nil
+	case StatusRunning:
+		m.status = StatusPaused
+		m.logger.Info("Trading paused: new entries disabled")
+		return nil
+	case StatusSafe:
+		m.safeReturn = StatusPaused
+		m.logger.Info("Trading paused: new entries stay disabled after the exchange outage")
+		return nil
+	default:
+		return fmt.Errorf("cannot pause trading system in status %s", m.status)
+	}
+}
+
+// Resume re-enables new entries after Pause, Halt or an exchange outage,
+// re-arming the kill-switch
+func (m *Manager) Resume() error {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+
+	switch m.status {
+	case StatusRunning:
+		return nil
+	case StatusPaused, StatusHalted, StatusSafe:
+		m.status = StatusRunning
+		if m.killSwitch != nil {
+			m.killSwitch.Reset()
+		}
+		m.outage.Clear()
+		m.logger.Info("Trading resumed: new entries enabled")
+		return nil
+	default:
+		return fmt.Errorf("cannot resume trading system in status %s", m.status)
+	}
+}
+
+// Halt stops new entries until an explicit Resume; used by circuit breakers
+func (m *Manager) Halt(reason string) error {
+	m.statusMutex.Lock()
+	defer m.statusMutex.Unlock()
+
+	switch m.status {
+	case StatusHalted:
+		return nil
+	case StatusRunning, StatusPaused, StatusSafe:
+		m.status = StatusHalted
+		m.logger.Warning(fmt.Sprintf("Trading halted: %s", reason))
+		return nil
+	default:
+		return fmt.Errorf("cannot halt trading system in status %s", m.status)
+	}
+}
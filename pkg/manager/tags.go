@@ -0,0 +1,20 @@
+package manager
+
+import (
+	"fmt"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// tagEntry stores the tags the strategy gave a trade at entry in the
+// trade history, where the trade can be annotated while still open
+func (m *Manager) tagEntry(signal *types.Signal) {
+	if m.store == nil || len(signal.Tags) == 0 {
+		return
+	}
+	if err := m.store.TagTrade(signal.TradeID, signal.Tags...); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to tag trade: %v", err),
+			logger.ComponentKey, "storage", logger.TradeIDKey, signal.TradeID)
+	}
+}
@@ -0,0 +1,21 @@
+package market_test
+
+import (
+	"testing"
+
+	"TRADE/pkg/bench"
+	"TRADE/pkg/events"
+)
+
+// BenchmarkAddTick measures storing and publishing a tick without
+// subscribers, on a full history
+func BenchmarkAddTick(b *testing.B) {
+	ticks := bench.Ticks(2*bench.HistorySize, 1)
+	md := bench.NewMarket(events.NewBus(), ticks[:bench.HistorySize])
+	replay := ticks[bench.HistorySize:]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bench.AddTick(md, &replay[i%len(replay)])
+	}
+}
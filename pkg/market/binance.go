@@ -0,0 +1,390 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// Default Binance WebSocket endpoint and keepalive timing. Binance pings every 20
+// seconds and drops connections that do not answer within a minute.
+const (
+	binanceStreamURL = "wss://stream.binance.com:9443/ws"
+	wsReadTimeout    = time.Minute      // Silence after which the connection is considered dead
+	wsPingInterval   = 30 * time.Second // Client pings keep idle proxies from closing the connection
+	wsWriteTimeout   = 10 * time.Second
+)
+
+// messageKind classifies a WebSocket text message
+type messageKind int
+
+const (
+	messageTrade        messageKind = iota
+	messageBookTicker               // Best bid and ask
+	messageMarkPrice                // Mark price and funding rate of a perpetual
+	messageDepth                    // Top levels of the order book
+	messageDepthUpdate              // Changed levels of the order book
+	messageSubscription             // Response to a SUBSCRIBE request
+	messageError                    // Error payload from the exchange
+	messageUnknown                  // Well-formed, but not a stream we handle
+)
+
+// exchangeError is a Binance error payload
+type exchangeError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// streamMessage holds every field of the Binance messages we recognize.
+// Fields whose names differ only in case (e/E, t/T, m/M, a/A, b/B, p/P) are
+// all declared, since encoding/json otherwise matches keys case-insensitively
+// and "M" would overwrite "m".
+type streamMessage struct {
+	// Trade stream payload
+	EventType    string      `json:"e"`
+	EventTime    int64       `json:"E"`
+	Symbol       string      `json:"s"`
+	TradeID      json.Number `json:"t"`
+	Price        string      `json:"p"`
+	Quantity     string      `json:"q"`
+	TradeTime    int64       `json:"T"` // Unix milliseconds
+	BuyerIsMaker bool        `json:"m"`
+	Ignore       bool        `json:"M"`
+
+	// Mark price payload of perpetuals: {"e":"markPriceUpdate","E":1562305380000,
+	// "s":"BTCUSDT","p":"11794.15","i":"11784.62","P":"11784.25",
+	// "r":"0.00038167","T":1562306400000}. The mark price is in Price and
+	// the next funding time in TradeTime.
+	IndexPrice  string `json:"i"`
+	SettlePrice string `json:"P"` // Estimated settle price
+	FundingRate string `json:"r"`
+
+	// Book ticker payload: {"u":400900217,"s":"BNBUSDT","b":"25.35",
+	// "B":"31.21","a":"25.36","A":"40.66"}
+	UpdateID    *int64   `json:"u"`
+	BidPrice    bookSide `json:"b"`
+	BidQuantity string   `json:"B"`
+	AskPrice    bookSide `json:"a"`
+	AskQuantity string   `json:"A"`
+
+	// Diff depth payload: {"e":"depthUpdate","E":1672515782136,
+	// "s":"BNBBTC","U":157,"u":160,"b":[["0.0024","10"]],"a":[["0.0026",
+	// "100"]]}, with "pu", the previous event's "u", on futures. The
+	// changed levels are in the Levels of BidPrice and AskPrice.
+	FirstUpdateID *int64 `json:"U"`
+	PrevUpdateID  *int64 `json:"pu"`
+
+	// Partial book depth payload: {"lastUpdateId":160,"bids":[["0.0024",
+	// "10"]],"asks":[["0.0026","100"]]}. It names no symbol, so it can
+	// only be routed by its combined stream name.
+	LastUpdateID *int64     `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+
+	// Request responses: {"result":null,"id":1} on success, an error
+	// object or top-level code/msg on failure
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *exchangeError  `json:"error"`
+	Code   *int            `json:"code"`
+	Msg    string          `json:"msg"`
+
+	// Combined stream wrapper: {"stream":"btcusdt@trade","data":{...}}
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// bookSide is the "b" or "a" field of a message: a price in book ticker
+// payloads, a list of levels in diff depth payloads
+type bookSide struct {
+	Price  string
+	Levels [][]string
+}
+
+// UnmarshalJSON decodes either form
+func (s *bookSide) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, &s.Levels)
+	}
+	return json.Unmarshal(data, &s.Price)
+}
+
+// classify decodes a message into msg and reports its kind. Trades are
+// validated strictly: a message that claims to be a trade but lacks a
+// valid price, quantity or time is an error rather than a zero tick.
+func classify(data []byte, msg *streamMessage) (messageKind, error) {
+	*msg = streamMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return messageUnknown, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if msg.Stream != "" && len(msg.Data) > 0 {
+		stream := msg.Stream
+		kind, err := classify(msg.Data, msg)
+		msg.Stream = stream
+		return kind, err
+	}
+
+	switch {
+	case msg.Error != nil:
+		return messageError, nil
+	case msg.Code != nil:
+		msg.Error = &exchangeError{Code: *msg.Code, Msg: msg.Msg}
+		return messageError, nil
+	case msg.ID != nil:
+		return messageSubscription, nil
+	case msg.EventType == "trade":
+		return messageTrade, msg.validateTrade()
+	case msg.LastUpdateID != nil:
+		_, err := msg.depth()
+		return messageDepth, err
+	case msg.EventType == "depthUpdate":
+		_, err := msg.depthUpdate()
+		return messageDepthUpdate, err
+	case msg.EventType == "bookTicker" || msg.EventType == "" && msg.UpdateID != nil:
+		_, err := msg.quote()
+		return messageBookTicker, err
+	case msg.EventType == "markPriceUpdate":
+		_, err := msg.funding()
+		return messageMarkPrice, err
+	}
+	return messageUnknown, nil
+}
+
+// symbol returns the lower-case symbol a message belongs to, from the
+// combined stream name if there is one
+func (msg *streamMessage) symbol() string {
+	if i := strings.IndexByte(msg.Stream, '@'); i > 0 {
+		return msg.Stream[:i]
+	}
+	return strings.ToLower(msg.Symbol)
+}
+
+// validateTrade checks the fields a tick is built from
+func (msg *streamMessage) validateTrade() error {
+	price, err := strconv.ParseFloat(msg.Price, 64)
+	if err != nil || price <= 0 {
+		return fmt.Errorf("invalid trade price %q", msg.Price)
+	}
+	quantity, err := strconv.ParseFloat(msg.Quantity, 64)
+	if err != nil || quantity < 0 {
+		return fmt.Errorf("invalid trade quantity %q", msg.Quantity)
+	}
+	if msg.TradeTime <= 0 {
+		return fmt.Errorf("missing trade time")
+	}
+	return nil
+}
+
+// tick returns the tick of a validated trade message
+func (msg *streamMessage) tick() Tick {
+	// classify has validated both numbers
+	price, _ := strconv.ParseFloat(msg.Price, 64)
+	quantity, _ := strconv.ParseFloat(msg.Quantity, 64)
+	return Tick{
+		Symbol: msg.symbol(),
+		TickData: types.TickData{
+			Price:     price,
+			Volume:    quantity,
+			IsAsk:     !msg.BuyerIsMaker,
+			Timestamp: time.UnixMilli(msg.TradeTime).UTC(),
+		},
+	}
+}
+
+// quote parses a book ticker payload
+func (msg *streamMessage) quote() (Quote, error) {
+	var quote Quote
+	fields := []struct {
+		name  string
+		value string
+		dst   *float64
+	}{
+		{"bid price", msg.BidPrice.Price, &quote.Bid},
+		{"bid quantity", msg.BidQuantity, &quote.BidQuantity},
+		{"ask price", msg.AskPrice.Price, &quote.Ask},
+		{"ask quantity", msg.AskQuantity, &quote.AskQuantity},
+	}
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(field.value, 64)
+		if err != nil || value < 0 {
+			return Quote{}, fmt.Errorf("invalid book ticker %s %q", field.name, field.value)
+		}
+		*field.dst = value
+	}
+	if quote.Bid <= 0 || quote.Ask < quote.Bid {
+		return Quote{}, fmt.Errorf("invalid book ticker prices %s/%s", msg.BidPrice.Price, msg.AskPrice.Price)
+	}
+	return quote, nil
+}
+
+// depth parses a partial book depth payload
+func (msg *streamMessage) depth() (Depth, error) {
+	var depth Depth
+	var err error
+	if depth.Bids, err = parseLevels("bid", msg.Bids); err != nil {
+		return Depth{}, err
+	}
+	if depth.Asks, err = parseLevels("ask", msg.Asks); err != nil {
+		return Depth{}, err
+	}
+	return depth, nil
+}
+
+// depthUpdate parses a diff depth payload
+func (msg *streamMessage) depthUpdate() (DepthUpdate, error) {
+	if msg.FirstUpdateID == nil || msg.UpdateID == nil || *msg.FirstUpdateID > *msg.UpdateID {
+		return DepthUpdate{}, fmt.Errorf("invalid depth update IDs")
+	}
+	update := DepthUpdate{Symbol: msg.symbol(), FirstID: *msg.FirstUpdateID, LastID: *msg.UpdateID}
+	if msg.PrevUpdateID != nil {
+		update.PrevLastID = *msg.PrevUpdateID
+	}
+	var err error
+	if update.Bids, err = parseLevels("bid", msg.BidPrice.Levels); err != nil {
+		return DepthUpdate{}, err
+	}
+	if update.Asks, err = parseLevels("ask", msg.AskPrice.Levels); err != nil {
+		return DepthUpdate{}, err
+	}
+	return update, nil
+}
+
+// parseLevels parses the [price, quantity] levels of a book side
+func parseLevels(side string, levels [][]string) ([]Level, error) {
+	parsed := make([]Level, 0, len(levels))
+	for _, level := range levels {
+		if len(level) != 2 {
+			return nil, fmt.Errorf("invalid book depth %s level %q", side, level)
+		}
+		price, err := strconv.ParseFloat(level[0], 64)
+		if err != nil || price <= 0 {
+			return nil, fmt.Errorf("invalid book depth %s price %q", side, level[0])
+		}
+		quantity, err := strconv.ParseFloat(level[1], 64)
+		if err != nil || quantity < 0 {
+			return nil, fmt.Errorf("invalid book depth %s quantity %q", side, level[1])
+		}
+		parsed = append(parsed, Level{Price: price, Quantity: quantity})
+	}
+	return parsed, nil
+}
+
+// funding parses a mark price payload. The index price is optional; the
+// mark price, funding rate and next funding time are required.
+func (msg *streamMessage) funding() (*events.FundingEvent, error) {
+	mark, err := strconv.ParseFloat(msg.Price, 64)
+	if err != nil || mark <= 0 {
+		return nil, fmt.Errorf("invalid mark price %q", msg.Price)
+	}
+	rate, err := strconv.ParseFloat(msg.FundingRate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid funding rate %q", msg.FundingRate)
+	}
+	if msg.TradeTime <= 0 {
+		return nil, fmt.Errorf("missing next funding time")
+	}
+	index, _ := strconv.ParseFloat(msg.IndexPrice, 64)
+	return &events.FundingEvent{
+		Symbol:          strings.ToLower(msg.Symbol),
+		MarkPrice:       mark,
+		IndexPrice:      index,
+		Rate:            rate,
+		NextFundingTime: time.UnixMilli(msg.TradeTime).UTC(),
+		Timestamp:       time.UnixMilli(msg.EventTime).UTC(),
+	}, nil
+}
+
+// subscribeRequest subscribes to streams on a /ws connection
+type subscribeRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int64    `json:"id"`
+}
+
+// newSubscribeRequest subscribes to the trade and book ticker streams of
+// the symbols, to their mark price streams if perpetual and to their
+// partial book depth streams if depthLevels is not 0, or their diff depth
+// streams if it is diffDepth
+func newSubscribeRequest(id int64, perpetual bool, depthLevels int, symbols ...string) subscribeRequest {
+	request := subscribeRequest{Method: "SUBSCRIBE", ID: id}
+	for _, symbol := range symbols {
+		symbol = strings.ToLower(symbol)
+		request.Params = append(request.Params, symbol+"@trade", symbol+"@bookTicker")
+		if perpetual {
+			request.Params = append(request.Params, symbol+"@markPrice")
+		}
+		switch {
+		case depthLevels == diffDepth:
+			request.Params = append(request.Params, symbol+"@depth@100ms")
+		case depthLevels > 0:
+			request.Params = append(request.Params, depthStream(symbol, depthLevels))
+		}
+	}
+	return request
+}
+
+// FeedStats counts the messages received on the live feed
+type FeedStats struct {
+	Messages      int64  `json:"messages"`
+	Trades        int64  `json:"trades"`
+	Quotes        int64  `json:"quotes"`
+	MarkPrices    int64  `json:"mark_prices"`
+	Depths        int64  `json:"depth_updates"`
+	BookResyncs   int64  `json:"book_resyncs"` // Local order books discarded for a gap in the updates
+	Subscriptions int64  `json:"subscription_responses"`
+	Errors        int64  `json:"exchange_errors"`
+	Unknown       int64  `json:"unknown"`
+	Malformed     int64  `json:"malformed"`
+	Pings         int64  `json:"pings"`
+	Pongs         int64  `json:"pongs"`
+	LastMalformed string `json:"last_malformed,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// feedCounters are the live FeedStats, updated without locks on the
+// connection goroutine
+type feedCounters struct {
+	messages, trades, quotes, markPrices, depths, resyncs, subscriptions, errors, unknown, malformed, pings, pongs int64
+
+	lastMalformed atomic.Value // string
+	lastError     atomic.Value // string
+}
+
+// snapshot returns the current counts
+func (c *feedCounters) snapshot() FeedStats {
+	stats := FeedStats{
+		Messages:      atomic.LoadInt64(&c.messages),
+		Trades:        atomic.LoadInt64(&c.trades),
+		Quotes:        atomic.LoadInt64(&c.quotes),
+		MarkPrices:    atomic.LoadInt64(&c.markPrices),
+		Depths:        atomic.LoadInt64(&c.depths),
+		BookResyncs:   atomic.LoadInt64(&c.resyncs),
+		Subscriptions: atomic.LoadInt64(&c.subscriptions),
+		Errors:        atomic.LoadInt64(&c.errors),
+		Unknown:       atomic.LoadInt64(&c.unknown),
+		Malformed:     atomic.LoadInt64(&c.malformed),
+		Pings:         atomic.LoadInt64(&c.pings),
+		Pongs:         atomic.LoadInt64(&c.pongs),
+	}
+	stats.LastMalformed, _ = c.lastMalformed.Load().(string)
+	stats.LastError, _ = c.lastError.Load().(string)
+	return stats
+}
+
+// sample shortens a raw message for logs and stats
+func sample(data []byte) string {
+	const max = 200
+	if len(data) > max {
+		return string(data[:max]) + "..."
+	}
+	return string(data)
+}
+
+// lastRequestID numbers WebSocket requests across reconnects
+var lastRequestID int64
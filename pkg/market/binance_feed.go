@@ -0,0 +1,464 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// BinanceFeed is the live feed of Binance: the trade and book ticker
+// streams (and optionally the mark price and book depth streams) of its
+// symbols on one connection to the combined stream endpoint
+type BinanceFeed struct {
+	symbols   map[string]bool // Lower-case symbols subscribed
+	url       string
+	perpetual bool
+	depth     int // Book levels streamed; 0 streams no depth, diffDepth the local books' updates
+	orderBook OrderBookSettings
+	books     map[string]*bookSync // Local order books, by lower-case symbol
+
+	conn    *websocket.Conn
+	started bool
+	active  bool
+	closed  bool
+	pending map[int64][]string // Streams of unanswered subscribe requests
+	feed    feedCounters
+
+	ticks   chan Tick
+	quotes  chan Quote
+	depths  chan Depth
+	funding chan *events.FundingEvent
+	done    chan struct{}
+	running sync.WaitGroup
+
+	bus    *events.Bus
+	logger logger.Interface
+	mutex  sync.RWMutex
+	write  sync.Mutex // Serializes requests on the connection
+}
+
+// NewBinanceFeed creates a feed on the default Binance endpoint publishing
+// connection errors on the bus
+func NewBinanceFeed(log logger.Interface, bus *events.Bus) *BinanceFeed {
+	return &BinanceFeed{
+		symbols: make(map[string]bool),
+		url:     binanceStreamURL,
+		pending: make(map[int64][]string),
+		books:   make(map[string]*bookSync),
+		ticks:   make(chan Tick, feedBuffer),
+		quotes:  make(chan Quote, feedBuffer),
+		depths:  make(chan Depth, feedBuffer),
+		funding: make(chan *events.FundingEvent, feedBuffer),
+		done:    make(chan struct{}),
+		bus:     bus,
+		logger:  log,
+	}
+}
+
+// SetURL sets the WebSocket endpoint. A raw stream endpoint ending in /ws
+// is replaced by the combined stream endpoint of the same host.
+func (f *BinanceFeed) SetURL(url string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.url = url
+}
+
+// SetPerpetual subscribes to the mark price stream of every symbol, which
+// carries the funding rate of perpetual futures
+func (f *BinanceFeed) SetPerpetual(perpetual bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.perpetual = perpetual
+}
+
+// SetDepth subscribes to the partial book depth stream of every symbol
+// with the given number of levels (5, 10 or 20); 0 disables it
+func (f *BinanceFeed) SetDepth(levels int) error {
+	if levels != 0 && !ValidDepthLevels(levels) {
+		return fmt.Errorf("invalid book depth of %d levels (want 5, 10 or 20)", levels)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.depth = levels
+	return nil
+}
+
+// SetOrderBook keeps a local order book of every symbol from a snapshot
+// and the diff depth stream, delivering its top levels as the book depth
+// on every update, instead of the partial book depth stream
+func (f *BinanceFeed) SetOrderBook(settings OrderBookSettings) error {
+	if settings.Levels <= 0 || settings.SnapshotLimit < settings.Levels {
+		return fmt.Errorf("invalid order book of %d levels from snapshots of %d", settings.Levels, settings.SnapshotLimit)
+	}
+	if settings.SnapshotURL == "" {
+		settings.SnapshotURL = DefaultDepthSnapshotURL
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.depth = diffDepth
+	f.orderBook = settings
+	return nil
+}
+
+// Subscribe adds symbols to the feed. On an open connection their streams
+// are subscribed right away, otherwise when it is opened.
+func (f *BinanceFeed) Subscribe(symbols ...string) error {
+	f.mutex.Lock()
+	var added []string
+	for _, symbol := range symbols {
+		symbol = strings.ToLower(symbol)
+		if symbol == "" || f.symbols[symbol] {
+			continue
+		}
+		f.symbols[symbol] = true
+		added = append(added, symbol)
+	}
+	conn := f.conn
+	f.mutex.Unlock()
+
+	if conn == nil || len(added) == 0 {
+		return nil
+	}
+	return f.subscribe(conn, added)
+}
+
+// Ticks delivers the trades of all subscribed symbols
+func (f *BinanceFeed) Ticks() <-chan Tick { return f.ticks }
+
+// Quotes delivers the book ticker of all subscribed symbols
+func (f *BinanceFeed) Quotes() <-chan Quote { return f.quotes }
+
+// Depths delivers the book depth of all subscribed symbols, if enabled
+func (f *BinanceFeed) Depths() <-chan Depth { return f.depths }
+
+// Funding delivers the mark price updates of perpetuals, if enabled
+func (f *BinanceFeed) Funding() <-chan *events.FundingEvent { return f.funding }
+
+// Connect dials the endpoint and subscribes to the streams of all symbols
+// in the background
+func (f *BinanceFeed) Connect() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch {
+	case f.closed:
+		return errs.Errorf(errs.ErrFeedDisconnected, "", "market data feed is closed")
+	case f.started:
+		return fmt.Errorf("already connected to market data")
+	case len(f.symbols) == 0:
+		return fmt.Errorf("no symbols to stream")
+	}
+	f.started = true
+	f.running.Add(1)
+	go f.run()
+	return nil
+}
+
+// Connected reports whether the connection is up
+func (f *BinanceFeed) Connected() bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.active
+}
+
+// Reconnect drops the current WebSocket connection and dials a new one
+func (f *BinanceFeed) Reconnect() error {
+	f.mutex.Lock()
+	if f.closed || !f.started {
+		f.mutex.Unlock()
+		return errs.Errorf(errs.ErrFeedDisconnected, "", "not connected to live market data")
+	}
+	if f.conn != nil {
+		f.conn.Close()
+		f.conn = nil
+	}
+	f.active = false
+	f.running.Add(1)
+	f.mutex.Unlock()
+
+	f.logger.Info("Reconnecting to live market data")
+	go f.run()
+	return nil
+}
+
+// Close closes the connection for good. The delivery channels are closed
+// once the connection goroutine has stopped, which a pending dial delays.
+func (f *BinanceFeed) Close() error {
+	f.mutex.Lock()
+	if f.closed {
+		f.mutex.Unlock()
+		return nil
+	}
+	f.closed = true
+	close(f.done)
+	if f.conn != nil {
+		f.conn.Close()
+		f.conn = nil
+	}
+	f.active = false
+	f.mutex.Unlock()
+
+	go func() {
+		f.running.Wait()
+		close(f.ticks)
+		close(f.quotes)
+		close(f.depths)
+		close(f.funding)
+	}()
+	return nil
+}
+
+// FeedStats returns the message counts of the connection
+func (f *BinanceFeed) FeedStats() FeedStats {
+	return f.feed.snapshot()
+}
+
+// subscribedSymbols returns the subscribed symbols, sorted. The caller
+// holds the lock.
+func (f *BinanceFeed) subscribedSymbols() []string {
+	symbols := make([]string, 0, len(f.symbols))
+	for symbol := range f.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// subscribe sends a subscribe request for the streams of symbols; the
+// exchange's answer is logged by the connection goroutine
+func (f *BinanceFeed) subscribe(conn *websocket.Conn, symbols []string) error {
+	f.mutex.Lock()
+	request := newSubscribeRequest(atomic.AddInt64(&lastRequestID, 1), f.perpetual, f.depth, symbols...)
+	f.pending[request.ID] = request.Params
+	f.mutex.Unlock()
+
+	f.write.Lock()
+	defer f.write.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(request)
+}
+
+// send delivers a value on a feed channel unless the feed is closed first
+func send[T any](ch chan<- T, value T, done <-chan struct{}) {
+	select {
+	case ch <- value:
+	case <-done:
+	}
+}
+
+// run establishes and maintains the WebSocket connection
+func (f *BinanceFeed) run() {
+	defer f.running.Done()
+
+	f.mutex.RLock()
+	url := combinedURL(f.url)
+	f.mutex.RUnlock()
+	f.logger.Info(fmt.Sprintf("Connecting to %s", url))
+
+	// Connect to WebSocket
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		f.logger.Error(fmt.Sprintf("WebSocket connection error: %v", err))
+		f.publishError(errs.Wrap(errs.ErrFeedDisconnected, "websocket dial", err))
+		return
+	}
+
+	// Publish the connection together with the symbols subscribed on it,
+	// so symbols added from now on are subscribed by Subscribe
+	f.mutex.Lock()
+	if f.closed {
+		f.mutex.Unlock()
+		conn.Close()
+		return
+	}
+	f.conn = conn
+	symbols := f.subscribedSymbols()
+	f.mutex.Unlock()
+
+	// Updates were missed while disconnected
+	f.resetBooks()
+
+	// Subscribe explicitly so the exchange confirms or rejects the streams
+	if err := f.subscribe(conn, symbols); err != nil {
+		conn.Close()
+		f.logger.Error(fmt.Sprintf("WebSocket subscribe error: %v", err))
+		f.publishError(errs.Wrap(errs.ErrFeedDisconnected, "websocket subscribe", err))
+		f.mutex.Lock()
+		if f.conn == conn {
+			f.conn = nil
+		}
+		f.mutex.Unlock()
+		return
+	}
+
+	f.mutex.Lock()
+	f.active = f.conn == conn
+	f.mutex.Unlock()
+
+	f.logger.Info("WebSocket connection established")
+
+	// Any frame, including pings and pongs, proves the connection alive
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPingHandler(func(appData string) error {
+		atomic.AddInt64(&f.feed.pings, 1)
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(wsWriteTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+	conn.SetPongHandler(func(string) error {
+		atomic.AddInt64(&f.feed.pongs, 1)
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		return nil
+	})
+	stopPing := make(chan struct{})
+	go f.keepAlive(conn, stopPing)
+
+	// Handle incoming messages
+	var msg streamMessage
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			f.logger.Error(fmt.Sprintf("WebSocket read error: %v", err))
+			f.publishError(errs.Wrap(errs.ErrFeedDisconnected, "websocket read", err))
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		atomic.AddInt64(&f.feed.messages, 1)
+		if messageType != websocket.TextMessage {
+			f.malformed(fmt.Errorf("unexpected binary message"), message)
+			continue
+		}
+
+		kind, err := classify(message, &msg)
+		if err != nil {
+			f.malformed(err, message)
+			continue
+		}
+		if kind == messageTrade || kind == messageBookTicker || kind == messageDepth || kind == messageDepthUpdate {
+			if !f.subscribed(msg.symbol()) {
+				atomic.AddInt64(&f.feed.unknown, 1)
+				f.logger.Debug(fmt.Sprintf("Ignoring message of unsubscribed symbol: %s", sample(message)))
+				continue
+			}
+		}
+		switch kind {
+		case messageTrade:
+			atomic.AddInt64(&f.feed.trades, 1)
+			send(f.ticks, msg.tick(), f.done)
+		case messageBookTicker:
+			atomic.AddInt64(&f.feed.quotes, 1)
+			quote, _ := msg.quote()
+			quote.Symbol = msg.symbol()
+			quote.Time = time.Now()
+			send(f.quotes, quote, f.done)
+		case messageDepth:
+			atomic.AddInt64(&f.feed.depths, 1)
+			depth, _ := msg.depth()
+			depth.Symbol = msg.symbol()
+			depth.Time = time.Now()
+			send(f.depths, depth, f.done)
+		case messageDepthUpdate:
+			atomic.AddInt64(&f.feed.depths, 1)
+			update, _ := msg.depthUpdate()
+			f.applyUpdate(update)
+		case messageMarkPrice:
+			atomic.AddInt64(&f.feed.markPrices, 1)
+			funding, _ := msg.funding()
+			send(f.funding, funding, f.done)
+		case messageSubscription:
+			atomic.AddInt64(&f.feed.subscriptions, 1)
+			if msg.ID != nil {
+				f.mutex.Lock()
+				streams, ok := f.pending[*msg.ID]
+				delete(f.pending, *msg.ID)
+				f.mutex.Unlock()
+				if ok {
+					f.logger.Info(fmt.Sprintf("Subscribed to %s", strings.Join(streams, ", ")))
+				}
+			}
+		case messageError:
+			atomic.AddInt64(&f.feed.errors, 1)
+			text := fmt.Sprintf("exchange error %d: %s", msg.Error.Code, msg.Error.Msg)
+			f.feed.lastError.Store(text)
+			f.logger.Error(fmt.Sprintf("WebSocket %s", text))
+			f.publishError(fmt.Errorf("websocket: %s", text))
+		default:
+			atomic.AddInt64(&f.feed.unknown, 1)
+			f.logger.Debug(fmt.Sprintf("Ignoring WebSocket message: %s", sample(message)))
+		}
+	}
+	close(stopPing)
+
+	// Clean up unless a reconnect already replaced this connection
+	f.mutex.Lock()
+	if f.conn == conn {
+		f.conn = nil
+		f.active = false
+	}
+	f.mutex.Unlock()
+
+	stats := f.feed.snapshot()
+	f.logger.Info("WebSocket connection closed", "messages", stats.Messages, "trades", stats.Trades,
+		"malformed", stats.Malformed, "exchange_errors", stats.Errors)
+}
+
+// subscribed reports whether a symbol is subscribed
+func (f *BinanceFeed) subscribed(symbol string) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.symbols[symbol]
+}
+
+// keepAlive pings the exchange until stop is closed
+func (f *BinanceFeed) keepAlive(conn *websocket.Conn, stop chan struct{}) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				f.logger.Debug(fmt.Sprintf("WebSocket ping failed: %v", err))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// malformed counts and logs a message that could not be used
+func (f *BinanceFeed) malformed(err error, message []byte) {
+	atomic.AddInt64(&f.feed.malformed, 1)
+	f.feed.lastMalformed.Store(fmt.Sprintf("%v: %s", err, sample(message)))
+	f.logger.Warning(fmt.Sprintf("Malformed WebSocket message: %v", err), "message", sample(message))
+}
+
+// publishError reports a market data error on the event bus
+func (f *BinanceFeed) publishError(err error) {
+	f.bus.Publish(&events.ErrorEvent{
+		Component: "market",
+		Err:       err,
+		Timestamp: time.Now(),
+	})
+}
+
+// combinedURL returns the combined stream endpoint for a raw stream
+// endpoint, whose messages name the stream they belong to
+func combinedURL(url string) string {
+	if strings.HasSuffix(url, "/ws") {
+		return strings.TrimSuffix(url, "/ws") + "/stream"
+	}
+	return url
+}
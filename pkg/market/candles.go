@@ -0,0 +1,152 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"TRADE/pkg/bars"
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// DefaultCandleHistory is the number of closed candles kept per timeframe
+// unless configured otherwise
+const DefaultCandleHistory = 500
+
+// Candle is the OHLCV bar of one interval of a timeframe
+type Candle = bars.Bar
+
+// CandleHandler is called with each candle closed, and its timeframe
+type CandleHandler func(timeframe time.Duration, candle Candle)
+
+// candleSeries builds the candles of one timeframe and keeps the last
+// closed ones
+type candleSeries struct {
+	resampler *bars.Resampler
+	closed    *rolling.Window[Candle]
+}
+
+// CandleBuilder aggregates ticks into OHLCV candles of several
+// timeframes. Candles are aligned to the Unix epoch (1m candles start on
+// the minute) and close with the first tick of the next interval.
+type CandleBuilder struct {
+	series     map[time.Duration]*candleSeries
+	timeframes []time.Duration // Shortest first
+	handlers   []CandleHandler
+	mutex      sync.RWMutex
+}
+
+// NewCandleBuilder creates a builder of the timeframes (e.g. 1s, 1m, 5m,
+// 1h), keeping history closed candles of each
+func NewCandleBuilder(timeframes []time.Duration, history int) (*CandleBuilder, error) {
+	if history <= 0 {
+		history = DefaultCandleHistory
+	}
+	b := &CandleBuilder{series: make(map[time.Duration]*candleSeries, len(timeframes))}
+	for _, timeframe := range timeframes {
+		if _, ok := b.series[timeframe]; ok {
+			return nil, fmt.Errorf("candle timeframe %s configured twice", bars.FormatInterval(timeframe))
+		}
+		resampler, err := bars.NewResampler(timeframe, false)
+		if err != nil {
+			return nil, err
+		}
+		b.series[timeframe] = &candleSeries{resampler: resampler, closed: rolling.NewWindow[Candle](history)}
+		b.timeframes = append(b.timeframes, timeframe)
+	}
+	sort.Slice(b.timeframes, func(i, j int) bool { return b.timeframes[i] < b.timeframes[j] })
+	return b, nil
+}
+
+// ParseTimeframes parses candle timeframes such as 1s, 1m, 5m and 1h
+func ParseTimeframes(values []string) ([]time.Duration, error) {
+	timeframes := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		timeframe, err := bars.ParseInterval(value)
+		if err != nil {
+			return nil, err
+		}
+		timeframes = append(timeframes, timeframe)
+	}
+	return timeframes, nil
+}
+
+// Timeframes returns the timeframes built, shortest first
+func (b *CandleBuilder) Timeframes() []time.Duration {
+	return append([]time.Duration(nil), b.timeframes...)
+}
+
+// OnClose adds a handler called with every candle closed. Handlers run on
+// the goroutine adding ticks, after the candle is in the history.
+func (b *CandleBuilder) OnClose(handler CandleHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// add adds a tick to the candles of every timeframe and returns the
+// candles it closed, by timeframe
+func (b *CandleBuilder) add(tick *types.TickData, closed []closedCandle) []closedCandle {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, timeframe := range b.timeframes {
+		series := b.series[timeframe]
+		series.resampler.Add(tick, func(candle Candle) {
+			series.closed.Push(candle)
+			closed = append(closed, closedCandle{timeframe: timeframe, candle: candle})
+		})
+	}
+	return closed
+}
+
+// closedCandle is a candle closed by a tick, waiting for the handlers
+type closedCandle struct {
+	timeframe time.Duration
+	candle    Candle
+}
+
+// emit calls the handlers with the closed candles
+func (b *CandleBuilder) emit(closed []closedCandle) {
+	if len(closed) == 0 {
+		return
+	}
+	b.mutex.RLock()
+	handlers := b.handlers
+	b.mutex.RUnlock()
+	for _, c := range closed {
+		for _, handler := range handlers {
+			handler(c.timeframe, c.candle)
+		}
+	}
+}
+
+// Candles returns the last n closed candles of a timeframe, oldest first;
+// fewer while the history is shorter
+func (b *CandleBuilder) Candles(timeframe time.Duration, n int) ([]Candle, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	series, ok := b.series[timeframe]
+	if !ok {
+		return nil, fmt.Errorf("no %s candles are built", bars.FormatInterval(timeframe))
+	}
+	length := series.closed.Len()
+	if n <= 0 || n > length {
+		n = length
+	}
+	return series.closed.View().Slice(length-n, length).AppendTo(make([]Candle, 0, n)), nil
+}
+
+// Reset drops all candles, closed and forming
+func (b *CandleBuilder) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for timeframe, series := range b.series {
+		series.resampler, _ = bars.NewResampler(timeframe, false)
+		series.closed.Reset()
+	}
+}
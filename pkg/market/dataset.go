@@ -0,0 +1,108 @@
+package market
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/types"
+)
+
+// InvalidRowError reports a dataset row that could not be parsed; reading
+// can continue with the next row
+type InvalidRowError struct {
+	Line    int
+	Message string
+}
+
+// Error returns the message
+func (e *InvalidRowError) Error() string {
+	return e.Message
+}
+
+// TickReader reads ticks from a tick dataset: a CSV file with timestamp
+// (RFC 3339 or Unix milliseconds, as recorded from the Binance stream),
+// price, volume and is_ask columns in any order
+type TickReader struct {
+	reader *csv.Reader
+	line   int
+	// Column indices
+	timestamp, price, volume, isAsk int
+}
+
+// NewTickReader reads the header of a tick dataset
+func NewTickReader(r io.Reader) (*TickReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	tr := &TickReader{reader: reader, line: 1, timestamp: -1, price: -1, volume: -1, isAsk: -1}
+	for i, col := range header {
+		switch strings.ToLower(col) {
+		case "timestamp":
+			tr.timestamp = i
+		case "price":
+			tr.price = i
+		case "volume":
+			tr.volume = i
+		case "is_ask":
+			tr.isAsk = i
+		}
+	}
+	if tr.timestamp == -1 || tr.price == -1 || tr.volume == -1 || tr.isAsk == -1 {
+		return nil, fmt.Errorf("missing required columns in CSV file")
+	}
+	return tr, nil
+}
+
+// Read fills tick with the next row. It returns io.EOF after the last row
+// and an *InvalidRowError for a row that cannot be parsed.
+func (tr *TickReader) Read(tick *types.TickData) error {
+	row, err := tr.reader.Read()
+	if err != nil {
+		return err
+	}
+	tr.line++
+
+	timestamp, err := parseTimestamp(row[tr.timestamp])
+	if err != nil {
+		return tr.invalid("Invalid timestamp format: %s", row[tr.timestamp])
+	}
+	price, err := strconv.ParseFloat(row[tr.price], 64)
+	if err != nil {
+		return tr.invalid("Invalid price: %s", row[tr.price])
+	}
+	volume, err := strconv.ParseFloat(row[tr.volume], 64)
+	if err != nil {
+		return tr.invalid("Invalid volume: %s", row[tr.volume])
+	}
+	isAsk, err := strconv.ParseBool(row[tr.isAsk])
+	if err != nil {
+		return tr.invalid("Invalid is_ask value: %s", row[tr.isAsk])
+	}
+
+	tick.Price = price
+	tick.Volume = volume
+	tick.IsAsk = isAsk
+	tick.Timestamp = timestamp.UTC()
+	return nil
+}
+
+// parseTimestamp parses an RFC 3339 time or a Unix time in milliseconds
+func parseTimestamp(value string) (time.Time, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// invalid returns an InvalidRowError for the current line
+func (tr *TickReader) invalid(format string, args ...interface{}) error {
+	return &InvalidRowError{Line: tr.line, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,177 @@
+package market
+
+import (
+	"fmt"
+	"time"
+)
+
+// Depth levels the partial book depth stream is available with
+var depthLevels = map[int]bool{5: true, 10: true, 20: true}
+
+// ValidDepthLevels reports whether the partial book depth stream carries
+// the given number of levels
+func ValidDepthLevels(levels int) bool {
+	return depthLevels[levels]
+}
+
+// The streams send a book snapshot, or an update of the local book, every
+// 100ms. A snapshot is used for depthStaleAfter after it was received, and
+// the imbalance of the last bookSamples snapshots (a minute) is kept for
+// its trend.
+const (
+	depthStaleAfter = 5 * time.Second
+	bookSamples     = 600
+)
+
+// Level is a price level of the order book
+type Level struct {
+	Price    float64
+	Quantity float64
+}
+
+// Depth is a snapshot of the top levels of the order book, best first
+type Depth struct {
+	Symbol string // Lower-case symbol
+	Bids   []Level
+	Asks   []Level
+	Time   time.Time // Wall-clock time the snapshot was received
+}
+
+// Imbalance returns the share of the bid quantity in the quantity of all
+// levels, from 0 (only asks) to 1 (only bids); 0.5 for an empty book.
+// It is comparable to the trade-flow order imbalance of the analyzer.
+func (d Depth) Imbalance() float64 {
+	bids, asks := 0.0, 0.0
+	for _, level := range d.Bids {
+		bids += level.Quantity
+	}
+	for _, level := range d.Asks {
+		asks += level.Quantity
+	}
+	if bids+asks == 0 {
+		return 0.5
+	}
+	return bids / (bids + asks)
+}
+
+// BookStats are the metrics of a book snapshot
+type BookStats struct {
+	// SpreadBps is the best ask minus the best bid in basis points of
+	// their midpoint
+	SpreadBps float64
+	// TopImbalance is the bid share of the quantity at the best bid and
+	// ask, from 0 to 1
+	TopImbalance float64
+	// WeightedMid is the mid price weighted by depth: the average prices
+	// of the bid and ask levels, each weighted by the quantity of the
+	// other side, so it leans towards the thinner side
+	WeightedMid float64
+}
+
+// neutralBookStats are the stats without a book: no spread, balanced and
+// no price
+var neutralBookStats = BookStats{TopImbalance: 0.5}
+
+// Stats returns the metrics of the snapshot; neutral if a side is empty
+func (d Depth) Stats() BookStats {
+	if len(d.Bids) == 0 || len(d.Asks) == 0 {
+		return neutralBookStats
+	}
+	bid, ask := d.Bids[0], d.Asks[0]
+	stats := BookStats{TopImbalance: 0.5}
+	if mid := (bid.Price + ask.Price) / 2; mid > 0 {
+		stats.SpreadBps = (ask.Price - bid.Price) / mid * 10000
+	}
+	if top := bid.Quantity + ask.Quantity; top > 0 {
+		stats.TopImbalance = bid.Quantity / top
+	}
+
+	bidQuantity, bidNotional := 0.0, 0.0
+	for _, level := range d.Bids {
+		bidQuantity += level.Quantity
+		bidNotional += level.Price * level.Quantity
+	}
+	askQuantity, askNotional := 0.0, 0.0
+	for _, level := range d.Asks {
+		askQuantity += level.Quantity
+		askNotional += level.Price * level.Quantity
+	}
+	if bidQuantity > 0 && askQuantity > 0 {
+		bidPrice, askPrice := bidNotional/bidQuantity, askNotional/askQuantity
+		stats.WeightedMid = (bidPrice*askQuantity + askPrice*bidQuantity) / (bidQuantity + askQuantity)
+	} else {
+		stats.WeightedMid = (bid.Price + ask.Price) / 2
+	}
+	return stats
+}
+
+// bookSample is the imbalance of one book snapshot
+type bookSample struct {
+	imbalance float64
+	time      time.Time
+}
+
+// SetDepth records a book snapshot, its metrics and its imbalance
+func (md *MarketData) SetDepth(depth Depth) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.depth = depth
+	md.bookStats = depth.Stats()
+	md.bookImbalance.Push(bookSample{imbalance: depth.Imbalance(), time: depth.Time})
+}
+
+// BookStats returns the metrics of the last book snapshot; neutral, and
+// ok false, without a recent snapshot
+func (md *MarketData) BookStats() (stats BookStats, ok bool) {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	if md.depth.Time.IsZero() || time.Since(md.depth.Time) > depthStaleAfter {
+		return neutralBookStats, false
+	}
+	return md.bookStats, true
+}
+
+// Depth returns the last book snapshot; ok is false if none was received
+func (md *MarketData) Depth() (depth Depth, ok bool) {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	return md.depth, !md.depth.Time.IsZero()
+}
+
+// BookImbalance returns the imbalance of the last book snapshot and its
+// trend: how far it is above the mean imbalance of the snapshots received
+// within window before it, which is capped at the last minute of
+// snapshots. ok is false without a recent snapshot.
+func (md *MarketData) BookImbalance(window time.Duration) (imbalance, trend float64, ok bool) {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+
+	n := md.bookImbalance.Len()
+	if n == 0 {
+		return 0.5, 0, false
+	}
+	last := md.bookImbalance.Last()
+	if time.Since(last.time) > depthStaleAfter {
+		return 0.5, 0, false
+	}
+
+	since := last.time.Add(-window)
+	sum, count := 0.0, 0
+	for i := n - 2; i >= 0; i-- {
+		sample := md.bookImbalance.At(i)
+		if !sample.time.After(since) {
+			break
+		}
+		sum += sample.imbalance
+		count++
+	}
+	if count == 0 {
+		return last.imbalance, 0, true
+	}
+	return last.imbalance, last.imbalance - sum/float64(count), true
+}
+
+// depthStream returns the partial book depth stream of a symbol
+func depthStream(symbol string, levels int) string {
+	return fmt.Sprintf("%s@depth%d@100ms", symbol, levels)
+}
@@ -0,0 +1,77 @@
+package market
+
+import (
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// feedBuffer is the capacity of the channels a feed delivers on; a full
+// channel holds the feed back until the stream catches up
+const feedBuffer = 1024
+
+// Feed is a live market data connection to an exchange. A Stream routes
+// the ticks of a feed to the MarketData of their symbols, so a connector
+// only deals with its exchange's protocol.
+//
+// Connect opens the connection in the background; the feed keeps
+// delivering until Close. Subscribe adds symbols, on the open connection
+// or when it is opened. Ticks delivers the trades of all subscribed
+// symbols and is closed by Close.
+type Feed interface {
+	Connect() error
+	Subscribe(symbols ...string) error
+	Ticks() <-chan Tick
+	Close() error
+}
+
+// Tick is a trade delivered by a feed
+type Tick struct {
+	Symbol string // Lower-case symbol
+	types.TickData
+}
+
+// QuoteFeed is a feed that also delivers the best bid and ask of its
+// symbols. Quotes is closed by Close.
+type QuoteFeed interface {
+	Feed
+	Quotes() <-chan Quote
+}
+
+// DepthFeed is a feed that can also deliver the top levels of the order
+// book of its symbols. SetDepth selects the number of levels before the
+// connection is opened; 0 disables depth. Depths is closed by Close.
+type DepthFeed interface {
+	Feed
+	SetDepth(levels int) error
+	Depths() <-chan Depth
+}
+
+// OrderBookFeed is a depth feed that can keep a local order book of its
+// symbols from the exchange's incremental updates, delivering the top
+// levels of the book as its depth on every update. SetOrderBook is called
+// before the connection is opened, instead of SetDepth.
+type OrderBookFeed interface {
+	DepthFeed
+	SetOrderBook(settings OrderBookSettings) error
+}
+
+// FundingFeed is a feed that also delivers the mark price and funding
+// rate of perpetual contracts. Funding is closed by Close.
+type FundingFeed interface {
+	Feed
+	Funding() <-chan *events.FundingEvent
+}
+
+// MonitoredFeed is a feed that reports the state of its connection
+type MonitoredFeed interface {
+	Feed
+	Connected() bool
+	FeedStats() FeedStats
+}
+
+// ReconnectingFeed is a feed whose connection can be replaced, e.g. by
+// the watchdog when it goes stale
+type ReconnectingFeed interface {
+	Feed
+	Reconnect() error
+}
@@ -0,0 +1,139 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/types"
+)
+
+// DefaultInstrumentsURL is Binance's spot exchange metadata endpoint
+const DefaultInstrumentsURL = "https://api.binance.com/api/v3/exchangeInfo"
+
+// DefaultFuturesInstrumentsURL is Binance's USDⓈ-M futures exchange
+// metadata endpoint
+const DefaultFuturesInstrumentsURL = "https://fapi.binance.com/fapi/v1/exchangeInfo"
+
+// Instruments is the registry of the symbols' instruments: the price and
+// quantity steps prices, stops and orders are rounded to
+type Instruments struct {
+	instruments map[string]*types.Instrument // By lower-case symbol
+	mutex       sync.RWMutex
+}
+
+// NewInstruments creates an empty registry
+func NewInstruments() *Instruments {
+	return &Instruments{instruments: make(map[string]*types.Instrument)}
+}
+
+// Add registers an instrument, replacing the one of its symbol
+func (r *Instruments) Add(instrument *types.Instrument) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.instruments[strings.ToLower(instrument.Symbol)] = instrument
+}
+
+// Get returns the instrument of a symbol, if registered
+func (r *Instruments) Get(symbol string) (*types.Instrument, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	instrument, ok := r.instruments[strings.ToLower(symbol)]
+	return instrument, ok
+}
+
+// Symbols returns the symbols registered, sorted
+func (r *Instruments) Symbols() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	symbols := make([]string, 0, len(r.instruments))
+	for symbol := range r.instruments {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// binanceExchangeInfo is the part of Binance's exchange metadata holding
+// the symbols' filters
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol  string `json:"symbol"`
+		Filters []struct {
+			FilterType string `json:"filterType"`
+			TickSize   string `json:"tickSize"`
+			StepSize   string `json:"stepSize"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+// FetchBinance registers the instruments of the symbols from the exchange
+// metadata at endpoint: the tick size of their PRICE_FILTER and the step
+// size of their LOT_SIZE filter. It returns the symbols registered; those
+// the exchange does not list are left out.
+func (r *Instruments) FetchBinance(endpoint string, symbols []string) ([]string, error) {
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(symbols))
+	upper := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		upper[i] = strings.ToUpper(symbol)
+		wanted[upper[i]] = true
+	}
+	// The spot endpoint filters by symbol; the futures one lists them all
+	requestURL := endpoint
+	if strings.Contains(endpoint, "/api/v3/") {
+		query, err := json.Marshal(upper)
+		if err != nil {
+			return nil, err
+		}
+		requestURL += "?symbols=" + url.QueryEscape(string(query))
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange metadata returned %s", resp.Status)
+	}
+	var info binanceExchangeInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid exchange metadata: %v", err)
+	}
+
+	var registered []string
+	for _, entry := range info.Symbols {
+		if !wanted[entry.Symbol] {
+			continue
+		}
+		var tickSize, lotSize decimal.Decimal
+		for _, filter := range entry.Filters {
+			switch filter.FilterType {
+			case "PRICE_FILTER":
+				tickSize, err = decimal.Parse(filter.TickSize)
+			case "LOT_SIZE":
+				lotSize, err = decimal.Parse(filter.StepSize)
+			}
+			if err != nil {
+				return registered, fmt.Errorf("invalid %s filter of %s: %v", filter.FilterType, entry.Symbol, err)
+			}
+		}
+		if tickSize.Sign() <= 0 || lotSize.Sign() <= 0 {
+			return registered, fmt.Errorf("exchange metadata of %s has no tick or lot size", entry.Symbol)
+		}
+		symbol := strings.ToLower(entry.Symbol)
+		r.Add(types.NewInstrument(symbol, tickSize, lotSize))
+		registered = append(registered, symbol)
+	}
+	return registered, nil
+}
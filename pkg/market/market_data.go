@@ -1,90 +1,139 @@
 package market
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
 	"TRADE/pkg/logger"
+	"TRADE/pkg/rolling"
 	"TRADE/pkg/types"
 )
 
-// TickCallback is a function that gets called when new market data is received
-type TickCallback func(tick *types.TickData)
+// HistorySize is the number of ticks the market data keeps
+const HistorySize = 1000
 
 // MarketData handles market data acquisition and storage
 type MarketData struct {
 	// Data storage
-	priceHistory []float64
-	volumeHistory []float64
-	bidVolume []float64
-	askVolume []float64
-	timeStamps []time.Time
-	highPrices []float64
-	lowPrices []float64
-	
+	priceHistory  *rolling.Stats
+	volumeHistory *rolling.Window[float64]
+	bidVolume     *rolling.Stats
+	askVolume     *rolling.Stats
+	timeStamps    *rolling.Window[time.Time]
+	highPrices    *rolling.Window[float64]
+	lowPrices     *rolling.Window[float64]
+
 	// Configuration
-	maxSize int
-	roundNum int
-	prevPrice float64
-	
-	// Websocket connection for live data
-	wsConn *websocket.Conn
-	wsActive bool
-	symbols []string
-	
-	// Callback for new data
-	tickCallback TickCallback
-	
+	maxSize      int
+	roundNum     int
+	tickSize     float64 // Price step of the instrument; 0 without metadata
+	prevPrice    float64
+	count        int64     // Ticks stored since the last reset
+	lastTickTime time.Time // Wall-clock time the last tick was received
+
+	// Symbol of the ticks, and the live stream delivering them
+	symbol string
+	stream *Stream
+
+	// Event bus that receives new ticks
+	bus *events.Bus
+
+	// Best bid and ask from the book ticker stream
+	quote Quote
+
+	// Top levels from the book depth stream, and the imbalance of the
+	// recent snapshots
+	depth         Depth
+	bookStats     BookStats
+	bookImbalance *rolling.Window[bookSample]
+
+	// Candles built from the ticks; nil if none are
+	candles *CandleBuilder
+
 	// Utilities
-	logger *logger.Logger
-	mutex sync.RWMutex
+	logger logger.Interface
+	mutex  sync.RWMutex
 }
 
-// NewMarketData creates a new market data handler
-func NewMarketData(log *logger.Logger) *MarketData {
+// NewMarketData creates a market data handler for the ticks of a symbol,
+// publishing them on the bus
+func NewMarketData(symbol string, log logger.Interface, bus *events.Bus) *MarketData {
 	return &MarketData{
-		priceHistory: make([]float64, 0, 1000),
-		volumeHistory: make([]float64, 0, 1000),
-		bidVolume: make([]float64, 0, 1000),
-		askVolume: make([]float64, 0, 1000),
-		timeStamps: make([]time.Time, 0, 1000),
-		highPrices: make([]float64, 0, 1000),
-		lowPrices: make([]float64, 0, 1000),
-		maxSize: 1000,
-		wsActive: false,
-		logger: log,
+		priceHistory:  rolling.NewStats(HistorySize),
+		volumeHistory: rolling.NewWindow[float64](HistorySize),
+		bidVolume:     rolling.NewStats(HistorySize),
+		askVolume:     rolling.NewStats(HistorySize),
+		timeStamps:    rolling.NewWindow[time.Time](HistorySize),
+		highPrices:    rolling.NewWindow[float64](HistorySize),
+		lowPrices:     rolling.NewWindow[float64](HistorySize),
+		bookImbalance: rolling.NewWindow[bookSample](bookSamples),
+		maxSize:       HistorySize,
+		symbol:        strings.ToLower(symbol),
+		bus:           bus,
+		logger:        log,
 	}
 }
 
-// SetTickCallback sets the callback function for new market data
-func (md *MarketData) SetTickCallback(callback TickCallback) {
+// Symbol returns the lower-case symbol of the market data
+func (md *MarketData) Symbol() string {
+	return md.symbol
+}
+
+// setStream records the live stream the market data is fed by
+func (md *MarketData) setStream(stream *Stream) {
 	md.mutex.Lock()
 	defer md.mutex.Unlock()
-	md.tickCallback = callback
+	md.stream = stream
 }
 
-// AddTick adds a new tick to the market data
+// AddTick adds a new tick to the market data and publishes it on the bus.
+// It takes ownership of the tick and releases it to the pool once all
+// subscribers have run (see NewTick).
 func (md *MarketData) AddTick(tick *types.TickData) {
+	md.addTick(tick, 0)
+}
+
+// addTick adds a tick with backlog ticks of the live stream queued behind
+// it, which the tick event carries
+func (md *MarketData) addTick(tick *types.TickData, backlog int) {
+	md.storeTick(tick)
+
+	// Candles close before the tick is published, so indicators defined
+	// on them are current when subscribers see it
+	md.mutex.RLock()
+	candles := md.candles
+	md.mutex.RUnlock()
+	if candles != nil {
+		candles.emit(candles.add(tick, nil))
+	}
+
+	// Publish outside the lock so subscribers can read the market data
+	md.bus.Publish(&events.TickEvent{Symbol: md.symbol, Tick: tick, Backlog: backlog})
+	releaseTick(tick)
+}
+
+// storeTick records a tick in the histories
+func (md *MarketData) storeTick(tick *types.TickData) {
 	md.mutex.Lock()
 	defer md.mutex.Unlock()
-	
+
 	price := tick.Price
 	volume := tick.Volume
 	isAsk := tick.IsAsk
 	timestamp := tick.Timestamp
-	
-	// Determine rounding precision if not set
-	if md.roundNum == 0 {
+
+	// Without instrument metadata, guess the rounding precision from the
+	// first price
+	if md.roundNum == 0 && md.tickSize == 0 {
 		priceStr := fmt.Sprintf("%f", price)
 		parts := strings.Split(priceStr, ".")
 		if len(parts) > 1 {
@@ -95,387 +144,364 @@ func (md *MarketData) AddTick(tick *types.TickData) {
 		}
 		md.prevPrice = md.round(price)
 	}
-	
-	// Round price to appropriate precision
+
+	// Round price to the instrument's ticks
 	price = md.round(price)
-	
-	// Add data to histories with capacity management
-	md.addToLimitedSlice(&md.priceHistory, price)
-	md.addToLimitedSlice(&md.volumeHistory, volume)
-	md.addToLimitedSlice(&md.timeStamps, timestamp)
-	
+	md.lastTickTime = time.Now()
+	md.count++
+
+	// Add data to histories; full windows drop their oldest value
+	md.priceHistory.Push(price)
+	md.volumeHistory.Push(volume)
+	md.timeStamps.Push(timestamp)
+
 	// Update high and low prices
-	if len(md.highPrices) == 0 || price > md.highPrices[len(md.highPrices)-1] {
-		md.addToLimitedSlice(&md.highPrices, price)
+	if md.highPrices.Len() == 0 || price > md.highPrices.Last() {
+		md.highPrices.Push(price)
 	} else {
-		md.addToLimitedSlice(&md.highPrices, md.highPrices[len(md.highPrices)-1])
+		md.highPrices.Push(md.highPrices.Last())
 	}
-	
-	if len(md.lowPrices) == 0 || price < md.lowPrices[len(md.lowPrices)-1] {
-		md.addToLimitedSlice(&md.lowPrices, price)
+
+	if md.lowPrices.Len() == 0 || price < md.lowPrices.Last() {
+		md.lowPrices.Push(price)
 	} else {
-		md.addToLimitedSlice(&md.lowPrices, md.lowPrices[len(md.lowPrices)-1])
+		md.lowPrices.Push(md.lowPrices.Last())
 	}
-	
+
 	// Update volume data
 	if isAsk {
-		md.addToLimitedSlice(&md.askVolume, volume)
+		md.askVolume.Push(volume)
 	} else {
-		md.addToLimitedSlice(&md.bidVolume, volume)
-	}
-	
-	// Call the callback if set
-	if md.tickCallback != nil {
-		md.tickCallback(tick)
+		md.bidVolume.Push(volume)
 	}
 }
 
-// Helper method to add to a slice with capacity management
-func (md *MarketData) addToLimitedSlice(slice interface{}, value interface{}) {
-	switch s := slice.(type) {
-	case *[]float64:
-		if len(*s) >= md.maxSize {
-			*s = append((*s)[1:], value.(float64))
-		} else {
-			*s = append(*s, value.(float64))
-		}
-	case *[]time.Time:
-		if len(*s) >= md.maxSize {
-			*s = append((*s)[1:], value.(time.Time))
-		} else {
-			*s = append(*s, value.(time.Time))
-		}
-	}
-}
-
-// Helper function to round a float to the current precision
+// Helper function to round a float to the tick size, then the current
+// precision
 func (md *MarketData) round(num float64) float64 {
+	if md.tickSize > 0 {
+		num = math.Round(num/md.tickSize) * md.tickSize
+	}
 	shift := math.Pow(10, float64(md.roundNum))
 	return math.Round(num*shift) / shift
 }
 
+// SetInstrument rounds the prices of the ticks to the instrument's tick
+// size instead of a precision guessed from the first price
+func (md *MarketData) SetInstrument(instrument *types.Instrument) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.tickSize = instrument.TickSize.Float64()
+	md.roundNum = instrument.PricePrecision()
+}
+
+// SetCandles makes the market data build candles from its ticks
+func (md *MarketData) SetCandles(candles *CandleBuilder) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.candles = candles
+}
+
+// CandleBuilder returns the builder of the candles; nil if none are built
+func (md *MarketData) CandleBuilder() *CandleBuilder {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	return md.candles
+}
+
+// GetCandles returns the last n closed candles of a timeframe, oldest
+// first
+func (md *MarketData) GetCandles(timeframe time.Duration, n int) ([]Candle, error) {
+	candles := md.CandleBuilder()
+	if candles == nil {
+		return nil, fmt.Errorf("no candles are built")
+	}
+	return candles.Candles(timeframe, n)
+}
+
 // GetCurrentPrice returns the most recent price
 func (md *MarketData) GetCurrentPrice() float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	if len(md.priceHistory) == 0 {
-		return 0
-	}
-	return md.priceHistory[len(md.priceHistory)-1]
+
+	return md.priceHistory.Last()
+}
+
+// Series holds read-only views of the market data buffers
+type Series struct {
+	Prices     rolling.View[float64]
+	Volumes    rolling.View[float64]
+	BidVolumes rolling.View[float64]
+	AskVolumes rolling.View[float64]
+	HighPrices rolling.View[float64]
+	LowPrices  rolling.View[float64]
+	Timestamps rolling.View[time.Time]
+
+	// Totals of BidVolumes and AskVolumes, kept as ticks are added
+	BidVolume float64
+	AskVolume float64
+
+	// Count is the number of ticks stored since the last reset, so callers
+	// keeping their own state can tell how many prices are new
+	Count int64
 }
 
-// GetPriceArray returns the price history as a slice
+// ReadSeries calls fn with views of the buffers under the read lock, so
+// metrics can be computed over them without copying. The views are only
+// valid during fn, which must not keep them, modify them or call methods
+// that lock the market data. The series is passed by value so reading it
+// allocates nothing.
+func (md *MarketData) ReadSeries(fn func(series Series)) {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+
+	fn(Series{
+		Prices:     md.priceHistory.View(),
+		Volumes:    md.volumeHistory.View(),
+		BidVolumes: md.bidVolume.View(),
+		AskVolumes: md.askVolume.View(),
+		HighPrices: md.highPrices.View(),
+		LowPrices:  md.lowPrices.View(),
+		Timestamps: md.timeStamps.View(),
+		BidVolume:  md.bidVolume.Sum(),
+		AskVolume:  md.askVolume.Sum(),
+		Count:      md.count,
+	})
+}
+
+// GetPriceArray returns a copy of the price history; prefer ReadSeries on
+// hot paths
 func (md *MarketData) GetPriceArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.priceHistory))
-	copy(result, md.priceHistory)
-	return result
+
+	return md.priceHistory.Values()
 }
 
 // GetVolumeArray returns the volume history as a slice
 func (md *MarketData) GetVolumeArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.volumeHistory))
-	copy(result, md.volumeHistory)
-	return result
+
+	return md.volumeHistory.Values()
 }
 
 // GetBidVolumeArray returns the bid volume history as a slice
 func (md *MarketData) GetBidVolumeArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.bidVolume))
-	copy(result, md.bidVolume)
-	return result
+
+	return md.bidVolume.Values()
 }
 
 // GetAskVolumeArray returns the ask volume history as a slice
 func (md *MarketData) GetAskVolumeArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.askVolume))
-	copy(result, md.askVolume)
-	return result
+
+	return md.askVolume.Values()
 }
 
 // GetHighPricesArray returns the high prices history as a slice
 func (md *MarketData) GetHighPricesArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.highPrices))
-	copy(result, md.highPrices)
-	return result
+
+	return md.highPrices.Values()
 }
 
 // GetLowPricesArray returns the low prices history as a slice
 func (md *MarketData) GetLowPricesArray() []float64 {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	result := make([]float64, len(md.lowPrices))
-	copy(result, md.lowPrices)
-	return result
+
+	return md.lowPrices.Values()
+}
+
+// Summary describes the state of the market data buffers
+type Summary struct {
+	Symbol       string
+	Connected    bool
+	Ticks        int
+	Capacity     int
+	CurrentPrice float64
+	MinPrice     float64
+	MaxPrice     float64
+	FirstTick    time.Time
+	LastTick     time.Time
+	LastReceived time.Time
+	Precision    int
+	TickSize     float64 // 0 while Precision is guessed from the prices
+	Feed         FeedStats
+}
+
+// GetSummary returns a summary of the buffered market data
+func (md *MarketData) GetSummary() Summary {
+	md.mutex.RLock()
+	summary := Summary{
+		Symbol:       md.symbol,
+		Ticks:        md.priceHistory.Len(),
+		Capacity:     md.maxSize,
+		LastReceived: md.lastTickTime,
+		Precision:    md.roundNum,
+		TickSize:     md.tickSize,
+	}
+
+	if md.priceHistory.Len() > 0 {
+		summary.CurrentPrice = md.priceHistory.Last()
+		summary.MinPrice = md.priceHistory.Min()
+		summary.MaxPrice = md.priceHistory.Max()
+	}
+	if md.timeStamps.Len() > 0 {
+		summary.FirstTick = md.timeStamps.First()
+		summary.LastTick = md.timeStamps.Last()
+	}
+	stream := md.stream
+	md.mutex.RUnlock()
+
+	// Ask the stream outside the lock, which Stream.Add takes under its own
+	if stream != nil {
+		summary.Connected = stream.Connected()
+		summary.Feed = stream.FeedStats()
+	}
+	return summary
 }
 
 // HasMinimumData checks if we have enough data for analysis
 func (md *MarketData) HasMinimumData(minTicks int) bool {
 	md.mutex.RLock()
 	defer md.mutex.RUnlock()
-	
-	return len(md.priceHistory) >= minTicks
+
+	return md.priceHistory.Len() >= minTicks
 }
 
 // Reset clears all market data
 func (md *MarketData) Reset() {
 	md.mutex.Lock()
 	defer md.mutex.Unlock()
-	
-	md.priceHistory = md.priceHistory[:0]
-	md.volumeHistory = md.volumeHistory[:0]
-	md.bidVolume = md.bidVolume[:0]
-	md.askVolume = md.askVolume[:0]
-	md.timeStamps = md.timeStamps[:0]
-	md.highPrices = md.highPrices[:0]
-	md.lowPrices = md.lowPrices[:0]
+
+	md.priceHistory.Reset()
+	md.volumeHistory.Reset()
+	md.bidVolume.Reset()
+	md.askVolume.Reset()
+	md.timeStamps.Reset()
+	md.highPrices.Reset()
+	md.lowPrices.Reset()
 	md.prevPrice = 0
-	md.roundNum = 0
+	if md.tickSize == 0 {
+		md.roundNum = 0 // Guessed again from the next price
+	}
+	md.count = 0
+	md.lastTickTime = time.Time{}
+	md.quote = Quote{}
+	md.depth = Depth{}
+	md.bookStats = BookStats{}
+	md.bookImbalance.Reset()
+	if md.candles != nil {
+		md.candles.Reset()
+	}
 }
 
-// ConnectLive connects to live market data via WebSocket
-func (md *MarketData) ConnectLive(symbols []string) error {
-	md.mutex.Lock()
-	defer md.mutex.Unlock()
-	
-	if md.wsActive {
-		return fmt.Errorf("already connected to market data")
+// FeedStats returns the message counts of the live stream feeding the
+// market data; zero if it is not streamed
+func (md *MarketData) FeedStats() FeedStats {
+	md.mutex.RLock()
+	stream := md.stream
+	md.mutex.RUnlock()
+
+	if stream == nil {
+		return FeedStats{}
 	}
-	
-	md.symbols = symbols
-	
-	// Start WebSocket connection in a goroutine
-	go md.startWebSocketConnection()
-	
-	return nil
+	return stream.FeedStats()
 }
 
-// startWebSocketConnection establishes and maintains the WebSocket connection
-func (md *MarketData) startWebSocketConnection() {
-	if len(md.symbols) == 0 {
-		md.logger.Error("No symbols specified for WebSocket connection")
-		return
-	}
-	
-	symbol := md.symbols[0]
-	url := fmt.Sprintf("wss://stream.binance.com:9443/ws/%s@trade", strings.ToLower(symbol))
-	
-	md.logger.Info(fmt.Sprintf("Connecting to %s", url))
-	
-	// Connect to WebSocket
-	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
-	if err != nil {
-		md.logger.Error(fmt.Sprintf("WebSocket connection error: %v", err))
-		return
-	}
-	
-	md.mutex.Lock()
-	md.wsConn = conn
-	md.wsActive = true
-	md.mutex.Unlock()
-	
-	md.logger.Info("WebSocket connection established")
-	
-	// Handle incoming messages
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			md.logger.Error(fmt.Sprintf("WebSocket read error: %v", err))
-			break
-		}
-		
-		// Parse message
-		var data map[string]interface{}
-		if err := json.Unmarshal(message, &data); err != nil {
-			md.logger.Error(fmt.Sprintf("JSON parse error: %v", err))
-			continue
-		}
-		
-		// Extract and normalize data
-		price, _ := data["p"].(string)
-		quantity, _ := data["q"].(string)
-		isMaker, _ := data["m"].(bool)
-		timestampMs, _ := data["T"].(float64)
-		
-		// Convert to appropriate types
-		priceFloat, err := strconv.ParseFloat(price, 64)
-		if err != nil {
-			md.logger.Error(fmt.Sprintf("Price parse error: %v", err))
-			continue
-		}
-		
-		quantityFloat, err := strconv.ParseFloat(quantity, 64)
-		if err != nil {
-			md.logger.Error(fmt.Sprintf("Quantity parse error: %v", err))
-			continue
-		}
-		
-		timestamp := time.Unix(0, int64(timestampMs)*int64(time.Millisecond))
-		
-		// Create tick data
-		tick := &types.TickData{
-			Price:     priceFloat,
-			Volume:    quantityFloat,
-			IsAsk:     !isMaker,
-			Timestamp: timestamp,
-		}
-		
-		// Add tick to market data
-		md.AddTick(tick)
-	}
-	
-	// Clean up
-	md.mutex.Lock()
-	md.wsConn = nil
-	md.wsActive = false
-	md.mutex.Unlock()
-	
-	md.logger.Info("WebSocket connection closed")
+// LastTickTime returns the wall-clock time the last tick was received
+func (md *MarketData) LastTickTime() time.Time {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	return md.lastTickTime
 }
 
-// Disconnect closes the WebSocket connection
-func (md *MarketData) Disconnect() {
-	md.mutex.Lock()
-	defer md.mutex.Unlock()
-	
-	if md.wsConn != nil {
-		md.wsConn.Close()
-		md.wsConn = nil
+// LastTimestamp returns the market time of the last tick, which is in the
+// past when replaying historical data
+func (md *MarketData) LastTimestamp() time.Time {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	if md.timeStamps.Len() == 0 {
+		return time.Time{}
 	}
-	
-	md.wsActive = false
+	return md.timeStamps.Last()
 }
 
 // GetAvailableDatasets returns a list of available historical datasets
 func (md *MarketData) GetAvailableDatasets() ([]string, error) {
 	dataDir := "data"
-	
+
 	// Check if data directory exists
 	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("data directory does not exist")
 	}
-	
+
 	// Find all CSV files in the data directory
 	files, err := ioutil.ReadDir(dataDir)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	var datasets []string
 	for _, file := range files {
 		if !file.IsDir() && strings.HasSuffix(file.Name(), ".csv") {
 			datasets = append(datasets, filepath.Join(dataDir, file.Name()))
 		}
 	}
-	
+
 	return datasets, nil
 }
 
 // LoadHistoricalData loads and processes historical data from a CSV file
 func (md *MarketData) LoadHistoricalData(filePath string) error {
 	md.logger.Info(fmt.Sprintf("Loading historical data from %s", filePath))
-	
+
 	// Reset current data
 	md.Reset()
-	
+
 	// Open the CSV file
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %v", err)
 	}
 	defer file.Close()
-	
-	// Create a CSV reader
-	reader := csv.NewReader(file)
-	
+
 	// Read the header
-	header, err := reader.Read()
+	ticks, err := NewTickReader(file)
 	if err != nil {
-		return fmt.Errorf("failed to read header: %v", err)
+		return err
 	}
-	
-	// Find column indices
-	timestampIdx, priceIdx, volumeIdx, isAskIdx := -1, -1, -1, -1
-	for i, col := range header {
-		switch strings.ToLower(col) {
-		case "timestamp":
-			timestampIdx = i
-		case "price":
-			priceIdx = i
-		case "volume":
-			volumeIdx = i
-		case "is_ask":
-			isAskIdx = i
-		}
-	}
-	
-	// Check if all required columns are found
-	if timestampIdx == -1 || priceIdx == -1 || volumeIdx == -1 || isAskIdx == -1 {
-		return fmt.Errorf("missing required columns in CSV file")
-	}
-	
+
 	// Read and process each row
 	lineCount := 0
 	for {
-		row, err := reader.Read()
-		if err != nil {
-			break // End of file or error
-		}
-		
-		// Parse values
-		timestamp, err := time.Parse(time.RFC3339, row[timestampIdx])
-		if err != nil {
-			md.logger.Warning(fmt.Sprintf("Invalid timestamp format: %s", row[timestampIdx]))
+		// Fill a pooled tick; AddTick releases it
+		tick := NewTick()
+		err := ticks.Read(tick)
+		var invalid *InvalidRowError
+		if errors.As(err, &invalid) {
+			releaseTick(tick)
+			md.logger.Warning(invalid.Message)
 			continue
 		}
-		
-		price, err := strconv.ParseFloat(row[priceIdx], 64)
 		if err != nil {
-			md.logger.Warning(fmt.Sprintf("Invalid price: %s", row[priceIdx]))
-			continue
-		}
-		
-		volume, err := strconv.ParseFloat(row[volumeIdx], 64)
-		if err != nil {
-			md.logger.Warning(fmt.Sprintf("Invalid volume: %s", row[volumeIdx]))
-			continue
-		}
-		
-		isAsk, err := strconv.ParseBool(row[isAskIdx])
-		if err != nil {
-			md.logger.Warning(fmt.Sprintf("Invalid is_ask value: %s", row[isAskIdx]))
-			continue
-		}
-		
-		// Create tick data
-		tick := &types.TickData{
-			Price:     price,
-			Volume:    volume,
-			IsAsk:     isAsk,
-			Timestamp: timestamp,
+			releaseTick(tick)
+			break // End of file or error
 		}
-		
-		// Add tick to market data
+
 		md.AddTick(tick)
 		lineCount++
 	}
-	
+
 	md.logger.Info(fmt.Sprintf("Loaded %d historical data points", lineCount))
+	if lineCount == 0 {
+		return errs.Errorf(errs.ErrInsufficientData, "", "no valid rows in %s", filePath)
+	}
 	return nil
-}
\ No newline at end of file
+}
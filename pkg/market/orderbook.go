@@ -0,0 +1,319 @@
+package market
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/errs"
+)
+
+// Default Binance REST endpoints of the order book snapshots the local
+// books are synced from
+const (
+	DefaultDepthSnapshotURL        = "https://api.binance.com/api/v3/depth"
+	DefaultFuturesDepthSnapshotURL = "https://fapi.binance.com/fapi/v1/depth"
+)
+
+// Order book syncing: the updates buffered while a snapshot is fetched,
+// and the minimum time between snapshot requests of a symbol
+const (
+	maxPendingUpdates = 1000
+	snapshotRetry     = 5 * time.Second
+)
+
+// diffDepth as the depth levels of a feed subscribes to the diff depth
+// stream, which the local order books are kept from
+const diffDepth = -1
+
+// errBookGap reports an update that does not follow the last one applied:
+// updates were lost and the book must be synced again
+var errBookGap = errors.New("order book update out of sequence")
+
+// OrderBookSettings configures the local order books a feed keeps
+type OrderBookSettings struct {
+	Levels        int    // Top levels of each side delivered as the book depth
+	SnapshotURL   string // REST endpoint of the order book snapshots
+	SnapshotLimit int    // Levels of each side requested in a snapshot
+}
+
+// DepthUpdate is an event of the diff depth stream: the new quantity of
+// every level changed, 0 for a level removed, between two update IDs
+type DepthUpdate struct {
+	Symbol  string // Lower-case symbol
+	FirstID int64
+	LastID  int64
+	// PrevLastID is the last ID of the previous event, on the futures
+	// streams only, which chain their events by it; 0 on spot
+	PrevLastID int64
+	Bids       []Level
+	Asks       []Level
+}
+
+// OrderBook is a local copy of an exchange's order book, kept in sync
+// from a snapshot and the updates of the diff depth stream. Each side is
+// sorted best first.
+type OrderBook struct {
+	bids, asks   []Level
+	lastUpdateID int64
+	applied      bool // An update was applied since the snapshot
+}
+
+// NewOrderBook creates a book from a snapshot of its levels as of an
+// update ID
+func NewOrderBook(lastUpdateID int64, bids, asks []Level) *OrderBook {
+	book := &OrderBook{lastUpdateID: lastUpdateID}
+	for _, level := range bids {
+		book.bids = setLevel(book.bids, level, true)
+	}
+	for _, level := range asks {
+		book.asks = setLevel(book.asks, level, false)
+	}
+	return book
+}
+
+// Apply applies an update, reporting whether it changed the book. Updates
+// the snapshot already includes are skipped; an update that does not
+// follow the last one applied returns errBookGap and leaves the book as
+// it was.
+func (b *OrderBook) Apply(update DepthUpdate) (bool, error) {
+	chained := update.PrevLastID != 0
+	switch {
+	case !b.applied:
+		// The first update must span the snapshot's ID, or the one after
+		// it on spot
+		next := b.lastUpdateID
+		if !chained {
+			next++
+		}
+		if update.LastID < next {
+			return false, nil
+		}
+		if update.FirstID > next {
+			return false, errBookGap
+		}
+	case update.LastID <= b.lastUpdateID:
+		return false, nil
+	case chained && update.PrevLastID != b.lastUpdateID,
+		!chained && update.FirstID != b.lastUpdateID+1:
+		return false, errBookGap
+	}
+
+	for _, level := range update.Bids {
+		b.bids = setLevel(b.bids, level, true)
+	}
+	for _, level := range update.Asks {
+		b.asks = setLevel(b.asks, level, false)
+	}
+	b.lastUpdateID = update.LastID
+	b.applied = true
+	return true, nil
+}
+
+// setLevel sets the quantity of a price level of a side, removing it for
+// a zero quantity, and returns the side
+func setLevel(side []Level, level Level, bids bool) []Level {
+	i := sort.Search(len(side), func(i int) bool {
+		if bids {
+			return side[i].Price <= level.Price
+		}
+		return side[i].Price >= level.Price
+	})
+	exists := i < len(side) && side[i].Price == level.Price
+	switch {
+	case level.Quantity == 0 && exists:
+		return append(side[:i], side[i+1:]...)
+	case level.Quantity == 0:
+		return side
+	case exists:
+		side[i].Quantity = level.Quantity
+		return side
+	}
+	side = append(side, Level{})
+	copy(side[i+1:], side[i:])
+	side[i] = level
+	return side
+}
+
+// LastUpdateID returns the ID of the last update the book includes
+func (b *OrderBook) LastUpdateID() int64 {
+	return b.lastUpdateID
+}
+
+// Depth returns a copy of the top levels of each side
+func (b *OrderBook) Depth(levels int) Depth {
+	return Depth{
+		Bids: append([]Level(nil), b.bids[:min(levels, len(b.bids))]...),
+		Asks: append([]Level(nil), b.asks[:min(levels, len(b.asks))]...),
+	}
+}
+
+// bookSync keeps the local book of a symbol in sync: updates are buffered
+// until a snapshot is fetched, then applied to it as they arrive. A gap in
+// the updates discards the book and fetches a new snapshot.
+type bookSync struct {
+	book      *OrderBook // nil while not synced
+	pending   []DepthUpdate
+	fetching  bool
+	lastFetch time.Time
+	mutex     sync.Mutex
+}
+
+// syncedBook returns the sync state of a symbol's book, creating it
+func (f *BinanceFeed) syncedBook(symbol string) *bookSync {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	state, ok := f.books[symbol]
+	if !ok {
+		state = &bookSync{}
+		f.books[symbol] = state
+	}
+	return state
+}
+
+// resetBooks discards the local books, which miss the updates sent while
+// the connection was down
+func (f *BinanceFeed) resetBooks() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	clear(f.books)
+}
+
+// applyUpdate applies an update to the local book of its symbol and
+// delivers the book's depth, or buffers the update until the book is
+// synced. It runs on the connection goroutine.
+func (f *BinanceFeed) applyUpdate(update DepthUpdate) {
+	state := f.syncedBook(update.Symbol)
+	state.mutex.Lock()
+	if state.book == nil {
+		if len(state.pending) == maxPendingUpdates {
+			state.pending = append(state.pending[:0], state.pending[1:]...)
+		}
+		state.pending = append(state.pending, update)
+		fetch := !state.fetching && time.Since(state.lastFetch) >= snapshotRetry
+		if fetch {
+			state.fetching = true
+			state.lastFetch = time.Now()
+		}
+		state.mutex.Unlock()
+		if fetch {
+			f.running.Add(1)
+			go f.fetchBook(update.Symbol, state)
+		}
+		return
+	}
+
+	changed, err := state.book.Apply(update)
+	if err != nil {
+		// Resync from the next update on
+		state.book = nil
+		state.pending = append(state.pending[:0], update)
+		state.mutex.Unlock()
+		f.resync(update.Symbol, err)
+		return
+	}
+	var depth Depth
+	if changed {
+		depth = state.book.Depth(f.orderBook.Levels)
+	}
+	state.mutex.Unlock()
+
+	if changed {
+		f.deliverBook(update.Symbol, depth)
+	}
+}
+
+// fetchBook fetches the snapshot of a symbol's book and applies the
+// updates buffered since
+func (f *BinanceFeed) fetchBook(symbol string, state *bookSync) {
+	defer f.running.Done()
+
+	book, err := fetchDepthSnapshot(f.orderBook.SnapshotURL, symbol, f.orderBook.SnapshotLimit)
+	state.mutex.Lock()
+	state.fetching = false
+	if err != nil {
+		state.mutex.Unlock()
+		f.logger.Warning(fmt.Sprintf("Failed to fetch the order book of %s (retrying with the next update): %v", symbol, err))
+		f.publishError(fmt.Errorf("order book snapshot of %s: %w", symbol, err))
+		return
+	}
+	for _, update := range state.pending {
+		if _, err = book.Apply(update); err != nil {
+			break
+		}
+	}
+	state.pending = state.pending[:0]
+	if err != nil {
+		// The snapshot is older than the updates buffered; fetch another
+		state.mutex.Unlock()
+		f.resync(symbol, err)
+		return
+	}
+	state.book = book
+	depth := book.Depth(f.orderBook.Levels)
+	state.mutex.Unlock()
+
+	f.logger.Info(fmt.Sprintf("Order book of %s synced at update %d", symbol, book.LastUpdateID()))
+	f.deliverBook(symbol, depth)
+}
+
+// resync counts and logs a book discarded to be synced again
+func (f *BinanceFeed) resync(symbol string, err error) {
+	atomic.AddInt64(&f.feed.resyncs, 1)
+	f.logger.Warning(fmt.Sprintf("Order book of %s out of sync, fetching a new snapshot: %v", symbol, err))
+}
+
+// deliverBook delivers the depth of a synced book
+func (f *BinanceFeed) deliverBook(symbol string, depth Depth) {
+	depth.Symbol = symbol
+	depth.Time = time.Now()
+	send(f.depths, depth, f.done)
+}
+
+// depthSnapshot is a REST order book snapshot
+type depthSnapshot struct {
+	LastUpdateID int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
+// fetchDepthSnapshot fetches the order book of a symbol with limit levels
+// of each side from a Binance depth endpoint
+func fetchDepthSnapshot(endpoint, symbol string, limit int) (*OrderBook, error) {
+	query := url.Values{}
+	query.Set("symbol", strings.ToUpper(symbol))
+	query.Set("limit", fmt.Sprint(limit))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint + "?" + query.Encode())
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrExchangeUnavailable, "depth snapshot", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return nil, errs.Errorf(errs.ErrExchangeUnavailable, "depth snapshot", "depth endpoint returned %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("depth endpoint returned %s", resp.Status)
+	}
+	var snapshot depthSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("invalid order book snapshot: %v", err)
+	}
+	bids, err := parseLevels("bid", snapshot.Bids)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := parseLevels("ask", snapshot.Asks)
+	if err != nil {
+		return nil, err
+	}
+	return NewOrderBook(snapshot.LastUpdateID, bids, asks), nil
+}
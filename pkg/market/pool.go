@@ -0,0 +1,38 @@
+package market
+
+import (
+	"sync"
+
+	"TRADE/pkg/types"
+)
+
+// Tick ownership
+//
+// At high message rates a TickData per message puts heavy load on the
+// garbage collector, so the feeds (WebSocket, CSV, simulator) take ticks
+// from a pool with NewTick and hand them to AddTick, which owns them from
+// then on:
+//
+//  1. AddTick stores the tick's values and publishes a TickEvent.
+//  2. Tick subscribers run synchronously during Publish. They may read
+//     event.Tick but must not keep it (or the event) after returning;
+//     code that needs the tick later keeps tick.Clone() or a copy of the
+//     event from events.Detach.
+//  3. When Publish returns, AddTick releases the tick back to the pool,
+//     where it is reused by the next message.
+//
+// Callers must not touch a tick after passing it to AddTick.
+var tickPool = sync.Pool{
+	New: func() interface{} { return new(types.TickData) },
+}
+
+// NewTick returns a zeroed tick from the pool
+func NewTick() *types.TickData {
+	return tickPool.Get().(*types.TickData)
+}
+
+// releaseTick returns a tick to the pool
+func releaseTick(tick *types.TickData) {
+	*tick = types.TickData{}
+	tickPool.Put(tick)
+}
@@ -0,0 +1,195 @@
+package market
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BackpressurePolicy decides what the tick queue of a stream does when it
+// is full, i.e. the analyzer and strategy fall behind the feed
+type BackpressurePolicy string
+
+// Backpressure policies
+const (
+	// PolicyBlock waits for room, holding the feed back until the
+	// pipeline catches up: no tick is lost, but the feed's connection
+	// stops being read meanwhile
+	PolicyBlock BackpressurePolicy = "block"
+	// PolicyDropOldest drops the oldest queued tick
+	PolicyDropOldest BackpressurePolicy = "drop_oldest"
+	// PolicyDropNewest drops the tick arriving
+	PolicyDropNewest BackpressurePolicy = "drop_newest"
+	// PolicyCoalesce merges the tick arriving into the newest queued one
+	// of the same symbol and side, which keeps the last price and the
+	// summed volume; the oldest tick is dropped when they differ
+	PolicyCoalesce BackpressurePolicy = "coalesce"
+)
+
+// Default settings of the tick queue
+const (
+	DefaultQueueSize = 4096
+	DefaultPolicy    = PolicyCoalesce
+)
+
+// ParseBackpressurePolicy returns the policy of a name; empty is the
+// default policy
+func ParseBackpressurePolicy(name string) (BackpressurePolicy, error) {
+	switch policy := BackpressurePolicy(name); policy {
+	case "":
+		return DefaultPolicy, nil
+	case PolicyBlock, PolicyDropOldest, PolicyDropNewest, PolicyCoalesce:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown backpressure policy %q (want block, drop_oldest, drop_newest or coalesce)", name)
+}
+
+// QueueSettings configures the tick queue between a feed and the pipeline
+type QueueSettings struct {
+	Size   int // Ticks held while the pipeline is behind
+	Policy BackpressurePolicy
+}
+
+// QueueStats counts the data of a stream's queue
+type QueueStats struct {
+	Depth     int   `json:"depth"`              // Queued now
+	MaxDepth  int   `json:"max_depth"`          // Most ever queued
+	Capacity  int   `json:"capacity,omitempty"` // Of the tick queue only
+	Enqueued  int64 `json:"enqueued"`
+	Dropped   int64 `json:"dropped"`
+	Coalesced int64 `json:"coalesced"` // Merged into or replaced by newer data
+}
+
+// PipelineStats are the queue counts of a stream: ticks are queued by the
+// backpressure policy, and the quotes, depths and funding updates waiting
+// are replaced by newer ones of their symbol
+type PipelineStats struct {
+	Policy  BackpressurePolicy `json:"policy"`
+	Ticks   QueueStats         `json:"ticks"`
+	Quotes  QueueStats         `json:"quotes"`
+	Depths  QueueStats         `json:"depths"`
+	Funding QueueStats         `json:"funding"`
+}
+
+// tickQueue is the bounded FIFO of ticks between the intake and the
+// routing goroutine of a stream
+type tickQueue struct {
+	ticks  []Tick // Ring buffer of capacity len(ticks)
+	head   int
+	size   int
+	policy BackpressurePolicy
+	stats  QueueStats
+	closed bool
+	room   *sync.Cond // Signalled when a tick is taken, for PolicyBlock
+}
+
+// newTickQueue creates a queue of the given settings guarded by mutex
+func newTickQueue(settings QueueSettings, mutex *sync.Mutex) *tickQueue {
+	return &tickQueue{
+		ticks:  make([]Tick, settings.Size),
+		policy: settings.Policy,
+		stats:  QueueStats{Capacity: settings.Size},
+		room:   sync.NewCond(mutex),
+	}
+}
+
+// push queues a tick by the policy and reports whether one was lost. The
+// caller holds the mutex; PolicyBlock waits on it for room.
+func (q *tickQueue) push(tick Tick) (dropped bool) {
+	for q.size == len(q.ticks) && q.policy == PolicyBlock && !q.closed {
+		q.room.Wait()
+	}
+	if q.closed {
+		return false
+	}
+	q.stats.Enqueued++
+	if q.size == len(q.ticks) {
+		switch q.policy {
+		case PolicyDropNewest:
+			q.stats.Dropped++
+			return true
+		case PolicyCoalesce:
+			last := &q.ticks[(q.head+q.size-1)%len(q.ticks)]
+			if last.Symbol == tick.Symbol && last.IsAsk == tick.IsAsk {
+				last.Price = tick.Price
+				last.Volume += tick.Volume
+				last.Timestamp = tick.Timestamp
+				q.stats.Coalesced++
+				return false
+			}
+		}
+		q.head = (q.head + 1) % len(q.ticks)
+		q.size--
+		q.stats.Dropped++
+		dropped = true
+	}
+	q.ticks[(q.head+q.size)%len(q.ticks)] = tick
+	q.size++
+	q.stats.MaxDepth = max(q.stats.MaxDepth, q.size)
+	return dropped
+}
+
+// pop takes the oldest tick. The caller holds the mutex.
+func (q *tickQueue) pop() (Tick, bool) {
+	if q.size == 0 {
+		return Tick{}, false
+	}
+	tick := q.ticks[q.head]
+	q.ticks[q.head] = Tick{}
+	q.head = (q.head + 1) % len(q.ticks)
+	q.size--
+	q.room.Signal()
+	return tick, true
+}
+
+// close releases a push waiting for room
+func (q *tickQueue) close() {
+	q.closed = true
+	q.room.Broadcast()
+}
+
+// latestQueue holds the newest value of each symbol not yet routed, in the
+// order their symbols were first queued
+type latestQueue[T any] struct {
+	values  map[string]T
+	symbols []string
+	stats   QueueStats
+}
+
+// newLatestQueue creates an empty queue
+func newLatestQueue[T any]() *latestQueue[T] {
+	return &latestQueue[T]{values: make(map[string]T)}
+}
+
+// push queues a value, replacing the one of its symbol still waiting. The
+// caller holds the mutex.
+func (q *latestQueue[T]) push(symbol string, value T) {
+	q.stats.Enqueued++
+	if _, ok := q.values[symbol]; ok {
+		q.stats.Coalesced++
+	} else {
+		q.symbols = append(q.symbols, symbol)
+	}
+	q.values[symbol] = value
+	q.stats.MaxDepth = max(q.stats.MaxDepth, len(q.symbols))
+}
+
+// pop takes the value of the symbol queued first. The caller holds the
+// mutex.
+func (q *latestQueue[T]) pop() (T, bool) {
+	var value T
+	if len(q.symbols) == 0 {
+		return value, false
+	}
+	symbol := q.symbols[0]
+	q.symbols = q.symbols[1:]
+	value = q.values[symbol]
+	delete(q.values, symbol)
+	return value, true
+}
+
+// snapshot returns the counts with the current depth
+func (q *latestQueue[T]) snapshot() QueueStats {
+	stats := q.stats
+	stats.Depth = len(q.symbols)
+	return stats
+}
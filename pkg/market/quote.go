@@ -0,0 +1,61 @@
+package market
+
+import (
+	"time"
+)
+
+// Quote is the best bid and ask of the order book
+type Quote struct {
+	Symbol      string // Lower-case symbol
+	Bid         float64
+	BidQuantity float64
+	Ask         float64
+	AskQuantity float64
+	Time        time.Time // Wall-clock time the quote was received
+}
+
+// Mid returns the midpoint of bid and ask
+func (q Quote) Mid() float64 {
+	return (q.Bid + q.Ask) / 2
+}
+
+// SpreadBps returns the bid-ask spread in basis points of the midpoint
+func (q Quote) SpreadBps() float64 {
+	mid := q.Mid()
+	if mid <= 0 {
+		return 0
+	}
+	return (q.Ask - q.Bid) / mid * 10000
+}
+
+// SetQuote records the current best bid and ask
+func (md *MarketData) SetQuote(quote Quote) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.quote = quote
+}
+
+// Quote returns the last best bid and ask; ok is false if none was received
+func (md *MarketData) Quote() (quote Quote, ok bool) {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+	return md.quote, !md.quote.Time.IsZero()
+}
+
+// RecentVolume returns the volume traded within window of the last tick.
+// Only the ticks still in the history buffer are counted.
+func (md *MarketData) RecentVolume(window time.Duration) float64 {
+	md.mutex.RLock()
+	defer md.mutex.RUnlock()
+
+	n := md.timeStamps.Len()
+	if n == 0 {
+		return 0
+	}
+	since := md.timeStamps.Last().Add(-window)
+	volume := 0.0
+	for i := n - 1; i >= 0 && md.timeStamps.At(i).After(since); i-- {
+		volume += md.volumeHistory.At(i)
+	}
+	return volume
+}
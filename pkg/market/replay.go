@@ -0,0 +1,246 @@
+package market
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// reorderRows is the number of rows of each dataset held back so rows
+// recorded slightly out of order are replayed in order
+const reorderRows = 4096
+
+// ReplayStats counts what a replay did with the rows of its datasets
+type ReplayStats struct {
+	Datasets   int `json:"datasets"`
+	Ticks      int `json:"ticks"`      // Ticks replayed
+	Invalid    int `json:"invalid"`    // Rows that could not be parsed
+	Reordered  int `json:"reordered"`  // Rows older than a previous row of their dataset, put back in order
+	Duplicates int `json:"duplicates"` // Rows another dataset had already replayed
+	Late       int `json:"late"`       // Rows too far out of order to be replayed in order, dropped
+}
+
+// Replay merges tick datasets, e.g. months split across files or the
+// files of several symbols, into one chronological stream. Each dataset
+// is read in its own order with up to reorderRows rows held back, and the
+// datasets are merged by timestamp, earlier datasets first on ties.
+// Overlapping datasets replay each row once: a row is a duplicate when
+// another dataset has already replayed as many identical rows (symbol,
+// time, price, volume, side) at its timestamp.
+type Replay struct {
+	sources []*replaySource
+	logger  logger.Interface
+	last    time.Time      // Timestamp of the last replayed tick
+	emitted map[rowKey]int // Ticks replayed at last, by row
+	stats   ReplayStats
+}
+
+// replaySource is a dataset being replayed
+type replaySource struct {
+	path    string
+	symbol  string
+	file    *os.File
+	reader  *TickReader
+	pending tickHeap
+	seq     int       // Rows read, ordering rows with equal timestamps
+	newest  time.Time // Latest timestamp read
+	done    bool
+	counts  map[rowKey]int // Rows of this dataset replayed at the replay's last timestamp
+}
+
+// rowKey identifies identical rows across datasets
+type rowKey struct {
+	symbol string
+	time   int64
+	price  float64
+	volume float64
+	isAsk  bool
+}
+
+// OpenReplay opens the datasets to merge, in priority order for rows with
+// equal timestamps
+func OpenReplay(paths []string, log logger.Interface) (*Replay, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no dataset to replay")
+	}
+	r := &Replay{logger: log, emitted: make(map[rowKey]int)}
+	for _, path := range paths {
+		source, err := openReplaySource(path)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		r.sources = append(r.sources, source)
+		r.fill(source)
+	}
+	r.stats.Datasets = len(paths)
+	return r, nil
+}
+
+// openReplaySource opens a dataset and reads its header
+func openReplaySource(path string) (*replaySource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	reader, err := NewTickReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &replaySource{
+		path:   path,
+		symbol: DatasetSymbol(path),
+		file:   file,
+		reader: reader,
+		counts: make(map[rowKey]int),
+	}, nil
+}
+
+// DatasetSymbol returns the symbol a dataset's file name starts with, as
+// recorded datasets are named (btcusdt_20250310_214415.csv); empty when
+// the name has no underscore
+func DatasetSymbol(path string) string {
+	name := filepath.Base(path)
+	symbol, _, found := strings.Cut(name, "_")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(symbol)
+}
+
+// Next fills tick with the earliest tick left in the datasets. Its symbol
+// is the dataset's (see DatasetSymbol). It returns io.EOF once every
+// dataset is replayed.
+func (r *Replay) Next(tick *Tick) error {
+	for {
+		source := r.earliest()
+		if source == nil {
+			return io.EOF
+		}
+		next := heap.Pop(&source.pending).(pendingTick)
+		if !next.Timestamp.Equal(r.last) {
+			r.last = next.Timestamp
+			clear(r.emitted)
+			for _, s := range r.sources {
+				clear(s.counts)
+			}
+		}
+		r.fill(source)
+
+		key := rowKey{source.symbol, next.Timestamp.UnixNano(), next.Price, next.Volume, next.IsAsk}
+		source.counts[key]++
+		if source.counts[key] <= r.emitted[key] {
+			r.stats.Duplicates++
+			continue
+		}
+		r.emitted[key]++
+		r.stats.Ticks++
+
+		tick.Symbol = source.symbol
+		tick.TickData = next.TickData
+		return nil
+	}
+}
+
+// earliest returns the source holding the earliest tick, nil when all are
+// replayed
+func (r *Replay) earliest() *replaySource {
+	var earliest *replaySource
+	for _, source := range r.sources {
+		if len(source.pending) == 0 {
+			continue
+		}
+		if earliest == nil || source.pending[0].Timestamp.Before(earliest.pending[0].Timestamp) {
+			earliest = source
+		}
+	}
+	return earliest
+}
+
+// fill reads rows of a source until reorderRows are held back or the
+// dataset ends. Rows older than the last replayed tick cannot be replayed
+// in order and are dropped.
+func (r *Replay) fill(source *replaySource) {
+	for !source.done && len(source.pending) < reorderRows {
+		var row pendingTick
+		err := source.reader.Read(&row.TickData)
+		var invalid *InvalidRowError
+		if errors.As(err, &invalid) {
+			r.stats.Invalid++
+			r.logger.Warning(fmt.Sprintf("%s:%d: %s", source.path, invalid.Line, invalid.Message))
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				r.logger.Warning(fmt.Sprintf("%s: reading stopped: %v", source.path, err))
+			}
+			source.done = true
+			break
+		}
+
+		if row.Timestamp.Before(r.last) {
+			r.stats.Late++
+			r.logger.Debug(fmt.Sprintf("%s:%d: row at %s is older than the replay (%s); dropped",
+				source.path, source.reader.line, row.Timestamp.Format(time.RFC3339Nano), r.last.Format(time.RFC3339Nano)))
+			continue
+		}
+		if row.Timestamp.Before(source.newest) {
+			r.stats.Reordered++
+		} else {
+			source.newest = row.Timestamp
+		}
+		source.seq++
+		row.seq = source.seq
+		heap.Push(&source.pending, row)
+	}
+}
+
+// Stats returns the counts of the rows replayed so far
+func (r *Replay) Stats() ReplayStats {
+	return r.stats
+}
+
+// Close closes the datasets
+func (r *Replay) Close() error {
+	var first error
+	for _, source := range r.sources {
+		if err := source.file.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// pendingTick is a row held back by a source, with its read order
+type pendingTick struct {
+	types.TickData
+	seq int
+}
+
+// tickHeap orders held-back rows by timestamp, then by read order
+type tickHeap []pendingTick
+
+func (h tickHeap) Len() int { return len(h) }
+func (h tickHeap) Less(i, j int) bool {
+	if !h[i].Timestamp.Equal(h[j].Timestamp) {
+		return h[i].Timestamp.Before(h[j].Timestamp)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h tickHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *tickHeap) Push(x interface{}) { *h = append(*h, x.(pendingTick)) }
+func (h *tickHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,169 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// SimRegime is a market regime with its own drift and volatility
+type SimRegime struct {
+	Name       string
+	Drift      float64 // Annualized drift (0.5 = +50% per year)
+	Volatility float64 // Annualized volatility (0.6 = 60%)
+}
+
+// SimulatorParams configures the synthetic tick generator
+type SimulatorParams struct {
+	Seed             int64
+	InitialPrice     float64
+	StartTime        time.Time
+	TickInterval     time.Duration // Simulated time between ticks
+	Realtime         bool          // Pace ticks in wall-clock time
+	MaxTicks         int           // Stop after this many ticks (0 = unlimited)
+	JumpIntensity    float64       // Expected jumps per day
+	JumpMean         float64       // Mean log jump size
+	JumpStd          float64       // Standard deviation of log jump size
+	RegimeSwitchProb float64       // Per-tick probability of switching regime
+	Regimes          []SimRegime
+	MeanVolume       float64
+}
+
+// Simulator generates synthetic ticks using geometric Brownian motion with
+// jumps and random regime switches. A fixed seed yields the same price path.
+type Simulator struct {
+	params   SimulatorParams
+	market   *MarketData
+	logger   logger.Interface
+	rng      *rand.Rand
+	price    float64
+	regime   int
+	clock    time.Time
+	ticks    int
+	stopChan chan struct{}
+	mutex    sync.Mutex
+}
+
+// NewSimulator creates a simulator feeding ticks into the market data
+func NewSimulator(md *MarketData, params SimulatorParams, log logger.Interface) (*Simulator, error) {
+	if params.InitialPrice <= 0 {
+		return nil, fmt.Errorf("initial price must be positive")
+	}
+	if params.TickInterval <= 0 {
+		return nil, fmt.Errorf("tick interval must be positive")
+	}
+	if len(params.Regimes) == 0 {
+		return nil, fmt.Errorf("at least one regime is required")
+	}
+	if params.StartTime.IsZero() {
+		params.StartTime = time.Now().UTC()
+	}
+	if params.MeanVolume <= 0 {
+		params.MeanVolume = 0.05
+	}
+
+	return &Simulator{
+		params:   params,
+		market:   md,
+		logger:   log,
+		rng:      rand.New(rand.NewSource(params.Seed)),
+		price:    params.InitialPrice,
+		clock:    params.StartTime,
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// Next generates the next synthetic tick, taken from the tick pool
+func (s *Simulator) Next() *types.TickData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Random regime switch
+	if len(s.params.Regimes) > 1 && s.rng.Float64() < s.params.RegimeSwitchProb {
+		next := s.rng.Intn(len(s.params.Regimes) - 1)
+		if next >= s.regime {
+			next++
+		}
+		s.regime = next
+		s.logger.Info(fmt.Sprintf("Simulator switched to %s regime", s.params.Regimes[s.regime].Name))
+	}
+	regime := s.params.Regimes[s.regime]
+
+	// GBM step: dt in years
+	dt := s.params.TickInterval.Seconds() / (365 * 24 * 3600)
+	logReturn := (regime.Drift-0.5*regime.Volatility*regime.Volatility)*dt +
+		regime.Volatility*math.Sqrt(dt)*s.rng.NormFloat64()
+
+	// Poisson jumps
+	jumpProb := s.params.JumpIntensity * s.params.TickInterval.Seconds() / (24 * 3600)
+	if s.rng.Float64() < jumpProb {
+		logReturn += s.params.JumpMean + s.params.JumpStd*s.rng.NormFloat64()
+	}
+
+	s.price *= math.Exp(logReturn)
+	s.clock = s.clock.Add(s.params.TickInterval)
+	s.ticks++
+
+	// Aggressor side leans with the price move
+	askProb := 0.5
+	if logReturn > 0 {
+		askProb = 0.65
+	} else if logReturn < 0 {
+		askProb = 0.35
+	}
+
+	tick := NewTick()
+	tick.Price = s.price
+	tick.Volume = s.rng.ExpFloat64() * s.params.MeanVolume
+	tick.IsAsk = s.rng.Float64() < askProb
+	tick.Timestamp = s.clock
+	return tick
+}
+
+// Run feeds ticks into the market data until MaxTicks is reached or Stop is called
+func (s *Simulator) Run() {
+	s.logger.Info(fmt.Sprintf("Simulator started (seed %d, start price %.2f)", s.params.Seed, s.params.InitialPrice))
+
+	var ticker *time.Ticker
+	if s.params.Realtime {
+		ticker = time.NewTicker(s.params.TickInterval)
+		defer ticker.Stop()
+	}
+
+	for s.params.MaxTicks <= 0 || s.ticks < s.params.MaxTicks {
+		if ticker != nil {
+			select {
+			case <-ticker.C:
+			case <-s.stopChan:
+				return
+			}
+		} else {
+			select {
+			case <-s.stopChan:
+				return
+			default:
+			}
+		}
+
+		s.market.AddTick(s.Next())
+	}
+
+	s.logger.Info(fmt.Sprintf("Simulator finished after %d ticks", s.ticks))
+}
+
+// Stop ends a running simulation
+func (s *Simulator) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.stopChan:
+	default:
+		close(s.stopChan)
+	}
+}
@@ -0,0 +1,352 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"TRADE/pkg/errs"
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// dropWarningInterval is the minimum time between the warnings of ticks
+// lost to backpressure
+const dropWarningInterval = time.Minute
+
+// Stream routes the data of a live feed to the MarketData of each symbol,
+// so every instrument keeps its own history and its ticks are published
+// with its symbol. The feed is exchange specific; the stream is not.
+//
+// An intake goroutine takes the feed's data as it arrives into bounded
+// queues, which a routing goroutine drains through the pipeline, so a slow
+// pipeline is handled by the backpressure policy instead of holding the
+// feed back.
+type Stream struct {
+	feed    Feed
+	markets map[string]*MarketData // Keyed by lower-case symbol
+	started bool
+
+	queue    QueueSettings
+	ticks    *tickQueue
+	quotes   *latestQueue[Quote]
+	depths   *latestQueue[Depth]
+	funding  *latestQueue[*events.FundingEvent]
+	closed   bool          // The feed closed its channels
+	ready    chan struct{} // Signalled when data is queued
+	warned   time.Time     // Last warning of ticks dropped
+	dropped  int64         // Ticks dropped at the last warning
+	pipeline sync.Mutex    // Guards the queues
+
+	bus    *events.Bus
+	logger logger.Interface
+	mutex  sync.RWMutex
+}
+
+// NewStream creates a stream of the data of a feed, publishing the funding
+// updates of perpetuals on the bus
+func NewStream(feed Feed, log logger.Interface, bus *events.Bus) *Stream {
+	return &Stream{
+		feed:    feed,
+		markets: make(map[string]*MarketData),
+		queue:   QueueSettings{Size: DefaultQueueSize, Policy: DefaultPolicy},
+		quotes:  newLatestQueue[Quote](),
+		depths:  newLatestQueue[Depth](),
+		funding: newLatestQueue[*events.FundingEvent](),
+		ready:   make(chan struct{}, 1),
+		bus:     bus,
+		logger:  log,
+	}
+}
+
+// SetQueue sets the size and the backpressure policy of the tick queue,
+// before the stream is connected
+func (s *Stream) SetQueue(settings QueueSettings) error {
+	if settings.Size <= 0 {
+		return fmt.Errorf("invalid tick queue of %d ticks", settings.Size)
+	}
+	if _, err := ParseBackpressurePolicy(string(settings.Policy)); err != nil || settings.Policy == "" {
+		return fmt.Errorf("invalid backpressure policy %q", settings.Policy)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return fmt.Errorf("the tick queue is set before connecting")
+	}
+	s.queue = settings
+	return nil
+}
+
+// SetDepth has the feed deliver the given number of book levels of every
+// symbol; 0 disables depth. It fails if the feed does not deliver depth.
+func (s *Stream) SetDepth(levels int) error {
+	feed, ok := s.feed.(DepthFeed)
+	if !ok {
+		if levels == 0 {
+			return nil
+		}
+		return fmt.Errorf("market data feed does not deliver book depth")
+	}
+	return feed.SetDepth(levels)
+}
+
+// SetOrderBook has the feed keep a local order book of every symbol and
+// deliver its top levels. It fails if the feed keeps no order books.
+func (s *Stream) SetOrderBook(settings OrderBookSettings) error {
+	feed, ok := s.feed.(OrderBookFeed)
+	if !ok {
+		return fmt.Errorf("market data feed does not keep order books")
+	}
+	return feed.SetOrderBook(settings)
+}
+
+// Add routes the data of a market data's symbol to it and subscribes the
+// feed to the symbol
+func (s *Stream) Add(md *MarketData) error {
+	symbol := md.Symbol()
+	if symbol == "" {
+		return fmt.Errorf("market data has no symbol")
+	}
+
+	s.mutex.Lock()
+	if _, ok := s.markets[symbol]; ok {
+		s.mutex.Unlock()
+		return fmt.Errorf("symbol %s is already streamed", symbol)
+	}
+	s.markets[symbol] = md
+	md.setStream(s)
+	s.mutex.Unlock()
+
+	return s.feed.Subscribe(symbol)
+}
+
+// Symbols returns the streamed symbols, sorted
+func (s *Stream) Symbols() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	symbols := make([]string, 0, len(s.markets))
+	for symbol := range s.markets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Connect connects the feed and starts routing its data
+func (s *Stream) Connect() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return fmt.Errorf("already connected to market data")
+	}
+	if len(s.markets) == 0 {
+		return fmt.Errorf("no symbols to stream")
+	}
+	if err := s.feed.Connect(); err != nil {
+		return err
+	}
+	s.started = true
+	s.ticks = newTickQueue(s.queue, &s.pipeline)
+	go s.intake()
+	go s.route()
+	return nil
+}
+
+// Connected reports whether the feed's connection is up
+func (s *Stream) Connected() bool {
+	if feed, ok := s.feed.(MonitoredFeed); ok {
+		return feed.Connected()
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.started
+}
+
+// Disconnect closes the feed
+func (s *Stream) Disconnect() {
+	if err := s.feed.Close(); err != nil {
+		s.logger.Warning(fmt.Sprintf("Market data feed not closed cleanly: %v", err))
+	}
+}
+
+// Reconnect replaces the feed's connection
+func (s *Stream) Reconnect() error {
+	feed, ok := s.feed.(ReconnectingFeed)
+	if !ok {
+		return errs.Errorf(errs.ErrFeedDisconnected, "", "market data feed cannot reconnect")
+	}
+	return feed.Reconnect()
+}
+
+// FeedStats returns the message counts of the feed; zero if it keeps none
+func (s *Stream) FeedStats() FeedStats {
+	if feed, ok := s.feed.(MonitoredFeed); ok {
+		return feed.FeedStats()
+	}
+	return FeedStats{}
+}
+
+// PipelineStats returns the counts of the stream's queues; zero before it
+// is connected
+func (s *Stream) PipelineStats() PipelineStats {
+	s.mutex.RLock()
+	policy := s.queue.Policy
+	s.mutex.RUnlock()
+
+	s.pipeline.Lock()
+	defer s.pipeline.Unlock()
+	stats := PipelineStats{
+		Policy:  policy,
+		Quotes:  s.quotes.snapshot(),
+		Depths:  s.depths.snapshot(),
+		Funding: s.funding.snapshot(),
+	}
+	if s.ticks != nil {
+		stats.Ticks = s.ticks.stats
+		stats.Ticks.Depth = s.ticks.size
+	}
+	return stats
+}
+
+// market returns the market data of a symbol, or nil
+func (s *Stream) market(symbol string) *MarketData {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.markets[symbol]
+}
+
+// intake queues the feed's data as it arrives until the feed is closed
+func (s *Stream) intake() {
+	ticks := s.feed.Ticks()
+	var quotes <-chan Quote
+	if feed, ok := s.feed.(QuoteFeed); ok {
+		quotes = feed.Quotes()
+	}
+	var depths <-chan Depth
+	if feed, ok := s.feed.(DepthFeed); ok {
+		depths = feed.Depths()
+	}
+	var funding <-chan *events.FundingEvent
+	if feed, ok := s.feed.(FundingFeed); ok {
+		funding = feed.Funding()
+	}
+
+	for {
+		select {
+		case tick, ok := <-ticks:
+			s.pipeline.Lock()
+			if !ok {
+				s.closed = true
+				s.ticks.close()
+				s.pipeline.Unlock()
+				s.notify()
+				return
+			}
+			dropped := s.ticks.push(tick)
+			s.pipeline.Unlock()
+			if dropped {
+				s.warnDropped()
+			}
+		case quote, ok := <-quotes:
+			if !ok {
+				quotes = nil
+				continue
+			}
+			s.pipeline.Lock()
+			s.quotes.push(quote.Symbol, quote)
+			s.pipeline.Unlock()
+		case depth, ok := <-depths:
+			if !ok {
+				depths = nil
+				continue
+			}
+			s.pipeline.Lock()
+			s.depths.push(depth.Symbol, depth)
+			s.pipeline.Unlock()
+		case event, ok := <-funding:
+			if !ok {
+				funding = nil
+				continue
+			}
+			s.pipeline.Lock()
+			s.funding.push(event.Symbol, event)
+			s.pipeline.Unlock()
+		}
+		s.notify()
+	}
+}
+
+// notify wakes the routing goroutine
+func (s *Stream) notify() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// warnDropped logs the ticks lost to backpressure, at most once per
+// dropWarningInterval
+func (s *Stream) warnDropped() {
+	s.pipeline.Lock()
+	if time.Since(s.warned) < dropWarningInterval {
+		s.pipeline.Unlock()
+		return
+	}
+	s.warned = time.Now()
+	dropped := s.ticks.stats.Dropped - s.dropped
+	s.dropped = s.ticks.stats.Dropped
+	s.pipeline.Unlock()
+
+	s.logger.Warning(fmt.Sprintf("Market data pipeline is behind the feed: %d ticks dropped by the %s policy",
+		dropped, s.queue.Policy))
+}
+
+// route hands the queued data to the market data of their symbols until
+// the feed is closed. One goroutine handles every kind of data, so bus
+// subscribers never see tick and funding events concurrently. The quotes,
+// depths and funding updates waiting are routed before each tick, so it is
+// analyzed with the latest book.
+func (s *Stream) route() {
+	for range s.ready {
+		for {
+			s.pipeline.Lock()
+			quote, hasQuote := s.quotes.pop()
+			depth, hasDepth := s.depths.pop()
+			event, hasFunding := s.funding.pop()
+			tick, hasTick := s.ticks.pop()
+			backlog, closed := s.ticks.size, s.closed
+			s.pipeline.Unlock()
+
+			if hasQuote {
+				if md := s.market(quote.Symbol); md != nil {
+					md.SetQuote(quote)
+				}
+			}
+			if hasDepth {
+				if md := s.market(depth.Symbol); md != nil {
+					md.SetDepth(depth)
+				}
+			}
+			if hasFunding {
+				s.bus.Publish(event)
+			}
+			if hasTick {
+				if md := s.market(tick.Symbol); md != nil {
+					// Fill a pooled tick; addTick releases it
+					pooled := NewTick()
+					*pooled = tick.TickData
+					md.addTick(pooled, backlog)
+				}
+			}
+			if !hasQuote && !hasDepth && !hasFunding && !hasTick {
+				if closed {
+					return
+				}
+				break
+			}
+		}
+	}
+}
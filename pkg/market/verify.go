@@ -0,0 +1,248 @@
+package market
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of dataset quality issues
+const (
+	IssueMissingColumns = "missing_columns" // The header or a row lacks a required column
+	IssueInvalidRow     = "invalid_row"     // A value cannot be parsed
+	IssueOutOfOrder     = "out_of_order"    // A row older than the row before it
+	IssuePriceSpike     = "price_spike"     // A price far from the prices around it
+)
+
+// maxListedIssues bounds the issues a report lists; all are counted
+const maxListedIssues = 1000
+
+// spikeNeighbours is the number of rows on each side whose median price a
+// row's price is compared to
+const spikeNeighbours = 5
+
+// VerifyOptions configures the checks of a dataset
+type VerifyOptions struct {
+	// SpikeThreshold is the deviation from the median price of the
+	// neighbouring rows, as a fraction, above which a price is a spike
+	SpikeThreshold float64
+}
+
+// QualityIssue is a problem found at a line of a dataset
+type QualityIssue struct {
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// QualityReport is the result of checking a tick dataset
+type QualityReport struct {
+	Dataset             string         `json:"dataset"`
+	Checked             time.Time      `json:"checked"`
+	Rows                int            `json:"rows"`
+	ValidRows           int            `json:"valid_rows"`
+	First               time.Time      `json:"first,omitempty"`
+	Last                time.Time      `json:"last,omitempty"`
+	MissingColumns      []string       `json:"missing_columns,omitempty"` // Required columns missing from the header
+	DuplicateTimestamps int            `json:"duplicate_timestamps"`      // Rows sharing their timestamp with an earlier row
+	DuplicateRows       int            `json:"duplicate_rows"`            // Rows identical to an earlier row (see Repair)
+	Counts              map[string]int `json:"counts"`                    // Issues by kind
+	Issues              []QualityIssue `json:"issues"`                    // The first maxListedIssues issues
+	Truncated           bool           `json:"truncated,omitempty"`
+	Repaired            string         `json:"repaired,omitempty"` // Path of the repaired dataset
+	RepairedRows        int            `json:"repaired_rows,omitempty"`
+
+	rows []verifiedRow // Valid rows, for the repair
+}
+
+// verifiedRow is a valid row of a dataset with its parsed values
+type verifiedRow struct {
+	line   int
+	fields [4]string // timestamp, price, volume and is_ask as recorded
+	key    rowKey
+	spike  bool
+	dup    bool
+}
+
+// OK returns whether no issue was found
+func (q *QualityReport) OK() bool {
+	return len(q.MissingColumns) == 0 && len(q.Counts) == 0
+}
+
+// add records an issue
+func (q *QualityReport) add(line int, kind, format string, args ...interface{}) {
+	q.Counts[kind]++
+	if len(q.Issues) >= maxListedIssues {
+		q.Truncated = true
+		return
+	}
+	q.Issues = append(q.Issues, QualityIssue{Line: line, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// VerifyDataset checks a tick dataset for missing columns, rows that
+// cannot be parsed, rows out of time order and price spikes, and counts
+// the rows duplicating an earlier one
+func VerifyDataset(name string, r io.Reader, opts VerifyOptions) (*QualityReport, error) {
+	report := &QualityReport{Dataset: name, Checked: time.Now().UTC(), Counts: make(map[string]int)}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	columns := map[string]int{"timestamp": -1, "price": -1, "volume": -1, "is_ask": -1}
+	for i, col := range header {
+		if _, ok := columns[strings.ToLower(col)]; ok {
+			columns[strings.ToLower(col)] = i
+		}
+	}
+	order := []string{"timestamp", "price", "volume", "is_ask"}
+	width := 0
+	for _, col := range order {
+		if columns[col] == -1 {
+			report.MissingColumns = append(report.MissingColumns, col)
+		}
+		width = max(width, columns[col]+1)
+	}
+	if len(report.MissingColumns) > 0 {
+		report.add(1, IssueMissingColumns, "header has no %s column", strings.Join(report.MissingColumns, ", "))
+		return report, nil
+	}
+
+	seen := make(map[rowKey]bool)
+	stamps := make(map[int64]bool)
+	var previous time.Time
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		report.Rows++
+		if len(record) < width {
+			report.add(line, IssueMissingColumns, "row has %d of %d columns", len(record), len(header))
+			continue
+		}
+
+		row := verifiedRow{line: line}
+		for i, col := range order {
+			row.fields[i] = record[columns[col]]
+		}
+		timestamp, err := parseTimestamp(row.fields[0])
+		if err != nil {
+			report.add(line, IssueInvalidRow, "invalid timestamp: %q", row.fields[0])
+			continue
+		}
+		price, err := strconv.ParseFloat(row.fields[1], 64)
+		if err != nil || price <= 0 || math.IsInf(price, 0) {
+			report.add(line, IssueInvalidRow, "invalid price: %q", row.fields[1])
+			continue
+		}
+		volume, err := strconv.ParseFloat(row.fields[2], 64)
+		if err != nil || volume < 0 || math.IsInf(volume, 0) {
+			report.add(line, IssueInvalidRow, "invalid volume: %q", row.fields[2])
+			continue
+		}
+		isAsk, err := strconv.ParseBool(row.fields[3])
+		if err != nil {
+			report.add(line, IssueInvalidRow, "invalid is_ask value: %q", row.fields[3])
+			continue
+		}
+		row.key = rowKey{time: timestamp.UnixNano(), price: price, volume: volume, isAsk: isAsk}
+
+		if timestamp.Before(previous) {
+			report.add(line, IssueOutOfOrder, "%s is older than the previous row (%s)",
+				timestamp.UTC().Format(time.RFC3339Nano), previous.UTC().Format(time.RFC3339Nano))
+		}
+		previous = timestamp
+		if stamps[row.key.time] {
+			report.DuplicateTimestamps++
+		}
+		stamps[row.key.time] = true
+		if seen[row.key] {
+			row.dup = true
+			report.DuplicateRows++
+		}
+		seen[row.key] = true
+		report.rows = append(report.rows, row)
+	}
+
+	report.ValidRows = len(report.rows)
+	sort.SliceStable(report.rows, func(i, j int) bool { return report.rows[i].key.time < report.rows[j].key.time })
+	if n := len(report.rows); n > 0 {
+		report.First = time.Unix(0, report.rows[0].key.time).UTC()
+		report.Last = time.Unix(0, report.rows[n-1].key.time).UTC()
+	}
+	report.findSpikes(opts.SpikeThreshold)
+	return report, nil
+}
+
+// findSpikes marks the rows, in time order, whose price deviates more than
+// threshold from the median price of the rows around them. A real move
+// shifts the prices on both sides, so only prices that revert are spikes.
+func (q *QualityReport) findSpikes(threshold float64) {
+	if threshold <= 0 {
+		return
+	}
+	neighbours := make([]float64, 0, 2*spikeNeighbours)
+	for i := range q.rows {
+		neighbours = neighbours[:0]
+		for j := max(0, i-spikeNeighbours); j <= min(len(q.rows)-1, i+spikeNeighbours); j++ {
+			if j != i {
+				neighbours = append(neighbours, q.rows[j].key.price)
+			}
+		}
+		if len(neighbours) < spikeNeighbours {
+			continue
+		}
+		sort.Float64s(neighbours)
+		median := neighbours[len(neighbours)/2]
+		if len(neighbours)%2 == 0 {
+			median = (median + neighbours[len(neighbours)/2-1]) / 2
+		}
+		row := &q.rows[i]
+		if deviation := math.Abs(row.key.price/median - 1); deviation > threshold {
+			row.spike = true
+			q.add(row.line, IssuePriceSpike, "price %s is %.2f%% from the median of the rows around it (%g)",
+				row.fields[1], deviation*100, median)
+		}
+	}
+	// Keep the listed issues in line order
+	sort.SliceStable(q.Issues, func(i, j int) bool { return q.Issues[i].Line < q.Issues[j].Line })
+}
+
+// Repair writes the valid rows of the dataset in time order, without
+// price spikes and, if dropDuplicates, without duplicate rows, as a
+// dataset of the timestamp, price, volume and is_ask columns. Trades of the
+// same size and side in the same millisecond are common in recorded data,
+// so duplicates are kept unless dropped explicitly. It returns the number
+// of rows written.
+func (q *QualityReport) Repair(w io.Writer, dropDuplicates bool) (int, error) {
+	if len(q.MissingColumns) > 0 {
+		return 0, fmt.Errorf("missing columns cannot be repaired: %s", strings.Join(q.MissingColumns, ", "))
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"timestamp", "price", "volume", "is_ask"}); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, row := range q.rows {
+		if row.spike || (row.dup && dropDuplicates) {
+			continue
+		}
+		if err := writer.Write(row.fields[:]); err != nil {
+			return written, err
+		}
+		written++
+	}
+	writer.Flush()
+	return written, writer.Error()
+}
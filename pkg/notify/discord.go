@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"TRADE/pkg/events"
+)
+
+// Discord limits of the embed fields
+const (
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+)
+
+// Discord posts notifications to a Discord channel webhook
+type Discord struct {
+	name     string
+	url      string
+	username string
+	client   *http.Client
+}
+
+// NewDiscord creates a notifier posting to a Discord webhook URL, as
+// username if not empty
+func NewDiscord(name, url, username string) *Discord {
+	return &Discord{name: name, url: url, username: username, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name returns the name of the notifier
+func (d *Discord) Name() string { return d.name }
+
+// discordColors are the embed colors of the levels
+var discordColors = map[events.AlertLevel]int{
+	events.AlertInfo:     0x2eb886,
+	events.AlertWarning:  0xdaa038,
+	events.AlertCritical: 0xa30200,
+}
+
+// Notify posts the notification as an embed
+func (d *Discord) Notify(ctx context.Context, n Notification) error {
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":       truncate(n.Title, discordMaxTitle),
+			"description": truncate(n.Text, discordMaxDescription),
+			"color":       discordColors[n.Level],
+			"timestamp":   n.Time.UTC().Format(time.RFC3339),
+			"footer":      map[string]string{"text": string(n.Type)},
+		}},
+	}
+	if d.username != "" {
+		payload["username"] = d.username
+	}
+	return postJSON(ctx, d.client, d.url, payload)
+}
+
+// truncate cuts text to at most max characters
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
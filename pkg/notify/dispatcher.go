@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// maxRepeats caps the notifications remembered per notifier to suppress
+// their repeats; older ones are forgotten first
+const maxRepeats = 1000
+
+// Route sends the notifications of some events to notifiers
+type Route struct {
+	Types     []events.Type     // Empty matches every type of Types
+	MinLevel  events.AlertLevel // Empty matches every level
+	Symbols   []string          // Empty matches every symbol
+	Notifiers []string          // Names of the notifiers
+}
+
+// matches returns whether a notification takes the route
+func (r *Route) matches(n Notification) bool {
+	if len(r.Types) > 0 && !containsType(r.Types, n.Type) {
+		return false
+	}
+	if r.MinLevel != "" && severity(n.Level) < severity(r.MinLevel) {
+		return false
+	}
+	if len(r.Symbols) > 0 {
+		found := false
+		for _, symbol := range r.Symbols {
+			if strings.EqualFold(symbol, n.Symbol) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// containsType returns whether types holds eventType
+func containsType(types []events.Type, eventType events.Type) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures a dispatcher
+type Options struct {
+	BufferSize int           // Notifications queued per notifier before dropping
+	Timeout    time.Duration // Time a notifier has to deliver a notification
+	// Repeat is the least time between two identical notifications to a
+	// notifier; the repeats in between are dropped and counted in the next
+	Repeat time.Duration
+}
+
+// worker delivers the notifications of one notifier in order
+type worker struct {
+	notifier   Notifier
+	queue      chan Notification
+	dropped    int64
+	mutex      sync.Mutex
+	sent       map[string]time.Time // Last time each notification was queued
+	suppressed map[string]int       // Repeats dropped since
+}
+
+// Dispatcher routes the events of the bus to the notifiers. Events are put
+// into words on the publishing goroutine and delivered by one goroutine
+// per notifier without ever blocking it: a notifier that falls behind its
+// buffer loses notifications instead.
+type Dispatcher struct {
+	bus          *events.Bus
+	routes       []Route
+	workers      map[string]*worker
+	wanted       map[events.Type]bool // Types some route matches
+	options      Options
+	logger       logger.Interface
+	subscription events.SubscriptionID
+	wg           sync.WaitGroup
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewDispatcher creates a dispatcher of the events published on bus to
+// the notifiers, along routes
+func NewDispatcher(bus *events.Bus, notifiers []Notifier, routes []Route, options Options, log logger.Interface) (*Dispatcher, error) {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 100
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	d := &Dispatcher{
+		bus:     bus,
+		routes:  routes,
+		workers: make(map[string]*worker, len(notifiers)),
+		wanted:  make(map[events.Type]bool),
+		options: options,
+		logger:  log,
+		done:    make(chan struct{}),
+	}
+	for _, notifier := range notifiers {
+		if _, ok := d.workers[notifier.Name()]; ok {
+			return nil, fmt.Errorf("notifier %q is defined twice", notifier.Name())
+		}
+		d.workers[notifier.Name()] = &worker{
+			notifier:   notifier,
+			queue:      make(chan Notification, options.BufferSize),
+			sent:       make(map[string]time.Time),
+			suppressed: make(map[string]int),
+		}
+	}
+	for i, route := range routes {
+		if len(route.Notifiers) == 0 {
+			return nil, fmt.Errorf("route %d has no notifiers", i+1)
+		}
+		for _, name := range route.Notifiers {
+			if _, ok := d.workers[name]; !ok {
+				return nil, fmt.Errorf("route %d: unknown notifier %q", i+1, name)
+			}
+		}
+		types := route.Types
+		if len(types) == 0 {
+			types = Types
+		}
+		for _, eventType := range types {
+			if !containsType(Types, eventType) {
+				return nil, fmt.Errorf("route %d: no notifications are written for %s events", i+1, eventType)
+			}
+			d.wanted[eventType] = true
+		}
+	}
+	return d, nil
+}
+
+// Start subscribes to the bus and starts delivering
+func (d *Dispatcher) Start() {
+	for _, w := range d.workers {
+		d.wg.Add(1)
+		go d.run(w)
+	}
+	d.subscription = d.bus.SubscribeAll(d.dispatch)
+}
+
+// Stop unsubscribes and delivers what is queued, for at most the
+// delivery timeout
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		d.bus.Unsubscribe(d.subscription)
+		close(d.done)
+		d.wg.Wait()
+		for name, w := range d.workers {
+			if dropped := atomic.LoadInt64(&w.dropped); dropped > 0 {
+				d.logger.Warning(fmt.Sprintf("Notifier %s dropped %d notification(s)", name, dropped))
+			}
+		}
+	})
+}
+
+// dispatch puts an event into words and queues it for the notifiers its
+// routes lead to
+func (d *Dispatcher) dispatch(event events.Event) {
+	if !d.wanted[event.Type()] {
+		return
+	}
+	n, ok := Format(event)
+	if !ok {
+		return
+	}
+	queued := make(map[string]bool)
+	for i := range d.routes {
+		if !d.routes[i].matches(n) {
+			continue
+		}
+		for _, name := range d.routes[i].Notifiers {
+			if queued[name] {
+				continue
+			}
+			queued[name] = true
+			d.enqueue(d.workers[name], n)
+		}
+	}
+}
+
+// enqueue queues a notification unless it repeats one queued within the
+// repeat interval
+func (d *Dispatcher) enqueue(w *worker, n Notification) {
+	if d.options.Repeat > 0 {
+		key := n.Title + "\n" + n.Text
+		now := time.Now()
+		w.mutex.Lock()
+		if now.Sub(w.sent[key]) < d.options.Repeat {
+			w.suppressed[key]++
+			w.mutex.Unlock()
+			return
+		}
+		if repeats := w.suppressed[key]; repeats > 0 {
+			n.Text = strings.TrimSpace(n.Text + fmt.Sprintf("\n(repeated %d more time(s) in the last %s)", repeats, now.Sub(w.sent[key]).Round(time.Second)))
+			delete(w.suppressed, key)
+		}
+		if len(w.sent) >= maxRepeats {
+			forget(w, now.Add(-d.options.Repeat))
+		}
+		w.sent[key] = now
+		w.mutex.Unlock()
+	}
+
+	select {
+	case w.queue <- n:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// forget drops the notifications a worker queued before a time, or all of
+// them if none is that old
+func forget(w *worker, before time.Time) {
+	for key, sent := range w.sent {
+		if sent.Before(before) {
+			delete(w.sent, key)
+			delete(w.suppressed, key)
+		}
+	}
+	if len(w.sent) >= maxRepeats {
+		w.sent = make(map[string]time.Time)
+		w.suppressed = make(map[string]int)
+	}
+}
+
+// run delivers the notifications queued for a notifier until stopped
+func (d *Dispatcher) run(w *worker) {
+	defer d.wg.Done()
+	for {
+		select {
+		case n := <-w.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), d.options.Timeout)
+			d.deliver(ctx, w, n)
+			cancel()
+		case <-d.done:
+			d.drain(w)
+			return
+		}
+	}
+}
+
+// drain delivers what is still queued on shutdown within one timeout
+func (d *Dispatcher) drain(w *worker) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.options.Timeout)
+	defer cancel()
+	for {
+		select {
+		case n := <-w.queue:
+			if ctx.Err() != nil {
+				atomic.AddInt64(&w.dropped, 1)
+				continue
+			}
+			d.deliver(ctx, w, n)
+		default:
+			return
+		}
+	}
+}
+
+// deliver has a notifier deliver a notification
+func (d *Dispatcher) deliver(ctx context.Context, w *worker, n Notification) {
+	if err := w.notifier.Notify(ctx, n); err != nil {
+		d.logger.Warning(fmt.Sprintf("Notifier %s failed to deliver %s notification: %v", w.notifier.Name(), n.Type, err))
+	}
+}
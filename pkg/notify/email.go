@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpsPort is the port of SMTP over implicit TLS
+const smtpsPort = 465
+
+// EmailOptions configures the SMTP server emails are sent through
+type EmailOptions struct {
+	Host string
+	Port int // 587 (STARTTLS) by default; 465 connects over TLS
+	// Username and Password authenticate with PLAIN auth, which is only
+	// sent over TLS or to localhost; empty sends without authenticating
+	Username string
+	Password string
+	From     string
+	To       []string
+	// SubjectPrefix starts every subject, e.g. "[TRADE]"
+	SubjectPrefix string
+}
+
+// Email sends notifications as plain text emails over SMTP
+type Email struct {
+	name    string
+	options EmailOptions
+}
+
+// NewEmail creates a notifier emailing the recipients of options
+func NewEmail(name string, options EmailOptions) (*Email, error) {
+	if options.Host == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if options.From == "" || len(options.To) == 0 {
+		return nil, fmt.Errorf("email sender and recipients are required")
+	}
+	if options.Port == 0 {
+		options.Port = 587
+	}
+	return &Email{name: name, options: options}, nil
+}
+
+// Name returns the name of the notifier
+func (e *Email) Name() string { return e.name }
+
+// Notify sends the notification to the recipients
+func (e *Email) Notify(ctx context.Context, n Notification) error {
+	message, err := e.message(n)
+	if err != nil {
+		return err
+	}
+
+	host := e.options.Host
+	address := net.JoinHostPort(host, strconv.Itoa(e.options.Port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if e.options.Port == smtpsPort {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.options.Port != smtpsPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+	}
+	if e.options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.options.Username, e.options.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.options.From); err != nil {
+		return err
+	}
+	for _, to := range e.options.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message writes the email of a notification
+func (e *Email) message(n Notification) ([]byte, error) {
+	subject := fmt.Sprintf("%s %s", n.Level, n.Title)
+	if e.options.SubjectPrefix != "" {
+		subject = e.options.SubjectPrefix + " " + subject
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.options.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.options.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	lines := []string{n.Title, ""}
+	if n.Text != "" {
+		lines = append(lines, n.Text, "")
+	}
+	lines = append(lines, fmt.Sprintf("Event: %s at %s", n.Type, n.Time.UTC().Format(time.RFC3339)))
+	if _, err := body.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
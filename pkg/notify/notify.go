@@ -0,0 +1,193 @@
+// Package notify tells people about trading events over chat and email:
+// a Notifier delivers human readable notifications to one channel (Slack
+// or Discord webhooks, SMTP email) and a Dispatcher routes the events of
+// the bus to the notifiers by type, severity and symbol, e.g. errors to
+// email and fills to Discord.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/events"
+)
+
+// Notification is an event put into words
+type Notification struct {
+	Level  events.AlertLevel
+	Type   events.Type
+	Symbol string
+	Title  string // One line summary
+	Text   string // Details, one per line
+	Time   time.Time
+}
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	// Name identifies the notifier in routes and logs
+	Name() string
+	// Notify delivers a notification, giving up when ctx is done
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Types are the event types notifications are written for
+var Types = []events.Type{
+	events.TypeSignal, events.TypeOrder, events.TypeFill, events.TypeTradeClosed, events.TypeRiskRejected,
+	events.TypeDailySummary, events.TypeError, events.TypeAlert, events.TypePattern,
+}
+
+// ParseLevel parses a severity: INFO, WARNING or CRITICAL
+func ParseLevel(name string) (events.AlertLevel, error) {
+	level := events.AlertLevel(strings.ToUpper(strings.TrimSpace(name)))
+	switch level {
+	case events.AlertInfo, events.AlertWarning, events.AlertCritical:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown level %q (want INFO, WARNING or CRITICAL)", name)
+}
+
+// severity orders the levels; unknown ones count as INFO
+func severity(level events.AlertLevel) int {
+	switch level {
+	case events.AlertWarning:
+		return 1
+	case events.AlertCritical:
+		return 2
+	}
+	return 0
+}
+
+// Format puts an event into words; false for the types notifications are
+// not written for
+func Format(event events.Event) (Notification, bool) {
+	n := Notification{Level: events.AlertInfo, Type: event.Type(), Symbol: strings.ToUpper(events.SymbolOf(event)), Time: time.Now()}
+	var lines []string
+	switch ev := event.(type) {
+	case *events.SignalEvent:
+		signal := ev.Signal
+		n.Title = fmt.Sprintf("%s %s signal at %.8g", n.Symbol, signal.Action, signal.Price)
+		n.Time = signal.Time
+		lines = append(lines, "Reason: "+signal.Reason)
+		if signal.Strategy != "" {
+			lines = append(lines, "Strategy: "+signal.Strategy)
+		}
+		if signal.ProfitPercent != 0 {
+			lines = append(lines, fmt.Sprintf("Profit: %.2f%%", signal.ProfitPercent))
+		}
+	case *events.OrderEvent:
+		n.Title = fmt.Sprintf("%s %s order of %s at %s", n.Symbol, strings.ToUpper(ev.Side), ev.Quantity, ev.Price)
+		n.Time = ev.Timestamp
+		lines = append(lines, "Order: "+ev.OrderID, "Trade: "+ev.TradeID, "Reason: "+ev.Reason)
+		if ev.Account != "" {
+			lines = append(lines, "Account: "+ev.Account)
+		}
+	case *events.FillEvent:
+		n.Title = fmt.Sprintf("%s %s filled %s at %s", n.Symbol, strings.ToUpper(ev.Side), ev.Quantity, ev.Price)
+		n.Time = ev.Timestamp
+		lines = append(lines, "Order: "+ev.OrderID, "Trade: "+ev.TradeID, "Fee: "+ev.Fee.String())
+		if ev.Account != "" {
+			lines = append(lines, "Account: "+ev.Account)
+		}
+	case *events.TradeClosedEvent:
+		n.Title = fmt.Sprintf("%s %s trade closed: %s %s (%+.2f%%)", n.Symbol, ev.Direction, ev.PnL, ev.Currency, ev.PnLPercent)
+		n.Time = ev.ExitTime
+		lines = append(lines,
+			"Trade: "+ev.TradeID,
+			fmt.Sprintf("Entry %s, exit %s, quantity %s", ev.EntryPrice, ev.ExitPrice, ev.Quantity),
+			"Reason: "+ev.Reason,
+			fmt.Sprintf("Held %s, MFE %.2f%%, MAE %.2f%%", ev.ExitTime.Sub(ev.EntryTime).Round(time.Second), ev.MFE, ev.MAE))
+		if ev.ReportingCurrency != "" && ev.ReportingCurrency != ev.Currency {
+			lines = append(lines, fmt.Sprintf("PnL: %s %s", ev.ReportingPnL, ev.ReportingCurrency))
+		}
+		if len(ev.Tags) > 0 {
+			lines = append(lines, "Tags: "+strings.Join(ev.Tags, ", "))
+		}
+	case *events.RiskRejectedEvent:
+		n.Level = events.AlertWarning
+		n.Title = fmt.Sprintf("%s entry rejected by %s", n.Symbol, ev.Rule)
+		n.Time = ev.Timestamp
+		lines = append(lines, ev.Message, fmt.Sprintf("Value %.2f, limit %.2f", ev.Value, ev.Limit))
+	case *events.DailySummaryEvent:
+		n.Title = fmt.Sprintf("%s daily summary %s: %s %s", n.Symbol, ev.Date, ev.PnL, ev.Currency)
+		n.Time = ev.End
+		lines = append(lines,
+			fmt.Sprintf("Trades: %d (%d won, %d lost, %.1f%%)", ev.Trades, ev.Wins, ev.Losses, ev.WinRate),
+			fmt.Sprintf("Fees: %s %s", ev.Fees, ev.Currency))
+	case *events.ErrorEvent:
+		n.Level = events.AlertCritical
+		n.Title = fmt.Sprintf("Error in %s", ev.Component)
+		n.Time = ev.Timestamp
+		if ev.Err != nil {
+			lines = append(lines, ev.Err.Error())
+		}
+	case *events.AlertEvent:
+		n.Level = ev.Level
+		n.Title = fmt.Sprintf("%s alert from %s", ev.Level, ev.Source)
+		if n.Symbol != "" {
+			n.Title = n.Symbol + " " + n.Title
+		}
+		n.Time = ev.Timestamp
+		lines = append(lines, ev.Message)
+	case *events.PatternEvent:
+		n.Title = fmt.Sprintf("%s %s on %s candles, close %.8g", n.Symbol, ev.Pattern.Name, ev.Pattern.Timeframe, ev.Close)
+		n.Time = ev.Pattern.Time
+	default:
+		return Notification{}, false
+	}
+	n.Text = strings.Join(lines, "\n")
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	return n, true
+}
+
+// maxRetryAfter caps the wait a rate limited webhook asks for
+const maxRetryAfter = 10 * time.Second
+
+// postJSON posts a JSON payload to a webhook, retrying once when rate
+// limited
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt > 0 {
+			return fmt.Errorf("webhook rejected notification: %s %s", resp.Status, strings.TrimSpace(string(message)))
+		}
+
+		wait := time.Second
+		if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+			wait = time.Duration(seconds * float64(time.Second))
+		}
+		if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
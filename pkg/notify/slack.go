@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"TRADE/pkg/events"
+)
+
+// Slack posts notifications to a Slack incoming webhook
+type Slack struct {
+	name     string
+	url      string
+	username string
+	client   *http.Client
+}
+
+// NewSlack creates a notifier posting to a Slack incoming webhook URL, as
+// username if not empty
+func NewSlack(name, url, username string) *Slack {
+	return &Slack{name: name, url: url, username: username, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name returns the name of the notifier
+func (s *Slack) Name() string { return s.name }
+
+// slackColors are the attachment bar colors of the levels
+var slackColors = map[events.AlertLevel]string{
+	events.AlertInfo:     "#2eb886",
+	events.AlertWarning:  "#daa038",
+	events.AlertCritical: "#a30200",
+}
+
+// Notify posts the notification as a colored attachment
+func (s *Slack) Notify(ctx context.Context, n Notification) error {
+	payload := map[string]interface{}{
+		"text": n.Title,
+		"attachments": []map[string]interface{}{{
+			"color":  slackColors[n.Level],
+			"title":  n.Title,
+			"text":   n.Text,
+			"footer": string(n.Type),
+			"ts":     n.Time.Unix(),
+		}},
+	}
+	if s.username != "" {
+		payload["username"] = s.username
+	}
+	return postJSON(ctx, s.client, s.url, payload)
+}
@@ -0,0 +1,234 @@
+// Package patterns detects candlestick patterns on closed OHLC candles:
+// single candle shapes (doji, hammer, shooting star), two candle patterns
+// (engulfing, inside bar) and three candle stars. Patterns are judged on
+// the proportions of the candles alone; whether the market around them
+// makes them meaningful is left to the strategy using them.
+package patterns
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"TRADE/pkg/bars"
+)
+
+// Patterns detected
+const (
+	Doji             = "doji"
+	Hammer           = "hammer"
+	ShootingStar     = "shooting_star"
+	BullishEngulfing = "bullish_engulfing"
+	BearishEngulfing = "bearish_engulfing"
+	InsideBar        = "inside_bar"
+	MorningStar      = "morning_star"
+	EveningStar      = "evening_star"
+)
+
+// Biases of the patterns
+const (
+	Bullish = 1
+	Bearish = -1
+	Neutral = 0 // Indecision: a doji or an inside bar
+)
+
+// MaxCandles is the number of candles the longest pattern spans
+const MaxCandles = 3
+
+// Proportions the patterns are judged on
+const (
+	dojiBody    = 0.1 // Body of a doji, at most, as a fraction of its range
+	shadowRatio = 2.0 // Long shadow of a hammer or star, at least, in bodies
+	longBody    = 0.5 // Body of the first candle of a star, at least, as a fraction of its range
+	starBody    = 0.3 // Body of the middle candle of a star, at most, as a fraction of the first's
+)
+
+// pattern is a detectable pattern
+type pattern struct {
+	name    string
+	bias    int
+	candles int // Candles it spans, the last completing it
+	match   func(candles []bars.Bar) bool
+}
+
+// all are the detectable patterns, in the order they are reported
+var all = []pattern{
+	{Doji, Neutral, 1, doji},
+	{Hammer, Bullish, 1, hammer},
+	{ShootingStar, Bearish, 1, shootingStar},
+	{BullishEngulfing, Bullish, 2, bullishEngulfing},
+	{BearishEngulfing, Bearish, 2, bearishEngulfing},
+	{InsideBar, Neutral, 2, insideBar},
+	{MorningStar, Bullish, 3, morningStar},
+	{EveningStar, Bearish, 3, eveningStar},
+}
+
+// Names returns the names of the detectable patterns
+func Names() []string {
+	names := make([]string, len(all))
+	for i, p := range all {
+		names[i] = p.name
+	}
+	return names
+}
+
+// Bias returns the direction a pattern points to: Bullish, Bearish or
+// Neutral
+func Bias(name string) int {
+	for _, p := range all {
+		if p.name == name {
+			return p.bias
+		}
+	}
+	return Neutral
+}
+
+// Match is a pattern a candle completed
+type Match struct {
+	Name string
+	Bias int
+}
+
+// Detector detects a set of patterns
+type Detector struct {
+	patterns []pattern
+}
+
+// NewDetector creates a detector of the named patterns; none detects all
+func NewDetector(names []string) (*Detector, error) {
+	if len(names) == 0 {
+		return &Detector{patterns: all}, nil
+	}
+	d := &Detector{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, p := range all {
+			if p.name == name {
+				d.patterns = append(d.patterns, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown candlestick pattern %q (want %s)", name, strings.Join(Names(), ", "))
+		}
+	}
+	return d, nil
+}
+
+// Detect returns the patterns the last of candles, oldest first,
+// completes. Patterns spanning more candles than given are not looked for.
+func (d *Detector) Detect(candles []bars.Bar) []Match {
+	var matches []Match
+	for _, p := range d.patterns {
+		if len(candles) < p.candles {
+			continue
+		}
+		window := candles[len(candles)-p.candles:]
+		if flat(window) {
+			continue
+		}
+		if p.match(window) {
+			matches = append(matches, Match{Name: p.name, Bias: p.bias})
+		}
+	}
+	return matches
+}
+
+// flat returns whether a candle has no range, which no pattern is judged on
+func flat(candles []bars.Bar) bool {
+	for _, c := range candles {
+		if c.High <= c.Low {
+			return true
+		}
+	}
+	return false
+}
+
+// body returns the size of a candle's body
+func body(c bars.Bar) float64 {
+	return math.Abs(c.Close - c.Open)
+}
+
+// upperShadow returns the size of a candle's upper shadow
+func upperShadow(c bars.Bar) float64 {
+	return c.High - math.Max(c.Open, c.Close)
+}
+
+// lowerShadow returns the size of a candle's lower shadow
+func lowerShadow(c bars.Bar) float64 {
+	return math.Min(c.Open, c.Close) - c.Low
+}
+
+// bullish returns whether a candle closed above its open
+func bullish(c bars.Bar) bool {
+	return c.Close > c.Open
+}
+
+// bearish returns whether a candle closed below its open
+func bearish(c bars.Bar) bool {
+	return c.Close < c.Open
+}
+
+// isDoji returns whether a candle opened and closed at about the same price
+func isDoji(c bars.Bar) bool {
+	return body(c) <= dojiBody*(c.High-c.Low)
+}
+
+// doji: open and close about equal, indecision
+func doji(candles []bars.Bar) bool {
+	return isDoji(candles[0])
+}
+
+// hammer: a small body at the top of the range, under it a long lower
+// shadow of sellers pushed back
+func hammer(candles []bars.Bar) bool {
+	c := candles[0]
+	return !isDoji(c) && lowerShadow(c) >= shadowRatio*body(c) && upperShadow(c) <= body(c)
+}
+
+// shootingStar: a small body at the bottom of the range, over it a long
+// upper shadow of buyers pushed back
+func shootingStar(candles []bars.Bar) bool {
+	c := candles[0]
+	return !isDoji(c) && upperShadow(c) >= shadowRatio*body(c) && lowerShadow(c) <= body(c)
+}
+
+// bullishEngulfing: a bullish body engulfing the bearish body before it
+func bullishEngulfing(candles []bars.Bar) bool {
+	previous, c := candles[0], candles[1]
+	return bearish(previous) && bullish(c) &&
+		c.Open <= previous.Close && c.Close >= previous.Open && body(c) > body(previous)
+}
+
+// bearishEngulfing: a bearish body engulfing the bullish body before it
+func bearishEngulfing(candles []bars.Bar) bool {
+	previous, c := candles[0], candles[1]
+	return bullish(previous) && bearish(c) &&
+		c.Open >= previous.Close && c.Close <= previous.Open && body(c) > body(previous)
+}
+
+// insideBar: a range within the range before it, a pause
+func insideBar(candles []bars.Bar) bool {
+	previous, c := candles[0], candles[1]
+	return c.High < previous.High && c.Low > previous.Low
+}
+
+// morningStar: a long bearish candle, a small body, then a bullish candle
+// closing past the middle of the first's body
+func morningStar(candles []bars.Bar) bool {
+	first, star, last := candles[0], candles[1], candles[2]
+	return bearish(first) && body(first) >= longBody*(first.High-first.Low) &&
+		body(star) <= starBody*body(first) &&
+		bullish(last) && last.Close > (first.Open+first.Close)/2
+}
+
+// eveningStar: a long bullish candle, a small body, then a bearish candle
+// closing past the middle of the first's body
+func eveningStar(candles []bars.Bar) bool {
+	first, star, last := candles[0], candles[1], candles[2]
+	return bullish(first) && body(first) >= longBody*(first.High-first.Low) &&
+		body(star) <= starBody*body(first) &&
+		bearish(last) && last.Close < (first.Open+first.Close)/2
+}
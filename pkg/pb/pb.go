@@ -0,0 +1,904 @@
+// Package pb encodes market data, signals and trade events in the protobuf
+// wire format defined by proto/trade/v1/trade.proto, for exchange with
+// non-Go services and compact storage. Messages are encoded directly from
+// and decoded into the existing event and types structs.
+package pb
+
+import (
+	"fmt"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// Field numbers of the Event payload oneof
+const (
+	eventTick         = 1
+	eventMetrics      = 2
+	eventSignal       = 3
+	eventOrder        = 4
+	eventFill         = 5
+	eventTradeClosed  = 6
+	eventRiskRejected = 7
+	eventFunding      = 8
+	eventDailySummary = 9
+	eventAlert        = 10
+	eventPattern      = 11
+)
+
+// MarshalEvent encodes a tick, metrics, signal, order, fill, trade-closed,
+// risk-rejected, funding, daily summary, alert or pattern event as a
+// trade.v1.Event message
+func MarshalEvent(event events.Event) ([]byte, error) {
+	var e encoder
+	switch ev := event.(type) {
+	case *events.TickEvent:
+		e.message(eventTick, func(m *encoder) { encodeTick(m, ev.Symbol, ev.Tick) })
+	case *events.MetricsEvent:
+		e.message(eventMetrics, func(m *encoder) { encodeMetricsUpdate(m, ev) })
+	case *events.SignalEvent:
+		e.message(eventSignal, func(m *encoder) { encodeSignal(m, ev.Symbol, ev.Signal) })
+	case *events.OrderEvent:
+		e.message(eventOrder, func(m *encoder) { encodeOrder(m, ev) })
+	case *events.FillEvent:
+		e.message(eventFill, func(m *encoder) { encodeFill(m, ev) })
+	case *events.TradeClosedEvent:
+		e.message(eventTradeClosed, func(m *encoder) { encodeTradeClosed(m, ev) })
+	case *events.RiskRejectedEvent:
+		e.message(eventRiskRejected, func(m *encoder) { encodeRiskRejected(m, ev) })
+	case *events.FundingEvent:
+		e.message(eventFunding, func(m *encoder) { encodeFunding(m, ev) })
+	case *events.DailySummaryEvent:
+		e.message(eventDailySummary, func(m *encoder) { encodeDailySummary(m, ev) })
+	case *events.AlertEvent:
+		e.message(eventAlert, func(m *encoder) { encodeAlert(m, ev) })
+	case *events.PatternEvent:
+		e.message(eventPattern, func(m *encoder) { encodePattern(m, ev) })
+	default:
+		return nil, fmt.Errorf("unsupported event type: %T", event)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalEvent decodes a trade.v1.Event message
+func UnmarshalEvent(data []byte) (events.Event, error) {
+	r := &fieldReader{d: decoder{buf: data}}
+	var event events.Event
+	for field := r.next(); field != 0; field = r.next() {
+		var err error
+		switch field {
+		case eventTick:
+			event, err = decodeTick(r.bytes())
+		case eventMetrics:
+			event, err = decodeMetricsUpdate(r.bytes())
+		case eventSignal:
+			event, err = decodeSignal(r.bytes())
+		case eventOrder:
+			event, err = decodeOrder(r.bytes())
+		case eventFill:
+			event, err = decodeFill(r.bytes())
+		case eventTradeClosed:
+			event, err = decodeTradeClosed(r.bytes())
+		case eventRiskRejected:
+			event, err = decodeRiskRejected(r.bytes())
+		case eventFunding:
+			event, err = decodeFunding(r.bytes())
+		case eventDailySummary:
+			event, err = decodeDailySummary(r.bytes())
+		case eventAlert:
+			event, err = decodeAlert(r.bytes())
+		case eventPattern:
+			event, err = decodePattern(r.bytes())
+		default:
+			r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode event: %v", r.err)
+	}
+	if event == nil {
+		return nil, fmt.Errorf("failed to decode event: no known payload")
+	}
+	return event, nil
+}
+
+// SubscribeRequest selects the events a MarketStream subscriber receives
+type SubscribeRequest struct {
+	Types  []events.Type // Empty means all types
+	Symbol string        // Empty means all symbols
+}
+
+// MarshalSubscribeRequest encodes a trade.v1.SubscribeRequest
+func MarshalSubscribeRequest(req *SubscribeRequest) []byte {
+	var e encoder
+	names := make([]string, len(req.Types))
+	for i, eventType := range req.Types {
+		names[i] = string(eventType)
+	}
+	e.strings(1, names)
+	e.string(2, req.Symbol)
+	return e.buf
+}
+
+// UnmarshalSubscribeRequest decodes a trade.v1.SubscribeRequest
+func UnmarshalSubscribeRequest(data []byte) (*SubscribeRequest, error) {
+	req := &SubscribeRequest{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			req.Types = append(req.Types, events.Type(r.string()))
+		case 2:
+			req.Symbol = r.string()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode subscribe request: %v", r.err)
+	}
+	return req, nil
+}
+
+// encodeTick encodes a trade.v1.Tick
+func encodeTick(e *encoder, symbol string, tick *types.TickData) {
+	e.string(1, symbol)
+	if tick == nil {
+		return
+	}
+	e.time(2, tick.Timestamp)
+	e.double(3, tick.Price)
+	e.double(4, tick.Volume)
+	e.bool(5, tick.IsAsk)
+}
+
+// decodeTick decodes a trade.v1.Tick
+func decodeTick(data []byte) (*events.TickEvent, error) {
+	event := &events.TickEvent{Tick: &types.TickData{}}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			event.Symbol = r.string()
+		case 2:
+			event.Tick.Timestamp = r.time()
+		case 3:
+			event.Tick.Price = r.double()
+		case 4:
+			event.Tick.Volume = r.double()
+		case 5:
+			event.Tick.IsAsk = r.bool()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode tick: %v", r.err)
+	}
+	return event, nil
+}
+
+// encodeMarketMetrics encodes a trade.v1.MarketMetrics
+func encodeMarketMetrics(e *encoder, metrics *types.MarketMetrics) {
+	e.double(1, metrics.RealizedVolatility)
+	e.double(2, metrics.ATR)
+	e.double(3, metrics.RelativeStrength)
+	e.double(4, metrics.OrderImbalance)
+	e.double(5, metrics.TrendStrength)
+	e.double(6, metrics.AvgTrendStrength)
+	e.double(7, metrics.MarketEfficiencyRatio)
+	e.double(8, metrics.BookImbalance)
+	e.double(9, metrics.BookImbalanceTrend)
+	e.double(10, metrics.StrengthDivergence)
+	e.double(11, metrics.DeltaDivergence)
+	e.double(12, metrics.RSI)
+	for i := range metrics.Timeframes {
+		timeframe := &metrics.Timeframes[i]
+		e.message(13, func(m *encoder) { encodeTimeframeMetrics(m, timeframe) })
+	}
+	e.double(14, metrics.SpreadBps)
+	e.double(15, metrics.TopImbalance)
+	e.double(16, metrics.WeightedMid)
+	e.double(17, metrics.MACD)
+	e.double(18, metrics.MACDSignal)
+	e.double(19, metrics.MACDHistogram)
+	e.double(20, metrics.BollingerUpper)
+	e.double(21, metrics.BollingerMiddle)
+	e.double(22, metrics.BollingerLower)
+	e.double(23, metrics.BollingerPercentB)
+	e.double(24, metrics.FastMA)
+	e.double(25, metrics.SlowMA)
+	e.double(26, metrics.MACross)
+	for i := range metrics.Patterns {
+		pattern := &metrics.Patterns[i]
+		e.message(27, func(m *encoder) { encodeCandlePattern(m, pattern) })
+	}
+}
+
+// encodeCandlePattern encodes a trade.v1.CandlePattern
+func encodeCandlePattern(e *encoder, pattern *types.CandlePattern) {
+	e.string(1, pattern.Name)
+	e.int64(2, int64(pattern.Bias))
+	e.string(3, pattern.Timeframe)
+	e.time(4, pattern.Time)
+}
+
+// decodeCandlePattern decodes a trade.v1.CandlePattern
+func decodeCandlePattern(data []byte) (types.CandlePattern, error) {
+	var pattern types.CandlePattern
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			pattern.Name = r.string()
+		case 2:
+			pattern.Bias = int(int32(r.int64()))
+		case 3:
+			pattern.Timeframe = r.string()
+		case 4:
+			pattern.Time = r.time()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return pattern, fmt.Errorf("failed to decode candle pattern: %v", r.err)
+	}
+	return pattern, nil
+}
+
+// encodeTimeframeMetrics encodes a trade.v1.TimeframeMetrics
+func encodeTimeframeMetrics(e *encoder, metrics *types.TimeframeMetrics) {
+	e.string(1, metrics.Timeframe)
+	e.double(2, metrics.Trend)
+	e.double(3, metrics.MarketEfficiencyRatio)
+	e.double(4, metrics.RSI)
+	e.bool(5, metrics.Ready)
+}
+
+// decodeTimeframeMetrics decodes a trade.v1.TimeframeMetrics
+func decodeTimeframeMetrics(data []byte) (types.TimeframeMetrics, error) {
+	var metrics types.TimeframeMetrics
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			metrics.Timeframe = r.string()
+		case 2:
+			metrics.Trend = r.double()
+		case 3:
+			metrics.MarketEfficiencyRatio = r.double()
+		case 4:
+			metrics.RSI = r.double()
+		case 5:
+			metrics.Ready = r.bool()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return metrics, fmt.Errorf("failed to decode timeframe metrics: %v", r.err)
+	}
+	return metrics, nil
+}
+
+// decodeMarketMetrics decodes a trade.v1.MarketMetrics
+func decodeMarketMetrics(data []byte) (*types.MarketMetrics, error) {
+	metrics := &types.MarketMetrics{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			metrics.RealizedVolatility = r.double()
+		case 2:
+			metrics.ATR = r.double()
+		case 3:
+			metrics.RelativeStrength = r.double()
+		case 4:
+			metrics.OrderImbalance = r.double()
+		case 5:
+			metrics.TrendStrength = r.double()
+		case 6:
+			metrics.AvgTrendStrength = r.double()
+		case 7:
+			metrics.MarketEfficiencyRatio = r.double()
+		case 8:
+			metrics.BookImbalance = r.double()
+		case 9:
+			metrics.BookImbalanceTrend = r.double()
+		case 10:
+			metrics.StrengthDivergence = r.double()
+		case 11:
+			metrics.DeltaDivergence = r.double()
+		case 12:
+			metrics.RSI = r.double()
+		case 13:
+			timeframe, err := decodeTimeframeMetrics(r.bytes())
+			if err != nil {
+				return nil, err
+			}
+			metrics.Timeframes = append(metrics.Timeframes, timeframe)
+		case 14:
+			metrics.SpreadBps = r.double()
+		case 15:
+			metrics.TopImbalance = r.double()
+		case 16:
+			metrics.WeightedMid = r.double()
+		case 17:
+			metrics.MACD = r.double()
+		case 18:
+			metrics.MACDSignal = r.double()
+		case 19:
+			metrics.MACDHistogram = r.double()
+		case 20:
+			metrics.BollingerUpper = r.double()
+		case 21:
+			metrics.BollingerMiddle = r.double()
+		case 22:
+			metrics.BollingerLower = r.double()
+		case 23:
+			metrics.BollingerPercentB = r.double()
+		case 24:
+			metrics.FastMA = r.double()
+		case 25:
+			metrics.SlowMA = r.double()
+		case 26:
+			metrics.MACross = r.double()
+		case 27:
+			pattern, err := decodeCandlePattern(r.bytes())
+			if err != nil {
+				return nil, err
+			}
+			metrics.Patterns = append(metrics.Patterns, pattern)
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode market metrics: %v", r.err)
+	}
+	return metrics, nil
+}
+
+// encodeMetricsUpdate encodes a trade.v1.MetricsUpdate
+func encodeMetricsUpdate(e *encoder, event *events.MetricsEvent) {
+	e.string(1, event.Symbol)
+	e.time(2, event.Timestamp)
+	e.double(3, event.Price)
+	if event.Metrics != nil {
+		e.message(4, func(m *encoder) { encodeMarketMetrics(m, event.Metrics) })
+	}
+}
+
+// decodeMetricsUpdate decodes a trade.v1.MetricsUpdate
+func decodeMetricsUpdate(data []byte) (*events.MetricsEvent, error) {
+	event := &events.MetricsEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		var err error
+		switch field {
+		case 1:
+			event.Symbol = r.string()
+		case 2:
+			event.Timestamp = r.time()
+		case 3:
+			event.Price = r.double()
+		case 4:
+			event.Metrics, err = decodeMarketMetrics(r.bytes())
+		default:
+			r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode metrics update: %v", r.err)
+	}
+	return event, nil
+}
+
+// encodeSignal encodes a trade.v1.Signal
+func encodeSignal(e *encoder, symbol string, signal *types.Signal) {
+	if signal == nil {
+		e.string(12, symbol)
+		return
+	}
+	e.string(1, signal.ID)
+	e.string(2, signal.TradeID)
+	e.string(3, signal.CorrelationID)
+	e.string(4, signal.Action)
+	e.string(5, signal.Side)
+	e.double(6, signal.Price)
+	e.time(7, signal.Time)
+	e.string(8, signal.Reason)
+	e.double(9, signal.ProfitPercent)
+	e.double(10, signal.UpdatedStopLoss)
+	if signal.Metrics != nil {
+		e.message(11, func(m *encoder) { encodeMarketMetrics(m, signal.Metrics) })
+	}
+	e.string(12, symbol)
+	e.double(13, signal.MFE)
+	e.double(14, signal.MAE)
+	e.double(15, signal.InitialRisk)
+	e.double(16, signal.Quantity)
+	e.string(17, signal.Direction)
+	e.string(18, signal.Strategy)
+	e.strings(19, signal.Tags)
+}
+
+// decodeSignal decodes a trade.v1.Signal
+func decodeSignal(data []byte) (*events.SignalEvent, error) {
+	signal := &types.Signal{}
+	event := &events.SignalEvent{Signal: signal}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		var err error
+		switch field {
+		case 1:
+			signal.ID = r.string()
+		case 2:
+			signal.TradeID = r.string()
+		case 3:
+			signal.CorrelationID = r.string()
+		case 4:
+			signal.Action = r.string()
+		case 5:
+			signal.Side = r.string()
+		case 6:
+			signal.Price = r.double()
+		case 7:
+			signal.Time = r.time()
+		case 8:
+			signal.Reason = r.string()
+		case 9:
+			signal.ProfitPercent = r.double()
+		case 10:
+			signal.UpdatedStopLoss = r.double()
+		case 11:
+			signal.Metrics, err = decodeMarketMetrics(r.bytes())
+		case 12:
+			event.Symbol = r.string()
+		case 13:
+			signal.MFE = r.double()
+		case 14:
+			signal.MAE = r.double()
+		case 15:
+			signal.InitialRisk = r.double()
+		case 16:
+			signal.Quantity = r.double()
+		case 17:
+			signal.Direction = r.string()
+		case 18:
+			signal.Strategy = r.string()
+		case 19:
+			signal.Tags = append(signal.Tags, r.string())
+		default:
+			r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode signal: %v", r.err)
+	}
+	return event, nil
+}
+
+// encodeOrder encodes a trade.v1.Order
+func encodeOrder(e *encoder, order *events.OrderEvent) {
+	e.string(1, order.OrderID)
+	e.string(2, order.CorrelationID)
+	e.string(3, order.TradeID)
+	e.string(4, order.Symbol)
+	e.string(5, order.Side)
+	e.decimal(6, order.Price)
+	e.decimal(7, order.Quantity)
+	e.string(8, order.Reason)
+	e.time(9, order.Timestamp)
+	e.string(10, order.Account)
+}
+
+// decodeOrder decodes a trade.v1.Order
+func decodeOrder(data []byte) (*events.OrderEvent, error) {
+	order := &events.OrderEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			order.OrderID = r.string()
+		case 2:
+			order.CorrelationID = r.string()
+		case 3:
+			order.TradeID = r.string()
+		case 4:
+			order.Symbol = r.string()
+		case 5:
+			order.Side = r.string()
+		case 6:
+			order.Price = r.decimal()
+		case 7:
+			order.Quantity = r.decimal()
+		case 8:
+			order.Reason = r.string()
+		case 9:
+			order.Timestamp = r.time()
+		case 10:
+			order.Account = r.string()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode order: %v", r.err)
+	}
+	return order, nil
+}
+
+// encodeFill encodes a trade.v1.Fill
+func encodeFill(e *encoder, fill *events.FillEvent) {
+	e.string(1, fill.FillID)
+	e.string(2, fill.OrderID)
+	e.string(3, fill.CorrelationID)
+	e.string(4, fill.TradeID)
+	e.string(5, fill.Symbol)
+	e.string(6, fill.Side)
+	e.decimal(7, fill.Price)
+	e.decimal(8, fill.Quantity)
+	e.decimal(9, fill.Fee)
+	e.time(10, fill.Timestamp)
+	e.string(11, fill.Account)
+}
+
+// decodeFill decodes a trade.v1.Fill
+func decodeFill(data []byte) (*events.FillEvent, error) {
+	fill := &events.FillEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			fill.FillID = r.string()
+		case 2:
+			fill.OrderID = r.string()
+		case 3:
+			fill.CorrelationID = r.string()
+		case 4:
+			fill.TradeID = r.string()
+		case 5:
+			fill.Symbol = r.string()
+		case 6:
+			fill.Side = r.string()
+		case 7:
+			fill.Price = r.decimal()
+		case 8:
+			fill.Quantity = r.decimal()
+		case 9:
+			fill.Fee = r.decimal()
+		case 10:
+			fill.Timestamp = r.time()
+		case 11:
+			fill.Account = r.string()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode fill: %v", r.err)
+	}
+	return fill, nil
+}
+
+// encodeTradeClosed encodes a trade.v1.TradeClosed
+func encodeTradeClosed(e *encoder, trade *events.TradeClosedEvent) {
+	e.string(1, trade.TradeID)
+	e.string(2, trade.CorrelationID)
+	e.string(3, trade.Symbol)
+	e.decimal(4, trade.EntryPrice)
+	e.decimal(5, trade.ExitPrice)
+	e.decimal(6, trade.Quantity)
+	e.decimal(7, trade.PnL)
+	e.double(8, trade.PnLPercent)
+	e.string(9, trade.Reason)
+	e.time(10, trade.EntryTime)
+	e.time(11, trade.ExitTime)
+	e.string(12, trade.Currency)
+	e.decimal(13, trade.ReportingPnL)
+	e.string(14, trade.ReportingCurrency)
+	e.decimal(15, trade.Funding)
+	e.double(16, trade.MFE)
+	e.double(17, trade.MAE)
+	e.string(18, trade.Direction)
+	e.string(19, trade.Strategy)
+	e.strings(20, trade.Tags)
+}
+
+// decodeTradeClosed decodes a trade.v1.TradeClosed
+func decodeTradeClosed(data []byte) (*events.TradeClosedEvent, error) {
+	trade := &events.TradeClosedEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			trade.TradeID = r.string()
+		case 2:
+			trade.CorrelationID = r.string()
+		case 3:
+			trade.Symbol = r.string()
+		case 4:
+			trade.EntryPrice = r.decimal()
+		case 5:
+			trade.ExitPrice = r.decimal()
+		case 6:
+			trade.Quantity = r.decimal()
+		case 7:
+			trade.PnL = r.decimal()
+		case 8:
+			trade.PnLPercent = r.double()
+		case 9:
+			trade.Reason = r.string()
+		case 10:
+			trade.EntryTime = r.time()
+		case 11:
+			trade.ExitTime = r.time()
+		case 12:
+			trade.Currency = r.string()
+		case 13:
+			trade.ReportingPnL = r.decimal()
+		case 14:
+			trade.ReportingCurrency = r.string()
+		case 15:
+			trade.Funding = r.decimal()
+		case 16:
+			trade.MFE = r.double()
+		case 17:
+			trade.MAE = r.double()
+		case 18:
+			trade.Direction = r.string()
+		case 19:
+			trade.Strategy = r.string()
+		case 20:
+			trade.Tags = append(trade.Tags, r.string())
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode trade: %v", r.err)
+	}
+	return trade, nil
+}
+
+// encodeRiskRejected encodes a trade.v1.RiskRejected
+func encodeRiskRejected(e *encoder, rejection *events.RiskRejectedEvent) {
+	e.string(1, rejection.TradeID)
+	e.string(2, rejection.CorrelationID)
+	e.string(3, rejection.Symbol)
+	e.string(4, rejection.Rule)
+	e.double(5, rejection.Notional)
+	e.double(6, rejection.Value)
+	e.double(7, rejection.Limit)
+	e.strings(8, rejection.Related)
+	e.string(9, rejection.Message)
+	e.time(10, rejection.Timestamp)
+}
+
+// decodeRiskRejected decodes a trade.v1.RiskRejected
+func decodeRiskRejected(data []byte) (*events.RiskRejectedEvent, error) {
+	rejection := &events.RiskRejectedEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			rejection.TradeID = r.string()
+		case 2:
+			rejection.CorrelationID = r.string()
+		case 3:
+			rejection.Symbol = r.string()
+		case 4:
+			rejection.Rule = r.string()
+		case 5:
+			rejection.Notional = r.double()
+		case 6:
+			rejection.Value = r.double()
+		case 7:
+			rejection.Limit = r.double()
+		case 8:
+			rejection.Related = append(rejection.Related, r.string())
+		case 9:
+			rejection.Message = r.string()
+		case 10:
+			rejection.Timestamp = r.time()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode risk rejection: %v", r.err)
+	}
+	return rejection, nil
+}
+
+// decimal appends a fixed-point decimal as a string field
+func (e *encoder) decimal(field int, v decimal.Decimal) {
+	if v.IsZero() {
+		return
+	}
+	e.string(field, v.String())
+}
+
+// decimal reads the current field as a fixed-point decimal string
+func (r *fieldReader) decimal() decimal.Decimal {
+	s := r.string()
+	if r.err != nil || s == "" {
+		return decimal.Zero
+	}
+	v, err := decimal.Parse(s)
+	if err != nil {
+		r.err = err
+	}
+	return v
+}
+
+// encodeFunding encodes a trade.v1.Funding
+func encodeFunding(e *encoder, funding *events.FundingEvent) {
+	e.string(1, funding.Symbol)
+	e.double(2, funding.MarkPrice)
+	e.double(3, funding.IndexPrice)
+	e.double(4, funding.Rate)
+	e.time(5, funding.NextFundingTime)
+	e.time(6, funding.Timestamp)
+}
+
+// decodeFunding decodes a trade.v1.Funding
+func decodeFunding(data []byte) (*events.FundingEvent, error) {
+	funding := &events.FundingEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			funding.Symbol = r.string()
+		case 2:
+			funding.MarkPrice = r.double()
+		case 3:
+			funding.IndexPrice = r.double()
+		case 4:
+			funding.Rate = r.double()
+		case 5:
+			funding.NextFundingTime = r.time()
+		case 6:
+			funding.Timestamp = r.time()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode funding: %v", r.err)
+	}
+	return funding, nil
+}
+
+// encodeDailySummary encodes a trade.v1.DailySummary
+func encodeDailySummary(e *encoder, summary *events.DailySummaryEvent) {
+	e.string(1, summary.Symbol)
+	e.string(2, summary.Date)
+	e.time(3, summary.Start)
+	e.time(4, summary.End)
+	e.int64(5, int64(summary.Trades))
+	e.int64(6, int64(summary.Wins))
+	e.int64(7, int64(summary.Losses))
+	e.double(8, summary.WinRate)
+	e.decimal(9, summary.PnL)
+	e.decimal(10, summary.Fees)
+	e.string(11, summary.Currency)
+}
+
+// decodeDailySummary decodes a trade.v1.DailySummary
+func decodeDailySummary(data []byte) (*events.DailySummaryEvent, error) {
+	summary := &events.DailySummaryEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			summary.Symbol = r.string()
+		case 2:
+			summary.Date = r.string()
+		case 3:
+			summary.Start = r.time()
+		case 4:
+			summary.End = r.time()
+		case 5:
+			summary.Trades = int(r.int64())
+		case 6:
+			summary.Wins = int(r.int64())
+		case 7:
+			summary.Losses = int(r.int64())
+		case 8:
+			summary.WinRate = r.double()
+		case 9:
+			summary.PnL = r.decimal()
+		case 10:
+			summary.Fees = r.decimal()
+		case 11:
+			summary.Currency = r.string()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode daily summary: %v", r.err)
+	}
+	return summary, nil
+}
+
+// encodeAlert encodes a trade.v1.Alert
+func encodeAlert(e *encoder, alert *events.AlertEvent) {
+	e.string(1, string(alert.Level))
+	e.string(2, alert.Source)
+	e.string(3, alert.Symbol)
+	e.string(4, alert.Message)
+	e.time(5, alert.Timestamp)
+}
+
+// decodeAlert decodes a trade.v1.Alert
+func decodeAlert(data []byte) (*events.AlertEvent, error) {
+	alert := &events.AlertEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			alert.Level = events.AlertLevel(r.string())
+		case 2:
+			alert.Source = r.string()
+		case 3:
+			alert.Symbol = r.string()
+		case 4:
+			alert.Message = r.string()
+		case 5:
+			alert.Timestamp = r.time()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode alert: %v", r.err)
+	}
+	return alert, nil
+}
+
+// encodePattern encodes a trade.v1.Pattern
+func encodePattern(e *encoder, event *events.PatternEvent) {
+	e.string(1, event.Symbol)
+	e.message(2, func(m *encoder) { encodeCandlePattern(m, &event.Pattern) })
+	e.double(3, event.Close)
+}
+
+// decodePattern decodes a trade.v1.Pattern
+func decodePattern(data []byte) (*events.PatternEvent, error) {
+	event := &events.PatternEvent{}
+	r := &fieldReader{d: decoder{buf: data}}
+	for field := r.next(); field != 0; field = r.next() {
+		switch field {
+		case 1:
+			event.Symbol = r.string()
+		case 2:
+			pattern, err := decodeCandlePattern(r.bytes())
+			if err != nil {
+				return nil, err
+			}
+			event.Pattern = pattern
+		case 3:
+			event.Close = r.double()
+		default:
+			r.skip()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode pattern: %v", r.err)
+	}
+	return event, nil
+}
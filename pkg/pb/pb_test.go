@@ -0,0 +1,284 @@
+package pb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// The golden encodings below were produced by google.golang.org/protobuf
+// (deterministic marshaling of dynamic messages built from
+// proto/trade/v1/trade.proto) for the same values as the events.
+
+var (
+	ts       = time.Unix(0, 1741610096123456789).UTC()
+	ts2      = time.Unix(0, 1741613696000000000).UTC()
+	dayStart = time.Unix(0, 1741564800000000000).UTC()
+	dayEnd   = time.Unix(0, 1741651200000000000).UTC()
+)
+
+var goldenEvents = []struct {
+	name   string
+	event  events.Event
+	golden string
+}{
+	{
+		name: "tick",
+		event: &events.TickEvent{Symbol: "BTCUSDT", Tick: &types.TickData{
+			Price: 50123.45, Volume: 0.015, IsAsk: true, Timestamp: ts,
+		}},
+		golden: "0a270a07425443555344541095dab0e5faa4dc951819666666666e79e84021b81e85eb51b88e3f2801",
+	},
+	{
+		name: "metrics",
+		event: &events.MetricsEvent{Symbol: "BTCUSDT", Price: 50123.45, Timestamp: ts, Metrics: &types.MarketMetrics{
+			RealizedVolatility: 0.012,
+			ATR:                35.5,
+			TrendStrength:      math.Copysign(0, -1),
+			BookImbalance:      -0.25,
+			StrengthDivergence: -1,
+			RSI:                61.25,
+			SpreadBps:          1.5,
+			TopImbalance:       0.5,
+			WeightedMid:        50123.4,
+			BollingerPercentB:  0.5,
+			MACross:            1,
+			Timeframes: []types.TimeframeMetrics{
+				{Timeframe: "5m", Trend: 0.02, MarketEfficiencyRatio: 0.4, RSI: 55, Ready: true},
+				{Timeframe: "1h", Trend: -0.01, RSI: 48.5},
+			},
+			Patterns: []types.CandlePattern{
+				{Name: "bearish_engulfing", Bias: -1, Timeframe: "5m", Time: time.Unix(0, 1741609800000000000).UTC()},
+			},
+		}},
+		golden: "12ef010a07425443555344541095dab0e5faa4dc951819666666666e79e84022d00109fa7e6abc7493883f1100000000" +
+			"00c0414029000000000000008041000000000000d0bf51000000000000f0bf610000000000a04e406a210a02356d117b14" +
+			"ae47e17a943f199a9999999999d93f210000000000804b4028016a160a023168117b14ae47e17a84bf21000000000040" +
+			"484071000000000000f83f79000000000000e03f8101cdcccccc6c79e840b901000000000000e03fd101000000000000" +
+			"f03fda012c0a11626561726973685f656e67756c66696e6710ffffffffffffffffff011a02356d2080a0dbd2ab9cdc9518",
+	},
+	{
+		name: "signal",
+		event: &events.SignalEvent{Symbol: "BTCUSDT", Signal: &types.Signal{
+			ID:              "sig-1",
+			TradeID:         "trade-1",
+			CorrelationID:   "corr-1",
+			Action:          "BUY",
+			Side:            "BUY",
+			Price:           50123.45,
+			Time:            ts,
+			Reason:          "momentum breakout",
+			UpdatedStopLoss: 49900,
+			Metrics:         &types.MarketMetrics{RSI: 50},
+			InitialRisk:     223.45,
+			Quantity:        0.02,
+			Direction:       "buy",
+			Strategy:        "momentum",
+			Tags:            []string{"regime:trending", "", "session:us"},
+		}},
+		golden: "1aab010a057369672d31120774726164652d311a06636f72722d3122034255592a0342555931666666666e79e8403895" +
+			"dab0e5faa4dc951842116d6f6d656e74756d20627265616b6f75745100000000805de8405a0961000000000000494062" +
+			"0742544355534454796666666666ee6b4081017b14ae47e17a943f8a01036275799201086d6f6d656e74756d9a010f72" +
+			"6567696d653a7472656e64696e679a01009a010a73657373696f6e3a7573",
+	},
+	{
+		name: "order",
+		event: &events.OrderEvent{
+			OrderID:       "order-1",
+			CorrelationID: "corr-1",
+			TradeID:       "trade-1",
+			Symbol:        "BTCUSDT",
+			Side:          "BUY",
+			Price:         decimal.MustParse("50123.45"),
+			Quantity:      decimal.MustParse("0.02"),
+			Reason:        "entry",
+			Account:       "main",
+			Timestamp:     ts,
+		},
+		golden: "224f0a076f726465722d311206636f72722d311a0774726164652d312207425443555344542a03425559320835303132" +
+			"332e34353a04302e30324205656e7472794895dab0e5faa4dc951852046d61696e",
+	},
+	{
+		name: "fill",
+		event: &events.FillEvent{
+			FillID:        "fill-1",
+			OrderID:       "order-1",
+			CorrelationID: "corr-1",
+			TradeID:       "trade-1",
+			Symbol:        "BTCUSDT",
+			Side:          "BUY",
+			Price:         decimal.MustParse("50123.45"),
+			Quantity:      decimal.MustParse("0.02"),
+			Fee:           decimal.MustParse("0.01002469"),
+			Account:       "main",
+			Timestamp:     ts,
+		},
+		golden: "2a5c0a0666696c6c2d3112076f726465722d311a06636f72722d31220774726164652d312a07425443555344543203" +
+			"4255593a0835303132332e34354204302e30324a0a302e30313030323436395095dab0e5faa4dc95185a046d61696e",
+	},
+	{
+		name: "trade closed",
+		event: &events.TradeClosedEvent{
+			TradeID:           "trade-1",
+			CorrelationID:     "corr-1",
+			Symbol:            "BTCUSDT",
+			EntryPrice:        decimal.MustParse("50123.45"),
+			ExitPrice:         decimal.MustParse("50350"),
+			Quantity:          decimal.MustParse("0.02"),
+			PnL:               decimal.MustParse("4.5185"),
+			PnLPercent:        0.45,
+			Reason:            "take_profit",
+			Direction:         "buy",
+			Strategy:          "momentum",
+			EntryTime:         ts,
+			ExitTime:          ts2,
+			Currency:          "USDT",
+			MFE:               0.6,
+			MAE:               -0.12,
+			Funding:           decimal.MustParse("-0.0125"),
+			ReportingPnL:      decimal.MustParse("4.15"),
+			ReportingCurrency: "EUR",
+			Tags:              []string{"regime:trending"},
+		},
+		golden: "32b4010a0774726164652d311206636f72722d311a0742544355534454220835303132332e34352a0535303335303204" +
+			"302e30323a06342e3531383541cdccccccccccdc3f4a0b74616b655f70726f6669745095dab0e5faa4dc951858808" +
+			"0a4b0dd8ddd95186204555344546a04342e313572034555527a072d302e303132358101333333333333e33f8901b81e" +
+			"85eb51b8bebf9201036275799a01086d6f6d656e74756da2010f726567696d653a7472656e64696e67",
+	},
+	{
+		name: "risk rejected",
+		event: &events.RiskRejectedEvent{
+			TradeID:       "trade-2",
+			CorrelationID: "corr-2",
+			Symbol:        "BTCUSDT",
+			Rule:          "max_correlated_notional",
+			Notional:      1002.47,
+			Value:         5100,
+			Limit:         5000,
+			Related:       []string{"ETHUSDT", "SOLUSDT"},
+			Message:       "correlated notional over limit",
+			Timestamp:     ts,
+		},
+		golden: "3a8a010a0774726164652d321206636f72722d321a074254435553445422176d61785f636f7272656c617465645f6e" +
+			"6f74696f6e616c29f6285c8fc2538f40310000000000ecb34039000000000088b3404207455448555344544207534f" +
+			"4c555344544a1e636f7272656c61746564206e6f74696f6e616c206f766572206c696d69745095dab0e5faa4dc9518",
+	},
+	{
+		name: "funding",
+		event: &events.FundingEvent{
+			Symbol:          "BTCUSDT",
+			MarkPrice:       50120.1,
+			IndexPrice:      50118.7,
+			Rate:            -0.0001,
+			NextFundingTime: ts2,
+			Timestamp:       ts,
+		},
+		golden: "42380a074254435553445411333333330379e8401966666666d678e840212d431cebe2361abf288080a4b0dd8ddd9518" +
+			"3095dab0e5faa4dc9518",
+	},
+	{
+		name: "daily summary",
+		event: &events.DailySummaryEvent{
+			Symbol:   "BTCUSDT",
+			Date:     "2025-03-10",
+			Start:    dayStart,
+			End:      dayEnd,
+			Trades:   8,
+			Wins:     7,
+			Losses:   1,
+			WinRate:  87.5,
+			PnL:      decimal.MustParse("26.06"),
+			Fees:     decimal.MustParse("1.2"),
+			Currency: "USDT",
+		},
+		golden: "4a4a0a0742544355534454120a323032352d30332d3130188080cccad5fed1951820808088d59ed1e595182808300738" +
+			"01410000000000e055404a0532362e30365203312e325a0455534454",
+	},
+	{
+		name: "alert",
+		event: &events.AlertEvent{
+			Level:     events.AlertCritical,
+			Source:    "watchdog",
+			Symbol:    "BTCUSDT",
+			Message:   "no ticks for 30s",
+			Timestamp: ts,
+		},
+		golden: "52390a08435249544943414c12087761746368646f671a074254435553445422106e6f207469636b7320666f72203330" +
+			"732895dab0e5faa4dc9518",
+	},
+	{
+		name: "pattern",
+		event: &events.PatternEvent{
+			Symbol:  "BTCUSDT",
+			Pattern: types.CandlePattern{Name: "hammer", Bias: 1, Timeframe: "15m", Time: time.Unix(0, 1741609200000000000).UTC()},
+			Close:   50123.45,
+		},
+		golden: "5a2d0a074254435553445412190a0668616d6d657210011a0331356d2080c0b5bcf08adc951819666666666e79e840",
+	},
+}
+
+func TestMarshalEventMatchesReference(t *testing.T) {
+	for _, test := range goldenEvents {
+		got, err := MarshalEvent(test.event)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if want := mustHex(t, test.golden); !bytes.Equal(got, want) {
+			t.Errorf("%s: encoded\n%x\nwant\n%x", test.name, got, want)
+		}
+	}
+}
+
+func TestUnmarshalEventFromReference(t *testing.T) {
+	for _, test := range goldenEvents {
+		got, err := UnmarshalEvent(mustHex(t, test.golden))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.event) {
+			t.Errorf("%s: decoded\n%+v\nwant\n%+v", test.name, got, test.event)
+		}
+	}
+}
+
+func TestSubscribeRequestMatchesReference(t *testing.T) {
+	req := &SubscribeRequest{Types: []events.Type{events.TypeTick, events.TypeSignal}, Symbol: "BTCUSDT"}
+	golden := mustHex(t, "0a047469636b0a067369676e616c120742544355534454")
+	if got := MarshalSubscribeRequest(req); !bytes.Equal(got, golden) {
+		t.Errorf("encoded %x, want %x", got, golden)
+	}
+	got, err := UnmarshalSubscribeRequest(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, req) {
+		t.Errorf("decoded %+v, want %+v", got, req)
+	}
+}
+
+func TestUnmarshalEventRejectsTruncatedInput(t *testing.T) {
+	golden := mustHex(t, goldenEvents[0].golden)
+	for n := 1; n < len(golden); n++ {
+		if _, err := UnmarshalEvent(golden[:n]); err == nil {
+			t.Errorf("decoded %d of %d bytes without error", n, len(golden))
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
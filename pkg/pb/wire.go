@@ -0,0 +1,272 @@
+package pb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// encoder appends protobuf fields to a buffer. Fields holding their
+// default value are omitted, as in proto3.
+type encoder struct {
+	buf []byte
+}
+
+// varint appends an unsigned varint
+func (e *encoder) varint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// tag appends a field key
+func (e *encoder) tag(field, wireType int) {
+	e.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// int64 appends an int64 field
+func (e *encoder) int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(uint64(v))
+}
+
+// bool appends a bool field
+func (e *encoder) bool(field int, v bool) {
+	if !v {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(1)
+}
+
+// double appends a double field; negative zero is not the default and is
+// written
+func (e *encoder) double(field int, v float64) {
+	if math.Float64bits(v) == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// string appends a string field
+func (e *encoder) string(field int, v string) {
+	if v == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// strings appends a repeated string field. Unlike singular strings, empty
+// elements are written.
+func (e *encoder) strings(field int, values []string) {
+	for _, v := range values {
+		e.tag(field, wireBytes)
+		e.varint(uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	}
+}
+
+// time appends a timestamp field as Unix nanoseconds
+func (e *encoder) time(field int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	e.int64(field, t.UnixNano())
+}
+
+// message appends an embedded message field. Unlike scalars it is written
+// even when empty, so presence survives the round trip.
+func (e *encoder) message(field int, encode func(*encoder)) {
+	var inner encoder
+	encode(&inner)
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(inner.buf)))
+	e.buf = append(e.buf, inner.buf...)
+}
+
+// decoder reads protobuf fields from a buffer
+type decoder struct {
+	buf []byte
+}
+
+// next reads the next field key; it returns false at the end of the buffer
+func (d *decoder) next() (field, wireType int, ok bool, err error) {
+	if len(d.buf) == 0 {
+		return 0, 0, false, nil
+	}
+	key, err := d.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	field, wireType = int(key>>3), int(key&7)
+	if field <= 0 {
+		return 0, 0, false, fmt.Errorf("invalid field number %d", field)
+	}
+	return field, wireType, true, nil
+}
+
+// varint reads an unsigned varint
+func (d *decoder) varint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		return 0, fmt.Errorf("malformed varint")
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+// fixed64 reads a little-endian 64-bit value
+func (d *decoder) fixed64() (uint64, error) {
+	if len(d.buf) < 8 {
+		return 0, fmt.Errorf("truncated fixed64")
+	}
+	v := binary.LittleEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v, nil
+}
+
+// bytes reads a length-delimited value
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)) {
+		return nil, fmt.Errorf("truncated length-delimited field")
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+// skip discards a field of an unknown number, for forward compatibility
+func (d *decoder) skip(wireType int) error {
+	switch wireType {
+	case wireVarint:
+		_, err := d.varint()
+		return err
+	case wireFixed64:
+		_, err := d.fixed64()
+		return err
+	case wireBytes:
+		_, err := d.bytes()
+		return err
+	case wireFixed32:
+		if len(d.buf) < 4 {
+			return fmt.Errorf("truncated fixed32")
+		}
+		d.buf = d.buf[4:]
+		return nil
+	default:
+		return fmt.Errorf("unsupported wire type %d", wireType)
+	}
+}
+
+// fieldReader decodes a message field by field. Each accessor checks the
+// wire type of the current field and records the first error.
+type fieldReader struct {
+	d        decoder
+	wireType int
+	err      error
+}
+
+// next advances to the next field and returns its number, or 0 at the end
+// of the message or after an error
+func (r *fieldReader) next() int {
+	if r.err != nil {
+		return 0
+	}
+	field, wireType, ok, err := r.d.next()
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	if !ok {
+		return 0
+	}
+	r.wireType = wireType
+	return field
+}
+
+// expect records an error unless the current field has the wire type
+func (r *fieldReader) expect(wireType int) bool {
+	if r.wireType != wireType {
+		r.err = fmt.Errorf("unexpected wire type %d (want %d)", r.wireType, wireType)
+		return false
+	}
+	return true
+}
+
+// skip discards the current field
+func (r *fieldReader) skip() {
+	if err := r.d.skip(r.wireType); err != nil {
+		r.err = err
+	}
+}
+
+// int64 reads the current field as an int64
+func (r *fieldReader) int64() int64 {
+	if !r.expect(wireVarint) {
+		return 0
+	}
+	v, err := r.d.varint()
+	if err != nil {
+		r.err = err
+	}
+	return int64(v)
+}
+
+// bool reads the current field as a bool
+func (r *fieldReader) bool() bool {
+	return r.int64() != 0
+}
+
+// double reads the current field as a double
+func (r *fieldReader) double() float64 {
+	if !r.expect(wireFixed64) {
+		return 0
+	}
+	v, err := r.d.fixed64()
+	if err != nil {
+		r.err = err
+	}
+	return math.Float64frombits(v)
+}
+
+// string reads the current field as a string
+func (r *fieldReader) string() string {
+	return string(r.bytes())
+}
+
+// bytes reads the current field as raw bytes (strings and messages)
+func (r *fieldReader) bytes() []byte {
+	if !r.expect(wireBytes) {
+		return nil
+	}
+	v, err := r.d.bytes()
+	if err != nil {
+		r.err = err
+	}
+	return v
+}
+
+// time reads the current field as a timestamp in Unix nanoseconds
+func (r *fieldReader) time() time.Time {
+	if nanos := r.int64(); nanos != 0 {
+		return time.Unix(0, nanos).UTC()
+	}
+	return time.Time{}
+}
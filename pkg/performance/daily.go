@@ -0,0 +1,91 @@
+package performance
+
+import (
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+)
+
+// DailySummary is the performance of the trades closed in one day
+type DailySummary struct {
+	Date     string          `json:"date"` // YYYY-MM-DD of the day summarized, in its time zone
+	Start    time.Time       `json:"start"`
+	End      time.Time       `json:"end"`
+	Trades   int             `json:"trades"`
+	Wins     int             `json:"wins"`
+	Losses   int             `json:"losses"`
+	WinRate  float64         `json:"win_rate"` // Percent of the trades
+	PnL      decimal.Decimal `json:"pnl"`
+	Fees     decimal.Decimal `json:"fees"`
+	Currency string          `json:"currency"` // Currency of PnL and fees
+}
+
+// Day accumulates the trades closed and fees paid since it was last
+// closed, for the daily summary
+type Day struct {
+	start  time.Time
+	trades int
+	wins   int
+	losses int
+	pnl    decimal.Decimal
+	fees   decimal.Decimal
+	mutex  sync.Mutex
+}
+
+// NewDay creates a day starting at start
+func NewDay(start time.Time) *Day {
+	return &Day{start: start}
+}
+
+// Record adds a closed trade, by its PnL in the reporting currency
+func (d *Day) Record(trade *events.TradeClosedEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pnl := trade.NormalizedPnL()
+	d.trades++
+	switch pnl.Sign() {
+	case 1:
+		d.wins++
+	case -1:
+		d.losses++
+	}
+	d.pnl = d.pnl.Add(pnl)
+}
+
+// AddFee adds a fee paid on a fill
+func (d *Day) AddFee(fee decimal.Decimal) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.fees = d.fees.Add(fee)
+}
+
+// Close returns the summary of the day ending at end, dated in location,
+// and starts the next day at end
+func (d *Day) Close(end time.Time, location *time.Location, currency string) DailySummary {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// A day ending at midnight is dated by the day before it
+	summary := DailySummary{
+		Date:     end.In(location).Add(-time.Nanosecond).Format("2006-01-02"),
+		Start:    d.start,
+		End:      end,
+		Trades:   d.trades,
+		Wins:     d.wins,
+		Losses:   d.losses,
+		PnL:      d.pnl,
+		Fees:     d.fees,
+		Currency: currency,
+	}
+	if d.trades > 0 {
+		summary.WinRate = float64(d.wins) / float64(d.trades) * 100
+	}
+
+	d.start = end
+	d.trades, d.wins, d.losses = 0, 0, 0
+	d.pnl, d.fees = decimal.Zero, decimal.Zero
+	return summary
+}
@@ -0,0 +1,59 @@
+package performance
+
+import (
+	"sync"
+	"time"
+
+	"TRADE/pkg/types"
+)
+
+// EquityCurve follows the marked-to-market equity of a session and its
+// drawdown from the highest equity reached
+type EquityCurve struct {
+	initial float64
+	last    types.EquityPoint
+	mutex   sync.RWMutex
+}
+
+// NewEquityCurve starts a curve at the initial capital
+func NewEquityCurve(initial float64) *EquityCurve {
+	return &EquityCurve{
+		initial: initial,
+		last:    types.EquityPoint{Equity: initial, Peak: initial},
+	}
+}
+
+// Mark records the equity at t from the realized PnL of closed trades and
+// the unrealized PnL of open positions
+func (c *EquityCurve) Mark(t time.Time, realized, unrealized float64) types.EquityPoint {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	point := types.EquityPoint{
+		Time:          t,
+		Equity:        c.initial + realized + unrealized,
+		RealizedPnL:   realized,
+		UnrealizedPnL: unrealized,
+		Peak:          c.last.Peak,
+		MaxDrawdown:   c.last.MaxDrawdown,
+	}
+	if point.Equity > point.Peak {
+		point.Peak = point.Equity
+	}
+	point.Drawdown = point.Peak - point.Equity
+	if point.Peak > 0 {
+		point.DrawdownPercent = point.Drawdown / point.Peak * 100
+	}
+	if point.DrawdownPercent > point.MaxDrawdown {
+		point.MaxDrawdown = point.DrawdownPercent
+	}
+	c.last = point
+	return point
+}
+
+// Current returns the last marked equity
+func (c *EquityCurve) Current() types.EquityPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.last
+}
@@ -0,0 +1,86 @@
+package performance
+
+import (
+	"sync"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+)
+
+// FundingSettlement is a funding settlement of a perpetual contract
+type FundingSettlement struct {
+	Time      time.Time `json:"time"`
+	Rate      float64   `json:"rate"`
+	MarkPrice float64   `json:"mark_price"`
+}
+
+// Payment returns the funding a long position of quantity receives at the
+// settlement: the position's notional at the mark price times the rate,
+// negative (paid) when the rate is positive
+func (s FundingSettlement) Payment(quantity decimal.Decimal) decimal.Decimal {
+	notional := quantity.Mul(decimal.FromFloat(s.MarkPrice))
+	return notional.Mul(decimal.FromFloat(s.Rate)).Neg()
+}
+
+// Funding follows the funding rate announced on the mark price stream of a
+// perpetual contract and reports each settlement once it has passed. A
+// settlement is settled at the last rate and mark price announced for it.
+type Funding struct {
+	next        FundingSettlement // Upcoming settlement
+	last        FundingSettlement // Last settlement passed
+	settlements int64
+	booked      decimal.Decimal
+	mutex       sync.Mutex
+}
+
+// FundingStats describes the upcoming and last settlements and the
+// payments booked
+type FundingStats struct {
+	Next        *FundingSettlement `json:"next,omitempty"`
+	Last        *FundingSettlement `json:"last,omitempty"`
+	Settlements int64              `json:"settlements"`
+	Booked      decimal.Decimal    `json:"booked"` // Received, negative if paid
+}
+
+// Update records a mark price update. Once an update announces a later
+// funding time, the settlement announced before has passed and is returned.
+func (f *Funding) Update(event *events.FundingEvent) (FundingSettlement, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	announced := FundingSettlement{Time: event.NextFundingTime, Rate: event.Rate, MarkPrice: event.MarkPrice}
+	if f.next.Time.IsZero() || !announced.Time.After(f.next.Time) {
+		f.next = announced
+		return FundingSettlement{}, false
+	}
+	settled := f.next
+	f.next = announced
+	f.last = settled
+	f.settlements++
+	return settled, true
+}
+
+// Book adds a payment to the total booked against positions
+func (f *Funding) Book(amount decimal.Decimal) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.booked = f.booked.Add(amount)
+}
+
+// Stats returns the upcoming and last settlements and the payments booked
+func (f *Funding) Stats() FundingStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stats := FundingStats{Settlements: f.settlements, Booked: f.booked}
+	if !f.next.Time.IsZero() {
+		next := f.next
+		stats.Next = &next
+	}
+	if !f.last.Time.IsZero() {
+		last := f.last
+		stats.Last = &last
+	}
+	return stats
+}
@@ -0,0 +1,64 @@
+package performance
+
+import (
+	"sync"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// Strategies measures the performance of each strategy instance apart,
+// from the instance named on the closed trades recorded
+type Strategies struct {
+	names    []string // Instances in reporting order
+	trackers map[string]*Tracker
+	mutex    sync.RWMutex
+}
+
+// NewStrategies creates per-strategy trackers for the named instances
+func NewStrategies(names []string) *Strategies {
+	s := &Strategies{trackers: make(map[string]*Tracker, len(names))}
+	for _, name := range names {
+		s.tracker(name)
+	}
+	return s
+}
+
+// tracker returns the tracker of an instance, adding it when first seen;
+// the caller holds the write lock or is the constructor
+func (s *Strategies) tracker(name string) *Tracker {
+	tracker, ok := s.trackers[name]
+	if !ok {
+		tracker = NewTracker(nil)
+		s.trackers[name] = tracker
+		s.names = append(s.names, name)
+	}
+	return tracker
+}
+
+// Record adds a closed trade to the metrics of its instance
+func (s *Strategies) Record(trade *events.TradeClosedEvent) {
+	s.mutex.Lock()
+	tracker := s.tracker(trade.Strategy)
+	s.mutex.Unlock()
+	tracker.Record(trade)
+}
+
+// Names returns the instances measured, in reporting order
+func (s *Strategies) Names() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]string(nil), s.names...)
+}
+
+// Metrics returns a copy of the metrics of each instance, by name
+func (s *Strategies) Metrics() map[string]*types.PerformanceMetrics {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	metrics := make(map[string]*types.PerformanceMetrics, len(s.trackers))
+	for name, tracker := range s.trackers {
+		metrics[name] = tracker.Metrics()
+	}
+	return metrics
+}
@@ -0,0 +1,212 @@
+package performance
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// Tracker maintains performance metrics from closed trades. It consumes
+// trade-closed events, so live, paper and backtest runs are measured the
+// same way.
+type Tracker struct {
+	bus          *events.Bus
+	metrics      types.PerformanceMetrics
+	totalPnL     decimal.Decimal
+	grossProfit  decimal.Decimal
+	grossLoss    decimal.Decimal
+	peakPnL      decimal.Decimal // Highest cumulative PnL seen, for drawdown
+	maxDrawdown  decimal.Decimal
+	totalMFE     float64 // Sums of the trades' excursions, for the averages
+	totalMAE     float64
+	sumReturns   float64 // Sums of the trades' returns and their squares, for the Sharpe ratio
+	sumSquares   float64
+	calendar     *calendar.Calendar    // Trading days the daily returns are taken over
+	tradingDays  float64               // Trading days in a year, annualizing the daily Sharpe ratio
+	days         map[time.Time]float64 // Sum of the trades' returns by trading day of their exit
+	subscription events.SubscriptionID
+	subscribed   bool
+	mutex        sync.RWMutex
+}
+
+// NewTracker creates a performance tracker for the trades published on bus
+func NewTracker(bus *events.Bus) *Tracker {
+	return &Tracker{
+		bus:         bus,
+		calendar:    calendar.Crypto(),
+		tradingDays: 365,
+		days:        make(map[time.Time]float64),
+	}
+}
+
+// SetAnnualization annualizes the Sharpe ratio of the daily returns over
+// the trading days of a calendar, tradingDays of them in a year. By
+// default every UTC day trades, 365 of them a year. It must be set before
+// trades are recorded.
+func (t *Tracker) SetAnnualization(cal *calendar.Calendar, tradingDays float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.calendar = cal
+	t.tradingDays = tradingDays
+}
+
+// Start subscribes to trade-closed events
+func (t *Tracker) Start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.subscribed {
+		return
+	}
+	t.subscription = t.bus.Subscribe(events.TypeTradeClosed, func(event events.Event) {
+		t.Record(event.(*events.TradeClosedEvent))
+	})
+	t.subscribed = true
+}
+
+// Stop unsubscribes from trade-closed events; metrics remain available
+func (t *Tracker) Stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.subscribed {
+		return
+	}
+	t.bus.Unsubscribe(t.subscription)
+	t.subscribed = false
+}
+
+// Record adds a closed trade to the metrics
+func (t *Tracker) Record(trade *events.TradeClosedEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Trades of different quote currencies add up in the reporting currency
+	pnl := trade.NormalizedPnL()
+
+	t.metrics.TotalTrades++
+	switch pnl.Sign() {
+	case 1:
+		t.metrics.WinningTrades++
+		t.grossProfit = t.grossProfit.Add(pnl)
+	case -1:
+		t.metrics.LosingTrades++
+		t.grossLoss = t.grossLoss.Sub(pnl)
+	}
+
+	// Drawdown is measured on the cumulative realized PnL curve
+	t.totalPnL = t.totalPnL.Add(pnl)
+	if t.totalPnL.Cmp(t.peakPnL) > 0 {
+		t.peakPnL = t.totalPnL
+	}
+	if drawdown := t.peakPnL.Sub(t.totalPnL); drawdown.Cmp(t.maxDrawdown) > 0 {
+		t.maxDrawdown = drawdown
+	}
+
+	if held := trade.ExitTime.Sub(trade.EntryTime); held > 0 {
+		t.metrics.ExposureTime += held
+	}
+	t.totalMFE += trade.MFE
+	t.totalMAE += trade.MAE
+	t.sumReturns += trade.PnLPercent
+	t.sumSquares += trade.PnLPercent * trade.PnLPercent
+	t.days[t.calendar.TradingDay(trade.ExitTime)] += trade.PnLPercent
+
+	t.metrics.WinRate = float64(t.metrics.WinningTrades) / float64(t.metrics.TotalTrades) * 100
+	t.metrics.TotalPnL = t.totalPnL.Float64()
+	t.metrics.AveragePnL = t.metrics.TotalPnL / float64(t.metrics.TotalTrades)
+	t.metrics.AverageMFE = t.totalMFE / float64(t.metrics.TotalTrades)
+	t.metrics.AverageMAE = t.totalMAE / float64(t.metrics.TotalTrades)
+	t.metrics.MaxDrawdown = t.maxDrawdown.Float64()
+	t.metrics.ProfitFactor = profitFactor(t.grossProfit, t.grossLoss)
+	t.metrics.SharpeRatio = sharpeRatio(t.sumReturns, t.sumSquares, t.metrics.TotalTrades)
+	t.metrics.AnnualizedSharpe = t.annualizedSharpe()
+	t.metrics.Expectancy = t.sumReturns / float64(t.metrics.TotalTrades)
+}
+
+// Metrics returns a copy of the current metrics
+func (t *Tracker) Metrics() *types.PerformanceMetrics {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	metrics := t.metrics
+	return &metrics
+}
+
+// Reset clears all recorded trades
+func (t *Tracker) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.metrics = types.PerformanceMetrics{}
+	t.totalPnL = decimal.Zero
+	t.grossProfit = decimal.Zero
+	t.grossLoss = decimal.Zero
+	t.peakPnL = decimal.Zero
+	t.maxDrawdown = decimal.Zero
+	t.totalMFE = 0
+	t.totalMAE = 0
+	t.sumReturns = 0
+	t.sumSquares = 0
+	clear(t.days)
+}
+
+// profitFactor returns gross profit over gross loss, or 0 while it is
+// undefined (no losing trades yet) so the value stays valid JSON
+func profitFactor(grossProfit, grossLoss decimal.Decimal) float64 {
+	if grossLoss.IsZero() {
+		return 0
+	}
+	return grossProfit.Float64() / grossLoss.Float64()
+}
+
+// sharpeRatio returns the mean of n trade returns over their sample
+// standard deviation, unannualized, or 0 while it is undefined (fewer than
+// two trades or identical returns)
+func sharpeRatio(sum, sumSquares float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	mean := sum / float64(n)
+	variance := (sumSquares - float64(n)*mean*mean) / float64(n-1)
+	if variance <= 0 {
+		return 0
+	}
+	return mean / math.Sqrt(variance)
+}
+
+// annualizedSharpe returns the Sharpe ratio of the daily returns from the
+// first to the last trading day with a closed trade, trading days without
+// one returning 0, annualized over the trading days in a year
+func (t *Tracker) annualizedSharpe() float64 {
+	if len(t.days) == 0 {
+		return 0
+	}
+	var first, last time.Time
+	for day := range t.days {
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+		if day.After(last) {
+			last = day
+		}
+	}
+
+	sum, sumSquares, n := 0.0, 0.0, 0
+	for day := first; !day.After(last); day = t.calendar.TradingDay(day.AddDate(0, 0, 1)) {
+		total, traded := t.days[day]
+		if !traded && !t.calendar.IsTradingDay(day) {
+			continue
+		}
+		sum += total
+		sumSquares += total * total
+		n++
+	}
+	return sharpeRatio(sum, sumSquares, n) * math.Sqrt(t.tradingDays)
+}
@@ -0,0 +1,294 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"TRADE/pkg/errs"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/rolling"
+)
+
+// Allocation holds the capital assigned to one strategy/symbol pair
+type Allocation struct {
+	Name          string
+	Weight        float64   // Fraction of total capital assigned
+	Capital       float64   // Capital assigned (Weight * total capital)
+	Exposure      float64   // Notional currently in open positions
+	RecentReturns []float64 // Returns of recently closed positions (fractions), oldest first
+}
+
+// allocation is an Allocation with the ring buffer its recent returns are
+// kept in
+type allocation struct {
+	Allocation
+	returns *rolling.Window[float64]
+}
+
+// Portfolio allocates capital across strategies and enforces exposure limits
+type Portfolio struct {
+	totalCapital float64
+	maxExposure  float64 // Max total exposure as a fraction of total capital
+	minWeight    float64
+	maxWeight    float64
+	historySize  int
+	allocations  map[string]*allocation
+	logger       logger.Interface
+	stopChan     chan struct{}
+	mutex        sync.RWMutex
+}
+
+// NewPortfolio creates a portfolio with the given capital and exposure limit
+func NewPortfolio(totalCapital, maxExposure float64, log logger.Interface) *Portfolio {
+	return &Portfolio{
+		totalCapital: totalCapital,
+		maxExposure:  maxExposure,
+		minWeight:    0.05,
+		maxWeight:    0.60,
+		historySize:  20,
+		allocations:  make(map[string]*allocation),
+		logger:       log,
+	}
+}
+
+// SetWeightBounds sets the minimum and maximum weight a rebalance may assign
+func (p *Portfolio) SetWeightBounds(minWeight, maxWeight float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.minWeight = minWeight
+	p.maxWeight = maxWeight
+}
+
+// Register adds a strategy/symbol allocation with an initial weight
+func (p *Portfolio) Register(name string, weight float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, exists := p.allocations[name]; exists {
+		return fmt.Errorf("allocation %s already registered", name)
+	}
+	if weight <= 0 || weight > 1 {
+		return fmt.Errorf("invalid weight %.2f for %s", weight, name)
+	}
+
+	totalWeight := weight
+	for _, alloc := range p.allocations {
+		totalWeight += alloc.Weight
+	}
+	if totalWeight > 1+1e-9 {
+		return fmt.Errorf("total weight %.2f exceeds 1.0", totalWeight)
+	}
+
+	p.allocations[name] = &allocation{
+		Allocation: Allocation{
+			Name:    name,
+			Weight:  weight,
+			Capital: weight * p.totalCapital,
+		},
+		returns: rolling.NewWindow[float64](p.historySize),
+	}
+	return nil
+}
+
+// AvailableCapital returns the unused capital of an allocation
+func (p *Portfolio) AvailableCapital(name string) float64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	alloc, ok := p.allocations[name]
+	if !ok {
+		return 0
+	}
+	return math.Max(0, alloc.Capital-alloc.Exposure)
+}
+
+// Reserve books notional exposure for a new position if limits allow it
+func (p *Portfolio) Reserve(name string, notional float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	alloc, ok := p.allocations[name]
+	if !ok {
+		return fmt.Errorf("unknown allocation %s", name)
+	}
+	if notional <= 0 {
+		return fmt.Errorf("invalid notional %.2f", notional)
+	}
+
+	// Per-allocation capital limit
+	if alloc.Exposure+notional > alloc.Capital+1e-9 {
+		return errs.Errorf(errs.ErrRiskLimit, "", "allocation %s capital exceeded: %.2f + %.2f > %.2f",
+			name, alloc.Exposure, notional, alloc.Capital)
+	}
+
+	// Portfolio-wide exposure limit
+	totalExposure := notional
+	for _, a := range p.allocations {
+		totalExposure += a.Exposure
+	}
+	limit := p.maxExposure * p.totalCapital
+	if totalExposure > limit+1e-9 {
+		return errs.Errorf(errs.ErrRiskLimit, "", "total exposure %.2f > %.2f", totalExposure, limit)
+	}
+
+	alloc.Exposure += notional
+	return nil
+}
+
+// Unreserve frees exposure reserved for an entry that was not filled,
+// without recording a return
+func (p *Portfolio) Unreserve(name string, notional float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if alloc, ok := p.allocations[name]; ok {
+		alloc.Exposure = math.Max(0, alloc.Exposure-notional)
+	}
+}
+
+// Release frees exposure when a position closes and records its return
+func (p *Portfolio) Release(name string, notional float64, returnPct float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	alloc, ok := p.allocations[name]
+	if !ok {
+		return
+	}
+
+	alloc.Exposure = math.Max(0, alloc.Exposure-notional)
+
+	// Realized PnL changes the capital base
+	pnl := notional * returnPct / 100
+	p.totalCapital += pnl
+	alloc.Capital += pnl
+
+	alloc.returns.Push(returnPct / 100)
+}
+
+// Rebalance redistributes weights based on recent performance.
+// Each allocation is scored by its cumulative recent return; scores are
+// normalized into weights clamped to the configured bounds.
+func (p *Portfolio) Rebalance() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.allocations) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(p.allocations))
+	for name := range p.allocations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Score allocations by their recent cumulative return
+	scores := make(map[string]float64, len(names))
+	totalScore := 0.0
+	for _, name := range names {
+		alloc := p.allocations[name]
+		score := alloc.Weight
+		head, tail := alloc.returns.View().Segments()
+		for _, ret := range head {
+			score *= 1 + ret
+		}
+		for _, ret := range tail {
+			score *= 1 + ret
+		}
+		score = math.Max(score, 1e-6)
+		scores[name] = score
+		totalScore += score
+	}
+
+	// Normalize, clamp and renormalize the weights
+	weights := make(map[string]float64, len(names))
+	totalWeight := 0.0
+	for _, name := range names {
+		weight := scores[name] / totalScore
+		weight = math.Max(p.minWeight, math.Min(p.maxWeight, weight))
+		weights[name] = weight
+		totalWeight += weight
+	}
+
+	for _, name := range names {
+		alloc := p.allocations[name]
+		alloc.Weight = weights[name] / totalWeight
+		alloc.Capital = alloc.Weight * p.totalCapital
+		p.logger.Info(fmt.Sprintf("Rebalanced %s: weight %.2f, capital %.2f", name, alloc.Weight, alloc.Capital))
+	}
+}
+
+// StartRebalancing rebalances the portfolio periodically until Stop is called
+func (p *Portfolio) StartRebalancing(interval time.Duration) {
+	p.mutex.Lock()
+	if p.stopChan != nil {
+		p.mutex.Unlock()
+		return
+	}
+	p.stopChan = make(chan struct{})
+	stopChan := p.stopChan
+	p.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.Rebalance()
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops periodic rebalancing
+func (p *Portfolio) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopChan != nil {
+		close(p.stopChan)
+		p.stopChan = nil
+	}
+}
+
+// GetAllocations returns a copy of all allocations sorted by name
+func (p *Portfolio) GetAllocations() []Allocation {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	result := make([]Allocation, 0, len(p.allocations))
+	for _, alloc := range p.allocations {
+		allocCopy := alloc.Allocation
+		allocCopy.RecentReturns = alloc.returns.Values()
+		result = append(result, allocCopy)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// TotalCapital returns the current capital base of the portfolio
+func (p *Portfolio) TotalCapital() float64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.totalCapital
+}
+
+// TotalExposure returns the notional in open positions across allocations
+func (p *Portfolio) TotalExposure() float64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	total := 0.0
+	for _, alloc := range p.allocations {
+		total += alloc.Exposure
+	}
+	return total
+}
@@ -0,0 +1,167 @@
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/version"
+)
+
+// natsConn is a minimal NATS client connection: it publishes messages and
+// answers server PINGs, which is all a fire-and-forget publisher needs
+type natsConn struct {
+	conn   net.Conn
+	writer *bufio.Writer
+	mutex  sync.Mutex // Guards writer; PONG replies come from the reader
+	errs   chan error // Receives the first read error or -ERR from the server
+}
+
+// dialNATS connects to a NATS server URL (nats://[user:pass@]host:port or
+// nats://token@host:port) and completes the CONNECT handshake
+func dialNATS(rawURL string, timeout time.Duration) (*natsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %v", err)
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme: %s", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// The server greets with INFO
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read NATS INFO: %v", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected NATS greeting: %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "trade",
+		"lang":     "go",
+		"version":  version.Version,
+		"protocol": 1,
+	}
+	if user := u.User; user != nil {
+		if pass, ok := user.Password(); ok {
+			options["user"] = user.Username()
+			options["pass"] = pass
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// PING after CONNECT; the PONG confirms the server accepted us
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send NATS CONNECT: %v", err)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("NATS handshake failed: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("NATS server rejected connection: %s", line)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{
+		conn:   conn,
+		writer: bufio.NewWriter(conn),
+		errs:   make(chan error, 1),
+	}
+	go c.readLoop(reader)
+	return c, nil
+}
+
+// readLoop answers PINGs and reports server errors until the connection fails
+func (c *natsConn) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.mutex.Lock()
+			c.writer.WriteString("PONG\r\n")
+			err = c.writer.Flush()
+			c.mutex.Unlock()
+			if err != nil {
+				c.fail(err)
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.fail(fmt.Errorf("NATS server error: %s", line))
+			return
+		}
+	}
+}
+
+// fail records the first connection error
+func (c *natsConn) fail(err error) {
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+// publish buffers a PUB message; call flush to send buffered messages
+func (c *natsConn) publish(subject string, payload []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	fmt.Fprintf(c.writer, "PUB %s %d\r\n", subject, len(payload))
+	c.writer.Write(payload)
+	_, err := c.writer.WriteString("\r\n")
+	return err
+}
+
+// flush sends buffered messages
+func (c *natsConn) flush() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.writer.Flush()
+}
+
+// close flushes and closes the connection
+func (c *natsConn) close() error {
+	c.flush()
+	return c.conn.Close()
+}
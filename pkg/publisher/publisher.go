@@ -0,0 +1,244 @@
+// Package publisher pushes signals and trade events from the event bus onto
+// NATS subjects for low-latency fan-out to other in-house systems.
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/pb"
+)
+
+// DefaultTypes are the event types published when none are configured
+var DefaultTypes = []events.Type{events.TypeSignal, events.TypeOrder, events.TypeFill, events.TypeTradeClosed, events.TypeDailySummary, events.TypeAlert}
+
+// Options configures the publisher
+type Options struct {
+	URL string // nats://[user:pass@]host:port
+	// Topic is the subject template; {symbol} and {type} are replaced by
+	// the event's symbol and type, e.g. "trade.{symbol}.{type}"
+	Topic string
+	// Topics overrides Topic per symbol
+	Topics map[string]string
+	// Types lists the event types to publish; empty means DefaultTypes
+	Types []events.Type
+	// Format is "protobuf" (trade.v1.Event, default) or "json"
+	Format     string
+	BufferSize int // Messages buffered while disconnected before dropping
+}
+
+// message is an encoded event waiting to be published
+type message struct {
+	subject string
+	payload []byte
+}
+
+// Publisher forwards bus events to NATS in the background. Publishing never
+// blocks trading: when the buffer is full, new messages are dropped.
+type Publisher struct {
+	bus          *events.Bus
+	options      Options
+	logger       logger.Interface
+	types        map[events.Type]bool
+	queue        chan message
+	dropped      int64
+	subscription events.SubscriptionID
+	done         chan struct{}
+	stopped      chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewPublisher creates a publisher for the events published on bus
+func NewPublisher(bus *events.Bus, options Options, log logger.Interface) (*Publisher, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("publisher URL is required")
+	}
+	if options.Topic == "" {
+		options.Topic = "trade.{symbol}.{type}"
+	}
+	switch options.Format {
+	case "":
+		options.Format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return nil, fmt.Errorf("unknown publisher format: %s", options.Format)
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 10000
+	}
+	if len(options.Types) == 0 {
+		options.Types = DefaultTypes
+	}
+
+	types := make(map[events.Type]bool)
+	for _, eventType := range options.Types {
+		types[eventType] = true
+	}
+	return &Publisher{
+		bus:     bus,
+		options: options,
+		logger:  log,
+		types:   types,
+		queue:   make(chan message, options.BufferSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// Start subscribes to the bus and starts publishing
+func (p *Publisher) Start() {
+	p.subscription = p.bus.SubscribeAll(p.enqueue)
+	go p.run()
+}
+
+// Stop unsubscribes, sends what is buffered if connected and disconnects
+func (p *Publisher) Stop() {
+	p.stopOnce.Do(func() {
+		p.bus.Unsubscribe(p.subscription)
+		close(p.done)
+		<-p.stopped
+		if dropped := atomic.LoadInt64(&p.dropped); dropped > 0 {
+			p.logger.Warning(fmt.Sprintf("Publisher dropped %d message(s)", dropped))
+		}
+	})
+}
+
+// enqueue encodes a bus event and queues it for publishing
+func (p *Publisher) enqueue(event events.Event) {
+	if !p.types[event.Type()] {
+		return
+	}
+	payload, err := p.encode(event)
+	if err != nil {
+		p.logger.Debug(fmt.Sprintf("Publisher skipped %s event: %v", event.Type(), err))
+		return
+	}
+
+	select {
+	case p.queue <- message{subject: p.subject(event), payload: payload}:
+	default:
+		atomic.AddInt64(&p.dropped, 1)
+	}
+}
+
+// encode converts an event to the configured wire format
+func (p *Publisher) encode(event events.Event) ([]byte, error) {
+	if p.options.Format == "json" {
+		return json.Marshal(struct {
+			Type  events.Type  `json:"type"`
+			Event events.Event `json:"event"`
+		}{event.Type(), event})
+	}
+	return pb.MarshalEvent(event)
+}
+
+// subject returns the NATS subject of an event
+func (p *Publisher) subject(event events.Event) string {
+	symbol := strings.ToLower(events.SymbolOf(event))
+	topic := p.options.Topic
+	if override, ok := p.options.Topics[symbol]; ok {
+		topic = override
+	}
+	if symbol == "" {
+		symbol = "all"
+	}
+	return strings.NewReplacer("{symbol}", symbol, "{type}", string(event.Type())).Replace(topic)
+}
+
+// run publishes queued messages until stopped, reconnecting with backoff
+func (p *Publisher) run() {
+	defer close(p.stopped)
+
+	var conn *natsConn
+	defer func() {
+		if conn != nil {
+			conn.close()
+		}
+	}()
+	backoff := time.Duration(0)
+
+	for {
+		if conn == nil {
+			if backoff > 0 {
+				select {
+				case <-time.After(backoff):
+				case <-p.done:
+					return
+				}
+			}
+			var err error
+			if conn, err = dialNATS(p.options.URL, 5*time.Second); err != nil {
+				if backoff == 0 {
+					backoff = time.Second
+				} else if backoff < time.Minute {
+					backoff *= 2
+				}
+				p.logger.Warning(fmt.Sprintf("Publisher connection failed (retrying in %s): %v", backoff, err))
+				continue
+			}
+			backoff = 0
+			p.logger.Info(fmt.Sprintf("Publisher connected to %s", redactURL(p.options.URL)))
+		}
+
+		var err error
+		select {
+		case msg := <-p.queue:
+			err = p.publish(conn, msg)
+		case err = <-conn.errs:
+		case <-p.done:
+			p.drain(conn)
+			return
+		}
+		if err != nil {
+			p.logger.Warning(fmt.Sprintf("Publisher disconnected: %v", err))
+			conn.conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// publish sends a message plus everything else already queued in one flush
+func (p *Publisher) publish(conn *natsConn, msg message) error {
+	for {
+		if err := conn.publish(msg.subject, msg.payload); err != nil {
+			return err
+		}
+		select {
+		case msg = <-p.queue:
+			continue
+		default:
+		}
+		return conn.flush()
+	}
+}
+
+// drain sends whatever is still queued on shutdown
+func (p *Publisher) drain(conn *natsConn) {
+	for {
+		select {
+		case msg := <-p.queue:
+			if conn.publish(msg.subject, msg.payload) != nil {
+				return
+			}
+		default:
+			conn.flush()
+			return
+		}
+	}
+}
+
+// redactURL hides credentials in a URL for logging
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,127 @@
+package risk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KillSwitchLimits configures the kill-switch. Zero disables a limit.
+type KillSwitchLimits struct {
+	MaxDailyLoss         float64 // Realized loss of a trading day, in the reporting currency
+	MaxConsecutiveLosses int     // Losing trades in a row
+	MaxOpenExposure      float64 // Open notional marked to market, in the reporting currency
+	// Flatten closes the open positions when the switch trips, instead of
+	// only halting new entries
+	Flatten bool
+}
+
+// Trip describes why the kill-switch tripped
+type Trip struct {
+	Rule  Rule
+	Value float64 // Value that broke the limit
+	Limit float64
+	Time  time.Time
+}
+
+// String describes the broken limit
+func (t Trip) String() string {
+	return fmt.Sprintf("%s: %.6g (limit %.6g)", t.Rule, t.Value, t.Limit)
+}
+
+// Kill-switch rules
+const (
+	RuleDailyLoss         Rule = "max_daily_loss"
+	RuleConsecutiveLosses Rule = "max_consecutive_losses"
+	RuleOpenExposure      Rule = "max_open_exposure"
+)
+
+// KillSwitch halts trading once losses or open exposure go past their
+// limits. It stays tripped until Reset, even across trading days, so a
+// human decides when trading resumes.
+type KillSwitch struct {
+	limits   KillSwitchLimits
+	day      time.Time // Trading day the daily PnL is counted for
+	dailyPnL float64
+	losses   int // Losing trades in a row
+	trip     *Trip
+	mutex    sync.Mutex
+}
+
+// NewKillSwitch creates a kill-switch with the given limits
+func NewKillSwitch(limits KillSwitchLimits) *KillSwitch {
+	return &KillSwitch{limits: limits}
+}
+
+// Limits returns the configured limits
+func (k *KillSwitch) Limits() KillSwitchLimits {
+	return k.limits
+}
+
+// RecordTrade adds the realized PnL of a trade closed on a trading day and
+// returns the trip if the trade broke a limit; nil otherwise, or if the
+// switch had already tripped
+func (k *KillSwitch) RecordTrade(day time.Time, pnl float64, at time.Time) *Trip {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if !day.Equal(k.day) {
+		k.day = day
+		k.dailyPnL = 0
+	}
+	k.dailyPnL += pnl
+	if pnl < 0 {
+		k.losses++
+	} else {
+		k.losses = 0
+	}
+
+	if k.limits.MaxDailyLoss > 0 && -k.dailyPnL > k.limits.MaxDailyLoss {
+		return k.tripped(Trip{Rule: RuleDailyLoss, Value: -k.dailyPnL, Limit: k.limits.MaxDailyLoss, Time: at})
+	}
+	if k.limits.MaxConsecutiveLosses > 0 && k.losses >= k.limits.MaxConsecutiveLosses {
+		return k.tripped(Trip{Rule: RuleConsecutiveLosses, Value: float64(k.losses),
+			Limit: float64(k.limits.MaxConsecutiveLosses), Time: at})
+	}
+	return nil
+}
+
+// CheckExposure checks the open notional marked to market and returns the
+// trip if it is over the limit; nil otherwise, or if the switch had
+// already tripped
+func (k *KillSwitch) CheckExposure(exposure float64, at time.Time) *Trip {
+	if k.limits.MaxOpenExposure <= 0 || exposure <= k.limits.MaxOpenExposure {
+		return nil
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.tripped(Trip{Rule: RuleOpenExposure, Value: exposure, Limit: k.limits.MaxOpenExposure, Time: at})
+}
+
+// tripped trips the switch unless it already is, and returns the new trip
+func (k *KillSwitch) tripped(trip Trip) *Trip {
+	if k.trip != nil {
+		return nil
+	}
+	k.trip = &trip
+	return &trip
+}
+
+// Tripped returns why the switch tripped; ok is false while it has not
+func (k *KillSwitch) Tripped() (trip Trip, ok bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.trip == nil {
+		return trip, false
+	}
+	return *k.trip, true
+}
+
+// Reset re-arms the switch and restarts the count of losses in a row; the
+// daily PnL keeps counting towards the day's limit
+func (k *KillSwitch) Reset() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.trip = nil
+	k.losses = 0
+}
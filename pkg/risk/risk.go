@@ -0,0 +1,472 @@
+// Package risk checks every new entry against the exposure limits of the
+// whole book: the number of open positions, notional per symbol and in
+// total, each symbol's contribution to portfolio volatility, and the
+// notional held across symbols that move together. Entries whose initial
+// stop is too far away are refused or resized first (SizeForStop).
+//
+// Notionals and capital are in the reporting currency. Volatility and
+// correlation are estimated from returns sampled at a fixed interval, so
+// they need a warm-up before the rules that use them take effect.
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/errs"
+	"TRADE/pkg/rolling"
+)
+
+// minSamples is the number of returns needed to estimate volatility and
+// correlation; rules that need them are skipped until then
+const minSamples = 10
+
+// tradingDay scales sampled volatility to a daily figure unless set per
+// symbol; crypto trades around the clock
+const tradingDay = 24 * time.Hour
+
+// Limits configures the risk checks. Zero disables a limit.
+type Limits struct {
+	MaxOpenPositions  int     // Open positions across all symbols
+	MaxSymbolNotional float64 // Notional held in one symbol
+	MaxTotalNotional  float64 // Notional held across all symbols
+	// MaxVolatilityContribution caps a symbol's share of the daily portfolio
+	// volatility, as a fraction of capital (0.02 = 2% of capital per day)
+	MaxVolatilityContribution float64
+	// MaxCorrelatedNotional caps the notional held in a symbol together with
+	// the symbols whose return correlation with it is at least
+	// CorrelationThreshold
+	MaxCorrelatedNotional float64
+	CorrelationThreshold  float64
+	SampleInterval        time.Duration // Spacing of the sampled returns
+	Window                int           // Number of returns kept per symbol
+	// MaxStopDistance caps the distance to an entry's initial stop as a
+	// fraction of its price (0.02 = 2%), and MaxStopRisk the loss at that
+	// stop as a fraction of capital (0.01 = 1%)
+	MaxStopDistance float64
+	MaxStopRisk     float64
+	// ResizeStops shrinks an entry over a stop limit until its loss at the
+	// stop fits, instead of refusing it
+	ResizeStops bool
+}
+
+// Rule names the limit an entry broke
+type Rule string
+
+const (
+	// Rules
+	RuleOpenPositions          Rule = "max_open_positions"
+	RuleSymbolNotional         Rule = "max_symbol_notional"
+	RuleTotalNotional          Rule = "max_total_notional"
+	RuleVolatilityContribution Rule = "max_volatility_contribution"
+	RuleCorrelatedNotional     Rule = "max_correlated_notional"
+	RuleStopDistance           Rule = "max_stop_distance"
+	RuleStopRisk               Rule = "max_stop_risk"
+)
+
+// Rejection describes an entry refused by a risk rule
+type Rejection struct {
+	Rule     Rule
+	Symbol   string
+	Notional float64  // Notional of the refused entry
+	Value    float64  // Value the entry would have brought the rule to
+	Limit    float64  // Configured limit
+	Related  []string // Correlated symbols counted by RuleCorrelatedNotional
+}
+
+// Error describes the broken limit
+func (r *Rejection) Error() string {
+	msg := fmt.Sprintf("%s %s: %.6g > %.6g", r.Symbol, r.Rule, r.Value, r.Limit)
+	if len(r.Related) > 0 {
+		msg += fmt.Sprintf(" (with %s)", strings.Join(r.Related, ", "))
+	}
+	return msg
+}
+
+// sample is a return over one sample interval
+type sample struct {
+	bucket int64 // Interval whose close the return ends at
+	value  float64
+}
+
+// series holds the sampled returns of one symbol
+type series struct {
+	bucket  int64   // Interval of the last price
+	price   float64 // Last price, the close of bucket once it ends
+	close   float64 // Close of the interval before bucket; 0 until one ended
+	returns *rolling.Window[sample]
+}
+
+// Exposure is the open risk of the book
+type Exposure struct {
+	Positions int
+	Total     float64
+	Symbols   map[string]float64 // Open notional per symbol
+}
+
+// Manager tracks open exposure and the return history needed by the
+// volatility and correlation rules
+type Manager struct {
+	limits    Limits
+	notional  map[string]float64
+	positions map[string]int
+	series    map[string]*series
+	days      map[string]time.Duration // Trading day of the symbols not trading around the clock
+	mutex     sync.RWMutex
+}
+
+// NewManager creates a risk manager with the given limits
+func NewManager(limits Limits) *Manager {
+	if limits.SampleInterval <= 0 {
+		limits.SampleInterval = time.Minute
+	}
+	if limits.Window < minSamples {
+		limits.Window = minSamples
+	}
+	return &Manager{
+		limits:    limits,
+		notional:  make(map[string]float64),
+		positions: make(map[string]int),
+		series:    make(map[string]*series),
+		days:      make(map[string]time.Duration),
+	}
+}
+
+// SetTradingDay sets the trading time in a day of a symbol, which scales
+// its sampled volatility to a daily figure
+func (m *Manager) SetTradingDay(symbol string, length time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.days[strings.ToLower(symbol)] = length
+}
+
+// Limits returns the configured limits
+func (m *Manager) Limits() Limits {
+	return m.limits
+}
+
+// ObservePrice records a price of a symbol; one return is sampled per
+// interval, from the close of the previous interval to its own, the last
+// price seen in each
+func (m *Manager) ObservePrice(symbol string, price float64, t time.Time) {
+	if price <= 0 {
+		return
+	}
+	symbol = strings.ToLower(symbol)
+	bucket := t.UnixNano() / int64(m.limits.SampleInterval)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s, ok := m.series[symbol]
+	if !ok {
+		m.series[symbol] = &series{bucket: bucket, price: price, returns: rolling.NewWindow[sample](m.limits.Window)}
+		return
+	}
+	if bucket > s.bucket {
+		if s.close > 0 {
+			s.returns.Push(sample{bucket: s.bucket, value: math.Log(s.price / s.close)})
+		}
+		s.close = s.price
+		s.bucket = bucket
+	}
+	s.price = price
+}
+
+// Check reports whether an entry of notional in symbol fits the limits
+// given the capital of the book. A refused entry returns an ErrRiskLimit
+// error wrapping a *Rejection.
+func (m *Manager) Check(symbol string, notional, capital float64) error {
+	symbol = strings.ToLower(symbol)
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if rejection := m.check(symbol, notional, capital); rejection != nil {
+		return errs.Wrap(errs.ErrRiskLimit, "risk.Check", rejection)
+	}
+	return nil
+}
+
+// SizeForStop checks an entry of notional at price, whose initial stop is
+// stopDistance below it, against the stop limits given the capital of the
+// book. It returns the notional to enter with: unchanged within the
+// limits, or reduced to fit them with ResizeStops. Otherwise a broken limit
+// returns an ErrRiskLimit error wrapping a *Rejection.
+func (m *Manager) SizeForStop(symbol string, notional, price, stopDistance, capital float64) (float64, error) {
+	limits := m.limits
+	if notional <= 0 || price <= 0 || stopDistance <= 0 {
+		return notional, nil
+	}
+	distance := stopDistance / price
+	sized := notional
+	var rejection *Rejection
+
+	// A stop twice the allowed distance risks what twice the notional at
+	// the allowed distance would
+	if limits.MaxStopDistance > 0 && distance > limits.MaxStopDistance {
+		sized = notional * limits.MaxStopDistance / distance
+		rejection = &Rejection{Rule: RuleStopDistance, Value: distance, Limit: limits.MaxStopDistance}
+	}
+	if limits.MaxStopRisk > 0 && capital > 0 {
+		if loss := sized * distance / capital; loss > limits.MaxStopRisk {
+			sized = limits.MaxStopRisk * capital / distance
+			if rejection == nil {
+				rejection = &Rejection{Rule: RuleStopRisk, Value: loss, Limit: limits.MaxStopRisk}
+			}
+		}
+	}
+
+	if rejection == nil || limits.ResizeStops {
+		return sized, nil
+	}
+	rejection.Symbol = strings.ToLower(symbol)
+	rejection.Notional = notional
+	return 0, errs.Wrap(errs.ErrRiskLimit, "risk.SizeForStop", rejection)
+}
+
+// check applies the rules in order and returns the first broken one
+func (m *Manager) check(symbol string, notional, capital float64) *Rejection {
+	limits := m.limits
+	reject := func(rule Rule, value, limit float64) *Rejection {
+		return &Rejection{Rule: rule, Symbol: symbol, Notional: notional, Value: value, Limit: limit}
+	}
+
+	if limits.MaxOpenPositions > 0 {
+		open := 0
+		for _, count := range m.positions {
+			open += count
+		}
+		if open+1 > limits.MaxOpenPositions {
+			return reject(RuleOpenPositions, float64(open+1), float64(limits.MaxOpenPositions))
+		}
+	}
+
+	if limits.MaxSymbolNotional > 0 {
+		if value := m.notional[symbol] + notional; value > limits.MaxSymbolNotional+1e-9 {
+			return reject(RuleSymbolNotional, value, limits.MaxSymbolNotional)
+		}
+	}
+
+	if limits.MaxTotalNotional > 0 {
+		value := notional
+		for _, open := range m.notional {
+			value += open
+		}
+		if value > limits.MaxTotalNotional+1e-9 {
+			return reject(RuleTotalNotional, value, limits.MaxTotalNotional)
+		}
+	}
+
+	if limits.MaxVolatilityContribution > 0 && capital > 0 {
+		if contribution, ok := m.contribution(symbol, notional); ok {
+			if value := contribution / capital; value > limits.MaxVolatilityContribution {
+				return reject(RuleVolatilityContribution, value, limits.MaxVolatilityContribution)
+			}
+		}
+	}
+
+	if limits.MaxCorrelatedNotional > 0 {
+		value := m.notional[symbol] + notional
+		var related []string
+		for other, open := range m.notional {
+			if other == symbol || open <= 0 {
+				continue
+			}
+			if rho, ok := m.correlation(symbol, other); ok && rho >= limits.CorrelationThreshold {
+				value += open
+				related = append(related, other)
+			}
+		}
+		if value > limits.MaxCorrelatedNotional+1e-9 {
+			rejection := reject(RuleCorrelatedNotional, value, limits.MaxCorrelatedNotional)
+			sort.Strings(related)
+			rejection.Related = related
+			return rejection
+		}
+	}
+	return nil
+}
+
+// contribution returns the daily volatility symbol would contribute to the
+// book after adding notional, in the reporting currency. The contributions
+// of all symbols add up to the portfolio volatility. ok is false until the
+// symbol's volatility can be estimated.
+func (m *Manager) contribution(symbol string, notional float64) (float64, bool) {
+	sigma, ok := m.volatility(symbol)
+	if !ok {
+		return 0, false
+	}
+
+	book := make(map[string]float64, len(m.notional)+1)
+	for other, open := range m.notional {
+		if open > 0 {
+			book[other] = open
+		}
+	}
+	book[symbol] += notional
+
+	// Covariance of symbol with the book, and the book's variance; symbols
+	// without enough history are left out
+	covariance, variance := 0.0, 0.0
+	for a, notionalA := range book {
+		sigmaA, ok := m.volatility(a)
+		if !ok {
+			continue
+		}
+		for b, notionalB := range book {
+			sigmaB, ok := m.volatility(b)
+			if !ok {
+				continue
+			}
+			rho := 1.0
+			if a != b {
+				if rho, ok = m.correlation(a, b); !ok {
+					rho = 0
+				}
+			}
+			term := notionalA * notionalB * sigmaA * sigmaB * rho
+			variance += term
+			if a == symbol {
+				covariance += term
+			}
+		}
+	}
+	if variance <= 0 || sigma == 0 {
+		return 0, true
+	}
+	return covariance / math.Sqrt(variance), true
+}
+
+// volatility returns the daily volatility of a symbol's returns
+func (m *Manager) volatility(symbol string) (float64, bool) {
+	s, ok := m.series[symbol]
+	if !ok || s.returns.Len() < minSamples {
+		return 0, false
+	}
+	n := s.returns.Len()
+	mean := 0.0
+	for i := 0; i < n; i++ {
+		mean += s.returns.At(i).value
+	}
+	mean /= float64(n)
+	variance := 0.0
+	for i := 0; i < n; i++ {
+		d := s.returns.At(i).value - mean
+		variance += d * d
+	}
+	variance /= float64(n - 1)
+	day, ok := m.days[symbol]
+	if !ok {
+		day = tradingDay
+	}
+	return math.Sqrt(variance * float64(day) / float64(m.limits.SampleInterval)), true
+}
+
+// correlation returns the correlation of the returns two symbols have in
+// common intervals
+func (m *Manager) correlation(a, b string) (float64, bool) {
+	sa, sb := m.series[a], m.series[b]
+	if sa == nil || sb == nil {
+		return 0, false
+	}
+	returns := make(map[int64]float64, sb.returns.Len())
+	for i := 0; i < sb.returns.Len(); i++ {
+		r := sb.returns.At(i)
+		returns[r.bucket] = r.value
+	}
+
+	var xs, ys []float64
+	for i := 0; i < sa.returns.Len(); i++ {
+		r := sa.returns.At(i)
+		if y, ok := returns[r.bucket]; ok {
+			xs = append(xs, r.value)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < minSamples {
+		return 0, false
+	}
+
+	n := float64(len(xs))
+	meanX, meanY := 0.0, 0.0
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+	cov, varX, varY := 0.0, 0.0, 0.0
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
+
+// Open books a new position of notional in symbol
+func (m *Manager) Open(symbol string, notional float64) {
+	symbol = strings.ToLower(symbol)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.notional[symbol] += notional
+	m.positions[symbol]++
+}
+
+// Close releases a position of notional in symbol
+func (m *Manager) Close(symbol string, notional float64) {
+	symbol = strings.ToLower(symbol)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.positions[symbol] == 0 {
+		return
+	}
+	m.positions[symbol]--
+	m.notional[symbol] = math.Max(0, m.notional[symbol]-notional)
+	if m.positions[symbol] == 0 {
+		delete(m.positions, symbol)
+		delete(m.notional, symbol)
+	}
+}
+
+// Exposure returns the open positions and notional of the book
+func (m *Manager) Exposure() Exposure {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	exposure := Exposure{Symbols: make(map[string]float64, len(m.notional))}
+	for symbol, open := range m.notional {
+		exposure.Symbols[symbol] = open
+		exposure.Total += open
+	}
+	for _, count := range m.positions {
+		exposure.Positions += count
+	}
+	return exposure
+}
+
+// Volatility returns the estimated daily volatility of a symbol's returns;
+// ok is false until enough returns have been sampled
+func (m *Manager) Volatility(symbol string) (float64, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.volatility(strings.ToLower(symbol))
+}
+
+// Correlation returns the estimated return correlation of two symbols
+func (m *Manager) Correlation(a, b string) (float64, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.correlation(strings.ToLower(a), strings.ToLower(b))
+}
@@ -0,0 +1,34 @@
+package risk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestObservePriceSamplesCloseToClose(t *testing.T) {
+	m := NewManager(Limits{SampleInterval: time.Minute})
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// Each minute opens far from the last close and ends at 100, then 110
+	closes := []float64{100, 110, 100, 110}
+	for i, end := range closes {
+		at := start.Add(time.Duration(i) * time.Minute)
+		m.ObservePrice("BTCUSDT", 150, at)
+		m.ObservePrice("BTCUSDT", 50, at.Add(20*time.Second))
+		m.ObservePrice("BTCUSDT", end, at.Add(40*time.Second))
+	}
+	// The first tick of the next minute ends the last one
+	m.ObservePrice("BTCUSDT", 150, start.Add(time.Duration(len(closes))*time.Minute))
+
+	returns := m.series["btcusdt"].returns
+	if returns.Len() != len(closes)-1 {
+		t.Fatalf("%d returns sampled, want %d", returns.Len(), len(closes)-1)
+	}
+	for i := 0; i < returns.Len(); i++ {
+		want := math.Log(closes[i+1] / closes[i])
+		if got := returns.At(i).value; math.Abs(got-want) > 1e-12 {
+			t.Errorf("return %d = %.6f, want %.6f", i, got, want)
+		}
+	}
+}
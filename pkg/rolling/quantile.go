@@ -0,0 +1,109 @@
+package rolling
+
+import (
+	"math"
+	"sort"
+)
+
+// Quantile estimates a quantile of a stream in constant memory using the
+// P² algorithm (Jain & Chlamtac), e.g. for latency or spread percentiles
+// where keeping every value is too expensive
+type Quantile struct {
+	p         float64
+	count     int
+	heights   [5]float64 // Marker heights
+	positions [5]float64 // Actual marker positions
+	desired   [5]float64 // Desired marker positions
+	increment [5]float64 // Desired position increments per value
+}
+
+// NewQuantile creates an estimator for quantile p (0 < p < 1)
+func NewQuantile(p float64) *Quantile {
+	p = math.Min(math.Max(p, 0), 1)
+	return &Quantile{
+		p:         p,
+		positions: [5]float64{1, 2, 3, 4, 5},
+		desired:   [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		increment: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// Push adds a value to the stream
+func (q *Quantile) Push(value float64) {
+	if q.count < 5 {
+		q.heights[q.count] = value
+		q.count++
+		if q.count == 5 {
+			sort.Float64s(q.heights[:])
+		}
+		return
+	}
+	q.count++
+
+	// Find the cell containing the value, extending the extremes
+	var k int
+	switch {
+	case value < q.heights[0]:
+		q.heights[0] = value
+		k = 0
+	case value >= q.heights[4]:
+		q.heights[4] = math.Max(q.heights[4], value)
+		k = 3
+	default:
+		for k = 0; k < 3 && value >= q.heights[k+1]; k++ {
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		q.positions[i]++
+	}
+	for i := range q.desired {
+		q.desired[i] += q.increment[i]
+	}
+
+	// Adjust the middle markers towards their desired positions
+	for i := 1; i <= 3; i++ {
+		d := q.desired[i] - q.positions[i]
+		if (d >= 1 && q.positions[i+1]-q.positions[i] > 1) || (d <= -1 && q.positions[i-1]-q.positions[i] < -1) {
+			step := math.Copysign(1, d)
+			height := q.parabolic(i, step)
+			if height <= q.heights[i-1] || height >= q.heights[i+1] {
+				height = q.linear(i, step)
+			}
+			q.heights[i] = height
+			q.positions[i] += step
+		}
+	}
+}
+
+// parabolic returns the piecewise-parabolic prediction for marker i
+func (q *Quantile) parabolic(i int, step float64) float64 {
+	n, h := q.positions, q.heights
+	return h[i] + step/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+step)*(h[i+1]-h[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-step)*(h[i]-h[i-1])/(n[i]-n[i-1]))
+}
+
+// linear returns the linear prediction for marker i
+func (q *Quantile) linear(i int, step float64) float64 {
+	j := i + int(step)
+	return q.heights[i] + step*(q.heights[j]-q.heights[i])/(q.positions[j]-q.positions[i])
+}
+
+// Count returns the number of values pushed
+func (q *Quantile) Count() int {
+	return q.count
+}
+
+// Value returns the current estimate, exact for fewer than 5 values
+func (q *Quantile) Value() float64 {
+	if q.count == 0 {
+		return 0
+	}
+	if q.count < 5 {
+		values := append([]float64(nil), q.heights[:q.count]...)
+		sort.Float64s(values)
+		return values[int(math.Round(q.p*float64(q.count-1)))]
+	}
+	return q.heights[2]
+}
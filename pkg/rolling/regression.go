@@ -0,0 +1,104 @@
+package rolling
+
+import "math"
+
+// Regression is the least-squares line through the last capacity values
+// against their index in the window, 0 for the oldest, maintained in O(1)
+// per push
+type Regression struct {
+	window *Window[float64]
+	seq    int64
+	offset float64 // Shift applied to sums to limit cancellation error
+	sumY   float64 // Sum of (value - offset)
+	sumXY  float64 // Sum of index × (value - offset)
+	sumYY  float64 // Sum of (value - offset)^2
+}
+
+// NewRegression creates a regression over the last capacity values
+func NewRegression(capacity int) *Regression {
+	return &Regression{window: NewWindow[float64](capacity)}
+}
+
+// Push adds a value, evicting the oldest one if the window is full
+func (r *Regression) Push(value float64) {
+	if r.window.Len() == 0 {
+		r.offset = value
+	}
+	index := r.window.Len()
+	evicted, ok := r.window.Push(value)
+	r.seq++
+
+	if ok {
+		// The values left move down one index
+		shifted := evicted - r.offset
+		r.sumY -= shifted
+		r.sumXY -= r.sumY
+		r.sumYY -= shifted * shifted
+		index--
+	}
+	shifted := value - r.offset
+	r.sumY += shifted
+	r.sumXY += float64(index) * shifted
+	r.sumYY += shifted * shifted
+
+	// Recompute the sums once per window length so rounding errors from
+	// evictions never accumulate
+	if r.seq%int64(r.window.Cap()) == 0 {
+		r.recompute()
+	}
+}
+
+// recompute rebuilds the sums from the window around its oldest value
+func (r *Regression) recompute() {
+	r.offset = r.window.First()
+	r.sumY, r.sumXY, r.sumYY = 0, 0, 0
+	for i := 0; i < r.window.Len(); i++ {
+		shifted := r.window.At(i) - r.offset
+		r.sumY += shifted
+		r.sumXY += float64(i) * shifted
+		r.sumYY += shifted * shifted
+	}
+}
+
+// Len returns the number of values in the window
+func (r *Regression) Len() int {
+	return r.window.Len()
+}
+
+// Mean returns the mean of the values, or 0 if the window is empty
+func (r *Regression) Mean() float64 {
+	n := r.window.Len()
+	if n == 0 {
+		return 0
+	}
+	return r.offset + r.sumY/float64(n)
+}
+
+// Fit returns the slope and intercept of the line and the correlation of
+// the values with their index, all 0 with fewer than two values
+func (r *Regression) Fit() (slope, intercept, correlation float64) {
+	n := float64(r.window.Len())
+	if n < 2 {
+		return 0, 0, 0
+	}
+	// Sums of the indexes 0, 1, ..., n-1 and of their squares
+	sumX := n * (n - 1) / 2
+	sumXX := (n - 1) * n * (2*n - 1) / 6
+
+	covariance := n*r.sumXY - sumX*r.sumY
+	varianceX := n*sumXX - sumX*sumX
+	varianceY := n*r.sumYY - r.sumY*r.sumY
+	slope = covariance / varianceX
+	intercept = r.offset + (r.sumY-slope*sumX)/n
+	if varianceY > 0 {
+		correlation = math.Max(-1, math.Min(1, covariance/math.Sqrt(varianceX*varianceY)))
+	}
+	return slope, intercept, correlation
+}
+
+// Reset removes all values
+func (r *Regression) Reset() {
+	r.window.Reset()
+	r.seq = 0
+	r.offset, r.sumY, r.sumXY, r.sumYY = 0, 0, 0, 0
+}
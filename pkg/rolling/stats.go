@@ -0,0 +1,172 @@
+package rolling
+
+import "math"
+
+// entry is a value of a monotonic min/max queue with its push sequence
+type entry struct {
+	seq   int64
+	value float64
+}
+
+// Stats is a window of float64 values with sum, mean, variance, min and
+// max maintained in O(1) (amortized) per push
+type Stats struct {
+	window *Window[float64]
+	seq    int64   // Number of values pushed since the last reset
+	offset float64 // Shift applied to sums to limit cancellation error
+	sum    float64 // Sum of (value - offset)
+	sumSq  float64 // Sum of (value - offset)^2
+	mins   []entry // Increasing values; the front is the window minimum
+	maxs   []entry // Decreasing values; the front is the window maximum
+}
+
+// NewStats creates statistics over the last capacity values
+func NewStats(capacity int) *Stats {
+	return &Stats{window: NewWindow[float64](capacity)}
+}
+
+// Push adds a value, evicting the oldest one if the window is full
+func (s *Stats) Push(value float64) {
+	if s.window.Len() == 0 {
+		s.offset = value
+	}
+	evicted, ok := s.window.Push(value)
+	s.seq++
+
+	shifted := value - s.offset
+	s.sum += shifted
+	s.sumSq += shifted * shifted
+	if ok {
+		shifted = evicted - s.offset
+		s.sum -= shifted
+		s.sumSq -= shifted * shifted
+	}
+
+	// Recompute the sums once per window length so rounding errors from
+	// evictions never accumulate
+	if s.seq%int64(s.window.Cap()) == 0 {
+		s.recompute()
+	}
+
+	for len(s.mins) > 0 && s.mins[len(s.mins)-1].value >= value {
+		s.mins = s.mins[:len(s.mins)-1]
+	}
+	s.mins = append(s.mins, entry{seq: s.seq, value: value})
+	for len(s.maxs) > 0 && s.maxs[len(s.maxs)-1].value <= value {
+		s.maxs = s.maxs[:len(s.maxs)-1]
+	}
+	s.maxs = append(s.maxs, entry{seq: s.seq, value: value})
+
+	// Drop extremes that have left the window
+	oldest := s.seq - int64(s.window.Len())
+	for s.mins[0].seq <= oldest {
+		s.mins = s.mins[1:]
+	}
+	for s.maxs[0].seq <= oldest {
+		s.maxs = s.maxs[1:]
+	}
+}
+
+// recompute rebuilds the sums from the window around its oldest value
+func (s *Stats) recompute() {
+	s.offset = s.window.First()
+	s.sum, s.sumSq = 0, 0
+	for i := 0; i < s.window.Len(); i++ {
+		shifted := s.window.At(i) - s.offset
+		s.sum += shifted
+		s.sumSq += shifted * shifted
+	}
+}
+
+// Len returns the number of values in the window
+func (s *Stats) Len() int {
+	return s.window.Len()
+}
+
+// Cap returns the capacity of the window
+func (s *Stats) Cap() int {
+	return s.window.Cap()
+}
+
+// Last returns the newest value, or 0 if the window is empty
+func (s *Stats) Last() float64 {
+	return s.window.Last()
+}
+
+// Values returns a copy of the values, oldest first
+func (s *Stats) Values() []float64 {
+	return s.window.Values()
+}
+
+// View returns a read-only view of the values without copying them
+func (s *Stats) View() View[float64] {
+	return s.window.View()
+}
+
+// Window returns the underlying window; callers must not push into it
+func (s *Stats) Window() *Window[float64] {
+	return s.window
+}
+
+// Sum returns the sum of the values
+func (s *Stats) Sum() float64 {
+	return s.sum + s.offset*float64(s.window.Len())
+}
+
+// Mean returns the mean of the values, or 0 if the window is empty
+func (s *Stats) Mean() float64 {
+	n := s.window.Len()
+	if n == 0 {
+		return 0
+	}
+	return s.offset + s.sum/float64(n)
+}
+
+// Variance returns the population variance of the values
+func (s *Stats) Variance() float64 {
+	n := float64(s.window.Len())
+	if n == 0 {
+		return 0
+	}
+	mean := s.sum / n
+	return math.Max(0, s.sumSq/n-mean*mean)
+}
+
+// SampleVariance returns the sample (n-1) variance of the values
+func (s *Stats) SampleVariance() float64 {
+	n := float64(s.window.Len())
+	if n < 2 {
+		return 0
+	}
+	return s.Variance() * n / (n - 1)
+}
+
+// StdDev returns the population standard deviation of the values
+func (s *Stats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Min returns the smallest value, or 0 if the window is empty
+func (s *Stats) Min() float64 {
+	if len(s.mins) == 0 {
+		return 0
+	}
+	return s.mins[0].value
+}
+
+// Max returns the largest value, or 0 if the window is empty
+func (s *Stats) Max() float64 {
+	if len(s.maxs) == 0 {
+		return 0
+	}
+	return s.maxs[0].value
+}
+
+// Reset removes all values
+func (s *Stats) Reset() {
+	s.window.Reset()
+	s.seq = 0
+	s.offset, s.sum, s.sumSq = 0, 0, 0
+	s.mins = s.mins[:0]
+	s.maxs = s.maxs[:0]
+}
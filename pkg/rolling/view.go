@@ -0,0 +1,71 @@
+package rolling
+
+// View is a read-only view of a window's values, oldest first. It shares
+// the window's storage instead of copying it, so it is only valid until
+// the window is next modified; callers must hold whatever lock guards the
+// window for as long as they use the view.
+type View[T any] struct {
+	head []T // Oldest values
+	tail []T // Values that wrapped around to the start of the buffer
+}
+
+// View returns a view of the window's current values
+func (w *Window[T]) View() View[T] {
+	end := w.start + w.size
+	if end <= len(w.buf) {
+		return View[T]{head: w.buf[w.start:end]}
+	}
+	return View[T]{head: w.buf[w.start:], tail: w.buf[:end-len(w.buf)]}
+}
+
+// Len returns the number of values in the view
+func (v View[T]) Len() int {
+	return len(v.head) + len(v.tail)
+}
+
+// At returns the i-th value, oldest first. It panics if i is out of range.
+func (v View[T]) At(i int) T {
+	if i < len(v.head) {
+		return v.head[i]
+	}
+	return v.tail[i-len(v.head)]
+}
+
+// Last returns the newest value, or the zero value if the view is empty
+func (v View[T]) Last() T {
+	var zero T
+	switch {
+	case len(v.tail) > 0:
+		return v.tail[len(v.tail)-1]
+	case len(v.head) > 0:
+		return v.head[len(v.head)-1]
+	}
+	return zero
+}
+
+// Slice returns the view of values from index from up to (excluding) to
+func (v View[T]) Slice(from, to int) View[T] {
+	if from < 0 || to < from || to > v.Len() {
+		panic("rolling: slice out of range")
+	}
+	n := len(v.head)
+	switch {
+	case to <= n:
+		return View[T]{head: v.head[from:to]}
+	case from >= n:
+		return View[T]{head: v.tail[from-n : to-n]}
+	}
+	return View[T]{head: v.head[from:], tail: v.tail[:to-n]}
+}
+
+// Segments returns the values as at most two contiguous slices, oldest
+// first; iterating over both visits every value without copying. The
+// slices must not be modified.
+func (v View[T]) Segments() (head, tail []T) {
+	return v.head, v.tail
+}
+
+// AppendTo appends the values, oldest first, to dst and returns it
+func (v View[T]) AppendTo(dst []T) []T {
+	return append(append(dst, v.head...), v.tail...)
+}
@@ -0,0 +1,93 @@
+// Package rolling provides fixed-size windows over streams of values and
+// statistics that are updated incrementally as values enter and leave them.
+package rolling
+
+// Window is a fixed-capacity ring buffer; pushing into a full window
+// evicts the oldest value
+type Window[T any] struct {
+	buf   []T
+	start int // Index of the oldest value in buf
+	size  int
+}
+
+// NewWindow creates a window holding up to capacity values
+func NewWindow[T any](capacity int) *Window[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Window[T]{buf: make([]T, capacity)}
+}
+
+// Push appends a value and returns the evicted value, if any
+func (w *Window[T]) Push(value T) (evicted T, ok bool) {
+	if w.size < len(w.buf) {
+		w.buf[(w.start+w.size)%len(w.buf)] = value
+		w.size++
+		return evicted, false
+	}
+	evicted = w.buf[w.start]
+	w.buf[w.start] = value
+	w.start = (w.start + 1) % len(w.buf)
+	return evicted, true
+}
+
+// Len returns the number of values in the window
+func (w *Window[T]) Len() int {
+	return w.size
+}
+
+// Cap returns the capacity of the window
+func (w *Window[T]) Cap() int {
+	return len(w.buf)
+}
+
+// Full reports whether the window is at capacity
+func (w *Window[T]) Full() bool {
+	return w.size == len(w.buf)
+}
+
+// At returns the i-th value, oldest first. It panics if i is out of range.
+func (w *Window[T]) At(i int) T {
+	if i < 0 || i >= w.size {
+		panic("rolling: index out of range")
+	}
+	return w.buf[(w.start+i)%len(w.buf)]
+}
+
+// First returns the oldest value, or the zero value if the window is empty
+func (w *Window[T]) First() T {
+	var zero T
+	if w.size == 0 {
+		return zero
+	}
+	return w.buf[w.start]
+}
+
+// Last returns the newest value, or the zero value if the window is empty
+func (w *Window[T]) Last() T {
+	var zero T
+	if w.size == 0 {
+		return zero
+	}
+	return w.buf[(w.start+w.size-1)%len(w.buf)]
+}
+
+// Values returns a copy of the values, oldest first
+func (w *Window[T]) Values() []T {
+	return w.AppendTo(make([]T, 0, w.size))
+}
+
+// AppendTo appends the values, oldest first, to dst and returns it
+func (w *Window[T]) AppendTo(dst []T) []T {
+	return w.View().AppendTo(dst)
+}
+
+// Reset removes all values
+func (w *Window[T]) Reset() {
+	var zero T
+	for i := range w.buf {
+		w.buf[i] = zero
+	}
+	w.start = 0
+	w.size = 0
+}
@@ -0,0 +1,271 @@
+// Package rpc serves the trade.v1.MarketStream gRPC service, streaming live
+// ticks, metrics, signals and trade events to external consumers.
+//
+// The service speaks the gRPC wire protocol (HTTP/2, length-prefixed
+// protobuf messages, grpc-status trailers) on top of net/http, so any gRPC
+// client generated from proto/trade/v1/trade.proto can subscribe. HTTP/2
+// requires TLS, so clients must connect with TLS credentials.
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/pb"
+)
+
+// SubscribePath is the HTTP/2 path of the MarketStream.Subscribe method
+const SubscribePath = "/trade.v1.MarketStream/Subscribe"
+
+// gRPC status codes used by the server
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeUnavailable       = 14
+)
+
+// maxRequestSize bounds the size of a subscribe request
+const maxRequestSize = 64 * 1024
+
+// Options configures the streaming server
+type Options struct {
+	Address        string // Listen address, e.g. ":9090"
+	CertFile       string // TLS certificate (required for HTTP/2)
+	KeyFile        string // TLS private key
+	BufferSize     int    // Events buffered per subscriber before dropping
+	MaxSubscribers int    // 0 means unlimited
+}
+
+// Server streams events from the bus to gRPC subscribers
+type Server struct {
+	bus         *events.Bus
+	options     Options
+	logger      logger.Interface
+	server      *http.Server
+	listener    net.Listener
+	subscribers int64
+	done        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewServer creates a streaming server for the events published on bus
+func NewServer(bus *events.Bus, options Options, log logger.Interface) *Server {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 1024
+	}
+	s := &Server{
+		bus:     bus,
+		options: options,
+		logger:  log,
+		done:    make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handle)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Start listens on the configured address and serves in the background
+func (s *Server) Start() error {
+	if s.options.CertFile == "" || s.options.KeyFile == "" {
+		return fmt.Errorf("gRPC server requires a TLS certificate and key")
+	}
+	listener, err := net.Listen("tcp", s.options.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.options.Address, err)
+	}
+	s.listener = listener
+
+	go func() {
+		err := s.server.ServeTLS(listener, s.options.CertFile, s.options.KeyFile)
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error(fmt.Sprintf("gRPC server stopped: %v", err))
+		}
+	}()
+	s.logger.Info(fmt.Sprintf("gRPC MarketStream listening on %s", listener.Addr()))
+	return nil
+}
+
+// Addr returns the listen address, or nil before Start
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop ends all streams and shuts the server down
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(ctx)
+		// Shutdown only closes the listener once serving, which starts
+		// after the certificate is loaded
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	})
+}
+
+// handle serves one gRPC call
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requires HTTP/2 with content-type application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.URL.Path != SubscribePath {
+		writeStatus(w, codeUnimplemented, fmt.Sprintf("unknown method %s", r.URL.Path))
+		return
+	}
+
+	req, err := readRequest(r.Body)
+	if err != nil {
+		writeStatus(w, codeInvalidArgument, err.Error())
+		return
+	}
+
+	if count := atomic.AddInt64(&s.subscribers, 1); s.options.MaxSubscribers > 0 && count > int64(s.options.MaxSubscribers) {
+		atomic.AddInt64(&s.subscribers, -1)
+		writeStatus(w, codeResourceExhausted, "too many subscribers")
+		return
+	}
+	defer atomic.AddInt64(&s.subscribers, -1)
+
+	s.stream(w, r, req)
+}
+
+// stream forwards matching events to the subscriber until it disconnects
+// or the server stops
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, req *pb.SubscribeRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeStatus(w, codeUnavailable, "streaming not supported")
+		return
+	}
+
+	wanted := make(map[events.Type]bool)
+	for _, eventType := range req.Types {
+		wanted[eventType] = true
+	}
+
+	// Publishers never block on a slow subscriber; events that do not fit
+	// its buffer are dropped instead
+	updates := make(chan events.Event, s.options.BufferSize)
+	var dropped int64
+	subscription := s.bus.SubscribeAll(func(event events.Event) {
+		if len(wanted) > 0 && !wanted[event.Type()] {
+			return
+		}
+		if req.Symbol != "" && !strings.EqualFold(events.SymbolOf(event), req.Symbol) {
+			return
+		}
+		// The event is sent after the handler returns, by which time a
+		// pooled tick has been reused
+		select {
+		case updates <- events.Detach(event):
+		default:
+			atomic.AddInt64(&dropped, 1)
+		}
+	})
+	defer s.bus.Unsubscribe(subscription)
+
+	client := r.RemoteAddr
+	s.logger.Info(fmt.Sprintf("gRPC subscriber %s connected", client), "types", fmt.Sprint(req.Types), "symbol", req.Symbol)
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	code, message := codeOK, ""
+	for done := false; !done; {
+		select {
+		case event := <-updates:
+			data, err := pb.MarshalEvent(event)
+			if err != nil {
+				continue // Event type without a wire format
+			}
+			if err := writeMessage(w, data); err != nil {
+				done = true
+				break
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			done = true
+		case <-s.done:
+			code, message = codeUnavailable, "server shutting down"
+			done = true
+		}
+	}
+
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	w.Header().Set("Grpc-Message", encodeMessage(message))
+	s.logger.Info(fmt.Sprintf("gRPC subscriber %s disconnected", client), "dropped", atomic.LoadInt64(&dropped))
+}
+
+// readRequest reads the single length-prefixed request message
+func readRequest(body io.Reader) (*pb.SubscribeRequest, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(body, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read request: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, fmt.Errorf("compressed requests are not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxRequestSize {
+		return nil, fmt.Errorf("request too large: %d bytes", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, fmt.Errorf("failed to read request: %v", err)
+	}
+	return pb.UnmarshalSubscribeRequest(data)
+}
+
+// writeMessage writes one length-prefixed, uncompressed message
+func writeMessage(w io.Writer, data []byte) error {
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// writeStatus ends a call without messages ("trailers-only" response)
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	w.Header().Set("Grpc-Message", encodeMessage(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// encodeMessage percent-encodes a grpc-message value: bytes outside
+// printable ASCII and the percent sign itself, as the protocol requires
+func encodeMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c < 0x20 || c > 0x7E || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,247 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/pb"
+	"TRADE/pkg/types"
+)
+
+// startServer starts a server on a free local port and returns it with an
+// HTTP/2 client trusting its certificate
+func startServer(t *testing.T, bus *events.Bus, options Options) (*Server, *http.Client) {
+	t.Helper()
+	certFile, keyFile := writeCert(t)
+	options.Address = "127.0.0.1:0"
+	options.CertFile, options.KeyFile = certFile, keyFile
+	s := NewServer(bus, options, logger.NewLoggerWithHandler(slog.NewTextHandler(io.Discard, nil)))
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true}
+	t.Cleanup(transport.CloseIdleConnections)
+	return s, &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+// call sends one gRPC request with the given length-prefixed body
+func call(t *testing.T, s *Server, client *http.Client, path string, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "https://"+s.Addr().String()+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s answered over %s, want HTTP/2", path, resp.Proto)
+	}
+	return resp
+}
+
+// frame length-prefixes an uncompressed message
+func frame(data []byte) []byte {
+	var b bytes.Buffer
+	if err := writeMessage(&b, data); err != nil {
+		panic(err)
+	}
+	return b.Bytes()
+}
+
+func TestSubscribeStreamsMatchingEventsUntilStop(t *testing.T) {
+	bus := events.NewBus()
+	s, client := startServer(t, bus, Options{})
+
+	request := pb.MarshalSubscribeRequest(&pb.SubscribeRequest{Types: []events.Type{events.TypeTick}, Symbol: "btcusdt"})
+	resp := call(t, s, client, SubscribePath, frame(request))
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc+proto" {
+		t.Fatalf("response %d %q, want 200 application/grpc+proto", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if status := resp.Header.Get("Grpc-Status"); status != "" {
+		t.Fatalf("stream opened with status %s in the headers", status)
+	}
+
+	// The headers are sent once subscribed: other symbols and types are
+	// filtered out
+	tick := &types.TickData{Price: 50123.45, Volume: 0.5, Timestamp: time.Unix(1741610096, 0).UTC()}
+	bus.Publish(&events.TickEvent{Symbol: "ETHUSDT", Tick: &types.TickData{Price: 2000, Timestamp: tick.Timestamp}})
+	bus.Publish(&events.AlertEvent{Level: events.AlertInfo, Symbol: "BTCUSDT", Message: "heartbeat"})
+	bus.Publish(&events.TickEvent{Symbol: "BTCUSDT", Tick: tick})
+
+	var header [5]byte
+	if _, err := io.ReadFull(resp.Body, header[:]); err != nil {
+		t.Fatalf("reading message header: %v", err)
+	}
+	if header[0] != 0 {
+		t.Fatalf("message flagged compressed: %x", header)
+	}
+	message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(resp.Body, message); err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	event, err := pb.UnmarshalEvent(message)
+	if err != nil {
+		t.Fatalf("UnmarshalEvent: %v", err)
+	}
+	got, ok := event.(*events.TickEvent)
+	if !ok || got.Symbol != "BTCUSDT" || *got.Tick != *tick {
+		t.Fatalf("received %+v, want the BTCUSDT tick", event)
+	}
+
+	// Stopping ends the stream with UNAVAILABLE in the trailers
+	s.Stop()
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading to the end of the stream: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes after the matching event, want none", len(rest))
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "14" {
+		t.Errorf("trailer grpc-status = %q, want 14", status)
+	}
+	if message := resp.Trailer.Get("Grpc-Message"); message != "server shutting down" {
+		t.Errorf("trailer grpc-message = %q", message)
+	}
+}
+
+func TestCallErrorsAreTrailersOnly(t *testing.T) {
+	bus := events.NewBus()
+	s, client := startServer(t, bus, Options{MaxSubscribers: 1})
+
+	request := frame(pb.MarshalSubscribeRequest(&pb.SubscribeRequest{}))
+	compressed := append([]byte(nil), request...)
+	compressed[0] = 1
+	oversized := make([]byte, 5)
+	binary.BigEndian.PutUint32(oversized[1:], maxRequestSize+1)
+
+	// The only allowed subscriber holds its stream open
+	call(t, s, client, SubscribePath, request)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   []byte
+		status string
+	}{
+		{"unknown method", "/trade.v1.MarketStream/Publish", request, "12"},
+		{"empty request", SubscribePath, nil, "3"},
+		{"truncated request", SubscribePath, request[:3], "3"},
+		{"compressed request", SubscribePath, compressed, "3"},
+		{"oversized request", SubscribePath, oversized, "3"},
+		{"malformed request", SubscribePath, frame([]byte{0x0a, 0x05, 't'}), "3"},
+		{"too many subscribers", SubscribePath, request, "8"},
+	}
+	for _, test := range tests {
+		resp := call(t, s, client, test.path, test.body)
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc+proto" {
+			t.Errorf("%s: response %d %q, want 200 application/grpc+proto", test.name, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if len(body) != 0 {
+			t.Errorf("%s: %d bytes of messages, want none", test.name, len(body))
+		}
+		// Trailers-only: the status comes with the headers
+		if status := resp.Header.Get("Grpc-Status"); status != test.status {
+			t.Errorf("%s: grpc-status = %q, want %s", test.name, status, test.status)
+		}
+		if resp.Header.Get("Grpc-Message") == "" {
+			t.Errorf("%s: no grpc-message", test.name)
+		}
+	}
+
+	// The message is percent-encoded
+	resp := call(t, s, client, "/trade.v1.MarketStream/%C3%A9t%C3%A9%25", request)
+	io.ReadAll(resp.Body)
+	if message := resp.Header.Get("Grpc-Message"); message != "unknown method /trade.v1.MarketStream/%C3%A9t%C3%A9%25" {
+		t.Errorf("grpc-message = %q, want it percent-encoded", message)
+	}
+}
+
+func TestRejectsHTTP1(t *testing.T) {
+	s, client := startServer(t, events.NewBus(), Options{})
+	transport := client.Transport.(*http.Transport)
+	transport.ForceAttemptHTTP2 = false
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+
+	req, err := http.NewRequest(http.MethodPost, "https://"+s.Addr().String()+SubscribePath, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("HTTP/1.1 call answered %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+}
+
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return certFile, keyFile
+}
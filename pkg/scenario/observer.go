@@ -0,0 +1,147 @@
+package scenario
+
+import (
+	"strings"
+	"time"
+
+	"TRADE/pkg/events"
+)
+
+// trade is a trade opened in a scenario run
+type trade struct {
+	ID         string
+	Direction  string
+	EntryTick  int
+	EntryPrice float64
+	ExitTick   int // -1 while open
+	Reason     string
+	PnLPercent float64
+}
+
+// alert is an alert published in a scenario run
+type alert struct {
+	Tick    int
+	Time    time.Time
+	Source  string
+	Message string
+}
+
+// observer follows a scenario run on the event bus. The bus delivers
+// synchronously, so each event belongs to the tick being fed.
+type observer struct {
+	tick      int
+	lastPrice float64
+	lastTime  time.Time
+
+	// Shock, from its first tick
+	start      int // -1 before the shock
+	startTime  time.Time
+	startPrice float64 // Last price before the shock
+	low, high  float64 // Price extremes while the shock lasts
+	length     int
+	caught     []*trade // Trades open when the shock started
+
+	pending    map[string]*trade // Entry signals not filled yet, by trade ID
+	open       map[string]*trade
+	trades     []*trade
+	rejections int
+	alerts     []alert
+	staleTick  int // Tick the watchdog found the feed stale at after the shock, -1 if it did not
+	freshTick  int // Tick it found fresh data at again, -1 if it did not
+}
+
+// newObserver creates the observer of a run of a shock lasting length ticks
+func newObserver(length int) *observer {
+	return &observer{
+		start:     -1,
+		length:    length,
+		pending:   make(map[string]*trade),
+		open:      make(map[string]*trade),
+		staleTick: -1,
+		freshTick: -1,
+	}
+}
+
+// subscribe follows the signals, fills, closed trades, rejections and
+// alerts published on bus
+func (o *observer) subscribe(bus *events.Bus) {
+	bus.Subscribe(events.TypeSignal, func(event events.Event) {
+		signal := event.(*events.SignalEvent).Signal
+		if signal.IsEntry() {
+			o.pending[signal.TradeID] = &trade{
+				ID:         signal.TradeID,
+				Direction:  signal.Direction,
+				EntryTick:  o.tick,
+				EntryPrice: signal.Price,
+				ExitTick:   -1,
+			}
+		}
+	})
+	bus.Subscribe(events.TypeFill, func(event events.Event) {
+		fill := event.(*events.FillEvent)
+		if entry, ok := o.pending[fill.TradeID]; ok {
+			delete(o.pending, fill.TradeID)
+			o.open[fill.TradeID] = entry
+			o.trades = append(o.trades, entry)
+		}
+	})
+	bus.Subscribe(events.TypeTradeClosed, func(event events.Event) {
+		closed := event.(*events.TradeClosedEvent)
+		if entry, ok := o.open[closed.TradeID]; ok {
+			delete(o.open, closed.TradeID)
+			entry.ExitTick = o.tick
+			entry.Reason = closed.Reason
+			entry.PnLPercent = closed.PnLPercent
+		}
+	})
+	bus.Subscribe(events.TypeRiskRejected, func(event events.Event) {
+		rejected := event.(*events.RiskRejectedEvent)
+		delete(o.pending, rejected.TradeID)
+		o.rejections++
+	})
+	bus.Subscribe(events.TypeAlert, func(event events.Event) {
+		published := event.(*events.AlertEvent)
+		o.alerts = append(o.alerts, alert{Tick: o.tick, Time: published.Timestamp, Source: published.Source, Message: published.Message})
+		if published.Source != "watchdog" || o.start < 0 {
+			return
+		}
+		switch {
+		case o.staleTick < 0 && strings.HasPrefix(published.Message, "Stale data"):
+			o.staleTick = o.tick
+		case o.staleTick >= 0 && o.freshTick < 0 && strings.HasPrefix(published.Message, "Fresh data"):
+			o.freshTick = o.tick
+		}
+	})
+}
+
+// begin marks the first tick of the shock
+func (o *observer) begin(tick int, at time.Time) {
+	o.start, o.startTime, o.startPrice = tick, at, o.lastPrice
+	o.low, o.high = o.lastPrice, o.lastPrice
+	for _, entry := range o.trades {
+		if entry.ExitTick < 0 {
+			o.caught = append(o.caught, entry)
+		}
+	}
+}
+
+// advance moves to the next tick, about to be fed
+func (o *observer) advance(tick int, price float64, at time.Time) {
+	o.tick, o.lastPrice, o.lastTime = tick, price, at
+	if o.start >= 0 && tick < o.start+o.length {
+		o.low = min(o.low, price)
+		o.high = max(o.high, price)
+	}
+}
+
+// entriesBetween returns the trades entered from tick first up to, not
+// including, tick last in direction; any direction if empty
+func (o *observer) entriesBetween(first, last int, direction string) []*trade {
+	var entered []*trade
+	for _, entry := range o.trades {
+		if entry.EntryTick >= first && entry.EntryTick < last && (direction == "" || entry.Direction == direction) {
+			entered = append(entered, entry)
+		}
+	}
+	return entered
+}
@@ -0,0 +1,59 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteReport writes the results of the runs as a pass/fail report, one
+// section per scenario
+func WriteReport(w io.Writer, results []*Result) {
+	for _, result := range results {
+		verdict := "PASS"
+		if !result.Passed {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(w, "%-16s %s  %s\n", result.Scenario, verdict, result.Description)
+		if result.Error != "" {
+			fmt.Fprintf(w, "  run failed: %s\n\n", result.Error)
+			continue
+		}
+		if result.ShockTick >= 0 {
+			fmt.Fprintf(w, "  shock at tick %d (%s) from %.2f, range %.2f - %.2f\n", result.ShockTick,
+				result.ShockTime.UTC().Format("2006-01-02 15:04:05"), result.ShockPrice, result.Low, result.High)
+		}
+		fmt.Fprintf(w, "  %d tick(s), %d trade(s) entered, %d closed", result.Ticks, result.Entries, len(result.Exits))
+		if reasons := exitReasons(result.Exits); reasons != "" {
+			fmt.Fprintf(w, " (%s)", reasons)
+		}
+		fmt.Fprintf(w, ", %d spread block(s), %d risk rejection(s)\n", result.SpreadBlocks, result.RiskRejections)
+		if result.KillSwitch != "" {
+			fmt.Fprintf(w, "  kill-switch tripped: %s\n", result.KillSwitch)
+		}
+		for _, alert := range result.Alerts {
+			fmt.Fprintf(w, "  alert %s\n", alert)
+		}
+		for _, check := range result.Checks {
+			fmt.Fprintf(w, "  [%s] %s: %s\n", strings.ToUpper(string(check.Outcome)), check.Name, check.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// exitReasons counts the exits by reason, e.g. "2 stop_loss, 1 trailing_stop"
+func exitReasons(exits []Exit) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, exit := range exits {
+		if counts[exit.Reason] == 0 {
+			order = append(order, exit.Reason)
+		}
+		counts[exit.Reason]++
+	}
+	parts := make([]string, len(order))
+	for i, reason := range order {
+		parts[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,297 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"TRADE/pkg/config"
+	"TRADE/pkg/manager"
+)
+
+// Outcome is the grade of a check
+type Outcome string
+
+const (
+	// Check outcomes
+	OutcomePass Outcome = "pass"
+	OutcomeFail Outcome = "fail"
+	OutcomeSkip Outcome = "skip" // Nothing in the run to check
+)
+
+// Check is one expectation on how a scenario was handled
+type Check struct {
+	Name    string  `json:"name"`
+	Outcome Outcome `json:"outcome"`
+	Detail  string  `json:"detail"`
+}
+
+// Exit is a trade closed in a scenario run
+type Exit struct {
+	Direction  string  `json:"direction"`
+	Reason     string  `json:"reason"`
+	PnLPercent float64 `json:"pnl_percent"`
+	Tick       int     `json:"tick"` // Relative to the start of the shock
+}
+
+// Result is the report of a scenario run
+type Result struct {
+	Scenario       string    `json:"scenario"`
+	Description    string    `json:"description"`
+	Passed         bool      `json:"passed"`
+	Error          string    `json:"error,omitempty"`
+	Ticks          int       `json:"ticks"`
+	ShockTick      int       `json:"shock_tick"` // -1 if the run ended first
+	ShockTime      time.Time `json:"shock_time"`
+	ShockPrice     float64   `json:"shock_price"` // Last price before the shock
+	Low            float64   `json:"low"`         // Price extremes while the shock lasted
+	High           float64   `json:"high"`
+	Entries        int       `json:"entries"`
+	Exits          []Exit    `json:"exits"`
+	SpreadBlocks   int64     `json:"spread_blocks"` // Entry signals the spread guard blocked
+	RiskRejections int       `json:"risk_rejections"`
+	KillSwitch     string    `json:"kill_switch,omitempty"`
+	Alerts         []string  `json:"alerts"`
+	Checks         []Check   `json:"checks"`
+}
+
+// sharedLog keeps the log open across the runs, each system closing its
+// log on shutdown
+type sharedLog struct {
+	manager.Logger
+}
+
+// Close leaves the log open
+func (sharedLog) Close() {}
+
+// Run runs each scenario on a system of its own built from cfg, with the
+// simulator's market as its base, and grades how it was handled
+func Run(cfg *config.Config, log manager.Logger, scenarios []Scenario) []*Result {
+	results := make([]*Result, 0, len(scenarios))
+	for i := range scenarios {
+		results = append(results, run(cfg, log, &scenarios[i]))
+	}
+	return results
+}
+
+// run runs one scenario
+func run(cfg *config.Config, log manager.Logger, sc *Scenario) *Result {
+	// Runs leave no trade history, trade contexts or traces behind and
+	// serve nothing
+	settings := *cfg
+	settings.Storage.Type = ""
+	settings.TradeContext.Enabled = false
+	settings.Tracing.Enabled = false
+	settings.Admin.Enabled = false
+	settings.API.Enabled = false
+	settings.GRPC.Enabled = false
+	settings.Publisher.Type = ""
+
+	system := manager.NewManager(sharedLog{log}, &settings)
+	watch := newObserver(sc.length())
+	watch.subscribe(system.EventBus())
+	shaper := &script{scenario: sc, watch: watch}
+	err := system.RunScenario(shaper)
+	snapshot := system.Snapshot()
+	system.Shutdown()
+
+	result := &Result{
+		Scenario:       sc.Name,
+		Description:    sc.Description,
+		Ticks:          watch.tick + 1,
+		ShockTick:      watch.start,
+		ShockTime:      watch.startTime,
+		ShockPrice:     watch.startPrice,
+		Low:            watch.low,
+		High:           watch.high,
+		Entries:        len(watch.trades),
+		RiskRejections: watch.rejections,
+		Exits:          make([]Exit, 0, len(watch.trades)),
+		Alerts:         make([]string, 0, len(watch.alerts)),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, entry := range watch.trades {
+		if entry.ExitTick >= 0 {
+			result.Exits = append(result.Exits, Exit{
+				Direction:  entry.Direction,
+				Reason:     entry.Reason,
+				PnLPercent: entry.PnLPercent,
+				Tick:       entry.ExitTick - watch.start,
+			})
+		}
+	}
+	if snapshot.EntryFilter != nil {
+		result.SpreadBlocks = snapshot.EntryFilter.SpreadBlocks
+	}
+	if snapshot.KillSwitch != nil {
+		result.KillSwitch = snapshot.KillSwitch.String()
+	}
+	for _, published := range watch.alerts {
+		result.Alerts = append(result.Alerts, fmt.Sprintf("%s: %s", published.Source, published.Message))
+	}
+
+	if watch.start < 0 {
+		result.Checks = []Check{{Name: "Shock injected", Outcome: OutcomeFail, Detail: "the run ended before the shock started"}}
+	} else {
+		result.Checks = grade(&settings, sc, watch, result)
+	}
+	result.Passed = true
+	for _, check := range result.Checks {
+		if check.Outcome == OutcomeFail {
+			result.Passed = false
+		}
+	}
+	return result
+}
+
+// grade checks the run of a scenario against what its shock calls for
+func grade(cfg *config.Config, sc *Scenario, watch *observer, result *Result) []Check {
+	var checks []Check
+	if sc.Move != 0 {
+		checks = append(checks, checkCaught(sc, watch), checkChasing(sc, watch))
+	}
+	if sc.Freeze > 0 {
+		checks = append(checks, checkFreeze(cfg, sc, watch)...)
+	}
+	if sc.SpreadBps > 0 {
+		checks = append(checks, checkSpread(cfg, sc, watch, result)...)
+	}
+	return checks
+}
+
+// checkCaught expects the trades open against the move to be closed by
+// the time it completes, before the low of a fall or the high of a rise
+func checkCaught(sc *Scenario, watch *observer) Check {
+	check := Check{Name: "Trades caught against the move are closed before it completes"}
+	direction := sc.adverse()
+	deadline := watch.start + sc.MoveTicks - 1
+
+	var details []string
+	caught := 0
+	for _, entry := range watch.caught {
+		if entry.Direction != direction {
+			continue
+		}
+		caught++
+		switch {
+		case entry.ExitTick < 0:
+			details = append(details, fmt.Sprintf("%s trade %s is still open", entry.Direction, entry.ID))
+			check.Outcome = OutcomeFail
+		case entry.ExitTick > deadline:
+			details = append(details, fmt.Sprintf("%s trade %s closed %d tick(s) after the move by %s at %+.2f%%",
+				entry.Direction, entry.ID, entry.ExitTick-deadline, entry.Reason, entry.PnLPercent))
+			check.Outcome = OutcomeFail
+		default:
+			details = append(details, fmt.Sprintf("%s trade %s closed at tick %d of %d by %s at %+.2f%%",
+				entry.Direction, entry.ID, entry.ExitTick-watch.start+1, sc.MoveTicks, entry.Reason, entry.PnLPercent))
+		}
+	}
+	switch {
+	case caught == 0 && len(watch.caught) > 0:
+		check.Outcome = OutcomeSkip
+		check.Detail = fmt.Sprintf("%d trade(s) open, all with the move", len(watch.caught))
+	case caught == 0:
+		check.Outcome = OutcomeSkip
+		check.Detail = "no trade was open when the shock started"
+	default:
+		if check.Outcome == "" {
+			check.Outcome = OutcomePass
+		}
+		check.Detail = strings.Join(details, "; ")
+	}
+	return check
+}
+
+// checkChasing expects no trade entered against the move while it lasts
+func checkChasing(sc *Scenario, watch *observer) Check {
+	check := Check{Name: "No entries against the move while it lasts"}
+	entered := watch.entriesBetween(watch.start, watch.start+sc.MoveTicks, sc.adverse())
+	if len(entered) > 0 {
+		check.Outcome = OutcomeFail
+		check.Detail = fmt.Sprintf("%d %s trade(s) entered, first at %.2f", len(entered), sc.adverse(), entered[0].EntryPrice)
+		return check
+	}
+	check.Outcome = OutcomePass
+	check.Detail = fmt.Sprintf("price %+.2f%% from %.2f over %d tick(s)", percent(watch.startPrice, moveExtreme(sc, watch)), watch.startPrice, sc.MoveTicks)
+	return check
+}
+
+// moveExtreme returns the price the move reached
+func moveExtreme(sc *Scenario, watch *observer) float64 {
+	if sc.Move < 0 {
+		return watch.low
+	}
+	return watch.high
+}
+
+// checkFreeze expects the watchdog to find the silent feed stale, keep
+// entries out while it is and find fresh data once the feed is back
+func checkFreeze(cfg *config.Config, sc *Scenario, watch *observer) []Check {
+	detected := Check{Name: "Stale feed detected"}
+	frozen := Check{Name: "No entries while the feed is stale"}
+	resumed := Check{Name: "Fresh data detected once the feed is back"}
+
+	if watch.staleTick < 0 {
+		detected.Outcome = OutcomeFail
+		detected.Detail = fmt.Sprintf("no stale data alert in a %s silence (watchdog.stale_after %s)", sc.Freeze, cfg.Watchdog.StaleAfter)
+		frozen.Outcome, frozen.Detail = OutcomeSkip, "the feed was never found stale"
+		resumed.Outcome, resumed.Detail = OutcomeSkip, "the feed was never found stale"
+		return []Check{detected, frozen, resumed}
+	}
+	detected.Outcome = OutcomePass
+	detected.Detail = fmt.Sprintf("in a %s silence (watchdog.stale_after %s)", sc.Freeze, cfg.Watchdog.StaleAfter)
+
+	last := watch.freshTick
+	if last < 0 {
+		last = watch.tick + 1
+	}
+	entered := watch.entriesBetween(watch.staleTick, last, "")
+	if len(entered) > 0 {
+		frozen.Outcome = OutcomeFail
+		frozen.Detail = fmt.Sprintf("%d trade(s) entered on stale data (watchdog.freeze_entries %t)", len(entered), cfg.Watchdog.FreezeEntries)
+	} else {
+		frozen.Outcome = OutcomePass
+		frozen.Detail = fmt.Sprintf("%d tick(s) fed before fresh data was detected", last-watch.start)
+	}
+
+	if watch.freshTick < 0 {
+		resumed.Outcome = OutcomeFail
+		resumed.Detail = "the feed was still considered stale at the end of the run"
+	} else {
+		resumed.Outcome = OutcomePass
+		resumed.Detail = fmt.Sprintf("at tick %d after the feed came back", watch.freshTick-watch.start+1)
+	}
+	return []Check{detected, frozen, resumed}
+}
+
+// checkSpread expects the entry filter to be set below the blown-out
+// spread and no trade entered into it
+func checkSpread(cfg *config.Config, sc *Scenario, watch *observer, result *Result) []Check {
+	guarded := Check{Name: "Entry filter limits the spread below the blowout"}
+	limit := cfg.EntryFilter.MaxSpreadBps
+	switch {
+	case limit <= 0:
+		guarded.Outcome = OutcomeFail
+		guarded.Detail = "entry_filter.max_spread_bps is 0: entries are not checked against the spread"
+	case limit >= sc.SpreadBps:
+		guarded.Outcome = OutcomeFail
+		guarded.Detail = fmt.Sprintf("entry_filter.max_spread_bps %g admits the %g bps spread", limit, sc.SpreadBps)
+	default:
+		guarded.Outcome = OutcomePass
+		guarded.Detail = fmt.Sprintf("entry_filter.max_spread_bps %g, %d entry signal(s) blocked", limit, result.SpreadBlocks)
+	}
+
+	entries := Check{Name: "No entries into the wide spread"}
+	entered := watch.entriesBetween(watch.start, watch.start+sc.SpreadTicks, "")
+	if len(entered) > 0 {
+		entries.Outcome = OutcomeFail
+		entries.Detail = fmt.Sprintf("%d trade(s) entered at a %g bps spread", len(entered), sc.SpreadBps)
+	} else {
+		entries.Outcome = OutcomePass
+		entries.Detail = fmt.Sprintf("%d tick(s) at a %g bps spread", sc.SpreadTicks, sc.SpreadBps)
+	}
+	return []Check{guarded, entries}
+}
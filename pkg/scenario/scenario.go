@@ -0,0 +1,213 @@
+// Package scenario stresses the system with scripted extreme markets: a
+// flash crash, a gap, a frozen feed or a blown-out spread is injected into
+// the simulator's price path, and the strategy, its stops and the risk
+// guards are graded on how they handled it.
+package scenario
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"TRADE/pkg/market"
+	"TRADE/pkg/types"
+)
+
+// normalSpreadBps is the spread of the scripted quotes outside a blowout
+const normalSpreadBps = 1.0
+
+// shockVolume multiplies the traded volume while the price moves in a shock
+const shockVolume = 5.0
+
+// Scenario is an extreme market injected into the simulator's price path.
+// A shock silences the feed for Freeze, moves the price by Move percent
+// over MoveTicks ticks and retraces Rebound of the move over ReboundTicks;
+// its quotes are SpreadBps wide for its first SpreadTicks ticks.
+type Scenario struct {
+	Name        string
+	Description string
+
+	Freeze       time.Duration // Silence of the feed before the shock's first tick
+	Move         float64       // Price move, in percent (negative falls)
+	MoveTicks    int           // Ticks the move takes; 1 gaps
+	Rebound      float64       // Share of the move retraced after it
+	ReboundTicks int
+	SpreadBps    float64 // Spread of the quotes in the shock; 0 keeps it normal
+	SpreadTicks  int
+
+	// The shock starts at the first tick from Warmup with a trade open if
+	// OnPosition is set, at Latest without one, and the run goes on for
+	// After ticks once it is over
+	OnPosition bool
+	Warmup     int
+	Latest     int
+	After      int
+}
+
+// Builtin returns the scenarios the scenario runner knows
+func Builtin() []Scenario {
+	return []Scenario{
+		{
+			Name:         "flash_crash",
+			Description:  "Price falls 8% in 30 ticks with a trade open, then retraces half of the fall",
+			Move:         -8,
+			MoveTicks:    30,
+			Rebound:      0.5,
+			ReboundTicks: 300,
+			OnPosition:   true,
+			Warmup:       1000,
+			Latest:       6000,
+			After:        1500,
+		},
+		{
+			Name:        "gap_up",
+			Description: "Price gaps 5% higher in one tick with a trade open",
+			Move:        5,
+			MoveTicks:   1,
+			OnPosition:  true,
+			Warmup:      1000,
+			Latest:      6000,
+			After:       1500,
+		},
+		{
+			Name:        "feed_freeze",
+			Description: "Feed goes silent for 2 minutes and comes back 3% lower",
+			Freeze:      2 * time.Minute,
+			Move:        -3,
+			MoveTicks:   1,
+			Warmup:      2000,
+			Latest:      2000,
+			After:       3000,
+		},
+		{
+			Name:        "spread_blowout",
+			Description: "Bid-ask spread widens to 150 bps for 2000 ticks",
+			SpreadBps:   150,
+			SpreadTicks: 2000,
+			Warmup:      1000,
+			Latest:      1000,
+			After:       1500,
+		},
+	}
+}
+
+// Select returns the built-in scenarios named in a comma-separated list;
+// empty or "all" selects all of them
+func Select(names string) ([]Scenario, error) {
+	builtin := Builtin()
+	if names == "" || names == "all" {
+		return builtin, nil
+	}
+	var selected []Scenario
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, scenario := range builtin {
+			if scenario.Name == name {
+				selected = append(selected, scenario)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown scenario %q (want one of %s)", name, strings.Join(Names(), ", "))
+		}
+	}
+	return selected, nil
+}
+
+// Names returns the names of the built-in scenarios
+func Names() []string {
+	var names []string
+	for _, scenario := range Builtin() {
+		names = append(names, scenario.Name)
+	}
+	return names
+}
+
+// length returns the number of ticks the shock lasts
+func (s *Scenario) length() int {
+	return max(s.MoveTicks+s.ReboundTicks, s.SpreadTicks, 1)
+}
+
+// factor returns the multiplier of the price k ticks into the shock
+func (s *Scenario) factor(k int) float64 {
+	move := s.Move / 100
+	switch {
+	case s.MoveTicks <= 0 || move == 0:
+		return 1
+	case k < s.MoveTicks:
+		return 1 + move*float64(k+1)/float64(s.MoveTicks)
+	case k < s.MoveTicks+s.ReboundTicks:
+		return 1 + move*(1-s.Rebound*float64(k-s.MoveTicks+1)/float64(s.ReboundTicks))
+	default:
+		return 1 + move*(1-s.Rebound)
+	}
+}
+
+// adverse returns the direction of the trades the move goes against
+func (s *Scenario) adverse() string {
+	if s.Move < 0 {
+		return types.DirectionLong
+	}
+	return types.DirectionShort
+}
+
+// script shapes the simulator's market into a scenario
+type script struct {
+	scenario *Scenario
+	watch    *observer
+	tick     int           // Index of the tick being shaped
+	offset   time.Duration // Shift of the clock by the freeze
+}
+
+// Shape injects the shock into the next simulator tick
+func (s *script) Shape(tick *types.TickData) (market.Quote, bool) {
+	sc, watch := s.scenario, s.watch
+	index := s.tick
+	s.tick++
+
+	if watch.start < 0 && index >= sc.Warmup && (!sc.OnPosition || len(watch.open) > 0 || index >= sc.Latest) {
+		s.offset += sc.Freeze
+		watch.begin(index, tick.Timestamp.Add(s.offset))
+	}
+	end := sc.Latest + sc.length() + sc.After
+	if watch.start >= 0 {
+		end = watch.start + sc.length() + sc.After
+	}
+	if index >= end {
+		return market.Quote{}, true
+	}
+
+	spread := normalSpreadBps
+	if watch.start >= 0 {
+		k := index - watch.start
+		tick.Price *= sc.factor(k)
+		if k < sc.MoveTicks && sc.Move != 0 {
+			tick.IsAsk = sc.Move > 0
+			tick.Volume *= shockVolume
+		}
+		if k < sc.SpreadTicks {
+			spread = sc.SpreadBps
+		}
+	}
+	tick.Timestamp = tick.Timestamp.Add(s.offset)
+	watch.advance(index, tick.Price, tick.Timestamp)
+
+	half := tick.Price * spread / 20000
+	return market.Quote{
+		Bid:         tick.Price - half,
+		BidQuantity: 1,
+		Ask:         tick.Price + half,
+		AskQuantity: 1,
+	}, false
+}
+
+// percent returns the change from before to after in percent
+func percent(before, after float64) float64 {
+	if before == 0 {
+		return math.NaN()
+	}
+	return (after/before - 1) * 100
+}
@@ -0,0 +1,91 @@
+// Package schedule runs tasks at wall-clock times.
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+)
+
+// ParseClock parses a time of day written as HH:MM into its offset from
+// midnight
+func ParseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (want HH:MM)", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// Daily is a task run once a day when the clock of a location shows a time
+// of day
+type Daily struct {
+	name     string
+	at       time.Duration // Offset from midnight
+	location *time.Location
+	task     func(now time.Time)
+	logger   logger.Interface
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDaily creates a task running at the HH:MM time of day of location
+func NewDaily(name, at string, location *time.Location, task func(now time.Time), log logger.Interface) (*Daily, error) {
+	offset, err := ParseClock(at)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		location = time.Local
+	}
+	return &Daily{
+		name:     name,
+		at:       offset,
+		location: location,
+		task:     task,
+		logger:   log,
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// Next returns the first run after now. On days a clock change skips the
+// time of day, the task runs at the corresponding instant after the change.
+func (d *Daily) Next(now time.Time) time.Time {
+	local := now.In(d.location)
+	next := d.on(local)
+	if !next.After(now) {
+		next = d.on(local.AddDate(0, 0, 1))
+	}
+	return next
+}
+
+// on returns the run on the day of t
+func (d *Daily) on(t time.Time) time.Time {
+	hours, minutes := d.at/time.Hour, d.at%time.Hour/time.Minute
+	return time.Date(t.Year(), t.Month(), t.Day(), int(hours), int(minutes), 0, 0, d.location)
+}
+
+// Start runs the task at each scheduled time in the background
+func (d *Daily) Start() {
+	go func() {
+		for {
+			next := d.Next(time.Now())
+			d.logger.Debug(fmt.Sprintf("Next %s at %s", d.name, next.Format(time.RFC3339)))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case now := <-timer.C:
+				d.task(now)
+			case <-d.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops running the task
+func (d *Daily) Stop() {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+}
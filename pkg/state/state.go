@@ -0,0 +1,159 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"TRADE/pkg/account"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/types"
+)
+
+// Position is an open position handed over between process restarts
+type Position struct {
+	Symbol           string
+	Allocation       string
+	ReservedNotional float64
+	Quantity         decimal.Decimal // Filled quantity of the position
+	EntryFill        decimal.Decimal // Fill price of the entry
+	// Holdings is the quantity held per account; empty without accounts
+	Holdings []account.Allocation `json:",omitempty"`
+	Funding  decimal.Decimal      // Funding received while open, for perpetuals
+	Trade    types.TradeData
+	// Adopted marks a position opened outside TRADE; it has no allocation
+	// reserved yet and is not in the account ledgers
+	Adopted bool `json:",omitempty"`
+}
+
+// HandoffState is the runtime state persisted before a graceful restart
+type HandoffState struct {
+	SavedAt   time.Time
+	Positions []Position
+	Balances  []account.Balance `json:",omitempty"` // Account ledgers
+}
+
+// Save writes the state to path atomically (write to temp file, then rename)
+func Save(path string, st *HandoffState) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create state directory: %v", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// Load reads the state from path. It returns nil without error when
+// there is no saved state.
+func Load(path string) (*HandoffState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state: %v", err)
+	}
+
+	st := &HandoffState{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %v", err)
+	}
+
+	// State handed off by a version whose trades had no JSON tags
+	if bytes.Contains(data, []byte(`"EntryPrice"`)) {
+		if err := decodeLegacyTrades(data, st); err != nil {
+			return nil, err
+		}
+	}
+
+	return st, nil
+}
+
+// legacyTrade is types.TradeData as encoded before it had JSON tags
+type legacyTrade struct {
+	ID           string
+	Active       bool
+	Direction    string
+	EntryPrice   float64
+	EntryTime    time.Time
+	HighestPrice float64
+	LowestPrice  float64
+	StopLoss     float64
+	CurrentPnL   float64
+}
+
+// decodeLegacyTrades fills the trades of st from a legacy state file
+func decodeLegacyTrades(data []byte, st *HandoffState) error {
+	var legacy struct {
+		Positions []struct {
+			Trade legacyTrade
+		}
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("failed to decode state: %v", err)
+	}
+
+	for i := range st.Positions {
+		if i >= len(legacy.Positions) {
+			break
+		}
+		trade := legacy.Positions[i].Trade
+		st.Positions[i].Trade = types.TradeData{
+			ID:           trade.ID,
+			Active:       trade.Active,
+			Direction:    trade.Direction,
+			EntryPrice:   trade.EntryPrice,
+			EntryTime:    trade.EntryTime,
+			HighestPrice: trade.HighestPrice,
+			LowestPrice:  trade.LowestPrice,
+			StopLoss:     trade.StopLoss,
+			CurrentPnL:   trade.CurrentPnL,
+		}
+	}
+	return nil
+}
+
+// Adopt adds a position opened outside TRADE to the state at path, so the
+// next process takes over its exit management. A symbol can only have one
+// open position.
+func Adopt(path string, position Position) error {
+	st, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		st = &HandoffState{}
+	}
+	for _, open := range st.Positions {
+		if open.Symbol == position.Symbol {
+			return fmt.Errorf("%s already has an open position (trade %s) in %s", position.Symbol, open.Trade.ID, path)
+		}
+	}
+
+	position.Adopted = true
+	st.Positions = append(st.Positions, position)
+	st.SavedAt = time.Now()
+	return Save(path, st)
+}
+
+// Remove deletes a consumed state file
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"TRADE/pkg/calendar"
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// FormatProfitFactor formats the profit factor, which is undefined until
+// the first losing trade
+func FormatProfitFactor(performance *types.PerformanceMetrics) string {
+	if performance.LosingTrades == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f", performance.ProfitFactor)
+}
+
+// FormatDivergence describes the divergences of the relative strength
+// and the volume delta, e.g. "bearish (strength, delta)"
+func FormatDivergence(metrics *types.MarketMetrics) string {
+	direction := "bearish"
+	if metrics.BullishDivergence() {
+		direction = "bullish"
+	} else if !metrics.BearishDivergence() {
+		return "none"
+	}
+	var sources []string
+	if metrics.StrengthDivergence != 0 {
+		sources = append(sources, "strength")
+	}
+	if metrics.DeltaDivergence != 0 {
+		sources = append(sources, "delta")
+	}
+	return fmt.Sprintf("%s (%s)", direction, strings.Join(sources, ", "))
+}
+
+// FormatCrossover describes the moving averages of the crossover, e.g.
+// "fast above slow (101.2 / 100.8)"
+func FormatCrossover(metrics *types.MarketMetrics) string {
+	switch metrics.MACross {
+	case 1:
+		return fmt.Sprintf("fast above slow (%.6f / %.6f)", metrics.FastMA, metrics.SlowMA)
+	case -1:
+		return fmt.Sprintf("fast below slow (%.6f / %.6f)", metrics.FastMA, metrics.SlowMA)
+	}
+	return "n/a"
+}
+
+// FormatPosition describes the position of an instance of a portfolio,
+// e.g. "buy 1520.40 (open +12.30)"
+func FormatPosition(position types.SymbolPosition) string {
+	if position.Direction == "" {
+		return "flat"
+	}
+	return fmt.Sprintf("%s %.2f (open %+.2f)", position.Direction, position.Notional, position.OpenPnL)
+}
+
+// FormatTopRisks lists the top contributors to the open risk of a
+// portfolio, e.g. "BTCUSDT 62% (45.10), ETHUSDT 38% (27.60)"
+func FormatTopRisks(view *types.PortfolioView) string {
+	if len(view.TopRisks) == 0 {
+		return "none"
+	}
+	risks := make([]string, len(view.TopRisks))
+	for i, risk := range view.TopRisks {
+		risks[i] = fmt.Sprintf("%s %.0f%% (%.2f)", strings.ToUpper(risk.Symbol), risk.Share*100, risk.OpenRisk)
+	}
+	return strings.Join(risks, ", ")
+}
+
+// ConsoleRenderer prints the multi-line market status block
+type ConsoleRenderer struct{}
+
+// Render writes the status block
+func (r *ConsoleRenderer) Render(w io.Writer, status *events.StatusEvent) error {
+	metrics := status.Metrics
+
+	tradeLine := "No Active Trade"
+	if status.TradeActive {
+		tradeLine = fmt.Sprintf("Active Trade | Current PnL: %.2f%%", status.TradePnL)
+	}
+
+	performanceLine := "No Closed Trades"
+	if performance := status.Performance; performance != nil && performance.TotalTrades > 0 {
+		performanceLine = fmt.Sprintf("Trades: %d | Win: %.1f%% | PnL: %.2f | DD: %.2f | PF: %s | Exp: %+.2f%%",
+			performance.TotalTrades, performance.WinRate, performance.TotalPnL,
+			performance.MaxDrawdown, FormatProfitFactor(performance), performance.Expectancy)
+	}
+
+	equityLine := ""
+	if equity := status.Equity; equity != nil {
+		equityLine = fmt.Sprintf("Equity: %.2f | Unrealized: %+.2f | DD: %.2f%% (max %.2f%%)\n",
+			equity.Equity, equity.UnrealizedPnL, equity.DrawdownPercent, equity.MaxDrawdown)
+	}
+
+	portfolioLines := ""
+	if view := status.Portfolio; view != nil {
+		portfolioLines = fmt.Sprintf("Portfolio Equity: %.2f | Open PnL: %+.2f | Gross: %.2f | Net: %+.2f\n",
+			view.Equity, view.OpenPnL, view.GrossExposure, view.NetExposure)
+		for _, position := range view.Positions {
+			portfolioLines += fmt.Sprintf("  %s: %s\n", strings.ToUpper(position.Symbol), FormatPosition(position))
+		}
+		portfolioLines += fmt.Sprintf("Top Risk: %s\n", FormatTopRisks(view))
+	}
+
+	_, err := fmt.Fprintf(w,
+		"\n=== MARKET STATUS [%s] ===\n"+
+			"Price: %.6f | Vol: %.2f%% | RS: %.2f\n"+
+			"Trend: %.2f | Order Imb: %.2f | MER: %.2f\n"+
+			"%s\n"+
+			"%s\n"+
+			"%s"+
+			"%s"+
+			"=====================\n",
+		status.ExecutionMode,
+		status.Price,
+		metrics.RealizedVolatility,
+		metrics.RelativeStrength,
+		metrics.TrendStrength,
+		metrics.OrderImbalance,
+		metrics.MarketEfficiencyRatio,
+		tradeLine,
+		performanceLine,
+		equityLine,
+		portfolioLines,
+	)
+	return err
+}
+
+// jsonStatus is the single-line JSON status record
+type jsonStatus struct {
+	Timestamp     string                    `json:"timestamp"`
+	Symbol        string                    `json:"symbol"`
+	Status        string                    `json:"status"`
+	ExecutionMode string                    `json:"execution_mode"`
+	Price         float64                   `json:"price"`
+	Metrics       jsonMetrics               `json:"metrics"`
+	TradeActive   bool                      `json:"trade_active"`
+	TradePnL      float64                   `json:"trade_pnl"`
+	Performance   *types.PerformanceMetrics `json:"performance,omitempty"`
+	Equity        *types.EquityPoint        `json:"equity,omitempty"`
+	Portfolio     *types.PortfolioView      `json:"portfolio,omitempty"`
+}
+
+// jsonMetrics holds all market metrics of a JSON status record
+type jsonMetrics struct {
+	RealizedVolatility    float64 `json:"realized_volatility"`
+	ATR                   float64 `json:"atr"`
+	RelativeStrength      float64 `json:"relative_strength"`
+	OrderImbalance        float64 `json:"order_imbalance"`
+	TrendStrength         float64 `json:"trend_strength"`
+	AvgTrendStrength      float64 `json:"avg_trend_strength"`
+	MarketEfficiencyRatio float64 `json:"market_efficiency_ratio"`
+	BookImbalance         float64 `json:"book_imbalance"`
+	BookImbalanceTrend    float64 `json:"book_imbalance_trend"`
+	SpreadBps             float64 `json:"spread_bps"`
+	TopImbalance          float64 `json:"top_imbalance"`
+	WeightedMid           float64 `json:"weighted_mid"`
+	StrengthDivergence    float64 `json:"strength_divergence"`
+	DeltaDivergence       float64 `json:"delta_divergence"`
+	RSI                   float64 `json:"rsi"`
+	MACD                  float64 `json:"macd"`
+	MACDSignal            float64 `json:"macd_signal"`
+	MACDHistogram         float64 `json:"macd_histogram"`
+	BollingerUpper        float64 `json:"bollinger_upper"`
+	BollingerMiddle       float64 `json:"bollinger_middle"`
+	BollingerLower        float64 `json:"bollinger_lower"`
+	BollingerPercentB     float64 `json:"bollinger_percent_b"`
+	FastMA                float64 `json:"fast_ma"`
+	SlowMA                float64 `json:"slow_ma"`
+	MACross               float64 `json:"ma_cross"`
+}
+
+// JSONRenderer writes each status as one JSON line for piping or scraping
+type JSONRenderer struct{}
+
+// Render writes the status as a JSON line
+func (r *JSONRenderer) Render(w io.Writer, status *events.StatusEvent) error {
+	record := jsonStatus{
+		Timestamp:     status.Timestamp.UTC().Format(time.RFC3339Nano),
+		Symbol:        status.Symbol,
+		Status:        status.Status,
+		ExecutionMode: string(status.ExecutionMode),
+		Price:         status.Price,
+		TradeActive:   status.TradeActive,
+		TradePnL:      status.TradePnL,
+		Performance:   status.Performance,
+		Equity:        status.Equity,
+		Portfolio:     status.Portfolio,
+	}
+	if metrics := status.Metrics; metrics != nil {
+		record.Metrics = jsonMetrics{
+			RealizedVolatility:    metrics.RealizedVolatility,
+			ATR:                   metrics.ATR,
+			RelativeStrength:      metrics.RelativeStrength,
+			OrderImbalance:        metrics.OrderImbalance,
+			TrendStrength:         metrics.TrendStrength,
+			AvgTrendStrength:      metrics.AvgTrendStrength,
+			MarketEfficiencyRatio: metrics.MarketEfficiencyRatio,
+			BookImbalance:         metrics.BookImbalance,
+			BookImbalanceTrend:    metrics.BookImbalanceTrend,
+			SpreadBps:             metrics.SpreadBps,
+			TopImbalance:          metrics.TopImbalance,
+			WeightedMid:           metrics.WeightedMid,
+			StrengthDivergence:    metrics.StrengthDivergence,
+			DeltaDivergence:       metrics.DeltaDivergence,
+			RSI:                   metrics.RSI,
+			MACD:                  metrics.MACD,
+			MACDSignal:            metrics.MACDSignal,
+			MACDHistogram:         metrics.MACDHistogram,
+			BollingerUpper:        metrics.BollingerUpper,
+			BollingerMiddle:       metrics.BollingerMiddle,
+			BollingerLower:        metrics.BollingerLower,
+			BollingerPercentB:     metrics.BollingerPercentB,
+			FastMA:                metrics.FastMA,
+			SlowMA:                metrics.SlowMA,
+			MACross:               metrics.MACross,
+		}
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", line)
+	return err
+}
+
+// TUIRenderer redraws a full-screen dashboard on every update
+type TUIRenderer struct{}
+
+// Render clears the terminal and draws the dashboard
+func (r *TUIRenderer) Render(w io.Writer, status *events.StatusEvent) error {
+	metrics := status.Metrics
+	rows := [][2]string{
+		{"Symbol", strings.ToUpper(status.Symbol)},
+		{"Status", status.Status},
+		{"Execution", string(status.ExecutionMode)},
+		{"Updated", calendar.Display(status.Timestamp).Format("2006-01-02 15:04:05")},
+		{"Price", fmt.Sprintf("%.6f", status.Price)},
+		{"Volatility", fmt.Sprintf("%.2f%%", metrics.RealizedVolatility)},
+		{"ATR", fmt.Sprintf("%.6f", metrics.ATR)},
+		{"Rel. strength", fmt.Sprintf("%.2f", metrics.RelativeStrength)},
+		{"Trend", fmt.Sprintf("%.2f (avg %.2f)", metrics.TrendStrength, metrics.AvgTrendStrength)},
+		{"Order imbalance", fmt.Sprintf("%.2f", metrics.OrderImbalance)},
+		{"Book imbalance", fmt.Sprintf("%.2f (trend %+.3f)", metrics.BookImbalance, metrics.BookImbalanceTrend)},
+		{"Book", fmt.Sprintf("spread %.2f bps, top imbalance %.2f, weighted mid %.6f",
+			metrics.SpreadBps, metrics.TopImbalance, metrics.WeightedMid)},
+		{"Efficiency", fmt.Sprintf("%.2f", metrics.MarketEfficiencyRatio)},
+		{"Divergence", FormatDivergence(metrics)},
+		{"RSI", fmt.Sprintf("%.1f", metrics.RSI)},
+		{"MACD", fmt.Sprintf("%.6f (signal %.6f, histogram %+.6f)", metrics.MACD, metrics.MACDSignal, metrics.MACDHistogram)},
+		{"Bollinger", fmt.Sprintf("%.6f / %.6f / %.6f (%%B %.2f)",
+			metrics.BollingerLower, metrics.BollingerMiddle, metrics.BollingerUpper, metrics.BollingerPercentB)},
+		{"MA crossover", FormatCrossover(metrics)},
+	}
+	if status.TradeActive {
+		rows = append(rows, [2]string{"Trade", fmt.Sprintf("ACTIVE  PnL %+.2f%%", status.TradePnL)})
+	} else {
+		rows = append(rows, [2]string{"Trade", "none"})
+	}
+	if performance := status.Performance; performance != nil {
+		rows = append(rows,
+			[2]string{"Closed trades", fmt.Sprintf("%d (win %.1f%%)", performance.TotalTrades, performance.WinRate)},
+			[2]string{"Total PnL", fmt.Sprintf("%+.2f (avg %+.2f)", performance.TotalPnL, performance.AveragePnL)},
+			[2]string{"Max drawdown", fmt.Sprintf("%.2f", performance.MaxDrawdown)},
+			[2]string{"Profit factor", FormatProfitFactor(performance)},
+			[2]string{"Expectancy", fmt.Sprintf("%+.2f%% per trade", performance.Expectancy)},
+			[2]string{"Exposure", performance.ExposureTime.Round(time.Second).String()},
+		)
+	}
+	if equity := status.Equity; equity != nil {
+		rows = append(rows,
+			[2]string{"Equity", fmt.Sprintf("%.2f (open %+.2f)", equity.Equity, equity.UnrealizedPnL)},
+			[2]string{"Drawdown", fmt.Sprintf("%.2f%% (max %.2f%%)", equity.DrawdownPercent, equity.MaxDrawdown)},
+		)
+	}
+	if view := status.Portfolio; view != nil {
+		rows = append(rows,
+			[2]string{"Portfolio equity", fmt.Sprintf("%.2f (open %+.2f)", view.Equity, view.OpenPnL)},
+			[2]string{"Gross / net", fmt.Sprintf("%.2f / %+.2f", view.GrossExposure, view.NetExposure)},
+		)
+		for _, position := range view.Positions {
+			rows = append(rows, [2]string{"  " + strings.ToUpper(position.Symbol), FormatPosition(position)})
+		}
+		rows = append(rows, [2]string{"Top risk", FormatTopRisks(view)})
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J") // Home and clear screen
+	b.WriteString("+------------------+------------------------------+\n")
+	b.WriteString("| TRADE            |                              |\n")
+	b.WriteString("+------------------+------------------------------+\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "| %-16s | %-28s |\n", row[0], row[1])
+	}
+	b.WriteString("+------------------+------------------------------+\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
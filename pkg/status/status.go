@@ -0,0 +1,85 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"TRADE/pkg/events"
+)
+
+// Renderer formats a status update for display
+type Renderer interface {
+	Render(w io.Writer, status *events.StatusEvent) error
+}
+
+// NewRenderer returns the renderer with the given name ("console", "json"
+// or "tui")
+func NewRenderer(name string) (Renderer, error) {
+	switch name {
+	case "", "console":
+		return &ConsoleRenderer{}, nil
+	case "json":
+		return &JSONRenderer{}, nil
+	case "tui":
+		return &TUIRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown status renderer: %s", name)
+	}
+}
+
+// Reporter displays status events from the event bus, separate from
+// operational logging. Rendering happens on its own goroutine so slow
+// terminals never block publishers.
+type Reporter struct {
+	bus          *events.Bus
+	renderer     Renderer
+	writer       io.Writer
+	updates      chan *events.StatusEvent
+	done         chan struct{}
+	subscription events.SubscriptionID
+	stopOnce     sync.Once
+}
+
+// NewReporter creates a status reporter writing to w
+func NewReporter(bus *events.Bus, renderer Renderer, w io.Writer) *Reporter {
+	return &Reporter{
+		bus:      bus,
+		renderer: renderer,
+		writer:   w,
+		updates:  make(chan *events.StatusEvent, 10),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start subscribes to status events and starts rendering
+func (r *Reporter) Start() {
+	r.subscription = r.bus.Subscribe(events.TypeStatus, func(event events.Event) {
+		select {
+		case r.updates <- event.(*events.StatusEvent):
+		default:
+			// Display is behind; drop this update
+		}
+	})
+	go r.run()
+}
+
+// run renders status updates until stopped
+func (r *Reporter) run() {
+	for {
+		select {
+		case update := <-r.updates:
+			r.renderer.Render(r.writer, update)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Stop unsubscribes and stops rendering
+func (r *Reporter) Stop() {
+	r.stopOnce.Do(func() {
+		r.bus.Unsubscribe(r.subscription)
+		close(r.done)
+	})
+}
@@ -0,0 +1,627 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"TRADE/pkg/types"
+)
+
+// sqlStore implements Store on a database/sql database. Statements are
+// written with ? placeholders and portable SQL; drivers that number their
+// placeholders ($1, $2, ...) set numbered.
+//
+// Decimals are stored as exact text (see decimal.Value); times as Unix
+// nanoseconds so ranges sort and compare as integers. Symbols are stored
+// lower case.
+type sqlStore struct {
+	db       *sql.DB
+	numbered bool
+}
+
+// schema creates the tables on first use. It sticks to types both SQLite
+// and PostgreSQL understand.
+const schema = `
+CREATE TABLE IF NOT EXISTS trades (
+	id             TEXT PRIMARY KEY,
+	run_id         TEXT NOT NULL,
+	mode           TEXT NOT NULL,
+	symbol         TEXT NOT NULL,
+	correlation_id TEXT NOT NULL DEFAULT '',
+	entry_price    TEXT NOT NULL,
+	exit_price     TEXT NOT NULL,
+	quantity       TEXT NOT NULL,
+	pnl            TEXT NOT NULL,
+	pnl_percent    DOUBLE PRECISION NOT NULL,
+	outcome        TEXT NOT NULL,
+	reason         TEXT NOT NULL DEFAULT '',
+	entry_time     BIGINT NOT NULL,
+	exit_time      BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS trades_symbol_exit_time ON trades (symbol, exit_time);
+CREATE INDEX IF NOT EXISTS trades_run_id ON trades (run_id);
+
+CREATE TABLE IF NOT EXISTS trade_tags (
+	trade_id TEXT NOT NULL,
+	tag      TEXT NOT NULL,
+	PRIMARY KEY (trade_id, tag)
+);
+CREATE INDEX IF NOT EXISTS trade_tags_tag ON trade_tags (tag);
+
+CREATE TABLE IF NOT EXISTS trade_notes (
+	trade_id   TEXT NOT NULL,
+	written_at BIGINT NOT NULL,
+	author     TEXT NOT NULL DEFAULT '',
+	text       TEXT NOT NULL,
+	PRIMARY KEY (trade_id, written_at)
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id             TEXT PRIMARY KEY,
+	run_id         TEXT NOT NULL,
+	mode           TEXT NOT NULL,
+	trade_id       TEXT NOT NULL DEFAULT '',
+	correlation_id TEXT NOT NULL DEFAULT '',
+	symbol         TEXT NOT NULL,
+	side           TEXT NOT NULL,
+	price          TEXT NOT NULL,
+	quantity       TEXT NOT NULL,
+	reason         TEXT NOT NULL DEFAULT '',
+	placed_at      BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS orders_symbol_placed_at ON orders (symbol, placed_at);
+CREATE INDEX IF NOT EXISTS orders_trade_id ON orders (trade_id);
+
+CREATE TABLE IF NOT EXISTS equity_snapshots (
+	run_id       TEXT NOT NULL,
+	mode         TEXT NOT NULL,
+	taken_at     BIGINT NOT NULL,
+	equity       DOUBLE PRECISION NOT NULL,
+	realized_pnl DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (run_id, taken_at)
+);
+
+CREATE TABLE IF NOT EXISTS instances (
+	run_id     TEXT PRIMARY KEY,
+	mode       TEXT NOT NULL,
+	symbol     TEXT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	equity     DOUBLE PRECISION NOT NULL,
+	direction  TEXT NOT NULL,
+	notional   DOUBLE PRECISION NOT NULL,
+	open_pnl   DOUBLE PRECISION NOT NULL,
+	open_risk  DOUBLE PRECISION NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS backtest_runs (
+	id            TEXT PRIMARY KEY,
+	dataset       TEXT NOT NULL,
+	version       TEXT NOT NULL,
+	started_at    BIGINT NOT NULL,
+	finished_at   BIGINT NOT NULL,
+	total_trades  BIGINT NOT NULL,
+	win_rate      DOUBLE PRECISION NOT NULL,
+	total_pnl     DOUBLE PRECISION NOT NULL,
+	max_drawdown  DOUBLE PRECISION NOT NULL,
+	profit_factor DOUBLE PRECISION NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS daily_summaries (
+	id         TEXT PRIMARY KEY,
+	run_id     TEXT NOT NULL,
+	mode       TEXT NOT NULL,
+	symbol     TEXT NOT NULL,
+	day        TEXT NOT NULL,
+	start_time BIGINT NOT NULL,
+	end_time   BIGINT NOT NULL,
+	trades     BIGINT NOT NULL,
+	wins       BIGINT NOT NULL,
+	losses     BIGINT NOT NULL,
+	win_rate   DOUBLE PRECISION NOT NULL,
+	pnl        TEXT NOT NULL,
+	fees       TEXT NOT NULL,
+	currency   TEXT NOT NULL
+);
+`
+
+// bind rewrites ? placeholders for the driver
+func (s *sqlStore) bind(statement string) string {
+	if !s.numbered {
+		return statement
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range statement {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// exec runs a statement without results
+func (s *sqlStore) exec(statement string, args ...interface{}) error {
+	_, err := s.db.Exec(s.bind(statement), args...)
+	return err
+}
+
+// query runs a statement returning rows
+func (s *sqlStore) query(statement string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.bind(statement), args...)
+}
+
+// SaveTrade stores a trade, replacing any trade with the same ID
+func (s *sqlStore) SaveTrade(trade *Trade) error {
+	err := s.exec(`INSERT INTO trades
+		(id, run_id, mode, symbol, correlation_id, entry_price, exit_price, quantity,
+		 pnl, pnl_percent, outcome, reason, entry_time, exit_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+		 run_id = excluded.run_id, mode = excluded.mode, symbol = excluded.symbol,
+		 correlation_id = excluded.correlation_id, entry_price = excluded.entry_price,
+		 exit_price = excluded.exit_price, quantity = excluded.quantity, pnl = excluded.pnl,
+		 pnl_percent = excluded.pnl_percent, outcome = excluded.outcome, reason = excluded.reason,
+		 entry_time = excluded.entry_time, exit_time = excluded.exit_time`,
+		trade.ID, trade.RunID, trade.Mode, strings.ToLower(trade.Symbol), trade.CorrelationID,
+		trade.EntryPrice, trade.ExitPrice, trade.Quantity, trade.PnL, trade.PnLPercent, string(trade.Outcome()), trade.Reason,
+		unixNanos(trade.EntryTime), unixNanos(trade.ExitTime))
+	if err != nil {
+		return fmt.Errorf("failed to save trade %s: %v", trade.ID, err)
+	}
+	return nil
+}
+
+// Trades returns the matching trades with their tags and notes, oldest
+// first
+func (s *sqlStore) Trades(query TradeQuery) ([]Trade, error) {
+	var where filters
+	where.add("run_id = ?", query.RunID, query.RunID != "")
+	where.add("mode = ?", query.Mode, query.Mode != "")
+	where.add("symbol = ?", strings.ToLower(query.Symbol), query.Symbol != "")
+	where.add("exit_time >= ?", unixNanos(query.Since), !query.Since.IsZero())
+	where.add("exit_time < ?", unixNanos(query.Until), !query.Until.IsZero())
+	where.add("outcome = ?", string(query.Outcome), query.Outcome != "")
+	where.add("id = ?", query.ID, query.ID != "")
+	for _, tag := range query.Tags {
+		tag, _ := types.NormalizeTag(tag)
+		where.add("id IN (SELECT trade_id FROM trade_tags WHERE tag = ?)", tag, true)
+	}
+	selection := " FROM trades" + where.clause("exit_time", query.Limit)
+
+	rows, err := s.query(`SELECT id, run_id, mode, symbol, correlation_id, entry_price, exit_price,
+		quantity, pnl, pnl_percent, reason, entry_time, exit_time`+selection, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trades: %v", err)
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var trade Trade
+		var entryTime, exitTime int64
+		if err := rows.Scan(&trade.ID, &trade.RunID, &trade.Mode, &trade.Symbol, &trade.CorrelationID,
+			&trade.EntryPrice, &trade.ExitPrice, &trade.Quantity, &trade.PnL, &trade.PnLPercent,
+			&trade.Reason, &entryTime, &exitTime); err != nil {
+			return nil, fmt.Errorf("failed to read trade: %v", err)
+		}
+		trade.EntryTime = fromUnixNanos(entryTime)
+		trade.ExitTime = fromUnixNanos(exitTime)
+		trades = append(trades, trade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query trades: %v", err)
+	}
+	if len(trades) > 0 {
+		if err := s.annotate(trades, "trade_id IN (SELECT id"+selection+")", where.args...); err != nil {
+			return nil, err
+		}
+	}
+	reverse(trades)
+	return trades, nil
+}
+
+// annotate loads the tags and notes of trades, selected by condition
+func (s *sqlStore) annotate(trades []Trade, condition string, args ...interface{}) error {
+	byID := make(map[string]*Trade, len(trades))
+	for i := range trades {
+		byID[trades[i].ID] = &trades[i]
+	}
+
+	rows, err := s.query(`SELECT trade_id, tag FROM trade_tags WHERE `+condition+`
+		ORDER BY trade_id, tag`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query trade tags: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tradeID, tag string
+		if err := rows.Scan(&tradeID, &tag); err != nil {
+			return fmt.Errorf("failed to read trade tag: %v", err)
+		}
+		if trade, ok := byID[tradeID]; ok {
+			trade.Tags = append(trade.Tags, tag)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query trade tags: %v", err)
+	}
+
+	notes, err := s.query(`SELECT trade_id, written_at, author, text FROM trade_notes WHERE `+condition+`
+		ORDER BY trade_id, written_at`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query trade notes: %v", err)
+	}
+	defer notes.Close()
+	for notes.Next() {
+		var note Note
+		var writtenAt int64
+		if err := notes.Scan(&note.TradeID, &writtenAt, &note.Author, &note.Text); err != nil {
+			return fmt.Errorf("failed to read trade note: %v", err)
+		}
+		note.Time = fromUnixNanos(writtenAt)
+		if trade, ok := byID[note.TradeID]; ok {
+			trade.Notes = append(trade.Notes, note)
+		}
+	}
+	if err := notes.Err(); err != nil {
+		return fmt.Errorf("failed to query trade notes: %v", err)
+	}
+	return nil
+}
+
+// Annotations returns the tags and notes of a trade, open or closed
+func (s *sqlStore) Annotations(tradeID string) ([]string, []Note, error) {
+	trades := []Trade{{ID: tradeID}}
+	if err := s.annotate(trades, "trade_id = ?", tradeID); err != nil {
+		return nil, nil, err
+	}
+	return trades[0].Tags, trades[0].Notes, nil
+}
+
+// TagTrade tags a trade; tags it already carries are kept once
+func (s *sqlStore) TagTrade(tradeID string, tags ...string) error {
+	parsed, err := ParseTags(tags)
+	if err != nil {
+		return err
+	}
+	for _, tag := range parsed {
+		if err := s.exec(`INSERT INTO trade_tags (trade_id, tag) VALUES (?, ?)
+			ON CONFLICT (trade_id, tag) DO NOTHING`, tradeID, tag); err != nil {
+			return fmt.Errorf("failed to tag trade %s: %v", tradeID, err)
+		}
+	}
+	return nil
+}
+
+// UntagTrade removes tags from a trade
+func (s *sqlStore) UntagTrade(tradeID string, tags ...string) error {
+	for _, tag := range tags {
+		tag, _ := types.NormalizeTag(tag)
+		if err := s.exec(`DELETE FROM trade_tags WHERE trade_id = ? AND tag = ?`, tradeID, tag); err != nil {
+			return fmt.Errorf("failed to untag trade %s: %v", tradeID, err)
+		}
+	}
+	return nil
+}
+
+// AddNote stores a note, replacing any note on the trade written at the
+// same time
+func (s *sqlStore) AddNote(note *Note) error {
+	if strings.TrimSpace(note.Text) == "" {
+		return fmt.Errorf("empty note on trade %s", note.TradeID)
+	}
+	err := s.exec(`INSERT INTO trade_notes (trade_id, written_at, author, text) VALUES (?, ?, ?, ?)
+		ON CONFLICT (trade_id, written_at) DO UPDATE SET author = excluded.author, text = excluded.text`,
+		note.TradeID, unixNanos(note.Time), note.Author, note.Text)
+	if err != nil {
+		return fmt.Errorf("failed to save note on trade %s: %v", note.TradeID, err)
+	}
+	return nil
+}
+
+// SaveOrder stores an order, replacing any order with the same ID
+func (s *sqlStore) SaveOrder(order *Order) error {
+	err := s.exec(`INSERT INTO orders
+		(id, run_id, mode, trade_id, correlation_id, symbol, side, price, quantity, reason, placed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+		 run_id = excluded.run_id, mode = excluded.mode, trade_id = excluded.trade_id,
+		 correlation_id = excluded.correlation_id, symbol = excluded.symbol, side = excluded.side,
+		 price = excluded.price, quantity = excluded.quantity, reason = excluded.reason,
+		 placed_at = excluded.placed_at`,
+		order.ID, order.RunID, order.Mode, order.TradeID, order.CorrelationID,
+		strings.ToLower(order.Symbol), order.Side, order.Price, order.Quantity, order.Reason, unixNanos(order.Time))
+	if err != nil {
+		return fmt.Errorf("failed to save order %s: %v", order.ID, err)
+	}
+	return nil
+}
+
+// Orders returns the matching orders, oldest first
+func (s *sqlStore) Orders(query OrderQuery) ([]Order, error) {
+	var where filters
+	where.add("run_id = ?", query.RunID, query.RunID != "")
+	where.add("trade_id = ?", query.TradeID, query.TradeID != "")
+	where.add("symbol = ?", strings.ToLower(query.Symbol), query.Symbol != "")
+	where.add("placed_at >= ?", unixNanos(query.Since), !query.Since.IsZero())
+	where.add("placed_at < ?", unixNanos(query.Until), !query.Until.IsZero())
+
+	rows, err := s.query(`SELECT id, run_id, mode, trade_id, correlation_id, symbol, side,
+		price, quantity, reason, placed_at FROM orders`+where.clause("placed_at", query.Limit), where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %v", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var order Order
+		var timestamp int64
+		if err := rows.Scan(&order.ID, &order.RunID, &order.Mode, &order.TradeID, &order.CorrelationID,
+			&order.Symbol, &order.Side, &order.Price, &order.Quantity, &order.Reason, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to read order: %v", err)
+		}
+		order.Time = fromUnixNanos(timestamp)
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query orders: %v", err)
+	}
+	reverse(orders)
+	return orders, nil
+}
+
+// SaveEquity stores an equity snapshot, replacing one of the same run and time
+func (s *sqlStore) SaveEquity(snapshot *EquitySnapshot) error {
+	err := s.exec(`INSERT INTO equity_snapshots (run_id, mode, taken_at, equity, realized_pnl)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (run_id, taken_at) DO UPDATE SET
+		 mode = excluded.mode, equity = excluded.equity, realized_pnl = excluded.realized_pnl`,
+		snapshot.RunID, snapshot.Mode, unixNanos(snapshot.Time), snapshot.Equity, snapshot.RealizedPnL)
+	if err != nil {
+		return fmt.Errorf("failed to save equity snapshot: %v", err)
+	}
+	return nil
+}
+
+// EquityCurve returns the snapshots of a run, oldest first
+func (s *sqlStore) EquityCurve(runID string) ([]EquitySnapshot, error) {
+	rows, err := s.query(`SELECT run_id, mode, taken_at, equity, realized_pnl FROM equity_snapshots
+		WHERE run_id = ? ORDER BY taken_at`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query equity curve: %v", err)
+	}
+	defer rows.Close()
+
+	var curve []EquitySnapshot
+	for rows.Next() {
+		var snapshot EquitySnapshot
+		var timestamp int64
+		if err := rows.Scan(&snapshot.RunID, &snapshot.Mode, &timestamp,
+			&snapshot.Equity, &snapshot.RealizedPnL); err != nil {
+			return nil, fmt.Errorf("failed to read equity snapshot: %v", err)
+		}
+		snapshot.Time = fromUnixNanos(timestamp)
+		curve = append(curve, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query equity curve: %v", err)
+	}
+	return curve, nil
+}
+
+// SaveInstance stores the snapshot of an instance, replacing its last
+func (s *sqlStore) SaveInstance(snapshot *InstanceSnapshot) error {
+	err := s.exec(`INSERT INTO instances
+		(run_id, mode, symbol, updated_at, equity, direction, notional, open_pnl, open_risk)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (run_id) DO UPDATE SET
+		 mode = excluded.mode, symbol = excluded.symbol, updated_at = excluded.updated_at,
+		 equity = excluded.equity, direction = excluded.direction, notional = excluded.notional,
+		 open_pnl = excluded.open_pnl, open_risk = excluded.open_risk`,
+		snapshot.RunID, snapshot.Mode, strings.ToLower(snapshot.Symbol), unixNanos(snapshot.Time), snapshot.Equity,
+		snapshot.Direction, snapshot.Notional, snapshot.OpenPnL, snapshot.OpenRisk)
+	if err != nil {
+		return fmt.Errorf("failed to save instance snapshot: %v", err)
+	}
+	return nil
+}
+
+// Instances returns the snapshots of the instances of mode taken since a
+// time, by symbol
+func (s *sqlStore) Instances(mode string, since time.Time) ([]InstanceSnapshot, error) {
+	rows, err := s.query(`SELECT run_id, mode, symbol, updated_at, equity, direction, notional, open_pnl, open_risk
+		FROM instances WHERE mode = ? AND updated_at >= ? ORDER BY symbol, run_id`, mode, unixNanos(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query instances: %v", err)
+	}
+	defer rows.Close()
+
+	var snapshots []InstanceSnapshot
+	for rows.Next() {
+		var snapshot InstanceSnapshot
+		var updated int64
+		if err := rows.Scan(&snapshot.RunID, &snapshot.Mode, &snapshot.Symbol, &updated, &snapshot.Equity,
+			&snapshot.Direction, &snapshot.Notional, &snapshot.OpenPnL, &snapshot.OpenRisk); err != nil {
+			return nil, fmt.Errorf("failed to read instance snapshot: %v", err)
+		}
+		snapshot.Time = fromUnixNanos(updated)
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query instances: %v", err)
+	}
+	return snapshots, nil
+}
+
+// RemoveInstance removes the snapshot of a run that stopped
+func (s *sqlStore) RemoveInstance(runID string) error {
+	if err := s.exec(`DELETE FROM instances WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to remove instance snapshot: %v", err)
+	}
+	return nil
+}
+
+// SaveBacktestRun stores a run, replacing any run with the same ID
+func (s *sqlStore) SaveBacktestRun(run *BacktestRun) error {
+	err := s.exec(`INSERT INTO backtest_runs
+		(id, dataset, version, started_at, finished_at, total_trades, win_rate,
+		 total_pnl, max_drawdown, profit_factor)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+		 dataset = excluded.dataset, version = excluded.version, started_at = excluded.started_at,
+		 finished_at = excluded.finished_at, total_trades = excluded.total_trades,
+		 win_rate = excluded.win_rate, total_pnl = excluded.total_pnl,
+		 max_drawdown = excluded.max_drawdown, profit_factor = excluded.profit_factor`,
+		run.ID, run.Dataset, run.Version, unixNanos(run.StartedAt), unixNanos(run.FinishedAt),
+		run.TotalTrades, run.WinRate, run.TotalPnL, run.MaxDrawdown, run.ProfitFactor)
+	if err != nil {
+		return fmt.Errorf("failed to save backtest run %s: %v", run.ID, err)
+	}
+	return nil
+}
+
+// BacktestRuns returns the most recent limit runs (0 means all), oldest first
+func (s *sqlStore) BacktestRuns(limit int) ([]BacktestRun, error) {
+	var where filters
+	rows, err := s.query(`SELECT id, dataset, version, started_at, finished_at, total_trades,
+		win_rate, total_pnl, max_drawdown, profit_factor FROM backtest_runs`+
+		where.clause("started_at", limit), where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backtest runs: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []BacktestRun
+	for rows.Next() {
+		var run BacktestRun
+		var startedAt, finishedAt int64
+		if err := rows.Scan(&run.ID, &run.Dataset, &run.Version, &startedAt, &finishedAt,
+			&run.TotalTrades, &run.WinRate, &run.TotalPnL, &run.MaxDrawdown, &run.ProfitFactor); err != nil {
+			return nil, fmt.Errorf("failed to read backtest run: %v", err)
+		}
+		run.StartedAt = fromUnixNanos(startedAt)
+		run.FinishedAt = fromUnixNanos(finishedAt)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query backtest runs: %v", err)
+	}
+	reverse(runs)
+	return runs, nil
+}
+
+// SaveDailySummary stores a summary, replacing any summary with the same ID
+func (s *sqlStore) SaveDailySummary(summary *DailySummary) error {
+	err := s.exec(`INSERT INTO daily_summaries
+		(id, run_id, mode, symbol, day, start_time, end_time, trades, wins, losses,
+		 win_rate, pnl, fees, currency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+		 run_id = excluded.run_id, mode = excluded.mode, symbol = excluded.symbol,
+		 day = excluded.day, start_time = excluded.start_time, end_time = excluded.end_time,
+		 trades = excluded.trades, wins = excluded.wins, losses = excluded.losses,
+		 win_rate = excluded.win_rate, pnl = excluded.pnl, fees = excluded.fees,
+		 currency = excluded.currency`,
+		summary.ID, summary.RunID, summary.Mode, strings.ToLower(summary.Symbol), summary.Date,
+		unixNanos(summary.Start), unixNanos(summary.End), summary.Trades, summary.Wins, summary.Losses,
+		summary.WinRate, summary.PnL, summary.Fees, summary.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to save daily summary %s: %v", summary.ID, err)
+	}
+	return nil
+}
+
+// DailySummaries returns the most recent limit summaries (0 means all),
+// oldest first
+func (s *sqlStore) DailySummaries(limit int) ([]DailySummary, error) {
+	var where filters
+	rows, err := s.query(`SELECT id, run_id, mode, symbol, day, start_time, end_time, trades,
+		wins, losses, win_rate, pnl, fees, currency FROM daily_summaries`+
+		where.clause("end_time", limit), where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily summaries: %v", err)
+	}
+	defer rows.Close()
+
+	var summaries []DailySummary
+	for rows.Next() {
+		var summary DailySummary
+		var start, end int64
+		if err := rows.Scan(&summary.ID, &summary.RunID, &summary.Mode, &summary.Symbol, &summary.Date,
+			&start, &end, &summary.Trades, &summary.Wins, &summary.Losses, &summary.WinRate,
+			&summary.PnL, &summary.Fees, &summary.Currency); err != nil {
+			return nil, fmt.Errorf("failed to read daily summary: %v", err)
+		}
+		summary.Start = fromUnixNanos(start)
+		summary.End = fromUnixNanos(end)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query daily summaries: %v", err)
+	}
+	reverse(summaries)
+	return summaries, nil
+}
+
+// Close closes the database
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// filters collects the conditions of a WHERE clause with their arguments
+type filters struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add adds a condition when set
+func (f *filters) add(condition string, arg interface{}, set bool) {
+	if set {
+		f.conditions = append(f.conditions, condition)
+		f.args = append(f.args, arg)
+	}
+}
+
+// clause returns the WHERE clause, ordered newest first by column so the
+// limit keeps the most recent rows; callers reverse the result
+func (f *filters) clause(column string, limit int) string {
+	var clause string
+	if len(f.conditions) > 0 {
+		clause = " WHERE " + strings.Join(f.conditions, " AND ")
+	}
+	clause += fmt.Sprintf(" ORDER BY %s DESC, id DESC", column)
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return clause
+}
+
+// reverse reverses a slice in place
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
+// unixNanos converts a time for storage; the zero time is stored as 0
+func unixNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNanos converts a stored time back
+func fromUnixNanos(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the "sqlite3" driver
+)
+
+// SQLiteStore is a Store backed by a local SQLite database file
+type SQLiteStore struct {
+	sqlStore
+}
+
+// OpenSQLite opens (creating if needed) the SQLite database at path
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trade database: %v", err)
+	}
+	// A single connection serializes writers; SQLite allows only one anyway
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize trade database: %v", err)
+	}
+	return &SQLiteStore{sqlStore{db: db}}, nil
+}
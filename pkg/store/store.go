@@ -0,0 +1,332 @@
+// Package store persists trading history so it can be queried after the
+// session that produced it has ended.
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/events"
+	"TRADE/pkg/types"
+)
+
+// Run modes a trade can be tagged with
+const (
+	ModeLive     = "live"
+	ModePaper    = "paper"
+	ModeBacktest = "backtest"
+)
+
+// Outcome classifies a closed trade by its realized PnL
+type Outcome string
+
+const (
+	// Trade outcomes
+	OutcomeWin  Outcome = "win"
+	OutcomeLoss Outcome = "loss"
+	OutcomeFlat Outcome = "flat"
+)
+
+// ParseOutcome converts "win", "loss", "flat" or "" (any) to an Outcome
+func ParseOutcome(name string) (Outcome, error) {
+	switch outcome := Outcome(name); outcome {
+	case "", OutcomeWin, OutcomeLoss, OutcomeFlat:
+		return outcome, nil
+	default:
+		return "", fmt.Errorf("invalid outcome: %s (want win, loss or flat)", name)
+	}
+}
+
+// Trade is a closed round-trip trade
+type Trade struct {
+	ID            string
+	RunID         string // Session that produced the trade
+	Mode          string // ModeLive, ModePaper or ModeBacktest
+	Symbol        string
+	CorrelationID string
+	EntryPrice    decimal.Decimal
+	ExitPrice     decimal.Decimal
+	Quantity      decimal.Decimal
+	PnL           decimal.Decimal
+	PnLPercent    float64
+	Reason        string
+	EntryTime     time.Time
+	ExitTime      time.Time
+	Tags          []string // Sorted; stored through AnnotationStore
+	Notes         []Note   // Oldest first; stored through AnnotationStore
+}
+
+// Note is a free-text note on a trade, written while reviewing it
+type Note struct {
+	TradeID string
+	Time    time.Time
+	Author  string
+	Text    string
+}
+
+// Outcome classifies the trade by its PnL
+func (t *Trade) Outcome() Outcome {
+	switch t.PnL.Sign() {
+	case 1:
+		return OutcomeWin
+	case -1:
+		return OutcomeLoss
+	default:
+		return OutcomeFlat
+	}
+}
+
+// TradeFromEvent converts a trade-closed event of the given run
+func TradeFromEvent(event *events.TradeClosedEvent, runID, mode string) *Trade {
+	return &Trade{
+		ID:            event.TradeID,
+		RunID:         runID,
+		Mode:          mode,
+		Symbol:        event.Symbol,
+		CorrelationID: event.CorrelationID,
+		EntryPrice:    event.EntryPrice,
+		ExitPrice:     event.ExitPrice,
+		Quantity:      event.Quantity,
+		PnL:           event.PnL,
+		PnLPercent:    event.PnLPercent,
+		Reason:        event.Reason,
+		EntryTime:     event.EntryTime,
+		ExitTime:      event.ExitTime,
+	}
+}
+
+// Event converts the trade back to the event it was recorded from
+func (t *Trade) Event() *events.TradeClosedEvent {
+	return &events.TradeClosedEvent{
+		TradeID:       t.ID,
+		CorrelationID: t.CorrelationID,
+		Symbol:        t.Symbol,
+		EntryPrice:    t.EntryPrice,
+		ExitPrice:     t.ExitPrice,
+		Quantity:      t.Quantity,
+		PnL:           t.PnL,
+		PnLPercent:    t.PnLPercent,
+		Reason:        t.Reason,
+		EntryTime:     t.EntryTime,
+		ExitTime:      t.ExitTime,
+		Tags:          t.Tags,
+	}
+}
+
+// TradeQuery selects trades; zero fields match everything
+type TradeQuery struct {
+	ID      string
+	RunID   string
+	Mode    string
+	Symbol  string
+	Since   time.Time // Closed at or after
+	Until   time.Time // Closed before
+	Outcome Outcome
+	Tags    []string // Tagged with every one of these
+	Limit   int      // Only the most recent Limit trades; 0 means all
+}
+
+// Order is an order sent (or simulated) for a trade
+type Order struct {
+	ID            string
+	RunID         string
+	Mode          string
+	TradeID       string
+	CorrelationID string
+	Symbol        string
+	Side          string
+	Price         decimal.Decimal
+	Quantity      decimal.Decimal
+	Reason        string
+	Time          time.Time
+}
+
+// OrderFromEvent converts an order event of the given run
+func OrderFromEvent(event *events.OrderEvent, runID, mode string) *Order {
+	return &Order{
+		ID:            event.OrderID,
+		RunID:         runID,
+		Mode:          mode,
+		TradeID:       event.TradeID,
+		CorrelationID: event.CorrelationID,
+		Symbol:        event.Symbol,
+		Side:          event.Side,
+		Price:         event.Price,
+		Quantity:      event.Quantity,
+		Reason:        event.Reason,
+		Time:          event.Timestamp,
+	}
+}
+
+// OrderQuery selects orders; zero fields match everything
+type OrderQuery struct {
+	RunID   string
+	TradeID string
+	Symbol  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // Only the most recent Limit orders; 0 means all
+}
+
+// EquitySnapshot is the account equity of a run at a point in time
+type EquitySnapshot struct {
+	RunID       string
+	Mode        string
+	Time        time.Time
+	Equity      float64
+	RealizedPnL float64
+}
+
+// InstanceSnapshot is the latest state of a running instance, shared with
+// the instances trading other symbols for the portfolio view. Amounts are
+// in the reporting currency.
+type InstanceSnapshot struct {
+	RunID     string
+	Mode      string
+	Symbol    string
+	Time      time.Time
+	Equity    float64
+	Direction string  // Of the open position; empty when flat
+	Notional  float64 // Open position marked to market
+	OpenPnL   float64
+	OpenRisk  float64 // Loss if the stop is hit
+}
+
+// BacktestRun summarizes a finished backtest
+type BacktestRun struct {
+	ID           string
+	Dataset      string
+	Version      string // Build that produced the results
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	TotalTrades  int
+	WinRate      float64
+	TotalPnL     float64
+	MaxDrawdown  float64
+	ProfitFactor float64
+}
+
+// DailySummary is the performance of the trades a run closed in one day
+type DailySummary struct {
+	ID       string // Run and date
+	RunID    string
+	Mode     string
+	Symbol   string
+	Date     string // YYYY-MM-DD of the day summarized
+	Start    time.Time
+	End      time.Time
+	Trades   int
+	Wins     int
+	Losses   int
+	WinRate  float64
+	PnL      decimal.Decimal
+	Fees     decimal.Decimal
+	Currency string // Currency of PnL and fees
+}
+
+// TradeStore persists closed trades
+type TradeStore interface {
+	// SaveTrade stores a trade, replacing any trade with the same ID
+	SaveTrade(trade *Trade) error
+	// Trades returns the matching trades with their tags and notes, oldest
+	// first
+	Trades(query TradeQuery) ([]Trade, error)
+}
+
+// AnnotationStore persists the tags and notes of trades. A trade can be
+// annotated while still open; its annotations are returned with it once
+// it is closed.
+type AnnotationStore interface {
+	// Annotations returns the tags and notes of a trade
+	Annotations(tradeID string) (tags []string, notes []Note, err error)
+	// TagTrade tags a trade; tags it already carries are kept once
+	TagTrade(tradeID string, tags ...string) error
+	// UntagTrade removes tags from a trade
+	UntagTrade(tradeID string, tags ...string) error
+	// AddNote stores a note, replacing any note on the trade written at the
+	// same time
+	AddNote(note *Note) error
+}
+
+// OrderStore persists orders
+type OrderStore interface {
+	// SaveOrder stores an order, replacing any order with the same ID
+	SaveOrder(order *Order) error
+	// Orders returns the matching orders, oldest first
+	Orders(query OrderQuery) ([]Order, error)
+}
+
+// EquityStore persists equity snapshots
+type EquityStore interface {
+	SaveEquity(snapshot *EquitySnapshot) error
+	// EquityCurve returns the snapshots of a run, oldest first
+	EquityCurve(runID string) ([]EquitySnapshot, error)
+}
+
+// InstanceStore shares the state of the running instances
+type InstanceStore interface {
+	// SaveInstance stores the snapshot of an instance, replacing its last
+	SaveInstance(snapshot *InstanceSnapshot) error
+	// Instances returns the snapshots of the instances of mode taken since
+	// a time, by symbol
+	Instances(mode string, since time.Time) ([]InstanceSnapshot, error)
+	// RemoveInstance removes the snapshot of a run that stopped
+	RemoveInstance(runID string) error
+}
+
+// BacktestStore persists backtest results
+type BacktestStore interface {
+	// SaveBacktestRun stores a run, replacing any run with the same ID
+	SaveBacktestRun(run *BacktestRun) error
+	// BacktestRuns returns the most recent limit runs (0 means all), oldest first
+	BacktestRuns(limit int) ([]BacktestRun, error)
+}
+
+// SummaryStore persists daily performance summaries
+type SummaryStore interface {
+	// SaveDailySummary stores a summary, replacing any summary with the same ID
+	SaveDailySummary(summary *DailySummary) error
+	// DailySummaries returns the most recent limit summaries (0 means all),
+	// oldest first
+	DailySummaries(limit int) ([]DailySummary, error)
+}
+
+// Store is a database holding the whole trading history
+type Store interface {
+	TradeStore
+	AnnotationStore
+	OrderStore
+	EquityStore
+	InstanceStore
+	BacktestStore
+	SummaryStore
+	Close() error
+}
+
+// ParseTags normalizes tags (see types.NormalizeTag), failing on the first
+// invalid one
+func ParseTags(tags []string) ([]string, error) {
+	parsed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized, ok := types.NormalizeTag(tag)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q: want letters, digits and . _ : / - only", tag)
+		}
+		parsed = append(parsed, normalized)
+	}
+	return parsed, nil
+}
+
+// Open opens a store of the given type: "sqlite" with a database file path
+// or "postgres" with a connection URL
+func Open(kind, source string) (Store, error) {
+	switch kind {
+	case "sqlite":
+		return OpenSQLite(source)
+	case "postgres":
+		return OpenPostgres(source)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", kind)
+	}
+}
@@ -0,0 +1,175 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+// Thresholds that can scale with the volatility regime, named as in
+// DefaultThresholds
+const (
+	ParamTrendStrength      = "trend_strength"      // Entry: minimum trend strength
+	ParamAvgTrendStrength   = "avg_trend_strength"  // Entry: minimum average trend strength
+	ParamProfitTarget       = "profit_target"       // Exit: take-profit as a multiple of the stop distance
+	ParamTrailingDistance   = "trailing_distance"   // Exit: stop distance in ATRs
+	ParamTrailingActivation = "trailing_activation" // Exit: gain in percent that activates the trailing stop
+	ParamTrendExit          = "trend_exit"          // Exit: trend strength below which a profitable trade is closed
+)
+
+// adaptiveParams lists the thresholds Adaptive accepts
+var adaptiveParams = []string{
+	ParamTrendStrength,
+	ParamAvgTrendStrength,
+	ParamProfitTarget,
+	ParamTrailingDistance,
+	ParamTrailingActivation,
+	ParamTrendExit,
+}
+
+// Scaling maps the volatility regime to a multiplier of a threshold: Low
+// applies at the calmest ATR seen in the window, High at the most volatile,
+// and the multiplier is interpolated linearly by the ATR's percentile
+type Scaling struct {
+	Low  float64
+	High float64
+}
+
+// factor returns the multiplier at a percentile between 0 and 1
+func (s Scaling) factor(percentile float64) float64 {
+	return s.Low + (s.High-s.Low)*percentile
+}
+
+// AdaptiveSettings configures volatility-adaptive thresholds
+type AdaptiveSettings struct {
+	SampleInterval time.Duration      // Spacing of the sampled ATRs
+	Window         int                // Number of ATRs the percentile is taken over
+	MinSamples     int                // ATRs needed before thresholds scale
+	Parameters     map[string]Scaling // Scaling per threshold; others stay absolute
+}
+
+// AdaptiveStats describes the current volatility regime
+type AdaptiveStats struct {
+	Samples    int                `json:"samples"`
+	Ready      bool               `json:"ready"`
+	ATR        float64            `json:"atr"`        // Last sampled ATR as a fraction of price
+	Percentile float64            `json:"percentile"` // Its rank among the sampled ATRs (0-1)
+	Factors    map[string]float64 `json:"factors"`    // Current multiplier per threshold
+}
+
+// Adaptive scales strategy thresholds with the percentile of the current
+// ATR among recent ones, so the same configuration asks for more in a
+// volatile market and less in a calm one. It is not safe for concurrent
+// use; the strategy calls it under its own lock.
+type Adaptive struct {
+	settings   AdaptiveSettings
+	atrs       *rolling.Window[float64]
+	bucket     int64 // Interval of the last sample
+	atr        float64
+	percentile float64
+}
+
+// NewAdaptive creates volatility-adaptive thresholds; unknown parameter
+// names are rejected
+func NewAdaptive(settings AdaptiveSettings) (*Adaptive, error) {
+	for name, scaling := range settings.Parameters {
+		if !isAdaptiveParam(name) {
+			return nil, fmt.Errorf("unknown adaptive parameter %q (want one of %s)", name, strings.Join(adaptiveParams, ", "))
+		}
+		if scaling.Low <= 0 || scaling.High <= 0 {
+			return nil, fmt.Errorf("adaptive parameter %s: low and high must be positive", name)
+		}
+	}
+	if settings.SampleInterval <= 0 {
+		settings.SampleInterval = time.Minute
+	}
+	if settings.Window < 2 {
+		settings.Window = 2
+	}
+	if settings.MinSamples < 2 {
+		settings.MinSamples = 2
+	}
+	if settings.MinSamples > settings.Window {
+		settings.MinSamples = settings.Window
+	}
+	return &Adaptive{
+		settings: settings,
+		atrs:     rolling.NewWindow[float64](settings.Window),
+		bucket:   -1,
+	}, nil
+}
+
+// isAdaptiveParam reports whether name is a threshold Adaptive can scale
+func isAdaptiveParam(name string) bool {
+	for _, param := range adaptiveParams {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe samples the ATR, relative to price, once per sample interval
+func (a *Adaptive) Observe(price float64, t time.Time, metrics *types.MarketMetrics) {
+	if metrics == nil || price <= 0 || metrics.ATR <= 0 {
+		return
+	}
+	bucket := t.UnixNano() / int64(a.settings.SampleInterval)
+	if bucket <= a.bucket {
+		return
+	}
+	a.bucket = bucket
+	a.atr = metrics.ATR / price
+	a.atrs.Push(a.atr)
+
+	// Rank of the new ATR among the window, ties counted half
+	below, equal := 0, 0
+	view := a.atrs.View()
+	for i := 0; i < view.Len(); i++ {
+		switch v := view.At(i); {
+		case v < a.atr:
+			below++
+		case v == a.atr:
+			equal++
+		}
+	}
+	if n := view.Len(); n > 1 {
+		a.percentile = (float64(below) + float64(equal-1)/2) / float64(n-1)
+	} else {
+		a.percentile = 0.5
+	}
+}
+
+// Ready reports whether enough ATRs have been sampled for thresholds to
+// scale
+func (a *Adaptive) Ready() bool {
+	return a.atrs.Len() >= a.settings.MinSamples
+}
+
+// Scale returns a threshold scaled for the current regime; thresholds
+// without a scaling, and all thresholds during warm-up, are returned as is
+func (a *Adaptive) Scale(param string, base float64) float64 {
+	scaling, ok := a.settings.Parameters[param]
+	if !ok || !a.Ready() {
+		return base
+	}
+	return base * scaling.factor(a.percentile)
+}
+
+// Stats returns the current regime and multipliers
+func (a *Adaptive) Stats() AdaptiveStats {
+	stats := AdaptiveStats{
+		Samples:    a.atrs.Len(),
+		Ready:      a.Ready(),
+		ATR:        a.atr,
+		Percentile: a.percentile,
+		Factors:    make(map[string]float64, len(a.settings.Parameters)),
+	}
+	for name := range a.settings.Parameters {
+		stats.Factors[name] = a.Scale(name, 1)
+	}
+	return stats
+}
@@ -0,0 +1,342 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/tracing"
+	"TRADE/pkg/types"
+)
+
+// Arbitration selects which of the entry signals of several strategy
+// instances on the same feed is executed
+type Arbitration string
+
+const (
+	// ArbitrationPriority takes the entry of the instance with the highest
+	// priority among those signalling on a tick; ties go to the first
+	// configured
+	ArbitrationPriority Arbitration = "priority"
+	// ArbitrationVoting takes an entry once enough instances signalled
+	// the same direction within the vote window
+	ArbitrationVoting Arbitration = "voting"
+	// ArbitrationFirst takes the entry of the first configured instance
+	// signalling on a tick
+	ArbitrationFirst Arbitration = "first"
+)
+
+// defaultVoteWindow is how long an entry signal counts as a vote unless
+// configured otherwise
+const defaultVoteWindow = time.Minute
+
+// ParseArbitration parses an arbitration mode; empty selects priority
+func ParseArbitration(name string) (Arbitration, error) {
+	switch mode := Arbitration(strings.ToLower(name)); mode {
+	case "":
+		return ArbitrationPriority, nil
+	case ArbitrationPriority, ArbitrationVoting, ArbitrationFirst:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown arbitration mode %q (want priority, voting or first)", name)
+}
+
+// Instance is an engine run by an arbiter, with its priority
+type Instance struct {
+	Engine   *Engine
+	Priority int // Higher wins under priority arbitration
+}
+
+// ArbiterSettings configures how an arbiter decides between entries
+type ArbiterSettings struct {
+	Mode Arbitration
+	// MinVotes is the number of instances that must signal the same
+	// direction for a voting entry; 0 requires a majority
+	MinVotes int
+	// VoteWindow is how long an instance's entry signal counts as its
+	// vote; 0 uses a minute
+	VoteWindow time.Duration
+}
+
+// vote is the direction an instance last signalled an entry in
+type vote struct {
+	direction string
+	time      time.Time
+}
+
+// candidate is an entry signal waiting for arbitration
+type candidate struct {
+	index  int
+	signal *types.Signal
+}
+
+// Arbiter runs several strategy engines on the same feed and trades one
+// position: while flat it executes the entry its arbitration selects and
+// cancels the others, and while a position is open only the exits and
+// stop moves of the engine that opened it
+type Arbiter struct {
+	instances  []Instance
+	mode       Arbitration
+	minVotes   int
+	voteWindow time.Duration
+	votes      map[int]vote // Votes of the instances, by index
+	owner      int          // Index of the instance holding the position, -1 while flat
+	logger     logger.Interface
+	mutex      sync.Mutex
+}
+
+// NewArbiter creates an arbiter of the instances, which need distinct
+// names
+func NewArbiter(instances []Instance, settings ArbiterSettings, log logger.Interface) (*Arbiter, error) {
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no strategy instances")
+	}
+	names := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		name := instance.Engine.Instance()
+		if names[name] {
+			return nil, fmt.Errorf("strategy instance %q configured twice", name)
+		}
+		names[name] = true
+	}
+	mode, err := ParseArbitration(string(settings.Mode))
+	if err != nil {
+		return nil, err
+	}
+	minVotes := settings.MinVotes
+	if minVotes == 0 {
+		minVotes = len(instances)/2 + 1
+	}
+	if minVotes < 0 || minVotes > len(instances) {
+		return nil, fmt.Errorf("arbitration min votes must be between 1 and the %d instances", len(instances))
+	}
+	window := settings.VoteWindow
+	if window == 0 {
+		window = defaultVoteWindow
+	}
+	if window < 0 {
+		return nil, fmt.Errorf("arbitration vote window cannot be negative")
+	}
+	return &Arbiter{
+		instances:  instances,
+		mode:       mode,
+		minVotes:   minVotes,
+		voteWindow: window,
+		votes:      make(map[int]vote),
+		owner:      -1,
+		logger:     log,
+	}, nil
+}
+
+// Mode returns the arbitration mode
+func (a *Arbiter) Mode() Arbitration {
+	return a.mode
+}
+
+// Engines returns the engines run, in configured order
+func (a *Arbiter) Engines() []*Engine {
+	engines := make([]*Engine, len(a.instances))
+	for i, instance := range a.instances {
+		engines[i] = instance.Engine
+	}
+	return engines
+}
+
+// GenerateSignal runs every engine on a tick and returns the signal to
+// execute: the selected entry while flat, the exit or stop move of the
+// open position otherwise. Each engine runs in a span under the trace
+// span of ctx, if traced.
+func (a *Arbiter) GenerateSignal(ctx context.Context, price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// The position may have been closed, forced out or its entry rejected
+	if a.owner >= 0 && !a.instances[a.owner].Engine.IsActiveTrade() {
+		a.owner = -1
+	}
+	holding := a.owner >= 0
+
+	var result *types.Signal
+	var entries []candidate
+	for i, instance := range a.instances {
+		span := tracing.SpanFromContext(ctx).Child("strategy", tracing.String("strategy", instance.Engine.Instance()))
+		signal := instance.Engine.GenerateSignal(tracing.ContextWithSpan(ctx, span), price, timestamp, metrics)
+		if signal != nil {
+			span.SetAttributes(tracing.String("signal", signal.Action))
+		}
+		span.End()
+		switch {
+		case signal == nil:
+		case i == a.owner:
+			result = signal
+		case !signal.IsEntry():
+		case holding:
+			instance.Engine.CancelEntry(signal.TradeID)
+		default:
+			entries = append(entries, candidate{index: i, signal: signal})
+		}
+	}
+	if len(entries) == 0 {
+		return result
+	}
+
+	winner := a.arbitrate(entries, timestamp)
+	for _, entry := range entries {
+		if entry.index == winner {
+			result = entry.signal
+			continue
+		}
+		a.instances[entry.index].Engine.CancelEntry(entry.signal.TradeID)
+	}
+	if winner < 0 {
+		return nil
+	}
+	a.owner = winner
+	clear(a.votes)
+	if len(a.instances) > 1 {
+		a.logger.Info(fmt.Sprintf("Entry of %s selected by %s arbitration out of %d signal(s)",
+			result.Strategy, a.mode, len(entries)),
+			logger.TradeIDKey, result.TradeID, logger.CorrelationIDKey, result.CorrelationID)
+	}
+	return result
+}
+
+// arbitrate returns the index of the instance whose entry is taken, or -1
+func (a *Arbiter) arbitrate(entries []candidate, timestamp time.Time) int {
+	switch a.mode {
+	case ArbitrationFirst:
+		return entries[0].index
+	case ArbitrationVoting:
+		for _, entry := range entries {
+			a.votes[entry.index] = vote{direction: entry.signal.Direction, time: timestamp}
+		}
+		winner := -1
+		for _, entry := range entries {
+			if a.count(entry.signal.Direction, timestamp) >= a.minVotes && a.outranks(entry.index, winner) {
+				winner = entry.index
+			}
+		}
+		return winner
+	default:
+		winner := -1
+		for _, entry := range entries {
+			if a.outranks(entry.index, winner) {
+				winner = entry.index
+			}
+		}
+		return winner
+	}
+}
+
+// outranks returns whether instance i has a higher priority than j; any
+// instance outranks none (-1)
+func (a *Arbiter) outranks(i, j int) bool {
+	return j < 0 || a.instances[i].Priority > a.instances[j].Priority
+}
+
+// count returns the number of current votes for direction, dropping the
+// expired ones
+func (a *Arbiter) count(direction string, timestamp time.Time) int {
+	votes := 0
+	for index, vote := range a.votes {
+		if timestamp.Sub(vote.time) > a.voteWindow {
+			delete(a.votes, index)
+			continue
+		}
+		if vote.direction == direction {
+			votes++
+		}
+	}
+	return votes
+}
+
+// active returns the engine with an active trade, or nil
+func (a *Arbiter) active() *Engine {
+	for _, instance := range a.instances {
+		if instance.Engine.IsActiveTrade() {
+			return instance.Engine
+		}
+	}
+	return nil
+}
+
+// IsActiveTrade returns whether a position is open
+func (a *Arbiter) IsActiveTrade() bool {
+	return a.active() != nil
+}
+
+// GetActiveTradeData returns data about the open position's trade
+func (a *Arbiter) GetActiveTradeData() *types.TradeData {
+	if engine := a.active(); engine != nil {
+		return engine.GetActiveTradeData()
+	}
+	return a.instances[0].Engine.GetActiveTradeData()
+}
+
+// ForceExit closes the open position's trade at price outside the exit
+// rules and returns its close signal, or nil while flat
+func (a *Arbiter) ForceExit(price float64, timestamp time.Time, reason string) *types.Signal {
+	if engine := a.active(); engine != nil {
+		return engine.ForceExit(price, timestamp, reason)
+	}
+	return nil
+}
+
+// CancelEntry drops the trade opened by an entry signal that was not
+// executed
+func (a *Arbiter) CancelEntry(tradeID string) {
+	for _, instance := range a.instances {
+		instance.Engine.CancelEntry(tradeID)
+	}
+}
+
+// KeepTrade resumes managing a trade whose exit left part of the position
+// unfilled, by the instance that exited it
+func (a *Arbiter) KeepTrade(tradeID string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for i, instance := range a.instances {
+		if instance.Engine.KeepTrade(tradeID) {
+			a.owner = i
+			return
+		}
+	}
+}
+
+// RestoreTrade resumes exit management for a trade opened by a previous
+// run, by the instance that opened it or, if it is no longer configured,
+// the first
+func (a *Arbiter) RestoreTrade(trade *types.TradeData) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.owner = 0
+	for i, instance := range a.instances {
+		if instance.Engine.Instance() == trade.Strategy {
+			a.owner = i
+			break
+		}
+	}
+	a.instances[a.owner].Engine.RestoreTrade(trade)
+}
+
+// AdaptiveStats returns the volatility regime the thresholds are scaled
+// for, which all engines measure on the same feed
+func (a *Arbiter) AdaptiveStats() (stats AdaptiveStats, ok bool) {
+	return a.instances[0].Engine.AdaptiveStats()
+}
+
+// Close releases the resources of the engines' strategies
+func (a *Arbiter) Close() error {
+	var first error
+	for _, instance := range a.instances {
+		if err := instance.Engine.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,24 @@
+package strategy_test
+
+import (
+	"context"
+	"testing"
+
+	"TRADE/pkg/bench"
+	"TRADE/pkg/events"
+)
+
+// BenchmarkGenerateSignal measures checking the metrics of the history for
+// a signal
+func BenchmarkGenerateSignal(b *testing.B) {
+	ticks := bench.Ticks(2*bench.HistorySize, 1)
+	metrics := bench.NewAnalyzer(bench.NewMarket(events.NewBus(), ticks[:bench.HistorySize])).GetMetrics()
+	engine := bench.NewEngine(nil)
+	replay := ticks[bench.HistorySize:]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tick := &replay[i%len(replay)]
+		engine.GenerateSignal(context.Background(), tick.Price, tick.Timestamp, metrics)
+	}
+}
@@ -0,0 +1,75 @@
+package strategy
+
+import (
+	"context"
+	"math"
+
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+func init() {
+	Register("breakout", func(engine *Engine) Strategy { return &Breakout{engine: engine} })
+}
+
+// Breakout enters when the price leaves its range over the last
+// breakout_window ticks by more than breakout_margin, with the order
+// imbalance behind the move. It exits when the price has moved the trade's
+// initial risk (1.5 ATR) against it, at profit_target times that risk, or
+// once it gives back trailing_distance ATR from its best price in profit.
+type Breakout struct {
+	engine *Engine
+	prices *rolling.Stats
+}
+
+// GenerateSignal returns an entry signal on a breakout of the range, and a
+// close signal when the active trade is stopped, at its target or trailed
+func (b *Breakout) GenerateSignal(ctx context.Context, state types.MarketState) *types.Signal {
+	e := b.engine
+	thresholds := e.thresholds
+	price, timestamp, metrics := state.CurrentPrice, state.Timestamp, state.Metrics
+
+	// The range is that of the ticks before this one
+	window := int(thresholds["breakout_window"])
+	if b.prices == nil || b.prices.Cap() != window {
+		b.prices = rolling.NewStats(window)
+	}
+	full := b.prices.Len() == window
+	high, low := b.prices.Max(), b.prices.Min()
+	b.prices.Push(price)
+
+	if trade := state.ActiveTrade; trade != nil {
+		direction := 1.0
+		if trade.Short() {
+			direction = -1
+		}
+		move := direction * (price - trade.EntryPrice)
+		atr := math.Max(metrics.ATR, price*minRiskFraction)
+		reason := ""
+		switch {
+		case trade.InitialRisk > 0 && move <= -trade.InitialRisk:
+			reason = "stop_loss"
+		case trade.InitialRisk > 0 && move >= e.threshold(ParamProfitTarget)*trade.InitialRisk:
+			reason = "take_profit"
+		case move > 0 && direction*(trade.BestPrice()-price) >= e.threshold(ParamTrailingDistance)*atr:
+			reason = "trailing_stop"
+		default:
+			return nil
+		}
+		return types.NewSellSignal(price, timestamp, reason, trade.Return(price)*100, trade.StopLoss)
+	}
+
+	if !full ||
+		metrics.RealizedVolatility > thresholds["realized_volatility_hi"] ||
+		metrics.RealizedVolatility < thresholds["realized_volatility_lo"] {
+		return nil
+	}
+	margin := thresholds["breakout_margin"]
+	switch {
+	case price > high*(1+margin) && e.imbalanceCondition(metrics):
+		return types.NewBuySignal(price, timestamp, metrics)
+	case price < low*(1-margin) && e.shortImbalanceCondition(metrics):
+		return types.NewShortSignal(price, timestamp, metrics)
+	}
+	return nil
+}
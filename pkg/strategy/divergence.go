@@ -0,0 +1,49 @@
+package strategy
+
+import "TRADE/pkg/types"
+
+// DivergenceRule uses a bearish divergence, the relative strength or the
+// volume delta not confirming a new high of the price, as a sign the move
+// up is running out, and a bullish divergence for the move down
+type DivergenceRule struct {
+	// EntryFilter skips long entries while a bearish divergence is
+	// reported, and short entries while a bullish one is
+	EntryFilter bool
+	// Exit closes a long trade on a bearish divergence, and a short trade
+	// on a bullish one, once it has made the minimum profit (min_profit),
+	// like the trend reversal exit
+	Exit bool
+}
+
+// SetDivergence applies the divergences the analyzer reports to entries
+// and exits; nil ignores them
+func (e *Engine) SetDivergence(rule *DivergenceRule) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.divergence = rule
+}
+
+// divergenceCondition returns whether the divergence filter lets an
+// entry through: a long one without a bearish divergence, a short one
+// without a bullish divergence
+func (e *Engine) divergenceCondition(metrics *types.MarketMetrics, short bool) bool {
+	if e.divergence == nil || !e.divergence.EntryFilter {
+		return true
+	}
+	if short {
+		return !metrics.BullishDivergence()
+	}
+	return !metrics.BearishDivergence()
+}
+
+// divergenceExit returns whether a trade in profit should exit on a
+// divergence against its direction
+func (e *Engine) divergenceExit(metrics *types.MarketMetrics, profit float64, short bool) bool {
+	if e.divergence == nil || !e.divergence.Exit || profit < e.thresholds["min_profit"]/100 {
+		return false
+	}
+	if short {
+		return metrics.BullishDivergence()
+	}
+	return metrics.BearishDivergence()
+}
@@ -0,0 +1,396 @@
+package strategy
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/analyzer"
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/ids"
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+// Engine runs a Strategy: it keeps the active trade, opens it on the
+// strategy's entry signals, sizes them and manages the trade's stops,
+// and closes it on the strategy's exit signals or at its stop
+type Engine struct {
+	analyzer    *analyzer.Analyzer
+	logger      logger.Interface
+	strategy    Strategy
+	name        string // Registered name of strategy
+	instance    string // Name of the instance in signals and trades; defaults to name
+	activeTrade *types.TradeData
+	instrument  *types.Instrument // Rounds stops to exchange ticks when set
+	stops       *StopManager
+	adaptive    *Adaptive // Scales thresholds with volatility when set
+	thresholds  map[string]float64
+	imbalance   ImbalanceSource // Order imbalance the entry condition uses
+	divergence  *DivergenceRule // Filters entries and exits on divergences when set
+	timeframes  *TimeframeRule  // Requires higher timeframes to confirm entries when set
+	patterns    *PatternRule    // Requires candlestick patterns to confirm entries when set
+	sizing      *Sizing         // Sizes entry signals when set
+	shorts      bool            // Take the strategy's short entries
+	mutex       sync.RWMutex
+}
+
+// NewEngine creates an engine running the strategy registered under name;
+// empty selects DefaultStrategy
+func NewEngine(name string, analyzer *analyzer.Analyzer, log logger.Interface) (*Engine, error) {
+	factory, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = DefaultStrategy
+	}
+	e := &Engine{
+		analyzer:    analyzer,
+		logger:      log,
+		name:        strings.ToLower(name),
+		instance:    strings.ToLower(name),
+		activeTrade: types.NewTradeData(),
+		stops:       NewStopManager(),
+		thresholds:  DefaultThresholds(),
+		imbalance:   ImbalanceTradeFlow,
+	}
+	e.strategy = factory(e)
+	return e, nil
+}
+
+// Name returns the registered name of the strategy the engine runs
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// Instance returns the name the engine's signals and trades carry
+func (e *Engine) Instance() string {
+	return e.instance
+}
+
+// SetInstance names the engine's strategy instance, telling apart the
+// signals and trades of several engines on the same feed
+func (e *Engine) SetInstance(name string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.instance = name
+}
+
+// SetInstrument sets the instrument whose tick size stops are rounded to
+func (e *Engine) SetInstrument(instrument *types.Instrument) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.instrument = instrument
+}
+
+// SetBreakEven enables moving the stop to entry once a trade reaches the
+// rule's profit multiple; nil disables it
+func (e *Engine) SetBreakEven(rule *BreakEvenRule) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.stops.SetBreakEven(rule)
+}
+
+// SetShorts enables short entries, taken when the long entry conditions
+// hold mirrored for a falling market
+func (e *Engine) SetShorts(enabled bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.shorts = enabled
+}
+
+// SetAdaptive makes thresholds scale with the volatility regime; nil
+// restores the absolute thresholds
+func (e *Engine) SetAdaptive(adaptive *Adaptive) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.adaptive = adaptive
+}
+
+// AdaptiveStats returns the volatility regime the thresholds are scaled
+// for; ok is false when thresholds are absolute
+func (e *Engine) AdaptiveStats() (stats AdaptiveStats, ok bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	if e.adaptive == nil {
+		return stats, false
+	}
+	return e.adaptive.Stats(), true
+}
+
+// threshold returns a configured threshold, scaled for the volatility
+// regime when adaptive
+func (e *Engine) threshold(param string) float64 {
+	base := e.thresholds[param]
+	if e.adaptive == nil {
+		return base
+	}
+	return e.adaptive.Scale(param, base)
+}
+
+// roundPrice rounds a price to the instrument's tick size
+func (e *Engine) roundPrice(price float64) float64 {
+	if e.instrument == nil {
+		return price
+	}
+	return e.instrument.RoundPrice(decimal.FromFloat(price)).Float64()
+}
+
+// GenerateSignal asks the strategy for the signal of a tick: an entry
+// while no trade is active, an exit or a stop move of the active trade
+// otherwise. ctx is passed to the strategy, with the trace span of the
+// tick if traced.
+func (e *Engine) GenerateSignal(ctx context.Context, price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.adaptive != nil {
+		e.adaptive.Observe(price, timestamp, metrics)
+	}
+
+	// Check if we have an active trade
+	if e.activeTrade.Active {
+		return e.checkExitConditions(ctx, price, timestamp, metrics)
+	} else {
+		return e.checkEntryConditions(ctx, price, timestamp, metrics)
+	}
+}
+
+// state returns the market state the strategy decides on; its active
+// trade is nil while no trade is active
+func (e *Engine) state(price float64, timestamp time.Time, metrics *types.MarketMetrics) types.MarketState {
+	state := types.MarketState{Timestamp: timestamp, CurrentPrice: price, Metrics: metrics}
+	if e.activeTrade.Active {
+		state.ActiveTrade = e.activeTrade
+	}
+	return state
+}
+
+// checkEntryConditions opens a trade on the strategy's entry signal
+func (e *Engine) checkEntryConditions(ctx context.Context, price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
+	signal := e.strategy.GenerateSignal(ctx, e.state(price, timestamp, metrics))
+	if signal == nil || !signal.IsEntry() {
+		return nil
+	}
+	if signal.Short() && !e.shorts {
+		return nil
+	}
+	if !e.divergenceCondition(metrics, signal.Short()) || !e.timeframeCondition(metrics, signal.Short()) ||
+		!e.patternCondition(metrics, signal.Short()) {
+		return nil
+	}
+
+	// Create active trade
+	direction, message := types.DirectionLong, "Buy conditions met"
+	if signal.Short() {
+		direction, message = types.DirectionShort, "Short conditions met"
+	}
+	e.activeTrade.ID = ids.Trade()
+	e.activeTrade.Active = true
+	e.activeTrade.Direction = direction
+	e.activeTrade.Strategy = e.instance
+	e.activeTrade.EntryPrice = price
+	e.activeTrade.EntryTime = timestamp
+	e.activeTrade.HighestPrice = price
+	e.activeTrade.LowestPrice = price
+	e.activeTrade.MFE = 0
+	e.activeTrade.MAE = 0
+	e.activeTrade.Tags = e.entryTags(signal, metrics)
+	e.stops.Open(e.activeTrade, price, metrics)
+
+	// Complete the entry signal
+	signal.TradeID = e.activeTrade.ID
+	signal.Direction = direction
+	signal.Strategy = e.instance
+	signal.InitialRisk = e.activeTrade.InitialRisk
+	signal.Quantity = e.quantity(price, signal.InitialRisk)
+	signal.Tags = e.activeTrade.Tags
+	e.logger.Info(message,
+		logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+	return signal
+}
+
+// checkExitConditions closes the active trade on the strategy's exit
+// signal, a divergence or its stop, or moves its stop
+func (e *Engine) checkExitConditions(ctx context.Context, price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
+	// Update highest and lowest prices and the excursions
+	e.activeTrade.Track(price)
+
+	signal := e.strategy.GenerateSignal(ctx, e.state(price, timestamp, metrics))
+	if signal != nil && signal.Action != "CLOSE" {
+		signal = nil
+	}
+	profit := e.activeTrade.Return(price)
+	if signal == nil && e.divergenceExit(metrics, profit, e.activeTrade.Short()) {
+		signal = types.NewSellSignal(price, timestamp, "divergence", profit*100, e.activeTrade.StopLoss)
+	}
+
+	// A stop set on the trade takes precedence over the other exits
+	if e.stops.Triggered(e.activeTrade, price) {
+		signal = types.NewSellSignal(price, timestamp, e.stops.Reason(e.activeTrade), profit*100, e.activeTrade.StopLoss)
+	}
+
+	if signal != nil {
+		signal.UpdatedStopLoss = e.roundPrice(signal.UpdatedStopLoss)
+		e.exitSignal(signal)
+		e.logger.Info("Sell conditions met: "+signal.Reason,
+			logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+
+		// Reset active trade
+		e.activeTrade.Active = false
+
+		return signal
+	}
+
+	// Move the stop once the trade is far enough in profit
+	if stop := e.stops.Update(e.activeTrade, price, e.roundPrice); stop > 0 {
+		signal := e.tradeSignal(types.NewStopSignal(price, timestamp, "break_even", stop))
+		e.logger.Info("Stop moved to break-even", "stop_loss", stop,
+			logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+		return signal
+	}
+
+	return nil
+}
+
+// tradeSignal ties a signal to the active trade, its direction and the
+// instance that opened it
+func (e *Engine) tradeSignal(signal *types.Signal) *types.Signal {
+	signal.TradeID = e.activeTrade.ID
+	signal.Direction = e.activeTrade.Direction
+	signal.Strategy = e.activeTrade.Strategy
+	if e.activeTrade.Short() {
+		signal.Side = "buy" // Shorts are closed and stopped by buying back
+	}
+	return signal
+}
+
+// exitSignal ties a close signal to the active trade, with its excursions
+// and tags
+func (e *Engine) exitSignal(signal *types.Signal) *types.Signal {
+	e.tradeSignal(signal)
+	if signal.Side == "" {
+		signal.Side = "sell"
+	}
+	signal.MFE, signal.MAE = e.activeTrade.MFE, e.activeTrade.MAE
+	signal.Tags = e.activeTrade.Tags
+	return signal
+}
+
+// IsActiveTrade returns whether there is an active trade
+func (e *Engine) IsActiveTrade() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.activeTrade.Active
+}
+
+// GetActiveTradeData returns data about the active trade
+func (e *Engine) GetActiveTradeData() *types.TradeData {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	// Create a copy of the active trade data
+	tradeCopy := &types.TradeData{
+		ID:           e.activeTrade.ID,
+		Active:       e.activeTrade.Active,
+		Direction:    e.activeTrade.Direction,
+		Strategy:     e.activeTrade.Strategy,
+		EntryPrice:   e.activeTrade.EntryPrice,
+		EntryTime:    e.activeTrade.EntryTime,
+		HighestPrice: e.activeTrade.HighestPrice,
+		LowestPrice:  e.activeTrade.LowestPrice,
+		StopLoss:     e.activeTrade.StopLoss,
+		InitialRisk:  e.activeTrade.InitialRisk,
+		BreakEven:    e.activeTrade.BreakEven,
+		MFE:          e.activeTrade.MFE,
+		MAE:          e.activeTrade.MAE,
+		Tags:         append([]string(nil), e.activeTrade.Tags...),
+	}
+
+	// Calculate current PnL if active
+	if tradeCopy.Active {
+		currentPrice := e.activeTrade.BestPrice() // Use the best price as a proxy for current price
+		tradeCopy.CurrentPnL = e.activeTrade.Return(currentPrice) * 100
+	}
+
+	return tradeCopy
+}
+
+// RestoreTrade resumes exit management for a trade opened by a previous run
+func (e *Engine) RestoreTrade(trade *types.TradeData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	restored := *trade
+	restored.Active = true
+	if restored.Strategy == "" {
+		restored.Strategy = e.instance
+	}
+	e.activeTrade = &restored
+	e.logger.Info("Restored active trade from previous session", logger.TradeIDKey, restored.ID)
+}
+
+// CancelEntry drops the trade opened by an entry signal that was not
+// executed, so the strategy looks for a new entry instead of managing it
+func (e *Engine) CancelEntry(tradeID string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.activeTrade.Active && e.activeTrade.ID == tradeID {
+		e.activeTrade = types.NewTradeData()
+	}
+}
+
+// KeepTrade resumes managing a trade whose exit left part of the position
+// unfilled, so the rest is exited again. It reports whether the trade was
+// the last one exited.
+func (e *Engine) KeepTrade(tradeID string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.activeTrade.Active || e.activeTrade.ID != tradeID {
+		return false
+	}
+	e.activeTrade.Active = true
+	return true
+}
+
+// ForceExit closes the active trade at price outside the exit rules and
+// returns its close signal, or nil without an active trade
+func (e *Engine) ForceExit(price float64, timestamp time.Time, reason string) *types.Signal {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.activeTrade.Active {
+		return nil
+	}
+	e.activeTrade.Track(price)
+	profit := e.activeTrade.Return(price)
+	signal := e.exitSignal(types.NewSellSignal(price, timestamp, reason, profit*100, e.roundPrice(e.activeTrade.StopLoss)))
+	e.logger.Info("Forced exit: "+reason,
+		logger.TradeIDKey, signal.TradeID, logger.CorrelationIDKey, signal.CorrelationID)
+
+	e.activeTrade.Active = false
+	return signal
+}
+
+// Close releases the strategy's resources, such as a strategy process
+func (e *Engine) Close() error {
+	if closer, ok := e.strategy.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// UpdateStopLoss updates the stop loss level for the active trade
+func (e *Engine) UpdateStopLoss(newStopLoss float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.activeTrade.Active && newStopLoss > 0 {
+		e.activeTrade.StopLoss = e.roundPrice(newStopLoss)
+	}
+}
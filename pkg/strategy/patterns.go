@@ -0,0 +1,45 @@
+package strategy
+
+import "TRADE/pkg/types"
+
+// PatternRule uses the candlestick patterns the analyzer reports on the
+// last closed candles to confirm entries
+type PatternRule struct {
+	// Confirm requires a pattern pointing in an entry's direction: a
+	// bullish one for a long entry, a bearish one for a short one
+	Confirm bool
+	// Veto skips entries while a pattern points against them
+	Veto bool
+}
+
+// SetPatterns applies the candlestick patterns the analyzer reports to
+// entries; nil ignores them
+func (e *Engine) SetPatterns(rule *PatternRule) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.patterns = rule
+}
+
+// patternCondition returns whether the reported patterns let an entry
+// through
+func (e *Engine) patternCondition(metrics *types.MarketMetrics, short bool) bool {
+	if e.patterns == nil {
+		return true
+	}
+	bias := 1
+	if short {
+		bias = -1
+	}
+	confirmed := false
+	for _, pattern := range metrics.Patterns {
+		switch pattern.Bias {
+		case bias:
+			confirmed = true
+		case -bias:
+			if e.patterns.Veto {
+				return false
+			}
+		}
+	}
+	return confirmed || !e.patterns.Confirm
+}
@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginSettings describes an external strategy: a Go plugin or a
+// subprocess speaking the process protocol
+type PluginSettings struct {
+	// Path is a Go plugin (.so) built with this module's version, whose
+	// init functions register strategies with Register
+	Path string
+	// Name registers a subprocess strategy run by Command with Args
+	Name    string
+	Command string
+	Args    []string
+	// Timeout bounds the wait for the process's answer to a tick; 0
+	// uses defaultProcessTimeout
+	Timeout time.Duration
+}
+
+// loaded holds the plugins loaded, by path or name, and the strategies
+// each registered
+var (
+	loaded      = map[string][]string{}
+	loadedMutex sync.Mutex
+)
+
+// LoadPlugin makes the strategies of an external plugin selectable by
+// name and returns their names. Loading a plugin again returns the names
+// it registered the first time.
+func LoadPlugin(settings PluginSettings) ([]string, error) {
+	loadedMutex.Lock()
+	defer loadedMutex.Unlock()
+
+	switch {
+	case settings.Path != "" && settings.Command != "":
+		return nil, fmt.Errorf("a plugin is either a Go plugin (path) or a process (command), not both")
+	case settings.Path != "":
+		return loadGoPlugin(settings.Path)
+	case settings.Command != "":
+		return registerProcess(settings)
+	}
+	return nil, fmt.Errorf("a plugin needs a path or a command")
+}
+
+// loadGoPlugin opens a Go plugin, whose init functions register its
+// strategies
+func loadGoPlugin(path string) ([]string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if names, ok := loaded[path]; ok {
+		return names, nil
+	}
+
+	before := make(map[string]bool)
+	for _, name := range Names() {
+		before[name] = true
+	}
+	if _, err := plugin.Open(path); err != nil {
+		return nil, fmt.Errorf("failed to load plugin %s: %v", path, err)
+	}
+	var names []string
+	for _, name := range Names() {
+		if !before[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("plugin %s registered no strategy", path)
+	}
+	loaded[path] = names
+	return names, nil
+}
+
+// registerProcess registers a subprocess strategy under its name
+func registerProcess(settings PluginSettings) ([]string, error) {
+	if settings.Name == "" {
+		return nil, fmt.Errorf("process plugin %s needs a name", settings.Command)
+	}
+	if settings.Timeout < 0 {
+		return nil, fmt.Errorf("process plugin %s: timeout cannot be negative", settings.Name)
+	}
+	name := strings.ToLower(settings.Name)
+	key := "process:" + name
+	if names, ok := loaded[key]; ok {
+		return names, nil
+	}
+	if _, err := Lookup(name); err == nil {
+		return nil, fmt.Errorf("strategy %q is already registered", name)
+	}
+
+	Register(name, func(engine *Engine) Strategy { return newProcess(engine, settings) })
+	loaded[key] = []string{name}
+	return loaded[key], nil
+}
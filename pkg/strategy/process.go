@@ -0,0 +1,273 @@
+package strategy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"TRADE/pkg/tracing"
+	"TRADE/pkg/types"
+)
+
+// defaultProcessTimeout bounds the wait for a process strategy's answer
+// unless configured otherwise
+const defaultProcessTimeout = time.Second
+
+// processRestartDelay is the minimum time between starts of a process
+// strategy that exited
+const processRestartDelay = 5 * time.Second
+
+// processTimeoutLogEvery is how many timeouts are counted between warnings
+const processTimeoutLogEvery = 100
+
+// processStart is the first line written to a process strategy: the
+// instance it runs for and its thresholds
+type processStart struct {
+	Type       string             `json:"type"` // "start"
+	Strategy   string             `json:"strategy"`
+	Thresholds map[string]float64 `json:"thresholds"`
+	Shorts     bool               `json:"shorts"` // Short entries are taken
+}
+
+// processTick is written to a process strategy for each tick, which it
+// answers with a processSignal of the same id
+type processTick struct {
+	Type  string            `json:"type"` // "tick"
+	ID    uint64            `json:"id"`
+	State types.MarketState `json:"state"`
+	// Traceparent is the W3C trace context of the tick, if traced, for
+	// the process's own spans
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// processSignal is a process strategy's answer to a tick. Action is BUY
+// or SHORT while no trade is active, CLOSE while one is, or empty for no
+// signal.
+type processSignal struct {
+	ID       uint64   `json:"id"`
+	Action   string   `json:"action"`
+	Reason   string   `json:"reason"`
+	StopLoss float64  `json:"stop_loss"` // Stop level reported with a CLOSE
+	Tags     []string `json:"tags"`      // Tags of the trade a BUY or SHORT opens
+}
+
+// Process is a strategy run by an external process over the process
+// protocol: newline-delimited JSON on its standard input and output. The
+// process receives a start line, then a tick line per tick with the
+// market state, and answers each tick with a signal line. Its standard
+// error is logged. A process that exits is restarted on a later tick;
+// ticks it does not answer in time have no signal.
+type Process struct {
+	engine   *Engine
+	settings PluginSettings
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	encoder  *json.Encoder
+	answers  chan processSignal
+	exited   chan struct{} // Closed once the running process exited
+	started  time.Time
+	nextID   uint64
+	timeouts int
+	mutex    sync.Mutex
+}
+
+// newProcess creates a process strategy for engine; the process starts on
+// the first tick
+func newProcess(engine *Engine, settings PluginSettings) *Process {
+	if settings.Timeout == 0 {
+		settings.Timeout = defaultProcessTimeout
+	}
+	return &Process{engine: engine, settings: settings}
+}
+
+// GenerateSignal sends the tick's market state to the process and returns
+// the signal it answers
+func (p *Process) GenerateSignal(ctx context.Context, state types.MarketState) *types.Signal {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.running() {
+		if err := p.start(); err != nil {
+			return nil
+		}
+	}
+	p.nextID++
+	id := p.nextID
+	tick := processTick{Type: "tick", ID: id, State: state, Traceparent: tracing.SpanFromContext(ctx).Traceparent()}
+	if state.Metrics != nil {
+		tick.State.Metrics = state.Metrics.Finite()
+	}
+	if err := p.encoder.Encode(tick); err != nil {
+		p.engine.logger.Error(fmt.Sprintf("Failed to send tick to strategy process %s: %v", p.settings.Name, err))
+		p.stop()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.settings.Timeout)
+	defer cancel()
+	for {
+		select {
+		case answer := <-p.answers:
+			if answer.ID != id {
+				continue // A late answer to an earlier tick
+			}
+			return p.signal(answer, state)
+		case <-p.exited:
+			return nil
+		case <-ctx.Done():
+			if p.timeouts%processTimeoutLogEvery == 0 {
+				p.engine.logger.Warning(fmt.Sprintf("Strategy process %s did not answer within %s (%d timeout(s))",
+					p.settings.Name, p.settings.Timeout, p.timeouts+1))
+			}
+			p.timeouts++
+			return nil
+		}
+	}
+}
+
+// signal converts the process's answer into a signal
+func (p *Process) signal(answer processSignal, state types.MarketState) *types.Signal {
+	price, timestamp := state.CurrentPrice, state.Timestamp
+	switch strings.ToUpper(answer.Action) {
+	case "":
+		return nil
+	case "BUY":
+		signal := types.NewBuySignal(price, timestamp, state.Metrics)
+		signal.Tags = answer.Tags
+		return signal
+	case "SHORT":
+		signal := types.NewShortSignal(price, timestamp, state.Metrics)
+		signal.Tags = answer.Tags
+		return signal
+	case "CLOSE":
+		if state.ActiveTrade == nil {
+			return nil
+		}
+		reason := answer.Reason
+		if reason == "" {
+			reason = p.settings.Name
+		}
+		return types.NewSellSignal(price, timestamp, reason, state.ActiveTrade.Return(price)*100, answer.StopLoss)
+	}
+	p.engine.logger.Warning(fmt.Sprintf("Strategy process %s answered unknown action %q", p.settings.Name, answer.Action))
+	return nil
+}
+
+// running returns whether the process is running
+func (p *Process) running() bool {
+	if p.cmd == nil {
+		return false
+	}
+	select {
+	case <-p.exited:
+		p.cmd = nil
+		return false
+	default:
+		return true
+	}
+}
+
+// start starts the process and sends it the start line, at most once per
+// processRestartDelay
+func (p *Process) start() error {
+	if !p.started.IsZero() && time.Since(p.started) < processRestartDelay {
+		return fmt.Errorf("restart delayed")
+	}
+	p.started = time.Now()
+
+	cmd := exec.Command(p.settings.Command, p.settings.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		var stdout, stderr io.ReadCloser
+		if stdout, err = cmd.StdoutPipe(); err == nil {
+			if stderr, err = cmd.StderrPipe(); err == nil {
+				if err = cmd.Start(); err == nil {
+					p.cmd, p.stdin = cmd, stdin
+					p.encoder = json.NewEncoder(stdin)
+					p.answers = make(chan processSignal, 16)
+					p.exited = make(chan struct{})
+					go p.readAnswers(stdout, p.answers, p.exited)
+					go p.logErrors(cmd, stderr, p.exited)
+				}
+			}
+		}
+	}
+	if err != nil {
+		p.engine.logger.Error(fmt.Sprintf("Failed to start strategy process %s: %v", p.settings.Name, err))
+		return err
+	}
+
+	err = p.encoder.Encode(processStart{
+		Type:       "start",
+		Strategy:   p.engine.instance,
+		Thresholds: p.engine.thresholds,
+		Shorts:     p.engine.shorts,
+	})
+	if err != nil {
+		p.engine.logger.Error(fmt.Sprintf("Failed to start strategy process %s: %v", p.settings.Name, err))
+		p.stop()
+		return err
+	}
+	p.engine.logger.Info(fmt.Sprintf("Started strategy process %s (pid %d)", p.settings.Name, cmd.Process.Pid))
+	return nil
+}
+
+// readAnswers decodes the process's answers until its output closes
+func (p *Process) readAnswers(stdout io.Reader, answers chan<- processSignal, exited <-chan struct{}) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var answer processSignal
+		if err := json.Unmarshal(scanner.Bytes(), &answer); err != nil {
+			p.engine.logger.Warning(fmt.Sprintf("Strategy process %s answered an invalid line: %v", p.settings.Name, err))
+			continue
+		}
+		select {
+		case answers <- answer:
+		case <-exited:
+			return
+		}
+	}
+}
+
+// logErrors logs the process's standard error, then waits for it to exit
+func (p *Process) logErrors(cmd *exec.Cmd, stderr io.Reader, exited chan struct{}) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.engine.logger.Info(fmt.Sprintf("Strategy process %s: %s", p.settings.Name, scanner.Text()))
+	}
+	if err := cmd.Wait(); err != nil {
+		p.engine.logger.Warning(fmt.Sprintf("Strategy process %s exited: %v", p.settings.Name, err))
+	} else {
+		p.engine.logger.Info(fmt.Sprintf("Strategy process %s exited", p.settings.Name))
+	}
+	close(exited)
+}
+
+// stop closes the process's input and kills it if it does not exit
+func (p *Process) stop() {
+	if p.cmd == nil {
+		return
+	}
+	p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(2 * time.Second):
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+	p.cmd = nil
+}
+
+// Close stops the process
+func (p *Process) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stop()
+	return nil
+}
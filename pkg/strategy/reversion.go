@@ -0,0 +1,74 @@
+package strategy
+
+import (
+	"context"
+
+	"TRADE/pkg/rolling"
+	"TRADE/pkg/types"
+)
+
+func init() {
+	Register("mean_reversion", func(engine *Engine) Strategy { return &MeanReversion{engine: engine} })
+}
+
+// MeanReversion fades stretched moves in choppy markets. It enters against
+// a price reversion_entry standard deviations away from its mean over the
+// last reversion_window ticks, while the market efficiency ratio is at
+// most reversion_max_efficiency, and exits once the price is back within
+// reversion_exit standard deviations of the mean, or when it has moved the
+// trade's initial risk (1.5 ATR) against it.
+type MeanReversion struct {
+	engine *Engine
+	prices *rolling.Stats
+}
+
+// GenerateSignal returns an entry signal against a stretched price, and a
+// close signal once the active trade's price has reverted or is stopped
+func (r *MeanReversion) GenerateSignal(ctx context.Context, state types.MarketState) *types.Signal {
+	e := r.engine
+	thresholds := e.thresholds
+	price, timestamp, metrics := state.CurrentPrice, state.Timestamp, state.Metrics
+
+	window := int(thresholds["reversion_window"])
+	if r.prices == nil || r.prices.Cap() != window {
+		r.prices = rolling.NewStats(window)
+	}
+	r.prices.Push(price)
+	full := r.prices.Len() == window
+	deviations := 0.0 // Distance of the price from the mean, in standard deviations
+	if deviation := r.prices.StdDev(); deviation > 0 {
+		deviations = (price - r.prices.Mean()) / deviation
+	}
+
+	if trade := state.ActiveTrade; trade != nil {
+		direction := 1.0
+		if trade.Short() {
+			direction = -1
+		}
+		reason := ""
+		switch {
+		case trade.InitialRisk > 0 && direction*(trade.EntryPrice-price) >= trade.InitialRisk:
+			reason = "stop_loss"
+		case full && direction*deviations >= -thresholds["reversion_exit"]:
+			reason = "mean_reached"
+		default:
+			return nil
+		}
+		return types.NewSellSignal(price, timestamp, reason, trade.Return(price)*100, trade.StopLoss)
+	}
+
+	if !full ||
+		metrics.RealizedVolatility > thresholds["realized_volatility_hi"] ||
+		metrics.RealizedVolatility < thresholds["realized_volatility_lo"] ||
+		metrics.MarketEfficiencyRatio > thresholds["reversion_max_efficiency"] {
+		return nil
+	}
+	entry := thresholds["reversion_entry"]
+	switch {
+	case deviations <= -entry:
+		return types.NewBuySignal(price, timestamp, metrics)
+	case deviations >= entry:
+		return types.NewShortSignal(price, timestamp, metrics)
+	}
+	return nil
+}
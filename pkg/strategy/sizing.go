@@ -0,0 +1,81 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"TRADE/pkg/decimal"
+)
+
+// SizingMode selects how the quantity of an entry is computed
+type SizingMode string
+
+const (
+	// SizingNone leaves entries unsized; the manager enters with the
+	// strategy's available capital
+	SizingNone SizingMode = "none"
+	// SizingFixedFractional commits risk_percent of the equity to each
+	// entry
+	SizingFixedFractional SizingMode = "fixed_fractional"
+	// SizingATRRisk sizes entries so that reaching the initial stop, a
+	// multiple of ATR away, loses risk_percent of the equity
+	SizingATRRisk SizingMode = "atr_risk"
+)
+
+// ParseSizingMode parses a sizing mode; empty selects none
+func ParseSizingMode(name string) (SizingMode, error) {
+	switch mode := SizingMode(strings.ToLower(name)); mode {
+	case "":
+		return SizingNone, nil
+	case SizingNone, SizingFixedFractional, SizingATRRisk:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown sizing mode %q (want none, fixed_fractional or atr_risk)", name)
+}
+
+// Sizing computes the quantity of entries from the account equity and the
+// per-trade risk
+type Sizing struct {
+	Mode        SizingMode
+	Equity      float64 // Account equity, in the quote currency
+	RiskPercent float64 // Share of the equity per trade, in percent
+}
+
+// Quantity returns the base quantity of an entry at price whose initial
+// stop is initialRisk away; 0 when unsized or nothing can be risked
+func (z Sizing) Quantity(price, initialRisk float64) float64 {
+	if z.Equity <= 0 || z.RiskPercent <= 0 || price <= 0 {
+		return 0
+	}
+	budget := z.Equity * z.RiskPercent / 100
+	switch z.Mode {
+	case SizingFixedFractional:
+		return budget / price
+	case SizingATRRisk:
+		if initialRisk <= 0 {
+			return 0
+		}
+		return budget / initialRisk
+	}
+	return 0
+}
+
+// SetSizing makes entry signals carry a quantity; nil leaves them unsized
+func (e *Engine) SetSizing(sizing *Sizing) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.sizing = sizing
+}
+
+// quantity returns the sized quantity of an entry, in whole lots of the
+// instrument when set
+func (e *Engine) quantity(price, initialRisk float64) float64 {
+	if e.sizing == nil {
+		return 0
+	}
+	quantity := e.sizing.Quantity(price, initialRisk)
+	if e.instrument == nil || quantity <= 0 {
+		return quantity
+	}
+	return e.instrument.FloorQuantity(decimal.FromFloat(quantity)).Float64()
+}
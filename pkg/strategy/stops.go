@@ -0,0 +1,136 @@
+package strategy
+
+import (
+	"math"
+	"time"
+
+	"TRADE/pkg/ids"
+	"TRADE/pkg/types"
+)
+
+// Initial risk of a trade: the distance to the stop the exit rules start
+// from, a multiple of ATR with a floor of 0.1% of the entry price
+const (
+	riskATRMultiple = 1.5
+	minRiskFraction = 0.001
+)
+
+// BreakEvenRule moves the stop to the entry price once a trade is far
+// enough in profit, so a winner cannot turn into a loss
+type BreakEvenRule struct {
+	// TriggerMultiple is the profit, in multiples of the trade's initial
+	// risk, at which the stop moves
+	TriggerMultiple float64
+	// FeeBuffer is added above the entry price, as a fraction of it, so a
+	// stop-out at the new level still covers fees (0.002 = 0.2%)
+	FeeBuffer float64
+}
+
+// StopManager owns the stop level of the active trade
+type StopManager struct {
+	breakEven *BreakEvenRule
+}
+
+// NewStopManager creates a stop manager without stop rules
+func NewStopManager() *StopManager {
+	return &StopManager{}
+}
+
+// SetBreakEven enables the break-even rule; nil disables it
+func (m *StopManager) SetBreakEven(rule *BreakEvenRule) {
+	m.breakEven = rule
+}
+
+// Open records the initial risk of a trade entered at price
+func (m *StopManager) Open(trade *types.TradeData, price float64, metrics *types.MarketMetrics) {
+	atr := 0.0
+	if metrics != nil {
+		atr = metrics.ATR
+	}
+	trade.InitialRisk = riskATRMultiple * math.Max(atr, price*minRiskFraction)
+	trade.StopLoss = 0
+	trade.BreakEven = false
+}
+
+// AdoptedTrade creates the trade of a position entered outside the
+// strategy at entryPrice. Its initial risk is the distance to stopLoss, or
+// the minimum risk without a stop, as its entry ATR is unknown.
+func AdoptedTrade(entryPrice float64, entryTime time.Time, stopLoss float64) *types.TradeData {
+	trade := &types.TradeData{
+		ID:           ids.Trade(),
+		Active:       true,
+		Direction:    types.DirectionLong,
+		EntryPrice:   entryPrice,
+		EntryTime:    entryTime,
+		HighestPrice: entryPrice,
+		LowestPrice:  entryPrice,
+		StopLoss:     stopLoss,
+		InitialRisk:  riskATRMultiple * entryPrice * minRiskFraction,
+	}
+	if stopLoss > 0 {
+		trade.InitialRisk = entryPrice - stopLoss
+	}
+	return trade
+}
+
+// Triggered reports whether price has reached the trade's stop: below it
+// for a long trade, above it for a short one
+func (m *StopManager) Triggered(trade *types.TradeData, price float64) bool {
+	if trade.StopLoss <= 0 {
+		return false
+	}
+	if trade.Short() {
+		return price >= trade.StopLoss
+	}
+	return price <= trade.StopLoss
+}
+
+// Reason names the exit of a trade stopped out at its stop
+func (m *StopManager) Reason(trade *types.TradeData) string {
+	if trade.BreakEven {
+		return "break_even_stop"
+	}
+	return "stop_loss"
+}
+
+// Update applies the stop rules at price and returns the new stop, or 0 if
+// the stop did not move. round rounds the stop to the exchange tick.
+func (m *StopManager) Update(trade *types.TradeData, price float64, round func(float64) float64) float64 {
+	rule := m.breakEven
+	if rule == nil || trade.BreakEven || trade.InitialRisk <= 0 {
+		return 0
+	}
+	if trade.Short() {
+		return m.updateShort(trade, price, round)
+	}
+	if price-trade.EntryPrice < rule.TriggerMultiple*trade.InitialRisk {
+		return 0
+	}
+
+	// A stop at or above the price would fill at once; wait for more room
+	stop := round(trade.EntryPrice * (1 + rule.FeeBuffer))
+	if stop >= price || stop <= trade.StopLoss {
+		return 0
+	}
+	trade.StopLoss = stop
+	trade.BreakEven = true
+	return stop
+}
+
+// updateShort applies the break-even rule to a short trade: the stop moves
+// down to the entry price, less the fee buffer
+func (m *StopManager) updateShort(trade *types.TradeData, price float64, round func(float64) float64) float64 {
+	rule := m.breakEven
+	if trade.EntryPrice-price < rule.TriggerMultiple*trade.InitialRisk {
+		return 0
+	}
+
+	// A stop at or below the price would fill at once; wait for more room
+	stop := round(trade.EntryPrice * (1 - rule.FeeBuffer))
+	if stop <= price || (trade.StopLoss > 0 && stop >= trade.StopLoss) {
+		return 0
+	}
+	trade.StopLoss = stop
+	trade.BreakEven = true
+	return stop
+}
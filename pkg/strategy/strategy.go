@@ -1,244 +1,74 @@
 package strategy
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
-	"time"
 
-	"TRADE/pkg/analyzer"
-	"TRADE/pkg/logger"
 	"TRADE/pkg/types"
 )
 
-// Strategy generates trading signals based on market conditions
-type Strategy struct {
-	analyzer       *analyzer.Analyzer
-	logger         *logger.Logger
-	activeTrade    *types.TradeData
-	mutex          sync.RWMutex
-}
+// DefaultStrategy is the strategy engines run unless configured otherwise
+const DefaultStrategy = "trend_following"
 
-// NewStrategy creates a new trading strategy
-func NewStrategy(analyzer *analyzer.Analyzer, log *logger.Logger) *Strategy {
-	return &Strategy{
-		analyzer:    analyzer,
-		logger:      log,
-		activeTrade: types.NewTradeData(),
-	}
+// Strategy decides the trading signals of a market. While no trade is
+// active (state.ActiveTrade is nil) it returns an entry signal
+// (types.NewBuySignal or types.NewShortSignal) or nil; while one is, a
+// close signal of the trade (types.NewSellSignal) or nil. The Engine
+// running it opens and closes the trade, sizes entries and manages stops;
+// the active trade is the engine's and must not be modified.
+type Strategy interface {
+	GenerateSignal(ctx context.Context, state types.MarketState) *types.Signal
 }
 
-// GenerateSignal generates trading signals based on market conditions
-func (s *Strategy) GenerateSignal(price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	
-	// Check if we have an active trade
-	if s.activeTrade.Active {
-		return s.checkExitConditions(price, timestamp, metrics)
-	} else {
-		return s.checkEntryConditions(price, timestamp, metrics)
-	}
-}
-
-// checkEntryConditions checks for entry conditions based on market metrics
-func (s *Strategy) checkEntryConditions(price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
-	// Check buy conditions
-	if s.checkBuyConditions(metrics) {
-		s.logger.Info("Buy conditions met")
-		
-		// Create active trade
-		s.activeTrade.Active = true
-		s.activeTrade.Direction = "buy"
-		s.activeTrade.EntryPrice = price
-		s.activeTrade.EntryTime = timestamp
-		s.activeTrade.HighestPrice = price
-		s.activeTrade.LowestPrice = price
-		
-		// Generate buy signal
-		return types.NewBuySignal(price, timestamp, metrics)
-	}
-	
-	return nil
-}
+// Factory creates a strategy run by engine, whose thresholds and settings
+// it may read while generating signals
+type Factory func(engine *Engine) Strategy
 
-// checkExitConditions checks for exit conditions for an active trade
-func (s *Strategy) checkExitConditions(price float64, timestamp time.Time, metrics *types.MarketMetrics) *types.Signal {
-	// Update highest and lowest prices
-	if price > s.activeTrade.HighestPrice {
-		s.activeTrade.HighestPrice = price
-	}
-	if price < s.activeTrade.LowestPrice {
-		s.activeTrade.LowestPrice = price
-	}
-	
-	// Check sell conditions
-	stopTriggered, reason, stopLoss, profit := s.checkSellConditions(
-		s.activeTrade.EntryTime,
-		s.activeTrade.EntryPrice,
-		s.activeTrade.HighestPrice,
-		price,
-		timestamp,
-		metrics,
-	)
-	
-	if stopTriggered {
-		s.logger.Info("Sell conditions met: " + reason)
-		
-		// Generate sell signal
-		signal := types.NewSellSignal(price, timestamp, reason, profit*100, stopLoss)
-		
-		// Reset active trade
-		s.activeTrade.Active = false
-		
-		return signal
-	}
-	
-	return nil
-}
-
-// checkBuyConditions checks if buy conditions are met
-func (s *Strategy) checkBuyConditions(metrics *types.MarketMetrics) bool {
-	// Default thresholds
-	thresholds := map[string]float64{
-		"realized_volatility_hi": 0.70,
-		"realized_volatility_lo": 0.35,
-		"relative_strength_hi":   0.75,
-		"relative_strength_lo":   0.25,
-		"trend_strength":         5.0,
-		"avg_trend_strength":     3.0,
-		"order_imbalance":        0.65,
-		"market_efficiency_ratio": 0.93,
-	}
-	
-	// Check all conditions
-	return (
-		metrics.RealizedVolatility <= thresholds["realized_volatility_hi"] &&
-		metrics.RealizedVolatility >= thresholds["realized_volatility_lo"] &&
-		metrics.RelativeStrength <= thresholds["relative_strength_hi"] &&
-		metrics.RelativeStrength >= thresholds["relative_strength_lo"] &&
-		metrics.TrendStrength >= thresholds["trend_strength"] &&
-		metrics.AvgTrendStrength >= thresholds["avg_trend_strength"] &&
-		metrics.TrendStrength > metrics.AvgTrendStrength &&
-		metrics.OrderImbalance >= thresholds["order_imbalance"] &&
-		metrics.MarketEfficiencyRatio >= thresholds["market_efficiency_ratio"]
-	)
-}
+// registry holds the strategies selectable by name; plugins add to it at
+// runtime
+var (
+	registry      = map[string]Factory{}
+	registryMutex sync.RWMutex
+)
 
-// checkSellConditions checks if sell conditions are met
-func (s *Strategy) checkSellConditions(
-	entryTime time.Time,
-	entryPrice float64,
-	highestPrice float64,
-	currentPrice float64,
-	timestamp time.Time,
-	metrics *types.MarketMetrics,
-) (bool, string, float64, float64) {
-	// Constants for exit conditions
-	trailingStopActivation := 1.0  // Percentage gain to activate trailing stop
-	profitTargetMultiplier := 2.5  // Profit target as multiple of risk
-	trailingStopDistance := 1.5    // Trailing stop distance factor
-	trendStrengthThreshold := -7.0 // Trend strength threshold for exit
-	minProfit := 0.3               // Minimum profit percentage for time-based exit
-	
-	// Calculate current profit percentage
-	profit := (currentPrice / entryPrice - 1)
-	stopTriggered := false
-	reason := ""
-	
-	// Calculate stop loss and take profit levels
-	atr := metrics.ATR
-	if atr < currentPrice*0.001 {
-		atr = currentPrice * 0.001 // Use minimum 0.1% ATR
-	}
-	
-	stopDistance := trailingStopDistance * atr
-	profitDistance := stopDistance * profitTargetMultiplier
-	
-	// For long trades: stop below entry, target above entry
-	stopLoss := currentPrice - stopDistance
-	takeProfit := currentPrice + profitDistance
-	
-	// Check stop loss
-	if currentPrice <= stopLoss {
-		stopTriggered = true
-		reason = "stop_loss"
-	}
-	
-	// Check take profit
-	if currentPrice >= takeProfit {
-		stopTriggered = true
-		reason = "take_profit"
-	}
-	
-	// Adjust trailing stop if profit exceeds activation threshold
-	activationThreshold := trailingStopActivation / 100
-	if profit >= activationThreshold {
-		// Calculate trailing stop level
-		trailDistance := trailingStopActivation * (metrics.ATR / highestPrice)
-		trailLevel := highestPrice * (1 - trailDistance)
-		
-		// Update stop loss if trailing stop is higher
-		if trailLevel > stopLoss {
-			stopLoss = trailLevel
-			s.logger.Info("Trailing stop updated")
-		}
-	}
-	
-	// Check time-based exit
-	if !entryTime.IsZero() {
-		tradeDuration := timestamp.Sub(entryTime).Hours()
-		if tradeDuration > 4 && profit >= minProfit/100 {  // Exit after 4 hours
-			stopTriggered = true
-			reason = "time_exit"
-		}
-	}
-	
-	// Check trend reversal exit
-	if metrics.TrendStrength < trendStrengthThreshold && profit >= minProfit/100 {
-		stopTriggered = true
-		reason = "trend_reversal"
-	}
-	
-	return stopTriggered, reason, stopLoss, profit
+// Register makes a strategy selectable by name (strategy.type in the
+// config). It is meant to be called from init functions, including those
+// of Go plugins, and panics if the name is taken.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("strategy %q registered twice", name))
+	}
+	registry[name] = factory
 }
 
-// IsActiveTrade returns whether there is an active trade
-func (s *Strategy) IsActiveTrade() bool {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.activeTrade.Active
+// Lookup returns the factory of the strategy registered under name; empty
+// selects DefaultStrategy
+func Lookup(name string) (Factory, error) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	registryMutex.RLock()
+	factory, ok := registry[strings.ToLower(name)]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory, nil
 }
 
-// GetActiveTradeData returns data about the active trade
-func (s *Strategy) GetActiveTradeData() *types.TradeData {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	
-	// Create a copy of the active trade data
-	tradeCopy := &types.TradeData{
-		Active:       s.activeTrade.Active,
-		Direction:    s.activeTrade.Direction,
-		EntryPrice:   s.activeTrade.EntryPrice,
-		EntryTime:    s.activeTrade.EntryTime,
-		HighestPrice: s.activeTrade.HighestPrice,
-		LowestPrice:  s.activeTrade.LowestPrice,
-		StopLoss:     s.activeTrade.StopLoss,
-	}
-	
-	// Calculate current PnL if active
-	if tradeCopy.Active {
-		currentPrice := s.activeTrade.HighestPrice // Use highest price as a proxy for current price
-		tradeCopy.CurrentPnL = (currentPrice / s.activeTrade.EntryPrice - 1) * 100
-	}
-	
-	return tradeCopy
+// Names returns the names of the registered strategies, sorted
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
-
-// UpdateStopLoss updates the stop loss level for the active trade
-func (s *Strategy) UpdateStopLoss(newStopLoss float64) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	
-	if s.activeTrade.Active && newStopLoss > 0 {
-		s.activeTrade.StopLoss = newStopLoss
-	}
-}
\ No newline at end of file
@@ -0,0 +1,57 @@
+package strategy
+
+import (
+	"TRADE/pkg/types"
+)
+
+// regimeEfficiency is the market efficiency ratio from which a trade is
+// tagged as entered in a trending rather than a ranging market
+const regimeEfficiency = 0.5
+
+// ATR percentiles below and above which a trade is tagged as entered in
+// low or high volatility
+const (
+	lowVolatility  = 0.25
+	highVolatility = 0.75
+)
+
+// entryTags returns the tags of the trade an entry signal opens: the
+// strategy instance, the direction, the regime of the market and, with
+// adaptive thresholds past their warm-up, of its volatility, followed by
+// the tags the strategy gave the signal; invalid tags, such as of an
+// instance named with spaces, are left out
+func (e *Engine) entryTags(signal *types.Signal, metrics *types.MarketMetrics) []string {
+	direction := "long"
+	if signal.Short() {
+		direction = "short"
+	}
+	tags := []string{"strategy:" + e.instance, "direction:" + direction}
+	if metrics != nil {
+		regime := "ranging"
+		if metrics.MarketEfficiencyRatio >= regimeEfficiency {
+			regime = "trending"
+		}
+		tags = append(tags, "regime:"+regime)
+	}
+	if e.adaptive != nil && e.adaptive.Ready() {
+		volatility := "normal"
+		switch {
+		case e.adaptive.percentile < lowVolatility:
+			volatility = "low"
+		case e.adaptive.percentile > highVolatility:
+			volatility = "high"
+		}
+		tags = append(tags, "volatility:"+volatility)
+	}
+
+	candidates := append(tags, signal.Tags...)
+	tags = make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, tag := range candidates {
+		if tag, ok := types.NormalizeTag(tag); ok && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
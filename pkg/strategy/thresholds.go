@@ -0,0 +1,182 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"TRADE/pkg/types"
+)
+
+// ImbalanceSource selects the order imbalance the entry condition uses
+type ImbalanceSource string
+
+const (
+	// ImbalanceTradeFlow requires the share of buyer-initiated volume in
+	// the recent trades to reach order_imbalance
+	ImbalanceTradeFlow ImbalanceSource = "trade_flow"
+	// ImbalanceBook requires the bid share of the top book levels to reach
+	// book_imbalance and its short-term trend to reach book_imbalance_trend
+	ImbalanceBook ImbalanceSource = "book"
+)
+
+// ParseImbalanceSource parses an imbalance source; empty selects the
+// trade flow
+func ParseImbalanceSource(name string) (ImbalanceSource, error) {
+	switch source := ImbalanceSource(strings.ToLower(name)); source {
+	case "":
+		return ImbalanceTradeFlow, nil
+	case ImbalanceTradeFlow, ImbalanceBook:
+		return source, nil
+	}
+	return "", fmt.Errorf("unknown imbalance source %q (want trade_flow or book)", name)
+}
+
+// DefaultThresholds returns the entry and exit thresholds the strategies
+// trade with unless configured otherwise
+func DefaultThresholds() map[string]float64 {
+	return map[string]float64{
+		// Entry of trend_following; the realized volatility range bounds the
+		// entries of every strategy
+		"realized_volatility_hi":  0.70,
+		"realized_volatility_lo":  0.35,
+		"relative_strength_hi":    0.75,
+		"relative_strength_lo":    0.25,
+		ParamTrendStrength:        5.0,
+		ParamAvgTrendStrength:     3.0,
+		"order_imbalance":         0.65,
+		"book_imbalance":          0.60, // Used instead of order_imbalance with the book source
+		"book_imbalance_trend":    0.02,
+		"market_efficiency_ratio": 0.93,
+
+		// Entry of mean_reversion
+		"reversion_window":         300, // Ticks the mean and standard deviation are taken over
+		"reversion_entry":          2.0, // Standard deviations from the mean to enter
+		"reversion_exit":           0.0, // Standard deviations from the mean to exit
+		"reversion_max_efficiency": 0.5, // Maximum market efficiency ratio
+
+		// Entry of breakout
+		"breakout_window": 600,    // Ticks the range is taken over
+		"breakout_margin": 0.0001, // Fraction beyond the range to enter
+
+		// Exit
+		ParamTrailingActivation: 1.0,  // Percentage gain to activate trailing stop
+		ParamProfitTarget:       2.5,  // Profit target as multiple of risk
+		ParamTrailingDistance:   1.5,  // Trailing stop distance factor
+		ParamTrendExit:          -7.0, // Trend strength threshold for exit
+		"min_profit":            0.3,  // Minimum profit percentage for time-based and trend exits
+	}
+}
+
+// SetThresholds overrides thresholds by name; thresholds not given keep
+// their defaults. Unknown names are rejected.
+func (e *Engine) SetThresholds(overrides map[string]float64) error {
+	thresholds := DefaultThresholds()
+	for name, value := range overrides {
+		if _, ok := thresholds[name]; !ok {
+			return unknownThreshold(name, thresholds)
+		}
+		thresholds[name] = value
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.thresholds = thresholds
+	return nil
+}
+
+// SetImbalanceSource selects the order imbalance the entry condition uses
+func (e *Engine) SetImbalanceSource(source ImbalanceSource) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.imbalance = source
+}
+
+// imbalanceCondition checks the entry condition on the order imbalance of
+// the configured source
+func (e *Engine) imbalanceCondition(metrics *types.MarketMetrics) bool {
+	if e.imbalance == ImbalanceBook {
+		return metrics.BookImbalance >= e.thresholds["book_imbalance"] &&
+			metrics.BookImbalanceTrend >= e.thresholds["book_imbalance_trend"]
+	}
+	return metrics.OrderImbalance >= e.thresholds["order_imbalance"]
+}
+
+// shortImbalanceCondition checks the order imbalance condition of short
+// entries: the seller share of the configured source, and its trend,
+// mirrored
+func (e *Engine) shortImbalanceCondition(metrics *types.MarketMetrics) bool {
+	if e.imbalance == ImbalanceBook {
+		return 1-metrics.BookImbalance >= e.thresholds["book_imbalance"] &&
+			-metrics.BookImbalanceTrend >= e.thresholds["book_imbalance_trend"]
+	}
+	return 1-metrics.OrderImbalance >= e.thresholds["order_imbalance"]
+}
+
+// Thresholds returns the configured thresholds, before volatility scaling
+func (e *Engine) Thresholds() map[string]float64 {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	thresholds := make(map[string]float64, len(e.thresholds))
+	for name, value := range e.thresholds {
+		thresholds[name] = value
+	}
+	return thresholds
+}
+
+// CheckThresholds checks threshold overrides before they are set: names
+// must be known, shares and ratios within [0, 1], exit distances positive
+// and every lower bound at most its upper bound. It returns every problem.
+func CheckThresholds(overrides map[string]float64) []error {
+	var problems []error
+	thresholds := DefaultThresholds()
+	for name, value := range overrides {
+		if _, ok := thresholds[name]; !ok {
+			problems = append(problems, unknownThreshold(name, thresholds))
+			continue
+		}
+		thresholds[name] = value
+	}
+
+	for _, name := range []string{"order_imbalance", "book_imbalance", "market_efficiency_ratio", "reversion_max_efficiency"} {
+		if value := thresholds[name]; value < 0 || value > 1 {
+			problems = append(problems, fmt.Errorf("strategy threshold %s must be in [0, 1], got %g", name, value))
+		}
+	}
+	for _, name := range []string{ParamProfitTarget, ParamTrailingDistance} {
+		if value := thresholds[name]; value <= 0 {
+			problems = append(problems, fmt.Errorf("strategy threshold %s must be positive, got %g", name, value))
+		}
+	}
+	for _, name := range []string{ParamTrailingActivation, "min_profit", "realized_volatility_lo", "relative_strength_lo", "breakout_margin"} {
+		if value := thresholds[name]; value < 0 {
+			problems = append(problems, fmt.Errorf("strategy threshold %s cannot be negative, got %g", name, value))
+		}
+	}
+	for _, name := range []string{"reversion_window", "breakout_window"} {
+		if value := thresholds[name]; value < 2 {
+			problems = append(problems, fmt.Errorf("strategy threshold %s must be at least 2 ticks, got %g", name, value))
+		}
+	}
+	if entry, exit := thresholds["reversion_entry"], thresholds["reversion_exit"]; entry <= 0 || exit >= entry {
+		problems = append(problems, fmt.Errorf("strategy threshold reversion_entry (%g) must be positive and above reversion_exit (%g)", entry, exit))
+	}
+	for _, bound := range []string{"realized_volatility", "relative_strength"} {
+		if lo, hi := thresholds[bound+"_lo"], thresholds[bound+"_hi"]; lo > hi {
+			problems = append(problems, fmt.Errorf("strategy threshold %s_lo (%g) is above %s_hi (%g)", bound, lo, bound, hi))
+		}
+	}
+	return problems
+}
+
+// unknownThreshold returns the error of a threshold name that is not one
+// of thresholds
+func unknownThreshold(name string, thresholds map[string]float64) error {
+	names := make([]string, 0, len(thresholds))
+	for known := range thresholds {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown strategy threshold %q (want one of %s)", name, strings.Join(names, ", "))
+}
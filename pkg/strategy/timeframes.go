@@ -0,0 +1,45 @@
+package strategy
+
+import "TRADE/pkg/types"
+
+// TimeframeRule requires the trend of higher candle timeframes to confirm
+// an entry's direction before it is taken
+type TimeframeRule struct {
+	// Timeframes are the analyzed timeframes (e.g. 15m, 1h) that must
+	// confirm, named as in the metrics
+	Timeframes []string
+	// MinTrend is the trend, in percent of the price per candle, a
+	// timeframe must be above for a long entry and below the negative of
+	// for a short one
+	MinTrend float64
+}
+
+// SetTimeframes requires higher timeframes to confirm entries; nil takes
+// entries on the tick metrics alone
+func (e *Engine) SetTimeframes(rule *TimeframeRule) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.timeframes = rule
+}
+
+// timeframeCondition returns whether the confirming timeframes let an
+// entry through: each measured over its full period, trending up for a
+// long entry or down for a short one
+func (e *Engine) timeframeCondition(metrics *types.MarketMetrics, short bool) bool {
+	if e.timeframes == nil {
+		return true
+	}
+	for _, timeframe := range e.timeframes.Timeframes {
+		confirm, ok := metrics.Timeframe(timeframe)
+		if !ok || !confirm.Ready {
+			return false
+		}
+		if short && confirm.Trend >= -e.timeframes.MinTrend {
+			return false
+		}
+		if !short && confirm.Trend <= e.timeframes.MinTrend {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package strategy
+
+import (
+	"context"
+	"time"
+
+	"TRADE/pkg/logger"
+	"TRADE/pkg/types"
+)
+
+func init() {
+	Register("trend_following", func(engine *Engine) Strategy { return &TrendFollowing{engine: engine} })
+}
+
+// TrendFollowing enters in the direction of a strong, efficient trend
+// backed by the order imbalance, and exits on the trend reversing, its
+// profit target, a trailing stop or after four hours in profit
+type TrendFollowing struct {
+	engine *Engine
+}
+
+// GenerateSignal returns an entry signal when the buy conditions, or the
+// short conditions, are met, and a close signal when the exit conditions
+// of the active trade are
+func (t *TrendFollowing) GenerateSignal(ctx context.Context, state types.MarketState) *types.Signal {
+	price, timestamp, metrics := state.CurrentPrice, state.Timestamp, state.Metrics
+	if trade := state.ActiveTrade; trade != nil {
+		exit, reason, stopLoss, profit := t.checkSellConditions(trade, price, timestamp, metrics)
+		if !exit {
+			return nil
+		}
+		return types.NewSellSignal(price, timestamp, reason, profit*100, stopLoss)
+	}
+
+	// Check buy conditions, then the short conditions
+	if t.checkBuyConditions(metrics) {
+		return types.NewBuySignal(price, timestamp, metrics)
+	}
+	if t.checkShortConditions(metrics) {
+		return types.NewShortSignal(price, timestamp, metrics)
+	}
+	return nil
+}
+
+// checkBuyConditions checks if buy conditions are met
+func (t *TrendFollowing) checkBuyConditions(metrics *types.MarketMetrics) bool {
+	e := t.engine
+	thresholds := e.thresholds
+
+	// Check all conditions
+	return metrics.RealizedVolatility <= thresholds["realized_volatility_hi"] &&
+		metrics.RealizedVolatility >= thresholds["realized_volatility_lo"] &&
+		metrics.RelativeStrength <= thresholds["relative_strength_hi"] &&
+		metrics.RelativeStrength >= thresholds["relative_strength_lo"] &&
+		metrics.TrendStrength >= e.threshold(ParamTrendStrength) &&
+		metrics.AvgTrendStrength >= e.threshold(ParamAvgTrendStrength) &&
+		metrics.TrendStrength > metrics.AvgTrendStrength &&
+		e.imbalanceCondition(metrics) &&
+		metrics.MarketEfficiencyRatio >= thresholds["market_efficiency_ratio"]
+}
+
+// checkShortConditions checks if the short entry conditions are met: the
+// buy conditions mirrored, on a falling trend, with sellers in control
+func (t *TrendFollowing) checkShortConditions(metrics *types.MarketMetrics) bool {
+	e := t.engine
+	thresholds := e.thresholds
+	weakness := 1 - metrics.RelativeStrength
+
+	return metrics.RealizedVolatility <= thresholds["realized_volatility_hi"] &&
+		metrics.RealizedVolatility >= thresholds["realized_volatility_lo"] &&
+		weakness <= thresholds["relative_strength_hi"] &&
+		weakness >= thresholds["relative_strength_lo"] &&
+		-metrics.TrendStrength >= e.threshold(ParamTrendStrength) &&
+		-metrics.AvgTrendStrength >= e.threshold(ParamAvgTrendStrength) &&
+		metrics.TrendStrength < metrics.AvgTrendStrength &&
+		e.shortImbalanceCondition(metrics) &&
+		metrics.MarketEfficiencyRatio >= thresholds["market_efficiency_ratio"]
+}
+
+// checkSellConditions checks if the exit conditions of the trade, long or
+// short, are met. It returns whether to exit, why, the stop level and the
+// trade's return.
+func (t *TrendFollowing) checkSellConditions(
+	trade *types.TradeData,
+	currentPrice float64,
+	timestamp time.Time,
+	metrics *types.MarketMetrics,
+) (bool, string, float64, float64) {
+	e := t.engine
+
+	// Exit thresholds, scaled with the volatility regime when adaptive
+	trailingStopActivation := e.threshold(ParamTrailingActivation) // Percentage gain to activate trailing stop
+	profitTargetMultiplier := e.threshold(ParamProfitTarget)       // Profit target as multiple of risk
+	trailingStopDistance := e.threshold(ParamTrailingDistance)     // Trailing stop distance factor
+	trendStrengthThreshold := e.threshold(ParamTrendExit)          // Trend strength threshold for exit
+	minProfit := e.thresholds["min_profit"]                        // Minimum profit percentage for time-based exit
+
+	// Calculate current profit percentage; prices move against a short
+	direction := 1.0
+	if trade.Short() {
+		direction = -1
+	}
+	bestPrice := trade.BestPrice()
+	profit := trade.Return(currentPrice)
+	stopTriggered := false
+	reason := ""
+
+	// Calculate stop loss and take profit levels
+	atr := metrics.ATR
+	if atr < currentPrice*0.001 {
+		atr = currentPrice * 0.001 // Use minimum 0.1% ATR
+	}
+
+	stopDistance := trailingStopDistance * atr
+	profitDistance := stopDistance * profitTargetMultiplier
+
+	// For long trades: stop below entry, target above entry; the other
+	// way around for short trades
+	stopLoss := currentPrice - direction*stopDistance
+	takeProfit := currentPrice + direction*profitDistance
+
+	// Check stop loss
+	if direction*(currentPrice-stopLoss) <= 0 {
+		stopTriggered = true
+		reason = "stop_loss"
+	}
+
+	// Check take profit
+	if direction*(currentPrice-takeProfit) >= 0 {
+		stopTriggered = true
+		reason = "take_profit"
+	}
+
+	// Adjust trailing stop if profit exceeds activation threshold
+	activationThreshold := trailingStopActivation / 100
+	if profit >= activationThreshold {
+		// Calculate trailing stop level, trailing the best price
+		trailDistance := trailingStopActivation * (metrics.ATR / bestPrice)
+		trailLevel := bestPrice * (1 - direction*trailDistance)
+
+		// Update stop loss if trailing stop is tighter
+		if direction*(trailLevel-stopLoss) > 0 {
+			stopLoss = trailLevel
+			e.logger.Info("Trailing stop updated", logger.TradeIDKey, trade.ID)
+		}
+	}
+
+	// Check time-based exit
+	if !trade.EntryTime.IsZero() {
+		tradeDuration := timestamp.Sub(trade.EntryTime).Hours()
+		if tradeDuration > 4 && profit >= minProfit/100 { // Exit after 4 hours
+			stopTriggered = true
+			reason = "time_exit"
+		}
+	}
+
+	// Check trend reversal exit
+	if direction*metrics.TrendStrength < trendStrengthThreshold && profit >= minProfit/100 {
+		stopTriggered = true
+		reason = "trend_reversal"
+	}
+
+	return stopTriggered, reason, stopLoss, profit
+}
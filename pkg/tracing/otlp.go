@@ -0,0 +1,349 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+)
+
+// scopeName is the instrumentation scope the spans are exported under
+const scopeName = "TRADE/pkg/tracing"
+
+// Span kind and status codes of the OTLP protocol
+const (
+	kindInternal    = 1
+	statusCodeError = 2
+)
+
+// Options configures the tracer and the export of its spans
+type Options struct {
+	// Endpoint is the OTLP/HTTP traces URL of the collector, e.g.
+	// http://localhost:4318/v1/traces
+	Endpoint string
+	// Headers are sent with every export, e.g. the collector's
+	// authorization
+	Headers        map[string]string
+	ServiceName    string
+	ServiceVersion string
+	// SampleRatio is the share of traces exported among those with no
+	// error and no kept span
+	SampleRatio   float64
+	BatchSize     int // Spans per export request
+	BufferSize    int // Spans kept while the collector is unreachable
+	FlushInterval time.Duration
+}
+
+// ExportStats counts the spans exported and dropped
+type ExportStats struct {
+	Exported int64 `json:"exported"`
+	Dropped  int64 `json:"dropped"`  // Dropped from a full buffer
+	Failures int64 `json:"failures"` // Failed export requests, retried
+}
+
+// exporter buffers ended spans and exports them in batches in the
+// background, retrying with backoff while the collector is unreachable
+type exporter struct {
+	options  Options
+	resource otlpResource
+	client   *http.Client
+	logger   logger.Interface
+	buffer   []*Span
+	counts   ExportStats
+	notify   chan struct{}
+	done     chan struct{}
+	finished chan struct{}
+	mutex    sync.Mutex
+}
+
+// newExporter creates and starts an exporter to the endpoint of options
+func newExporter(options Options, log logger.Interface) (*exporter, error) {
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: an http or https URL is required", options.Endpoint)
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 512
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 20000
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 5 * time.Second
+	}
+	if options.ServiceName == "" {
+		options.ServiceName = "trade"
+	}
+
+	resource := otlpResource{Attributes: []otlpAttribute{attribute(String("service.name", options.ServiceName))}}
+	if options.ServiceVersion != "" {
+		resource.Attributes = append(resource.Attributes, attribute(String("service.version", options.ServiceVersion)))
+	}
+	e := &exporter{
+		options:  options,
+		resource: resource,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   log,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// enqueue buffers ended spans, dropping the oldest when the buffer is full
+func (e *exporter) enqueue(spans ...*Span) {
+	e.mutex.Lock()
+	e.buffer = append(e.buffer, spans...)
+	if over := len(e.buffer) - e.options.BufferSize; over > 0 {
+		clear(e.buffer[:over])
+		e.buffer = e.buffer[over:]
+		e.counts.Dropped += int64(over)
+	}
+	full := len(e.buffer) >= e.options.BatchSize
+	e.mutex.Unlock()
+
+	if full {
+		select {
+		case e.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports batches until closed, retrying failures with backoff
+func (e *exporter) run() {
+	defer close(e.finished)
+
+	ticker := time.NewTicker(e.options.FlushInterval)
+	defer ticker.Stop()
+	backoff := time.Duration(0)
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.notify:
+		}
+
+		if backoff > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-e.done:
+				return
+			}
+		}
+
+		if err := e.flush(); err != nil {
+			if backoff == 0 {
+				backoff = time.Second
+			} else if backoff < time.Minute {
+				backoff *= 2
+			}
+			e.logger.Warning(fmt.Sprintf("Trace export failed (retrying in %s): %v", backoff, err))
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// flush exports all buffered spans; a failed batch stays buffered
+func (e *exporter) flush() error {
+	for {
+		e.mutex.Lock()
+		n := min(len(e.buffer), e.options.BatchSize)
+		batch := append([]*Span(nil), e.buffer[:n]...)
+		e.mutex.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.export(batch); err != nil {
+			e.mutex.Lock()
+			e.counts.Failures++
+			e.mutex.Unlock()
+			return err
+		}
+
+		e.mutex.Lock()
+		// The buffer may have dropped spans meanwhile; remove what was sent
+		n = min(n, len(e.buffer))
+		clear(e.buffer[:n])
+		e.buffer = e.buffer[n:]
+		e.counts.Exported += int64(len(batch))
+		e.mutex.Unlock()
+	}
+}
+
+// export sends a batch of spans to the collector
+func (e *exporter) export(spans []*Span) error {
+	encoded := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		encoded[i] = encodeSpan(span)
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: encoded}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, e.options.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range e.options.Headers {
+		request.Header.Set(key, value)
+	}
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", response.Status, bytes.TrimSpace(detail))
+	}
+	io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+// stats returns the counts of spans exported and dropped
+func (e *exporter) stats() ExportStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.counts
+}
+
+// close exports the spans still buffered and stops the exporter
+func (e *exporter) close() error {
+	select {
+	case <-e.done:
+		return nil
+	default:
+		close(e.done)
+	}
+	<-e.finished
+	return e.flush()
+}
+
+// otlpRequest is an OTLP/HTTP trace export request in the JSON encoding
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// otlpResourceSpans are the spans of a resource, the traced process
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+// otlpResource describes the traced process
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+// otlpScopeSpans are the spans of an instrumentation scope
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+// otlpScope names the instrumentation scope
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// otlpSpan is an encoded span; IDs are hex and times Unix nanoseconds as
+// strings, as the JSON encoding of OTLP requires
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+// otlpStatus is the status of a span; the zero code is unset
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpAttribute is an encoded attribute
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue holds one of the value kinds; 64-bit integers are strings
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// encodeSpan encodes an ended span
+func encodeSpan(span *Span) otlpSpan {
+	encoded := otlpSpan{
+		TraceID: span.traceID.String(),
+		SpanID:  span.spanID.String(),
+		Name:    span.name,
+		Kind:    kindInternal,
+		Start:   strconv.FormatInt(span.start.UnixNano(), 10),
+		End:     strconv.FormatInt(span.end.UnixNano(), 10),
+	}
+	if span.parentID != (SpanID{}) {
+		encoded.ParentSpanID = span.parentID.String()
+	}
+	for _, attr := range span.attributes {
+		encoded.Attributes = append(encoded.Attributes, attribute(attr))
+	}
+	if span.failed {
+		encoded.Status = otlpStatus{Code: statusCodeError, Message: span.failure}
+	}
+	return encoded
+}
+
+// attribute encodes an attribute; values of other types and non-finite
+// numbers are sent as strings
+func attribute(attr Attribute) otlpAttribute {
+	var value otlpValue
+	switch v := attr.Value.(type) {
+	case string:
+		value.StringValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		value.IntValue = &s
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			s := strconv.FormatFloat(v, 'g', -1, 64)
+			value.StringValue = &s
+		} else {
+			value.DoubleValue = &v
+		}
+	case bool:
+		value.BoolValue = &v
+	default:
+		s := fmt.Sprint(v)
+		value.StringValue = &s
+	}
+	return otlpAttribute{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,266 @@
+// Package tracing records OpenTelemetry spans of the tick → analysis →
+// signal → order path and exports them to a collector over OTLP/HTTP, so
+// the latency and the failures of each stage can be followed in
+// production.
+//
+// Spans of a trace are held until its root span ends, then exported if
+// the trace is sampled, or kept whatever the sampling: traces with an
+// error or a span marked with Keep, such as those of signals, are always
+// exported. A nil *Tracer and a nil *Span do nothing, so the path can be
+// instrumented unconditionally.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"TRADE/pkg/logger"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the ID in lowercase hex
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within its trace
+type SpanID [8]byte
+
+// String returns the ID in lowercase hex
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Attribute is a key and a string, int64, float64 or bool value
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float returns a floating-point attribute
+func Float(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// trace holds the ended spans of a trace until its root span ends and
+// decides whether the trace is exported
+type trace struct {
+	spans   []*Span
+	keep    bool // Exported whatever the sampling
+	decided bool // The root ended; later spans follow its decision
+	export  bool
+	mutex   sync.Mutex
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	tracer     *Tracer
+	trace      *trace
+	name       string
+	traceID    TraceID
+	spanID     SpanID
+	parentID   SpanID // Zero for the root span
+	start      time.Time
+	end        time.Time
+	attributes []Attribute
+	failure    string // Status message of a failed span
+	failed     bool
+}
+
+// Tracer starts traces and exports their spans
+type Tracer struct {
+	exporter  *exporter
+	threshold uint64 // Traces whose ID is below it are sampled
+}
+
+// NewTracer creates a tracer exporting to the OTLP/HTTP endpoint of
+// options, logging export failures to log
+func NewTracer(options Options, log logger.Interface) (*Tracer, error) {
+	if options.SampleRatio < 0 || options.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample ratio must be between 0 and 1")
+	}
+	exporter, err := newExporter(options, log)
+	if err != nil {
+		return nil, err
+	}
+	threshold := uint64(math.MaxUint64)
+	if options.SampleRatio < 1 {
+		threshold = uint64(options.SampleRatio * math.MaxUint64)
+	}
+	return &Tracer{exporter: exporter, threshold: threshold}, nil
+}
+
+// Start starts the root span of a new trace
+func (t *Tracer) Start(name string, attributes ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, trace: &trace{}, name: name, start: time.Now(), attributes: attributes}
+	rand.Read(span.traceID[:])
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// StartUnder starts a span under parent, or the root span of a new trace
+// without one
+func (t *Tracer) StartUnder(parent *Span, name string, attributes ...Attribute) *Span {
+	if parent != nil {
+		return parent.Child(name, attributes...)
+	}
+	return t.Start(name, attributes...)
+}
+
+// sampled returns whether a trace is exported by the sample ratio
+func (t *Tracer) sampled(id TraceID) bool {
+	return binary.BigEndian.Uint64(id[8:]) < t.threshold || t.threshold == math.MaxUint64
+}
+
+// Stats returns the counts of spans exported and dropped
+func (t *Tracer) Stats() ExportStats {
+	if t == nil {
+		return ExportStats{}
+	}
+	return t.exporter.stats()
+}
+
+// Close exports the spans still buffered and stops the exporter
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.close()
+}
+
+// Child starts a span under s, in its trace
+func (s *Span) Child(name string, attributes ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	child := &Span{tracer: s.tracer, trace: s.trace, name: name, traceID: s.traceID, parentID: s.spanID,
+		start: time.Now(), attributes: attributes}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// RecordError marks the span failed with err and exports its trace
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.failed = true
+	s.failure = err.Error()
+	s.Keep()
+}
+
+// Keep exports the span's trace whatever the sampling; once its root
+// ended, the spans still running are exported
+func (s *Span) Keep() {
+	if s == nil {
+		return
+	}
+	s.trace.mutex.Lock()
+	s.trace.keep = true
+	if s.trace.decided {
+		s.trace.export = true
+	}
+	s.trace.mutex.Unlock()
+}
+
+// End ends the span. The root span's end decides whether the trace is
+// exported; spans ending after it follow that decision.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+
+	t := s.trace
+	t.mutex.Lock()
+	if t.decided {
+		export := t.export
+		t.mutex.Unlock()
+		if export {
+			s.tracer.exporter.enqueue(s)
+		}
+		return
+	}
+	t.spans = append(t.spans, s)
+	if s.parentID != (SpanID{}) {
+		t.mutex.Unlock()
+		return
+	}
+	t.decided = true
+	t.export = t.keep || s.tracer.sampled(s.traceID)
+	export, spans := t.export, t.spans
+	t.spans = nil
+	t.mutex.Unlock()
+
+	if export {
+		s.tracer.exporter.enqueue(spans...)
+	}
+}
+
+// TraceID returns the ID of the span's trace, empty for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID.String()
+}
+
+// Traceparent returns the span's W3C trace context header, by which other
+// processes continue the trace; empty for a nil span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + s.traceID.String() + "-" + s.spanID.String() + "-01"
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying span as the current span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes a core type (TickData, Signal, TradeData,
+// MarketMetrics, PerformanceMetrics, ...) in its JSON wire format
+func MarshalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %v", v, err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes a core type from its JSON wire format
+func UnmarshalJSON(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %T: %v", v, err)
+	}
+	return nil
+}
+
+// MarshalGob encodes a core type with gob, for compact Go-to-Go transport
+func MarshalGob(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %v", v, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalGob decodes a core type encoded with MarshalGob
+func UnmarshalGob(data []byte, v interface{}) error {
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %T: %v", v, err)
+	}
+	return nil
+}
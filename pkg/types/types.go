@@ -1,51 +1,282 @@
 package types
 
 import (
+	"math"
+	"strings"
 	"time"
+
+	"TRADE/pkg/decimal"
+	"TRADE/pkg/ids"
 )
 
+// ExecutionMode defines whether orders are simulated or sent to the exchange
+type ExecutionMode string
+
+const (
+	// Execution modes
+	ExecutionPaper ExecutionMode = "PAPER"
+	ExecutionLive  ExecutionMode = "LIVE"
+)
+
+// Instrument holds the exchange's price and quantity increments for a symbol
+type Instrument struct {
+	Symbol   string          `json:"symbol"`
+	TickSize decimal.Decimal `json:"tick_size"` // Smallest price increment
+	LotSize  decimal.Decimal `json:"lot_size"`  // Smallest quantity increment
+}
+
+// NewInstrument creates an instrument with the given tick and lot sizes
+func NewInstrument(symbol string, tickSize, lotSize decimal.Decimal) *Instrument {
+	return &Instrument{Symbol: symbol, TickSize: tickSize, LotSize: lotSize}
+}
+
+// RoundPrice rounds a price to the nearest exchange-acceptable tick
+func (i *Instrument) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return price.RoundToStep(i.TickSize)
+}
+
+// PricePrecision returns the number of decimal places of the instrument's
+// prices
+func (i *Instrument) PricePrecision() int {
+	return i.TickSize.Scale()
+}
+
+// FloorQuantity rounds a quantity down to a whole number of lots, so an
+// order never exceeds the capital it was sized from
+func (i *Instrument) FloorQuantity(quantity decimal.Decimal) decimal.Decimal {
+	return quantity.FloorToStep(i.LotSize)
+}
+
 // MarketMetrics contains all calculated market metrics
 type MarketMetrics struct {
-	RealizedVolatility   float64
-	ATR                  float64
-	RelativeStrength     float64
-	OrderImbalance       float64
-	TrendStrength        float64
-	AvgTrendStrength     float64
-	MarketEfficiencyRatio float64
+	RealizedVolatility    float64 `json:"realized_volatility"`
+	ATR                   float64 `json:"atr"`
+	RelativeStrength      float64 `json:"relative_strength"`
+	OrderImbalance        float64 `json:"order_imbalance"`
+	TrendStrength         float64 `json:"trend_strength"`
+	AvgTrendStrength      float64 `json:"avg_trend_strength"`
+	MarketEfficiencyRatio float64 `json:"market_efficiency_ratio"`
+	// BookImbalance is the bid share of the quantity on the top levels of
+	// the order book, and BookImbalanceTrend its change against the recent
+	// snapshots; neutral (0.5 and 0) without book depth data
+	BookImbalance      float64 `json:"book_imbalance"`
+	BookImbalanceTrend float64 `json:"book_imbalance_trend"`
+	// SpreadBps, TopImbalance and WeightedMid are measured on the order
+	// book: the spread in basis points of the mid price, the bid share of
+	// the quantity at the best bid and ask, and the mid price weighted by
+	// the depth of the levels; neutral (0, 0.5 and 0) without book depth
+	SpreadBps    float64 `json:"spread_bps"`
+	TopImbalance float64 `json:"top_imbalance"`
+	WeightedMid  float64 `json:"weighted_mid"`
+	// StrengthDivergence and DeltaDivergence report whether the last new
+	// high or low of the price over the divergence lookback diverged from
+	// the relative strength or the cumulative volume delta: DivergenceBearish
+	// for a higher high against a lower high, DivergenceBullish for a lower
+	// low against a higher low, 0 for none or while detection is disabled
+	StrengthDivergence float64 `json:"strength_divergence"`
+	DeltaDivergence    float64 `json:"delta_divergence"`
+	// RSI is the relative strength index of the candles of the indicator
+	// timeframe, or of the prices of the classic indicators when enabled,
+	// 0 to 100; neutral (50) until computed
+	RSI float64 `json:"rsi"`
+	// MACD is the difference of the fast and slow EMAs of the prices,
+	// MACDSignal its EMA and MACDHistogram the difference of the two; 0
+	// until computed
+	MACD          float64 `json:"macd"`
+	MACDSignal    float64 `json:"macd_signal"`
+	MACDHistogram float64 `json:"macd_histogram"`
+	// BollingerUpper, BollingerMiddle and BollingerLower are the Bollinger
+	// Bands of the prices, 0 until computed, and BollingerPercentB where the
+	// price is between them: 0 at the lower band, 1 at the upper; 0.5 until
+	// computed
+	BollingerUpper    float64 `json:"bollinger_upper"`
+	BollingerMiddle   float64 `json:"bollinger_middle"`
+	BollingerLower    float64 `json:"bollinger_lower"`
+	BollingerPercentB float64 `json:"bollinger_percent_b"`
+	// FastMA and SlowMA are the moving averages of the crossover, and
+	// MACross 1 while the fast one is above the slow one, -1 while below
+	// and 0 until both are computed: a change of sign is a crossover
+	FastMA  float64 `json:"fast_ma"`
+	SlowMA  float64 `json:"slow_ma"`
+	MACross float64 `json:"ma_cross"`
+	// Timeframes are the metrics of the candle timeframes analyzed,
+	// shortest first; replaced, never modified, as candles close
+	Timeframes []TimeframeMetrics `json:"timeframes,omitempty"`
+	// Patterns are the candlestick patterns completed by the last closed
+	// candles of the pattern timeframe, newest first; replaced, never
+	// modified, as candles close
+	Patterns []CandlePattern `json:"patterns,omitempty"`
+}
+
+// CandlePattern is a candlestick pattern completed by a closed candle
+type CandlePattern struct {
+	Name      string    `json:"name"`      // e.g. bullish_engulfing
+	Bias      int       `json:"bias"`      // +1 bullish, -1 bearish, 0 indecision
+	Timeframe string    `json:"timeframe"` // e.g. 5m
+	Time      time.Time `json:"time"`      // Start of the candle completing it
+}
+
+// TimeframeMetrics are metrics measured on the closed candles of a
+// timeframe
+type TimeframeMetrics struct {
+	Timeframe string `json:"timeframe"` // e.g. 15m
+	// Trend is the regression slope of the closes in percent of the price
+	// per candle, scaled by the fit's r²: positive when rising
+	Trend                 float64 `json:"trend"`
+	MarketEfficiencyRatio float64 `json:"market_efficiency_ratio"`
+	RSI                   float64 `json:"rsi"`
+	// Ready reports whether the period of candles measured over closed
+	Ready bool `json:"ready"`
+}
+
+// Timeframe returns the metrics of a candle timeframe, if analyzed
+func (m *MarketMetrics) Timeframe(timeframe string) (TimeframeMetrics, bool) {
+	for _, metrics := range m.Timeframes {
+		if metrics.Timeframe == timeframe {
+			return metrics, true
+		}
+	}
+	return TimeframeMetrics{}, false
+}
+
+// Divergence directions reported in the metrics
+const (
+	DivergenceBearish = -1.0
+	DivergenceBullish = 1.0
+)
+
+// BearishDivergence returns whether the relative strength or the volume
+// delta failed to confirm the last new high of the price
+func (m *MarketMetrics) BearishDivergence() bool {
+	return m.StrengthDivergence == DivergenceBearish || m.DeltaDivergence == DivergenceBearish
+}
+
+// BullishDivergence returns whether the relative strength or the volume
+// delta failed to confirm the last new low of the price
+func (m *MarketMetrics) BullishDivergence() bool {
+	return m.StrengthDivergence == DivergenceBullish || m.DeltaDivergence == DivergenceBullish
+}
+
+// Finite returns a copy of the metrics JSON can carry: the metrics not
+// yet measured during the warmup, NaN or infinite, are zero
+func (m *MarketMetrics) Finite() *MarketMetrics {
+	finite := *m
+	for _, value := range []*float64{
+		&finite.RealizedVolatility, &finite.ATR, &finite.RelativeStrength, &finite.OrderImbalance,
+		&finite.TrendStrength, &finite.AvgTrendStrength, &finite.MarketEfficiencyRatio,
+		&finite.BookImbalance, &finite.BookImbalanceTrend, &finite.SpreadBps, &finite.TopImbalance, &finite.WeightedMid,
+		&finite.StrengthDivergence, &finite.DeltaDivergence, &finite.RSI,
+		&finite.MACD, &finite.MACDSignal, &finite.MACDHistogram,
+		&finite.BollingerUpper, &finite.BollingerMiddle, &finite.BollingerLower, &finite.BollingerPercentB,
+		&finite.FastMA, &finite.SlowMA, &finite.MACross,
+	} {
+		if math.IsNaN(*value) || math.IsInf(*value, 0) {
+			*value = 0
+		}
+	}
+	return &finite
 }
 
 // NewMarketMetrics creates a new MarketMetrics with default values
 func NewMarketMetrics() *MarketMetrics {
 	return &MarketMetrics{
-		RealizedVolatility:   0.0,
-		ATR:                  0.0,
-		RelativeStrength:     0.5,
-		OrderImbalance:       0.5,
-		TrendStrength:        0.0,
-		AvgTrendStrength:     0.0,
+		RealizedVolatility:    0.0,
+		ATR:                   0.0,
+		RelativeStrength:      0.5,
+		OrderImbalance:        0.5,
+		TrendStrength:         0.0,
+		AvgTrendStrength:      0.0,
 		MarketEfficiencyRatio: 0.0,
+		BookImbalance:         0.5,
+		TopImbalance:          0.5,
+		RSI:                   50,
+		BollingerPercentB:     0.5,
 	}
 }
 
 // TickData represents a single market tick
 type TickData struct {
-	Price     float64
-	Volume    float64
-	IsAsk     bool
-	Timestamp time.Time
+	Price     float64   `json:"price"`
+	Volume    float64   `json:"volume"`
+	IsAsk     bool      `json:"is_ask"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
+// Clone returns a copy of the tick, for keeping a pooled tick beyond
+// the call it was received in
+func (t *TickData) Clone() *TickData {
+	clone := *t
+	return &clone
+}
+
+// Trade directions, named after the side of the entry order
+const (
+	DirectionLong  = "buy"
+	DirectionShort = "sell"
+)
+
 // TradeData represents an active trade
 type TradeData struct {
-	Active       bool
-	Direction    string
-	EntryPrice   float64
-	EntryTime    time.Time
-	HighestPrice float64
-	LowestPrice  float64
-	StopLoss     float64
-	CurrentPnL   float64
+	ID           string    `json:"id"`
+	Active       bool      `json:"active"`
+	Direction    string    `json:"direction"`          // DirectionLong or DirectionShort
+	Strategy     string    `json:"strategy,omitempty"` // Instance of the strategy that opened the trade
+	EntryPrice   float64   `json:"entry_price"`
+	EntryTime    time.Time `json:"entry_time"`
+	HighestPrice float64   `json:"highest_price"`
+	LowestPrice  float64   `json:"lowest_price"`
+	StopLoss     float64   `json:"stop_loss"`
+	CurrentPnL   float64   `json:"current_pnl"`
+	InitialRisk  float64   `json:"initial_risk,omitempty"` // Price distance to the initial stop
+	BreakEven    bool      `json:"break_even,omitempty"`   // Stop moved to the entry price
+	MFE          float64   `json:"mfe_percent"`            // Maximum favorable excursion, percent of the entry price
+	MAE          float64   `json:"mae_percent"`            // Maximum adverse excursion, percent of the entry price (zero or negative)
+	Tags         []string  `json:"tags,omitempty"`         // Tags given at entry, e.g. the regime it was entered in
+}
+
+// Short returns whether the trade profits from a falling price
+func (t *TradeData) Short() bool {
+	return t.Direction == DirectionShort
+}
+
+// Return returns the trade's return at price, as a fraction of the entry
+// price: positive when the price moved in the trade's favor
+func (t *TradeData) Return(price float64) float64 {
+	if t.EntryPrice <= 0 {
+		return 0
+	}
+	if t.Short() {
+		return 1 - price/t.EntryPrice
+	}
+	return price/t.EntryPrice - 1
+}
+
+// BestPrice returns the most favorable price the trade has seen: the
+// highest for a long trade, the lowest for a short one
+func (t *TradeData) BestPrice() float64 {
+	if t.Short() {
+		return t.LowestPrice
+	}
+	return t.HighestPrice
+}
+
+// Track records a price seen while the trade is open in its highest and
+// lowest prices and its excursions
+func (t *TradeData) Track(price float64) {
+	if price > t.HighestPrice {
+		t.HighestPrice = price
+	}
+	if price < t.LowestPrice {
+		t.LowestPrice = price
+	}
+	if t.EntryPrice > 0 {
+		best, worst := t.HighestPrice, t.LowestPrice
+		if t.Short() {
+			best, worst = worst, best
+		}
+		t.MFE = t.Return(best) * 100
+		t.MAE = t.Return(worst) * 100
+	}
 }
 
 // NewTradeData creates a new TradeData with default values
@@ -55,32 +286,89 @@ func NewTradeData() *TradeData {
 	}
 }
 
+// NormalizeTag returns a trade tag as it is stored, trimmed and lower
+// case, and whether it is valid: letters, digits and . _ : / - only, such
+// as "regime:trending"
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", false
+	}
+	for _, r := range tag {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', strings.ContainsRune("._:/-", r):
+		default:
+			return tag, false
+		}
+	}
+	return tag, true
+}
+
 // Signal represents a trading signal
 type Signal struct {
-	Action          string
-	Side            string
-	Price           float64
-	Time            time.Time
-	Reason          string
-	ProfitPercent   float64
-	UpdatedStopLoss float64
-	Metrics         *MarketMetrics
+	ID              string         `json:"id"`
+	TradeID         string         `json:"trade_id,omitempty"`
+	CorrelationID   string         `json:"correlation_id"` // Follows the signal through execution, fills and the journal
+	Action          string         `json:"action"`
+	Side            string         `json:"side,omitempty"`
+	Price           float64        `json:"price"`
+	Time            time.Time      `json:"time"`
+	Reason          string         `json:"reason,omitempty"`
+	ProfitPercent   float64        `json:"profit_percent,omitempty"`
+	UpdatedStopLoss float64        `json:"updated_stop_loss,omitempty"`
+	MFE             float64        `json:"mfe_percent,omitempty"` // Excursions of the trade an exit closes
+	MAE             float64        `json:"mae_percent,omitempty"`
+	InitialRisk     float64        `json:"initial_risk,omitempty"` // Distance to the initial stop of the trade an entry opens
+	Quantity        float64        `json:"quantity,omitempty"`     // Base quantity an entry is sized for; 0 enters with the available capital
+	Direction       string         `json:"direction,omitempty"`    // Direction of the trade the signal enters, exits or moves the stop of
+	Strategy        string         `json:"strategy,omitempty"`     // Instance of the strategy whose trade the signal is for
+	Tags            []string       `json:"tags,omitempty"`         // Tags of the trade an entry opens or an exit closes
+	Metrics         *MarketMetrics `json:"metrics,omitempty"`
 }
 
 // NewBuySignal creates a new buy signal
 func NewBuySignal(price float64, timestamp time.Time, metrics *MarketMetrics) *Signal {
 	return &Signal{
-		Action:  "BUY",
-		Side:    "buy",
-		Price:   price,
-		Time:    timestamp,
-		Metrics: metrics,
+		ID:            ids.Signal(),
+		CorrelationID: ids.Correlation(),
+		Action:        "BUY",
+		Side:          "buy",
+		Price:         price,
+		Time:          timestamp,
+		Direction:     DirectionLong,
+		Metrics:       metrics,
+	}
+}
+
+// NewShortSignal creates a signal entering a short trade
+func NewShortSignal(price float64, timestamp time.Time, metrics *MarketMetrics) *Signal {
+	return &Signal{
+		ID:            ids.Signal(),
+		CorrelationID: ids.Correlation(),
+		Action:        "SHORT",
+		Side:          "sell",
+		Price:         price,
+		Time:          timestamp,
+		Direction:     DirectionShort,
+		Metrics:       metrics,
 	}
 }
 
+// IsEntry returns whether the signal enters a trade, long or short
+func (s *Signal) IsEntry() bool {
+	return s.Action == "BUY" || s.Action == "SHORT"
+}
+
+// Short returns whether the signal is for a short trade
+func (s *Signal) Short() bool {
+	return s.Direction == DirectionShort
+}
+
 // NewSellSignal creates a new sell signal
 func NewSellSignal(price float64, timestamp time.Time, reason string, profitPercent float64, stopLoss float64) *Signal {
 	return &Signal{
+		ID:              ids.Signal(),
+		CorrelationID:   ids.Correlation(),
 		Action:          "CLOSE",
 		Price:           price,
 		Time:            timestamp,
@@ -90,35 +378,106 @@ func NewSellSignal(price float64, timestamp time.Time, reason string, profitPerc
 	}
 }
 
+// NewStopSignal creates a signal moving the stop of an open trade
+func NewStopSignal(price float64, timestamp time.Time, reason string, stopLoss float64) *Signal {
+	return &Signal{
+		ID:              ids.Signal(),
+		CorrelationID:   ids.Correlation(),
+		Action:          "MOVE_STOP",
+		Side:            "sell",
+		Price:           price,
+		Time:            timestamp,
+		Reason:          reason,
+		UpdatedStopLoss: stopLoss,
+	}
+}
+
 // MarketState represents the current state of the market
 type MarketState struct {
-	Timestamp    time.Time
-	CurrentPrice float64
-	Metrics      *MarketMetrics
-	ActiveTrade  *TradeData
-	Performance  *PerformanceMetrics
+	Timestamp    time.Time           `json:"timestamp"`
+	CurrentPrice float64             `json:"current_price"`
+	Metrics      *MarketMetrics      `json:"metrics,omitempty"`
+	ActiveTrade  *TradeData          `json:"active_trade,omitempty"`
+	Performance  *PerformanceMetrics `json:"performance,omitempty"`
 }
 
 // PerformanceMetrics represents trading performance statistics
 type PerformanceMetrics struct {
-	TotalTrades  int
-	WinningTrades int
-	LosingTrades int
-	WinRate      float64
-	AveragePnL   float64
-	TotalPnL     float64
-	MaxDrawdown  float64
+	TotalTrades   int           `json:"total_trades"`
+	WinningTrades int           `json:"winning_trades"`
+	LosingTrades  int           `json:"losing_trades"`
+	WinRate       float64       `json:"win_rate"`
+	AveragePnL    float64       `json:"average_pnl"`
+	TotalPnL      float64       `json:"total_pnl"`
+	MaxDrawdown   float64       `json:"max_drawdown"`
+	ProfitFactor  float64       `json:"profit_factor"`       // Gross profit / gross loss
+	ExposureTime  time.Duration `json:"exposure_time"`       // Total time spent in trades
+	AverageMFE    float64       `json:"average_mfe_percent"` // Mean maximum favorable excursion
+	AverageMAE    float64       `json:"average_mae_percent"` // Mean maximum adverse excursion
+	SharpeRatio   float64       `json:"sharpe_ratio"`        // Mean trade return over its standard deviation, per trade
+	Expectancy    float64       `json:"expectancy_percent"`  // Expected return of a trade: win rate × average win − loss rate × average loss
+
+	// AnnualizedSharpe is the Sharpe ratio of the daily returns, annualized
+	// over the trading days of the symbol's asset class
+	AnnualizedSharpe float64 `json:"annualized_sharpe"`
+}
+
+// EquityPoint is the account equity with open positions marked to market
+type EquityPoint struct {
+	Time            time.Time `json:"time"`
+	Equity          float64   `json:"equity"`
+	RealizedPnL     float64   `json:"realized_pnl"`
+	UnrealizedPnL   float64   `json:"unrealized_pnl"`
+	Peak            float64   `json:"peak"`             // Highest equity of the session
+	Drawdown        float64   `json:"drawdown"`         // Equity below Peak
+	DrawdownPercent float64   `json:"drawdown_percent"` // Drawdown as a percentage of Peak
+	MaxDrawdown     float64   `json:"max_drawdown_percent"`
+}
+
+// PortfolioView aggregates the instances running the symbols of a
+// portfolio; amounts are in the reporting currency
+type PortfolioView struct {
+	Time          time.Time         `json:"time"`
+	Equity        float64           `json:"equity"` // Total equity of the instances
+	OpenPnL       float64           `json:"open_pnl"`
+	GrossExposure float64           `json:"gross_exposure"` // Long plus short notional
+	NetExposure   float64           `json:"net_exposure"`   // Long minus short notional
+	OpenRisk      float64           `json:"open_risk"`      // Loss if every stop is hit
+	Positions     []SymbolPosition  `json:"positions"`      // One per instance, by symbol
+	TopRisks      []RiskContributor `json:"top_risks"`
+}
+
+// SymbolPosition is the state of an instance of a portfolio
+type SymbolPosition struct {
+	Symbol    string    `json:"symbol"`
+	RunID     string    `json:"run_id"`
+	Direction string    `json:"direction,omitempty"` // DirectionLong or DirectionShort; empty when flat
+	Notional  float64   `json:"notional"`            // Open position marked to market
+	OpenPnL   float64   `json:"open_pnl"`
+	OpenRisk  float64   `json:"open_risk"`
+	Equity    float64   `json:"equity"`
+	Updated   time.Time `json:"updated"`
+}
+
+// RiskContributor is a symbol with its share of the open risk of a
+// portfolio
+type RiskContributor struct {
+	Symbol   string  `json:"symbol"`
+	OpenRisk float64 `json:"open_risk"`
+	Share    float64 `json:"share"` // Fraction of the portfolio's open risk
 }
 
 // NewPerformanceMetrics creates a new PerformanceMetrics with default values
 func NewPerformanceMetrics() *PerformanceMetrics {
 	return &PerformanceMetrics{
-		TotalTrades:  0,
+		TotalTrades:   0,
 		WinningTrades: 0,
-		LosingTrades: 0,
-		WinRate:      0.0,
-		AveragePnL:   0.0,
-		TotalPnL:     0.0,
-		MaxDrawdown:  0.0,
+		LosingTrades:  0,
+		WinRate:       0.0,
+		AveragePnL:    0.0,
+		TotalPnL:      0.0,
+		MaxDrawdown:   0.0,
+		ProfitFactor:  0.0,
+		ExposureTime:  0,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,203 @@
+package watchdog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"TRADE/pkg/events"
+	"TRADE/pkg/logger"
+)
+
+// Source describes a monitored per-symbol data pipeline
+type Source struct {
+	Symbol     string
+	LastTick   func() time.Time // Time the last tick was received
+	LastUpdate func() time.Time // Time the analyzer last updated its metrics
+	Reconnect  func() error     // Forces a feed reconnect
+}
+
+// FreezeHandler is called when entries for a symbol should be frozen or thawed
+type FreezeHandler func(symbol string, frozen bool)
+
+// Watchdog detects stalled market data and analyzer updates
+type Watchdog struct {
+	threshold     time.Duration
+	checkInterval time.Duration
+	freezeEntries bool
+	sources       []Source
+	stale         map[string]bool
+	startTime     time.Time
+	onFreeze      FreezeHandler
+	bus           *events.Bus
+	logger        logger.Interface
+	stopChan      chan struct{}
+	mutex         sync.Mutex
+}
+
+// NewWatchdog creates a watchdog that treats data older than threshold as stale
+func NewWatchdog(threshold time.Duration, freezeEntries bool, bus *events.Bus, log logger.Interface) *Watchdog {
+	checkInterval := threshold / 3
+	if checkInterval < time.Second {
+		checkInterval = time.Second
+	}
+
+	return &Watchdog{
+		threshold:     threshold,
+		checkInterval: checkInterval,
+		freezeEntries: freezeEntries,
+		stale:         make(map[string]bool),
+		bus:           bus,
+		logger:        log,
+	}
+}
+
+// AddSource registers a pipeline to monitor
+func (w *Watchdog) AddSource(source Source) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.sources = append(w.sources, source)
+}
+
+// SetFreezeHandler sets the callback used to freeze and thaw entries
+func (w *Watchdog) SetFreezeHandler(handler FreezeHandler) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onFreeze = handler
+}
+
+// Start begins periodic checks
+func (w *Watchdog) Start() {
+	w.mutex.Lock()
+	if w.stopChan != nil {
+		w.mutex.Unlock()
+		return
+	}
+	w.stopChan = make(chan struct{})
+	w.startTime = time.Now()
+	stopChan := w.stopChan
+	w.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(w.checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				w.Check(now)
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends periodic checks
+func (w *Watchdog) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.stopChan != nil {
+		close(w.stopChan)
+		w.stopChan = nil
+	}
+}
+
+// CheckInterval returns the time between two checks of the sources
+func (w *Watchdog) CheckInterval() time.Duration {
+	return w.checkInterval
+}
+
+// IsStale reports whether the symbol's data is currently considered stale
+func (w *Watchdog) IsStale(symbol string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.stale[symbol]
+}
+
+// Check inspects every source once
+func (w *Watchdog) Check(now time.Time) {
+	w.mutex.Lock()
+	sources := append([]Source(nil), w.sources...)
+	startTime := w.startTime
+	w.mutex.Unlock()
+
+	for _, source := range sources {
+		w.checkSource(source, now, startTime)
+	}
+}
+
+// checkSource detects stalls and recoveries for one source
+func (w *Watchdog) checkSource(source Source, now time.Time, startTime time.Time) {
+	// Ages are measured from start-up until the first data arrives
+	tickAge := age(now, source.LastTick(), startTime)
+	updateAge := time.Duration(0)
+	if source.LastUpdate != nil {
+		updateAge = age(now, source.LastUpdate(), startTime)
+	}
+
+	feedStalled := tickAge > w.threshold
+	analyzerStalled := updateAge > w.threshold
+
+	w.mutex.Lock()
+	wasStale := w.stale[source.Symbol]
+	isStale := feedStalled || analyzerStalled
+	w.stale[source.Symbol] = isStale
+	onFreeze := w.onFreeze
+	w.mutex.Unlock()
+
+	if isStale {
+		reason := fmt.Sprintf("no tick for %s", tickAge.Round(time.Second))
+		if !feedStalled {
+			reason = fmt.Sprintf("no analyzer update for %s", updateAge.Round(time.Second))
+		}
+
+		if !wasStale {
+			w.alert(events.AlertWarning, source.Symbol, "Stale data detected: "+reason, now)
+			if w.freezeEntries && onFreeze != nil {
+				onFreeze(source.Symbol, true)
+			}
+		}
+
+		// Keep trying to reconnect while the feed itself is silent
+		if feedStalled && source.Reconnect != nil {
+			if err := source.Reconnect(); err != nil {
+				w.logger.Error(fmt.Sprintf("Watchdog reconnect failed for %s: %v", source.Symbol, err))
+			}
+		}
+		return
+	}
+
+	if wasStale {
+		w.alert(events.AlertInfo, source.Symbol, "Fresh data resumed", now)
+		if w.freezeEntries && onFreeze != nil {
+			onFreeze(source.Symbol, false)
+		}
+	}
+}
+
+// alert logs an alert and publishes it on the bus
+func (w *Watchdog) alert(level events.AlertLevel, symbol string, message string, now time.Time) {
+	if level == events.AlertInfo {
+		w.logger.Info(fmt.Sprintf("Watchdog [%s]: %s", symbol, message))
+	} else {
+		w.logger.Warning(fmt.Sprintf("Watchdog [%s]: %s", symbol, message))
+	}
+
+	w.bus.Publish(&events.AlertEvent{
+		Level:     level,
+		Source:    "watchdog",
+		Symbol:    symbol,
+		Message:   message,
+		Timestamp: now,
+	})
+}
+
+// age returns how old the last event is, falling back to the start time
+func age(now time.Time, last time.Time, startTime time.Time) time.Duration {
+	if last.IsZero() {
+		last = startTime
+	}
+	return now.Sub(last)
+}
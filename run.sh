@@ -10,12 +10,17 @@ show_help() {
     echo "Options:"
     echo "  --live      Run in live trading mode (default)"
     echo "  --backtest  Run in backtest mode"
+    echo "  --sim       Run on a synthetic market simulator"
+    echo "  --daemon    Run as a supervised daemon (PID file, systemd notify)"
+    echo "  --config=PATH   Load configuration from a YAML file"
+    echo "  --live-trading  Send real orders (requires acknowledgment in config)"
     echo "  --help      Show this help message"
     echo ""
 }
 
 # Default mode
 MODE="live"
+EXTRA_ARGS=""
 
 # Parse command line arguments
 while [[ $# -gt 0 ]]; do
@@ -28,6 +33,18 @@ while [[ $# -gt 0 ]]; do
             MODE="backtest"
             shift
             ;;
+        --sim)
+            MODE="sim"
+            shift
+            ;;
+        --daemon)
+            EXTRA_ARGS="$EXTRA_ARGS --daemon"
+            shift
+            ;;
+        --config=*|--live-trading)
+            EXTRA_ARGS="$EXTRA_ARGS $1"
+            shift
+            ;;
         --help)
             show_help
             exit 0
@@ -51,10 +68,14 @@ fi
 echo "Checking dependencies..."
 go mod download
 
-# Build the application
+# Build the application with version information
 echo "Building TRADE..."
-go build -o TRADE cmd/main.go
+VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo "dev")
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo "unknown")
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+LDFLAGS="-X TRADE/pkg/version.Version=$VERSION -X TRADE/pkg/version.Commit=$COMMIT -X TRADE/pkg/version.BuildDate=$BUILD_DATE"
+go build -ldflags "$LDFLAGS" -o TRADE ./cmd
 
 # Run the application
 echo "Starting TRADE in $MODE mode..."
-./TRADE --mode=$MODE
\ No newline at end of file
+./TRADE --mode=$MODE $EXTRA_ARGS
\ No newline at end of file